@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/netip"
 	"os"
 	"os/signal"
 	"strings"
@@ -14,9 +15,11 @@ import (
 
 	_ "github.com/breml/rootcerts"
 	"github.com/qdm12/dns/pkg/unbound"
+	"github.com/qdm12/gluetun/internal/accesslog"
 	"github.com/qdm12/gluetun/internal/alpine"
 	"github.com/qdm12/gluetun/internal/cli"
 	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/qdm12/gluetun/internal/configuration/sources/configfile"
 	"github.com/qdm12/gluetun/internal/configuration/sources/env"
 	"github.com/qdm12/gluetun/internal/configuration/sources/files"
 	mux "github.com/qdm12/gluetun/internal/configuration/sources/merge"
@@ -34,7 +37,10 @@ import (
 	"github.com/qdm12/gluetun/internal/pprof"
 	"github.com/qdm12/gluetun/internal/provider"
 	"github.com/qdm12/gluetun/internal/publicip"
+	publicipapi "github.com/qdm12/gluetun/internal/publicip/api"
 	"github.com/qdm12/gluetun/internal/publicip/ipinfo"
+	"github.com/qdm12/gluetun/internal/ratelimit"
+	"github.com/qdm12/gluetun/internal/reload"
 	"github.com/qdm12/gluetun/internal/routing"
 	"github.com/qdm12/gluetun/internal/server"
 	"github.com/qdm12/gluetun/internal/shadowsocks"
@@ -44,6 +50,7 @@ import (
 	"github.com/qdm12/gluetun/internal/updater/resolver"
 	"github.com/qdm12/gluetun/internal/updater/unzip"
 	"github.com/qdm12/gluetun/internal/vpn"
+	"github.com/qdm12/gluetun/internal/wireguard"
 	"github.com/qdm12/golibs/command"
 	"github.com/qdm12/goshutdown"
 	"github.com/qdm12/goshutdown/goroutine"
@@ -85,7 +92,8 @@ func main() {
 	envReader := env.New(logger)
 	filesReader := files.New()
 	secretsReader := secrets.New()
-	muxReader := mux.New(envReader, filesReader, secretsReader)
+	configFileReader := configfile.New()
+	muxReader := mux.New(configFileReader, envReader, filesReader, secretsReader)
 
 	errorCh := make(chan error)
 	go func() {
@@ -154,6 +162,14 @@ func _main(ctx context.Context, buildInfo models.BuildInformation,
 			return cli.Update(ctx, args[2:], logger)
 		case "format-servers":
 			return cli.FormatServers(args[2:])
+		case "servers-history":
+			return cli.ServersHistory(args[2:])
+		case "servers-rollback":
+			return cli.ServersRollback(args[2:])
+		case "wireguard-server-client":
+			return cli.WireguardServerClient(args[2:], source)
+		case "config-schema":
+			return cli.ConfigSchema()
 		default:
 			return fmt.Errorf("%w: %s", errCommandUnknown, args[1])
 		}
@@ -239,7 +255,9 @@ func _main(ctx context.Context, buildInfo models.BuildInformation,
 
 	err = allSettings.Validate(storage, ipv6Supported)
 	if err != nil {
-		return err
+		return source.LocateError(err, func(settings settings.Settings) error {
+			return settings.Validate(storage, ipv6Supported)
+		})
 	}
 
 	allSettings.Pprof.HTTPServer.Logger = logger.New(log.SetComponent("pprof"))
@@ -330,6 +348,28 @@ func _main(ctx context.Context, buildInfo models.BuildInformation,
 		return fmt.Errorf("adding local rules: %w", err)
 	}
 
+	if *allSettings.Firewall.Gateway.Enabled {
+		err = setupGateway(ctx, allSettings.Firewall.Gateway.ClientSubnets,
+			localNetworks, *allSettings.DNS.DoT.Enabled, routingConf, firewallConf)
+		if err != nil {
+			if strings.Contains(err.Error(), "read-only file system") {
+				logger.Warn("💡 Tip: set the sysctl net.ipv4.ip_forward=1 on the container")
+			}
+			return fmt.Errorf("setting up gateway mode: %w", err)
+		}
+	}
+
+	if *allSettings.WireguardServer.Enabled {
+		err = setupWireguardServerNetwork(ctx, allSettings.WireguardServer,
+			defaultRoutes, routingConf, firewallConf)
+		if err != nil {
+			if strings.Contains(err.Error(), "read-only file system") {
+				logger.Warn("💡 Tip: set the sysctl net.ipv4.ip_forward=1 on the container")
+			}
+			return fmt.Errorf("setting up Wireguard server network: %w", err)
+		}
+	}
+
 	const tunDevice = "/dev/net/tun"
 	if err := tun.Check(tunDevice); err != nil {
 		logger.Info(err.Error() + "; creating it...")
@@ -395,10 +435,31 @@ func _main(ctx context.Context, buildInfo models.BuildInformation,
 	go unboundLooper.RunRestartTicker(dnsTickerCtx, dnsTickerDone)
 	controlGroupHandler.Add(dnsTickerHandler)
 
-	ipFetcher := ipinfo.New(httpClient)
-	publicIPLooper := publicip.NewLoop(ipFetcher,
-		logger.New(log.SetComponent("ip getter")),
-		allSettings.PublicIP, puid, pgid)
+	ipFetcher := ipinfo.New(httpClient, *allSettings.PublicIP.IPInfoToken)
+	publicIPLogger := logger.New(log.SetComponent("ip getter"))
+	publicIPAPISettings := publicipapi.Settings{
+		Names:        allSettings.PublicIP.APIs,
+		IPInfoToken:  *allSettings.PublicIP.IPInfoToken,
+		CustomURL:    *allSettings.PublicIP.CustomAPI.URL,
+		CustomFields: allSettings.PublicIP.CustomAPI.Fields,
+	}
+	publicIPFetchers, err := publicipapi.New(publicIPAPISettings,
+		publicipapi.NewFamilyClient(httpClient, false))
+	if err != nil {
+		return fmt.Errorf("creating public IP API fetchers: %w", err)
+	}
+	var publicIPv6Fetcher publicip.Fetcher
+	if ipv6Supported {
+		publicIPv6Fetchers, err := publicipapi.New(publicIPAPISettings,
+			publicipapi.NewFamilyClient(httpClient, true))
+		if err != nil {
+			return fmt.Errorf("creating public IPv6 API fetchers: %w", err)
+		}
+		publicIPv6Fetcher = publicipapi.NewResilient(publicIPv6Fetchers, publicIPLogger)
+	}
+	publicIPLooper := publicip.NewLoop(
+		publicipapi.NewResilient(publicIPFetchers, publicIPLogger), publicIPv6Fetcher,
+		publicIPLogger, allSettings.PublicIP, puid, pgid)
 	pubIPHandler, pubIPCtx, pubIPDone := goshutdown.NewGoRoutineHandler(
 		"public IP", goroutine.OptionTimeout(defaultShutdownTimeout))
 	go publicIPLooper.Run(pubIPCtx, pubIPDone)
@@ -412,7 +473,12 @@ func _main(ctx context.Context, buildInfo models.BuildInformation,
 	updaterLogger := logger.New(log.SetComponent("updater"))
 
 	unzipper := unzip.New(httpClient)
-	parallelResolver := resolver.NewParallelResolver(allSettings.Updater.DNSAddress)
+	resolverSettings, err := resolver.ParseSettings(allSettings.Updater.DNSProtocol,
+		allSettings.Updater.DNSAddress, allSettings.Updater.DNSProviders)
+	if err != nil {
+		return fmt.Errorf("converting updater resolver settings: %w", err)
+	}
+	parallelResolver := resolver.NewParallelResolver(resolverSettings)
 	openvpnFileExtractor := extract.New()
 	providers := provider.NewProviders(storage, time.Now, updaterLogger,
 		httpClient, unzipper, parallelResolver, ipFetcher, openvpnFileExtractor)
@@ -427,7 +493,7 @@ func _main(ctx context.Context, buildInfo models.BuildInformation,
 	go vpnLooper.Run(vpnCtx, vpnDone)
 
 	updaterLooper := updater.NewLoop(allSettings.Updater,
-		providers, storage, httpClient, updaterLogger)
+		providers, storage, parallelResolver, httpClient, updaterLogger)
 	updaterHandler, updaterCtx, updaterDone := goshutdown.NewGoRoutineHandler(
 		"updater", goroutine.OptionTimeout(defaultShutdownTimeout))
 	// wait for updaterLooper.Restart() or its ticket launched with RunRestartTicker
@@ -439,21 +505,78 @@ func _main(ctx context.Context, buildInfo models.BuildInformation,
 	go updaterLooper.RunRestartTicker(updaterTickerCtx, updaterTickerDone)
 	controlGroupHandler.Add(updaterTickerHandler)
 
+	const megabyte = 1024 * 1024
+	accessLog, err := accesslog.New(*allSettings.Log.AccessLog,
+		int64(*allSettings.Log.AccessLogMaxSize)*megabyte,
+		*allSettings.Log.AccessLogMaxBackups)
+	if err != nil {
+		return fmt.Errorf("creating access log: %w", err)
+	}
+	defer func() {
+		err := accessLog.Close()
+		if err != nil {
+			logger.Error("closing access log: " + err.Error())
+		}
+	}()
+
+	// The shaper is shared by the HTTP proxy and Shadowsocks server
+	// so the bandwidth limits apply to their clients combined.
+	shaper := ratelimit.NewShaper(allSettings.Shaping.Limits())
+
 	httpProxyLooper := httpproxy.NewLoop(
 		logger.New(log.SetComponent("http proxy")),
-		allSettings.HTTPProxy)
+		allSettings.HTTPProxy, accessLog, shaper, allSettings.Firewall.OutboundSubnets)
 	httpProxyHandler, httpProxyCtx, httpProxyDone := goshutdown.NewGoRoutineHandler(
 		"http proxy", goroutine.OptionTimeout(defaultShutdownTimeout))
 	go httpProxyLooper.Run(httpProxyCtx, httpProxyDone)
 	otherGroupHandler.Add(httpProxyHandler)
 
 	shadowsocksLooper := shadowsocks.NewLoop(allSettings.Shadowsocks,
-		logger.New(log.SetComponent("shadowsocks")))
+		logger.New(log.SetComponent("shadowsocks")), accessLog, shaper)
 	shadowsocksHandler, shadowsocksCtx, shadowsocksDone := goshutdown.NewGoRoutineHandler(
 		"shadowsocks proxy", goroutine.OptionTimeout(defaultShutdownTimeout))
 	go shadowsocksLooper.Run(shadowsocksCtx, shadowsocksDone)
 	otherGroupHandler.Add(shadowsocksHandler)
 
+	if *allSettings.WireguardServer.Enabled {
+		wireguardServerLogger := logger.New(log.SetComponent("wireguard server"))
+		wireguardServer, err := wireguard.NewServer(
+			makeWireguardServerSettings(allSettings.WireguardServer, allSettings.VPN.Wireguard),
+			netLinker, wireguardServerLogger)
+		if err != nil {
+			return fmt.Errorf("creating Wireguard server: %w", err)
+		}
+		wireguardServerHandler, wireguardServerCtx, wireguardServerDone := goshutdown.NewGoRoutineHandler(
+			"wireguard server", goroutine.OptionTimeout(defaultShutdownTimeout))
+		go runWireguardServer(wireguardServerCtx, wireguardServer,
+			wireguardServerLogger, wireguardServerDone)
+		otherGroupHandler.Add(wireguardServerHandler)
+	}
+
+	defaultInterfaces := make([]string, len(defaultRoutes))
+	for i, defaultRoute := range defaultRoutes {
+		defaultInterfaces[i] = defaultRoute.NetInterface
+	}
+	reloadLoops := reload.Loops{
+		DNS:         unboundLooper,
+		HTTPProxy:   httpProxyLooper,
+		Shadowsocks: shadowsocksLooper,
+		Shaper:      shaper,
+		PortForward: portForwardLooper,
+		PublicIP:    publicIPLooper,
+		Updater:     updaterLooper,
+	}
+	hangupCh := make(chan os.Signal, 1)
+	signal.Notify(hangupCh, syscall.SIGHUP)
+	defer signal.Stop(hangupCh)
+	reloader := reload.New(allSettings, source, storage, ipv6Supported,
+		reloadLoops, firewallConf, routingConf, defaultInterfaces,
+		source.Filepaths(), hangupCh, logger.New(log.SetComponent("reload")))
+	reloadHandler, reloadCtx, reloadDone := goshutdown.NewGoRoutineHandler(
+		"settings reloader", goroutine.OptionTimeout(defaultShutdownTimeout))
+	go reloader.Run(reloadCtx, reloadDone)
+	controlGroupHandler.Add(reloadHandler)
+
 	controlServerAddress := *allSettings.ControlServer.Address
 	controlServerLogging := *allSettings.ControlServer.Log
 	httpServerHandler, httpServerCtx, httpServerDone := goshutdown.NewGoRoutineHandler(
@@ -461,7 +584,7 @@ func _main(ctx context.Context, buildInfo models.BuildInformation,
 	httpServer, err := server.New(httpServerCtx, controlServerAddress, controlServerLogging,
 		logger.New(log.SetComponent("http server")),
 		buildInfo, vpnLooper, portForwardLooper, unboundLooper, updaterLooper, publicIPLooper,
-		storage, ipv6Supported)
+		portForwardLooper, httpProxyLooper, shadowsocksLooper, shaper, storage, ipv6Supported)
 	if err != nil {
 		return fmt.Errorf("setting up control server: %w", err)
 	}
@@ -561,9 +684,13 @@ type Linker interface {
 type clier interface {
 	ClientKey(args []string) error
 	FormatServers(args []string) error
+	ServersHistory(args []string) error
+	ServersRollback(args []string) error
 	OpenvpnConfig(logger cli.OpenvpnConfigLogger, source cli.Source, ipv6Checker cli.IPv6Checker) error
 	HealthCheck(ctx context.Context, source cli.Source, warner cli.Warner) error
 	Update(ctx context.Context, args []string, logger cli.UpdaterLogger) error
+	WireguardServerClient(args []string, source cli.Source) error
+	ConfigSchema() error
 }
 
 type Tun interface {
@@ -574,5 +701,102 @@ type Tun interface {
 type Source interface {
 	Read() (settings settings.Settings, err error)
 	ReadHealth() (health settings.Health, err error)
+	Filepaths() (paths []string)
+	LocateError(validationErr error, validate func(settings settings.Settings) error) error
 	String() string
 }
+
+func setupGateway(ctx context.Context, clientSubnets []netip.Prefix,
+	localNetworks []routing.LocalNetwork, redirectDNS bool,
+	routingConf *routing.Routing, firewallConf *firewall.Config) (err error) {
+	if len(clientSubnets) == 0 {
+		for _, localNetwork := range localNetworks {
+			if localNetwork.IPNet.Addr().Is4() {
+				clientSubnets = append(clientSubnets, localNetwork.IPNet)
+			}
+		}
+	}
+
+	ipv6 := false
+	for _, subnet := range clientSubnets {
+		if subnet.Addr().Is6() {
+			ipv6 = true
+			break
+		}
+	}
+
+	err = routingConf.EnableForwarding(ipv6)
+	if err != nil {
+		return err
+	}
+
+	return firewallConf.SetGateway(ctx, clientSubnets, redirectDNS)
+}
+
+func setupWireguardServerNetwork(ctx context.Context, serverSettings settings.WireguardServer,
+	defaultRoutes []routing.DefaultRoute, routingConf *routing.Routing,
+	firewallConf *firewall.Config) (err error) {
+	for _, defaultRoute := range defaultRoutes {
+		err = firewallConf.SetAllowedPort(ctx, *serverSettings.ListeningPort, defaultRoute.NetInterface)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Route replies to peers through the main table, where the
+	// Wireguard server interface subnet route is.
+	subnet := serverSettings.Address.Masked()
+	err = routingConf.AddLocalRules([]routing.LocalNetwork{{IPNet: subnet}})
+	if err != nil {
+		return fmt.Errorf("adding local rule: %w", err)
+	}
+
+	err = routingConf.EnableForwarding(subnet.Addr().Is6())
+	if err != nil {
+		return err
+	}
+
+	return firewallConf.SetInboundTunnel(ctx, serverSettings.Interface, subnet)
+}
+
+func makeWireguardServerSettings(serverSettings settings.WireguardServer,
+	vpnWireguard settings.Wireguard) (wireguardSettings wireguard.ServerSettings) {
+	wireguardSettings = wireguard.ServerSettings{
+		InterfaceName:  serverSettings.Interface,
+		PrivateKey:     *serverSettings.PrivateKey,
+		ListenPort:     *serverSettings.ListeningPort,
+		Address:        serverSettings.Address,
+		Peers:          make([]wireguard.ServerPeer, len(serverSettings.Peers)),
+		MTU:            vpnWireguard.MTU,
+		Implementation: vpnWireguard.Implementation,
+	}
+	for i, peer := range serverSettings.Peers {
+		wireguardSettings.Peers[i] = wireguard.ServerPeer{
+			PublicKey:    peer.PublicKey,
+			PreSharedKey: peer.PreSharedKey,
+			Address:      peer.Address,
+		}
+	}
+	return wireguardSettings
+}
+
+func runWireguardServer(ctx context.Context, server *wireguard.Server,
+	logger log.LoggerInterface, done chan<- struct{}) {
+	defer close(done)
+
+	waitError := make(chan error)
+	ready := make(chan struct{})
+	go server.Run(ctx, waitError, ready)
+
+	select {
+	case <-ready:
+	case err := <-waitError:
+		logger.Error(err.Error())
+		return
+	}
+
+	err := <-waitError
+	if !errors.Is(err, context.Canceled) {
+		logger.Error(err.Error())
+	}
+}
@@ -0,0 +1,109 @@
+package accesslog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Entry_MarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	entry := Entry{
+		Time:        time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		Proxy:       "http proxy",
+		Client:      "192.168.1.5",
+		User:        "alice",
+		Destination: "example.com:443",
+		Method:      "CONNECT",
+		Status:      200,
+		BytesUp:     10,
+		BytesDown:   20,
+		Duration:    1500 * time.Millisecond,
+	}
+
+	data, err := entry.MarshalJSON()
+
+	require.NoError(t, err)
+	const expected = `{"time":"2023-01-02T03:04:05Z","proxy":"http proxy",` +
+		`"client":"192.168.1.5","user":"alice","destination":"example.com:443",` +
+		`"method":"CONNECT","status":200,"bytes_up":10,"bytes_down":20,"duration_ms":1500}`
+	assert.Equal(t, expected, string(data))
+}
+
+func Test_Logger(t *testing.T) {
+	t.Parallel()
+
+	t.Run("nil", func(t *testing.T) {
+		t.Parallel()
+
+		logger, err := New("", 0, 0)
+		require.NoError(t, err)
+		assert.Nil(t, logger)
+		err = logger.Log(Entry{})
+		assert.NoError(t, err)
+		err = logger.Close()
+		assert.NoError(t, err)
+	})
+
+	t.Run("rotation", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "access.log")
+		entry := Entry{Client: "1.2.3.4"}
+		line, err := entry.MarshalJSON()
+		require.NoError(t, err)
+		lineSize := int64(len(line) + 1)
+
+		// Two lines fit in a file.
+		logger, err := New(path, 2*lineSize, 1)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = logger.Close() })
+
+		const entries = 5
+		for i := 0; i < entries; i++ {
+			err = logger.Log(entry)
+			require.NoError(t, err)
+		}
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, 1, strings.Count(string(data), "\n"))
+		data, err = os.ReadFile(path + ".1")
+		require.NoError(t, err)
+		assert.Equal(t, 2, strings.Count(string(data), "\n"))
+		_, err = os.Stat(path + ".2")
+		assert.True(t, os.IsNotExist(err))
+	})
+}
+
+func Test_Recorder(t *testing.T) {
+	t.Parallel()
+
+	recorder := NewRecorder(nil)
+	first := time.Unix(1, 0)
+	second := time.Unix(2, 0)
+
+	entries := []Entry{
+		{Time: first, Client: "10.0.0.2", User: "bob", BytesUp: 1, BytesDown: 2},
+		{Time: second, Client: "10.0.0.2", User: "alice", BytesUp: 3, BytesDown: 4},
+		{Time: first, Client: "10.0.0.1", BytesUp: 5, BytesDown: 6},
+		{Time: first, Client: "10.0.0.2", User: "bob", BytesUp: 7, BytesDown: 8},
+	}
+	for _, entry := range entries {
+		err := recorder.Record(entry)
+		require.NoError(t, err)
+	}
+
+	expected := []ClientStats{
+		{Client: "10.0.0.1", Requests: 1, BytesUp: 5, BytesDown: 6, LastSeen: first},
+		{Client: "10.0.0.2", Users: []string{"alice", "bob"}, Requests: 3,
+			BytesUp: 11, BytesDown: 14, LastSeen: second},
+	}
+	assert.Equal(t, expected, recorder.Clients())
+}
@@ -0,0 +1,61 @@
+// Package accesslog writes structured access logs for the proxies,
+// and aggregates the traffic proxied per client.
+package accesslog
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Entry is an access log entry for a request or
+// connection proxied.
+type Entry struct {
+	Time time.Time
+	// Proxy is the name of the proxy, for example "http proxy".
+	Proxy string
+	// Client is the IP address of the client.
+	Client string
+	// User is the authenticated user name, and is
+	// empty if the client is not authenticated.
+	User string
+	// Destination is the destination host and port.
+	Destination string
+	// Method is the HTTP method for the HTTP proxy,
+	// or the transport protocol for Shadowsocks.
+	Method string
+	// Status is the HTTP status code for the HTTP proxy,
+	// and is 0 for Shadowsocks.
+	Status int
+	// BytesUp is the number of bytes sent by the client.
+	BytesUp uint64
+	// BytesDown is the number of bytes received by the client.
+	BytesDown uint64
+	Duration  time.Duration
+}
+
+func (e Entry) MarshalJSON() (data []byte, err error) {
+	type jsonEntry struct {
+		Time        time.Time `json:"time"`
+		Proxy       string    `json:"proxy"`
+		Client      string    `json:"client"`
+		User        string    `json:"user,omitempty"`
+		Destination string    `json:"destination"`
+		Method      string    `json:"method"`
+		Status      int       `json:"status,omitempty"`
+		BytesUp     uint64    `json:"bytes_up"`
+		BytesDown   uint64    `json:"bytes_down"`
+		DurationMs  int64     `json:"duration_ms"`
+	}
+	return json.Marshal(jsonEntry{
+		Time:        e.Time,
+		Proxy:       e.Proxy,
+		Client:      e.Client,
+		User:        e.User,
+		Destination: e.Destination,
+		Method:      e.Method,
+		Status:      e.Status,
+		BytesUp:     e.BytesUp,
+		BytesDown:   e.BytesDown,
+		DurationMs:  e.Duration.Milliseconds(),
+	})
+}
@@ -0,0 +1,147 @@
+package accesslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Stdout is the path value to write access logs to
+// the standard output instead of a file.
+const Stdout = "stdout"
+
+// Logger writes access log entries as JSON lines to the standard
+// output or to a file rotated once it reaches its maximum size.
+// A nil *Logger is valid and discards all entries.
+type Logger struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mutex  sync.Mutex
+	writer io.Writer
+	file   *os.File
+	size   int64
+}
+
+// New creates an access logger writing to the path given, which
+// can be Stdout. It returns a nil logger if the path is empty.
+// The maximum size is in bytes, and the maximum number of backups
+// is the number of rotated files kept, named path.1, path.2, etc.
+func New(path string, maxSize int64, maxBackups int) (
+	logger *Logger, err error) {
+	switch path {
+	case "":
+		return nil, nil //nolint:nilnil
+	case Stdout:
+		return &Logger{writer: os.Stdout}, nil
+	}
+
+	logger = &Logger{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+	err = logger.open()
+	if err != nil {
+		return nil, err
+	}
+	return logger, nil
+}
+
+// Log writes the entry given as a JSON line.
+func (l *Logger) Log(entry Entry) (err error) {
+	if l == nil {
+		return nil
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encoding access log entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.file != nil && l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		err = l.rotate()
+		if err != nil {
+			return fmt.Errorf("rotating access log file: %w", err)
+		}
+	}
+
+	n, err := l.writer.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("writing access log entry: %w", err)
+	}
+	return nil
+}
+
+// Close closes the access log file, if any.
+func (l *Logger) Close() (err error) {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.file.Close()
+}
+
+func (l *Logger) open() (err error) {
+	const perm = 0600
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, perm)
+	if err != nil {
+		return fmt.Errorf("opening access log file: %w", err)
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("reading access log file information: %w", err)
+	}
+	l.file = file
+	l.writer = file
+	l.size = stat.Size()
+	return nil
+}
+
+// rotate closes the current file, shifts the backup files
+// and opens a new empty file. It must be called with the
+// mutex locked.
+func (l *Logger) rotate() (err error) {
+	err = l.file.Close()
+	if err != nil {
+		return fmt.Errorf("closing file: %w", err)
+	}
+
+	if l.maxBackups == 0 {
+		err = os.Remove(l.path)
+		if err != nil {
+			return fmt.Errorf("removing file: %w", err)
+		}
+		return l.open()
+	}
+
+	err = os.Remove(backupPath(l.path, l.maxBackups))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing oldest backup file: %w", err)
+	}
+	for i := l.maxBackups - 1; i > 0; i-- {
+		err = os.Rename(backupPath(l.path, i), backupPath(l.path, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("renaming backup file: %w", err)
+		}
+	}
+	err = os.Rename(l.path, backupPath(l.path, 1))
+	if err != nil {
+		return fmt.Errorf("renaming file: %w", err)
+	}
+	return l.open()
+}
+
+func backupPath(path string, index int) string {
+	return fmt.Sprintf("%s.%d", path, index)
+}
@@ -0,0 +1,93 @@
+package accesslog
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// ClientStats is the traffic proxied for a client.
+type ClientStats struct {
+	// Client is the IP address of the client.
+	Client string `json:"client"`
+	// Users are the user names the client authenticated with.
+	Users []string `json:"users,omitempty"`
+	// Requests is the number of requests or connections proxied.
+	Requests uint64 `json:"requests"`
+	// BytesUp is the number of bytes sent by the client.
+	BytesUp uint64 `json:"bytes_up"`
+	// BytesDown is the number of bytes received by the client.
+	BytesDown uint64 `json:"bytes_down"`
+	// LastSeen is the time of the last request or
+	// connection proxied for the client.
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// Recorder aggregates the traffic per client for a proxy,
+// and writes access log entries to its logger.
+type Recorder struct {
+	logger  *Logger
+	mutex   sync.Mutex
+	clients map[string]*ClientStats
+}
+
+// NewRecorder creates a recorder writing to the logger given,
+// which can be nil to only aggregate traffic per client.
+func NewRecorder(logger *Logger) *Recorder {
+	return &Recorder{
+		logger:  logger,
+		clients: make(map[string]*ClientStats),
+	}
+}
+
+// Record adds the entry to the client aggregates and writes it
+// to the access log. The entry is aggregated even if writing
+// it to the access log fails.
+func (r *Recorder) Record(entry Entry) (err error) {
+	r.mutex.Lock()
+	stats, ok := r.clients[entry.Client]
+	if !ok {
+		stats = &ClientStats{Client: entry.Client}
+		r.clients[entry.Client] = stats
+	}
+	if entry.User != "" && !contains(stats.Users, entry.User) {
+		stats.Users = append(stats.Users, entry.User)
+		sort.Strings(stats.Users)
+	}
+	stats.Requests++
+	stats.BytesUp += entry.BytesUp
+	stats.BytesDown += entry.BytesDown
+	if entry.Time.After(stats.LastSeen) {
+		stats.LastSeen = entry.Time
+	}
+	r.mutex.Unlock()
+
+	return r.logger.Log(entry)
+}
+
+// Clients returns the traffic aggregates of all
+// clients, sorted by client IP address.
+func (r *Recorder) Clients() (clients []ClientStats) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	clients = make([]ClientStats, 0, len(r.clients))
+	for _, stats := range r.clients {
+		client := *stats
+		client.Users = append([]string(nil), stats.Users...)
+		clients = append(clients, client)
+	}
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].Client < clients[j].Client
+	})
+	return clients
+}
+
+func contains(values []string, value string) bool {
+	for _, existing := range values {
+		if existing == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,18 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/qdm12/gluetun/internal/configuration/sources/configfile"
+)
+
+// ConfigSchema prints the JSON Schema of the structured
+// configuration file, for editors to validate it.
+func (c *CLI) ConfigSchema() error {
+	schema, err := configfile.Schema()
+	if err != nil {
+		return fmt.Errorf("generating JSON schema: %w", err)
+	}
+	fmt.Println(string(schema))
+	return nil
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/constants"
+	"github.com/qdm12/gluetun/internal/constants/providers"
+	"github.com/qdm12/gluetun/internal/storage"
+)
+
+var (
+	ErrProviderNotValid     = errors.New("provider is not valid")
+	ErrTimestampUnspecified = errors.New("snapshot timestamp was not specified")
+)
+
+// ServersHistory prints the previous servers snapshots
+// kept for a provider.
+func (c *CLI) ServersHistory(args []string) error {
+	flagSet := flag.NewFlagSet("servers-history", flag.ExitOnError)
+	provider := flagSet.String("provider", "", "VPN provider to list the servers history for")
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+
+	err := validateProvider(*provider)
+	if err != nil {
+		return err
+	}
+
+	logger := newNoopLogger()
+	storage, err := storage.New(logger, constants.ServersData)
+	if err != nil {
+		return fmt.Errorf("creating servers storage: %w", err)
+	}
+
+	snapshots, err := storage.History(*provider)
+	if err != nil {
+		return fmt.Errorf("getting servers history: %w", err)
+	}
+
+	if len(snapshots) == 0 {
+		fmt.Println("No servers history for " + *provider)
+		return nil
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) //nolint:gomnd
+	_, _ = fmt.Fprintln(writer, "TIMESTAMP\tUPDATED\tREPLACED\tVERSION\tSERVERS")
+	for _, snapshot := range snapshots {
+		_, _ = fmt.Fprintf(writer, "%d\t%s\t%s\t%d\t%d\n",
+			snapshot.Timestamp,
+			time.Unix(snapshot.Timestamp, 0).UTC().Format(time.RFC3339),
+			snapshot.ReplacedAt.UTC().Format(time.RFC3339),
+			snapshot.Version, snapshot.Count)
+	}
+	return writer.Flush()
+}
+
+// ServersRollback sets the servers of a provider back to
+// one of its previous servers snapshots.
+func (c *CLI) ServersRollback(args []string) error {
+	flagSet := flag.NewFlagSet("servers-rollback", flag.ExitOnError)
+	provider := flagSet.String("provider", "", "VPN provider to roll back the servers for")
+	timestamp := flagSet.Int64("timestamp", 0,
+		"Timestamp of the servers snapshot to roll back to, as listed by servers-history")
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+
+	err := validateProvider(*provider)
+	if err != nil {
+		return err
+	}
+
+	if *timestamp == 0 {
+		return fmt.Errorf("%w", ErrTimestampUnspecified)
+	}
+
+	logger := newNoopLogger()
+	storage, err := storage.New(logger, constants.ServersData)
+	if err != nil {
+		return fmt.Errorf("creating servers storage: %w", err)
+	}
+
+	err = storage.Rollback(*provider, *timestamp)
+	if err != nil {
+		return fmt.Errorf("rolling back servers: %w", err)
+	}
+
+	fmt.Printf("Rolled back %s servers to snapshot %d, "+
+		"restart any running instance to use them.\n", *provider, *timestamp)
+	return nil
+}
+
+func validateProvider(provider string) (err error) {
+	if provider == "" {
+		return fmt.Errorf("%w", ErrNoProviderSpecified)
+	}
+	for _, validProvider := range providers.All() {
+		if provider == validProvider {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrProviderNotValid, provider)
+}
@@ -2,10 +2,12 @@ package cli
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"net/http"
+	"os"
 	"strings"
 	"time"
 
@@ -22,8 +24,9 @@ import (
 )
 
 var (
-	ErrModeUnspecified     = errors.New("at least one of -enduser or -maintainer must be specified")
+	ErrModeUnspecified     = errors.New("at least one of -enduser, -maintainer or -dryrun must be specified")
 	ErrNoProviderSpecified = errors.New("no provider was specified")
+	ErrFormatNotValid      = errors.New("output format is not valid")
 )
 
 type UpdaterLogger interface {
@@ -34,26 +37,62 @@ type UpdaterLogger interface {
 
 func (c *CLI) Update(ctx context.Context, args []string, logger UpdaterLogger) error {
 	options := settings.Updater{}
-	var endUserMode, maintainerMode, updateAll bool
-	var csvProviders string
+	var endUserMode, maintainerMode, dryRun, updateAll bool
+	var csvProviders, format string
+	var minRatio float64
 	flagSet := flag.NewFlagSet("update", flag.ExitOnError)
 	flagSet.BoolVar(&endUserMode, "enduser", false, "Write results to /gluetun/servers.json (for end users)")
 	flagSet.BoolVar(&maintainerMode, "maintainer", false,
 		"Write results to ./internal/storage/servers.json to modify the program (for maintainers)")
+	flagSet.BoolVar(&dryRun, "dryrun", false,
+		"Only print the differences with the current servers, without writing them")
 	flagSet.StringVar(&options.DNSAddress, "dns", "8.8.8.8", "DNS resolver address to use")
-	const defaultMinRatio = 0.8
-	flagSet.Float64Var(&options.MinRatio, "minratio", defaultMinRatio,
-		"Minimum ratio of servers to find for the update to succeed")
+	flagSet.StringVar(&options.DNSProtocol, "dnsprotocol", resolver.ProtocolPlain,
+		"DNS protocol to use, either 'plain', 'dot' for DNS over TLS or 'doh' for DNS over HTTPS")
+	var csvDNSProviders string
+	flagSet.StringVar(&csvDNSProviders, "dnsproviders", "cloudflare",
+		"CSV string of DNS providers to use with DNS over TLS or DNS over HTTPS")
+	const defaultThreshold = 0.2
+	flagSet.Float64Var(&options.Threshold, "threshold", defaultThreshold,
+		"Maximum ratio of existing servers which can be removed or changed for each provider")
+	flagSet.StringVar(&options.ThresholdAction, "thresholdaction", settings.UpdaterThresholdReject,
+		"Action to take when the threshold is exceeded, either 'reject' or 'flag'")
+	var probe bool
+	var probeTimeout time.Duration
+	flagSet.BoolVar(&probe, "probe", false,
+		"Probe servers fetched to record their reachability and round trip time")
+	const defaultProbeTimeout = 3 * time.Second
+	flagSet.DurationVar(&probeTimeout, "probetimeout", defaultProbeTimeout,
+		"Timeout to probe each server")
+	const defaultProbeParallelism = 32
+	flagSet.IntVar(&options.ProbeParallelism, "probeparallelism", defaultProbeParallelism,
+		"Maximum number of servers to probe at the same time")
+	flagSet.StringVar(&options.MirrorURL, "mirror", "",
+		"URL of a signed servers JSON file to fetch servers from instead of each VPN provider")
+	flagSet.StringVar(&options.MirrorPublicKey, "mirrorkey", "",
+		"Minisign public key to verify the mirror servers signature, defaults to the built-in key")
+	flagSet.Float64Var(&minRatio, "minratio", 0,
+		"Deprecated: use -threshold instead, set to 1 minus the minimum ratio")
+	flagSet.StringVar(&format, "format", "text", "Output format of the servers differences, either 'text' or 'json'")
 	flagSet.BoolVar(&updateAll, "all", false, "Update servers for all VPN providers")
 	flagSet.StringVar(&csvProviders, "providers", "", "CSV string of VPN providers to update server data for")
 	if err := flagSet.Parse(args); err != nil {
 		return err
 	}
 
-	if !endUserMode && !maintainerMode {
+	if !endUserMode && !maintainerMode && !dryRun {
 		return fmt.Errorf("%w", ErrModeUnspecified)
 	}
 
+	if format != "text" && format != "json" {
+		return fmt.Errorf("%w: %s", ErrFormatNotValid, format)
+	}
+
+	if minRatio > 0 {
+		logger.Warn("-minratio is deprecated, please use -threshold instead")
+		options.Threshold = 1 - minRatio
+	}
+
 	if updateAll {
 		options.Providers = providers.All()
 	} else {
@@ -63,6 +102,10 @@ func (c *CLI) Update(ctx context.Context, args []string, logger UpdaterLogger) e
 		options.Providers = strings.Split(csvProviders, ",")
 	}
 
+	options.DNSProviders = strings.Split(csvDNSProviders, ",")
+	options.Probe = &probe
+	options.ProbeTimeout = &probeTimeout
+
 	options.SetDefaults(options.Providers[0])
 
 	err := options.Validate()
@@ -78,20 +121,38 @@ func (c *CLI) Update(ctx context.Context, args []string, logger UpdaterLogger) e
 	const clientTimeout = 10 * time.Second
 	httpClient := &http.Client{Timeout: clientTimeout}
 	unzipper := unzip.New(httpClient)
-	parallelResolver := resolver.NewParallelResolver(options.DNSAddress)
-	ipFetcher := ipinfo.New(httpClient)
+	resolverSettings, err := resolver.ParseSettings(options.DNSProtocol,
+		options.DNSAddress, options.DNSProviders)
+	if err != nil {
+		return fmt.Errorf("converting resolver settings: %w", err)
+	}
+	parallelResolver := resolver.NewParallelResolver(resolverSettings)
+	ipFetcher := ipinfo.New(httpClient, "")
 	openvpnFileExtractor := extract.New()
 
 	providers := provider.NewProviders(storage, time.Now, logger, httpClient,
 		unzipper, parallelResolver, ipFetcher, openvpnFileExtractor)
 
-	updater := updater.New(httpClient, storage, providers, logger)
-	err = updater.UpdateServers(ctx, options.Providers, options.MinRatio)
+	serversUpdater := updater.New(httpClient, storage, providers, logger)
+	updateOptions := updater.Options{
+		Threshold:           options.Threshold,
+		RejectOverThreshold: options.ThresholdAction == settings.UpdaterThresholdReject,
+		DryRun:              dryRun,
+		Probe:               *options.Probe,
+		ProbeTimeout:        *options.ProbeTimeout,
+		ProbeParallelism:    options.ProbeParallelism,
+		MirrorURL:           options.MirrorURL,
+		MirrorPublicKey:     options.MirrorPublicKey,
+	}
+	diffs, updateErr := serversUpdater.UpdateServers(ctx, options.Providers, updateOptions)
+	err = printDiffs(diffs, format)
 	if err != nil {
-		return fmt.Errorf("updating server information: %w", err)
+		return fmt.Errorf("printing servers differences: %w", err)
+	} else if updateErr != nil {
+		return fmt.Errorf("updating server information: %w", updateErr)
 	}
 
-	if maintainerMode {
+	if maintainerMode && !dryRun {
 		err := storage.FlushToFile(c.repoServersPath)
 		if err != nil {
 			return fmt.Errorf("writing servers data to embedded JSON file: %w", err)
@@ -100,3 +161,16 @@ func (c *CLI) Update(ctx context.Context, args []string, logger UpdaterLogger) e
 
 	return nil
 }
+
+func printDiffs(diffs []updater.ProviderDiff, format string) (err error) {
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(diffs)
+	}
+
+	for _, diff := range diffs {
+		fmt.Println(diff.String())
+	}
+	return nil
+}
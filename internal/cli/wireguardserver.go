@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/qdm12/gluetun/internal/wireguard"
+	qrcode "github.com/skip2/go-qrcode"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+var (
+	ErrPeerNameNotSet          = errors.New("peer name is not set")
+	ErrPeerPrivateKeyNotSet    = errors.New("peer private key is not set")
+	ErrServerEndpointNotSet    = errors.New("server endpoint is not set")
+	ErrServerPrivateKeyNotSet  = errors.New("server private key is not set")
+	ErrServerSubnetAddressFull = errors.New("no address available in the server subnet")
+)
+
+// WireguardServerClient prints the Wireguard client configuration,
+// and its QR code, for a peer of the Wireguard server. If the peer
+// does not exist in the settings, a new key pair and address are
+// generated for it, and the peer entry to add to the settings is printed.
+func (c *CLI) WireguardServerClient(args []string, source Source) error {
+	flagSet := flag.NewFlagSet("wireguard-server-client", flag.ExitOnError)
+	name := flagSet.String("name", "", "name of the peer")
+	privateKey := flagSet.String("privatekey", "",
+		"private key of an existing peer, to be included in its configuration")
+	endpoint := flagSet.String("endpoint", "",
+		"host the peer uses to reach the server, defaulting to WIREGUARD_SERVER_ENDPOINT")
+	printQR := flagSet.Bool("qr", true, "print the configuration as a QR code")
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+
+	if *name == "" {
+		return fmt.Errorf("%w", ErrPeerNameNotSet)
+	}
+
+	allSettings, err := source.Read()
+	if err != nil {
+		return err
+	}
+	allSettings.SetDefaults()
+	server := allSettings.WireguardServer
+
+	if *server.PrivateKey == "" {
+		return fmt.Errorf("%w", ErrServerPrivateKeyNotSet)
+	}
+	serverPrivateKey, err := wgtypes.ParseKey(*server.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("parsing server private key: %w", err)
+	}
+
+	if *endpoint == "" {
+		*endpoint = *server.Endpoint
+		if *endpoint == "" {
+			return fmt.Errorf("%w", ErrServerEndpointNotSet)
+		}
+	}
+
+	clientConfig := wireguard.ClientConfig{
+		PrivateKey:      *privateKey,
+		ServerPublicKey: serverPrivateKey.PublicKey().String(),
+		Endpoint: net.JoinHostPort(*endpoint,
+			strconv.Itoa(int(*server.ListeningPort))),
+	}
+	if *allSettings.DNS.DoT.Enabled {
+		clientConfig.DNS = server.Address.Addr()
+	}
+
+	peer, found := findWireguardServerPeer(server.Peers, *name)
+	if found {
+		if clientConfig.PrivateKey == "" {
+			return fmt.Errorf("%w: for existing peer %s", ErrPeerPrivateKeyNotSet, *name)
+		}
+		clientConfig.Address = peer.Address
+		clientConfig.PreSharedKey = peer.PreSharedKey
+	} else {
+		clientConfig.Address, err = nextFreeAddress(server.Address, server.Peers)
+		if err != nil {
+			return err
+		}
+
+		peerPrivateKey, err := wgtypes.GeneratePrivateKey()
+		if err != nil {
+			return fmt.Errorf("generating peer private key: %w", err)
+		}
+		clientConfig.PrivateKey = peerPrivateKey.String()
+
+		address := clientConfig.Address.String()
+		if clientConfig.Address.Is6() {
+			address = "[" + address + "]"
+		}
+		fmt.Fprintf(os.Stderr, "Add the peer %s:%s:%s to WIREGUARD_SERVER_PEERS\n\n",
+			*name, peerPrivateKey.PublicKey(), address)
+	}
+
+	configString := clientConfig.String()
+	fmt.Println(configString)
+
+	if *printQR {
+		qrCode, err := qrcode.New(configString, qrcode.Medium)
+		if err != nil {
+			return fmt.Errorf("creating QR code: %w", err)
+		}
+		const inverseColor = false
+		fmt.Print(qrCode.ToSmallString(inverseColor))
+	}
+
+	return nil
+}
+
+func findWireguardServerPeer(peers []settings.WireguardServerPeer,
+	name string) (peer settings.WireguardServerPeer, found bool) {
+	for _, peer := range peers {
+		if peer.Name == name {
+			return peer, true
+		}
+	}
+	return peer, false
+}
+
+func nextFreeAddress(serverAddress netip.Prefix,
+	peers []settings.WireguardServerPeer) (address netip.Addr, err error) {
+	used := make(map[netip.Addr]struct{}, len(peers)+1)
+	used[serverAddress.Addr()] = struct{}{}
+	for _, peer := range peers {
+		used[peer.Address] = struct{}{}
+	}
+
+	subnet := serverAddress.Masked()
+	// skip the network address
+	for address = subnet.Addr().Next(); subnet.Contains(address); address = address.Next() {
+		if _, exists := used[address]; exists {
+			continue
+		}
+		if address.Is4() && !subnet.Contains(address.Next()) {
+			break // IPv4 broadcast address
+		}
+		return address, nil
+	}
+	return netip.Addr{}, fmt.Errorf("%w: %s", ErrServerSubnetAddressFull, subnet)
+}
@@ -28,7 +28,8 @@ type DNS struct {
 	DoT DoT
 }
 
-func (d DNS) validate() (err error) {
+// Validate validates the DNS settings.
+func (d DNS) Validate() (err error) {
 	err = d.DoT.validate()
 	if err != nil {
 		return fmt.Errorf("validating DoT settings: %w", err)
@@ -53,10 +54,10 @@ func (d *DNS) mergeWith(other DNS) {
 	d.DoT.mergeWith(other.DoT)
 }
 
-// overrideWith overrides fields of the receiver
+// OverrideWith overrides fields of the receiver
 // settings object with any field set in the other
 // settings.
-func (d *DNS) overrideWith(other DNS) {
+func (d *DNS) OverrideWith(other DNS) {
 	d.ServerAddress = gosettings.OverrideWithValidator(d.ServerAddress, other.ServerAddress)
 	d.KeepNameserver = gosettings.OverrideWithPointer(d.KeepNameserver, other.KeepNameserver)
 	d.DoT.overrideWith(other.DoT)
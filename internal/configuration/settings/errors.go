@@ -3,14 +3,20 @@ package settings
 import "errors"
 
 var (
+	ErrAccessLogMaxBackupsNotValid     = errors.New("access log maximum backups is not valid")
+	ErrAccessLogMaxSizeNotValid        = errors.New("access log maximum size is not valid")
 	ErrCityNotValid                    = errors.New("the city specified is not valid")
 	ErrControlServerPrivilegedPort     = errors.New("cannot use privileged port without running as root")
 	ErrCountryNotValid                 = errors.New("the country specified is not valid")
 	ErrFilepathMissing                 = errors.New("filepath is missing")
+	ErrFirewallGatewayWithoutFirewall  = errors.New("gateway mode requires the firewall to be enabled")
 	ErrFirewallZeroPort                = errors.New("cannot have a zero port to block")
 	ErrHostnameNotValid                = errors.New("the hostname specified is not valid")
+	ErrHTTPProxyCredentialsNotValid    = errors.New("HTTP proxy credentials file is not valid")
+	ErrHTTPProxyDefaultPolicyNotValid  = errors.New("HTTP proxy default policy is not valid")
+	ErrHTTPProxyDestinationNotValid    = errors.New("HTTP proxy destination rule is not valid")
+	ErrHTTPProxyPACBypassNotValid      = errors.New("HTTP proxy auto-config bypass domain is not valid")
 	ErrISPNotValid                     = errors.New("the ISP specified is not valid")
-	ErrMinRatioNotValid                = errors.New("minimum ratio is not valid")
 	ErrMissingValue                    = errors.New("missing value")
 	ErrNameNotValid                    = errors.New("the server name specified is not valid")
 	ErrOpenVPNClientKeyMissing         = errors.New("client key is missing")
@@ -21,17 +27,40 @@ var (
 	ErrOpenVPNMSSFixIsTooHigh          = errors.New("mssfix option value is too high")
 	ErrOpenVPNPasswordIsEmpty          = errors.New("password is empty")
 	ErrOpenVPNTCPNotSupported          = errors.New("TCP protocol is not supported")
+	ErrOpenVPNTOTPSecretNotValid       = errors.New("TOTP secret is not valid")
+	ErrOpenVPNTOTPWithoutChallenge     = errors.New("TOTP secret is set but challenges are disabled")
 	ErrOpenVPNUserIsEmpty              = errors.New("user is empty")
 	ErrOpenVPNVerbosityIsOutOfBounds   = errors.New("verbosity value is out of bounds")
 	ErrOpenVPNVersionIsNotValid        = errors.New("version is not valid")
 	ErrPortForwardingEnabled           = errors.New("port forwarding cannot be enabled")
+	ErrPublicIPAPIsEmpty               = errors.New("public IP APIs list is empty")
+	ErrPublicIPAPINotValid             = errors.New("public IP API is not valid")
+	ErrPublicIPCustomIPFieldMissing    = errors.New("public IP custom API ip field is missing")
+	ErrPublicIPCustomURLEmpty          = errors.New("public IP custom API URL is empty")
+	ErrPublicIPExitActionNotValid      = errors.New("public IP exit mismatch action is not valid")
 	ErrPublicIPPeriodTooShort          = errors.New("public IP address check period is too short")
 	ErrRegionNotValid                  = errors.New("the region specified is not valid")
 	ErrServerAddressNotValid           = errors.New("server listening address is not valid")
+	ErrShadowsocksUserDuplicate        = errors.New("shadowsocks user name is used more than once")
+	ErrShadowsocksUserNotValid         = errors.New("shadowsocks user is not valid")
 	ErrSystemPGIDNotValid              = errors.New("process group id is not valid")
 	ErrSystemPUIDNotValid              = errors.New("process user id is not valid")
 	ErrSystemTimezoneNotValid          = errors.New("timezone is not valid")
+	ErrTunnelChallengeNotSupported     = errors.New("authentication challenges are not supported for tunnels")
+	ErrTunnelFirewallMarkDuplicate     = errors.New("tunnel firewall mark is used more than once")
+	ErrTunnelInterfaceDuplicate        = errors.New("tunnel interface name is used more than once")
+	ErrTunnelNameDuplicate             = errors.New("tunnel name is used more than once")
+	ErrTunnelNameNotValid              = errors.New("tunnel name is not valid")
+	ErrTunnelPortForwardingEnabled     = errors.New("port forwarding cannot be enabled for tunnels")
+	ErrUpdaterDNSProtocolNotValid      = errors.New("updater DNS protocol is not valid")
+	ErrUpdaterDNSProviderNotValid      = errors.New("updater DNS provider is not valid")
+	ErrUpdaterMirrorPublicKeyNotValid  = errors.New("updater mirror public key is not valid")
+	ErrUpdaterMirrorURLNotValid        = errors.New("updater mirror URL is not valid")
 	ErrUpdaterPeriodTooSmall           = errors.New("VPN server data updater period is too small")
+	ErrUpdaterProbeParallelismNotValid = errors.New("updater probe parallelism is not valid")
+	ErrUpdaterProbeTimeoutNotValid     = errors.New("updater probe timeout is not valid")
+	ErrUpdaterThresholdActionNotValid  = errors.New("updater threshold action is not valid")
+	ErrUpdaterThresholdNotValid        = errors.New("updater threshold is not valid")
 	ErrVPNProviderNameNotValid         = errors.New("VPN provider name is not valid")
 	ErrVPNTypeNotValid                 = errors.New("VPN type is not valid")
 	ErrWireguardEndpointIPNotSet       = errors.New("endpoint IP is not set")
@@ -45,5 +74,9 @@ var (
 	ErrWireguardPrivateKeyNotSet       = errors.New("private key is not set")
 	ErrWireguardPublicKeyNotSet        = errors.New("public key is not set")
 	ErrWireguardPublicKeyNotValid      = errors.New("public key is not valid")
+	ErrWireguardServerAddressNotValid  = errors.New("server address is not valid")
+	ErrWireguardServerPeerDuplicate    = errors.New("peer name or address is used more than once")
+	ErrWireguardServerPeerNotValid     = errors.New("peer is not valid")
+	ErrWireguardServerPortNotSet       = errors.New("listening port is not set")
 	ErrWireguardImplementationNotValid = errors.New("implementation is not valid")
 )
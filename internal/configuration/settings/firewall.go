@@ -15,6 +15,19 @@ type Firewall struct {
 	OutboundSubnets []netip.Prefix
 	Enabled         *bool
 	Debug           *bool
+	Gateway         FirewallGateway
+}
+
+// FirewallGateway contains settings for the gateway mode,
+// where other devices use Gluetun as their default gateway.
+type FirewallGateway struct {
+	// Enabled is true if forwarded traffic from client subnets
+	// should be routed and NATed through the VPN interface.
+	// It cannot be nil in the internal state.
+	Enabled *bool
+	// ClientSubnets are the subnets of clients allowed to use
+	// the gateway. It defaults to the IPv4 local networks if left empty.
+	ClientSubnets []netip.Prefix
 }
 
 func (f Firewall) validate() (err error) {
@@ -26,6 +39,10 @@ func (f Firewall) validate() (err error) {
 		return fmt.Errorf("input ports: %w", ErrFirewallZeroPort)
 	}
 
+	if *f.Gateway.Enabled && !*f.Enabled {
+		return fmt.Errorf("%w", ErrFirewallGatewayWithoutFirewall)
+	}
+
 	return nil
 }
 
@@ -45,6 +62,10 @@ func (f *Firewall) copy() (copied Firewall) {
 		OutboundSubnets: gosettings.CopySlice(f.OutboundSubnets),
 		Enabled:         gosettings.CopyPointer(f.Enabled),
 		Debug:           gosettings.CopyPointer(f.Debug),
+		Gateway: FirewallGateway{
+			Enabled:       gosettings.CopyPointer(f.Gateway.Enabled),
+			ClientSubnets: gosettings.CopySlice(f.Gateway.ClientSubnets),
+		},
 	}
 }
 
@@ -58,6 +79,8 @@ func (f *Firewall) mergeWith(other Firewall) {
 	f.OutboundSubnets = gosettings.MergeWithSlice(f.OutboundSubnets, other.OutboundSubnets)
 	f.Enabled = gosettings.MergeWithPointer(f.Enabled, other.Enabled)
 	f.Debug = gosettings.MergeWithPointer(f.Debug, other.Debug)
+	f.Gateway.Enabled = gosettings.MergeWithPointer(f.Gateway.Enabled, other.Gateway.Enabled)
+	f.Gateway.ClientSubnets = gosettings.MergeWithSlice(f.Gateway.ClientSubnets, other.Gateway.ClientSubnets)
 }
 
 // overrideWith overrides fields of the receiver
@@ -69,11 +92,14 @@ func (f *Firewall) overrideWith(other Firewall) {
 	f.OutboundSubnets = gosettings.OverrideWithSlice(f.OutboundSubnets, other.OutboundSubnets)
 	f.Enabled = gosettings.OverrideWithPointer(f.Enabled, other.Enabled)
 	f.Debug = gosettings.OverrideWithPointer(f.Debug, other.Debug)
+	f.Gateway.Enabled = gosettings.OverrideWithPointer(f.Gateway.Enabled, other.Gateway.Enabled)
+	f.Gateway.ClientSubnets = gosettings.OverrideWithSlice(f.Gateway.ClientSubnets, other.Gateway.ClientSubnets)
 }
 
 func (f *Firewall) setDefaults() {
 	f.Enabled = gosettings.DefaultPointer(f.Enabled, true)
 	f.Debug = gosettings.DefaultPointer(f.Debug, false)
+	f.Gateway.Enabled = gosettings.DefaultPointer(f.Gateway.Enabled, false)
 }
 
 func (f Firewall) String() string {
@@ -114,5 +140,17 @@ func (f Firewall) toLinesNode() (node *gotree.Node) {
 		}
 	}
 
+	if *f.Gateway.Enabled {
+		gatewayNode := node.Appendf("Gateway mode:")
+		if len(f.Gateway.ClientSubnets) == 0 {
+			gatewayNode.Appendf("Client subnets: IPv4 local networks")
+		} else {
+			clientSubnetsNode := gatewayNode.Appendf("Client subnets:")
+			for _, subnet := range f.Gateway.ClientSubnets {
+				clientSubnetsNode.Appendf("%s", subnet)
+			}
+		}
+	}
+
 	return node
 }
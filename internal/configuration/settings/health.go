@@ -26,6 +26,8 @@ type Health struct {
 	// to TCP dial to periodically for the health check.
 	// It cannot be the empty string in the internal state.
 	TargetAddress string
+	// Probes has settings for the probes run for the health check.
+	Probes HealthProbes
 	// SuccessWait is the duration to wait to re-run the
 	// healthcheck after a successful healthcheck.
 	// It defaults to 5 seconds and cannot be zero in
@@ -43,6 +45,11 @@ func (h Health) Validate() (err error) {
 		return fmt.Errorf("server listening address is not valid: %w", err)
 	}
 
+	err = h.Probes.validate()
+	if err != nil {
+		return fmt.Errorf("health probes settings: %w", err)
+	}
+
 	err = h.VPN.validate()
 	if err != nil {
 		return fmt.Errorf("health VPN settings: %w", err)
@@ -57,6 +64,7 @@ func (h *Health) copy() (copied Health) {
 		ReadHeaderTimeout: h.ReadHeaderTimeout,
 		ReadTimeout:       h.ReadTimeout,
 		TargetAddress:     h.TargetAddress,
+		Probes:            h.Probes.copy(),
 		SuccessWait:       h.SuccessWait,
 		VPN:               h.VPN.copy(),
 	}
@@ -69,6 +77,7 @@ func (h *Health) MergeWith(other Health) {
 	h.ReadHeaderTimeout = gosettings.MergeWithNumber(h.ReadHeaderTimeout, other.ReadHeaderTimeout)
 	h.ReadTimeout = gosettings.MergeWithNumber(h.ReadTimeout, other.ReadTimeout)
 	h.TargetAddress = gosettings.MergeWithString(h.TargetAddress, other.TargetAddress)
+	h.Probes.mergeWith(other.Probes)
 	h.SuccessWait = gosettings.MergeWithNumber(h.SuccessWait, other.SuccessWait)
 	h.VPN.mergeWith(other.VPN)
 }
@@ -81,6 +90,7 @@ func (h *Health) OverrideWith(other Health) {
 	h.ReadHeaderTimeout = gosettings.OverrideWithNumber(h.ReadHeaderTimeout, other.ReadHeaderTimeout)
 	h.ReadTimeout = gosettings.OverrideWithNumber(h.ReadTimeout, other.ReadTimeout)
 	h.TargetAddress = gosettings.OverrideWithString(h.TargetAddress, other.TargetAddress)
+	h.Probes.overrideWith(other.Probes)
 	h.SuccessWait = gosettings.OverrideWithNumber(h.SuccessWait, other.SuccessWait)
 	h.VPN.overrideWith(other.VPN)
 }
@@ -92,6 +102,7 @@ func (h *Health) SetDefaults() {
 	const defaultReadTimeout = 500 * time.Millisecond
 	h.ReadTimeout = gosettings.DefaultNumber(h.ReadTimeout, defaultReadTimeout)
 	h.TargetAddress = gosettings.DefaultString(h.TargetAddress, "cloudflare.com:443")
+	h.Probes.setDefaults()
 	const defaultSuccessWait = 5 * time.Second
 	h.SuccessWait = gosettings.DefaultNumber(h.SuccessWait, defaultSuccessWait)
 	h.VPN.setDefaults()
@@ -105,6 +116,7 @@ func (h Health) toLinesNode() (node *gotree.Node) {
 	node = gotree.New("Health settings:")
 	node.Appendf("Server listening address: %s", h.ServerAddress)
 	node.Appendf("Target address: %s", h.TargetAddress)
+	node.AppendNode(h.Probes.toLinesNode())
 	node.Appendf("Duration to wait after success: %s", h.SuccessWait)
 	node.Appendf("Read header timeout: %s", h.ReadHeaderTimeout)
 	node.Appendf("Read timeout: %s", h.ReadTimeout)
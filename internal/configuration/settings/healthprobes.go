@@ -0,0 +1,247 @@
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/qdm12/gosettings"
+	"github.com/qdm12/gosettings/validate"
+	"github.com/qdm12/gotree"
+	"github.com/qdm12/govalid/address"
+)
+
+const (
+	HealthProbeTCP  = "tcp"
+	HealthProbeHTTP = "http"
+	HealthProbeDNS  = "dns"
+	HealthProbeICMP = "icmp"
+)
+
+// HealthProbes contains settings for the probes run
+// periodically for the health check.
+type HealthProbes struct {
+	// Enabled is the list of probes to run, each being
+	// one of "tcp", "http", "dns" or "icmp".
+	// It defaults to the single "tcp" probe and cannot
+	// be empty in the internal state.
+	Enabled []string
+	// Quorum is the minimum number of probes which must
+	// succeed for the health check to pass.
+	// It defaults to the number of enabled probes and
+	// cannot be zero in the internal state.
+	Quorum uint
+	// TCP has settings for the TCP dial probe,
+	// which dials the health target address.
+	TCP HealthProbeTCPSettings
+	// HTTP has settings for the HTTP(S) GET probe.
+	HTTP HealthProbeHTTPSettings
+	// DNS has settings for the DNS resolution probe.
+	DNS HealthProbeDNSSettings
+	// ICMP has settings for the ICMP echo probe.
+	ICMP HealthProbeICMPSettings
+}
+
+// HealthProbeTCPSettings contains settings for the TCP dial probe.
+type HealthProbeTCPSettings struct {
+	// Timeout is the timeout for the probe to succeed.
+	// It defaults to 3 seconds and cannot be zero in
+	// the internal state.
+	Timeout time.Duration
+}
+
+// HealthProbeHTTPSettings contains settings for the HTTP(S) GET probe.
+type HealthProbeHTTPSettings struct {
+	// URL is the URL to send a GET request to.
+	// It defaults to https://cloudflare.com/cdn-cgi/trace
+	// and cannot be empty in the internal state.
+	URL string
+	// Status is the HTTP response status code expected.
+	// It defaults to 200 and cannot be zero in the internal state.
+	Status int
+	// Timeout is the timeout for the probe to succeed.
+	// It defaults to 3 seconds and cannot be zero in
+	// the internal state.
+	Timeout time.Duration
+}
+
+// HealthProbeDNSSettings contains settings for the DNS resolution probe.
+type HealthProbeDNSSettings struct {
+	// Server is the plaintext DNS server address to query,
+	// which defaults to the local DNS server 127.0.0.1:53.
+	// It cannot be empty in the internal state.
+	Server string
+	// Hostname is the hostname to resolve.
+	// It defaults to cloudflare.com and cannot be empty
+	// in the internal state.
+	Hostname string
+	// Timeout is the timeout for the probe to succeed.
+	// It defaults to 3 seconds and cannot be zero in
+	// the internal state.
+	Timeout time.Duration
+}
+
+// HealthProbeICMPSettings contains settings for the ICMP echo probe.
+type HealthProbeICMPSettings struct {
+	// Target is the IP address to send an ICMP echo request to.
+	// It defaults to 1.1.1.1 and cannot be the zero value in
+	// the internal state.
+	Target netip.Addr
+	// Timeout is the timeout for the probe to succeed.
+	// It defaults to 3 seconds and cannot be zero in
+	// the internal state.
+	Timeout time.Duration
+}
+
+var (
+	ErrHealthProbeNotValid       = errors.New("health probe is not valid")
+	ErrHealthProbeQuorumNotValid = errors.New("health probes quorum is not valid")
+	ErrHealthProbeURLNotValid    = errors.New("health probe URL is not valid")
+	ErrHealthProbeStatusNotValid = errors.New("health probe HTTP status is not valid")
+)
+
+func (h HealthProbes) validate() (err error) {
+	if len(h.Enabled) == 0 {
+		return fmt.Errorf("%w: no probe enabled", ErrHealthProbeNotValid)
+	}
+
+	validProbes := []string{HealthProbeTCP, HealthProbeHTTP, HealthProbeDNS, HealthProbeICMP}
+	err = validate.AreAllOneOf(h.Enabled, validProbes)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrHealthProbeNotValid, err)
+	}
+
+	if h.Quorum == 0 || h.Quorum > uint(len(h.Enabled)) {
+		return fmt.Errorf("%w: %d must be between 1 and %d",
+			ErrHealthProbeQuorumNotValid, h.Quorum, len(h.Enabled))
+	}
+
+	if h.isEnabled(HealthProbeHTTP) {
+		parsedURL, err := url.Parse(h.HTTP.URL)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrHealthProbeURLNotValid, err)
+		} else if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+			return fmt.Errorf("%w: scheme %q must be http or https",
+				ErrHealthProbeURLNotValid, parsedURL.Scheme)
+		}
+
+		const minStatus, maxStatus = 100, 599
+		err = validate.NumberBetween(h.HTTP.Status, minStatus, maxStatus)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrHealthProbeStatusNotValid, err)
+		}
+	}
+
+	if h.isEnabled(HealthProbeDNS) {
+		err = address.Validate(h.DNS.Server)
+		if err != nil {
+			return fmt.Errorf("DNS probe server address is not valid: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (h HealthProbes) isEnabled(probe string) bool {
+	for _, enabled := range h.Enabled {
+		if enabled == probe {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *HealthProbes) copy() (copied HealthProbes) {
+	return HealthProbes{
+		Enabled: gosettings.CopySlice(h.Enabled),
+		Quorum:  h.Quorum,
+		TCP:     h.TCP,
+		HTTP:    h.HTTP,
+		DNS:     h.DNS,
+		ICMP:    h.ICMP,
+	}
+}
+
+// mergeWith merges the other settings into any
+// unset field of the receiver settings object.
+func (h *HealthProbes) mergeWith(other HealthProbes) {
+	h.Enabled = gosettings.MergeWithSlice(h.Enabled, other.Enabled)
+	h.Quorum = gosettings.MergeWithNumber(h.Quorum, other.Quorum)
+	h.TCP.Timeout = gosettings.MergeWithNumber(h.TCP.Timeout, other.TCP.Timeout)
+	h.HTTP.URL = gosettings.MergeWithString(h.HTTP.URL, other.HTTP.URL)
+	h.HTTP.Status = gosettings.MergeWithNumber(h.HTTP.Status, other.HTTP.Status)
+	h.HTTP.Timeout = gosettings.MergeWithNumber(h.HTTP.Timeout, other.HTTP.Timeout)
+	h.DNS.Server = gosettings.MergeWithString(h.DNS.Server, other.DNS.Server)
+	h.DNS.Hostname = gosettings.MergeWithString(h.DNS.Hostname, other.DNS.Hostname)
+	h.DNS.Timeout = gosettings.MergeWithNumber(h.DNS.Timeout, other.DNS.Timeout)
+	if !h.ICMP.Target.IsValid() {
+		h.ICMP.Target = other.ICMP.Target
+	}
+	h.ICMP.Timeout = gosettings.MergeWithNumber(h.ICMP.Timeout, other.ICMP.Timeout)
+}
+
+// overrideWith overrides fields of the receiver
+// settings object with any field set in the other
+// settings.
+func (h *HealthProbes) overrideWith(other HealthProbes) {
+	h.Enabled = gosettings.OverrideWithSlice(h.Enabled, other.Enabled)
+	h.Quorum = gosettings.OverrideWithNumber(h.Quorum, other.Quorum)
+	h.TCP.Timeout = gosettings.OverrideWithNumber(h.TCP.Timeout, other.TCP.Timeout)
+	h.HTTP.URL = gosettings.OverrideWithString(h.HTTP.URL, other.HTTP.URL)
+	h.HTTP.Status = gosettings.OverrideWithNumber(h.HTTP.Status, other.HTTP.Status)
+	h.HTTP.Timeout = gosettings.OverrideWithNumber(h.HTTP.Timeout, other.HTTP.Timeout)
+	h.DNS.Server = gosettings.OverrideWithString(h.DNS.Server, other.DNS.Server)
+	h.DNS.Hostname = gosettings.OverrideWithString(h.DNS.Hostname, other.DNS.Hostname)
+	h.DNS.Timeout = gosettings.OverrideWithNumber(h.DNS.Timeout, other.DNS.Timeout)
+	if other.ICMP.Target.IsValid() {
+		h.ICMP.Target = other.ICMP.Target
+	}
+	h.ICMP.Timeout = gosettings.OverrideWithNumber(h.ICMP.Timeout, other.ICMP.Timeout)
+}
+
+func (h *HealthProbes) setDefaults() {
+	h.Enabled = gosettings.DefaultSlice(h.Enabled, []string{HealthProbeTCP})
+	h.Quorum = gosettings.DefaultNumber(h.Quorum, uint(len(h.Enabled)))
+	const defaultTimeout = 3 * time.Second
+	h.TCP.Timeout = gosettings.DefaultNumber(h.TCP.Timeout, defaultTimeout)
+	h.HTTP.URL = gosettings.DefaultString(h.HTTP.URL, "https://cloudflare.com/cdn-cgi/trace")
+	const defaultStatus = 200
+	h.HTTP.Status = gosettings.DefaultNumber(h.HTTP.Status, defaultStatus)
+	h.HTTP.Timeout = gosettings.DefaultNumber(h.HTTP.Timeout, defaultTimeout)
+	h.DNS.Server = gosettings.DefaultString(h.DNS.Server, "127.0.0.1:53")
+	h.DNS.Hostname = gosettings.DefaultString(h.DNS.Hostname, "cloudflare.com")
+	h.DNS.Timeout = gosettings.DefaultNumber(h.DNS.Timeout, defaultTimeout)
+	if !h.ICMP.Target.IsValid() {
+		h.ICMP.Target = netip.AddrFrom4([4]byte{1, 1, 1, 1})
+	}
+	h.ICMP.Timeout = gosettings.DefaultNumber(h.ICMP.Timeout, defaultTimeout)
+}
+
+func (h HealthProbes) String() string {
+	return h.toLinesNode().String()
+}
+
+func (h HealthProbes) toLinesNode() (node *gotree.Node) {
+	node = gotree.New("Probes:")
+	node.Appendf("Enabled: %s", strings.Join(h.Enabled, ", "))
+	node.Appendf("Quorum: %d", h.Quorum)
+	for _, probe := range h.Enabled {
+		switch probe {
+		case HealthProbeTCP:
+			node.Appendf("TCP dial timeout: %s", h.TCP.Timeout)
+		case HealthProbeHTTP:
+			node.Appendf("HTTP GET %s expecting status %d with timeout %s",
+				h.HTTP.URL, h.HTTP.Status, h.HTTP.Timeout)
+		case HealthProbeDNS:
+			node.Appendf("DNS resolution of %s using %s with timeout %s",
+				h.DNS.Hostname, h.DNS.Server, h.DNS.Timeout)
+		case HealthProbeICMP:
+			node.Appendf("ICMP echo to %s with timeout %s",
+				h.ICMP.Target, h.ICMP.Timeout)
+		}
+	}
+	return node
+}
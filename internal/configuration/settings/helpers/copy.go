@@ -0,0 +1,13 @@
+package helpers
+
+// CopyMap returns a copy of the map given, or nil if it is nil.
+func CopyMap[K comparable, V any](original map[K]V) (copied map[K]V) {
+	if original == nil {
+		return nil
+	}
+	copied = make(map[K]V, len(original))
+	for key, value := range original {
+		copied[key] = value
+	}
+	return copied
+}
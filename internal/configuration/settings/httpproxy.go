@@ -2,10 +2,15 @@ package settings
 
 import (
 	"fmt"
+	"net/netip"
 	"os"
+	"regexp"
 	"time"
 
+	"github.com/qdm12/gluetun/internal/httpproxy/acl"
+	"github.com/qdm12/gluetun/internal/httpproxy/htpasswd"
 	"github.com/qdm12/gosettings"
+	"github.com/qdm12/gosettings/validate"
 	"github.com/qdm12/gotree"
 	"github.com/qdm12/govalid/address"
 )
@@ -18,6 +23,12 @@ type HTTPProxy struct {
 	// Password is the password to use for the HTTP proxy.
 	// It cannot be nil in the internal state.
 	Password *string
+	// CredentialsFile is the path to an htpasswd-style credentials
+	// file, where each line is `name:hash[:max_connections[:bandwidth]]`.
+	// Its users are allowed in addition to the User above, and it is
+	// reloaded when it changes. It cannot be nil in the internal state,
+	// and the empty string disables it.
+	CredentialsFile *string
 	// ListeningAddress is the listening address
 	// of the HTTP proxy server.
 	// It cannot be the empty string in the internal state.
@@ -40,30 +51,105 @@ type HTTPProxy struct {
 	// ReadTimeout is the HTTP read timeout duration
 	// of the HTTP server. It defaults to 3 seconds if left unset.
 	ReadTimeout time.Duration
+	// AllowedClients is the list of client source IP prefixes
+	// allowed to use the HTTP proxy. All clients are allowed
+	// if it is left empty.
+	AllowedClients []netip.Prefix
+	// AllowedDestinations is the list of destination rules
+	// allowed, taking effect when the default policy is deny.
+	// Each rule is a domain name, a domain wildcard such as
+	// `*.example.com`, an IP address or an IP prefix, optionally
+	// followed by a port or port range such as `:8000-8999`.
+	AllowedDestinations []string
+	// BlockedDestinations is the list of destination rules
+	// blocked, with the same format as AllowedDestinations.
+	// It takes precedence over AllowedDestinations.
+	BlockedDestinations []string
+	// DefaultPolicy is the policy for destinations matching
+	// none of the allowed and blocked destinations, and can be
+	// "allow" or "deny". It cannot be empty in the internal state.
+	DefaultPolicy string
+	// PAC is true if the HTTP proxy server should serve a proxy
+	// auto-config file at /proxy.pac to clients of its listening
+	// address. It cannot be nil in the internal state.
+	PAC *bool
+	// WPAD is true if the proxy auto-config file should also be
+	// served at /wpad.dat for web proxy auto-discovery. It only
+	// takes effect if PAC is enabled, and it cannot be nil in the
+	// internal state.
+	WPAD *bool
+	// PACBypass is the list of domain names for which the proxy
+	// auto-config file instructs clients to connect directly.
+	// Each element is a domain name such as `example.com`, or a
+	// domain wildcard such as `*.example.com`.
+	PACBypass []string
 }
 
-func (h HTTPProxy) validate() (err error) {
+const (
+	HTTPProxyPolicyAllow = "allow"
+	HTTPProxyPolicyDeny  = "deny"
+)
+
+var regexPACBypass = regexp.MustCompile(`^(\*\.)?[a-zA-Z0-9-]+(\.[a-zA-Z0-9-]+)*$`)
+
+// Validate validates the HTTP proxy settings.
+func (h HTTPProxy) Validate() (err error) {
 	// Do not validate user and password
 
+	if *h.CredentialsFile != "" {
+		_, err = htpasswd.ParseFile(*h.CredentialsFile)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrHTTPProxyCredentialsNotValid, err)
+		}
+	}
+
 	uid := os.Getuid()
 	err = address.Validate(h.ListeningAddress, address.OptionListening(uid))
 	if err != nil {
 		return fmt.Errorf("%w: %s", ErrServerAddressNotValid, h.ListeningAddress)
 	}
 
+	for _, destinations := range [][]string{h.AllowedDestinations, h.BlockedDestinations} {
+		for _, destination := range destinations {
+			_, err = acl.ParseRule(destination)
+			if err != nil {
+				return fmt.Errorf("%w: %w", ErrHTTPProxyDestinationNotValid, err)
+			}
+		}
+	}
+
+	err = validate.IsOneOf(h.DefaultPolicy, HTTPProxyPolicyAllow, HTTPProxyPolicyDeny)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrHTTPProxyDefaultPolicyNotValid, err)
+	}
+
+	for _, domain := range h.PACBypass {
+		if !regexPACBypass.MatchString(domain) {
+			return fmt.Errorf("%w: %s", ErrHTTPProxyPACBypassNotValid, domain)
+		}
+	}
+
 	return nil
 }
 
 func (h *HTTPProxy) copy() (copied HTTPProxy) {
 	return HTTPProxy{
-		User:              gosettings.CopyPointer(h.User),
-		Password:          gosettings.CopyPointer(h.Password),
-		ListeningAddress:  h.ListeningAddress,
-		Enabled:           gosettings.CopyPointer(h.Enabled),
-		Stealth:           gosettings.CopyPointer(h.Stealth),
-		Log:               gosettings.CopyPointer(h.Log),
-		ReadHeaderTimeout: h.ReadHeaderTimeout,
-		ReadTimeout:       h.ReadTimeout,
+		User:                gosettings.CopyPointer(h.User),
+		Password:            gosettings.CopyPointer(h.Password),
+		CredentialsFile:     gosettings.CopyPointer(h.CredentialsFile),
+		ListeningAddress:    h.ListeningAddress,
+		Enabled:             gosettings.CopyPointer(h.Enabled),
+		Stealth:             gosettings.CopyPointer(h.Stealth),
+		Log:                 gosettings.CopyPointer(h.Log),
+		ReadHeaderTimeout:   h.ReadHeaderTimeout,
+		ReadTimeout:         h.ReadTimeout,
+		AllowedClients:      gosettings.CopySlice(h.AllowedClients),
+		AllowedDestinations: gosettings.CopySlice(h.AllowedDestinations),
+		BlockedDestinations: gosettings.CopySlice(h.BlockedDestinations),
+		DefaultPolicy:       h.DefaultPolicy,
+		PAC:                 gosettings.CopyPointer(h.PAC),
+		WPAD:                gosettings.CopyPointer(h.WPAD),
+		PACBypass:           gosettings.CopySlice(h.PACBypass),
 	}
 }
 
@@ -72,31 +158,48 @@ func (h *HTTPProxy) copy() (copied HTTPProxy) {
 func (h *HTTPProxy) mergeWith(other HTTPProxy) {
 	h.User = gosettings.MergeWithPointer(h.User, other.User)
 	h.Password = gosettings.MergeWithPointer(h.Password, other.Password)
+	h.CredentialsFile = gosettings.MergeWithPointer(h.CredentialsFile, other.CredentialsFile)
 	h.ListeningAddress = gosettings.MergeWithString(h.ListeningAddress, other.ListeningAddress)
 	h.Enabled = gosettings.MergeWithPointer(h.Enabled, other.Enabled)
 	h.Stealth = gosettings.MergeWithPointer(h.Stealth, other.Stealth)
 	h.Log = gosettings.MergeWithPointer(h.Log, other.Log)
 	h.ReadHeaderTimeout = gosettings.MergeWithNumber(h.ReadHeaderTimeout, other.ReadHeaderTimeout)
 	h.ReadTimeout = gosettings.MergeWithNumber(h.ReadTimeout, other.ReadTimeout)
+	h.AllowedClients = gosettings.MergeWithSlice(h.AllowedClients, other.AllowedClients)
+	h.AllowedDestinations = gosettings.MergeWithSlice(h.AllowedDestinations, other.AllowedDestinations)
+	h.BlockedDestinations = gosettings.MergeWithSlice(h.BlockedDestinations, other.BlockedDestinations)
+	h.DefaultPolicy = gosettings.MergeWithString(h.DefaultPolicy, other.DefaultPolicy)
+	h.PAC = gosettings.MergeWithPointer(h.PAC, other.PAC)
+	h.WPAD = gosettings.MergeWithPointer(h.WPAD, other.WPAD)
+	h.PACBypass = gosettings.MergeWithSlice(h.PACBypass, other.PACBypass)
 }
 
-// overrideWith overrides fields of the receiver
+// OverrideWith overrides fields of the receiver
 // settings object with any field set in the other
 // settings.
-func (h *HTTPProxy) overrideWith(other HTTPProxy) {
+func (h *HTTPProxy) OverrideWith(other HTTPProxy) {
 	h.User = gosettings.OverrideWithPointer(h.User, other.User)
 	h.Password = gosettings.OverrideWithPointer(h.Password, other.Password)
+	h.CredentialsFile = gosettings.OverrideWithPointer(h.CredentialsFile, other.CredentialsFile)
 	h.ListeningAddress = gosettings.OverrideWithString(h.ListeningAddress, other.ListeningAddress)
 	h.Enabled = gosettings.OverrideWithPointer(h.Enabled, other.Enabled)
 	h.Stealth = gosettings.OverrideWithPointer(h.Stealth, other.Stealth)
 	h.Log = gosettings.OverrideWithPointer(h.Log, other.Log)
 	h.ReadHeaderTimeout = gosettings.OverrideWithNumber(h.ReadHeaderTimeout, other.ReadHeaderTimeout)
 	h.ReadTimeout = gosettings.OverrideWithNumber(h.ReadTimeout, other.ReadTimeout)
+	h.AllowedClients = gosettings.OverrideWithSlice(h.AllowedClients, other.AllowedClients)
+	h.AllowedDestinations = gosettings.OverrideWithSlice(h.AllowedDestinations, other.AllowedDestinations)
+	h.BlockedDestinations = gosettings.OverrideWithSlice(h.BlockedDestinations, other.BlockedDestinations)
+	h.DefaultPolicy = gosettings.OverrideWithString(h.DefaultPolicy, other.DefaultPolicy)
+	h.PAC = gosettings.OverrideWithPointer(h.PAC, other.PAC)
+	h.WPAD = gosettings.OverrideWithPointer(h.WPAD, other.WPAD)
+	h.PACBypass = gosettings.OverrideWithSlice(h.PACBypass, other.PACBypass)
 }
 
 func (h *HTTPProxy) setDefaults() {
 	h.User = gosettings.DefaultPointer(h.User, "")
 	h.Password = gosettings.DefaultPointer(h.Password, "")
+	h.CredentialsFile = gosettings.DefaultPointer(h.CredentialsFile, "")
 	h.ListeningAddress = gosettings.DefaultString(h.ListeningAddress, ":8888")
 	h.Enabled = gosettings.DefaultPointer(h.Enabled, false)
 	h.Stealth = gosettings.DefaultPointer(h.Stealth, false)
@@ -105,6 +208,9 @@ func (h *HTTPProxy) setDefaults() {
 	h.ReadHeaderTimeout = gosettings.DefaultNumber(h.ReadHeaderTimeout, defaultReadHeaderTimeout)
 	const defaultReadTimeout = 3 * time.Second
 	h.ReadTimeout = gosettings.DefaultNumber(h.ReadTimeout, defaultReadTimeout)
+	h.DefaultPolicy = gosettings.DefaultString(h.DefaultPolicy, HTTPProxyPolicyAllow)
+	h.PAC = gosettings.DefaultPointer(h.PAC, false)
+	h.WPAD = gosettings.DefaultPointer(h.WPAD, false)
 }
 
 func (h HTTPProxy) String() string {
@@ -121,10 +227,47 @@ func (h HTTPProxy) toLinesNode() (node *gotree.Node) {
 	node.Appendf("Listening address: %s", h.ListeningAddress)
 	node.Appendf("User: %s", *h.User)
 	node.Appendf("Password: %s", gosettings.ObfuscateKey(*h.Password))
+	if *h.CredentialsFile != "" {
+		node.Appendf("Credentials file: %s", *h.CredentialsFile)
+	}
 	node.Appendf("Stealth mode: %s", gosettings.BoolToYesNo(h.Stealth))
 	node.Appendf("Log: %s", gosettings.BoolToYesNo(h.Log))
 	node.Appendf("Read header timeout: %s", h.ReadHeaderTimeout)
 	node.Appendf("Read timeout: %s", h.ReadTimeout)
 
+	if len(h.AllowedClients) > 0 {
+		allowedClientsNode := node.Appendf("Allowed clients:")
+		for _, prefix := range h.AllowedClients {
+			allowedClientsNode.Appendf(prefix.String())
+		}
+	}
+
+	if len(h.AllowedDestinations) > 0 {
+		allowedNode := node.Appendf("Allowed destinations:")
+		for _, destination := range h.AllowedDestinations {
+			allowedNode.Appendf(destination)
+		}
+	}
+
+	if len(h.BlockedDestinations) > 0 {
+		blockedNode := node.Appendf("Blocked destinations:")
+		for _, destination := range h.BlockedDestinations {
+			blockedNode.Appendf(destination)
+		}
+	}
+
+	node.Appendf("Default destination policy: %s", h.DefaultPolicy)
+
+	pacNode := node.Appendf("Proxy auto-config file: %s", gosettings.BoolToYesNo(h.PAC))
+	if *h.PAC {
+		pacNode.Appendf("Web proxy auto-discovery: %s", gosettings.BoolToYesNo(h.WPAD))
+		if len(h.PACBypass) > 0 {
+			bypassNode := pacNode.Appendf("Direct domains:")
+			for _, domain := range h.PACBypass {
+				bypassNode.Appendf(domain)
+			}
+		}
+	}
+
 	return node
 }
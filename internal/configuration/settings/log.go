@@ -1,6 +1,9 @@
 package settings
 
 import (
+	"fmt"
+
+	"github.com/qdm12/gluetun/internal/accesslog"
 	"github.com/qdm12/gosettings"
 	"github.com/qdm12/gotree"
 	"github.com/qdm12/log"
@@ -11,15 +14,41 @@ type Log struct {
 	// Level is the log level of the logger.
 	// It cannot be nil in the internal state.
 	Level *log.Level
+	// AccessLog is the destination of the access logs of
+	// the HTTP proxy and Shadowsocks server. It can be a
+	// file path, "stdout" to write to the standard output,
+	// or the empty string to disable access logs.
+	// It cannot be nil in the internal state.
+	AccessLog *string
+	// AccessLogMaxSize is the maximum size in megabytes of the
+	// access log file, after which it is rotated.
+	// It cannot be nil in the internal state.
+	AccessLogMaxSize *int
+	// AccessLogMaxBackups is the maximum number of rotated access
+	// log files to keep. It cannot be nil in the internal state.
+	AccessLogMaxBackups *int
 }
 
 func (l Log) validate() (err error) {
+	if *l.AccessLogMaxSize < 1 {
+		return fmt.Errorf("%w: %d must be at least 1",
+			ErrAccessLogMaxSizeNotValid, *l.AccessLogMaxSize)
+	}
+
+	if *l.AccessLogMaxBackups < 0 {
+		return fmt.Errorf("%w: %d must be positive",
+			ErrAccessLogMaxBackupsNotValid, *l.AccessLogMaxBackups)
+	}
+
 	return nil
 }
 
 func (l *Log) copy() (copied Log) {
 	return Log{
-		Level: gosettings.CopyPointer(l.Level),
+		Level:               gosettings.CopyPointer(l.Level),
+		AccessLog:           gosettings.CopyPointer(l.AccessLog),
+		AccessLogMaxSize:    gosettings.CopyPointer(l.AccessLogMaxSize),
+		AccessLogMaxBackups: gosettings.CopyPointer(l.AccessLogMaxBackups),
 	}
 }
 
@@ -27,6 +56,9 @@ func (l *Log) copy() (copied Log) {
 // unset field of the receiver settings object.
 func (l *Log) mergeWith(other Log) {
 	l.Level = gosettings.MergeWithPointer(l.Level, other.Level)
+	l.AccessLog = gosettings.MergeWithPointer(l.AccessLog, other.AccessLog)
+	l.AccessLogMaxSize = gosettings.MergeWithPointer(l.AccessLogMaxSize, other.AccessLogMaxSize)
+	l.AccessLogMaxBackups = gosettings.MergeWithPointer(l.AccessLogMaxBackups, other.AccessLogMaxBackups)
 }
 
 // overrideWith overrides fields of the receiver
@@ -34,10 +66,18 @@ func (l *Log) mergeWith(other Log) {
 // settings.
 func (l *Log) overrideWith(other Log) {
 	l.Level = gosettings.OverrideWithPointer(l.Level, other.Level)
+	l.AccessLog = gosettings.OverrideWithPointer(l.AccessLog, other.AccessLog)
+	l.AccessLogMaxSize = gosettings.OverrideWithPointer(l.AccessLogMaxSize, other.AccessLogMaxSize)
+	l.AccessLogMaxBackups = gosettings.OverrideWithPointer(l.AccessLogMaxBackups, other.AccessLogMaxBackups)
 }
 
 func (l *Log) setDefaults() {
 	l.Level = gosettings.DefaultPointer(l.Level, log.LevelInfo)
+	l.AccessLog = gosettings.DefaultPointer(l.AccessLog, "")
+	const defaultAccessLogMaxSize = 10
+	l.AccessLogMaxSize = gosettings.DefaultPointer(l.AccessLogMaxSize, defaultAccessLogMaxSize)
+	const defaultAccessLogMaxBackups = 3
+	l.AccessLogMaxBackups = gosettings.DefaultPointer(l.AccessLogMaxBackups, defaultAccessLogMaxBackups)
 }
 
 func (l Log) String() string {
@@ -47,5 +87,17 @@ func (l Log) String() string {
 func (l Log) toLinesNode() (node *gotree.Node) {
 	node = gotree.New("Log settings:")
 	node.Appendf("Log level: %s", l.Level.String())
+
+	switch *l.AccessLog {
+	case "":
+		node.Appendf("Access log: disabled")
+	case accesslog.Stdout:
+		node.Appendf("Access log: standard output")
+	default:
+		accessLogNode := node.Appendf("Access log: %s", *l.AccessLog)
+		accessLogNode.Appendf("Maximum size: %dMB", *l.AccessLogMaxSize)
+		accessLogNode.Appendf("Maximum backups: %d", *l.AccessLogMaxBackups)
+	}
+
 	return node
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/qdm12/gluetun/internal/constants/openvpn"
 	"github.com/qdm12/gluetun/internal/constants/providers"
 	"github.com/qdm12/gluetun/internal/openvpn/extract"
+	"github.com/qdm12/gluetun/internal/openvpn/totp"
 	"github.com/qdm12/gluetun/internal/provider/privateinternetaccess/presets"
 	"github.com/qdm12/gosettings"
 	"github.com/qdm12/gosettings/validate"
@@ -61,6 +62,18 @@ type OpenVPN struct {
 	// to decrypt the EncryptedPrivateKey. It defaults to the
 	// empty string and must be set if EncryptedPrivateKey is set.
 	KeyPassphrase *string
+	// TOTPSecret is the base32 encoded secret used to generate
+	// time based one time passwords, to answer static and dynamic
+	// authentication challenges from the OpenVPN server.
+	// It can be set to the empty string to be disabled.
+	// It cannot be nil in the internal state.
+	TOTPSecret *string
+	// Challenge is true if OpenVPN authentication challenges
+	// should be answered through the OpenVPN management interface,
+	// either using the TOTP secret or a response given through
+	// the control server. It defaults to true if TOTPSecret is set.
+	// It cannot be nil in the internal state.
+	Challenge *bool
 	// PIAEncPreset is the encryption preset for
 	// Private Internet Access. It can be set to an
 	// empty string for other providers.
@@ -133,6 +146,15 @@ func (o OpenVPN) validate(vpnProvider string) (err error) {
 		return fmt.Errorf("%w", ErrOpenVPNKeyPassphraseIsEmpty)
 	}
 
+	err = validateOpenVPNTOTPSecret(*o.TOTPSecret)
+	if err != nil {
+		return fmt.Errorf("TOTP secret: %w", err)
+	}
+
+	if *o.TOTPSecret != "" && !*o.Challenge {
+		return fmt.Errorf("%w", ErrOpenVPNTOTPWithoutChallenge)
+	}
+
 	const maxMSSFix = 10000
 	if *o.MSSFix > maxMSSFix {
 		return fmt.Errorf("%w: %d is over the maximum value of %d",
@@ -240,6 +262,18 @@ func validateOpenVPNEncryptedKey(vpnProvider,
 	return nil
 }
 
+func validateOpenVPNTOTPSecret(secret string) (err error) {
+	if secret == "" {
+		return nil
+	}
+
+	_, err = totp.DecodeSecret(secret)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrOpenVPNTOTPSecretNotValid, err)
+	}
+	return nil
+}
+
 func (o *OpenVPN) copy() (copied OpenVPN) {
 	return OpenVPN{
 		Version:       o.Version,
@@ -252,6 +286,8 @@ func (o *OpenVPN) copy() (copied OpenVPN) {
 		Key:           gosettings.CopyPointer(o.Key),
 		EncryptedKey:  gosettings.CopyPointer(o.EncryptedKey),
 		KeyPassphrase: gosettings.CopyPointer(o.KeyPassphrase),
+		TOTPSecret:    gosettings.CopyPointer(o.TOTPSecret),
+		Challenge:     gosettings.CopyPointer(o.Challenge),
 		PIAEncPreset:  gosettings.CopyPointer(o.PIAEncPreset),
 		MSSFix:        gosettings.CopyPointer(o.MSSFix),
 		Interface:     o.Interface,
@@ -274,6 +310,8 @@ func (o *OpenVPN) mergeWith(other OpenVPN) {
 	o.Key = gosettings.MergeWithPointer(o.Key, other.Key)
 	o.EncryptedKey = gosettings.MergeWithPointer(o.EncryptedKey, other.EncryptedKey)
 	o.KeyPassphrase = gosettings.MergeWithPointer(o.KeyPassphrase, other.KeyPassphrase)
+	o.TOTPSecret = gosettings.MergeWithPointer(o.TOTPSecret, other.TOTPSecret)
+	o.Challenge = gosettings.MergeWithPointer(o.Challenge, other.Challenge)
 	o.PIAEncPreset = gosettings.MergeWithPointer(o.PIAEncPreset, other.PIAEncPreset)
 	o.MSSFix = gosettings.MergeWithPointer(o.MSSFix, other.MSSFix)
 	o.Interface = gosettings.MergeWithString(o.Interface, other.Interface)
@@ -296,6 +334,8 @@ func (o *OpenVPN) overrideWith(other OpenVPN) {
 	o.Key = gosettings.OverrideWithPointer(o.Key, other.Key)
 	o.EncryptedKey = gosettings.OverrideWithPointer(o.EncryptedKey, other.EncryptedKey)
 	o.KeyPassphrase = gosettings.OverrideWithPointer(o.KeyPassphrase, other.KeyPassphrase)
+	o.TOTPSecret = gosettings.OverrideWithPointer(o.TOTPSecret, other.TOTPSecret)
+	o.Challenge = gosettings.OverrideWithPointer(o.Challenge, other.Challenge)
 	o.PIAEncPreset = gosettings.OverrideWithPointer(o.PIAEncPreset, other.PIAEncPreset)
 	o.MSSFix = gosettings.OverrideWithPointer(o.MSSFix, other.MSSFix)
 	o.Interface = gosettings.OverrideWithString(o.Interface, other.Interface)
@@ -319,6 +359,8 @@ func (o *OpenVPN) setDefaults(vpnProvider string) {
 	o.Key = gosettings.DefaultPointer(o.Key, "")
 	o.EncryptedKey = gosettings.DefaultPointer(o.EncryptedKey, "")
 	o.KeyPassphrase = gosettings.DefaultPointer(o.KeyPassphrase, "")
+	o.TOTPSecret = gosettings.DefaultPointer(o.TOTPSecret, "")
+	o.Challenge = gosettings.DefaultPointer(o.Challenge, *o.TOTPSecret != "")
 
 	var defaultEncPreset string
 	if vpnProvider == providers.PrivateInternetAccess {
@@ -366,6 +408,15 @@ func (o OpenVPN) toLinesNode() (node *gotree.Node) {
 			gosettings.ObfuscateKey(*o.EncryptedKey), gosettings.ObfuscateKey(*o.KeyPassphrase))
 	}
 
+	if *o.Challenge {
+		challengeNode := node.Appendf("Authentication challenges:")
+		if *o.TOTPSecret != "" {
+			challengeNode.Appendf("TOTP secret: %s", gosettings.ObfuscateKey(*o.TOTPSecret))
+		} else {
+			challengeNode.Appendf("Answered through the control server")
+		}
+	}
+
 	if *o.PIAEncPreset != "" {
 		node.Appendf("Private Internet Access encryption preset: %s", *o.PIAEncPreset)
 	}
@@ -22,7 +22,9 @@ type PortForwarding struct {
 	Filepath *string
 }
 
-func (p PortForwarding) validate(vpnProvider string) (err error) {
+// Validate validates the port forwarding settings
+// for the VPN provider given.
+func (p PortForwarding) Validate(vpnProvider string) (err error) {
 	if !*p.Enabled {
 		return nil
 	}
@@ -56,7 +58,10 @@ func (p *PortForwarding) mergeWith(other PortForwarding) {
 	p.Filepath = gosettings.MergeWithPointer(p.Filepath, other.Filepath)
 }
 
-func (p *PortForwarding) overrideWith(other PortForwarding) {
+// OverrideWith overrides fields of the receiver
+// settings object with any field set in the other
+// settings.
+func (p *PortForwarding) OverrideWith(other PortForwarding) {
 	p.Enabled = gosettings.OverrideWithPointer(p.Enabled, other.Enabled)
 	p.Filepath = gosettings.OverrideWithPointer(p.Filepath, other.Filepath)
 }
@@ -3,9 +3,14 @@ package settings
 import (
 	"fmt"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/qdm12/gluetun/internal/configuration/settings/helpers"
+	"github.com/qdm12/gluetun/internal/publicip/api"
 	"github.com/qdm12/gosettings"
+	"github.com/qdm12/gosettings/validate"
 	"github.com/qdm12/gotree"
 )
 
@@ -21,9 +26,61 @@ type PublicIP struct {
 	// to write to a file. It cannot be nil for the
 	// internal state
 	IPFilepath *string
+	// APIs is the ordered list of API names to use to fetch
+	// public IP address information. The next API is used
+	// if an API fails or is rate limited.
+	// It defaults to ipinfo and cannot be empty in the internal state.
+	APIs []string
+	// IPInfoToken is the optional ipinfo.io token to use.
+	// It cannot be nil in the internal state.
+	IPInfoToken *string
+	// CustomAPI has settings for a user defined API,
+	// used if "custom" is in APIs.
+	CustomAPI PublicIPCustomAPI
+	// Exit contains settings to enforce the exit location
+	// of the VPN connection.
+	Exit PublicIPExit
 }
 
-func (p PublicIP) validate() (err error) {
+// PublicIPCustomAPI contains settings for a user defined
+// public IP address information API.
+type PublicIPCustomAPI struct {
+	// URL is the URL to query with a GET request, which must
+	// respond with JSON data. It can contain the "{ip}" placeholder
+	// to be replaced with the IP address to fetch information on.
+	// It cannot be nil in the internal state.
+	URL *string
+	// Fields maps public IP field names ("ip", "region", "country",
+	// "city", "hostname", "location", "organization", "postal_code"
+	// and "timezone") to dot separated JSON paths in the response,
+	// such as "geo.country". The "ip" field must be mapped.
+	Fields map[string]string
+}
+
+const (
+	ExitActionWarn    = "warn"
+	ExitActionRestart = "restart"
+)
+
+// PublicIPExit contains settings to check the public IP address
+// information obtained against allowed exit locations.
+type PublicIPExit struct {
+	// Countries is the list of allowed exit countries.
+	// It can be empty to allow any country.
+	Countries []string
+	// Organizations is the list of allowed exit organizations,
+	// each matching if it is contained in the organization of
+	// the public IP address. It can be empty to allow any organization.
+	Organizations []string
+	// Action is the action to take on an exit location mismatch,
+	// and can be "warn" or "restart". The "restart" action restarts
+	// the VPN excluding the server until the next servers update.
+	// It cannot be nil in the internal state.
+	Action *string
+}
+
+// Validate validates the public IP settings.
+func (p PublicIP) Validate() (err error) { //nolint:cyclop
 	const minPeriod = 5 * time.Second
 	if *p.Period < minPeriod {
 		return fmt.Errorf("%w: %s must be at least %s",
@@ -37,30 +94,104 @@ func (p PublicIP) validate() (err error) {
 		}
 	}
 
+	if len(p.APIs) == 0 {
+		return fmt.Errorf("%w", ErrPublicIPAPIsEmpty)
+	}
+
+	err = validate.AreAllOneOf(p.APIs, api.Names())
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPublicIPAPINotValid, err)
+	}
+
+	for _, name := range p.APIs {
+		if name != api.Custom {
+			continue
+		}
+
+		if *p.CustomAPI.URL == "" {
+			return fmt.Errorf("%w", ErrPublicIPCustomURLEmpty)
+		}
+
+		_, ok := p.CustomAPI.Fields["ip"]
+		if !ok {
+			return fmt.Errorf("%w", ErrPublicIPCustomIPFieldMissing)
+		}
+
+		fieldNames := make([]string, 0, len(p.CustomAPI.Fields))
+		for fieldName := range p.CustomAPI.Fields {
+			fieldNames = append(fieldNames, fieldName)
+		}
+		err = validate.AreAllOneOf(fieldNames, api.CustomFieldNames())
+		if err != nil {
+			return fmt.Errorf("custom API field: %w", err)
+		}
+	}
+
+	err = validate.IsOneOf(*p.Exit.Action, ExitActionWarn, ExitActionRestart)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPublicIPExitActionNotValid, err)
+	}
+
 	return nil
 }
 
 func (p *PublicIP) copy() (copied PublicIP) {
 	return PublicIP{
-		Period:     gosettings.CopyPointer(p.Period),
-		IPFilepath: gosettings.CopyPointer(p.IPFilepath),
+		Period:      gosettings.CopyPointer(p.Period),
+		IPFilepath:  gosettings.CopyPointer(p.IPFilepath),
+		APIs:        gosettings.CopySlice(p.APIs),
+		IPInfoToken: gosettings.CopyPointer(p.IPInfoToken),
+		CustomAPI: PublicIPCustomAPI{
+			URL:    gosettings.CopyPointer(p.CustomAPI.URL),
+			Fields: helpers.CopyMap(p.CustomAPI.Fields),
+		},
+		Exit: PublicIPExit{
+			Countries:     gosettings.CopySlice(p.Exit.Countries),
+			Organizations: gosettings.CopySlice(p.Exit.Organizations),
+			Action:        gosettings.CopyPointer(p.Exit.Action),
+		},
 	}
 }
 
 func (p *PublicIP) mergeWith(other PublicIP) {
 	p.Period = gosettings.MergeWithPointer(p.Period, other.Period)
 	p.IPFilepath = gosettings.MergeWithPointer(p.IPFilepath, other.IPFilepath)
+	p.APIs = gosettings.MergeWithSlice(p.APIs, other.APIs)
+	p.IPInfoToken = gosettings.MergeWithPointer(p.IPInfoToken, other.IPInfoToken)
+	p.CustomAPI.URL = gosettings.MergeWithPointer(p.CustomAPI.URL, other.CustomAPI.URL)
+	if p.CustomAPI.Fields == nil {
+		p.CustomAPI.Fields = helpers.CopyMap(other.CustomAPI.Fields)
+	}
+	p.Exit.Countries = gosettings.MergeWithSlice(p.Exit.Countries, other.Exit.Countries)
+	p.Exit.Organizations = gosettings.MergeWithSlice(p.Exit.Organizations, other.Exit.Organizations)
+	p.Exit.Action = gosettings.MergeWithPointer(p.Exit.Action, other.Exit.Action)
 }
 
-func (p *PublicIP) overrideWith(other PublicIP) {
+// OverrideWith overrides fields of the receiver
+// settings object with any field set in the other
+// settings.
+func (p *PublicIP) OverrideWith(other PublicIP) {
 	p.Period = gosettings.OverrideWithPointer(p.Period, other.Period)
 	p.IPFilepath = gosettings.OverrideWithPointer(p.IPFilepath, other.IPFilepath)
+	p.APIs = gosettings.OverrideWithSlice(p.APIs, other.APIs)
+	p.IPInfoToken = gosettings.OverrideWithPointer(p.IPInfoToken, other.IPInfoToken)
+	p.CustomAPI.URL = gosettings.OverrideWithPointer(p.CustomAPI.URL, other.CustomAPI.URL)
+	if other.CustomAPI.Fields != nil {
+		p.CustomAPI.Fields = helpers.CopyMap(other.CustomAPI.Fields)
+	}
+	p.Exit.Countries = gosettings.OverrideWithSlice(p.Exit.Countries, other.Exit.Countries)
+	p.Exit.Organizations = gosettings.OverrideWithSlice(p.Exit.Organizations, other.Exit.Organizations)
+	p.Exit.Action = gosettings.OverrideWithPointer(p.Exit.Action, other.Exit.Action)
 }
 
 func (p *PublicIP) setDefaults() {
 	const defaultPeriod = 12 * time.Hour
 	p.Period = gosettings.DefaultPointer(p.Period, defaultPeriod)
 	p.IPFilepath = gosettings.DefaultPointer(p.IPFilepath, "/tmp/gluetun/ip")
+	p.APIs = gosettings.DefaultSlice(p.APIs, []string{api.IPInfo})
+	p.IPInfoToken = gosettings.DefaultPointer(p.IPInfoToken, "")
+	p.CustomAPI.URL = gosettings.DefaultPointer(p.CustomAPI.URL, "")
+	p.Exit.Action = gosettings.DefaultPointer(p.Exit.Action, ExitActionWarn)
 }
 
 func (p PublicIP) String() string {
@@ -85,5 +216,38 @@ func (p PublicIP) toLinesNode() (node *gotree.Node) {
 		node.Appendf("IP file path: %s", *p.IPFilepath)
 	}
 
+	if len(p.APIs) > 1 || p.APIs[0] != api.IPInfo {
+		node.Appendf("APIs: %s", strings.Join(p.APIs, ", "))
+	}
+
+	if *p.IPInfoToken != "" {
+		node.Appendf("IPInfo token: %s", gosettings.ObfuscateKey(*p.IPInfoToken))
+	}
+
+	if *p.CustomAPI.URL != "" {
+		customNode := node.Appendf("Custom API:")
+		customNode.Appendf("URL: %s", *p.CustomAPI.URL)
+		fieldNames := make([]string, 0, len(p.CustomAPI.Fields))
+		for fieldName := range p.CustomAPI.Fields {
+			fieldNames = append(fieldNames, fieldName)
+		}
+		sort.Strings(fieldNames)
+		fieldsNode := customNode.Appendf("Fields:")
+		for _, fieldName := range fieldNames {
+			fieldsNode.Appendf("%s: %s", fieldName, p.CustomAPI.Fields[fieldName])
+		}
+	}
+
+	if len(p.Exit.Countries) > 0 || len(p.Exit.Organizations) > 0 {
+		exitNode := node.Appendf("Exit enforcement:")
+		if len(p.Exit.Countries) > 0 {
+			exitNode.Appendf("Allowed countries: %s", strings.Join(p.Exit.Countries, ", "))
+		}
+		if len(p.Exit.Organizations) > 0 {
+			exitNode.Appendf("Allowed organizations: %s", strings.Join(p.Exit.Organizations, ", "))
+		}
+		exitNode.Appendf("Action on mismatch: %s", *p.Exit.Action)
+	}
+
 	return node
 }
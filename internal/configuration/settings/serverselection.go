@@ -57,6 +57,10 @@ type ServerSelection struct { //nolint:maligned
 	// MultiHopOnly is true if VPN servers that are not multihop
 	// should be filtered. This is used with Surfshark.
 	MultiHopOnly *bool
+	// ReachableOnly is true if VPN servers found unreachable
+	// by the updater probing servers should be filtered.
+	// Servers not probed or with an inconclusive probe are kept.
+	ReachableOnly *bool
 
 	// OpenVPN contains settings to select OpenVPN servers
 	// and the final connection.
@@ -207,22 +211,23 @@ func validateServerFilters(settings ServerSelection, filterChoices models.Filter
 
 func (ss *ServerSelection) copy() (copied ServerSelection) {
 	return ServerSelection{
-		VPN:          ss.VPN,
-		TargetIP:     ss.TargetIP,
-		Countries:    gosettings.CopySlice(ss.Countries),
-		Regions:      gosettings.CopySlice(ss.Regions),
-		Cities:       gosettings.CopySlice(ss.Cities),
-		ISPs:         gosettings.CopySlice(ss.ISPs),
-		Hostnames:    gosettings.CopySlice(ss.Hostnames),
-		Names:        gosettings.CopySlice(ss.Names),
-		Numbers:      gosettings.CopySlice(ss.Numbers),
-		OwnedOnly:    gosettings.CopyPointer(ss.OwnedOnly),
-		FreeOnly:     gosettings.CopyPointer(ss.FreeOnly),
-		PremiumOnly:  gosettings.CopyPointer(ss.PremiumOnly),
-		StreamOnly:   gosettings.CopyPointer(ss.StreamOnly),
-		MultiHopOnly: gosettings.CopyPointer(ss.MultiHopOnly),
-		OpenVPN:      ss.OpenVPN.copy(),
-		Wireguard:    ss.Wireguard.copy(),
+		VPN:           ss.VPN,
+		TargetIP:      ss.TargetIP,
+		Countries:     gosettings.CopySlice(ss.Countries),
+		Regions:       gosettings.CopySlice(ss.Regions),
+		Cities:        gosettings.CopySlice(ss.Cities),
+		ISPs:          gosettings.CopySlice(ss.ISPs),
+		Hostnames:     gosettings.CopySlice(ss.Hostnames),
+		Names:         gosettings.CopySlice(ss.Names),
+		Numbers:       gosettings.CopySlice(ss.Numbers),
+		OwnedOnly:     gosettings.CopyPointer(ss.OwnedOnly),
+		FreeOnly:      gosettings.CopyPointer(ss.FreeOnly),
+		PremiumOnly:   gosettings.CopyPointer(ss.PremiumOnly),
+		StreamOnly:    gosettings.CopyPointer(ss.StreamOnly),
+		MultiHopOnly:  gosettings.CopyPointer(ss.MultiHopOnly),
+		ReachableOnly: gosettings.CopyPointer(ss.ReachableOnly),
+		OpenVPN:       ss.OpenVPN.copy(),
+		Wireguard:     ss.Wireguard.copy(),
 	}
 }
 
@@ -241,6 +246,7 @@ func (ss *ServerSelection) mergeWith(other ServerSelection) {
 	ss.PremiumOnly = gosettings.MergeWithPointer(ss.PremiumOnly, other.PremiumOnly)
 	ss.StreamOnly = gosettings.MergeWithPointer(ss.StreamOnly, other.StreamOnly)
 	ss.MultiHopOnly = gosettings.MergeWithPointer(ss.MultiHopOnly, other.MultiHopOnly)
+	ss.ReachableOnly = gosettings.MergeWithPointer(ss.ReachableOnly, other.ReachableOnly)
 
 	ss.OpenVPN.mergeWith(other.OpenVPN)
 	ss.Wireguard.mergeWith(other.Wireguard)
@@ -261,6 +267,7 @@ func (ss *ServerSelection) overrideWith(other ServerSelection) {
 	ss.PremiumOnly = gosettings.OverrideWithPointer(ss.PremiumOnly, other.PremiumOnly)
 	ss.StreamOnly = gosettings.OverrideWithPointer(ss.StreamOnly, other.StreamOnly)
 	ss.MultiHopOnly = gosettings.OverrideWithPointer(ss.MultiHopOnly, other.MultiHopOnly)
+	ss.ReachableOnly = gosettings.OverrideWithPointer(ss.ReachableOnly, other.ReachableOnly)
 	ss.OpenVPN.overrideWith(other.OpenVPN)
 	ss.Wireguard.overrideWith(other.Wireguard)
 }
@@ -273,6 +280,7 @@ func (ss *ServerSelection) setDefaults(vpnProvider string) {
 	ss.PremiumOnly = gosettings.DefaultPointer(ss.PremiumOnly, false)
 	ss.StreamOnly = gosettings.DefaultPointer(ss.StreamOnly, false)
 	ss.MultiHopOnly = gosettings.DefaultPointer(ss.MultiHopOnly, false)
+	ss.ReachableOnly = gosettings.DefaultPointer(ss.ReachableOnly, false)
 	ss.OpenVPN.setDefaults(vpnProvider)
 	ss.Wireguard.setDefaults()
 }
@@ -339,6 +347,10 @@ func (ss ServerSelection) toLinesNode() (node *gotree.Node) {
 		node.Appendf("Multi-hop only servers: yes")
 	}
 
+	if *ss.ReachableOnly {
+		node.Appendf("Reachable only servers: yes")
+	}
+
 	if ss.VPN == vpn.OpenVPN {
 		node.AppendNode(ss.OpenVPN.toLinesNode())
 	} else {
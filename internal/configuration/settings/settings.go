@@ -2,6 +2,7 @@ package settings
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/qdm12/gluetun/internal/configuration/settings/helpers"
 	"github.com/qdm12/gluetun/internal/constants/providers"
@@ -12,19 +13,21 @@ import (
 )
 
 type Settings struct {
-	ControlServer ControlServer
-	DNS           DNS
-	Firewall      Firewall
-	Health        Health
-	HTTPProxy     HTTPProxy
-	Log           Log
-	PublicIP      PublicIP
-	Shadowsocks   Shadowsocks
-	System        System
-	Updater       Updater
-	Version       Version
-	VPN           VPN
-	Pprof         pprof.Settings
+	ControlServer   ControlServer
+	DNS             DNS
+	Firewall        Firewall
+	Health          Health
+	HTTPProxy       HTTPProxy
+	Log             Log
+	PublicIP        PublicIP
+	Shadowsocks     Shadowsocks
+	Shaping         Shaping
+	System          System
+	Updater         Updater
+	Version         Version
+	VPN             VPN
+	WireguardServer WireguardServer
+	Pprof           pprof.Settings
 }
 
 type Storage interface {
@@ -37,24 +40,36 @@ type Storage interface {
 func (s *Settings) Validate(storage Storage, ipv6Supported bool) (err error) {
 	nameToValidation := map[string]func() error{
 		"control server":  s.ControlServer.validate,
-		"dns":             s.DNS.validate,
+		"dns":             s.DNS.Validate,
 		"firewall":        s.Firewall.validate,
 		"health":          s.Health.Validate,
-		"http proxy":      s.HTTPProxy.validate,
+		"http proxy":      s.HTTPProxy.Validate,
 		"log":             s.Log.validate,
-		"public ip check": s.PublicIP.validate,
-		"shadowsocks":     s.Shadowsocks.validate,
+		"public ip check": s.PublicIP.Validate,
+		"shadowsocks":     s.Shadowsocks.Validate,
+		"shaping":         s.Shaping.Validate,
 		"system":          s.System.validate,
 		"updater":         s.Updater.Validate,
 		"version":         s.Version.validate,
+		"wireguard server": func() error {
+			return s.WireguardServer.validate(s.VPN.Wireguard.Interface)
+		},
 		// Pprof validation done in pprof constructor
 		"VPN": func() error {
 			return s.VPN.Validate(storage, ipv6Supported)
 		},
 	}
 
-	for name, validation := range nameToValidation {
-		err = validation()
+	// Validate in a deterministic order, for the error returned
+	// to be the same one if multiple settings are not valid.
+	names := make([]string, 0, len(nameToValidation))
+	for name := range nameToValidation {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		err = nameToValidation[name]()
 		if err != nil {
 			return fmt.Errorf("%s settings: %w", name, err)
 		}
@@ -65,19 +80,21 @@ func (s *Settings) Validate(storage Storage, ipv6Supported bool) (err error) {
 
 func (s *Settings) copy() (copied Settings) {
 	return Settings{
-		ControlServer: s.ControlServer.copy(),
-		DNS:           s.DNS.Copy(),
-		Firewall:      s.Firewall.copy(),
-		Health:        s.Health.copy(),
-		HTTPProxy:     s.HTTPProxy.copy(),
-		Log:           s.Log.copy(),
-		PublicIP:      s.PublicIP.copy(),
-		Shadowsocks:   s.Shadowsocks.copy(),
-		System:        s.System.copy(),
-		Updater:       s.Updater.copy(),
-		Version:       s.Version.copy(),
-		VPN:           s.VPN.Copy(),
-		Pprof:         s.Pprof.Copy(),
+		ControlServer:   s.ControlServer.copy(),
+		DNS:             s.DNS.Copy(),
+		Firewall:        s.Firewall.copy(),
+		Health:          s.Health.copy(),
+		HTTPProxy:       s.HTTPProxy.copy(),
+		Log:             s.Log.copy(),
+		PublicIP:        s.PublicIP.copy(),
+		Shadowsocks:     s.Shadowsocks.copy(),
+		Shaping:         s.Shaping.copy(),
+		System:          s.System.copy(),
+		Updater:         s.Updater.copy(),
+		Version:         s.Version.copy(),
+		VPN:             s.VPN.Copy(),
+		WireguardServer: s.WireguardServer.copy(),
+		Pprof:           s.Pprof.Copy(),
 	}
 }
 
@@ -90,10 +107,12 @@ func (s *Settings) MergeWith(other Settings) {
 	s.Log.mergeWith(other.Log)
 	s.PublicIP.mergeWith(other.PublicIP)
 	s.Shadowsocks.mergeWith(other.Shadowsocks)
+	s.Shaping.mergeWith(other.Shaping)
 	s.System.mergeWith(other.System)
 	s.Updater.mergeWith(other.Updater)
 	s.Version.mergeWith(other.Version)
 	s.VPN.mergeWith(other.VPN)
+	s.WireguardServer.mergeWith(other.WireguardServer)
 	s.Pprof.MergeWith(other.Pprof)
 }
 
@@ -101,17 +120,19 @@ func (s *Settings) OverrideWith(other Settings,
 	storage Storage, ipv6Supported bool) (err error) {
 	patchedSettings := s.copy()
 	patchedSettings.ControlServer.overrideWith(other.ControlServer)
-	patchedSettings.DNS.overrideWith(other.DNS)
+	patchedSettings.DNS.OverrideWith(other.DNS)
 	patchedSettings.Firewall.overrideWith(other.Firewall)
 	patchedSettings.Health.OverrideWith(other.Health)
-	patchedSettings.HTTPProxy.overrideWith(other.HTTPProxy)
+	patchedSettings.HTTPProxy.OverrideWith(other.HTTPProxy)
 	patchedSettings.Log.overrideWith(other.Log)
-	patchedSettings.PublicIP.overrideWith(other.PublicIP)
-	patchedSettings.Shadowsocks.overrideWith(other.Shadowsocks)
+	patchedSettings.PublicIP.OverrideWith(other.PublicIP)
+	patchedSettings.Shadowsocks.OverrideWith(other.Shadowsocks)
+	patchedSettings.Shaping.OverrideWith(other.Shaping)
 	patchedSettings.System.overrideWith(other.System)
-	patchedSettings.Updater.overrideWith(other.Updater)
+	patchedSettings.Updater.OverrideWith(other.Updater)
 	patchedSettings.Version.overrideWith(other.Version)
 	patchedSettings.VPN.OverrideWith(other.VPN)
+	patchedSettings.WireguardServer.overrideWith(other.WireguardServer)
 	patchedSettings.Pprof.OverrideWith(other.Pprof)
 	err = patchedSettings.Validate(storage, ipv6Supported)
 	if err != nil {
@@ -130,9 +151,11 @@ func (s *Settings) SetDefaults() {
 	s.Log.setDefaults()
 	s.PublicIP.setDefaults()
 	s.Shadowsocks.setDefaults()
+	s.Shaping.setDefaults()
 	s.System.setDefaults()
 	s.Version.setDefaults()
-	s.VPN.setDefaults()
+	s.VPN.SetDefaults()
+	s.WireguardServer.setDefaults()
 	s.Updater.SetDefaults(*s.VPN.Provider.Name)
 	s.Pprof.SetDefaults()
 }
@@ -151,6 +174,8 @@ func (s Settings) toLinesNode() (node *gotree.Node) {
 	node.AppendNode(s.Health.toLinesNode())
 	node.AppendNode(s.Shadowsocks.toLinesNode())
 	node.AppendNode(s.HTTPProxy.toLinesNode())
+	node.AppendNode(s.Shaping.toLinesNode())
+	node.AppendNode(s.WireguardServer.toLinesNode())
 	node.AppendNode(s.ControlServer.toLinesNode())
 	node.AppendNode(s.System.toLinesNode())
 	node.AppendNode(s.PublicIP.toLinesNode())
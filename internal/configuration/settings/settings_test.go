@@ -62,10 +62,15 @@ func Test_Settings_String(t *testing.T) {
 ├── Firewall settings:
 |   └── Enabled: yes
 ├── Log settings:
-|   └── Log level: INFO
+|   ├── Log level: INFO
+|   └── Access log: disabled
 ├── Health settings:
 |   ├── Server listening address: 127.0.0.1:9999
 |   ├── Target address: cloudflare.com:443
+|   ├── Probes:
+|   |   ├── Enabled: tcp
+|   |   ├── Quorum: 1
+|   |   └── TCP dial timeout: 3s
 |   ├── Duration to wait after success: 5s
 |   ├── Read header timeout: 100ms
 |   ├── Read timeout: 500ms
@@ -76,6 +81,10 @@ func Test_Settings_String(t *testing.T) {
 |   └── Enabled: no
 ├── HTTP proxy settings:
 |   └── Enabled: no
+├── Bandwidth shaping settings:
+|   └── Limits: none
+├── Wireguard server settings:
+|   └── Enabled: no
 ├── Control server settings:
 |   ├── Listening address: :8000
 |   └── Logging: yes
@@ -1,9 +1,14 @@
 package settings
 
 import (
+	"fmt"
+	"strings"
+
+	"github.com/qdm12/gluetun/internal/shadowsocks/relay"
 	"github.com/qdm12/gosettings"
 	"github.com/qdm12/gotree"
 	"github.com/qdm12/ss-server/pkg/tcpudp"
+	"github.com/qdm12/ss-server/pkg/validation"
 )
 
 // Shadowsocks contains settings to configure the Shadowsocks server.
@@ -13,16 +18,145 @@ type Shadowsocks struct {
 	Enabled *bool
 	// Settings are settings for the TCP+UDP server.
 	tcpudp.Settings
+	// Users are additional users, each with their own password,
+	// using the same cipher as the main password. The main password
+	// is only accepted if it is set or if there is no user.
+	// With the 2022 edition AES ciphers and the main password set,
+	// clients of users can be configured with the password
+	// "mainpassword:userpassword" to send identity headers.
+	Users []ShadowsocksUser
 }
 
-func (s Shadowsocks) validate() (err error) {
-	return s.Settings.Validate()
+// ShadowsocksUser is a user of the Shadowsocks server.
+type ShadowsocksUser struct {
+	// Name is the unique name of the user.
+	Name string
+	// Password is the user password, which must be a base64
+	// encoded key for the 2022 edition ciphers.
+	Password string
+	// Address is the listening address dedicated to the user.
+	// It can be the empty string for the user to share the main
+	// listening address, where users are told apart by their key.
+	Address string
+}
+
+// Validate validates the Shadowsocks settings.
+func (s Shadowsocks) Validate() (err error) {
+	// The ciphers are validated with the embedded relay, since
+	// it supports more ciphers than the tcpudp settings validation.
+	err = validation.ValidateAddress(s.Address)
+	if err != nil {
+		return err
+	}
+
+	for _, server := range [...]struct {
+		name     string
+		address  string
+		cipher   string
+		password *string
+	}{
+		{"TCP", s.TCP.Address, s.TCP.CipherName, s.TCP.Password},
+		{"UDP", s.UDP.Address, s.UDP.CipherName, s.UDP.Password},
+	} {
+		err = validation.ValidateAddress(server.address)
+		if err != nil {
+			return fmt.Errorf("%s server: %w", server.name, err)
+		}
+		if *server.password == "" && len(s.Users) > 0 {
+			// main password is not used
+			continue
+		}
+		err = relay.ValidateCipher(server.cipher, *server.password)
+		if err != nil {
+			return fmt.Errorf("%s server: %w", server.name, err)
+		}
+	}
+
+	names := make(map[string]struct{}, len(s.Users))
+	for _, user := range s.Users {
+		err = user.validate(s.TCP.CipherName, s.UDP.CipherName)
+		if err != nil {
+			return fmt.Errorf("user %s: %w", user.Name, err)
+		}
+
+		if _, exists := names[user.Name]; exists {
+			return fmt.Errorf("%w: %s", ErrShadowsocksUserDuplicate, user.Name)
+		}
+		names[user.Name] = struct{}{}
+	}
+
+	return nil
+}
+
+func (u ShadowsocksUser) validate(tcpCipher, udpCipher string) (err error) {
+	switch {
+	case !regexpPeerName.MatchString(u.Name):
+		return fmt.Errorf("%w: name '%s' does not match regex '%s'",
+			ErrShadowsocksUserNotValid, u.Name, regexpPeerName)
+	case u.Name == relay.DefaultUserName:
+		return fmt.Errorf("%w: name '%s' is reserved for the main password",
+			ErrShadowsocksUserNotValid, u.Name)
+	case u.Password == "":
+		return fmt.Errorf("%w: password is empty", ErrShadowsocksUserNotValid)
+	}
+
+	for _, cipher := range [...]string{tcpCipher, udpCipher} {
+		err = relay.ValidateCipher(cipher, u.Password)
+		if err != nil {
+			return err
+		}
+	}
+
+	if u.Address != "" {
+		err = validation.ValidateAddress(u.Address)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ParseShadowsocksUsers parses users separated by commas
+// or new lines, each in the format name:password[:port].
+func ParseShadowsocksUsers(s string) (users []ShadowsocksUser, err error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r'
+	})
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		parts := strings.Split(field, ":")
+		const minParts, maxParts = 2, 3
+		if len(parts) < minParts || len(parts) > maxParts {
+			return nil, fmt.Errorf("%w: %q must be in the format name:password[:port]",
+				ErrShadowsocksUserNotValid, field)
+		}
+		user := ShadowsocksUser{Name: parts[0], Password: parts[1]}
+		if len(parts) == maxParts {
+			user.Address = ":" + parts[2]
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// RelayUsers returns the users settings for the Shadowsocks relay.
+func (s Shadowsocks) RelayUsers() (users []relay.UserSettings) {
+	users = make([]relay.UserSettings, len(s.Users))
+	for i, user := range s.Users {
+		users[i] = relay.UserSettings(user)
+	}
+	return users
 }
 
 func (s *Shadowsocks) copy() (copied Shadowsocks) {
 	return Shadowsocks{
 		Enabled:  gosettings.CopyPointer(s.Enabled),
 		Settings: s.Settings.Copy(),
+		Users:    gosettings.CopySlice(s.Users),
 	}
 }
 
@@ -31,14 +165,16 @@ func (s *Shadowsocks) copy() (copied Shadowsocks) {
 func (s *Shadowsocks) mergeWith(other Shadowsocks) {
 	s.Enabled = gosettings.MergeWithPointer(s.Enabled, other.Enabled)
 	s.Settings.MergeWith(other.Settings)
+	s.Users = gosettings.MergeWithSlice(s.Users, other.Users)
 }
 
-// overrideWith overrides fields of the receiver
+// OverrideWith overrides fields of the receiver
 // settings object with any field set in the other
 // settings.
-func (s *Shadowsocks) overrideWith(other Shadowsocks) {
+func (s *Shadowsocks) OverrideWith(other Shadowsocks) {
 	s.Enabled = gosettings.OverrideWithPointer(s.Enabled, other.Enabled)
 	s.Settings.OverrideWith(other.Settings)
+	s.Users = gosettings.OverrideWithSlice(s.Users, other.Users)
 }
 
 func (s *Shadowsocks) setDefaults() {
@@ -63,6 +199,16 @@ func (s Shadowsocks) toLinesNode() (node *gotree.Node) {
 	node.Appendf("Cipher: %s", s.CipherName)
 	node.Appendf("Password: %s", gosettings.ObfuscateKey(*s.Password))
 	node.Appendf("Log addresses: %s", gosettings.BoolToYesNo(s.LogAddresses))
+	if len(s.Users) > 0 {
+		usersNode := node.Appendf("Users:")
+		for _, user := range s.Users {
+			userNode := usersNode.Appendf("%s:", user.Name)
+			userNode.Appendf("Password: %s", gosettings.ObfuscateKey(user.Password))
+			if user.Address != "" {
+				userNode.Appendf("Listening address: %s", user.Address)
+			}
+		}
+	}
 
 	return node
 }
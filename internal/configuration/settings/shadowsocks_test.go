@@ -0,0 +1,123 @@
+package settings
+
+import (
+	"testing"
+
+	"github.com/qdm12/gluetun/internal/shadowsocks/relay"
+	"github.com/qdm12/ss-server/pkg/tcpudp"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseShadowsocksUsers(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		s          string
+		users      []ShadowsocksUser
+		errWrapped error
+		errMessage string
+	}{
+		"empty": {},
+		"comma_separated": {
+			s: "alice:password1,bob:password2:8389",
+			users: []ShadowsocksUser{
+				{Name: "alice", Password: "password1"},
+				{Name: "bob", Password: "password2", Address: ":8389"},
+			},
+		},
+		"lines": {
+			s: "alice:password1\r\n\nbob:password2\n",
+			users: []ShadowsocksUser{
+				{Name: "alice", Password: "password1"},
+				{Name: "bob", Password: "password2"},
+			},
+		},
+		"malformed": {
+			s:          "alice",
+			errWrapped: ErrShadowsocksUserNotValid,
+			errMessage: `shadowsocks user is not valid: "alice" must be in the format name:password[:port]`,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			users, err := ParseShadowsocksUsers(testCase.s)
+
+			assert.Equal(t, testCase.users, users)
+			assert.ErrorIs(t, err, testCase.errWrapped)
+			if testCase.errWrapped != nil {
+				assert.EqualError(t, err, testCase.errMessage)
+			}
+		})
+	}
+}
+
+func Test_Shadowsocks_Validate(t *testing.T) {
+	t.Parallel()
+
+	const key2022 = "AAAAAAAAAAAAAAAAAAAAAA==" // 16 bytes
+
+	testCases := map[string]struct {
+		settings   Shadowsocks
+		errWrapped error
+		errMessage string
+	}{
+		"default": {},
+		"2022_cipher": {
+			settings: Shadowsocks{
+				Settings: tcpudp.Settings{CipherName: relay.Blake3AES128GCM},
+				Users:    []ShadowsocksUser{{Name: "alice", Password: key2022}},
+			},
+		},
+		"2022_cipher_password_not_valid": {
+			settings: Shadowsocks{
+				Settings: tcpudp.Settings{
+					CipherName: relay.Blake3AES128GCM,
+					Password:   stringPtr("password"),
+				},
+			},
+			errWrapped: relay.ErrKeyNotValid,
+			errMessage: "TCP server: key is not valid: 6 bytes instead of 16",
+		},
+		"duplicate_user": {
+			settings: Shadowsocks{
+				Settings: tcpudp.Settings{CipherName: relay.Blake3AES128GCM},
+				Users: []ShadowsocksUser{
+					{Name: "alice", Password: key2022},
+					{Name: "alice", Password: key2022},
+				},
+			},
+			errWrapped: ErrShadowsocksUserDuplicate,
+			errMessage: "shadowsocks user name is used more than once: alice",
+		},
+		"reserved_user_name": {
+			settings: Shadowsocks{
+				Settings: tcpudp.Settings{CipherName: relay.Blake3AES128GCM},
+				Users:    []ShadowsocksUser{{Name: "default", Password: key2022}},
+			},
+			errWrapped: ErrShadowsocksUserNotValid,
+			errMessage: "user default: shadowsocks user is not valid: " +
+				"name 'default' is reserved for the main password",
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			settings := testCase.settings
+			settings.setDefaults()
+
+			err := settings.Validate()
+
+			assert.ErrorIs(t, err, testCase.errWrapped)
+			if testCase.errWrapped != nil {
+				assert.EqualError(t, err, testCase.errMessage)
+			}
+		})
+	}
+}
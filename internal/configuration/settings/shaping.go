@@ -0,0 +1,112 @@
+package settings
+
+import (
+	"fmt"
+
+	"github.com/qdm12/gluetun/internal/ratelimit"
+	"github.com/qdm12/gosettings"
+	"github.com/qdm12/gotree"
+)
+
+// Shaping contains settings to limit the bandwidth used by
+// the clients of the HTTP proxy and Shadowsocks servers.
+// Each limit is in bytes per second, where 0 means no limit.
+type Shaping struct {
+	// Upload is the limit for the bytes sent by all clients
+	// combined. It cannot be nil in the internal state.
+	Upload *uint64
+	// Download is the limit for the bytes received by all
+	// clients combined. It cannot be nil in the internal state.
+	Download *uint64
+	// ClientUpload is the limit for the bytes sent by each
+	// client IP address. It cannot be nil in the internal state.
+	ClientUpload *uint64
+	// ClientDownload is the limit for the bytes received by each
+	// client IP address. It cannot be nil in the internal state.
+	ClientDownload *uint64
+}
+
+func (s Shaping) Validate() (err error) {
+	return nil
+}
+
+func (s *Shaping) copy() (copied Shaping) {
+	return Shaping{
+		Upload:         gosettings.CopyPointer(s.Upload),
+		Download:       gosettings.CopyPointer(s.Download),
+		ClientUpload:   gosettings.CopyPointer(s.ClientUpload),
+		ClientDownload: gosettings.CopyPointer(s.ClientDownload),
+	}
+}
+
+// mergeWith merges the other settings into any
+// unset field of the receiver settings object.
+func (s *Shaping) mergeWith(other Shaping) {
+	s.Upload = gosettings.MergeWithPointer(s.Upload, other.Upload)
+	s.Download = gosettings.MergeWithPointer(s.Download, other.Download)
+	s.ClientUpload = gosettings.MergeWithPointer(s.ClientUpload, other.ClientUpload)
+	s.ClientDownload = gosettings.MergeWithPointer(s.ClientDownload, other.ClientDownload)
+}
+
+// OverrideWith overrides fields of the receiver
+// settings object with any field set in the other
+// settings.
+func (s *Shaping) OverrideWith(other Shaping) {
+	s.Upload = gosettings.OverrideWithPointer(s.Upload, other.Upload)
+	s.Download = gosettings.OverrideWithPointer(s.Download, other.Download)
+	s.ClientUpload = gosettings.OverrideWithPointer(s.ClientUpload, other.ClientUpload)
+	s.ClientDownload = gosettings.OverrideWithPointer(s.ClientDownload, other.ClientDownload)
+}
+
+func (s *Shaping) setDefaults() {
+	s.Upload = gosettings.DefaultPointer(s.Upload, 0)
+	s.Download = gosettings.DefaultPointer(s.Download, 0)
+	s.ClientUpload = gosettings.DefaultPointer(s.ClientUpload, 0)
+	s.ClientDownload = gosettings.DefaultPointer(s.ClientDownload, 0)
+}
+
+// Limits returns the bandwidth limits of the settings,
+// which must have their defaults set.
+func (s Shaping) Limits() (limits ratelimit.Limits) {
+	return ratelimit.Limits{
+		Upload:         *s.Upload,
+		Download:       *s.Download,
+		ClientUpload:   *s.ClientUpload,
+		ClientDownload: *s.ClientDownload,
+	}
+}
+
+// ShapingFromLimits returns the shaping settings
+// corresponding to the bandwidth limits given.
+func ShapingFromLimits(limits ratelimit.Limits) (shaping Shaping) {
+	return Shaping{
+		Upload:         &limits.Upload,
+		Download:       &limits.Download,
+		ClientUpload:   &limits.ClientUpload,
+		ClientDownload: &limits.ClientDownload,
+	}
+}
+
+func (s Shaping) String() string {
+	return s.toLinesNode().String()
+}
+
+func (s Shaping) toLinesNode() (node *gotree.Node) {
+	node = gotree.New("Bandwidth shaping settings:")
+	if s.Limits() == (ratelimit.Limits{}) {
+		node.Appendf("Limits: none")
+		return node
+	}
+	node.Appendf("Upload limit: %s", rateToString(*s.Upload))
+	node.Appendf("Download limit: %s", rateToString(*s.Download))
+	node.Appendf("Upload limit per client: %s", rateToString(*s.ClientUpload))
+	node.Appendf("Download limit per client: %s", rateToString(*s.ClientDownload))
+	return node
+}
+
+func rateToString(bytesPerSecond uint64) string {
+	if bytesPerSecond == 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%d bytes/s", bytesPerSecond)
+}
@@ -0,0 +1,210 @@
+package settings
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+
+	"github.com/qdm12/gluetun/internal/constants/vpn"
+	"github.com/qdm12/gosettings"
+	"github.com/qdm12/gosettings/validate"
+	"github.com/qdm12/gotree"
+)
+
+// Tunnel contains settings for an additional named OpenVPN or
+// Wireguard tunnel, running alongside the main VPN connection.
+// Only traffic matching its policy routing rules goes through it.
+type Tunnel struct {
+	// Name is the unique name of the tunnel.
+	// It cannot be the empty string in the internal state.
+	Name string
+	// Type is the tunnel VPN type and can only be
+	// 'openvpn' or 'wireguard'. It defaults to 'wireguard'
+	// and cannot be the empty string in the internal state.
+	Type string
+	// FirewallMark is the firewall mark used for the tunnel
+	// encrypted packets and as the routing table number for
+	// the tunnel. It defaults to 51821 plus the tunnel index,
+	// and cannot be zero in the internal state.
+	FirewallMark int
+	// Provider contains the VPN provider and server selection
+	// settings of the tunnel. The provider name defaults to the
+	// main VPN provider name, and port forwarding cannot be enabled.
+	// The server selection VPN type is always the tunnel type.
+	Provider Provider
+	// OpenVPN contains the OpenVPN settings of the tunnel, used
+	// if Type is 'openvpn'. Its interface defaults to "tun_"
+	// followed by the tunnel name, and authentication challenges
+	// are not supported.
+	OpenVPN OpenVPN
+	// Wireguard contains the Wireguard settings of the tunnel, used
+	// if Type is 'wireguard'. Its interface defaults to "tun_"
+	// followed by the tunnel name.
+	Wireguard Wireguard
+	// Sources are source subnets for which traffic
+	// is routed through the tunnel.
+	Sources []netip.Prefix
+	// Destinations are destination subnets for which
+	// traffic is routed through the tunnel.
+	Destinations []netip.Prefix
+}
+
+var regexpTunnelName = regexp.MustCompile(`^[a-z0-9]{1,11}$`)
+
+func validateTunnels(tunnels []Tunnel, mainInterface string,
+	storage Storage, ipv6Supported bool) (err error) {
+	names := make(map[string]struct{}, len(tunnels))
+	interfaces := map[string]struct{}{mainInterface: {}}
+	marks := make(map[int]struct{}, len(tunnels))
+	for i := range tunnels {
+		tunnel := &tunnels[i]
+		err = tunnel.validate(storage, ipv6Supported)
+		if err != nil {
+			return fmt.Errorf("tunnel %s: %w", tunnel.Name, err)
+		}
+
+		if _, exists := names[tunnel.Name]; exists {
+			return fmt.Errorf("%w: %s", ErrTunnelNameDuplicate, tunnel.Name)
+		}
+		names[tunnel.Name] = struct{}{}
+
+		tunnelInterface := tunnel.Interface()
+		if _, exists := interfaces[tunnelInterface]; exists {
+			return fmt.Errorf("%w: tunnel %s: %s",
+				ErrTunnelInterfaceDuplicate, tunnel.Name, tunnelInterface)
+		}
+		interfaces[tunnelInterface] = struct{}{}
+
+		if _, exists := marks[tunnel.FirewallMark]; exists {
+			return fmt.Errorf("%w: tunnel %s: %d",
+				ErrTunnelFirewallMarkDuplicate, tunnel.Name, tunnel.FirewallMark)
+		}
+		marks[tunnel.FirewallMark] = struct{}{}
+	}
+	return nil
+}
+
+func (t *Tunnel) validate(storage Storage, ipv6Supported bool) (err error) {
+	if !regexpTunnelName.MatchString(t.Name) {
+		return fmt.Errorf("%w: %q must be 1 to 11 lowercase letters or digits",
+			ErrTunnelNameNotValid, t.Name)
+	}
+
+	validVPNTypes := []string{vpn.OpenVPN, vpn.Wireguard}
+	if err = validate.IsOneOf(t.Type, validVPNTypes...); err != nil {
+		return fmt.Errorf("%w: %w", ErrVPNTypeNotValid, err)
+	}
+
+	err = t.Provider.validate(t.Type, storage)
+	if err != nil {
+		return fmt.Errorf("provider settings: %w", err)
+	}
+
+	if *t.Provider.PortForwarding.Enabled {
+		return fmt.Errorf("%w", ErrTunnelPortForwardingEnabled)
+	}
+
+	if t.Type == vpn.OpenVPN {
+		err = t.OpenVPN.validate(*t.Provider.Name)
+		if err != nil {
+			return fmt.Errorf("OpenVPN settings: %w", err)
+		}
+		if *t.OpenVPN.Challenge {
+			return fmt.Errorf("OpenVPN settings: %w", ErrTunnelChallengeNotSupported)
+		}
+	} else {
+		err = t.Wireguard.validate(*t.Provider.Name, ipv6Supported)
+		if err != nil {
+			return fmt.Errorf("Wireguard settings: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Interface returns the network interface name of the tunnel,
+// depending on its VPN type.
+func (t Tunnel) Interface() string {
+	if t.Type == vpn.OpenVPN {
+		return t.OpenVPN.Interface
+	}
+	return t.Wireguard.Interface
+}
+
+func copyTunnels(tunnels []Tunnel) (copied []Tunnel) {
+	if tunnels == nil {
+		return nil
+	}
+	copied = make([]Tunnel, len(tunnels))
+	for i, tunnel := range tunnels {
+		copied[i] = tunnel.copy()
+	}
+	return copied
+}
+
+func (t *Tunnel) copy() (copied Tunnel) {
+	return Tunnel{
+		Name:         t.Name,
+		Type:         t.Type,
+		FirewallMark: t.FirewallMark,
+		Provider:     t.Provider.copy(),
+		OpenVPN:      t.OpenVPN.copy(),
+		Wireguard:    t.Wireguard.copy(),
+		Sources:      gosettings.CopySlice(t.Sources),
+		Destinations: gosettings.CopySlice(t.Destinations),
+	}
+}
+
+// OverrideWith overrides fields of the receiver settings object
+// with any field set in the other settings. The tunnel name is
+// never overridden since it identifies the tunnel.
+func (t *Tunnel) OverrideWith(other Tunnel) {
+	t.Type = gosettings.OverrideWithString(t.Type, other.Type)
+	t.FirewallMark = gosettings.OverrideWithNumber(t.FirewallMark, other.FirewallMark)
+	t.Provider.overrideWith(other.Provider)
+	t.Provider.ServerSelection.VPN = t.Type
+	t.OpenVPN.overrideWith(other.OpenVPN)
+	t.Wireguard.overrideWith(other.Wireguard)
+	t.Sources = gosettings.OverrideWithSlice(t.Sources, other.Sources)
+	t.Destinations = gosettings.OverrideWithSlice(t.Destinations, other.Destinations)
+}
+
+func (t *Tunnel) setDefaults(index int, mainProvider string) {
+	t.Type = gosettings.DefaultString(t.Type, vpn.Wireguard)
+	const firstFirewallMark = 51821
+	t.FirewallMark = gosettings.DefaultNumber(t.FirewallMark, firstFirewallMark+index)
+	t.Provider.Name = gosettings.DefaultPointer(t.Provider.Name, mainProvider)
+	t.Provider.ServerSelection.VPN = t.Type
+	t.Provider.setDefaults()
+	defaultInterface := "tun_" + t.Name
+	t.OpenVPN.Interface = gosettings.DefaultString(t.OpenVPN.Interface, defaultInterface)
+	t.OpenVPN.setDefaults(*t.Provider.Name)
+	t.Wireguard.Interface = gosettings.DefaultString(t.Wireguard.Interface, defaultInterface)
+	t.Wireguard.setDefaults()
+}
+
+func (t Tunnel) toLinesNode() (node *gotree.Node) {
+	node = gotree.New("Tunnel %s:", t.Name)
+	node.Appendf("Type: %s", t.Type)
+	node.Appendf("Firewall mark: %d", t.FirewallMark)
+	node.AppendNode(t.Provider.toLinesNode())
+
+	if t.Type == vpn.OpenVPN {
+		node.AppendNode(t.OpenVPN.toLinesNode())
+	} else {
+		node.AppendNode(t.Wireguard.toLinesNode())
+	}
+
+	appendPrefixes := func(title string, prefixes []netip.Prefix) {
+		if len(prefixes) == 0 {
+			return
+		}
+		prefixesNode := node.Appendf("%s:", title)
+		for _, prefix := range prefixes {
+			prefixesNode.Appendf(prefix.String())
+		}
+	}
+	appendPrefixes("Sources", t.Sources)
+	appendPrefixes("Destinations", t.Destinations)
+	return node
+}
@@ -0,0 +1,181 @@
+package settings
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/qdm12/gluetun/internal/constants/providers"
+	"github.com/qdm12/gluetun/internal/constants/vpn"
+	"github.com/qdm12/gluetun/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeStorage struct{}
+
+func (fakeStorage) GetFilterChoices(string) models.FilterChoices {
+	return models.FilterChoices{Countries: []string{"sweden"}}
+}
+
+func Test_validateTunnels(t *testing.T) {
+	t.Parallel()
+
+	const key = "aPjc9US5ICB30D1P4glR9tO7bkB2Ga+KZiFqnoypBHk="
+	makeTunnel := func(name string, index int) Tunnel {
+		tunnel := Tunnel{
+			Name: name,
+			Provider: Provider{
+				Name: stringPtr(providers.Custom),
+				ServerSelection: ServerSelection{
+					Wireguard: WireguardSelection{
+						EndpointIP:   netip.MustParseAddr("1.2.3.4"),
+						EndpointPort: uint16Ptr(51820),
+						PublicKey:    key,
+					},
+				},
+			},
+			Wireguard: Wireguard{
+				PrivateKey: stringPtr(key),
+				Addresses:  []netip.Prefix{netip.MustParsePrefix("10.64.0.2/32")},
+			},
+		}
+		tunnel.setDefaults(index, providers.Mullvad)
+		return tunnel
+	}
+
+	testCases := map[string]struct {
+		tunnels    []Tunnel
+		errWrapped error
+		errMessage string
+	}{
+		"no_tunnel": {},
+		"valid_tunnels": {
+			tunnels: []Tunnel{makeTunnel("us", 0), makeTunnel("uk", 1)},
+		},
+		"provider_selection": {
+			tunnels: []Tunnel{func() Tunnel {
+				tunnel := Tunnel{
+					Name: "se",
+					Provider: Provider{ServerSelection: ServerSelection{
+						Countries: []string{"sweden"},
+					}},
+					Wireguard: Wireguard{
+						PrivateKey: stringPtr(key),
+						Addresses:  []netip.Prefix{netip.MustParsePrefix("10.64.0.2/32")},
+					},
+				}
+				tunnel.setDefaults(0, providers.Mullvad)
+				return tunnel
+			}()},
+		},
+		"openvpn_tunnel": {
+			tunnels: []Tunnel{func() Tunnel {
+				tunnel := Tunnel{
+					Name:    "se",
+					Type:    vpn.OpenVPN,
+					OpenVPN: OpenVPN{User: stringPtr("user")},
+				}
+				tunnel.setDefaults(0, providers.Mullvad)
+				return tunnel
+			}()},
+		},
+		"invalid_name": {
+			tunnels:    []Tunnel{makeTunnel("US", 0)},
+			errWrapped: ErrTunnelNameNotValid,
+			errMessage: `tunnel US: tunnel name is not valid: "US" must be 1 to 11 lowercase letters or digits`,
+		},
+		"duplicate_name": {
+			tunnels:    []Tunnel{makeTunnel("us", 0), makeTunnel("us", 1)},
+			errWrapped: ErrTunnelNameDuplicate,
+			errMessage: "tunnel name is used more than once: us",
+		},
+		"main_interface": {
+			tunnels: []Tunnel{func() Tunnel {
+				tunnel := makeTunnel("us", 0)
+				tunnel.Wireguard.Interface = "tun0"
+				return tunnel
+			}()},
+			errWrapped: ErrTunnelInterfaceDuplicate,
+			errMessage: "tunnel interface name is used more than once: tunnel us: tun0",
+		},
+		"endpoint_missing": {
+			tunnels: []Tunnel{func() Tunnel {
+				tunnel := makeTunnel("us", 0)
+				tunnel.Provider.ServerSelection.Wireguard.EndpointIP = netip.IPv4Unspecified()
+				return tunnel
+			}()},
+			errWrapped: ErrWireguardEndpointIPNotSet,
+			errMessage: "tunnel us: provider settings: server selection: " +
+				"Wireguard server selection settings: endpoint IP is not set",
+		},
+		"port_forwarding": {
+			tunnels: []Tunnel{func() Tunnel {
+				tunnel := Tunnel{
+					Name: "pia",
+					Type: vpn.OpenVPN,
+					Provider: Provider{
+						Name:           stringPtr(providers.PrivateInternetAccess),
+						PortForwarding: PortForwarding{Enabled: boolPtr(true)},
+					},
+					OpenVPN: OpenVPN{
+						User:     stringPtr("user"),
+						Password: stringPtr("password"),
+					},
+				}
+				tunnel.setDefaults(0, providers.Mullvad)
+				return tunnel
+			}()},
+			errWrapped: ErrTunnelPortForwardingEnabled,
+			errMessage: "tunnel pia: port forwarding cannot be enabled for tunnels",
+		},
+		"openvpn_challenge": {
+			tunnels: []Tunnel{func() Tunnel {
+				tunnel := Tunnel{
+					Name: "se",
+					Type: vpn.OpenVPN,
+					OpenVPN: OpenVPN{
+						User:      stringPtr("user"),
+						Challenge: boolPtr(true),
+					},
+				}
+				tunnel.setDefaults(0, providers.Mullvad)
+				return tunnel
+			}()},
+			errWrapped: ErrTunnelChallengeNotSupported,
+			errMessage: "tunnel se: OpenVPN settings: " +
+				"authentication challenges are not supported for tunnels",
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := validateTunnels(testCase.tunnels, "tun0", fakeStorage{}, false)
+
+			assert.ErrorIs(t, err, testCase.errWrapped)
+			if testCase.errWrapped != nil {
+				assert.EqualError(t, err, testCase.errMessage)
+			}
+		})
+	}
+}
+
+func Test_Tunnel_OverrideWith(t *testing.T) {
+	t.Parallel()
+
+	tunnel := Tunnel{Name: "us"}
+	tunnel.setDefaults(0, providers.Mullvad)
+
+	tunnel.OverrideWith(Tunnel{
+		Name:    "uk",
+		Type:    vpn.OpenVPN,
+		Sources: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/24")},
+	})
+
+	assert.Equal(t, "us", tunnel.Name)
+	assert.Equal(t, vpn.OpenVPN, tunnel.Type)
+	assert.Equal(t, vpn.OpenVPN, tunnel.Provider.ServerSelection.VPN)
+	assert.Equal(t, "tun_us", tunnel.Interface())
+	assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/24")}, tunnel.Sources)
+}
@@ -1,10 +1,15 @@
 package settings
 
 import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"net/url"
 	"strings"
 	"time"
 
+	"github.com/qdm12/dns/pkg/provider"
 	"github.com/qdm12/gluetun/internal/constants/providers"
 	"github.com/qdm12/gosettings"
 	"github.com/qdm12/gosettings/validate"
@@ -20,18 +25,66 @@ type Updater struct {
 	// TODO change to value and add Enabled field.
 	Period *time.Duration
 	// DNSAddress is the DNS server address to use
-	// to resolve VPN server hostnames to IP addresses.
+	// to resolve VPN server hostnames to IP addresses,
+	// when DNSProtocol is 'plain'.
 	// It cannot be the empty string in the internal state.
 	DNSAddress string
-	// MinRatio is the minimum ratio of servers to
-	// find per provider, compared to the total current
-	// number of servers. It defaults to 0.8.
-	MinRatio float64
+	// DNSProtocol is the DNS protocol to use to resolve
+	// VPN server hostnames to IP addresses. It can be
+	// 'plain', 'dot' for DNS over TLS or 'doh' for DNS
+	// over HTTPS. It defaults to 'plain'.
+	DNSProtocol string
+	// DNSProviders is the list of DNS providers to use
+	// when DNSProtocol is 'dot' or 'doh'. It defaults
+	// to Cloudflare.
+	DNSProviders []string
+	// Threshold is the maximum ratio of existing servers
+	// which can be removed or changed by an update, per
+	// provider. Server IP address changes are not counted.
+	// It defaults to 0.2 and must be between 0+ and 1.
+	Threshold float64
+	// ThresholdAction is the action to take when an update
+	// exceeds the threshold for a provider. It can be
+	// 'reject' to keep the current servers, or 'flag' to
+	// apply the update and flag it in its diff.
+	// It defaults to 'reject'.
+	ThresholdAction string
+	// Probe is true if the servers fetched should be probed
+	// to record their reachability and round trip time.
+	// It cannot be nil in the internal state.
+	Probe *bool
+	// ProbeTimeout is the timeout to probe each server.
+	// It cannot be nil in the internal state.
+	ProbeTimeout *time.Duration
+	// ProbeParallelism is the maximum number of servers
+	// to probe at the same time. It defaults to 32.
+	ProbeParallelism int
+	// MirrorURL is the URL of a servers JSON file to fetch
+	// servers data from, instead of fetching it from each
+	// VPN provider. Its minisign detached signature is fetched
+	// at the same URL suffixed with '.minisig'. It defaults
+	// to the empty string which disables the mirror.
+	MirrorURL string
+	// MirrorPublicKey is the minisign public key to verify
+	// the mirror servers data signature. It defaults to the
+	// empty string to use the public key built in the program.
+	MirrorPublicKey string
 	// Providers is the list of VPN service providers
 	// to update server information for.
 	Providers []string
 }
 
+const (
+	UpdaterDNSProtocolPlain = "plain"
+	UpdaterDNSProtocolDoT   = "dot"
+	UpdaterDNSProtocolDoH   = "doh"
+)
+
+const (
+	UpdaterThresholdReject = "reject"
+	UpdaterThresholdFlag   = "flag"
+)
+
 func (u Updater) Validate() (err error) {
 	const minPeriod = time.Minute
 	if *u.Period > 0 && *u.Period < minPeriod {
@@ -39,9 +92,55 @@ func (u Updater) Validate() (err error) {
 			ErrUpdaterPeriodTooSmall, *u.Period, minPeriod)
 	}
 
-	if u.MinRatio <= 0 || u.MinRatio > 1 {
+	if u.Threshold <= 0 || u.Threshold > 1 {
 		return fmt.Errorf("%w: %.2f must be between 0+ and 1",
-			ErrMinRatioNotValid, u.MinRatio)
+			ErrUpdaterThresholdNotValid, u.Threshold)
+	}
+
+	err = validate.IsOneOf(u.DNSProtocol, UpdaterDNSProtocolPlain,
+		UpdaterDNSProtocolDoT, UpdaterDNSProtocolDoH)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrUpdaterDNSProtocolNotValid, err)
+	}
+
+	for _, name := range u.DNSProviders {
+		_, err = provider.Parse(name)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrUpdaterDNSProviderNotValid, err)
+		}
+	}
+
+	err = validate.IsOneOf(u.ThresholdAction,
+		UpdaterThresholdReject, UpdaterThresholdFlag)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrUpdaterThresholdActionNotValid, err)
+	}
+
+	if *u.ProbeTimeout <= 0 {
+		return fmt.Errorf("%w: %s must be positive",
+			ErrUpdaterProbeTimeoutNotValid, *u.ProbeTimeout)
+	}
+
+	if u.ProbeParallelism < 1 {
+		return fmt.Errorf("%w: %d must be at least 1",
+			ErrUpdaterProbeParallelismNotValid, u.ProbeParallelism)
+	}
+
+	if u.MirrorURL != "" {
+		parsedURL, err := url.Parse(u.MirrorURL)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrUpdaterMirrorURLNotValid, err)
+		} else if parsedURL.Scheme != "https" && parsedURL.Scheme != "http" {
+			return fmt.Errorf("%w: scheme %q is not http or https",
+				ErrUpdaterMirrorURLNotValid, parsedURL.Scheme)
+		}
+
+		if u.MirrorPublicKey != "" {
+			err = validateMinisignPublicKey(u.MirrorPublicKey)
+			if err != nil {
+				return fmt.Errorf("%w: %w", ErrUpdaterMirrorPublicKeyNotValid, err)
+			}
+		}
 	}
 
 	validProviders := providers.All()
@@ -57,10 +156,18 @@ func (u Updater) Validate() (err error) {
 
 func (u *Updater) copy() (copied Updater) {
 	return Updater{
-		Period:     gosettings.CopyPointer(u.Period),
-		DNSAddress: u.DNSAddress,
-		MinRatio:   u.MinRatio,
-		Providers:  gosettings.CopySlice(u.Providers),
+		Period:           gosettings.CopyPointer(u.Period),
+		DNSAddress:       u.DNSAddress,
+		DNSProtocol:      u.DNSProtocol,
+		DNSProviders:     gosettings.CopySlice(u.DNSProviders),
+		Threshold:        u.Threshold,
+		ThresholdAction:  u.ThresholdAction,
+		Probe:            gosettings.CopyPointer(u.Probe),
+		ProbeTimeout:     gosettings.CopyPointer(u.ProbeTimeout),
+		ProbeParallelism: u.ProbeParallelism,
+		MirrorURL:        u.MirrorURL,
+		MirrorPublicKey:  u.MirrorPublicKey,
+		Providers:        gosettings.CopySlice(u.Providers),
 	}
 }
 
@@ -69,28 +176,52 @@ func (u *Updater) copy() (copied Updater) {
 func (u *Updater) mergeWith(other Updater) {
 	u.Period = gosettings.MergeWithPointer(u.Period, other.Period)
 	u.DNSAddress = gosettings.MergeWithString(u.DNSAddress, other.DNSAddress)
-	u.MinRatio = gosettings.MergeWithNumber(u.MinRatio, other.MinRatio)
+	u.DNSProtocol = gosettings.MergeWithString(u.DNSProtocol, other.DNSProtocol)
+	u.DNSProviders = gosettings.MergeWithSlice(u.DNSProviders, other.DNSProviders)
+	u.Threshold = gosettings.MergeWithNumber(u.Threshold, other.Threshold)
+	u.ThresholdAction = gosettings.MergeWithString(u.ThresholdAction, other.ThresholdAction)
+	u.Probe = gosettings.MergeWithPointer(u.Probe, other.Probe)
+	u.ProbeTimeout = gosettings.MergeWithPointer(u.ProbeTimeout, other.ProbeTimeout)
+	u.ProbeParallelism = gosettings.MergeWithNumber(u.ProbeParallelism, other.ProbeParallelism)
+	u.MirrorURL = gosettings.MergeWithString(u.MirrorURL, other.MirrorURL)
+	u.MirrorPublicKey = gosettings.MergeWithString(u.MirrorPublicKey, other.MirrorPublicKey)
 	u.Providers = gosettings.MergeWithSlice(u.Providers, other.Providers)
 }
 
-// overrideWith overrides fields of the receiver
+// OverrideWith overrides fields of the receiver
 // settings object with any field set in the other
 // settings.
-func (u *Updater) overrideWith(other Updater) {
+func (u *Updater) OverrideWith(other Updater) {
 	u.Period = gosettings.OverrideWithPointer(u.Period, other.Period)
 	u.DNSAddress = gosettings.OverrideWithString(u.DNSAddress, other.DNSAddress)
-	u.MinRatio = gosettings.OverrideWithNumber(u.MinRatio, other.MinRatio)
+	u.DNSProtocol = gosettings.OverrideWithString(u.DNSProtocol, other.DNSProtocol)
+	u.DNSProviders = gosettings.OverrideWithSlice(u.DNSProviders, other.DNSProviders)
+	u.Threshold = gosettings.OverrideWithNumber(u.Threshold, other.Threshold)
+	u.ThresholdAction = gosettings.OverrideWithString(u.ThresholdAction, other.ThresholdAction)
+	u.Probe = gosettings.OverrideWithPointer(u.Probe, other.Probe)
+	u.ProbeTimeout = gosettings.OverrideWithPointer(u.ProbeTimeout, other.ProbeTimeout)
+	u.ProbeParallelism = gosettings.OverrideWithNumber(u.ProbeParallelism, other.ProbeParallelism)
+	u.MirrorURL = gosettings.OverrideWithString(u.MirrorURL, other.MirrorURL)
+	u.MirrorPublicKey = gosettings.OverrideWithString(u.MirrorPublicKey, other.MirrorPublicKey)
 	u.Providers = gosettings.OverrideWithSlice(u.Providers, other.Providers)
 }
 
 func (u *Updater) SetDefaults(vpnProvider string) {
 	u.Period = gosettings.DefaultPointer(u.Period, 0)
 	u.DNSAddress = gosettings.DefaultString(u.DNSAddress, "1.1.1.1:53")
+	u.DNSProtocol = gosettings.DefaultString(u.DNSProtocol, UpdaterDNSProtocolPlain)
+	u.DNSProviders = gosettings.DefaultSlice(u.DNSProviders,
+		[]string{provider.Cloudflare().String()})
 
-	if u.MinRatio == 0 {
-		const defaultMinRatio = 0.8
-		u.MinRatio = defaultMinRatio
-	}
+	const defaultThreshold = 0.2
+	u.Threshold = gosettings.DefaultNumber(u.Threshold, defaultThreshold)
+	u.ThresholdAction = gosettings.DefaultString(u.ThresholdAction, UpdaterThresholdReject)
+
+	u.Probe = gosettings.DefaultPointer(u.Probe, false)
+	const defaultProbeTimeout = 3 * time.Second
+	u.ProbeTimeout = gosettings.DefaultPointer(u.ProbeTimeout, defaultProbeTimeout)
+	const defaultProbeParallelism = 32
+	u.ProbeParallelism = gosettings.DefaultNumber(u.ProbeParallelism, defaultProbeParallelism)
 
 	if len(u.Providers) == 0 && vpnProvider != providers.Custom {
 		u.Providers = []string{vpnProvider}
@@ -108,9 +239,67 @@ func (u Updater) toLinesNode() (node *gotree.Node) {
 
 	node = gotree.New("Server data updater settings:")
 	node.Appendf("Update period: %s", *u.Period)
-	node.Appendf("DNS address: %s", u.DNSAddress)
-	node.Appendf("Minimum ratio: %.1f", u.MinRatio)
+	if u.MirrorURL != "" {
+		node.Appendf("Servers mirror: %s", u.MirrorURL)
+	}
+	switch u.DNSProtocol {
+	case UpdaterDNSProtocolDoT:
+		node.Appendf("DNS over TLS providers: %s", strings.Join(u.DNSProviders, ", "))
+	case UpdaterDNSProtocolDoH:
+		node.Appendf("DNS over HTTPS providers: %s", strings.Join(u.DNSProviders, ", "))
+	default:
+		node.Appendf("DNS address: %s", u.DNSAddress)
+	}
+	node.Appendf("Change threshold: %.2f", u.Threshold)
+	node.Appendf("Threshold action: %s", u.ThresholdAction)
+	if *u.Probe {
+		probeNode := node.Appendf("Server probing:")
+		probeNode.Appendf("Timeout: %s", *u.ProbeTimeout)
+		probeNode.Appendf("Parallelism: %d", u.ProbeParallelism)
+	}
 	node.Appendf("Providers to update: %s", strings.Join(u.Providers, ", "))
 
 	return node
 }
+
+var (
+	ErrMinisignKeyLinesCount = errors.New("expected one base64 encoded line")
+	ErrMinisignKeyLength     = errors.New("public key length is not valid")
+	ErrMinisignKeyAlgorithm  = errors.New("signature algorithm is not supported")
+)
+
+// validateMinisignPublicKey verifies the minisign public key given
+// is valid, either as its base64 encoded line or as the content of a
+// minisign public key file with its untrusted comment line.
+func validateMinisignPublicKey(key string) (err error) {
+	var lines []string
+	for _, line := range strings.Split(key, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > 0 && strings.HasPrefix(lines[0], "untrusted comment: ") {
+		lines = lines[1:]
+	}
+	if len(lines) != 1 {
+		return fmt.Errorf("%w", ErrMinisignKeyLinesCount)
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(lines[0])
+	if err != nil {
+		return err
+	}
+
+	const algorithm = "Ed"
+	const keyIDLength = 8
+	const length = len(algorithm) + keyIDLength + ed25519.PublicKeySize
+	switch {
+	case len(decoded) != length:
+		return fmt.Errorf("%w: %d bytes instead of %d",
+			ErrMinisignKeyLength, len(decoded), length)
+	case string(decoded[:len(algorithm)]) != algorithm:
+		return fmt.Errorf("%w: %q", ErrMinisignKeyAlgorithm, decoded[:len(algorithm)])
+	}
+	return nil
+}
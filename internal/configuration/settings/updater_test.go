@@ -0,0 +1,65 @@
+package settings
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_validateMinisignPublicKey(t *testing.T) {
+	t.Parallel()
+
+	key := append([]byte("Ed"), make([]byte, 8+32)...)
+	encodedKey := base64.StdEncoding.EncodeToString(key)
+
+	testCases := map[string]struct {
+		key        string
+		errWrapped error
+		errMessage string
+	}{
+		"public_key_line": {
+			key: encodedKey,
+		},
+		"public_key_file": {
+			key: "untrusted comment: minisign public key\n" + encodedKey + "\n",
+		},
+		"empty": {
+			errWrapped: ErrMinisignKeyLinesCount,
+			errMessage: "expected one base64 encoded line",
+		},
+		"not_base64": {
+			key:        "not base64",
+			errMessage: "illegal base64 data at input byte 3",
+		},
+		"wrong_length": {
+			key:        base64.StdEncoding.EncodeToString([]byte("Ed")),
+			errWrapped: ErrMinisignKeyLength,
+			errMessage: "public key length is not valid: 2 bytes instead of 42",
+		},
+		"hashed_algorithm": {
+			key: base64.StdEncoding.EncodeToString(
+				append([]byte("ED"), make([]byte, 8+32)...)),
+			errWrapped: ErrMinisignKeyAlgorithm,
+			errMessage: `signature algorithm is not supported: "ED"`,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := validateMinisignPublicKey(testCase.key)
+
+			if testCase.errMessage == "" {
+				assert.NoError(t, err)
+				return
+			}
+			if testCase.errWrapped != nil {
+				assert.ErrorIs(t, err, testCase.errWrapped)
+			}
+			assert.EqualError(t, err, testCase.errMessage)
+		})
+	}
+}
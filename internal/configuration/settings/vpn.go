@@ -17,6 +17,9 @@ type VPN struct {
 	Provider  Provider
 	OpenVPN   OpenVPN
 	Wireguard Wireguard
+	// Tunnels are additional named OpenVPN or Wireguard
+	// tunnels running alongside the main VPN connection.
+	Tunnels []Tunnel
 }
 
 // TODO v4 remove pointer for receiver (because of Surfshark).
@@ -44,6 +47,15 @@ func (v *VPN) Validate(storage Storage, ipv6Supported bool) (err error) {
 		}
 	}
 
+	mainInterface := v.OpenVPN.Interface
+	if v.Type == vpn.Wireguard {
+		mainInterface = v.Wireguard.Interface
+	}
+	err = validateTunnels(v.Tunnels, mainInterface, storage, ipv6Supported)
+	if err != nil {
+		return fmt.Errorf("tunnels settings: %w", err)
+	}
+
 	return nil
 }
 
@@ -53,6 +65,7 @@ func (v *VPN) Copy() (copied VPN) {
 		Provider:  v.Provider.copy(),
 		OpenVPN:   v.OpenVPN.copy(),
 		Wireguard: v.Wireguard.copy(),
+		Tunnels:   copyTunnels(v.Tunnels),
 	}
 }
 
@@ -61,6 +74,7 @@ func (v *VPN) mergeWith(other VPN) {
 	v.Provider.mergeWith(other.Provider)
 	v.OpenVPN.mergeWith(other.OpenVPN)
 	v.Wireguard.mergeWith(other.Wireguard)
+	v.Tunnels = gosettings.MergeWithSlice(v.Tunnels, copyTunnels(other.Tunnels))
 }
 
 func (v *VPN) OverrideWith(other VPN) {
@@ -68,13 +82,17 @@ func (v *VPN) OverrideWith(other VPN) {
 	v.Provider.overrideWith(other.Provider)
 	v.OpenVPN.overrideWith(other.OpenVPN)
 	v.Wireguard.overrideWith(other.Wireguard)
+	v.Tunnels = gosettings.OverrideWithSlice(v.Tunnels, copyTunnels(other.Tunnels))
 }
 
-func (v *VPN) setDefaults() {
+func (v *VPN) SetDefaults() {
 	v.Type = gosettings.DefaultString(v.Type, vpn.OpenVPN)
 	v.Provider.setDefaults()
 	v.OpenVPN.setDefaults(*v.Provider.Name)
 	v.Wireguard.setDefaults()
+	for i := range v.Tunnels {
+		v.Tunnels[i].setDefaults(i, *v.Provider.Name)
+	}
 }
 
 func (v VPN) String() string {
@@ -92,5 +110,9 @@ func (v VPN) toLinesNode() (node *gotree.Node) {
 		node.AppendNode(v.Wireguard.toLinesNode())
 	}
 
+	for _, tunnel := range v.Tunnels {
+		node.AppendNode(tunnel.toLinesNode())
+	}
+
 	return node
 }
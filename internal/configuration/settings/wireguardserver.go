@@ -0,0 +1,215 @@
+package settings
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+
+	"github.com/qdm12/gosettings"
+	"github.com/qdm12/gotree"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// WireguardServer contains settings to configure the embedded
+// Wireguard server, used for remote access into Gluetun's network
+// namespace with peers traffic going out through the VPN tunnel.
+type WireguardServer struct {
+	// Enabled is true if the Wireguard server should be running.
+	// It defaults to false, and cannot be nil in the internal state.
+	Enabled *bool
+	// Interface is the name of the Wireguard server interface
+	// to create. It defaults to "wgs0" and cannot be the empty
+	// string in the internal state.
+	Interface string
+	// ListeningPort is the UDP port the Wireguard server listens on.
+	// It defaults to 51820 and cannot be nil in the internal state.
+	ListeningPort *uint16
+	// PrivateKey is the Wireguard server private key.
+	// It cannot be nil in the internal state.
+	PrivateKey *string
+	// Address is the Wireguard server interface address, with
+	// its prefix length defining the peers subnet.
+	// It defaults to 10.13.13.1/24.
+	Address netip.Prefix
+	// Endpoint is the host, without port, peers use to reach
+	// the server, and is only used to generate client configurations.
+	// It can be the empty string and cannot be nil in the internal state.
+	Endpoint *string
+	// Peers are the peers allowed to connect to the server.
+	Peers []WireguardServerPeer
+}
+
+// WireguardServerPeer is a peer of the Wireguard server.
+type WireguardServerPeer struct {
+	// Name is the unique name of the peer.
+	Name string
+	// PublicKey is the Wireguard peer public key.
+	PublicKey string
+	// PreSharedKey is the Wireguard pre-shared key,
+	// and can be the empty string.
+	PreSharedKey string
+	// Address is the peer address within the server subnet.
+	Address netip.Addr
+}
+
+var regexpPeerName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+func (w WireguardServer) validate(vpnInterface string) (err error) {
+	if !*w.Enabled {
+		return nil
+	}
+
+	if !regexpInterfaceName.MatchString(w.Interface) {
+		return fmt.Errorf("%w: '%s' does not match regex '%s'",
+			ErrWireguardInterfaceNotValid, w.Interface, regexpInterfaceName)
+	} else if w.Interface == vpnInterface {
+		return fmt.Errorf("%w: %s is already used by the VPN",
+			ErrWireguardInterfaceNotValid, w.Interface)
+	}
+
+	if *w.ListeningPort == 0 {
+		return fmt.Errorf("%w", ErrWireguardServerPortNotSet)
+	}
+
+	if *w.PrivateKey == "" {
+		return fmt.Errorf("%w", ErrWireguardPrivateKeyNotSet)
+	}
+	_, err = wgtypes.ParseKey(*w.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("private key is not valid: %w", err)
+	}
+
+	if !w.Address.IsValid() || w.Address.Addr() == w.Address.Masked().Addr() {
+		return fmt.Errorf("%w: %s", ErrWireguardServerAddressNotValid, w.Address)
+	}
+
+	names := make(map[string]struct{}, len(w.Peers))
+	addresses := map[netip.Addr]struct{}{w.Address.Addr(): {}}
+	for _, peer := range w.Peers {
+		err = peer.validate(w.Address)
+		if err != nil {
+			return fmt.Errorf("peer %s: %w", peer.Name, err)
+		}
+
+		if _, exists := names[peer.Name]; exists {
+			return fmt.Errorf("%w: %s", ErrWireguardServerPeerDuplicate, peer.Name)
+		}
+		names[peer.Name] = struct{}{}
+
+		if _, exists := addresses[peer.Address]; exists {
+			return fmt.Errorf("%w: %s", ErrWireguardServerPeerDuplicate, peer.Address)
+		}
+		addresses[peer.Address] = struct{}{}
+	}
+
+	return nil
+}
+
+func (p WireguardServerPeer) validate(serverAddress netip.Prefix) (err error) {
+	if !regexpPeerName.MatchString(p.Name) {
+		return fmt.Errorf("%w: name '%s' does not match regex '%s'",
+			ErrWireguardServerPeerNotValid, p.Name, regexpPeerName)
+	}
+
+	_, err = wgtypes.ParseKey(p.PublicKey)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrWireguardPublicKeyNotValid, err)
+	}
+
+	if p.PreSharedKey != "" {
+		_, err = wgtypes.ParseKey(p.PreSharedKey)
+		if err != nil {
+			return fmt.Errorf("pre-shared key is not valid: %w", err)
+		}
+	}
+
+	if !serverAddress.Masked().Contains(p.Address) {
+		return fmt.Errorf("%w: address %s is not in subnet %s",
+			ErrWireguardServerPeerNotValid, p.Address, serverAddress.Masked())
+	}
+
+	return nil
+}
+
+func (w *WireguardServer) copy() (copied WireguardServer) {
+	return WireguardServer{
+		Enabled:       gosettings.CopyPointer(w.Enabled),
+		Interface:     w.Interface,
+		ListeningPort: gosettings.CopyPointer(w.ListeningPort),
+		PrivateKey:    gosettings.CopyPointer(w.PrivateKey),
+		Address:       w.Address,
+		Endpoint:      gosettings.CopyPointer(w.Endpoint),
+		Peers:         gosettings.CopySlice(w.Peers),
+	}
+}
+
+// mergeWith merges the other settings into any
+// unset field of the receiver settings object.
+func (w *WireguardServer) mergeWith(other WireguardServer) {
+	w.Enabled = gosettings.MergeWithPointer(w.Enabled, other.Enabled)
+	w.Interface = gosettings.MergeWithString(w.Interface, other.Interface)
+	w.ListeningPort = gosettings.MergeWithPointer(w.ListeningPort, other.ListeningPort)
+	w.PrivateKey = gosettings.MergeWithPointer(w.PrivateKey, other.PrivateKey)
+	w.Address = gosettings.MergeWithValidator(w.Address, other.Address)
+	w.Endpoint = gosettings.MergeWithPointer(w.Endpoint, other.Endpoint)
+	w.Peers = gosettings.MergeWithSlice(w.Peers, other.Peers)
+}
+
+// overrideWith overrides fields of the receiver
+// settings object with any field set in the other
+// settings.
+func (w *WireguardServer) overrideWith(other WireguardServer) {
+	w.Enabled = gosettings.OverrideWithPointer(w.Enabled, other.Enabled)
+	w.Interface = gosettings.OverrideWithString(w.Interface, other.Interface)
+	w.ListeningPort = gosettings.OverrideWithPointer(w.ListeningPort, other.ListeningPort)
+	w.PrivateKey = gosettings.OverrideWithPointer(w.PrivateKey, other.PrivateKey)
+	w.Address = gosettings.OverrideWithValidator(w.Address, other.Address)
+	w.Endpoint = gosettings.OverrideWithPointer(w.Endpoint, other.Endpoint)
+	w.Peers = gosettings.OverrideWithSlice(w.Peers, other.Peers)
+}
+
+func (w *WireguardServer) setDefaults() {
+	w.Enabled = gosettings.DefaultPointer(w.Enabled, false)
+	w.Interface = gosettings.DefaultString(w.Interface, "wgs0")
+	const defaultListeningPort = 51820
+	w.ListeningPort = gosettings.DefaultPointer(w.ListeningPort, defaultListeningPort)
+	w.PrivateKey = gosettings.DefaultPointer(w.PrivateKey, "")
+	defaultAddress := netip.PrefixFrom(netip.AddrFrom4([4]byte{10, 13, 13, 1}), 24) //nolint:gomnd
+	w.Address = gosettings.DefaultValidator(w.Address, defaultAddress)
+	w.Endpoint = gosettings.DefaultPointer(w.Endpoint, "")
+}
+
+func (w WireguardServer) String() string {
+	return w.toLinesNode().String()
+}
+
+func (w WireguardServer) toLinesNode() (node *gotree.Node) {
+	node = gotree.New("Wireguard server settings:")
+
+	node.Appendf("Enabled: %s", gosettings.BoolToYesNo(w.Enabled))
+	if !*w.Enabled {
+		return node
+	}
+
+	node.Appendf("Interface: %s", w.Interface)
+	node.Appendf("Listening port: %d", *w.ListeningPort)
+	node.Appendf("Private key: %s", gosettings.ObfuscateKey(*w.PrivateKey))
+	node.Appendf("Address: %s", w.Address)
+	if *w.Endpoint != "" {
+		node.Appendf("Endpoint: %s", *w.Endpoint)
+	}
+
+	if len(w.Peers) > 0 {
+		peersNode := node.Appendf("Peers:")
+		for _, peer := range w.Peers {
+			peerNode := peersNode.Appendf("%s:", peer.Name)
+			peerNode.Appendf("Public key: %s", peer.PublicKey)
+			if peer.PreSharedKey != "" {
+				peerNode.Appendf("Pre-shared key: %s", gosettings.ObfuscateKey(peer.PreSharedKey))
+			}
+			peerNode.Appendf("Address: %s", peer.Address)
+		}
+	}
+
+	return node
+}
@@ -0,0 +1,232 @@
+package configfile
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qdm12/log"
+)
+
+var (
+	ErrKeyUnknown       = errors.New("key is unknown")
+	ErrValueNotScalar   = errors.New("value must be a single value")
+	ErrValueNotList     = errors.New("value must be a list")
+	ErrValueNotMapping  = errors.New("value must be a mapping of keys to values")
+	ErrBooleanNotValid  = errors.New("boolean value is not valid")
+	ErrTypeNotSupported = errors.New("type is not supported")
+)
+
+// ValueError is an error caused by a value of the configuration
+// file, containing the line and key path of the offending value.
+type ValueError struct {
+	Line int
+	Path string
+	Err  error
+}
+
+func (e *ValueError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "document"
+	}
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s", path, e.Err)
+	}
+	return fmt.Sprintf("line %d: %s: %s", e.Line, path, e.Err)
+}
+
+func (e *ValueError) Unwrap() error { return e.Err }
+
+type scalarParser func(s string) (value any, err error)
+
+// scalarParsers are parsers for types which are configured with a
+// single string value but do not implement encoding.TextUnmarshaler.
+var scalarParsers = map[reflect.Type]scalarParser{ //nolint:gochecknoglobals
+	reflect.TypeOf(time.Duration(0)): func(s string) (value any, err error) {
+		return time.ParseDuration(s)
+	},
+	reflect.TypeOf(log.Level(0)): func(s string) (value any, err error) {
+		return log.ParseLevel(s)
+	},
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem() //nolint:gochecknoglobals
+
+// decode decodes the node into the value given, which
+// must be settable. The path is used for error messages.
+func decode(n *node, value reflect.Value, path string) (err error) {
+	valueType := value.Type()
+
+	if parse, ok := scalarParsers[valueType]; ok {
+		if n.kind != kindScalar {
+			return &ValueError{Line: n.line, Path: path, Err: ErrValueNotScalar}
+		}
+		parsed, err := parse(n.scalar)
+		if err != nil {
+			return &ValueError{Line: n.line, Path: path, Err: err}
+		}
+		value.Set(reflect.ValueOf(parsed).Convert(valueType))
+		return nil
+	}
+
+	if valueType.Kind() == reflect.Pointer {
+		element := reflect.New(valueType.Elem())
+		err = decode(n, element.Elem(), path)
+		if err != nil {
+			return err
+		}
+		value.Set(element)
+		return nil
+	}
+
+	if reflect.PointerTo(valueType).Implements(textUnmarshalerType) {
+		if n.kind != kindScalar {
+			return &ValueError{Line: n.line, Path: path, Err: ErrValueNotScalar}
+		}
+		unmarshaler := value.Addr().Interface().(encoding.TextUnmarshaler) //nolint:forcetypeassert
+		err = unmarshaler.UnmarshalText([]byte(n.scalar))
+		if err != nil {
+			return &ValueError{Line: n.line, Path: path, Err: err}
+		}
+		return nil
+	}
+
+	switch valueType.Kind() { //nolint:exhaustive
+	case reflect.Struct:
+		return decodeStruct(n, value, path)
+	case reflect.Slice:
+		return decodeSlice(n, value, path)
+	case reflect.Map:
+		return decodeMap(n, value, path)
+	}
+
+	if n.kind != kindScalar {
+		return &ValueError{Line: n.line, Path: path, Err: ErrValueNotScalar}
+	}
+	err = decodeScalar(n.scalar, value)
+	if err != nil {
+		return &ValueError{Line: n.line, Path: path, Err: err}
+	}
+	return nil
+}
+
+func decodeStruct(n *node, value reflect.Value, path string) (err error) {
+	if n.kind != kindMapping {
+		return &ValueError{Line: n.line, Path: path, Err: ErrValueNotMapping}
+	}
+
+	fields := structFields(value.Type())
+	keyToField := make(map[string]structField, len(fields))
+	for _, field := range fields {
+		keyToField[field.key] = field
+	}
+
+	for i, key := range n.keys {
+		keyPath := joinPath(path, key)
+		field, ok := keyToField[key]
+		if !ok {
+			return &ValueError{Line: n.values[i].line, Path: keyPath, Err: ErrKeyUnknown}
+		}
+
+		err = decode(n.values[i], value.FieldByIndex(field.index), keyPath)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeSlice(n *node, value reflect.Value, path string) (err error) {
+	items := n.items
+	switch n.kind {
+	case kindList:
+	case kindScalar: // single value list
+		items = []*node{n}
+	default:
+		return &ValueError{Line: n.line, Path: path, Err: ErrValueNotList}
+	}
+
+	slice := reflect.MakeSlice(value.Type(), len(items), len(items))
+	for i, item := range items {
+		err = decode(item, slice.Index(i), joinPath(path, strconv.Itoa(i)))
+		if err != nil {
+			return err
+		}
+	}
+	value.Set(slice)
+	return nil
+}
+
+func decodeMap(n *node, value reflect.Value, path string) (err error) {
+	if n.kind != kindMapping {
+		return &ValueError{Line: n.line, Path: path, Err: ErrValueNotMapping}
+	}
+
+	mapType := value.Type()
+	if mapType.Key().Kind() != reflect.String {
+		return &ValueError{Line: n.line, Path: path,
+			Err: fmt.Errorf("%w: %s", ErrTypeNotSupported, mapType)}
+	}
+
+	mapValue := reflect.MakeMapWithSize(mapType, len(n.keys))
+	for i, key := range n.keys {
+		element := reflect.New(mapType.Elem()).Elem()
+		err = decode(n.values[i], element, joinPath(path, key))
+		if err != nil {
+			return err
+		}
+		mapValue.SetMapIndex(reflect.ValueOf(key).Convert(mapType.Key()), element)
+	}
+	value.Set(mapValue)
+	return nil
+}
+
+func decodeScalar(s string, value reflect.Value) (err error) {
+	switch value.Kind() { //nolint:exhaustive
+	case reflect.String:
+		value.SetString(s)
+	case reflect.Bool:
+		b, err := parseBool(s)
+		if err != nil {
+			return err
+		}
+		value.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetFloat(f)
+	default:
+		return fmt.Errorf("%w: %s", ErrTypeNotSupported, value.Type())
+	}
+	return nil
+}
+
+func parseBool(s string) (b bool, err error) {
+	switch strings.ToLower(s) {
+	case "true", "yes", "on":
+		return true, nil
+	case "false", "no", "off":
+		return false, nil
+	default:
+		return false, fmt.Errorf("%w: %s", ErrBooleanNotValid, s)
+	}
+}
@@ -0,0 +1,80 @@
+package configfile
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// keyReplacer replaces mixed case words which would
+// otherwise be split in unnatural keys.
+var keyReplacer = strings.NewReplacer( //nolint:gochecknoglobals
+	"OpenVPN", "Openvpn",
+	"IPv6", "Ipv6",
+	"DoT", "Dot",
+)
+
+// fieldKey returns the snake case configuration file key for
+// the Go struct field name given, for example `ServerSelection`
+// gives `server_selection` and `AddBlockedIPs` gives `add_blocked_ips`.
+func fieldKey(fieldName string) (key string) {
+	runes := []rune(keyReplacer.Replace(fieldName))
+	var builder strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && startsWord(runes, i) {
+			builder.WriteRune('_')
+		}
+		builder.WriteRune(unicode.ToLower(r))
+	}
+	return builder.String()
+}
+
+func startsWord(runes []rune, i int) bool {
+	previous := runes[i-1]
+	if !unicode.IsUpper(previous) {
+		return true
+	}
+
+	// Previous rune is uppercase, so the rune at index i starts a
+	// new word only if it is followed by a lowercase rune, unless
+	// this is a pluralized acronym such as `IPs`.
+	if i+1 >= len(runes) || !unicode.IsLower(runes[i+1]) {
+		return false
+	}
+	pluralAcronym := runes[i+1] == 's' &&
+		(i+2 == len(runes) || unicode.IsUpper(runes[i+2]))
+	return !pluralAcronym
+}
+
+type structField struct {
+	key   string
+	index []int
+	typ   reflect.Type
+}
+
+// structFields returns the configurable fields of the struct
+// type given, flattening embedded structs. Unexported fields and
+// interface fields are not configurable and are skipped.
+func structFields(structType reflect.Type) (fields []structField) {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if !field.IsExported() || field.Type.Kind() == reflect.Interface {
+			continue
+		}
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			for _, embeddedField := range structFields(field.Type) {
+				embeddedField.index = append([]int{i}, embeddedField.index...)
+				fields = append(fields, embeddedField)
+			}
+			continue
+		}
+
+		fields = append(fields, structField{
+			key:   fieldKey(field.Name),
+			index: []int{i},
+			typ:   field.Type,
+		})
+	}
+	return fields
+}
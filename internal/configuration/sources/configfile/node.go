@@ -0,0 +1,61 @@
+package configfile
+
+type nodeKind uint8
+
+const (
+	kindScalar nodeKind = iota
+	kindList
+	kindMapping
+)
+
+// node is a configuration file value independent of the file
+// format, with the line number it is defined at.
+type node struct {
+	line   int
+	kind   nodeKind
+	scalar string
+	// items are the list items for a list node.
+	items []*node
+	// keys and values are the ordered mapping keys
+	// and their values for a mapping node.
+	keys   []string
+	values []*node
+}
+
+// leaf is a single value or a list of values set in
+// the configuration file, at the key path given.
+type leaf struct {
+	path string
+	line int
+}
+
+// leaves returns the leaves of the node, in the file order.
+func (n *node) leaves(path string) (leaves []leaf) {
+	if n.kind != kindMapping {
+		return []leaf{{path: path, line: n.line}}
+	}
+
+	for i, key := range n.keys {
+		leaves = append(leaves, n.values[i].leaves(joinPath(path, key))...)
+	}
+	return leaves
+}
+
+// without returns a copy of the node without the value at the
+// key path given. The node itself is not modified.
+func (n *node) without(path, excludedPath string) (copied *node) {
+	if n.kind != kindMapping {
+		return n
+	}
+
+	copied = &node{line: n.line, kind: kindMapping}
+	for i, key := range n.keys {
+		keyPath := joinPath(path, key)
+		if keyPath == excludedPath {
+			continue
+		}
+		copied.keys = append(copied.keys, key)
+		copied.values = append(copied.values, n.values[i].without(keyPath, excludedPath))
+	}
+	return copied
+}
@@ -0,0 +1,158 @@
+package configfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/qdm12/gosettings/sources/env"
+)
+
+// DefaultFilepath is the default structured configuration file path,
+// used if the environment variable CONFIG_FILEPATH is not set.
+const DefaultFilepath = "/gluetun/config.yaml"
+
+// Source reads settings from a structured YAML or TOML configuration
+// file, which is silently ignored if it does not exist. Keys are the
+// snake case names of the settings fields, for example:
+//
+//	vpn:
+//	  provider:
+//	    name: mullvad
+//	    server_selection:
+//	      countries: [Sweden, Norway]
+type Source struct{}
+
+func New() *Source {
+	return &Source{}
+}
+
+func (s *Source) String() string { return "config file" }
+
+// Filepaths returns the path of the configuration file, which may not exist.
+func (s *Source) Filepaths() (paths []string) {
+	return []string{filepathFromEnv()}
+}
+
+func filepathFromEnv() (path string) {
+	path = env.Get("CONFIG_FILEPATH", env.ForceLowercase(false))
+	if path == "" {
+		path = DefaultFilepath
+	}
+	return path
+}
+
+func (s *Source) Read() (settings settings.Settings, err error) {
+	path := filepathFromEnv()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return settings, nil
+		}
+		return settings, fmt.Errorf("reading config file: %w", err)
+	}
+
+	err = parse(data, filepath.Ext(path), &settings)
+	if err != nil {
+		return settings, fmt.Errorf("config file %s: %w", path, err)
+	}
+	return settings, nil
+}
+
+func (s *Source) ReadHealth() (health settings.Health, err error) {
+	allSettings, err := s.Read()
+	if err != nil {
+		return health, err
+	}
+	return allSettings.Health, nil
+}
+
+// parse parses the configuration file data, in the format
+// given by the file extension, into the settings given.
+func parse(data []byte, extension string, settings *settings.Settings) (err error) {
+	root, err := parseNode(data, extension)
+	if err != nil {
+		return err
+	}
+	return decodeRoot(root, settings)
+}
+
+// parseNode parses the configuration file data, in the format
+// given by the file extension, into a node. The node returned
+// is nil for a null document.
+func parseNode(data []byte, extension string) (root *node, err error) {
+	switch strings.ToLower(extension) {
+	case ".toml":
+		root, err = parseTOML(data)
+	default:
+		root, err = parseYAML(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing: %w", err)
+	}
+	return root, nil
+}
+
+func decodeRoot(root *node, settings *settings.Settings) (err error) {
+	if root == nil { // null document
+		return nil
+	}
+	return decode(root, reflect.ValueOf(settings).Elem(), "")
+}
+
+// LocateError returns the validation error given wrapped with the
+// line and key path of the configuration file value causing it, or
+// returns the error unchanged if no such value is found.
+// The validate function is called with the settings read from the
+// configuration file without one of its values at a time. The value
+// causing the error is the first one without which the validation
+// succeeds or, failing that, fails with a different error.
+func (s *Source) LocateError(validationErr error,
+	validate func(fileSettings settings.Settings) error,
+) error {
+	path := filepathFromEnv()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return validationErr
+	}
+	return locateError(data, path, validationErr, validate)
+}
+
+func locateError(data []byte, path string, validationErr error,
+	validate func(fileSettings settings.Settings) error,
+) error {
+	root, err := parseNode(data, filepath.Ext(path))
+	if err != nil || root == nil {
+		return validationErr
+	}
+
+	var located *leaf
+	for _, leaf := range root.leaves("") {
+		leaf := leaf
+		var fileSettings settings.Settings
+		err = decodeRoot(root.without("", leaf.path), &fileSettings)
+		if err != nil {
+			continue
+		}
+
+		err = validate(fileSettings)
+		if err == nil {
+			located = &leaf
+			break
+		} else if located == nil && err.Error() != validationErr.Error() {
+			located = &leaf
+		}
+	}
+
+	if located == nil {
+		return validationErr
+	}
+	return fmt.Errorf("config file %s: %w", path, &ValueError{
+		Line: located.line,
+		Path: located.path,
+		Err:  validationErr,
+	})
+}
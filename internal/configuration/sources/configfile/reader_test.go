@@ -0,0 +1,327 @@
+package configfile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ptrTo[T any](value T) *T { return &value }
+
+func Test_parse(t *testing.T) {
+	t.Parallel()
+
+	expectedSettings := settings.Settings{
+		VPN: settings.VPN{
+			Type: "wireguard",
+			Provider: settings.Provider{
+				Name: ptrTo("mullvad"),
+				ServerSelection: settings.ServerSelection{
+					Countries: []string{"Sweden", "Norway"},
+					OwnedOnly: ptrTo(true),
+				},
+			},
+			Tunnels: []settings.Tunnel{{
+				Name: "office",
+				Provider: settings.Provider{
+					Name: ptrTo("custom"),
+					ServerSelection: settings.ServerSelection{
+						Wireguard: settings.WireguardSelection{
+							EndpointIP: netip.MustParseAddr("1.2.3.4"),
+						},
+					},
+				},
+			}},
+		},
+		Firewall: settings.Firewall{
+			InputPorts:      []uint16{8080},
+			OutboundSubnets: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+		},
+		PublicIP: settings.PublicIP{
+			Period: ptrTo(time.Hour),
+			CustomAPI: settings.PublicIPCustomAPI{
+				Fields: map[string]string{"ip": "query"},
+			},
+		},
+	}
+
+	testCases := map[string]struct {
+		data       string
+		extension  string
+		settings   settings.Settings
+		errMessage string
+	}{
+		"empty YAML": {
+			extension: ".yaml",
+		},
+		"YAML": {
+			extension: ".yaml",
+			data: `
+vpn:
+  type: wireguard
+  provider:
+    name: mullvad
+    server_selection:
+      countries: [Sweden, Norway]
+      owned_only: yes
+  tunnels:
+    - name: office
+      provider:
+        name: custom
+        server_selection:
+          wireguard:
+            endpoint_ip: 1.2.3.4
+firewall:
+  input_ports: 8080
+  outbound_subnets:
+    - 10.0.0.0/8
+public_ip:
+  period: 1h
+  custom_api:
+    fields:
+      ip: query
+`,
+			settings: expectedSettings,
+		},
+		"TOML": {
+			extension: ".toml",
+			data: `
+[vpn]
+type = "wireguard"
+
+[vpn.provider]
+name = "mullvad"
+
+[vpn.provider.server_selection]
+countries = ["Sweden", "Norway"]
+owned_only = true
+
+[[vpn.tunnels]]
+name = "office"
+provider.name = "custom"
+provider.server_selection.wireguard.endpoint_ip = "1.2.3.4"
+
+[firewall]
+input_ports = [8080]
+outbound_subnets = ["10.0.0.0/8"]
+
+[public_ip]
+period = "1h"
+custom_api.fields.ip = "query"
+`,
+			settings: expectedSettings,
+		},
+		"YAML unknown key": {
+			extension: ".yml",
+			data: `
+vpn:
+  provider:
+    nam: mullvad
+`,
+			errMessage: "line 4: vpn.provider.nam: key is unknown",
+		},
+		"YAML invalid duration": {
+			extension: ".yml",
+			data: `
+public_ip:
+  period: 1 hour
+`,
+			errMessage: `line 3: public_ip.period: time: unknown unit " hour" in duration "1 hour"`,
+		},
+		"YAML invalid list item": {
+			extension: ".yml",
+			data: `
+firewall:
+  outbound_subnets:
+    - 10.0.0.0/8
+    - 10.0.0.0
+`,
+			errMessage: `line 5: firewall.outbound_subnets.1: netip.ParsePrefix("10.0.0.0"): no '/'`,
+		},
+		"TOML port out of range": {
+			extension: ".toml",
+			data: `
+[firewall]
+enabled = true
+input_ports = [70000]
+`,
+			errMessage: `line 4: firewall.input_ports.0: strconv.ParseUint: parsing "70000": value out of range`,
+		},
+		"TOML array of tables error": {
+			extension: ".toml",
+			data: `
+[[vpn.tunnels]]
+name = "a"
+
+[[vpn.tunnels]]
+name = "b"
+sources = ["x"]
+`,
+			errMessage: `line 7: vpn.tunnels.1.sources.0: netip.ParsePrefix("x"): no '/'`,
+		},
+		"scalar for struct": {
+			extension: ".yaml",
+			data:      "vpn: wireguard\n",
+			errMessage: "line 1: vpn: value must be a mapping " +
+				"of keys to values",
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var settings settings.Settings
+			err := parse([]byte(testCase.data), testCase.extension, &settings)
+
+			if testCase.errMessage != "" {
+				require.Error(t, err)
+				assert.Equal(t, testCase.errMessage, err.Error())
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.settings, settings)
+		})
+	}
+}
+
+func Test_locateError(t *testing.T) {
+	t.Parallel()
+
+	errPeriodTooShort := errors.New("period is too short")
+	errPortsMissing := errors.New("input ports are missing")
+	// validate fails if the public IP period is too short, or
+	// if there are no firewall input ports.
+	validate := func(fileSettings settings.Settings) error {
+		switch {
+		case fileSettings.PublicIP.Period != nil &&
+			*fileSettings.PublicIP.Period < time.Minute:
+			return fmt.Errorf("public ip settings: %w", errPeriodTooShort)
+		case len(fileSettings.Firewall.InputPorts) == 0:
+			return fmt.Errorf("firewall settings: %w", errPortsMissing)
+		}
+		return nil
+	}
+
+	testCases := map[string]struct {
+		data          string
+		path          string
+		validationErr error
+		errMessage    string
+	}{
+		"YAML value causing the error": {
+			data: `
+firewall:
+  input_ports: [8000]
+public_ip:
+  ip_filepath: /tmp/ip
+  period: 1s
+`,
+			path:          "config.yaml",
+			validationErr: fmt.Errorf("public ip settings: %w", errPeriodTooShort),
+			errMessage: "config file config.yaml: line 6: public_ip.period: " +
+				"public ip settings: period is too short",
+		},
+		"TOML value causing the error": {
+			data: `
+[firewall]
+input_ports = [8000]
+
+[public_ip]
+period = "1s"
+`,
+			path:          "config.toml",
+			validationErr: fmt.Errorf("public ip settings: %w", errPeriodTooShort),
+			errMessage: "config file config.toml: line 6: public_ip.period: " +
+				"public ip settings: period is too short",
+		},
+		"value changing the error": {
+			data: `
+firewall:
+  input_ports: [8000]
+`,
+			path:          "config.yaml",
+			validationErr: errors.New("other error"),
+			errMessage: "config file config.yaml: line 3: firewall.input_ports: " +
+				"other error",
+		},
+		"error not caused by the file": {
+			data: `
+public_ip:
+  ip_filepath: /tmp/ip
+`,
+			path:          "config.yaml",
+			validationErr: fmt.Errorf("firewall settings: %w", errPortsMissing),
+			errMessage:    "firewall settings: input ports are missing",
+		},
+		"empty file": {
+			path:          "config.yaml",
+			validationErr: fmt.Errorf("firewall settings: %w", errPortsMissing),
+			errMessage:    "firewall settings: input ports are missing",
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := locateError([]byte(testCase.data), testCase.path,
+				testCase.validationErr, validate)
+
+			assert.ErrorIs(t, err, testCase.validationErr)
+			assert.EqualError(t, err, testCase.errMessage)
+		})
+	}
+}
+
+func Test_fieldKey(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]string{
+		"VPN":             "vpn",
+		"ServerSelection": "server_selection",
+		"HTTPProxy":       "http_proxy",
+		"AddBlockedIPs":   "add_blocked_ips",
+		"APIs":            "apis",
+		"OpenVPN":         "openvpn",
+		"IPv6":            "ipv6",
+		"DoT":             "dot",
+		"PIAEncPreset":    "pia_enc_preset",
+		"TargetIP":        "target_ip",
+	}
+
+	for fieldName, expectedKey := range testCases {
+		assert.Equal(t, expectedKey, fieldKey(fieldName), fieldName)
+	}
+}
+
+func Test_Schema(t *testing.T) {
+	t.Parallel()
+
+	data, err := Schema()
+	require.NoError(t, err)
+
+	var schema struct {
+		Properties map[string]struct {
+			Type       string                     `json:"type"`
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"properties"`
+	}
+	err = json.Unmarshal(data, &schema)
+	require.NoError(t, err)
+
+	vpn, ok := schema.Properties["vpn"]
+	require.True(t, ok)
+	assert.Equal(t, "object", vpn.Type)
+	assert.Contains(t, vpn.Properties, "provider")
+	assert.Contains(t, vpn.Properties, "openvpn")
+}
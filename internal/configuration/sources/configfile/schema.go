@@ -0,0 +1,85 @@
+package configfile
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+)
+
+// Schema returns the JSON Schema of the structured configuration
+// file, which can be used by editors to validate configuration files.
+func Schema() (schema []byte, err error) {
+	root := typeSchema(reflect.TypeOf(settings.Settings{}))
+	root["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	root["title"] = "Gluetun configuration file"
+	const indent = "  "
+	return json.MarshalIndent(root, "", indent)
+}
+
+const durationPattern = `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+
+func typeSchema(t reflect.Type) (schema map[string]any) {
+	if t == reflect.TypeOf(time.Duration(0)) {
+		return map[string]any{"type": "string", "pattern": durationPattern}
+	} else if _, ok := scalarParsers[t]; ok {
+		return map[string]any{"type": "string"}
+	}
+
+	if t.Kind() == reflect.Pointer {
+		return typeSchema(t.Elem())
+	}
+
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return map[string]any{"type": "string"}
+	}
+
+	switch t.Kind() { //nolint:exhaustive
+	case reflect.Struct:
+		properties := make(map[string]any)
+		for _, field := range structFields(t) {
+			properties[field.key] = typeSchema(field.typ)
+		}
+		return map[string]any{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+	case reflect.Slice:
+		return map[string]any{
+			"type":  "array",
+			"items": typeSchema(t.Elem()),
+		}
+	case reflect.Map:
+		return map[string]any{
+			"type":                 "object",
+			"additionalProperties": typeSchema(t.Elem()),
+		}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{
+			"enum": []any{true, false, "yes", "no", "on", "off", "true", "false"},
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		bits := t.Bits()
+		return map[string]any{
+			"type":    "integer",
+			"minimum": -(int64(1) << (bits - 1)),
+			"maximum": int64(1)<<(bits-1) - 1,
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		maximum := uint64(math.MaxUint64) >> (64 - t.Bits()) //nolint:gomnd
+		return map[string]any{
+			"type":    "integer",
+			"minimum": 0,
+			"maximum": maximum,
+		}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	default:
+		return map[string]any{}
+	}
+}
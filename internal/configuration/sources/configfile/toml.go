@@ -0,0 +1,138 @@
+package configfile
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+func parseTOML(data []byte) (root *node, err error) {
+	var document map[string]any
+	_, err = toml.Decode(string(data), &document)
+	if err != nil {
+		return nil, err
+	}
+
+	keyToLine := tomlKeyLines(data)
+	return convertTOMLValue(document, "", keyToLine), nil
+}
+
+func convertTOMLValue(value any, path string, keyToLine map[string]int) (converted *node) {
+	converted = &node{line: lineForPath(path, keyToLine)}
+	switch typedValue := value.(type) {
+	case map[string]any:
+		converted.kind = kindMapping
+		keys := make([]string, 0, len(typedValue))
+		for key := range typedValue {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			lineI := lineForPath(joinPath(path, keys[i]), keyToLine)
+			lineJ := lineForPath(joinPath(path, keys[j]), keyToLine)
+			if lineI != lineJ {
+				return lineI < lineJ
+			}
+			return keys[i] < keys[j]
+		})
+		for _, key := range keys {
+			converted.keys = append(converted.keys, key)
+			converted.values = append(converted.values,
+				convertTOMLValue(typedValue[key], joinPath(path, key), keyToLine))
+		}
+	case []map[string]any:
+		converted.kind = kindList
+		for i, item := range typedValue {
+			converted.items = append(converted.items,
+				convertTOMLValue(item, joinPath(path, strconv.Itoa(i)), keyToLine))
+		}
+	case []any:
+		converted.kind = kindList
+		for i, item := range typedValue {
+			converted.items = append(converted.items,
+				convertTOMLValue(item, joinPath(path, strconv.Itoa(i)), keyToLine))
+		}
+	default:
+		converted.kind = kindScalar
+		converted.scalar = fmt.Sprint(typedValue)
+	}
+	return converted
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// lineForPath returns the line of the key path given, or of its
+// closest parent key path if it is not found. It returns 0 if no
+// line is found.
+func lineForPath(path string, keyToLine map[string]int) (line int) {
+	for path != "" {
+		line, ok := keyToLine[path]
+		if ok {
+			return line
+		}
+		lastDot := strings.LastIndex(path, ".")
+		if lastDot == -1 {
+			break
+		}
+		path = path[:lastDot]
+	}
+	return 0
+}
+
+var (
+	regexTOMLArrayTable = regexp.MustCompile(`^\s*\[\[\s*([^\]]+?)\s*\]\]`)
+	regexTOMLTable      = regexp.MustCompile(`^\s*\[\s*([^\]]+?)\s*\]`)
+	regexTOMLKeyValue   = regexp.MustCompile(`^\s*([A-Za-z0-9_\-."' ]+?)\s*=`)
+)
+
+// tomlKeyLines returns a best effort mapping of dotted key paths
+// to their line number, since the TOML decoder does not expose
+// key positions. Array of tables items are indexed in the path,
+// for example `tunnels.0.name`.
+func tomlKeyLines(data []byte) (keyToLine map[string]int) {
+	keyToLine = make(map[string]int)
+	arrayTableCounts := make(map[string]int)
+	tablePath := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+		if match := regexTOMLArrayTable.FindStringSubmatch(line); match != nil {
+			path := normalizeTOMLKey(match[1])
+			index := arrayTableCounts[path]
+			arrayTableCounts[path]++
+			tablePath = joinPath(path, strconv.Itoa(index))
+			keyToLine[path] = lineNumber
+			keyToLine[tablePath] = lineNumber
+			continue
+		}
+
+		if match := regexTOMLTable.FindStringSubmatch(line); match != nil {
+			tablePath = normalizeTOMLKey(match[1])
+			keyToLine[tablePath] = lineNumber
+			continue
+		}
+
+		if match := regexTOMLKeyValue.FindStringSubmatch(line); match != nil {
+			keyToLine[joinPath(tablePath, normalizeTOMLKey(match[1]))] = lineNumber
+		}
+	}
+	return keyToLine
+}
+
+func normalizeTOMLKey(key string) string {
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		parts[i] = strings.Trim(strings.TrimSpace(part), `"'`)
+	}
+	return strings.Join(parts, ".")
+}
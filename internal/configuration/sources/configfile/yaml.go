@@ -0,0 +1,72 @@
+package configfile
+
+import (
+	"errors"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+var ErrYAMLNodeKindUnknown = errors.New("YAML node kind is unknown")
+
+func parseYAML(data []byte) (root *node, err error) {
+	var document yaml.Node
+	err = yaml.Unmarshal(data, &document)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(document.Content) == 0 { // empty file
+		return &node{kind: kindMapping}, nil
+	}
+
+	return convertYAMLNode(document.Content[0])
+}
+
+// convertYAMLNode converts a YAML node to a node, returning
+// a nil node for null values.
+func convertYAMLNode(yamlNode *yaml.Node) (converted *node, err error) {
+	if yamlNode.Kind == yaml.AliasNode {
+		yamlNode = yamlNode.Alias
+	}
+
+	converted = &node{line: yamlNode.Line}
+	switch yamlNode.Kind {
+	case yaml.ScalarNode:
+		if yamlNode.Tag == "!!null" {
+			return nil, nil //nolint:nilnil
+		}
+		converted.kind = kindScalar
+		converted.scalar = yamlNode.Value
+	case yaml.SequenceNode:
+		converted.kind = kindList
+		for _, item := range yamlNode.Content {
+			convertedItem, err := convertYAMLNode(item)
+			if err != nil {
+				return nil, err
+			} else if convertedItem == nil {
+				continue
+			}
+			converted.items = append(converted.items, convertedItem)
+		}
+	case yaml.MappingNode:
+		converted.kind = kindMapping
+		for i := 0; i+1 < len(yamlNode.Content); i += 2 {
+			key, value := yamlNode.Content[i], yamlNode.Content[i+1]
+			convertedValue, err := convertYAMLNode(value)
+			if err != nil {
+				return nil, err
+			} else if convertedValue == nil {
+				continue
+			}
+			// report errors on the key line
+			convertedValue.line = key.Line
+			converted.keys = append(converted.keys, key.Value)
+			converted.values = append(converted.values, convertedValue)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %d at line %d",
+			ErrYAMLNodeKindUnknown, yamlNode.Kind, yamlNode.Line)
+	}
+	return converted, nil
+}
@@ -40,6 +40,16 @@ func (s *Source) readFirewall() (firewall settings.Firewall, err error) {
 		return firewall, fmt.Errorf("environment variable FIREWALL_DEBUG: %w", err)
 	}
 
+	firewall.Gateway.Enabled, err = env.BoolPtr("FIREWALL_GATEWAY")
+	if err != nil {
+		return firewall, fmt.Errorf("environment variable FIREWALL_GATEWAY: %w", err)
+	}
+
+	firewall.Gateway.ClientSubnets, err = stringsToNetipPrefixes(env.CSV("FIREWALL_GATEWAY_CLIENT_SUBNETS"))
+	if err != nil {
+		return firewall, fmt.Errorf("environment variable FIREWALL_GATEWAY_CLIENT_SUBNETS: %w", err)
+	}
+
 	return firewall, nil
 }
 
@@ -1,7 +1,9 @@
 package env
 
 import (
+	"errors"
 	"fmt"
+	"net/netip"
 	"time"
 
 	"github.com/qdm12/gluetun/internal/configuration/settings"
@@ -19,6 +21,11 @@ func (s *Source) ReadHealth() (health settings.Health, err error) {
 		health.SuccessWait = *successWaitPtr
 	}
 
+	health.Probes, err = readHealthProbes()
+	if err != nil {
+		return health, err
+	}
+
 	health.VPN.Initial, err = s.readDurationWithRetro(
 		"HEALTH_VPN_DURATION_INITIAL",
 		"HEALTH_OPENVPN_DURATION_INITIAL")
@@ -36,6 +43,67 @@ func (s *Source) ReadHealth() (health settings.Health, err error) {
 	return health, nil
 }
 
+func readHealthProbes() (probes settings.HealthProbes, err error) {
+	probes.Enabled = env.CSV("HEALTH_PROBES")
+
+	quorum, err := env.Int("HEALTH_PROBES_QUORUM")
+	if err != nil {
+		return probes, fmt.Errorf("environment variable HEALTH_PROBES_QUORUM: %w", err)
+	} else if quorum < 0 {
+		return probes, fmt.Errorf("environment variable HEALTH_PROBES_QUORUM: %w: %d",
+			ErrHealthQuorumNegative, quorum)
+	}
+	probes.Quorum = uint(quorum)
+
+	probes.TCP.Timeout, err = readDuration("HEALTH_TCP_TIMEOUT")
+	if err != nil {
+		return probes, err
+	}
+
+	probes.HTTP.URL = env.Get("HEALTH_HTTP_URL", env.ForceLowercase(false))
+	probes.HTTP.Status, err = env.Int("HEALTH_HTTP_STATUS")
+	if err != nil {
+		return probes, fmt.Errorf("environment variable HEALTH_HTTP_STATUS: %w", err)
+	}
+	probes.HTTP.Timeout, err = readDuration("HEALTH_HTTP_TIMEOUT")
+	if err != nil {
+		return probes, err
+	}
+
+	probes.DNS.Server = env.Get("HEALTH_DNS_SERVER")
+	probes.DNS.Hostname = env.Get("HEALTH_DNS_HOSTNAME")
+	probes.DNS.Timeout, err = readDuration("HEALTH_DNS_TIMEOUT")
+	if err != nil {
+		return probes, err
+	}
+
+	icmpTarget := env.Get("HEALTH_ICMP_TARGET")
+	if icmpTarget != "" {
+		probes.ICMP.Target, err = netip.ParseAddr(icmpTarget)
+		if err != nil {
+			return probes, fmt.Errorf("environment variable HEALTH_ICMP_TARGET: %w", err)
+		}
+	}
+	probes.ICMP.Timeout, err = readDuration("HEALTH_ICMP_TIMEOUT")
+	if err != nil {
+		return probes, err
+	}
+
+	return probes, nil
+}
+
+var ErrHealthQuorumNegative = errors.New("quorum cannot be negative")
+
+func readDuration(envKey string) (duration time.Duration, err error) {
+	durationPtr, err := env.DurationPtr(envKey)
+	if err != nil {
+		return 0, fmt.Errorf("environment variable %s: %w", envKey, err)
+	} else if durationPtr == nil {
+		return 0, nil
+	}
+	return *durationPtr, nil
+}
+
 func (s *Source) readDurationWithRetro(envKey, retroEnvKey string) (d *time.Duration, err error) {
 	envKey, value := s.getEnvWithRetro(envKey, []string{retroEnvKey})
 	if value == "" {
@@ -11,6 +11,7 @@ import (
 func (s *Source) readHTTPProxy() (httpProxy settings.HTTPProxy, err error) {
 	httpProxy.User = s.readHTTProxyUser()
 	httpProxy.Password = s.readHTTProxyPassword()
+	httpProxy.CredentialsFile = env.StringPtr("HTTPPROXY_CREDENTIALS_FILE", env.ForceLowercase(false))
 	httpProxy.ListeningAddress = s.readHTTProxyListeningAddress()
 
 	httpProxy.Enabled, err = s.readHTTProxyEnabled()
@@ -28,6 +29,27 @@ func (s *Source) readHTTPProxy() (httpProxy settings.HTTPProxy, err error) {
 		return httpProxy, err
 	}
 
+	httpProxy.AllowedClients, err = stringsToNetipPrefixes(env.CSV("HTTPPROXY_ALLOWED_CLIENTS"))
+	if err != nil {
+		return httpProxy, fmt.Errorf("environment variable HTTPPROXY_ALLOWED_CLIENTS: %w", err)
+	}
+
+	httpProxy.AllowedDestinations = env.CSV("HTTPPROXY_ALLOWED_DESTINATIONS")
+	httpProxy.BlockedDestinations = env.CSV("HTTPPROXY_BLOCKED_DESTINATIONS")
+	httpProxy.DefaultPolicy = env.Get("HTTPPROXY_DEFAULT_POLICY")
+
+	httpProxy.PAC, err = env.BoolPtr("HTTPPROXY_PAC")
+	if err != nil {
+		return httpProxy, fmt.Errorf("environment variable HTTPPROXY_PAC: %w", err)
+	}
+
+	httpProxy.WPAD, err = env.BoolPtr("HTTPPROXY_PAC_WPAD")
+	if err != nil {
+		return httpProxy, fmt.Errorf("environment variable HTTPPROXY_PAC_WPAD: %w", err)
+	}
+
+	httpProxy.PACBypass = env.CSV("HTTPPROXY_PAC_BYPASS")
+
 	return httpProxy, nil
 }
 
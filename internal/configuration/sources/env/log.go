@@ -16,6 +16,18 @@ func readLog() (log settings.Log, err error) {
 		return log, err
 	}
 
+	log.AccessLog = env.StringPtr("ACCESS_LOG", env.ForceLowercase(false))
+
+	log.AccessLogMaxSize, err = env.IntPtr("ACCESS_LOG_MAX_SIZE")
+	if err != nil {
+		return log, err
+	}
+
+	log.AccessLogMaxBackups, err = env.IntPtr("ACCESS_LOG_MAX_BACKUPS")
+	if err != nil {
+		return log, err
+	}
+
 	return log, nil
 }
 
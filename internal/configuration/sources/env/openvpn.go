@@ -13,7 +13,8 @@ func (s *Source) readOpenVPN() (
 	openVPN settings.OpenVPN, err error) {
 	defer func() {
 		err = unsetEnvKeys([]string{"OPENVPN_KEY", "OPENVPN_CERT",
-			"OPENVPN_KEY_PASSPHRASE", "OPENVPN_ENCRYPTED_KEY"}, err)
+			"OPENVPN_KEY_PASSPHRASE", "OPENVPN_ENCRYPTED_KEY",
+			"OPENVPN_TOTP_SECRET"}, err)
 	}()
 
 	openVPN.Version = env.Get("OPENVPN_VERSION")
@@ -38,6 +39,13 @@ func (s *Source) readOpenVPN() (
 
 	openVPN.KeyPassphrase = s.readOpenVPNKeyPassphrase()
 
+	openVPN.TOTPSecret = env.StringPtr("OPENVPN_TOTP_SECRET", env.ForceLowercase(false))
+
+	openVPN.Challenge, err = env.BoolPtr("OPENVPN_AUTH_CHALLENGE")
+	if err != nil {
+		return openVPN, fmt.Errorf("environment variable OPENVPN_AUTH_CHALLENGE: %w", err)
+	}
+
 	openVPN.PIAEncPreset = s.readPIAEncryptionPreset()
 
 	openVPN.MSSFix, err = env.Uint16Ptr("OPENVPN_MSSFIX")
@@ -1,7 +1,9 @@
 package env
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/qdm12/gluetun/internal/configuration/settings"
@@ -16,9 +18,42 @@ func (s *Source) readPublicIP() (publicIP settings.PublicIP, err error) {
 
 	publicIP.IPFilepath = s.readPublicIPFilepath()
 
+	publicIP.APIs = env.CSV("PUBLICIP_API")
+	publicIP.IPInfoToken = env.StringPtr("PUBLICIP_IPINFO_TOKEN", env.ForceLowercase(false))
+	publicIP.CustomAPI.URL = env.StringPtr("PUBLICIP_CUSTOM_URL", env.ForceLowercase(false))
+	publicIP.CustomAPI.Fields, err = readPublicIPCustomFields()
+	if err != nil {
+		return publicIP, err
+	}
+
+	publicIP.Exit.Countries = env.CSV("PUBLICIP_EXIT_COUNTRIES")
+	publicIP.Exit.Organizations = env.CSV("PUBLICIP_EXIT_ORGANIZATIONS", env.ForceLowercase(false))
+	publicIP.Exit.Action = env.StringPtr("PUBLICIP_EXIT_ACTION")
+
 	return publicIP, nil
 }
 
+var ErrPublicIPCustomFieldMalformed = errors.New("custom field mapping is malformed")
+
+func readPublicIPCustomFields() (fields map[string]string, err error) {
+	mappings := env.CSV("PUBLICIP_CUSTOM_FIELDS", env.ForceLowercase(false))
+	if len(mappings) == 0 {
+		return nil, nil
+	}
+
+	fields = make(map[string]string, len(mappings))
+	for _, mapping := range mappings {
+		field, path, ok := strings.Cut(mapping, "=")
+		if !ok || field == "" || path == "" {
+			return nil, fmt.Errorf("environment variable PUBLICIP_CUSTOM_FIELDS: %w: %q "+
+				"must be in the form field=json.path",
+				ErrPublicIPCustomFieldMalformed, mapping)
+		}
+		fields[strings.ToLower(strings.TrimSpace(field))] = strings.TrimSpace(path)
+	}
+	return fields, nil
+}
+
 func readPublicIPPeriod() (period *time.Duration, err error) {
 	s := env.Get("PUBLICIP_PERIOD")
 	if s == "" {
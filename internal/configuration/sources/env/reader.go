@@ -7,6 +7,11 @@ import (
 
 type Source struct {
 	warner Warner
+	// cached are the settings from the first successful read.
+	// Environment variables cannot change for a running process,
+	// and secret ones are unset once read, so subsequent reads such
+	// as on configuration reloads return these cached settings.
+	cached *settings.Settings
 }
 
 type Warner interface {
@@ -22,6 +27,10 @@ func New(warner Warner) *Source {
 func (s *Source) String() string { return "environment variables" }
 
 func (s *Source) Read() (settings settings.Settings, err error) {
+	if s.cached != nil {
+		return *s.cached, nil
+	}
+
 	settings.VPN, err = s.readVPN()
 	if err != nil {
 		return settings, err
@@ -57,7 +66,7 @@ func (s *Source) Read() (settings settings.Settings, err error) {
 		return settings, err
 	}
 
-	settings.Updater, err = readUpdater()
+	settings.Updater, err = s.readUpdater()
 	if err != nil {
 		return settings, err
 	}
@@ -72,6 +81,11 @@ func (s *Source) Read() (settings settings.Settings, err error) {
 		return settings, err
 	}
 
+	settings.Shaping, err = readShaping()
+	if err != nil {
+		return settings, err
+	}
+
 	settings.DNS, err = s.readDNS()
 	if err != nil {
 		return settings, err
@@ -82,11 +96,17 @@ func (s *Source) Read() (settings settings.Settings, err error) {
 		return settings, err
 	}
 
+	settings.WireguardServer, err = readWireguardServer()
+	if err != nil {
+		return settings, err
+	}
+
 	settings.Pprof, err = readPprof()
 	if err != nil {
 		return settings, err
 	}
 
+	s.cached = &settings
 	return settings, nil
 }
 
@@ -97,6 +97,11 @@ func (s *Source) readServerSelection(vpnProvider, vpnType string) (
 		return ss, fmt.Errorf("environment variable STREAM_ONLY: %w", err)
 	}
 
+	ss.ReachableOnly, err = env.BoolPtr("REACHABLE_ONLY")
+	if err != nil {
+		return ss, fmt.Errorf("environment variable REACHABLE_ONLY: %w", err)
+	}
+
 	ss.OpenVPN, err = s.readOpenVPNSelection()
 	if err != nil {
 		return ss, err
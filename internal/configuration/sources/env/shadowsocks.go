@@ -21,6 +21,11 @@ func (s *Source) readShadowsocks() (shadowsocks settings.Shadowsocks, err error)
 	}
 	shadowsocks.CipherName = s.readShadowsocksCipher()
 	shadowsocks.Password = env.StringPtr("SHADOWSOCKS_PASSWORD", env.ForceLowercase(false))
+	shadowsocks.Users, err = settings.ParseShadowsocksUsers(
+		env.Get("SHADOWSOCKS_USERS", env.ForceLowercase(false)))
+	if err != nil {
+		return shadowsocks, fmt.Errorf("environment variable SHADOWSOCKS_USERS: %w", err)
+	}
 
 	return shadowsocks, nil
 }
@@ -0,0 +1,49 @@
+package env
+
+import (
+	"fmt"
+
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/qdm12/gluetun/internal/ratelimit"
+	"github.com/qdm12/gosettings/sources/env"
+)
+
+func readShaping() (shaping settings.Shaping, err error) {
+	shaping.Upload, err = readRate("SHAPING_UPLOAD")
+	if err != nil {
+		return shaping, err
+	}
+
+	shaping.Download, err = readRate("SHAPING_DOWNLOAD")
+	if err != nil {
+		return shaping, err
+	}
+
+	shaping.ClientUpload, err = readRate("SHAPING_CLIENT_UPLOAD")
+	if err != nil {
+		return shaping, err
+	}
+
+	shaping.ClientDownload, err = readRate("SHAPING_CLIENT_DOWNLOAD")
+	if err != nil {
+		return shaping, err
+	}
+
+	return shaping, nil
+}
+
+// readRate reads a number of bytes per second from the environment
+// variable given, with an optional k, m or g suffix.
+func readRate(key string) (bytesPerSecond *uint64, err error) {
+	s := env.Get(key)
+	if s == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	value, err := ratelimit.ParseRate(s)
+	if err != nil {
+		return nil, fmt.Errorf("environment variable %s: %w", key, err)
+	}
+
+	return &value, nil
+}
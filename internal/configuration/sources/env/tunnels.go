@@ -0,0 +1,124 @@
+package env
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/qdm12/gluetun/internal/constants"
+	"github.com/qdm12/gosettings/sources/env"
+	"github.com/qdm12/govalid/port"
+)
+
+func readTunnels() (tunnels []settings.Tunnel, err error) {
+	names := env.CSV("VPN_TUNNELS")
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	tunnels = make([]settings.Tunnel, len(names))
+	for i, name := range names {
+		tunnels[i], err = readTunnel(name)
+		if err != nil {
+			return nil, fmt.Errorf("tunnel %s: %w", name, err)
+		}
+	}
+	return tunnels, nil
+}
+
+func readTunnel(name string) (tunnel settings.Tunnel, err error) {
+	prefix := "VPN_TUNNEL_" + strings.ToUpper(name) + "_"
+	passwordKey := prefix + "OPENVPN_PASSWORD"
+	privateKeyKey := prefix + "WIREGUARD_PRIVATE_KEY"
+	preSharedKeyKey := prefix + "WIREGUARD_PRESHARED_KEY"
+	defer func() {
+		err = unsetEnvKeys([]string{passwordKey, privateKeyKey, preSharedKeyKey}, err)
+	}()
+
+	tunnel.Name = name
+	tunnel.Type = env.Get(prefix + "TYPE")
+	tunnel.Provider, err = readTunnelProvider(prefix)
+	if err != nil {
+		return tunnel, err
+	}
+
+	intf := env.Get(prefix+"INTERFACE", env.ForceLowercase(false))
+	tunnel.OpenVPN.Interface = intf
+	tunnel.OpenVPN.User = env.StringPtr(prefix+"OPENVPN_USER", env.ForceLowercase(false))
+	tunnel.OpenVPN.Password = env.StringPtr(passwordKey, env.ForceLowercase(false))
+
+	tunnel.Wireguard.Interface = intf
+	tunnel.Wireguard.PrivateKey = env.StringPtr(privateKeyKey, env.ForceLowercase(false))
+	tunnel.Wireguard.PreSharedKey = env.StringPtr(preSharedKeyKey, env.ForceLowercase(false))
+	tunnel.Wireguard.Addresses, err = stringsToNetipPrefixes(env.CSV(prefix + "WIREGUARD_ADDRESSES"))
+	if err != nil {
+		return tunnel, fmt.Errorf("environment variable %sWIREGUARD_ADDRESSES: %w", prefix, err)
+	}
+
+	tunnel.Sources, err = stringsToNetipPrefixes(env.CSV(prefix + "SOURCES"))
+	if err != nil {
+		return tunnel, fmt.Errorf("environment variable %sSOURCES: %w", prefix, err)
+	}
+
+	tunnel.Destinations, err = stringsToNetipPrefixes(env.CSV(prefix + "DESTINATIONS"))
+	if err != nil {
+		return tunnel, fmt.Errorf("environment variable %sDESTINATIONS: %w", prefix, err)
+	}
+
+	return tunnel, nil
+}
+
+func readTunnelProvider(prefix string) (provider settings.Provider, err error) {
+	provider.Name = env.StringPtr(prefix + "VPN_SERVICE_PROVIDER")
+
+	selection := &provider.ServerSelection
+	selection.Countries = env.CSV(prefix + "SERVER_COUNTRIES")
+	selection.Regions = env.CSV(prefix + "SERVER_REGIONS")
+	selection.Cities = env.CSV(prefix + "SERVER_CITIES")
+	selection.Hostnames = env.CSV(prefix + "SERVER_HOSTNAMES")
+	selection.Names = env.CSV(prefix + "SERVER_NAMES")
+
+	endpointIPKey := prefix + "VPN_ENDPOINT_IP"
+	if value := env.Get(endpointIPKey); value != "" {
+		selection.Wireguard.EndpointIP, err = netip.ParseAddr(value)
+		if err != nil {
+			return provider, fmt.Errorf("environment variable %s: %w", endpointIPKey, err)
+		}
+		selection.TargetIP = selection.Wireguard.EndpointIP
+	}
+
+	endpointPortKey := prefix + "VPN_ENDPOINT_PORT"
+	if value := env.Get(endpointPortKey); value != "" {
+		endpointPort, err := port.Validate(value)
+		if err != nil {
+			return provider, fmt.Errorf("environment variable %s: %w", endpointPortKey, err)
+		}
+		selection.Wireguard.EndpointPort = ptrTo(endpointPort)
+		selection.OpenVPN.CustomPort = ptrTo(endpointPort)
+	}
+
+	selection.OpenVPN.TCP, err = readTunnelOpenVPNProtocol(prefix)
+	if err != nil {
+		return provider, err
+	}
+
+	selection.Wireguard.PublicKey = env.Get(prefix+"WIREGUARD_PUBLIC_KEY", env.ForceLowercase(false))
+
+	return provider, nil
+}
+
+func readTunnelOpenVPNProtocol(prefix string) (tcp *bool, err error) {
+	key := prefix + "OPENVPN_PROTOCOL"
+	switch protocol := env.Get(key); protocol {
+	case "":
+		return nil, nil //nolint:nilnil
+	case constants.UDP:
+		return ptrTo(false), nil
+	case constants.TCP:
+		return ptrTo(true), nil
+	default:
+		return nil, fmt.Errorf("environment variable %s: %w: %s",
+			key, ErrOpenVPNProtocolNotValid, protocol)
+	}
+}
@@ -2,13 +2,14 @@ package env
 
 import (
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/qdm12/gluetun/internal/configuration/settings"
 	"github.com/qdm12/gosettings/sources/env"
 )
 
-func readUpdater() (updater settings.Updater, err error) {
+func (s *Source) readUpdater() (updater settings.Updater, err error) {
 	updater.Period, err = readUpdaterPeriod()
 	if err != nil {
 		return updater, err
@@ -19,11 +20,34 @@ func readUpdater() (updater settings.Updater, err error) {
 		return updater, err
 	}
 
-	updater.MinRatio, err = env.Float64("UPDATER_MIN_RATIO")
+	updater.DNSProtocol = env.Get("UPDATER_DNS_PROTOCOL")
+	updater.DNSProviders = env.CSV("UPDATER_DNS_PROVIDERS")
+
+	updater.Threshold, err = s.readUpdaterThreshold()
+	if err != nil {
+		return updater, err
+	}
+
+	updater.ThresholdAction = env.Get("UPDATER_THRESHOLD_ACTION")
+
+	updater.Probe, err = env.BoolPtr("UPDATER_PROBE")
+	if err != nil {
+		return updater, fmt.Errorf("environment variable UPDATER_PROBE: %w", err)
+	}
+
+	updater.ProbeTimeout, err = env.DurationPtr("UPDATER_PROBE_TIMEOUT")
+	if err != nil {
+		return updater, fmt.Errorf("environment variable UPDATER_PROBE_TIMEOUT: %w", err)
+	}
+
+	updater.ProbeParallelism, err = env.Int("UPDATER_PROBE_PARALLELISM")
 	if err != nil {
-		return updater, fmt.Errorf("environment variable UPDATER_MIN_RATIO: %w", err)
+		return updater, fmt.Errorf("environment variable UPDATER_PROBE_PARALLELISM: %w", err)
 	}
 
+	updater.MirrorURL = env.Get("UPDATER_MIRROR_URL")
+	updater.MirrorPublicKey = env.Get("UPDATER_MIRROR_PUBLIC_KEY")
+
 	updater.Providers = env.CSV("UPDATER_VPN_SERVICE_PROVIDERS")
 
 	return updater, nil
@@ -42,6 +66,34 @@ func readUpdaterPeriod() (period *time.Duration, err error) {
 	return period, nil
 }
 
+// readUpdaterThreshold reads the updater threshold, falling back
+// on the retro-compatible minimum ratio of servers to find only
+// if the threshold is not set.
+func (s *Source) readUpdaterThreshold() (threshold float64, err error) {
+	if env.Get("UPDATER_THRESHOLD") != "" {
+		threshold, err = env.Float64("UPDATER_THRESHOLD")
+		if err != nil {
+			return 0, fmt.Errorf("environment variable UPDATER_THRESHOLD: %w", err)
+		}
+		return threshold, nil
+	}
+
+	// TODO remove in v4
+	minRatio, err := env.Float64("UPDATER_MIN_RATIO")
+	if err != nil {
+		return 0, fmt.Errorf("environment variable UPDATER_MIN_RATIO: %w", err)
+	} else if minRatio == 0 {
+		return 0, nil
+	}
+
+	const precision = 1000
+	threshold = math.Round((1-minRatio)*precision) / precision
+	s.warner.Warn(fmt.Sprintf("You are using the old environment variable "+
+		"UPDATER_MIN_RATIO=%g, please consider changing it to "+
+		"UPDATER_THRESHOLD=%g", minRatio, threshold))
+	return threshold, nil
+}
+
 func readUpdaterDNSAddress() (address string, err error) {
 	// TODO this is currently using Cloudflare in
 	// plaintext to not be blocked by DNS over TLS by default.
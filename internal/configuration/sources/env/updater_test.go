@@ -0,0 +1,72 @@
+package env
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testWarner struct {
+	warnings []string
+}
+
+func (w *testWarner) Warn(s string) {
+	w.warnings = append(w.warnings, s)
+}
+
+//nolint:paralleltest
+func Test_Source_readUpdaterThreshold(t *testing.T) {
+	// Test cases are not run in parallel since the
+	// environment variable keys are not test specific.
+	testCases := map[string]struct {
+		threshold  string
+		minRatio   string
+		expected   float64
+		warnings   []string
+		errMessage string
+	}{
+		"unset": {},
+		"threshold": {
+			threshold: "0.2",
+			expected:  0.2,
+		},
+		"min ratio": {
+			minRatio: "0.8",
+			expected: 0.2,
+			warnings: []string{"You are using the old environment variable " +
+				"UPDATER_MIN_RATIO=0.8, please consider changing it to " +
+				"UPDATER_THRESHOLD=0.2"},
+		},
+		"threshold and min ratio": {
+			threshold: "0.3",
+			minRatio:  "0.8",
+			expected:  0.3,
+		},
+		"threshold not valid": {
+			threshold: "x",
+			minRatio:  "0.8",
+			errMessage: `environment variable UPDATER_THRESHOLD: ` +
+				`strconv.ParseFloat: parsing "x": invalid syntax`,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("UPDATER_THRESHOLD", testCase.threshold)
+			t.Setenv("UPDATER_MIN_RATIO", testCase.minRatio)
+			warner := &testWarner{}
+			source := &Source{warner: warner}
+
+			threshold, err := source.readUpdaterThreshold()
+
+			if testCase.errMessage != "" {
+				assert.EqualError(t, err, testCase.errMessage)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, testCase.expected, threshold)
+			assert.Equal(t, testCase.warnings, warner.warnings)
+		})
+	}
+}
@@ -26,5 +26,10 @@ func (s *Source) readVPN() (vpn settings.VPN, err error) {
 		return vpn, fmt.Errorf("wireguard: %w", err)
 	}
 
+	vpn.Tunnels, err = readTunnels()
+	if err != nil {
+		return vpn, fmt.Errorf("tunnels: %w", err)
+	}
+
 	return vpn, nil
 }
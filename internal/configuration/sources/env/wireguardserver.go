@@ -0,0 +1,118 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/qdm12/gosettings/sources/env"
+)
+
+func readWireguardServer() (server settings.WireguardServer, err error) {
+	defer func() {
+		err = unsetEnvKeys([]string{"WIREGUARD_SERVER_PRIVATE_KEY"}, err)
+	}()
+
+	server.Enabled, err = env.BoolPtr("WIREGUARD_SERVER")
+	if err != nil {
+		return server, fmt.Errorf("environment variable WIREGUARD_SERVER: %w", err)
+	}
+
+	server.Interface = env.Get("WIREGUARD_SERVER_INTERFACE", env.ForceLowercase(false))
+
+	server.ListeningPort, err = env.Uint16Ptr("WIREGUARD_SERVER_PORT")
+	if err != nil {
+		return server, fmt.Errorf("environment variable WIREGUARD_SERVER_PORT: %w", err)
+	}
+
+	server.PrivateKey = env.StringPtr("WIREGUARD_SERVER_PRIVATE_KEY", env.ForceLowercase(false))
+
+	address := env.Get("WIREGUARD_SERVER_ADDRESS")
+	if address != "" {
+		server.Address, err = netip.ParsePrefix(address)
+		if err != nil {
+			return server, fmt.Errorf("environment variable WIREGUARD_SERVER_ADDRESS: %w", err)
+		}
+	}
+
+	server.Endpoint = env.StringPtr("WIREGUARD_SERVER_ENDPOINT", env.ForceLowercase(false))
+
+	server.Peers, err = readWireguardServerPeers()
+	if err != nil {
+		return server, fmt.Errorf("environment variable WIREGUARD_SERVER_PEERS: %w", err)
+	}
+
+	return server, nil
+}
+
+var ErrWireguardServerPeerMalformed = errors.New("peer is malformed")
+
+// readWireguardServerPeers reads peers from a comma separated list
+// of peers in the format name:publickey:address[:presharedkey], where
+// IPv6 addresses must be enclosed in square brackets.
+func readWireguardServerPeers() (peers []settings.WireguardServerPeer, err error) {
+	peersCSV := env.CSV("WIREGUARD_SERVER_PEERS", env.ForceLowercase(false))
+	if len(peersCSV) == 0 {
+		return nil, nil
+	}
+
+	peers = make([]settings.WireguardServerPeer, len(peersCSV))
+	for i, peerString := range peersCSV {
+		peers[i], err = parseWireguardServerPeer(peerString)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return peers, nil
+}
+
+func parseWireguardServerPeer(s string) (peer settings.WireguardServerPeer, err error) {
+	malformedErr := fmt.Errorf("%w: %q must be in the format "+
+		"name:publickey:address[:presharedkey] or "+
+		"name:publickey:[ipv6address][:presharedkey]",
+		ErrWireguardServerPeerMalformed, s)
+
+	name, rest, ok := strings.Cut(s, ":")
+	if !ok {
+		return peer, malformedErr
+	}
+	publicKey, rest, ok := strings.Cut(rest, ":")
+	if !ok {
+		return peer, malformedErr
+	}
+
+	var address, preSharedKey string
+	if strings.HasPrefix(rest, "[") {
+		var after string
+		address, after, ok = strings.Cut(rest[1:], "]")
+		if !ok {
+			return peer, malformedErr
+		}
+		if after != "" {
+			preSharedKey, ok = strings.CutPrefix(after, ":")
+			if !ok {
+				return peer, malformedErr
+			}
+		}
+	} else {
+		address, preSharedKey, _ = strings.Cut(rest, ":")
+	}
+
+	if strings.Contains(preSharedKey, ":") {
+		return peer, malformedErr
+	}
+
+	peerAddress, err := netip.ParseAddr(address)
+	if err != nil {
+		return peer, fmt.Errorf("peer %s: %w", name, err)
+	}
+
+	return settings.WireguardServerPeer{
+		Name:         name,
+		PublicKey:    publicKey,
+		PreSharedKey: preSharedKey,
+		Address:      peerAddress,
+	}, nil
+}
@@ -0,0 +1,102 @@
+package env
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseWireguardServerPeer(t *testing.T) {
+	t.Parallel()
+
+	const publicKey = "aPjc9US5ICB30D1P4glR9tO7bkB2Ga+KZiFqnoypBHk="
+	const preSharedKey = "8Y2s5z0e0bA2v5nVvN3nIyYtBhb2Q6r6LQ0+lQ5j1hM="
+
+	testCases := map[string]struct {
+		s          string
+		peer       settings.WireguardServerPeer
+		errWrapped error
+		errMessage string
+	}{
+		"ipv4": {
+			s: "phone:" + publicKey + ":10.13.13.2",
+			peer: settings.WireguardServerPeer{
+				Name:      "phone",
+				PublicKey: publicKey,
+				Address:   netip.MustParseAddr("10.13.13.2"),
+			},
+		},
+		"ipv4_with_preshared_key": {
+			s: "phone:" + publicKey + ":10.13.13.2:" + preSharedKey,
+			peer: settings.WireguardServerPeer{
+				Name:         "phone",
+				PublicKey:    publicKey,
+				PreSharedKey: preSharedKey,
+				Address:      netip.MustParseAddr("10.13.13.2"),
+			},
+		},
+		"ipv6": {
+			s: "phone:" + publicKey + ":[fd00::2]",
+			peer: settings.WireguardServerPeer{
+				Name:      "phone",
+				PublicKey: publicKey,
+				Address:   netip.MustParseAddr("fd00::2"),
+			},
+		},
+		"ipv6_with_preshared_key": {
+			s: "phone:" + publicKey + ":[fd00::2]:" + preSharedKey,
+			peer: settings.WireguardServerPeer{
+				Name:         "phone",
+				PublicKey:    publicKey,
+				PreSharedKey: preSharedKey,
+				Address:      netip.MustParseAddr("fd00::2"),
+			},
+		},
+		"missing_address": {
+			s:          "phone:" + publicKey,
+			errWrapped: ErrWireguardServerPeerMalformed,
+			errMessage: `peer is malformed: "phone:` + publicKey + `" must be in the format ` +
+				`name:publickey:address[:presharedkey] or ` +
+				`name:publickey:[ipv6address][:presharedkey]`,
+		},
+		"ipv6_without_brackets": {
+			s:          "phone:" + publicKey + ":fd00::2",
+			errWrapped: ErrWireguardServerPeerMalformed,
+			errMessage: `peer is malformed: "phone:` + publicKey + `:fd00::2" must be in the format ` +
+				`name:publickey:address[:presharedkey] or ` +
+				`name:publickey:[ipv6address][:presharedkey]`,
+		},
+		"ipv6_missing_closing_bracket": {
+			s:          "phone:" + publicKey + ":[fd00::2",
+			errWrapped: ErrWireguardServerPeerMalformed,
+			errMessage: `peer is malformed: "phone:` + publicKey + `:[fd00::2" must be in the format ` +
+				`name:publickey:address[:presharedkey] or ` +
+				`name:publickey:[ipv6address][:presharedkey]`,
+		},
+		"invalid_address": {
+			s:          "phone:" + publicKey + ":x",
+			errMessage: `peer phone: ParseAddr("x"): unable to parse IP`,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			peer, err := parseWireguardServerPeer(testCase.s)
+
+			if testCase.errWrapped != nil {
+				assert.ErrorIs(t, err, testCase.errWrapped)
+			}
+			if testCase.errMessage != "" {
+				assert.EqualError(t, err, testCase.errMessage)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, testCase.peer, peer)
+		})
+	}
+}
@@ -12,6 +12,16 @@ func New() *Source {
 
 func (s *Source) String() string { return "files" }
 
+// Filepaths returns the paths of the files read by the source,
+// which may not exist.
+func (s *Source) Filepaths() (paths []string) {
+	return []string{
+		OpenVPNClientKeyPath,
+		OpenVPNClientCertificatePath,
+		openVPNEncryptedKey,
+	}
+}
+
 func (s *Source) Read() (settings settings.Settings, err error) {
 	settings.VPN, err = s.readVPN()
 	if err != nil {
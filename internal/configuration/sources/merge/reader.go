@@ -3,6 +3,7 @@ package merge
 import (
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/qdm12/gluetun/internal/configuration/settings"
 )
@@ -13,8 +14,28 @@ type ConfigSource interface {
 	String() string
 }
 
+// Source merges settings read from multiple sources.
+//
+// Sources given first take precedence over the next ones: a field
+// is only taken from a source if it is unset in all the previous
+// sources. Gluetun uses, from highest to lowest precedence:
+//   - the structured YAML or TOML configuration file
+//   - environment variables
+//   - files, such as the OpenVPN client key and certificate
+//   - secret files
+//
+// The configuration file comes first since environment variables
+// all have default values set in the Docker image, which would
+// otherwise always override the configuration file. Note most list
+// fields, such as firewall input ports, are merged together
+// across sources instead of being overridden.
 type Source struct {
 	sources []ConfigSource
+	// sourcesSettings are the settings read from each
+	// source during the last Read call, used to locate
+	// validation errors.
+	sourcesSettings      []settings.Settings
+	sourcesSettingsMutex sync.Mutex
 }
 
 func New(sources ...ConfigSource) *Source {
@@ -31,19 +52,91 @@ func (s *Source) String() string {
 	return strings.Join(sources, ", ")
 }
 
+// Filepaths returns the file paths read by the sources
+// implementing a Filepaths method.
+func (s *Source) Filepaths() (paths []string) {
+	for _, source := range s.sources {
+		filepathsSource, ok := source.(interface{ Filepaths() []string })
+		if !ok {
+			continue
+		}
+		paths = append(paths, filepathsSource.Filepaths()...)
+	}
+	return paths
+}
+
 // Read reads the settings for each source, merging unset fields
 // with field set by the next source.
 // It then set defaults to remaining unset fields.
 func (s *Source) Read() (settings settings.Settings, err error) {
-	for _, source := range s.sources {
-		settingsFromSource, err := source.Read()
+	sourcesSettings, err := s.readSources()
+	if err != nil {
+		return settings, err
+	}
+
+	s.sourcesSettingsMutex.Lock()
+	s.sourcesSettings = sourcesSettings
+	s.sourcesSettingsMutex.Unlock()
+
+	return mergeSettings(sourcesSettings), nil
+}
+
+func (s *Source) readSources() (sourcesSettings []settings.Settings, err error) {
+	sourcesSettings = make([]settings.Settings, len(s.sources))
+	for i, source := range s.sources {
+		sourcesSettings[i], err = source.Read()
 		if err != nil {
-			return settings, fmt.Errorf("reading from %s: %w", source, err)
+			return nil, fmt.Errorf("reading from %s: %w", source, err)
 		}
-		settings.MergeWith(settingsFromSource)
 	}
-	settings.SetDefaults()
-	return settings, nil
+	return sourcesSettings, nil
+}
+
+func mergeSettings(sourcesSettings []settings.Settings) (merged settings.Settings) {
+	for _, sourceSettings := range sourcesSettings {
+		merged.MergeWith(sourceSettings)
+	}
+	merged.SetDefaults()
+	return merged
+}
+
+// ErrorLocator is a source able to locate the value causing
+// a validation error, such as the configuration file source.
+type ErrorLocator interface {
+	LocateError(validationErr error,
+		validate func(sourceSettings settings.Settings) error) error
+}
+
+// LocateError returns the validation error given wrapped with the
+// location of the value causing it, for sources implementing the
+// ErrorLocator interface. The validate function is called with
+// settings merged as in the last Read call, where the settings
+// of one source are modified to find the value causing the error.
+// The error is returned unchanged if no value is found.
+func (s *Source) LocateError(validationErr error,
+	validate func(settings settings.Settings) error,
+) error {
+	s.sourcesSettingsMutex.Lock()
+	sourcesSettings := s.sourcesSettings
+	s.sourcesSettingsMutex.Unlock()
+
+	for i, source := range s.sources {
+		locator, ok := source.(ErrorLocator)
+		if !ok || i >= len(sourcesSettings) {
+			continue
+		}
+
+		err := locator.LocateError(validationErr, func(sourceSettings settings.Settings) error {
+			modified := make([]settings.Settings, len(sourcesSettings))
+			copy(modified, sourcesSettings)
+			modified[i] = sourceSettings
+			return validate(mergeSettings(modified))
+		})
+		if err != validationErr { //nolint:errorlint
+			return err
+		}
+	}
+	return validationErr
 }
 
 // ReadHealth reads the health settings for each source, merging unset fields
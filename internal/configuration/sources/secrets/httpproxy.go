@@ -2,8 +2,10 @@ package secrets
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/qdm12/gosettings/sources/env"
 )
 
 func readHTTPProxy() (settings settings.HTTPProxy, err error) {
@@ -23,5 +25,28 @@ func readHTTPProxy() (settings settings.HTTPProxy, err error) {
 		return settings, fmt.Errorf("reading HTTP proxy password secret file: %w", err)
 	}
 
+	settings.CredentialsFile, err = readHTTPProxyCredentialsPath()
+	if err != nil {
+		return settings, fmt.Errorf("reading HTTP proxy credentials secret file: %w", err)
+	}
+
 	return settings, nil
 }
+
+// readHTTPProxyCredentialsPath returns the path of the credentials
+// secret file if it exists, since the HTTP proxy reads and watches
+// the file itself to reload it when it changes.
+func readHTTPProxyCredentialsPath() (path *string, err error) {
+	credentialsPath := env.Get("HTTPPROXY_CREDENTIALS_SECRETFILE", env.ForceLowercase(false))
+	if credentialsPath == "" {
+		credentialsPath = "/run/secrets/httpproxy_credentials"
+	}
+
+	_, err = os.Stat(credentialsPath)
+	if os.IsNotExist(err) {
+		return nil, nil //nolint:nilnil
+	} else if err != nil {
+		return nil, err
+	}
+	return &credentialsPath, nil
+}
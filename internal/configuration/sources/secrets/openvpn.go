@@ -48,6 +48,14 @@ func readOpenVPN() (
 		return settings, fmt.Errorf("reading key passphrase file: %w", err)
 	}
 
+	settings.TOTPSecret, err = readSecretFileAsStringPtr(
+		"OPENVPN_TOTP_SECRET_SECRETFILE",
+		"/run/secrets/openvpn_totp_secret",
+	)
+	if err != nil {
+		return settings, fmt.Errorf("reading TOTP secret file: %w", err)
+	}
+
 	settings.Cert, err = readPEMSecretFile(
 		"OPENVPN_CLIENTCRT_SECRETFILE",
 		"/run/secrets/openvpn_clientcrt",
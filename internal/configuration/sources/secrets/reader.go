@@ -1,6 +1,9 @@
 package secrets
 
 import (
+	"os"
+	"strings"
+
 	"github.com/qdm12/gluetun/internal/configuration/settings"
 )
 
@@ -12,6 +15,19 @@ func New() *Source {
 
 func (s *Source) String() string { return "secret files" }
 
+// Filepaths returns the paths of the secret files, set by
+// the environment variables ending with _SECRETFILE.
+func (s *Source) Filepaths() (paths []string) {
+	for _, keyValue := range os.Environ() {
+		key, value, ok := strings.Cut(keyValue, "=")
+		if !ok || !strings.HasSuffix(key, "_SECRETFILE") || value == "" {
+			continue
+		}
+		paths = append(paths, value)
+	}
+	return paths
+}
+
 func (s *Source) Read() (settings settings.Settings, err error) {
 	settings.VPN, err = readVPN()
 	if err != nil {
@@ -6,14 +6,27 @@ import (
 	"github.com/qdm12/gluetun/internal/configuration/settings"
 )
 
-func readShadowsocks() (settings settings.Shadowsocks, err error) {
-	settings.Password, err = readSecretFileAsStringPtr(
+func readShadowsocks() (shadowsocks settings.Shadowsocks, err error) {
+	shadowsocks.Password, err = readSecretFileAsStringPtr(
 		"SHADOWSOCKS_PASSWORD_SECRETFILE",
 		"/run/secrets/shadowsocks_password",
 	)
 	if err != nil {
-		return settings, fmt.Errorf("reading Shadowsocks password secret file: %w", err)
+		return shadowsocks, fmt.Errorf("reading Shadowsocks password secret file: %w", err)
 	}
 
-	return settings, nil
+	users, err := readSecretFileAsStringPtr(
+		"SHADOWSOCKS_USERS_SECRETFILE",
+		"/run/secrets/shadowsocks_users",
+	)
+	if err != nil {
+		return shadowsocks, fmt.Errorf("reading Shadowsocks users secret file: %w", err)
+	} else if users != nil {
+		shadowsocks.Users, err = settings.ParseShadowsocksUsers(*users)
+		if err != nil {
+			return shadowsocks, fmt.Errorf("parsing Shadowsocks users secret file: %w", err)
+		}
+	}
+
+	return shadowsocks, nil
 }
@@ -40,6 +40,8 @@ func (c *Config) SetEnabled(ctx context.Context, enabled bool) (err error) {
 }
 
 func (c *Config) disable(ctx context.Context) (err error) {
+	// Gateway rules in the nat table are not cleared below
+	c.removeGateway(ctx)
 	if err = c.clearAllRules(ctx); err != nil {
 		return fmt.Errorf("clearing all rules: %w", err)
 	}
@@ -122,6 +124,10 @@ func (c *Config) enable(ctx context.Context) (err error) {
 		return err
 	}
 
+	if err = c.applyGateway(ctx); err != nil {
+		return fmt.Errorf("setting gateway: %w", err)
+	}
+
 	if err := c.runUserPostRules(ctx, c.customRulesPath, remove); err != nil {
 		return fmt.Errorf("running user defined post firewall rules: %w", err)
 	}
@@ -130,15 +136,17 @@ func (c *Config) enable(ctx context.Context) (err error) {
 }
 
 func (c *Config) allowVPNIP(ctx context.Context) (err error) {
-	if !c.vpnConnection.IP.IsValid() {
-		return nil
-	}
-
 	const remove = false
-	for _, defaultRoute := range c.defaultRoutes {
-		err = c.acceptOutputTrafficToVPN(ctx, defaultRoute.NetInterface, c.vpnConnection, remove)
-		if err != nil {
-			return fmt.Errorf("accepting output traffic through VPN: %w", err)
+	for _, tunnel := range c.tunnels {
+		if !tunnel.connection.IP.IsValid() {
+			continue
+		}
+
+		for _, defaultRoute := range c.defaultRoutes {
+			err = c.acceptOutputTrafficToVPN(ctx, defaultRoute.NetInterface, tunnel.connection, remove)
+			if err != nil {
+				return fmt.Errorf("accepting output traffic through VPN: %w", err)
+			}
 		}
 	}
 
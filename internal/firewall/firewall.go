@@ -5,7 +5,6 @@ import (
 	"net/netip"
 	"sync"
 
-	"github.com/qdm12/gluetun/internal/models"
 	"github.com/qdm12/gluetun/internal/routing"
 	"github.com/qdm12/golibs/command"
 )
@@ -25,11 +24,16 @@ type Config struct { //nolint:maligned
 
 	// State
 	enabled           bool
-	vpnConnection     models.Connection
-	vpnIntf           string
+	tunnels           map[string]tunnel // tunnel name to tunnel mapping
 	outboundSubnets   []netip.Prefix
 	allowedInputPorts map[uint16]map[string]struct{} // port to interfaces set mapping
-	stateMutex        sync.Mutex
+	// Gateway mode state
+	gatewaySubnets     []netip.Prefix
+	gatewayRedirectDNS bool
+	inboundTunnels     map[string]netip.Prefix // interface to client subnet mapping
+	gatewayApplied     []gatewayClient         // clients for which rules are set
+	gatewayIntf        string                  // VPN interface for which forwarding rules are set
+	stateMutex         sync.Mutex
 }
 
 // NewConfig creates a new Config instance and returns an error
@@ -50,6 +54,8 @@ func NewConfig(ctx context.Context, logger Logger,
 	return &Config{
 		runner:            runner,
 		logger:            logger,
+		tunnels:           make(map[string]tunnel),
+		inboundTunnels:    make(map[string]netip.Prefix),
 		allowedInputPorts: make(map[uint16]map[string]struct{}),
 		ipTables:          iptables,
 		ip6Tables:         ip6tables,
@@ -0,0 +1,215 @@
+package firewall
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+)
+
+// gatewayClient is a client subnet reaching Gluetun through an
+// interface, with its traffic forwarded through the main VPN interface.
+type gatewayClient struct {
+	intf   string
+	subnet netip.Prefix
+	// inbound is true for clients of an inbound tunnel, which are
+	// allowed all input instead of only DNS.
+	inbound bool
+}
+
+// SetGateway sets the client subnets allowed to use Gluetun as their
+// gateway, with their traffic forwarded and NATed only through the main
+// VPN interface, such that it is blocked if the VPN is down. If redirectDNS
+// is true, DNS queries from clients are redirected to the local DNS server.
+// An empty client subnets slice disables the gateway mode.
+func (c *Config) SetGateway(ctx context.Context, clientSubnets []netip.Prefix,
+	redirectDNS bool) (err error) {
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	if !c.enabled {
+		c.logger.Info("firewall disabled, only updating gateway internal state")
+		c.gatewaySubnets = clientSubnets
+		c.gatewayRedirectDNS = redirectDNS
+		return nil
+	}
+
+	c.removeGateway(ctx)
+	c.gatewaySubnets = clientSubnets
+	c.gatewayRedirectDNS = redirectDNS
+	return c.applyGateway(ctx)
+}
+
+// SetInboundTunnel allows clients of the inbound tunnel interface
+// given, such as a Wireguard server interface, to reach Gluetun and
+// to have their traffic forwarded and NATed through the main VPN
+// interface. An invalid subnet removes the inbound tunnel.
+func (c *Config) SetInboundTunnel(ctx context.Context, intf string,
+	subnet netip.Prefix) (err error) {
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	if c.enabled {
+		c.removeGateway(ctx)
+	}
+
+	if subnet.IsValid() {
+		c.inboundTunnels[intf] = subnet
+	} else {
+		delete(c.inboundTunnels, intf)
+	}
+
+	if !c.enabled {
+		return nil
+	}
+	return c.applyGateway(ctx)
+}
+
+func (c *Config) gatewayClients() (clients []gatewayClient) {
+	for _, subnet := range c.gatewaySubnets {
+		for _, defaultRoute := range c.defaultRoutes {
+			clients = append(clients, gatewayClient{
+				intf:   defaultRoute.NetInterface,
+				subnet: subnet,
+			})
+		}
+	}
+	for intf, subnet := range c.inboundTunnels {
+		clients = append(clients, gatewayClient{
+			intf:    intf,
+			subnet:  subnet,
+			inbound: true,
+		})
+	}
+	return clients
+}
+
+// applyGateway adds the gateway rules for the current gateway
+// state and main VPN interface. It must be called with the state
+// mutex locked.
+func (c *Config) applyGateway(ctx context.Context) (err error) {
+	clients := c.gatewayClients()
+	if len(clients) == 0 {
+		return nil
+	}
+
+	const remove = false
+	for _, client := range clients {
+		err = c.acceptGatewayInput(ctx, client, remove)
+		if err != nil {
+			return fmt.Errorf("accepting gateway clients input: %w", err)
+		}
+		c.gatewayApplied = append(c.gatewayApplied, client)
+	}
+
+	vpnIntf := c.tunnels[mainTunnel].intf
+	if vpnIntf == "" {
+		return nil
+	}
+
+	return c.setGatewayInterface(ctx, vpnIntf)
+}
+
+// removeGateway removes the gateway rules currently set.
+// It must be called with the state mutex locked.
+func (c *Config) removeGateway(ctx context.Context) {
+	const remove = true
+	if c.gatewayIntf != "" {
+		for _, client := range c.gatewayApplied {
+			err := c.forwardGatewayThroughInterface(ctx, client, c.gatewayIntf, remove)
+			if err != nil {
+				c.logger.Error("cannot remove outdated gateway forwarding rule: " + err.Error())
+			}
+		}
+		c.gatewayIntf = ""
+	}
+
+	for _, client := range c.gatewayApplied {
+		err := c.acceptGatewayInput(ctx, client, remove)
+		if err != nil {
+			c.logger.Error("cannot remove outdated gateway input rule: " + err.Error())
+		}
+	}
+	c.gatewayApplied = nil
+}
+
+// setGatewayInterface moves the gateway forwarding rules to the main
+// VPN interface given. It must be called with the state mutex locked.
+func (c *Config) setGatewayInterface(ctx context.Context, vpnIntf string) (err error) {
+	if len(c.gatewayApplied) == 0 || c.gatewayIntf == vpnIntf {
+		return nil
+	}
+
+	if c.gatewayIntf != "" {
+		const remove = true
+		for _, client := range c.gatewayApplied {
+			err = c.forwardGatewayThroughInterface(ctx, client, c.gatewayIntf, remove)
+			if err != nil {
+				c.logger.Error("cannot remove outdated gateway forwarding rule: " + err.Error())
+			}
+		}
+		c.gatewayIntf = ""
+	}
+
+	const remove = false
+	for i, client := range c.gatewayApplied {
+		err = c.forwardGatewayThroughInterface(ctx, client, vpnIntf, remove)
+		if err != nil {
+			// remove rules added for previous clients
+			for _, addedClient := range c.gatewayApplied[:i] {
+				const remove = true
+				_ = c.forwardGatewayThroughInterface(ctx, addedClient, vpnIntf, remove)
+			}
+			return fmt.Errorf("forwarding gateway clients traffic: %w", err)
+		}
+	}
+	c.gatewayIntf = vpnIntf
+	return nil
+}
+
+func (c *Config) forwardGatewayThroughInterface(ctx context.Context,
+	client gatewayClient, vpnIntf string, remove bool) (err error) {
+	instructions := []string{
+		fmt.Sprintf("%s FORWARD -i %s -o %s -s %s -j ACCEPT",
+			appendOrDelete(remove), client.intf, vpnIntf, client.subnet),
+		fmt.Sprintf("%s FORWARD -i %s -o %s -d %s -m conntrack --ctstate ESTABLISHED,RELATED -j ACCEPT",
+			appendOrDelete(remove), vpnIntf, client.intf, client.subnet),
+		fmt.Sprintf("-t nat %s POSTROUTING -o %s -s %s -j MASQUERADE",
+			appendOrDelete(remove), vpnIntf, client.subnet),
+	}
+	return c.runSubnetFamilyInstructions(ctx, client.subnet, instructions)
+}
+
+func (c *Config) acceptGatewayInput(ctx context.Context,
+	client gatewayClient, remove bool) (err error) {
+	if client.inbound {
+		instruction := fmt.Sprintf("%s INPUT -i %s -s %s -j ACCEPT",
+			appendOrDelete(remove), client.intf, client.subnet)
+		return c.runSubnetFamilyInstructions(ctx, client.subnet, []string{instruction})
+	}
+
+	const dnsPort = 53
+	var instructions []string
+	for _, protocol := range []string{"udp", "tcp"} {
+		instructions = append(instructions, fmt.Sprintf(
+			"%s INPUT -i %s -s %s -p %s -m %s --dport %d -j ACCEPT",
+			appendOrDelete(remove), client.intf, client.subnet,
+			protocol, protocol, dnsPort))
+		if c.gatewayRedirectDNS {
+			instructions = append(instructions, fmt.Sprintf(
+				"-t nat %s PREROUTING -i %s -s %s -p %s -m %s --dport %d -j REDIRECT --to-ports %d",
+				appendOrDelete(remove), client.intf, client.subnet,
+				protocol, protocol, dnsPort, dnsPort))
+		}
+	}
+	return c.runSubnetFamilyInstructions(ctx, client.subnet, instructions)
+}
+
+func (c *Config) runSubnetFamilyInstructions(ctx context.Context,
+	subnet netip.Prefix, instructions []string) error {
+	if subnet.Addr().Is4() {
+		return c.runIptablesInstructions(ctx, instructions)
+	} else if c.ip6Tables == "" {
+		return fmt.Errorf("gateway rules for subnet %s: %w", subnet, ErrNeedIP6Tables)
+	}
+	return c.runIP6tablesInstructions(ctx, instructions)
+}
@@ -0,0 +1,140 @@
+package firewall
+
+import (
+	"context"
+	"net/netip"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/qdm12/gluetun/internal/routing"
+	"github.com/stretchr/testify/require"
+)
+
+type noopLogger struct{}
+
+func (noopLogger) Debug(string) {}
+func (noopLogger) Info(string)  {}
+func (noopLogger) Error(string) {}
+
+func newInstructionMatcher(path, instruction string) *cmdMatcher {
+	fields := strings.Fields(instruction)
+	argsRegex := make([]string, len(fields))
+	for i, field := range fields {
+		argsRegex[i] = "^" + regexp.QuoteMeta(field) + "$"
+	}
+	return newCmdMatcher(path, argsRegex...)
+}
+
+func Test_Config_gateway(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	runner := NewMockRunner(ctrl)
+
+	config := &Config{
+		runner:        runner,
+		logger:        noopLogger{},
+		ipTables:      "iptables",
+		enabled:       true,
+		tunnels:       map[string]tunnel{mainTunnel: {intf: "tun0"}},
+		defaultRoutes: []routing.DefaultRoute{{NetInterface: "eth0"}},
+	}
+	subnets := []netip.Prefix{netip.MustParsePrefix("192.168.1.0/24")}
+
+	// Gateway set while the VPN interface is tun0
+	gomock.InOrder(
+		runner.EXPECT().Run(newInstructionMatcher("iptables",
+			"--append INPUT -i eth0 -s 192.168.1.0/24 -p udp -m udp --dport 53 -j ACCEPT")).Return("", nil),
+		runner.EXPECT().Run(newInstructionMatcher("iptables",
+			"-t nat --append PREROUTING -i eth0 -s 192.168.1.0/24 -p udp -m udp --dport 53 -j REDIRECT --to-ports 53")).
+			Return("", nil),
+		runner.EXPECT().Run(newInstructionMatcher("iptables",
+			"--append INPUT -i eth0 -s 192.168.1.0/24 -p tcp -m tcp --dport 53 -j ACCEPT")).Return("", nil),
+		runner.EXPECT().Run(newInstructionMatcher("iptables",
+			"-t nat --append PREROUTING -i eth0 -s 192.168.1.0/24 -p tcp -m tcp --dport 53 -j REDIRECT --to-ports 53")).
+			Return("", nil),
+		runner.EXPECT().Run(newInstructionMatcher("iptables",
+			"--append FORWARD -i eth0 -o tun0 -s 192.168.1.0/24 -j ACCEPT")).Return("", nil),
+		runner.EXPECT().Run(newInstructionMatcher("iptables",
+			"--append FORWARD -i tun0 -o eth0 -d 192.168.1.0/24 -m conntrack --ctstate ESTABLISHED,RELATED -j ACCEPT")).
+			Return("", nil),
+		runner.EXPECT().Run(newInstructionMatcher("iptables",
+			"-t nat --append POSTROUTING -o tun0 -s 192.168.1.0/24 -j MASQUERADE")).Return("", nil),
+	)
+
+	err := config.SetGateway(context.Background(), subnets, true)
+	require.NoError(t, err)
+
+	// VPN interface changes to tun1
+	gomock.InOrder(
+		runner.EXPECT().Run(newInstructionMatcher("iptables",
+			"--delete FORWARD -i eth0 -o tun0 -s 192.168.1.0/24 -j ACCEPT")).Return("", nil),
+		runner.EXPECT().Run(newInstructionMatcher("iptables",
+			"--delete FORWARD -i tun0 -o eth0 -d 192.168.1.0/24 -m conntrack --ctstate ESTABLISHED,RELATED -j ACCEPT")).
+			Return("", nil),
+		runner.EXPECT().Run(newInstructionMatcher("iptables",
+			"-t nat --delete POSTROUTING -o tun0 -s 192.168.1.0/24 -j MASQUERADE")).Return("", nil),
+		runner.EXPECT().Run(newInstructionMatcher("iptables",
+			"--append FORWARD -i eth0 -o tun1 -s 192.168.1.0/24 -j ACCEPT")).Return("", nil),
+		runner.EXPECT().Run(newInstructionMatcher("iptables",
+			"--append FORWARD -i tun1 -o eth0 -d 192.168.1.0/24 -m conntrack --ctstate ESTABLISHED,RELATED -j ACCEPT")).
+			Return("", nil),
+		runner.EXPECT().Run(newInstructionMatcher("iptables",
+			"-t nat --append POSTROUTING -o tun1 -s 192.168.1.0/24 -j MASQUERADE")).Return("", nil),
+	)
+
+	config.stateMutex.Lock()
+	err = config.setGatewayInterface(context.Background(), "tun1")
+	config.stateMutex.Unlock()
+	require.NoError(t, err)
+}
+
+func Test_Config_SetInboundTunnel(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	runner := NewMockRunner(ctrl)
+
+	config := &Config{
+		runner:         runner,
+		logger:         noopLogger{},
+		ipTables:       "iptables",
+		enabled:        true,
+		tunnels:        map[string]tunnel{mainTunnel: {intf: "tun0"}},
+		inboundTunnels: make(map[string]netip.Prefix),
+	}
+	subnet := netip.MustParsePrefix("10.13.13.0/24")
+
+	gomock.InOrder(
+		runner.EXPECT().Run(newInstructionMatcher("iptables",
+			"--append INPUT -i wgs0 -s 10.13.13.0/24 -j ACCEPT")).Return("", nil),
+		runner.EXPECT().Run(newInstructionMatcher("iptables",
+			"--append FORWARD -i wgs0 -o tun0 -s 10.13.13.0/24 -j ACCEPT")).Return("", nil),
+		runner.EXPECT().Run(newInstructionMatcher("iptables",
+			"--append FORWARD -i tun0 -o wgs0 -d 10.13.13.0/24 -m conntrack --ctstate ESTABLISHED,RELATED -j ACCEPT")).
+			Return("", nil),
+		runner.EXPECT().Run(newInstructionMatcher("iptables",
+			"-t nat --append POSTROUTING -o tun0 -s 10.13.13.0/24 -j MASQUERADE")).Return("", nil),
+	)
+
+	err := config.SetInboundTunnel(context.Background(), "wgs0", subnet)
+	require.NoError(t, err)
+
+	// Removing the inbound tunnel
+	gomock.InOrder(
+		runner.EXPECT().Run(newInstructionMatcher("iptables",
+			"--delete FORWARD -i wgs0 -o tun0 -s 10.13.13.0/24 -j ACCEPT")).Return("", nil),
+		runner.EXPECT().Run(newInstructionMatcher("iptables",
+			"--delete FORWARD -i tun0 -o wgs0 -d 10.13.13.0/24 -m conntrack --ctstate ESTABLISHED,RELATED -j ACCEPT")).
+			Return("", nil),
+		runner.EXPECT().Run(newInstructionMatcher("iptables",
+			"-t nat --delete POSTROUTING -o tun0 -s 10.13.13.0/24 -j MASQUERADE")).Return("", nil),
+		runner.EXPECT().Run(newInstructionMatcher("iptables",
+			"--delete INPUT -i wgs0 -s 10.13.13.0/24 -j ACCEPT")).Return("", nil),
+	)
+
+	err = config.SetInboundTunnel(context.Background(), "wgs0", netip.Prefix{})
+	require.NoError(t, err)
+}
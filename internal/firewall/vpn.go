@@ -7,53 +7,104 @@ import (
 	"github.com/qdm12/gluetun/internal/models"
 )
 
+// mainTunnel is the name of the main VPN tunnel.
+const mainTunnel = ""
+
+type tunnel struct {
+	connection models.Connection
+	intf       string
+}
+
+// SetVPNConnection sets the connection and interface
+// of the main VPN tunnel.
 func (c *Config) SetVPNConnection(ctx context.Context,
 	connection models.Connection, vpnIntf string) (err error) {
+	return c.SetTunnelConnection(ctx, mainTunnel, connection, vpnIntf)
+}
+
+// SetTunnelConnection allows traffic to the connection given
+// through the default interfaces, and all traffic through the
+// tunnel interface given, for the tunnel with the given name.
+// Rules for a previous connection of that tunnel are removed.
+func (c *Config) SetTunnelConnection(ctx context.Context, name string,
+	connection models.Connection, intf string) (err error) {
 	c.stateMutex.Lock()
 	defer c.stateMutex.Unlock()
 
 	if !c.enabled {
 		c.logger.Info("firewall disabled, only updating internal VPN connection")
-		c.vpnConnection = connection
+		c.tunnels[name] = tunnel{connection: connection}
 		return nil
 	}
 
 	c.logger.Info("allowing VPN connection...")
 
-	if c.vpnConnection.Equal(connection) {
+	existing := c.tunnels[name]
+	if existing.connection.Equal(connection) && existing.intf == intf {
 		return nil
 	}
 
-	remove := true
-	if c.vpnConnection.IP.IsValid() {
-		for _, defaultRoute := range c.defaultRoutes {
-			if err := c.acceptOutputTrafficToVPN(ctx, defaultRoute.NetInterface, c.vpnConnection, remove); err != nil {
-				c.logger.Error("cannot remove outdated VPN connection rule: " + err.Error())
-			}
+	c.removeTunnel(ctx, name)
+
+	const remove = false
+	for _, defaultRoute := range c.defaultRoutes {
+		if err := c.acceptOutputTrafficToVPN(ctx, defaultRoute.NetInterface, connection, remove); err != nil {
+			return fmt.Errorf("allowing output traffic through VPN connection: %w", err)
 		}
 	}
-	c.vpnConnection = models.Connection{}
+	c.tunnels[name] = tunnel{connection: connection}
 
-	if c.vpnIntf != "" {
-		if err = c.acceptOutputThroughInterface(ctx, c.vpnIntf, remove); err != nil {
-			c.logger.Error("cannot remove outdated VPN interface rule: " + err.Error())
+	if err = c.acceptOutputThroughInterface(ctx, intf, remove); err != nil {
+		return fmt.Errorf("accepting output traffic through interface %s: %w", intf, err)
+	}
+	c.tunnels[name] = tunnel{connection: connection, intf: intf}
+
+	if name == mainTunnel {
+		err = c.setGatewayInterface(ctx, intf)
+		if err != nil {
+			return fmt.Errorf("setting gateway: %w", err)
 		}
 	}
-	c.vpnIntf = ""
 
-	remove = false
+	return nil
+}
 
-	for _, defaultRoute := range c.defaultRoutes {
-		if err := c.acceptOutputTrafficToVPN(ctx, defaultRoute.NetInterface, connection, remove); err != nil {
-			return fmt.Errorf("allowing output traffic through VPN connection: %w", err)
+// RemoveTunnelConnection removes the firewall rules
+// for the tunnel with the given name.
+func (c *Config) RemoveTunnelConnection(ctx context.Context, name string) {
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	if !c.enabled {
+		delete(c.tunnels, name)
+		return
+	}
+
+	c.removeTunnel(ctx, name)
+}
+
+// removeTunnel removes the firewall rules of the tunnel with the
+// given name. It must be called with the state mutex locked.
+func (c *Config) removeTunnel(ctx context.Context, name string) {
+	existing, ok := c.tunnels[name]
+	if !ok {
+		return
+	}
+
+	const remove = true
+	if existing.connection.IP.IsValid() {
+		for _, defaultRoute := range c.defaultRoutes {
+			if err := c.acceptOutputTrafficToVPN(ctx, defaultRoute.NetInterface, existing.connection, remove); err != nil {
+				c.logger.Error("cannot remove outdated VPN connection rule: " + err.Error())
+			}
 		}
 	}
-	c.vpnConnection = connection
 
-	if err = c.acceptOutputThroughInterface(ctx, vpnIntf, remove); err != nil {
-		return fmt.Errorf("accepting output traffic through interface %s: %w", vpnIntf, err)
+	if existing.intf != "" {
+		if err := c.acceptOutputThroughInterface(ctx, existing.intf, remove); err != nil {
+			c.logger.Error("cannot remove outdated VPN interface rule: " + err.Error())
+		}
 	}
-	c.vpnIntf = vpnIntf
 
-	return nil
+	delete(c.tunnels, name)
 }
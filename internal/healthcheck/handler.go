@@ -1,21 +1,28 @@
 package healthcheck
 
 import (
+	"encoding/json"
 	"errors"
 	"net/http"
+	"strings"
 	"sync"
+	"time"
 )
 
 type handler struct {
 	healthErr   error
 	healthErrMu sync.RWMutex
+	history     *history
+	logger      Logger
 }
 
 var errHealthcheckNotRunYet = errors.New("healthcheck did not run yet")
 
-func newHandler() *handler {
+func newHandler(history *history, logger Logger) *handler {
 	return &handler{
 		healthErr: errHealthcheckNotRunYet,
+		history:   history,
+		logger:    logger,
 	}
 }
 
@@ -24,6 +31,12 @@ func (h *handler) ServeHTTP(responseWriter http.ResponseWriter, request *http.Re
 		http.Error(responseWriter, "method not supported for healthcheck", http.StatusBadRequest)
 		return
 	}
+
+	if strings.TrimSuffix(request.URL.Path, "/") == "/status" {
+		h.getStatus(responseWriter)
+		return
+	}
+
 	if err := h.getErr(); err != nil {
 		http.Error(responseWriter, err.Error(), http.StatusInternalServerError)
 		return
@@ -31,6 +44,16 @@ func (h *handler) ServeHTTP(responseWriter http.ResponseWriter, request *http.Re
 	responseWriter.WriteHeader(http.StatusOK)
 }
 
+func (h *handler) getStatus(responseWriter http.ResponseWriter) {
+	status := h.history.status(time.Now())
+	responseWriter.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(responseWriter)
+	if err := encoder.Encode(status); err != nil {
+		h.logger.Error("encoding health status: " + err.Error())
+		responseWriter.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
 func (h *handler) setErr(err error) {
 	h.healthErrMu.Lock()
 	defer h.healthErrMu.Unlock()
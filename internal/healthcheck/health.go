@@ -16,11 +16,7 @@ func (s *Server) runHealthcheckLoop(ctx context.Context, done chan<- struct{}) {
 	for {
 		previousErr := s.handler.getErr()
 
-		const healthcheckTimeout = 3 * time.Second
-		healthcheckCtx, healthcheckCancel := context.WithTimeout(
-			ctx, healthcheckTimeout)
-		err := s.healthCheck(healthcheckCtx)
-		healthcheckCancel()
+		err := s.healthCheck(ctx)
 
 		s.handler.setErr(err)
 
@@ -28,6 +24,7 @@ func (s *Server) runHealthcheckLoop(ctx context.Context, done chan<- struct{}) {
 			s.logger.Info("healthy!")
 			s.vpn.healthyTimer.Stop()
 			s.vpn.healthyWait = *s.config.VPN.Initial
+			s.history.setHealthyWait(s.vpn.healthyWait)
 		} else if previousErr == nil && err != nil {
 			s.logger.Info("unhealthy: " + err.Error())
 			s.vpn.healthyTimer.Stop()
@@ -63,25 +60,11 @@ func (s *Server) runHealthcheckLoop(ctx context.Context, done chan<- struct{}) {
 }
 
 func (s *Server) healthCheck(ctx context.Context) (err error) {
-	// TODO use mullvad API if current provider is Mullvad
-
-	address, err := makeAddressToDial(s.config.TargetAddress)
-	if err != nil {
-		return err
-	}
-
-	const dialNetwork = "tcp4"
-	connection, err := s.dialer.DialContext(ctx, dialNetwork, address)
-	if err != nil {
-		return fmt.Errorf("dialing: %w", err)
-	}
-
-	err = connection.Close()
-	if err != nil {
-		return fmt.Errorf("closing connection: %w", err)
-	}
-
-	return nil
+	checkTime := time.Now()
+	results := runProbes(ctx, s.probes)
+	err = checkQuorum(results, s.config.Probes.Quorum)
+	s.history.addCheck(checkTime, results, err)
+	return err
 }
 
 func makeAddressToDial(address string) (addressToDial string, err error) {
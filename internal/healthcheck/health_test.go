@@ -2,17 +2,17 @@ package healthcheck
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"testing"
 	"time"
 
-	"github.com/qdm12/gluetun/internal/configuration/settings"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-func Test_Server_healthCheck(t *testing.T) {
+func Test_tcpProbe_Probe(t *testing.T) {
 	t.Parallel()
 
 	t.Run("canceled real dialer", func(t *testing.T) {
@@ -20,18 +20,14 @@ func Test_Server_healthCheck(t *testing.T) {
 
 		dialer := &net.Dialer{}
 		const address = "cloudflare.com:443"
+		const timeout = time.Second
 
-		server := &Server{
-			dialer: dialer,
-			config: settings.Health{
-				TargetAddress: address,
-			},
-		}
+		probe := newTCPProbe(dialer, address, timeout)
 
 		canceledCtx, cancel := context.WithCancel(context.Background())
 		cancel()
 
-		err := server.healthCheck(canceledCtx)
+		err := probe.Probe(canceledCtx)
 
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "operation was canceled")
@@ -50,23 +46,72 @@ func Test_Server_healthCheck(t *testing.T) {
 		listeningAddress := listener.Addr()
 
 		dialer := &net.Dialer{}
-		server := &Server{
-			dialer: dialer,
-			config: settings.Health{
-				TargetAddress: listeningAddress.String(),
-			},
-		}
-
 		const timeout = 100 * time.Millisecond
-		ctx, cancel := context.WithTimeout(context.Background(), timeout)
-		defer cancel()
+		probe := newTCPProbe(dialer, listeningAddress.String(), timeout)
 
-		err = server.healthCheck(ctx)
+		err = probe.Probe(context.Background())
 
 		assert.NoError(t, err)
 	})
 }
 
+func Test_checkQuorum(t *testing.T) {
+	t.Parallel()
+
+	errTest := errors.New("test error")
+
+	testCases := map[string]struct {
+		results    []probeResult
+		quorum     uint
+		errWrapped error
+		errMessage string
+	}{
+		"single probe success": {
+			results: []probeResult{{name: "tcp"}},
+			quorum:  1,
+		},
+		"single probe failure": {
+			results:    []probeResult{{name: "tcp", err: errTest}},
+			quorum:     1,
+			errWrapped: errTest,
+			errMessage: "test error",
+		},
+		"quorum reached": {
+			results: []probeResult{
+				{name: "tcp"},
+				{name: "http", err: errTest},
+				{name: "dns"},
+			},
+			quorum: 2,
+		},
+		"quorum not reached": {
+			results: []probeResult{
+				{name: "tcp"},
+				{name: "http", err: errTest},
+				{name: "dns", err: errTest},
+			},
+			quorum:     2,
+			errWrapped: ErrQuorumNotReached,
+			errMessage: "probes quorum not reached: 1 of 3 probes succeeded, " +
+				"2 required: http: test error; dns: test error",
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := checkQuorum(testCase.results, testCase.quorum)
+
+			assert.ErrorIs(t, err, testCase.errWrapped)
+			if testCase.errWrapped != nil {
+				assert.EqualError(t, err, testCase.errMessage)
+			}
+		})
+	}
+}
+
 func Test_makeAddressToDial(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,127 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+type icmpProbe struct {
+	target   netip.Addr
+	timeout  time.Duration
+	sequence atomic.Uint32
+	// listen listens for ICMP packets and is
+	// injectable for tests, which cannot use raw sockets.
+	listen func(network, address string) (net.PacketConn, error)
+}
+
+func newICMPProbe(target netip.Addr, timeout time.Duration) *icmpProbe {
+	return &icmpProbe{
+		target:  target,
+		timeout: timeout,
+		listen: func(network, address string) (net.PacketConn, error) {
+			return icmp.ListenPacket(network, address)
+		},
+	}
+}
+
+func (p *icmpProbe) Name() string { return settings.HealthProbeICMP }
+
+var ErrICMPNoReply = errors.New("no ICMP echo reply received")
+
+// Probe sends an ICMP echo request to the target and waits
+// for the matching echo reply, which requires the program
+// to have the NET_RAW capability.
+func (p *icmpProbe) Probe(ctx context.Context) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	network, address, protocol := "ip4:icmp", "0.0.0.0", 1
+	var requestType, replyType icmp.Type = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	if p.target.Is6() {
+		network, address, protocol = "ip6:ipv6-icmp", "::", 58 //nolint:gomnd
+		requestType, replyType = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+	}
+
+	connection, err := p.listen(network, address)
+	if err != nil {
+		return fmt.Errorf("listening for ICMP packets: %w", err)
+	}
+	defer connection.Close()
+
+	deadline, _ := ctx.Deadline()
+	err = connection.SetDeadline(deadline)
+	if err != nil {
+		return fmt.Errorf("setting connection deadline: %w", err)
+	}
+
+	go func() {
+		// Unblock reads if the parent context is canceled.
+		<-ctx.Done()
+		_ = connection.SetDeadline(time.Now())
+	}()
+
+	const idMask = 0xffff
+	id := os.Getpid() & idMask
+	sequence := int(p.sequence.Add(1) & idMask)
+	request := icmp.Message{
+		Type: requestType,
+		Body: &icmp.Echo{
+			ID:   id,
+			Seq:  sequence,
+			Data: []byte("gluetun healthcheck"),
+		},
+	}
+	requestBytes, err := request.Marshal(nil)
+	if err != nil {
+		return fmt.Errorf("encoding ICMP echo request: %w", err)
+	}
+
+	destination := &net.IPAddr{IP: p.target.AsSlice()}
+	_, err = connection.WriteTo(requestBytes, destination)
+	if err != nil {
+		return fmt.Errorf("writing ICMP echo request: %w", err)
+	}
+
+	const bufferSize = 1500
+	buffer := make([]byte, bufferSize)
+	for {
+		n, source, err := connection.ReadFrom(buffer)
+		if err != nil {
+			switch {
+			case ctx.Err() != nil:
+				return fmt.Errorf("%w: %w", ErrICMPNoReply, ctx.Err())
+			case errors.Is(err, os.ErrDeadlineExceeded):
+				// The connection deadline can be reached just
+				// before the context deadline with the same time.
+				return fmt.Errorf("%w: %w", ErrICMPNoReply, context.DeadlineExceeded)
+			}
+			return fmt.Errorf("reading ICMP packet: %w", err)
+		}
+
+		sourceIP, ok := source.(*net.IPAddr)
+		if !ok || !sourceIP.IP.Equal(destination.IP) {
+			continue
+		}
+
+		message, err := icmp.ParseMessage(protocol, buffer[:n])
+		if err != nil || message.Type != replyType {
+			continue
+		}
+
+		echo, ok := message.Body.(*icmp.Echo)
+		if ok && echo.ID == id && echo.Seq == sequence {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+type icmpPacket struct {
+	data   []byte
+	source net.Addr
+}
+
+// fakeICMPConnection is a fake ICMP connection answering the
+// ICMP echo requests written with the packets from respond.
+type fakeICMPConnection struct {
+	net.PacketConn // only the methods used by the probe are implemented
+	respond        func(t *testing.T, request *icmp.Echo) (packets []icmpPacket)
+	t              *testing.T
+	packets        chan icmpPacket
+	deadlineMutex  sync.Mutex
+	deadline       time.Time
+	deadlineSet    chan struct{}
+}
+
+func newFakeICMPConnection(t *testing.T,
+	respond func(t *testing.T, request *icmp.Echo) []icmpPacket,
+) *fakeICMPConnection {
+	return &fakeICMPConnection{
+		respond:     respond,
+		t:           t,
+		packets:     make(chan icmpPacket, 10), //nolint:gomnd
+		deadlineSet: make(chan struct{}, 1),
+	}
+}
+
+func (c *fakeICMPConnection) WriteTo(b []byte, _ net.Addr) (n int, err error) {
+	const icmpv4Protocol = 1
+	message, err := icmp.ParseMessage(icmpv4Protocol, b)
+	require.NoError(c.t, err)
+	require.Equal(c.t, ipv4.ICMPTypeEcho, message.Type)
+	echo, ok := message.Body.(*icmp.Echo)
+	require.True(c.t, ok)
+
+	for _, packet := range c.respond(c.t, echo) {
+		c.packets <- packet
+	}
+	return len(b), nil
+}
+
+func (c *fakeICMPConnection) ReadFrom(b []byte) (n int, source net.Addr, err error) {
+	for {
+		c.deadlineMutex.Lock()
+		deadline := c.deadline
+		c.deadlineMutex.Unlock()
+
+		select {
+		case packet := <-c.packets:
+			n = copy(b, packet.data)
+			return n, packet.source, nil
+		case <-time.After(time.Until(deadline)):
+			return 0, nil, os.ErrDeadlineExceeded
+		case <-c.deadlineSet:
+		}
+	}
+}
+
+func (c *fakeICMPConnection) SetDeadline(deadline time.Time) error {
+	c.deadlineMutex.Lock()
+	c.deadline = deadline
+	c.deadlineMutex.Unlock()
+	select {
+	case c.deadlineSet <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (c *fakeICMPConnection) Close() error { return nil }
+
+func makeEchoReply(t *testing.T, id, sequence int) []byte {
+	t.Helper()
+	message := icmp.Message{
+		Type: ipv4.ICMPTypeEchoReply,
+		Body: &icmp.Echo{ID: id, Seq: sequence},
+	}
+	data, err := message.Marshal(nil)
+	require.NoError(t, err)
+	return data
+}
+
+func Test_icmpProbe_Probe(t *testing.T) {
+	t.Parallel()
+
+	target := netip.MustParseAddr("1.2.3.4")
+	targetAddress := &net.IPAddr{IP: target.AsSlice()}
+	otherAddress := &net.IPAddr{IP: net.IPv4(5, 6, 7, 8)}
+	errTest := errors.New("test error")
+
+	testCases := map[string]struct {
+		respond    func(t *testing.T, request *icmp.Echo) []icmpPacket
+		listenErr  error
+		errWrapped error
+		errMessage string
+	}{
+		"echo reply": {
+			respond: func(t *testing.T, request *icmp.Echo) []icmpPacket {
+				return []icmpPacket{{
+					data:   makeEchoReply(t, request.ID, request.Seq),
+					source: targetAddress,
+				}}
+			},
+		},
+		"echo reply after other packets": {
+			respond: func(t *testing.T, request *icmp.Echo) []icmpPacket {
+				return []icmpPacket{
+					{data: makeEchoReply(t, request.ID, request.Seq), source: otherAddress},
+					{data: []byte{1, 2, 3}, source: targetAddress},
+					{data: makeEchoReply(t, request.ID, request.Seq+1), source: targetAddress},
+					{data: makeEchoReply(t, request.ID, request.Seq), source: targetAddress},
+				}
+			},
+		},
+		"echo reply from other source": {
+			respond: func(t *testing.T, request *icmp.Echo) []icmpPacket {
+				return []icmpPacket{{
+					data:   makeEchoReply(t, request.ID, request.Seq),
+					source: otherAddress,
+				}}
+			},
+			errWrapped: ErrICMPNoReply,
+			errMessage: "no ICMP echo reply received: context deadline exceeded",
+		},
+		"no reply": {
+			respond: func(*testing.T, *icmp.Echo) []icmpPacket {
+				return nil
+			},
+			errWrapped: ErrICMPNoReply,
+			errMessage: "no ICMP echo reply received: context deadline exceeded",
+		},
+		"listen error": {
+			listenErr:  errTest,
+			errWrapped: errTest,
+			errMessage: "listening for ICMP packets: test error",
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			const timeout = 50 * time.Millisecond
+			probe := newICMPProbe(target, timeout)
+			probe.listen = func(network, address string) (net.PacketConn, error) {
+				assert.Equal(t, "ip4:icmp", network)
+				assert.Equal(t, "0.0.0.0", address)
+				if testCase.listenErr != nil {
+					return nil, testCase.listenErr
+				}
+				return newFakeICMPConnection(t, testCase.respond), nil
+			}
+
+			err := probe.Probe(context.Background())
+
+			if testCase.errMessage == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, testCase.errWrapped)
+			assert.EqualError(t, err, testCase.errMessage)
+		})
+	}
+}
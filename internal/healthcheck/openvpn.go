@@ -20,5 +20,7 @@ func (s *Server) onUnhealthyVPN(ctx context.Context) {
 	_, _ = s.vpn.loop.ApplyStatus(ctx, constants.Stopped)
 	_, _ = s.vpn.loop.ApplyStatus(ctx, constants.Running)
 	s.vpn.healthyWait += *s.config.VPN.Addition
+	s.history.incrementVPNRestarts()
+	s.history.setHealthyWait(s.vpn.healthyWait)
 	s.vpn.healthyTimer = time.NewTimer(s.vpn.healthyWait)
 }
@@ -0,0 +1,242 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+)
+
+// Prober is a health check probe.
+type Prober interface {
+	// Name returns the name of the probe, for example "tcp".
+	Name() string
+	// Probe runs the probe and returns an error if it fails.
+	// It should return once its own timeout is reached.
+	Probe(ctx context.Context) (err error)
+}
+
+type probeResult struct {
+	name     string
+	start    time.Time
+	duration time.Duration
+	err      error
+}
+
+func newProbes(probesSettings settings.HealthProbes, targetAddress string,
+	dialer *net.Dialer) (probes []Prober) {
+	probes = make([]Prober, len(probesSettings.Enabled))
+	for i, name := range probesSettings.Enabled {
+		switch name {
+		case settings.HealthProbeTCP:
+			probes[i] = newTCPProbe(dialer, targetAddress,
+				probesSettings.TCP.Timeout)
+		case settings.HealthProbeHTTP:
+			probes[i] = newHTTPProbe(dialer, probesSettings.HTTP.URL,
+				probesSettings.HTTP.Status, probesSettings.HTTP.Timeout)
+		case settings.HealthProbeDNS:
+			probes[i] = newDNSProbe(dialer, probesSettings.DNS.Server,
+				probesSettings.DNS.Hostname, probesSettings.DNS.Timeout)
+		case settings.HealthProbeICMP:
+			probes[i] = newICMPProbe(probesSettings.ICMP.Target,
+				probesSettings.ICMP.Timeout)
+		default:
+			panic(fmt.Sprintf("probe %q is not implemented", name))
+		}
+	}
+	return probes
+}
+
+// runProbes runs all the probes in parallel and returns
+// their results in the same order as the probes given.
+func runProbes(ctx context.Context, probes []Prober) (results []probeResult) {
+	results = make([]probeResult, len(probes))
+	var wg sync.WaitGroup
+	wg.Add(len(probes))
+	for i, probe := range probes {
+		go func(i int, probe Prober) {
+			defer wg.Done()
+			start := time.Now()
+			err := probe.Probe(ctx)
+			results[i] = probeResult{
+				name:     probe.Name(),
+				start:    start,
+				duration: time.Since(start),
+				err:      err,
+			}
+		}(i, probe)
+	}
+	wg.Wait()
+	return results
+}
+
+var ErrQuorumNotReached = errors.New("probes quorum not reached")
+
+// checkQuorum returns an error if fewer than quorum probes succeeded.
+func checkQuorum(results []probeResult, quorum uint) (err error) {
+	var succeeded uint
+	errorMessages := make([]string, 0, len(results))
+	for _, result := range results {
+		if result.err == nil {
+			succeeded++
+			continue
+		}
+		errorMessages = append(errorMessages, result.name+": "+result.err.Error())
+	}
+
+	if succeeded >= quorum {
+		return nil
+	}
+
+	if len(results) == 1 {
+		// Keep the error message simple for a single probe
+		return results[0].err
+	}
+
+	return fmt.Errorf("%w: %d of %d probes succeeded, %d required: %s",
+		ErrQuorumNotReached, succeeded, len(results), quorum,
+		strings.Join(errorMessages, "; "))
+}
+
+type tcpProbe struct {
+	dialer  *net.Dialer
+	address string
+	timeout time.Duration
+}
+
+func newTCPProbe(dialer *net.Dialer, address string,
+	timeout time.Duration) *tcpProbe {
+	return &tcpProbe{
+		dialer:  dialer,
+		address: address,
+		timeout: timeout,
+	}
+}
+
+func (p *tcpProbe) Name() string { return settings.HealthProbeTCP }
+
+func (p *tcpProbe) Probe(ctx context.Context) (err error) {
+	// TODO use mullvad API if current provider is Mullvad
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	address, err := makeAddressToDial(p.address)
+	if err != nil {
+		return err
+	}
+
+	const dialNetwork = "tcp4"
+	connection, err := p.dialer.DialContext(ctx, dialNetwork, address)
+	if err != nil {
+		return fmt.Errorf("dialing: %w", err)
+	}
+
+	err = connection.Close()
+	if err != nil {
+		return fmt.Errorf("closing connection: %w", err)
+	}
+
+	return nil
+}
+
+type httpProbe struct {
+	client  *http.Client
+	url     string
+	status  int
+	timeout time.Duration
+}
+
+func newHTTPProbe(dialer *net.Dialer, url string, status int,
+	timeout time.Duration) *httpProbe {
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert
+	transport.DialContext = dialer.DialContext
+	// Probe the URL directly, ignoring proxy environment variables.
+	transport.Proxy = nil
+	// Do not reuse connections to check the full path each time.
+	transport.DisableKeepAlives = true
+	return &httpProbe{
+		client: &http.Client{
+			Transport: transport,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		url:     url,
+		status:  status,
+		timeout: timeout,
+	}
+}
+
+func (p *httpProbe) Name() string { return settings.HealthProbeHTTP }
+
+var ErrHTTPStatusUnexpected = errors.New("HTTP response status is unexpected")
+
+func (p *httpProbe) Probe(ctx context.Context) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return err
+	}
+
+	err = response.Body.Close()
+	if err != nil {
+		return fmt.Errorf("closing response body: %w", err)
+	}
+
+	if response.StatusCode != p.status {
+		return fmt.Errorf("%w: %d instead of %d", ErrHTTPStatusUnexpected,
+			response.StatusCode, p.status)
+	}
+
+	return nil
+}
+
+type dnsProbe struct {
+	resolver *net.Resolver
+	hostname string
+	timeout  time.Duration
+}
+
+func newDNSProbe(dialer *net.Dialer, server, hostname string,
+	timeout time.Duration) *dnsProbe {
+	return &dnsProbe{
+		resolver: &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, server)
+			},
+		},
+		hostname: hostname,
+		timeout:  timeout,
+	}
+}
+
+func (p *dnsProbe) Name() string { return settings.HealthProbeDNS }
+
+var ErrDNSNoAddress = errors.New("no address resolved")
+
+func (p *dnsProbe) Probe(ctx context.Context) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	ips, err := p.resolver.LookupNetIP(ctx, "ip", p.hostname)
+	if err != nil {
+		return fmt.Errorf("resolving %s: %w", p.hostname, err)
+	} else if len(ips) == 0 {
+		return fmt.Errorf("%w: for %s", ErrDNSNoAddress, p.hostname)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func Test_httpProbe_Probe(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusNoContent)
+		case "/redirect":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	testCases := map[string]struct {
+		path       string
+		status     int
+		errWrapped error
+		errMessage string
+	}{
+		"expected status": {
+			path:   "/ok",
+			status: http.StatusNoContent,
+		},
+		"unexpected status": {
+			path:       "/missing",
+			status:     http.StatusNoContent,
+			errWrapped: ErrHTTPStatusUnexpected,
+			errMessage: "HTTP response status is unexpected: 404 instead of 204",
+		},
+		"redirect not followed": {
+			path:       "/redirect",
+			status:     http.StatusNoContent,
+			errWrapped: ErrHTTPStatusUnexpected,
+			errMessage: "HTTP response status is unexpected: 302 instead of 204",
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			const timeout = time.Second
+			probe := newHTTPProbe(&net.Dialer{}, server.URL+testCase.path,
+				testCase.status, timeout)
+
+			err := probe.Probe(context.Background())
+
+			if testCase.errMessage == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, testCase.errWrapped)
+			assert.EqualError(t, err, testCase.errMessage)
+		})
+	}
+
+	t.Run("proxy ignored", func(t *testing.T) {
+		t.Parallel()
+
+		probe := newHTTPProbe(&net.Dialer{}, server.URL, http.StatusOK, time.Second)
+
+		transport, ok := probe.client.Transport.(*http.Transport)
+		require.True(t, ok)
+		assert.Nil(t, transport.Proxy)
+	})
+}
+
+// runDNSServer runs a local UDP DNS server answering A queries
+// for the hostname given with the IPv4 address given, other queries
+// for the hostname with no answer, and queries for other hostnames
+// with a name error. It returns the server address.
+func runDNSServer(t *testing.T, hostname string, ipv4 [4]byte) (address string) {
+	t.Helper()
+
+	connection, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		err := connection.Close()
+		assert.NoError(t, err)
+	})
+
+	name := dnsmessage.MustNewName(hostname + ".")
+	go func() {
+		buffer := make([]byte, 512) //nolint:gomnd
+		for {
+			n, clientAddress, err := connection.ReadFrom(buffer)
+			if err != nil {
+				return // connection closed
+			}
+
+			var request dnsmessage.Message
+			err = request.Unpack(buffer[:n])
+			if err != nil || len(request.Questions) != 1 {
+				continue
+			}
+
+			question := request.Questions[0]
+			response := dnsmessage.Message{
+				Header: dnsmessage.Header{
+					ID:                 request.ID,
+					Response:           true,
+					RecursionAvailable: true,
+					RCode:              dnsmessage.RCodeSuccess,
+				},
+				Questions: request.Questions,
+			}
+			switch {
+			case question.Name != name:
+				response.RCode = dnsmessage.RCodeNameError
+			case question.Type == dnsmessage.TypeA:
+				response.Answers = []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{
+						Name:  name,
+						Type:  dnsmessage.TypeA,
+						Class: dnsmessage.ClassINET,
+						TTL:   60, //nolint:gomnd
+					},
+					Body: &dnsmessage.AResource{A: ipv4},
+				}}
+			}
+
+			responseBytes, err := response.Pack()
+			if err != nil {
+				continue
+			}
+			_, _ = connection.WriteTo(responseBytes, clientAddress)
+		}
+	}()
+
+	return connection.LocalAddr().String()
+}
+
+func Test_dnsProbe_Probe(t *testing.T) {
+	t.Parallel()
+
+	serverAddress := runDNSServer(t, "example.com", [4]byte{1, 2, 3, 4})
+
+	testCases := map[string]struct {
+		hostname string
+		notFound bool
+	}{
+		"hostname resolved": {
+			hostname: "example.com",
+		},
+		"hostname not found": {
+			hostname: "unknown.com",
+			notFound: true,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			const timeout = time.Second
+			probe := newDNSProbe(&net.Dialer{}, serverAddress,
+				testCase.hostname, timeout)
+
+			err := probe.Probe(context.Background())
+
+			if !testCase.notFound {
+				assert.NoError(t, err)
+				return
+			}
+			var dnsErr *net.DNSError
+			require.True(t, errors.As(err, &dnsErr))
+			assert.True(t, dnsErr.IsNotFound)
+		})
+	}
+
+	t.Run("server not responding", func(t *testing.T) {
+		t.Parallel()
+
+		connection, err := net.ListenPacket("udp4", "127.0.0.1:0")
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			err := connection.Close()
+			assert.NoError(t, err)
+		})
+
+		const timeout = 50 * time.Millisecond
+		probe := newDNSProbe(&net.Dialer{}, connection.LocalAddr().String(),
+			"example.com", timeout)
+
+		err = probe.Probe(context.Background())
+
+		var dnsErr *net.DNSError
+		require.True(t, errors.As(err, &dnsErr))
+		assert.True(t, dnsErr.IsTimeout)
+	})
+}
@@ -11,22 +11,26 @@ import (
 type Server struct {
 	logger  Logger
 	handler *handler
-	dialer  *net.Dialer
+	history *history
+	probes  []Prober
 	config  settings.Health
 	vpn     vpnHealth
 }
 
 func NewServer(config settings.Health,
 	logger Logger, vpnLoop StatusApplier) *Server {
+	dialer := &net.Dialer{
+		Resolver: &net.Resolver{
+			PreferGo: true,
+		},
+	}
+	history := newHistory(*config.VPN.Initial)
 	return &Server{
 		logger:  logger,
-		handler: newHandler(),
-		dialer: &net.Dialer{
-			Resolver: &net.Resolver{
-				PreferGo: true,
-			},
-		},
-		config: config,
+		handler: newHandler(history, logger),
+		history: history,
+		probes:  newProbes(config.Probes, config.TargetAddress, dialer),
+		config:  config,
 		vpn: vpnHealth{
 			loop:        vpnLoop,
 			healthyWait: *config.VPN.Initial,
@@ -0,0 +1,144 @@
+package healthcheck
+
+import (
+	"sync"
+	"time"
+)
+
+// historySize is the maximum number of health checks
+// kept in the history reported by the status endpoint.
+const historySize = 50
+
+// Status is the detailed health status served as JSON.
+type Status struct {
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+	// LastHealthy is the time the health check last passed,
+	// and is nil if it never passed.
+	LastHealthy *time.Time `json:"last_healthy,omitempty"`
+	// SinceLastHealthy is the duration since the health check
+	// last passed, and is empty if currently healthy or if it
+	// never passed.
+	SinceLastHealthy string `json:"since_last_healthy,omitempty"`
+	// HealthyWait is the current duration to wait for the
+	// program to be healthy before restarting the VPN.
+	HealthyWait string `json:"healthy_wait"`
+	// VPNRestarts is the number of times the VPN was restarted
+	// because the program was unhealthy for too long.
+	VPNRestarts uint `json:"vpn_restarts"`
+	// Checks are the most recent health checks, oldest first.
+	Checks []CheckResult `json:"checks"`
+}
+
+// CheckResult is the result of a single health check run.
+type CheckResult struct {
+	Time    time.Time     `json:"time"`
+	Healthy bool          `json:"healthy"`
+	Error   string        `json:"error,omitempty"`
+	Probes  []ProbeResult `json:"probes"`
+}
+
+// ProbeResult is the result of a single probe of a health check.
+type ProbeResult struct {
+	Name     string    `json:"name"`
+	Start    time.Time `json:"start"`
+	Duration string    `json:"duration"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// history records the most recent health check results
+// as well as VPN restart information, safe for concurrent use.
+type history struct {
+	mutex       sync.RWMutex
+	checks      []CheckResult // ring buffer
+	next        int
+	full        bool
+	lastHealthy time.Time
+	healthyWait time.Duration
+	vpnRestarts uint
+}
+
+func newHistory(healthyWait time.Duration) *history {
+	return &history{
+		checks:      make([]CheckResult, historySize),
+		healthyWait: healthyWait,
+	}
+}
+
+func (h *history) addCheck(checkTime time.Time, results []probeResult, err error) {
+	check := CheckResult{
+		Time:    checkTime,
+		Healthy: err == nil,
+		Probes:  make([]ProbeResult, len(results)),
+	}
+	if err != nil {
+		check.Error = err.Error()
+	}
+	for i, result := range results {
+		check.Probes[i] = ProbeResult{
+			Name:     result.name,
+			Start:    result.start,
+			Duration: result.duration.String(),
+		}
+		if result.err != nil {
+			check.Probes[i].Error = result.err.Error()
+		}
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if err == nil {
+		h.lastHealthy = checkTime
+	}
+	h.checks[h.next] = check
+	h.next = (h.next + 1) % len(h.checks)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+func (h *history) setHealthyWait(healthyWait time.Duration) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.healthyWait = healthyWait
+}
+
+func (h *history) incrementVPNRestarts() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.vpnRestarts++
+}
+
+func (h *history) status(now time.Time) (status Status) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	status.HealthyWait = h.healthyWait.String()
+	status.VPNRestarts = h.vpnRestarts
+
+	if h.full {
+		status.Checks = make([]CheckResult, 0, len(h.checks))
+		status.Checks = append(status.Checks, h.checks[h.next:]...)
+	} else {
+		status.Checks = make([]CheckResult, 0, h.next)
+	}
+	status.Checks = append(status.Checks, h.checks[:h.next]...)
+
+	if len(status.Checks) > 0 {
+		last := status.Checks[len(status.Checks)-1]
+		status.Healthy = last.Healthy
+		status.Error = last.Error
+	} else {
+		status.Error = errHealthcheckNotRunYet.Error()
+	}
+
+	if !h.lastHealthy.IsZero() {
+		lastHealthy := h.lastHealthy
+		status.LastHealthy = &lastHealthy
+		if !status.Healthy {
+			status.SinceLastHealthy = now.Sub(lastHealthy).Round(time.Millisecond).String()
+		}
+	}
+
+	return status
+}
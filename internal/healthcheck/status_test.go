@@ -0,0 +1,84 @@
+package healthcheck
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_history(t *testing.T) {
+	t.Parallel()
+
+	start := time.Unix(1000, 0)
+	errTest := errors.New("test error")
+
+	history := newHistory(6 * time.Second)
+
+	status := history.status(start)
+	assert.Equal(t, Status{
+		Error:       "healthcheck did not run yet",
+		HealthyWait: "6s",
+		Checks:      []CheckResult{},
+	}, status)
+
+	history.addCheck(start, []probeResult{{
+		name:     "tcp",
+		start:    start,
+		duration: time.Millisecond,
+	}}, nil)
+	history.addCheck(start.Add(time.Second), []probeResult{{
+		name:     "tcp",
+		start:    start.Add(time.Second),
+		duration: 2 * time.Millisecond,
+		err:      errTest,
+	}}, errTest)
+	history.incrementVPNRestarts()
+	history.setHealthyWait(11 * time.Second)
+
+	status = history.status(start.Add(3 * time.Second))
+	expected := Status{
+		Healthy:          false,
+		Error:            "test error",
+		LastHealthy:      &start,
+		SinceLastHealthy: "3s",
+		HealthyWait:      "11s",
+		VPNRestarts:      1,
+		Checks: []CheckResult{
+			{
+				Time:    start,
+				Healthy: true,
+				Probes: []ProbeResult{{
+					Name:     "tcp",
+					Start:    start,
+					Duration: "1ms",
+				}},
+			},
+			{
+				Time:  start.Add(time.Second),
+				Error: "test error",
+				Probes: []ProbeResult{{
+					Name:     "tcp",
+					Start:    start.Add(time.Second),
+					Duration: "2ms",
+					Error:    "test error",
+				}},
+			},
+		},
+	}
+	assert.Equal(t, expected, status)
+
+	// Overflow the ring buffer
+	for i := 0; i < historySize; i++ {
+		history.addCheck(start.Add(time.Duration(i+2)*time.Second), nil, nil)
+	}
+	status = history.status(start)
+	require.Len(t, status.Checks, historySize)
+	assert.Equal(t, start.Add(2*time.Second), status.Checks[0].Time)
+	assert.Equal(t, start.Add(time.Duration(historySize+1)*time.Second),
+		status.Checks[historySize-1].Time)
+	assert.True(t, status.Healthy)
+	assert.Empty(t, status.SinceLastHealthy)
+}
@@ -0,0 +1,137 @@
+package httpproxy
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/accesslog"
+	"github.com/qdm12/gluetun/internal/ratelimit"
+)
+
+// GetClientStats returns the traffic proxied per client.
+func (l *Loop) GetClientStats() (stats []accesslog.ClientStats) {
+	return l.recorder.Clients()
+}
+
+// exchange is a request or tunnel proxied, recorded
+// in the access log once it completes.
+type exchange struct {
+	start       time.Time
+	remoteAddr  string
+	method      string
+	destination string
+	// usage is the usage of the authenticated user,
+	// and is nil if the client is not authenticated.
+	usage *userUsage
+	// shaping is the bandwidth shaping of the client,
+	// and is nil if bandwidth shaping is not set up.
+	shaping   *ratelimit.Client
+	status    int
+	bytesUp   atomic.Uint64
+	bytesDown atomic.Uint64
+}
+
+func newExchange(request *http.Request) *exchange {
+	destination := request.Host
+	if request.Method != http.MethodConnect && request.URL.Host != "" {
+		destination = request.URL.Host
+	}
+	return &exchange{
+		start:       time.Now(),
+		remoteAddr:  request.RemoteAddr,
+		method:      request.Method,
+		destination: destination,
+	}
+}
+
+// client returns the IP address of the client.
+func (e *exchange) client() string {
+	client, _, err := net.SplitHostPort(e.remoteAddr)
+	if err != nil {
+		return e.remoteAddr
+	}
+	return client
+}
+
+// describe returns the client address and user name, for logging.
+func (e *exchange) describe() string {
+	return e.usage.describe(e.remoteAddr)
+}
+
+// wrapSent returns a reader counting the bytes read as bytes
+// sent by the client, and limiting them for its user and client.
+func (e *exchange) wrapSent(ctx context.Context, reader io.Reader) io.Reader {
+	return &countingReader{
+		reader:  e.shaping.WrapUpload(ctx, e.usage.wrapSent(ctx, reader)),
+		counter: &e.bytesUp,
+	}
+}
+
+// wrapReceived returns a reader counting the bytes read as bytes
+// received by the client, and limiting them for its user and client.
+func (e *exchange) wrapReceived(ctx context.Context, reader io.Reader) io.Reader {
+	return &countingReader{
+		reader:  e.shaping.WrapDownload(ctx, e.usage.wrapReceived(ctx, reader)),
+		counter: &e.bytesDown,
+	}
+}
+
+func (h *handler) record(exchange *exchange) {
+	var user string
+	if exchange.usage != nil {
+		user = exchange.usage.name
+	}
+	entry := accesslog.Entry{
+		Time:        exchange.start,
+		Proxy:       "http proxy",
+		Client:      exchange.client(),
+		User:        user,
+		Destination: exchange.destination,
+		Method:      exchange.method,
+		Status:      exchange.status,
+		BytesUp:     exchange.bytesUp.Load(),
+		BytesDown:   exchange.bytesDown.Load(),
+		Duration:    time.Since(exchange.start),
+	}
+	err := h.recorder.Record(entry)
+	if err != nil {
+		h.logger.Error(err.Error())
+	}
+}
+
+// statusRecorder records the status code written
+// to the response writer in the exchange.
+type statusRecorder struct {
+	http.ResponseWriter
+	exchange *exchange
+}
+
+func (s *statusRecorder) WriteHeader(statusCode int) {
+	if s.exchange.status == 0 {
+		s.exchange.status = statusCode
+	}
+	s.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (s *statusRecorder) Write(b []byte) (n int, err error) {
+	if s.exchange.status == 0 {
+		s.exchange.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(b)
+}
+
+var errHijackNotSupported = errors.New("hijacking not supported")
+
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := s.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errHijackNotSupported
+	}
+	return hijacker.Hijack()
+}
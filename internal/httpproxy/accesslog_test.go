@@ -0,0 +1,77 @@
+package httpproxy
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/qdm12/gluetun/internal/accesslog"
+	"github.com/qdm12/gluetun/internal/httpproxy/acl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_handler_accessLog(t *testing.T) {
+	t.Parallel()
+
+	destination := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, "created")
+	}))
+	t.Cleanup(destination.Close)
+
+	path := filepath.Join(t.TempDir(), "access.log")
+	const maxSize = 1024 * 1024
+	logger, err := accesslog.New(path, maxSize, 0)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = logger.Close() })
+	recorder := accesslog.NewRecorder(logger)
+
+	accessControlList, err := acl.New(nil, nil, nil, true)
+	require.NoError(t, err)
+	credentials, err := newCredentials("", "", "")
+	require.NoError(t, err)
+	handler := newHandler(context.Background(), &sync.WaitGroup{}, noopLogger{},
+		true, false, credentials, accessControlList, newUsers(), recorder, nil, nil)
+
+	request := httptest.NewRequest(http.MethodPost, destination.URL+"/path",
+		strings.NewReader("payload"))
+	request.RemoteAddr = "192.168.1.5:5000"
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	require.Equal(t, http.StatusCreated, response.Code)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var entry struct {
+		Proxy       string `json:"proxy"`
+		Client      string `json:"client"`
+		Destination string `json:"destination"`
+		Method      string `json:"method"`
+		Status      int    `json:"status"`
+		BytesUp     uint64 `json:"bytes_up"`
+		BytesDown   uint64 `json:"bytes_down"`
+	}
+	err = json.Unmarshal(data, &entry)
+	require.NoError(t, err)
+	assert.Equal(t, "http proxy", entry.Proxy)
+	assert.Equal(t, "192.168.1.5", entry.Client)
+	assert.Equal(t, destination.Listener.Addr().String(), entry.Destination)
+	assert.Equal(t, http.MethodPost, entry.Method)
+	assert.Equal(t, http.StatusCreated, entry.Status)
+	assert.Equal(t, uint64(len("payload")), entry.BytesUp)
+	assert.Equal(t, uint64(len("created")), entry.BytesDown)
+
+	clients := recorder.Clients()
+	require.Len(t, clients, 1)
+	assert.Equal(t, "192.168.1.5", clients[0].Client)
+	assert.Equal(t, uint64(1), clients[0].Requests)
+}
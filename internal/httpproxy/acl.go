@@ -0,0 +1,102 @@
+package httpproxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/qdm12/gluetun/internal/httpproxy/acl"
+)
+
+func newACL(httpProxy settings.HTTPProxy) (accessControlList *acl.ACL, err error) {
+	defaultAllow := httpProxy.DefaultPolicy == settings.HTTPProxyPolicyAllow
+	return acl.New(httpProxy.AllowedClients, httpProxy.AllowedDestinations,
+		httpProxy.BlockedDestinations, defaultAllow)
+}
+
+func (h *handler) isClientAllowed(responseWriter http.ResponseWriter,
+	request *http.Request) (allowed bool) {
+	addrPort, err := netip.ParseAddrPort(request.RemoteAddr)
+	if err == nil && h.acl.ClientAllowed(addrPort.Addr()) {
+		return true
+	}
+	h.logger.Info("blocked client " + request.RemoteAddr + ": not in allowed clients")
+	http.Error(responseWriter, "client not allowed", http.StatusForbidden)
+	return false
+}
+
+var errDestinationNotAllowed = errors.New("destination not allowed")
+
+// dial dials the address given if it is allowed by the access control
+// list. Host names are resolved first so the IP addresses dialed are
+// the ones checked against the access control list. It returns an
+// error wrapping errDestinationNotAllowed if the destination is blocked.
+func (h *handler) dial(ctx context.Context, network, address string) (
+	conn net.Conn, err error) {
+	dialer := net.Dialer{}
+	if !h.acl.RestrictsDestinations() {
+		return dialer.DialContext(ctx, network, address)
+	}
+
+	host, portString, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	const base, bitSize = 10, 16
+	port64, err := strconv.ParseUint(portString, base, bitSize)
+	if err != nil {
+		return nil, fmt.Errorf("parsing port: %w", err)
+	}
+	port := uint16(port64)
+
+	var ips []netip.Addr
+	ip, err := netip.ParseAddr(host)
+	if err == nil {
+		host = ""
+		ips = []netip.Addr{ip}
+	} else {
+		ips, err = h.resolver.LookupNetIP(ctx, "ip", host)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var dialErr error
+	blockedErr := fmt.Errorf("%w: %s: no IP address resolved", errDestinationNotAllowed, address)
+	for _, ip := range ips {
+		ip = ip.Unmap()
+		allowed, reason := h.acl.DestinationAllowed(host, ip, port)
+		if !allowed {
+			blockedErr = fmt.Errorf("%w: %s: %s", errDestinationNotAllowed, address, reason)
+			continue
+		}
+
+		ipAddress := netip.AddrPortFrom(ip, port).String()
+		conn, dialErr = dialer.DialContext(ctx, network, ipAddress)
+		if dialErr == nil {
+			return conn, nil
+		}
+	}
+
+	if dialErr != nil {
+		return nil, dialErr
+	}
+	return nil, blockedErr
+}
+
+// writeDialError writes an error response for the dial error given,
+// which is 403 if the destination is blocked by the access control list.
+func (h *handler) writeDialError(responseWriter http.ResponseWriter,
+	client string, err error) {
+	if errors.Is(err, errDestinationNotAllowed) {
+		h.logger.Info("blocked request from " + client + ": " + err.Error())
+		http.Error(responseWriter, "destination not allowed", http.StatusForbidden)
+		return
+	}
+	http.Error(responseWriter, err.Error(), http.StatusServiceUnavailable)
+}
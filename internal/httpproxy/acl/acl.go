@@ -0,0 +1,102 @@
+// Package acl implements access control lists for the HTTP proxy,
+// to restrict which clients can use the proxy and which destinations
+// the proxy can reach.
+package acl
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// ACL restricts clients by source IP address and
+// destinations by host, IP address and port.
+type ACL struct {
+	allowedClients []netip.Prefix
+	allowed        []Rule
+	blocked        []Rule
+	defaultAllow   bool
+}
+
+// New creates an access control list. Clients are all allowed
+// if allowedClients is empty. A destination is blocked if it
+// matches any of the blocked rules, allowed if it matches any
+// of the allowed rules, and subject to the default policy
+// otherwise.
+func New(allowedClients []netip.Prefix, allowed, blocked []string,
+	defaultAllow bool) (acl *ACL, err error) {
+	acl = &ACL{
+		allowedClients: make([]netip.Prefix, len(allowedClients)),
+		defaultAllow:   defaultAllow,
+	}
+
+	for i, prefix := range allowedClients {
+		acl.allowedClients[i] = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()).Masked()
+	}
+
+	acl.allowed, err = parseRules(allowed)
+	if err != nil {
+		return nil, fmt.Errorf("parsing allowed destinations: %w", err)
+	}
+
+	acl.blocked, err = parseRules(blocked)
+	if err != nil {
+		return nil, fmt.Errorf("parsing blocked destinations: %w", err)
+	}
+
+	return acl, nil
+}
+
+func parseRules(rawRules []string) (rules []Rule, err error) {
+	rules = make([]Rule, len(rawRules))
+	for i, rawRule := range rawRules {
+		rules[i], err = ParseRule(rawRule)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
+
+// RestrictsDestinations returns true if some destinations
+// can be blocked by the access control list.
+func (a *ACL) RestrictsDestinations() bool {
+	return len(a.blocked) > 0 || !a.defaultAllow
+}
+
+// ClientAllowed returns true if the client IP address
+// given is allowed to use the proxy.
+func (a *ACL) ClientAllowed(ip netip.Addr) bool {
+	if len(a.allowedClients) == 0 {
+		return true
+	}
+	ip = ip.Unmap()
+	for _, prefix := range a.allowedClients {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// DestinationAllowed returns true if the destination given is allowed,
+// and a reason describing why it is blocked otherwise. The host name
+// can be left empty if the destination is an IP address.
+func (a *ACL) DestinationAllowed(host string, ip netip.Addr, port uint16) (
+	allowed bool, reason string) {
+	for _, rule := range a.blocked {
+		if rule.Match(host, ip, port) {
+			return false, "matches blocked destination " + rule.String()
+		}
+	}
+
+	for _, rule := range a.allowed {
+		if rule.Match(host, ip, port) {
+			return true, ""
+		}
+	}
+
+	if a.defaultAllow {
+		return true, ""
+	}
+	return false, "no allowed destination matched and the default policy is deny"
+}
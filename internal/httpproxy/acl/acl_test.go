@@ -0,0 +1,94 @@
+package acl
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ACL(t *testing.T) {
+	t.Parallel()
+
+	allowedClients := []netip.Prefix{netip.MustParsePrefix("192.168.1.0/24")}
+	allowed := []string{"*.example.com:443", "192.168.1.10:8080"}
+	blocked := []string{"192.168.0.0/16", "admin.example.com"}
+	acl, err := New(allowedClients, allowed, blocked, false)
+	require.NoError(t, err)
+
+	assert.True(t, acl.RestrictsDestinations())
+	assert.True(t, acl.ClientAllowed(netip.MustParseAddr("192.168.1.5")))
+	assert.True(t, acl.ClientAllowed(netip.MustParseAddr("::ffff:192.168.1.5")))
+	assert.False(t, acl.ClientAllowed(netip.MustParseAddr("10.0.0.1")))
+
+	publicIP := netip.MustParseAddr("93.184.216.34")
+	testCases := map[string]struct {
+		host    string
+		ip      netip.Addr
+		port    uint16
+		allowed bool
+		reason  string
+	}{
+		"allowed_domain": {
+			host:    "www.example.com",
+			ip:      publicIP,
+			port:    443,
+			allowed: true,
+		},
+		"allowed_domain_wrong_port": {
+			host:   "www.example.com",
+			ip:     publicIP,
+			port:   80,
+			reason: "no allowed destination matched and the default policy is deny",
+		},
+		"blocked_domain_takes_precedence": {
+			host:   "admin.example.com",
+			ip:     publicIP,
+			port:   443,
+			reason: "matches blocked destination admin.example.com",
+		},
+		"blocked_ip_takes_precedence": {
+			ip:     netip.MustParseAddr("192.168.1.10"),
+			port:   8080,
+			reason: "matches blocked destination 192.168.0.0/16",
+		},
+		"domain_resolving_to_blocked_ip": {
+			host:   "lan.example.com",
+			ip:     netip.MustParseAddr("192.168.1.1"),
+			port:   443,
+			reason: "matches blocked destination 192.168.0.0/16",
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			allowed, reason := acl.DestinationAllowed(testCase.host, testCase.ip, testCase.port)
+
+			assert.Equal(t, testCase.allowed, allowed)
+			assert.Equal(t, testCase.reason, reason)
+		})
+	}
+
+	t.Run("unrestricted", func(t *testing.T) {
+		t.Parallel()
+
+		acl, err := New(nil, nil, nil, true)
+		require.NoError(t, err)
+
+		assert.False(t, acl.RestrictsDestinations())
+		assert.True(t, acl.ClientAllowed(netip.MustParseAddr("10.0.0.1")))
+		allowed, _ := acl.DestinationAllowed("", publicIP, 80)
+		assert.True(t, allowed)
+	})
+
+	t.Run("invalid_rule", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := New(nil, nil, []string{"bad host"}, true)
+		assert.EqualError(t, err, "parsing blocked destinations: rule host is not valid: bad host")
+	})
+}
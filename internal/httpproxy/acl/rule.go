@@ -0,0 +1,173 @@
+package acl
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Rule is a destination rule matching a host and a port range.
+// The host can be a domain name, a domain wildcard such as
+// `*.example.com` matching all its subdomains, an IP address,
+// an IP prefix in CIDR notation or `*` to match any host.
+// The port range is optional and can be a single port or a
+// range such as `8000-8999`.
+type Rule struct {
+	raw string
+	// anyHost is true if the rule matches any host.
+	anyHost bool
+	// domain is the domain name to match, without its
+	// leading `*.` if it is a wildcard.
+	domain   string
+	wildcard bool
+	// prefix is the IP prefix to match, and is only
+	// valid if the rule matches IP addresses.
+	prefix netip.Prefix
+	// minPort and maxPort are both 0 if the rule
+	// matches any port.
+	minPort, maxPort uint16
+}
+
+var (
+	ErrRuleEmpty          = errors.New("rule is empty")
+	ErrRuleBracketMissing = errors.New("rule closing bracket is missing")
+	ErrRuleHostNotValid   = errors.New("rule host is not valid")
+	ErrRulePortNotValid   = errors.New("rule port is not valid")
+	ErrRulePortRangeOrder = errors.New("rule port range start is bigger than its end")
+)
+
+var domainRegex = regexp.MustCompile(`^[a-z0-9_]([a-z0-9\-_]*[a-z0-9_])?(\.[a-z0-9_]([a-z0-9\-_]*[a-z0-9_])?)*$`)
+
+// ParseRule parses a destination rule of the form `host`,
+// `host:ports` or `[ipv6host]:ports`.
+func ParseRule(s string) (rule Rule, err error) {
+	rule.raw = s
+	if s == "" {
+		return rule, fmt.Errorf("%w", ErrRuleEmpty)
+	}
+
+	host, ports := s, ""
+	switch {
+	case strings.HasPrefix(s, "["):
+		end := strings.Index(s, "]")
+		if end == -1 {
+			return rule, fmt.Errorf("%w: %s", ErrRuleBracketMissing, s)
+		}
+		host = s[1:end]
+		rest := s[end+1:]
+		if rest != "" {
+			if !strings.HasPrefix(rest, ":") {
+				return rule, fmt.Errorf("%w: %s", ErrRulePortNotValid, s)
+			}
+			ports = rest[1:]
+		}
+	case strings.Count(s, ":") == 1:
+		host, ports, _ = strings.Cut(s, ":")
+	}
+
+	err = rule.parseHost(host)
+	if err != nil {
+		return rule, err
+	}
+
+	if ports != "" {
+		rule.minPort, rule.maxPort, err = parsePortRange(ports)
+		if err != nil {
+			return rule, fmt.Errorf("%w: %s", err, s)
+		}
+	}
+
+	return rule, nil
+}
+
+func (r *Rule) parseHost(host string) (err error) {
+	if host == "*" {
+		r.anyHost = true
+		return nil
+	}
+
+	r.prefix, err = netip.ParsePrefix(host)
+	if err == nil {
+		r.prefix = netip.PrefixFrom(r.prefix.Addr().Unmap(), r.prefix.Bits()).Masked()
+		return nil
+	}
+
+	ip, err := netip.ParseAddr(host)
+	if err == nil {
+		ip = ip.Unmap()
+		r.prefix = netip.PrefixFrom(ip, ip.BitLen())
+		return nil
+	}
+
+	domain := strings.TrimSuffix(strings.ToLower(host), ".")
+	if strings.HasPrefix(domain, "*.") {
+		r.wildcard = true
+		domain = strings.TrimPrefix(domain, "*.")
+	}
+	if !domainRegex.MatchString(domain) {
+		return fmt.Errorf("%w: %s", ErrRuleHostNotValid, host)
+	}
+	r.domain = domain
+	return nil
+}
+
+func parsePortRange(s string) (minPort, maxPort uint16, err error) {
+	minString, maxString, isRange := strings.Cut(s, "-")
+	if !isRange {
+		maxString = minString
+	}
+
+	minPort, err = parsePort(minString)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	maxPort, err = parsePort(maxString)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if minPort > maxPort {
+		return 0, 0, fmt.Errorf("%w", ErrRulePortRangeOrder)
+	}
+	return minPort, maxPort, nil
+}
+
+func parsePort(s string) (port uint16, err error) {
+	const base, bitSize = 10, 16
+	value, err := strconv.ParseUint(s, base, bitSize)
+	if err != nil || value == 0 {
+		return 0, fmt.Errorf("%w", ErrRulePortNotValid)
+	}
+	return uint16(value), nil
+}
+
+// String returns the rule as it was parsed.
+func (r Rule) String() string {
+	return r.raw
+}
+
+// Match returns true if the rule matches the host name, IP address
+// and port given. The host name can be left empty if the destination
+// is an IP address.
+func (r Rule) Match(host string, ip netip.Addr, port uint16) bool {
+	if r.minPort != 0 && (port < r.minPort || port > r.maxPort) {
+		return false
+	}
+
+	switch {
+	case r.anyHost:
+		return true
+	case r.prefix.IsValid():
+		return ip.IsValid() && r.prefix.Contains(ip.Unmap())
+	}
+
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if r.wildcard {
+		return strings.HasSuffix(host, "."+r.domain)
+	}
+	return host == r.domain
+}
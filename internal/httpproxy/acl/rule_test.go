@@ -0,0 +1,182 @@
+package acl
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseRule(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		s          string
+		rule       Rule
+		errWrapped error
+		errMessage string
+	}{
+		"empty": {
+			errWrapped: ErrRuleEmpty,
+			errMessage: "rule is empty",
+		},
+		"any_host": {
+			s:    "*",
+			rule: Rule{raw: "*", anyHost: true},
+		},
+		"any_host_with_port": {
+			s:    "*:22",
+			rule: Rule{raw: "*:22", anyHost: true, minPort: 22, maxPort: 22},
+		},
+		"domain": {
+			s:    "Example.com",
+			rule: Rule{raw: "Example.com", domain: "example.com"},
+		},
+		"domain_wildcard_with_port_range": {
+			s: "*.example.com:8000-8999",
+			rule: Rule{raw: "*.example.com:8000-8999", domain: "example.com",
+				wildcard: true, minPort: 8000, maxPort: 8999},
+		},
+		"ipv4_address": {
+			s:    "192.168.1.1",
+			rule: Rule{raw: "192.168.1.1", prefix: netip.MustParsePrefix("192.168.1.1/32")},
+		},
+		"ipv4_prefix_masked": {
+			s:    "192.168.1.1/16:80",
+			rule: Rule{raw: "192.168.1.1/16:80", prefix: netip.MustParsePrefix("192.168.0.0/16"), minPort: 80, maxPort: 80},
+		},
+		"ipv6_prefix_without_port": {
+			s:    "fd00::/8",
+			rule: Rule{raw: "fd00::/8", prefix: netip.MustParsePrefix("fd00::/8")},
+		},
+		"ipv6_address_with_port": {
+			s:    "[::1]:22",
+			rule: Rule{raw: "[::1]:22", prefix: netip.MustParsePrefix("::1/128"), minPort: 22, maxPort: 22},
+		},
+		"missing_bracket": {
+			s:          "[::1:22",
+			errWrapped: ErrRuleBracketMissing,
+			errMessage: "rule closing bracket is missing: [::1:22",
+		},
+		"bad_host": {
+			s:          "exa mple.com",
+			errWrapped: ErrRuleHostNotValid,
+			errMessage: "rule host is not valid: exa mple.com",
+		},
+		"bad_port": {
+			s:          "example.com:0",
+			errWrapped: ErrRulePortNotValid,
+			errMessage: "rule port is not valid: example.com:0",
+		},
+		"port_range_reversed": {
+			s:          "example.com:90-80",
+			errWrapped: ErrRulePortRangeOrder,
+			errMessage: "rule port range start is bigger than its end: example.com:90-80",
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rule, err := ParseRule(testCase.s)
+
+			assert.ErrorIs(t, err, testCase.errWrapped)
+			if testCase.errWrapped != nil {
+				assert.EqualError(t, err, testCase.errMessage)
+				return
+			}
+			assert.Equal(t, testCase.rule, rule)
+		})
+	}
+}
+
+func Test_Rule_Match(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		rule  string
+		host  string
+		ip    netip.Addr
+		port  uint16
+		match bool
+	}{
+		"any_host": {
+			rule:  "*",
+			host:  "example.com",
+			port:  443,
+			match: true,
+		},
+		"port_mismatch": {
+			rule: "*:22",
+			host: "example.com",
+			port: 443,
+		},
+		"port_in_range": {
+			rule:  "*:8000-8999",
+			ip:    netip.MustParseAddr("1.2.3.4"),
+			port:  8080,
+			match: true,
+		},
+		"domain_case_insensitive": {
+			rule:  "example.com",
+			host:  "EXAMPLE.com.",
+			port:  80,
+			match: true,
+		},
+		"domain_does_not_match_subdomain": {
+			rule: "example.com",
+			host: "www.example.com",
+			port: 80,
+		},
+		"wildcard_matches_subdomain": {
+			rule:  "*.example.com",
+			host:  "a.b.example.com",
+			port:  80,
+			match: true,
+		},
+		"wildcard_does_not_match_domain": {
+			rule: "*.example.com",
+			host: "example.com",
+			port: 80,
+		},
+		"wildcard_does_not_match_suffix": {
+			rule: "*.example.com",
+			host: "badexample.com",
+			port: 80,
+		},
+		"prefix_contains_ip": {
+			rule:  "192.168.0.0/16",
+			host:  "router.lan",
+			ip:    netip.MustParseAddr("192.168.1.1"),
+			port:  80,
+			match: true,
+		},
+		"prefix_contains_ipv4_mapped_ip": {
+			rule:  "10.0.0.0/8",
+			ip:    netip.MustParseAddr("::ffff:10.1.2.3"),
+			port:  80,
+			match: true,
+		},
+		"prefix_does_not_contain_ip": {
+			rule: "192.168.0.0/16",
+			ip:   netip.MustParseAddr("1.1.1.1"),
+			port: 80,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rule, err := ParseRule(testCase.rule)
+			assert.NoError(t, err)
+
+			match := rule.Match(testCase.host, testCase.ip, testCase.port)
+
+			assert.Equal(t, testCase.match, match)
+		})
+	}
+}
@@ -5,18 +5,21 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+
+	"github.com/qdm12/gluetun/internal/httpproxy/htpasswd"
 )
 
-func (h *handler) isAuthorized(responseWriter http.ResponseWriter, request *http.Request) (authorized bool) {
-	if h.username == "" || (request.Method != "CONNECT" && !request.URL.IsAbs()) {
-		return true
+func (h *handler) isAuthorized(responseWriter http.ResponseWriter, request *http.Request) (
+	user htpasswd.User, authorized bool) {
+	if !h.credentials.enabled() || (request.Method != "CONNECT" && !request.URL.IsAbs()) {
+		return user, true
 	}
 	basicAuth := request.Header.Get("Proxy-Authorization")
 	if basicAuth == "" {
 		h.logger.Info("Proxy-Authorization header not found from " + request.RemoteAddr)
 		responseWriter.Header().Set("Proxy-Authenticate", `Basic realm="Access to Gluetun over HTTP"`)
 		responseWriter.WriteHeader(http.StatusProxyAuthRequired)
-		return false
+		return user, false
 	}
 	b64UsernamePassword := strings.TrimPrefix(basicAuth, "Basic ")
 	b, err := base64.StdEncoding.DecodeString(b64UsernamePassword)
@@ -24,21 +27,21 @@ func (h *handler) isAuthorized(responseWriter http.ResponseWriter, request *http
 		h.logger.Info("Cannot decode Proxy-Authorization header value from " +
 			request.RemoteAddr + ": " + err.Error())
 		responseWriter.WriteHeader(http.StatusUnauthorized)
-		return false
+		return user, false
 	}
-	usernamePassword := strings.Split(string(b), ":")
-	const expectedFields = 2
-	if len(usernamePassword) != expectedFields {
+	username, password, ok := strings.Cut(string(b), ":")
+	if !ok {
 		responseWriter.WriteHeader(http.StatusBadRequest)
-		return false
+		return user, false
 	}
-	if h.username != usernamePassword[0] || h.password != usernamePassword[1] {
-		h.logger.Info(fmt.Sprintf("Username (%q) or password (%q) mismatch from %s",
-			usernamePassword[0], usernamePassword[1], request.RemoteAddr))
-		h.logger.Debug("username provided \"" + usernamePassword[0] +
-			"\" and password provided \"" + usernamePassword[1] + "\"")
+	user, ok = h.credentials.authenticate(username, password)
+	if !ok {
+		h.logger.Info(fmt.Sprintf("Username (%q) or password mismatch from %s",
+			username, request.RemoteAddr))
+		h.logger.Debug("username provided \"" + username +
+			"\" and password provided \"" + password + "\"")
 		responseWriter.WriteHeader(http.StatusUnauthorized)
-		return false
+		return user, false
 	}
-	return true
+	return user, true
 }
@@ -0,0 +1,152 @@
+package httpproxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/httpproxy/htpasswd"
+)
+
+// credentials authenticates users of the HTTP proxy, using the
+// single user from the settings and the users of the credentials
+// file, which is reloaded when it changes.
+type credentials struct {
+	username string
+	password string
+	path     string
+
+	mutex sync.RWMutex
+	users map[string]htpasswd.User
+	// dummy is the user to verify passwords of unknown
+	// user names against, to not leak which names exist.
+	dummy   htpasswd.User
+	modTime time.Time
+	size    int64
+	// verified maps user names to the SHA256 digest of their
+	// last verified password, to avoid verifying slow bcrypt
+	// hashes on every request.
+	verified map[string][sha256.Size]byte
+}
+
+func newCredentials(username, password, path string) (
+	c *credentials, err error) {
+	c = &credentials{
+		username: username,
+		password: password,
+		path:     path,
+		verified: make(map[string][sha256.Size]byte),
+	}
+	if path != "" {
+		_, err = c.reload()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// enabled returns true if clients must authenticate.
+func (c *credentials) enabled() bool {
+	return c.username != "" || c.path != ""
+}
+
+// authenticate returns the user for the name and password given,
+// and false if the name and password do not match any user.
+func (c *credentials) authenticate(name, password string) (
+	user htpasswd.User, ok bool) {
+	if c.username != "" && name == c.username {
+		ok = subtle.ConstantTimeCompare([]byte(password), []byte(c.password)) == 1
+		return htpasswd.User{Name: name}, ok
+	}
+
+	digest := sha256.Sum256([]byte(password))
+	c.mutex.RLock()
+	user, exists := c.users[name]
+	verifiedDigest, verified := c.verified[name]
+	dummy := c.dummy
+	c.mutex.RUnlock()
+	switch {
+	case !exists:
+		_ = dummy.Verify(password)
+		return user, false
+	case verified && subtle.ConstantTimeCompare(digest[:], verifiedDigest[:]) == 1:
+		return user, true
+	case !user.Verify(password):
+		return user, false
+	}
+
+	c.mutex.Lock()
+	// only cache the digest if the file was not reloaded meanwhile
+	if _, exists := c.users[name]; exists {
+		c.verified[name] = digest
+	}
+	c.mutex.Unlock()
+	return user, true
+}
+
+// reload reloads the credentials file if its modification time
+// or size changed, and returns true if it was reloaded.
+func (c *credentials) reload() (reloaded bool, err error) {
+	stat, err := os.Stat(c.path)
+	if err != nil {
+		return false, fmt.Errorf("reading credentials file: %w", err)
+	}
+
+	c.mutex.RLock()
+	unchanged := stat.ModTime().Equal(c.modTime) && stat.Size() == c.size
+	c.mutex.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	users, err := htpasswd.ParseFile(c.path)
+	if err != nil {
+		return false, fmt.Errorf("reading credentials file: %w", err)
+	}
+
+	dummy, err := htpasswd.DummyUser(users)
+	if err != nil {
+		return false, fmt.Errorf("creating dummy user: %w", err)
+	}
+
+	c.mutex.Lock()
+	c.users = users
+	c.dummy = dummy
+	c.modTime = stat.ModTime()
+	c.size = stat.Size()
+	c.verified = make(map[string][sha256.Size]byte, len(users))
+	c.mutex.Unlock()
+	return true, nil
+}
+
+// watch reloads the credentials file periodically if it
+// changed, until the context is canceled. The previous users
+// are kept if the file cannot be read or parsed.
+func (c *credentials) watch(ctx context.Context, logger infoErrorer,
+	period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		reloaded, err := c.reload()
+		switch {
+		case err != nil:
+			logger.Error(err.Error())
+		case reloaded:
+			c.mutex.RLock()
+			count := len(c.users)
+			c.mutex.RUnlock()
+			logger.Info(fmt.Sprintf("reloaded %d users from credentials file %s", count, c.path))
+		}
+	}
+}
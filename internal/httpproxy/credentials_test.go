@@ -0,0 +1,163 @@
+package httpproxy
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/accesslog"
+	"github.com/qdm12/gluetun/internal/httpproxy/acl"
+	"github.com/qdm12/gluetun/internal/httpproxy/htpasswd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// shaHash is the htpasswd SHA hash of "password".
+const shaHash = "{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g="
+
+func Test_credentials(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "credentials")
+	err := os.WriteFile(path, []byte("alice:"+shaHash+"\n"), 0600)
+	require.NoError(t, err)
+
+	credentials, err := newCredentials("single", "single-password", path)
+	require.NoError(t, err)
+	assert.True(t, credentials.enabled())
+
+	user, ok := credentials.authenticate("single", "single-password")
+	assert.True(t, ok)
+	assert.Equal(t, "single", user.Name)
+
+	_, ok = credentials.authenticate("single", "password")
+	assert.False(t, ok)
+
+	user, ok = credentials.authenticate("alice", "password")
+	assert.True(t, ok)
+	assert.Equal(t, "alice", user.Name)
+	// verified password digest cached
+	_, ok = credentials.authenticate("alice", "password")
+	assert.True(t, ok)
+	_, ok = credentials.authenticate("alice", "wrong")
+	assert.False(t, ok)
+
+	_, ok = credentials.authenticate("bob", "password")
+	assert.False(t, ok)
+
+	reloaded, err := credentials.reload()
+	require.NoError(t, err)
+	assert.False(t, reloaded)
+
+	err = os.WriteFile(path, []byte("bob:"+shaHash+":1:1k\n"), 0600)
+	require.NoError(t, err)
+	// make sure the modification time changes
+	modTime := time.Now().Add(time.Second)
+	err = os.Chtimes(path, modTime, modTime)
+	require.NoError(t, err)
+
+	reloaded, err = credentials.reload()
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+
+	_, ok = credentials.authenticate("alice", "password")
+	assert.False(t, ok)
+	user, ok = credentials.authenticate("bob", "password")
+	assert.True(t, ok)
+	assert.Equal(t, uint(1), user.MaxConnections)
+	assert.Equal(t, uint64(1024), user.Bandwidth)
+}
+
+func Test_users(t *testing.T) {
+	t.Parallel()
+
+	users := newUsers()
+
+	usage, ok := users.acquire(htpasswd.User{})
+	assert.True(t, ok)
+	assert.Nil(t, usage)
+	users.release(usage)
+
+	user := htpasswd.User{Name: "bob", MaxConnections: 1}
+	first, ok := users.acquire(user)
+	require.True(t, ok)
+	_, ok = users.acquire(user)
+	assert.False(t, ok)
+	users.release(first)
+	second, ok := users.acquire(user)
+	require.True(t, ok)
+
+	expected := []UserStats{{
+		Name:              "bob",
+		ActiveConnections: 1,
+		Requests:          2,
+		Rejected:          1,
+	}}
+	assert.Equal(t, expected, users.stats())
+	users.release(second)
+}
+
+func Test_handler_credentials(t *testing.T) {
+	t.Parallel()
+
+	destination := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "reached")
+	}))
+	t.Cleanup(destination.Close)
+
+	path := filepath.Join(t.TempDir(), "credentials")
+	err := os.WriteFile(path, []byte("alice:"+shaHash+"\n"), 0600)
+	require.NoError(t, err)
+	credentials, err := newCredentials("", "", path)
+	require.NoError(t, err)
+	accessControlList, err := acl.New(nil, nil, nil, true)
+	require.NoError(t, err)
+	users := newUsers()
+	handler := newHandler(context.Background(), &sync.WaitGroup{}, noopLogger{},
+		true, false, credentials, accessControlList, users, accesslog.NewRecorder(nil), nil, nil)
+
+	testCases := map[string]struct {
+		authorization string
+		statusCode    int
+	}{
+		"no_authorization": {
+			statusCode: http.StatusProxyAuthRequired,
+		},
+		"wrong_password": {
+			authorization: "alice:wrong",
+			statusCode:    http.StatusUnauthorized,
+		},
+		"authorized": {
+			authorization: "alice:password",
+			statusCode:    http.StatusOK,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, destination.URL, nil)
+			if testCase.authorization != "" {
+				request.Header.Set("Proxy-Authorization", "Basic "+
+					base64.StdEncoding.EncodeToString([]byte(testCase.authorization)))
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, testCase.statusCode, recorder.Code)
+		})
+	}
+
+	expected := []UserStats{{
+		Name:          "alice",
+		Requests:      1,
+		BytesReceived: uint64(len("reached")),
+	}}
+	assert.Equal(t, expected, users.stats())
+}
@@ -2,52 +2,101 @@ package httpproxy
 
 import (
 	"context"
+	"net"
 	"net/http"
 	"sync"
 	"time"
+
+	"github.com/qdm12/gluetun/internal/accesslog"
+	"github.com/qdm12/gluetun/internal/httpproxy/acl"
+	"github.com/qdm12/gluetun/internal/ratelimit"
 )
 
 func newHandler(ctx context.Context, wg *sync.WaitGroup, logger Logger,
-	stealth, verbose bool, username, password string) http.Handler {
+	stealth, verbose bool, credentials *credentials, acl *acl.ACL,
+	users *users, recorder *accesslog.Recorder, shaper *ratelimit.Shaper,
+	pac *pacServer) http.Handler {
+	handler := &handler{
+		ctx:         ctx,
+		wg:          wg,
+		logger:      logger,
+		verbose:     verbose,
+		stealth:     stealth,
+		credentials: credentials,
+		acl:         acl,
+		users:       users,
+		recorder:    recorder,
+		shaper:      shaper,
+		pac:         pac,
+		resolver:    net.DefaultResolver,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert
+	transport.DialContext = handler.dial
 	const httpTimeout = 24 * time.Hour
-	return &handler{
-		ctx: ctx,
-		wg:  wg,
-		client: &http.Client{
-			Timeout:       httpTimeout,
-			CheckRedirect: returnRedirect},
-		logger:   logger,
-		verbose:  verbose,
-		stealth:  stealth,
-		username: username,
-		password: password,
+	handler.client = &http.Client{
+		Transport:     transport,
+		Timeout:       httpTimeout,
+		CheckRedirect: returnRedirect,
 	}
+	return handler
 }
 
 type handler struct {
-	ctx                context.Context //nolint:containedctx
-	wg                 *sync.WaitGroup
-	client             *http.Client
-	logger             Logger
-	verbose, stealth   bool
-	username, password string
+	ctx              context.Context //nolint:containedctx
+	wg               *sync.WaitGroup
+	client           *http.Client
+	logger           Logger
+	verbose, stealth bool
+	credentials      *credentials
+	acl              *acl.ACL
+	users            *users
+	recorder         *accesslog.Recorder
+	shaper           *ratelimit.Shaper
+	pac              *pacServer
+	resolver         *net.Resolver
 }
 
 func (h *handler) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	exchange := newExchange(request)
+	responseWriter = &statusRecorder{ResponseWriter: responseWriter, exchange: exchange}
+	defer h.record(exchange)
+
 	if !h.isAccepted(responseWriter, request) {
 		return
 	}
-	if !h.isAuthorized(responseWriter, request) {
+	if !h.isClientAllowed(responseWriter, request) {
+		return
+	}
+	if h.pac.matches(request) {
+		h.pac.ServeHTTP(responseWriter, request)
+		return
+	}
+	user, authorized := h.isAuthorized(responseWriter, request)
+	if !authorized {
+		return
+	}
+	usage, ok := h.users.acquire(user)
+	if !ok {
+		h.logger.Info("rejected request from " + usage.describe(request.RemoteAddr) +
+			": maximum number of connections reached")
+		http.Error(responseWriter, "too many connections", http.StatusTooManyRequests)
 		return
 	}
+	defer h.users.release(usage)
+	exchange.usage = usage
+	exchange.shaping = h.shaper.Acquire(exchange.client())
+	defer exchange.shaping.Release()
 	request.Header.Del("Proxy-Connection")
 	request.Header.Del("Proxy-Authenticate")
 	request.Header.Del("Proxy-Authorization")
-	switch request.Method {
-	case http.MethodConnect:
-		h.handleHTTPS(responseWriter, request)
+	switch {
+	case request.Method == http.MethodConnect:
+		h.handleHTTPS(responseWriter, request, exchange)
+	case isUpgradeRequest(request):
+		h.handleUpgrade(responseWriter, request, exchange)
 	default:
-		h.handleHTTP(responseWriter, request)
+		h.handleHTTP(responseWriter, request, exchange)
 	}
 }
 
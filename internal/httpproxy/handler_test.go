@@ -1,10 +1,18 @@
 package httpproxy
 
 import (
+	"context"
+	"io"
 	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync"
 	"testing"
 
+	"github.com/qdm12/gluetun/internal/accesslog"
+	"github.com/qdm12/gluetun/internal/httpproxy/acl"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_returnRedirect(t *testing.T) {
@@ -14,3 +22,62 @@ func Test_returnRedirect(t *testing.T) {
 
 	assert.Equal(t, http.ErrUseLastResponse, err)
 }
+
+type noopLogger struct{}
+
+func (noopLogger) Debug(string) {}
+func (noopLogger) Info(string)  {}
+func (noopLogger) Warn(string)  {}
+func (noopLogger) Error(string) {}
+
+func Test_handler_acl(t *testing.T) {
+	t.Parallel()
+
+	destination := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "reached")
+	}))
+	t.Cleanup(destination.Close)
+
+	testCases := map[string]struct {
+		allowedClients []netip.Prefix
+		blocked        []string
+		remoteAddr     string
+		statusCode     int
+	}{
+		"allowed": {
+			remoteAddr: "192.168.1.5:5000",
+			statusCode: http.StatusOK,
+		},
+		"client_not_allowed": {
+			allowedClients: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+			remoteAddr:     "192.168.1.5:5000",
+			statusCode:     http.StatusForbidden,
+		},
+		"destination_blocked": {
+			blocked:    []string{"127.0.0.0/8"},
+			remoteAddr: "192.168.1.5:5000",
+			statusCode: http.StatusForbidden,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			accessControlList, err := acl.New(testCase.allowedClients, nil, testCase.blocked, true)
+			require.NoError(t, err)
+			credentials, err := newCredentials("", "", "")
+			require.NoError(t, err)
+			handler := newHandler(context.Background(), &sync.WaitGroup{}, noopLogger{},
+				true, false, credentials, accessControlList, newUsers(), accesslog.NewRecorder(nil), nil, nil)
+
+			request := httptest.NewRequest(http.MethodGet, destination.URL, nil)
+			request.RemoteAddr = testCase.remoteAddr
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, testCase.statusCode, recorder.Code)
+		})
+	}
+}
@@ -0,0 +1,181 @@
+// Package htpasswd parses htpasswd-style credentials files,
+// extended with optional per-user limits.
+package htpasswd
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1" //nolint:gosec
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/qdm12/gluetun/internal/ratelimit"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// User is a user of a credentials file.
+type User struct {
+	Name string
+	hash string
+	// MaxConnections is the maximum number of concurrent
+	// connections for the user, and 0 means no limit.
+	MaxConnections uint
+	// Bandwidth is the maximum number of bytes per second
+	// for all the connections of the user, and 0 means no limit.
+	Bandwidth uint64
+}
+
+var (
+	ErrLineMalformed       = errors.New("line is malformed")
+	ErrUserDuplicate       = errors.New("user is defined more than once")
+	ErrHashNotSupported    = errors.New("password hash format is not supported")
+	ErrMaxConnectionsValue = errors.New("maximum connections value is not valid")
+	ErrBandwidthValue      = errors.New("bandwidth value is not valid")
+)
+
+// ParseFile reads and parses the credentials file at the path given.
+func ParseFile(path string) (users map[string]User, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	users, err = Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return users, nil
+}
+
+// Parse parses credentials data where each line is of the form
+// `name:hash[:max_connections[:bandwidth]]`. The hash can be a bcrypt
+// hash or a `{SHA}` hash, as generated by `htpasswd -B` or `htpasswd -s`.
+// The optional bandwidth is in bytes per second and can have a k, m or g
+// suffix for multiples of 1024. Empty lines and lines starting with `#`
+// are ignored.
+func Parse(data []byte) (users map[string]User, err error) {
+	users = make(map[string]User)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		user, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
+		_, exists := users[user.Name]
+		if exists {
+			return nil, fmt.Errorf("line %d: %w: %s", lineNumber, ErrUserDuplicate, user.Name)
+		}
+		users[user.Name] = user
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func parseLine(line string) (user User, err error) {
+	const minFields, maxFields = 2, 4
+	fields := strings.Split(line, ":")
+	if len(fields) < minFields || len(fields) > maxFields ||
+		fields[0] == "" || fields[1] == "" {
+		return user, fmt.Errorf("%w", ErrLineMalformed)
+	}
+	user.Name = fields[0]
+	user.hash = fields[1]
+
+	switch {
+	case isBcrypt(user.hash):
+		_, err = bcrypt.Cost([]byte(user.hash))
+		if err != nil {
+			return user, fmt.Errorf("user %s: bcrypt hash: %w", user.Name, err)
+		}
+	case strings.HasPrefix(user.hash, "{SHA}"):
+		_, err = base64.StdEncoding.DecodeString(strings.TrimPrefix(user.hash, "{SHA}"))
+		if err != nil {
+			return user, fmt.Errorf("user %s: SHA hash: %w", user.Name, err)
+		}
+	default:
+		return user, fmt.Errorf("user %s: %w", user.Name, ErrHashNotSupported)
+	}
+
+	if len(fields) > minFields && fields[2] != "" {
+		const base, bitSize = 10, 32
+		maxConnections, err := strconv.ParseUint(fields[2], base, bitSize)
+		if err != nil {
+			return user, fmt.Errorf("user %s: %w: %s", user.Name, ErrMaxConnectionsValue, fields[2])
+		}
+		user.MaxConnections = uint(maxConnections)
+	}
+
+	if len(fields) == maxFields && fields[3] != "" {
+		user.Bandwidth, err = ratelimit.ParseRate(fields[3])
+		if err != nil {
+			return user, fmt.Errorf("user %s: %w: %s", user.Name, ErrBandwidthValue, fields[3])
+		}
+	}
+
+	return user, nil
+}
+
+func isBcrypt(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") ||
+		strings.HasPrefix(hash, "$2b$") ||
+		strings.HasPrefix(hash, "$2y$")
+}
+
+// DummyUser returns a user with a bcrypt password hash of the
+// highest bcrypt cost of the users given, or a `{SHA}` password hash
+// if no user has a bcrypt password hash. Verifying a password for an
+// unknown user name against it takes as long as for the users given,
+// so response times do not reveal which user names exist.
+func DummyUser(users map[string]User) (user User, err error) {
+	maxCost := 0
+	for _, existing := range users {
+		if !isBcrypt(existing.hash) {
+			continue
+		}
+		cost, err := bcrypt.Cost([]byte(existing.hash))
+		if err != nil {
+			return user, fmt.Errorf("user %s: bcrypt hash: %w", existing.Name, err)
+		}
+		if cost > maxCost {
+			maxCost = cost
+		}
+	}
+
+	if maxCost == 0 {
+		return User{hash: "{SHA}" + base64.StdEncoding.EncodeToString(make([]byte, sha1.Size))}, nil
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("dummy"), maxCost)
+	if err != nil {
+		return user, fmt.Errorf("generating bcrypt hash: %w", err)
+	}
+	return User{hash: string(hash)}, nil
+}
+
+// Verify returns true if the password given matches
+// the password hash of the user. Note bcrypt hashes
+// are slow to verify by design.
+func (u User) Verify(password string) bool {
+	if isBcrypt(u.hash) {
+		err := bcrypt.CompareHashAndPassword([]byte(u.hash), []byte(password))
+		return err == nil
+	}
+
+	sum := sha1.Sum([]byte(password)) //nolint:gosec
+	expected := "{SHA}" + base64.StdEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(u.hash)) == 1
+}
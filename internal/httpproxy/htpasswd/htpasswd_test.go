@@ -0,0 +1,122 @@
+package htpasswd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func Test_Parse(t *testing.T) {
+	t.Parallel()
+
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("alice-password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	// htpasswd -nbs bob bob-password
+	const shaHash = "{SHA}oHryCTyM4ObJvET53dSBiRe/fXQ="
+
+	data := "# comment\n" +
+		"alice:" + string(bcryptHash) + ":2:512k\n" +
+		"\n" +
+		"bob:" + shaHash + "\n" +
+		"carol:" + shaHash + "::1m\n"
+
+	users, err := Parse([]byte(data))
+	require.NoError(t, err)
+	require.Len(t, users, 3)
+
+	alice := users["alice"]
+	assert.Equal(t, uint(2), alice.MaxConnections)
+	assert.Equal(t, uint64(512*1024), alice.Bandwidth)
+	assert.True(t, alice.Verify("alice-password"))
+	assert.False(t, alice.Verify("bob-password"))
+
+	bob := users["bob"]
+	assert.Equal(t, uint(0), bob.MaxConnections)
+	assert.Equal(t, uint64(0), bob.Bandwidth)
+	assert.True(t, bob.Verify("bob-password"))
+	assert.False(t, bob.Verify("alice-password"))
+
+	carol := users["carol"]
+	assert.Equal(t, uint(0), carol.MaxConnections)
+	assert.Equal(t, uint64(1024*1024), carol.Bandwidth)
+}
+
+func Test_DummyUser(t *testing.T) {
+	t.Parallel()
+
+	const shaHash = "{SHA}oHryCTyM4ObJvET53dSBiRe/fXQ="
+	minCostHash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	higherCost := bcrypt.MinCost + 1
+	higherCostHash, err := bcrypt.GenerateFromPassword([]byte("password"), higherCost)
+	require.NoError(t, err)
+
+	user, err := DummyUser(map[string]User{
+		"alice": {Name: "alice", hash: string(minCostHash)},
+		"bob":   {Name: "bob", hash: string(higherCostHash)},
+		"carol": {Name: "carol", hash: shaHash},
+	})
+	require.NoError(t, err)
+	cost, err := bcrypt.Cost([]byte(user.hash))
+	require.NoError(t, err)
+	assert.Equal(t, higherCost, cost)
+	assert.False(t, user.Verify("password"))
+
+	user, err = DummyUser(map[string]User{
+		"carol": {Name: "carol", hash: shaHash},
+	})
+	require.NoError(t, err)
+	assert.False(t, isBcrypt(user.hash))
+	assert.False(t, user.Verify("password"))
+}
+
+func Test_Parse_errors(t *testing.T) {
+	t.Parallel()
+
+	const shaHash = "{SHA}oHryCTyM4ObJvET53dSBiRe/fXQ="
+	testCases := map[string]struct {
+		data       string
+		errWrapped error
+		errMessage string
+	}{
+		"malformed": {
+			data:       "alice",
+			errWrapped: ErrLineMalformed,
+			errMessage: "line 1: line is malformed",
+		},
+		"plaintext": {
+			data:       "alice:password",
+			errWrapped: ErrHashNotSupported,
+			errMessage: "line 1: user alice: password hash format is not supported",
+		},
+		"duplicate": {
+			data:       "alice:" + shaHash + "\nalice:" + shaHash,
+			errWrapped: ErrUserDuplicate,
+			errMessage: "line 2: user is defined more than once: alice",
+		},
+		"max_connections": {
+			data:       "alice:" + shaHash + ":x",
+			errWrapped: ErrMaxConnectionsValue,
+			errMessage: "line 1: user alice: maximum connections value is not valid: x",
+		},
+		"bandwidth": {
+			data:       "alice:" + shaHash + ":1:1t",
+			errWrapped: ErrBandwidthValue,
+			errMessage: "line 1: user alice: bandwidth value is not valid: 1t",
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Parse([]byte(testCase.data))
+
+			assert.ErrorIs(t, err, testCase.errWrapped)
+			assert.EqualError(t, err, testCase.errMessage)
+		})
+	}
+}
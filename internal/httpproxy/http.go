@@ -1,6 +1,7 @@
 package httpproxy
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -8,7 +9,8 @@ import (
 	"strings"
 )
 
-func (h *handler) handleHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+func (h *handler) handleHTTP(responseWriter http.ResponseWriter, request *http.Request,
+	exchange *exchange) {
 	switch request.URL.Scheme {
 	case "http", "https":
 	default:
@@ -18,6 +20,13 @@ func (h *handler) handleHTTP(responseWriter http.ResponseWriter, request *http.R
 	}
 
 	request = request.WithContext(h.ctx)
+	client := exchange.describe()
+	if request.Body != nil && request.Body != http.NoBody {
+		request.Body = &readCloser{
+			Reader: exchange.wrapSent(h.ctx, request.Body),
+			Closer: request.Body,
+		}
+	}
 
 	request.RequestURI = ""
 
@@ -30,14 +39,17 @@ func (h *handler) handleHTTP(responseWriter http.ResponseWriter, request *http.R
 	}
 
 	response, err := h.client.Do(request)
-	if err != nil {
+	if errors.Is(err, errDestinationNotAllowed) {
+		h.writeDialError(responseWriter, client, err)
+		return
+	} else if err != nil {
 		http.Error(responseWriter, "server error", http.StatusInternalServerError)
-		h.logger.Warn("cannot process request for client " + request.RemoteAddr + ": " + err.Error())
+		h.logger.Warn("cannot process request for client " + client + ": " + err.Error())
 		return
 	}
 	defer response.Body.Close()
 	if h.verbose {
-		h.logger.Info(request.RemoteAddr + " " + response.Status + " " +
+		h.logger.Info(client + " " + response.Status + " " +
 			request.Method + " " + request.URL.String())
 	}
 
@@ -53,8 +65,9 @@ func (h *handler) handleHTTP(responseWriter http.ResponseWriter, request *http.R
 	}
 
 	responseWriter.WriteHeader(response.StatusCode)
-	if _, err := io.Copy(responseWriter, response.Body); err != nil {
-		h.logger.Error(request.RemoteAddr + " " + request.URL.String() +
+	body := exchange.wrapReceived(h.ctx, response.Body)
+	if _, err := io.Copy(responseWriter, body); err != nil {
+		h.logger.Error(client + " " + request.URL.String() +
 			": body copy error: " + err.Error())
 	}
 }
@@ -70,3 +83,8 @@ func setForwardedHeaders(request *http.Request) {
 	}
 	request.Header.Set("X-Forwarded-For", clientIP)
 }
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
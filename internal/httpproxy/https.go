@@ -6,11 +6,12 @@ import (
 	"net/http"
 )
 
-func (h *handler) handleHTTPS(responseWriter http.ResponseWriter, request *http.Request) {
-	dialer := net.Dialer{}
-	destinationConn, err := dialer.DialContext(h.ctx, "tcp", request.Host)
+func (h *handler) handleHTTPS(responseWriter http.ResponseWriter, request *http.Request,
+	exchange *exchange) {
+	client := exchange.describe()
+	destinationConn, err := h.dial(h.ctx, "tcp", request.Host)
 	if err != nil {
-		http.Error(responseWriter, err.Error(), http.StatusServiceUnavailable)
+		h.writeDialError(responseWriter, client, err)
 		return
 	}
 
@@ -32,15 +33,27 @@ func (h *handler) handleHTTPS(responseWriter http.ResponseWriter, request *http.
 	}
 
 	if h.verbose {
-		h.logger.Info(request.RemoteAddr + " <-> " + request.Host)
+		h.logger.Info(client + " <-> " + request.Host)
 	}
 
+	h.tunnel(clientConnection, destinationConn, clientConnection, destinationConn, exchange)
+}
+
+// tunnel transfers data in both directions between the client and
+// destination connections until one of them is closed or the handler
+// context is canceled. The client and destination readers given read
+// from their respective connections, and may contain buffered data.
+func (h *handler) tunnel(clientConnection, destinationConn net.Conn,
+	clientReader, destinationReader io.Reader, exchange *exchange) {
 	h.wg.Add(1)
+	defer h.wg.Done()
 
 	serverToClientDone := make(chan struct{})
 	clientToServerClientDone := make(chan struct{})
-	go transfer(destinationConn, clientConnection, clientToServerClientDone)
-	go transfer(clientConnection, destinationConn, serverToClientDone)
+	go transfer(destinationConn, clientConnection,
+		exchange.wrapSent(h.ctx, clientReader), clientToServerClientDone)
+	go transfer(clientConnection, destinationConn,
+		exchange.wrapReceived(h.ctx, destinationReader), serverToClientDone)
 
 	select {
 	case <-h.ctx.Done():
@@ -53,12 +66,13 @@ func (h *handler) handleHTTPS(responseWriter http.ResponseWriter, request *http.
 	case <-clientToServerClientDone: // happens more rarely, when a connection is closed on the client side
 		<-serverToClientDone
 	}
-
-	h.wg.Done()
 }
 
-func transfer(destination io.WriteCloser, source io.ReadCloser, done chan<- struct{}) {
-	_, _ = io.Copy(destination, source)
+// transfer copies from the reader to the destination, where the reader
+// reads from the source, and closes both the source and destination.
+func transfer(destination io.WriteCloser, source io.Closer,
+	reader io.Reader, done chan<- struct{}) {
+	_, _ = io.Copy(destination, reader)
 	_ = source.Close()
 	_ = destination.Close()
 	close(done)
@@ -2,20 +2,31 @@ package httpproxy
 
 import (
 	"context"
+	"net/netip"
+	"sync"
 	"time"
 
+	"github.com/qdm12/gluetun/internal/accesslog"
 	"github.com/qdm12/gluetun/internal/configuration/settings"
 	"github.com/qdm12/gluetun/internal/constants"
 	"github.com/qdm12/gluetun/internal/httpproxy/state"
 	"github.com/qdm12/gluetun/internal/loopstate"
 	"github.com/qdm12/gluetun/internal/models"
+	"github.com/qdm12/gluetun/internal/ratelimit"
 )
 
 type Loop struct {
 	statusManager *loopstate.State
 	state         *state.State
 	// Other objects
-	logger Logger
+	logger   Logger
+	users    *users
+	recorder *accesslog.Recorder
+	shaper   *ratelimit.Shaper
+	// outboundSubnets are the subnets the proxy auto-config
+	// file instructs clients to reach directly.
+	outboundSubnetsMutex sync.RWMutex
+	outboundSubnets      []netip.Prefix
 	// Internal channels and locks
 	running       chan models.LoopStatus
 	stop, stopped chan struct{}
@@ -26,7 +37,9 @@ type Loop struct {
 
 const defaultBackoffTime = 10 * time.Second
 
-func NewLoop(logger Logger, settings settings.HTTPProxy) *Loop {
+func NewLoop(logger Logger, settings settings.HTTPProxy,
+	accessLog *accesslog.Logger, shaper *ratelimit.Shaper,
+	outboundSubnets []netip.Prefix) *Loop {
 	start := make(chan struct{})
 	running := make(chan models.LoopStatus)
 	stop := make(chan struct{})
@@ -37,15 +50,19 @@ func NewLoop(logger Logger, settings settings.HTTPProxy) *Loop {
 	state := state.New(statusManager, settings)
 
 	return &Loop{
-		statusManager: statusManager,
-		state:         state,
-		logger:        logger,
-		start:         start,
-		running:       running,
-		stop:          stop,
-		stopped:       stopped,
-		userTrigger:   true,
-		backoffTime:   defaultBackoffTime,
+		statusManager:   statusManager,
+		state:           state,
+		logger:          logger,
+		users:           newUsers(),
+		recorder:        accesslog.NewRecorder(accessLog),
+		shaper:          shaper,
+		outboundSubnets: append([]netip.Prefix(nil), outboundSubnets...),
+		start:           start,
+		running:         running,
+		stop:            stop,
+		stopped:         stopped,
+		userTrigger:     true,
+		backoffTime:     defaultBackoffTime,
 	}
 }
 
@@ -0,0 +1,159 @@
+package httpproxy
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+)
+
+// PAC returns the proxy auto-config file content for clients
+// reaching gluetun with the request host given. It is generated
+// from the current settings and outbound subnets.
+func (l *Loop) PAC(requestHost string) (content string) {
+	settings := l.state.GetSettings()
+	l.outboundSubnetsMutex.RLock()
+	outboundSubnets := l.outboundSubnets
+	l.outboundSubnetsMutex.RUnlock()
+	proxyAddress := pacProxyAddress(requestHost, settings.ListeningAddress)
+	return generatePAC(proxyAddress, settings.PACBypass, outboundSubnets)
+}
+
+// SetOutboundSubnets sets the outbound subnets which clients
+// are instructed to reach directly by the proxy auto-config file.
+func (l *Loop) SetOutboundSubnets(subnets []netip.Prefix) {
+	subnets = append([]netip.Prefix(nil), subnets...)
+	l.outboundSubnetsMutex.Lock()
+	l.outboundSubnets = subnets
+	l.outboundSubnetsMutex.Unlock()
+}
+
+// pacProxyAddress returns the proxy address to use in the proxy
+// auto-config file. Its host is the request host, since it is the
+// address the client reaches gluetun with, and its port is the
+// HTTP proxy listening port.
+func pacProxyAddress(requestHost, listeningAddress string) (address string) {
+	host, _, err := net.SplitHostPort(requestHost)
+	if err != nil { // no port in request host
+		host = strings.TrimSuffix(strings.TrimPrefix(requestHost, "["), "]")
+	}
+	_, port, err := net.SplitHostPort(listeningAddress)
+	if err != nil {
+		port = "8888"
+	}
+	return net.JoinHostPort(host, port)
+}
+
+func generatePAC(proxyAddress string, bypass []string,
+	directSubnets []netip.Prefix) (content string) {
+	lines := []string{
+		"function FindProxyForURL(url, host) {",
+		`  if (isPlainHostName(host)) {`,
+		`    return "DIRECT";`,
+		`  }`,
+	}
+
+	for _, domain := range bypass {
+		condition := `host == "` + domain + `"`
+		if strings.HasPrefix(domain, "*.") {
+			condition = `dnsDomainIs(host, "` + domain[1:] + `")`
+		}
+		lines = append(lines,
+			"  if ("+condition+") {",
+			`    return "DIRECT";`,
+			"  }")
+	}
+
+	if len(directSubnets) > 0 {
+		lines = append(lines, "  var ip = dnsResolve(host);")
+	}
+	for _, subnet := range directSubnets {
+		subnet = subnet.Masked()
+		var condition string
+		if subnet.Addr().Is4() {
+			condition = `isInNet(ip, "` + subnet.Addr().String() + `", "` +
+				prefixToMask(subnet) + `")`
+		} else {
+			// isInNetEx supports IPv6 but is only implemented
+			// by some browsers, such as Chromium based ones.
+			condition = `typeof isInNetEx == "function" && isInNetEx(ip, "` +
+				subnet.String() + `")`
+		}
+		lines = append(lines,
+			"  if (ip && "+condition+") {",
+			`    return "DIRECT";`,
+			"  }")
+	}
+
+	lines = append(lines,
+		`  return "PROXY `+proxyAddress+`";`,
+		"}",
+		"")
+	return strings.Join(lines, "\n")
+}
+
+// prefixToMask returns the dotted decimal mask of the IPv4 prefix given.
+func prefixToMask(prefix netip.Prefix) (mask string) {
+	const bits = 32
+	return net.IP(net.CIDRMask(prefix.Bits(), bits)).String()
+}
+
+const (
+	pacPath  = "/proxy.pac"
+	wpadPath = "/wpad.dat"
+)
+
+// pacServer serves the proxy auto-config file to requests
+// sent directly to the HTTP proxy listening address.
+type pacServer struct {
+	paths    []string
+	generate func(requestHost string) (content string)
+}
+
+// newPACServer returns a PAC server for the settings given, or nil
+// if the proxy auto-config file is disabled.
+func newPACServer(settings settings.HTTPProxy,
+	generate func(requestHost string) (content string)) *pacServer {
+	if !*settings.PAC {
+		return nil
+	}
+	paths := []string{pacPath}
+	if *settings.WPAD {
+		paths = append(paths, wpadPath)
+	}
+	return &pacServer{
+		paths:    paths,
+		generate: generate,
+	}
+}
+
+// matches returns true if the request is for the proxy auto-config
+// file, and not a request to proxy. It is safe to call on a nil receiver.
+func (p *pacServer) matches(request *http.Request) bool {
+	if p == nil || request.URL.Host != "" || request.Method == http.MethodConnect {
+		return false
+	}
+	for _, path := range p.paths {
+		if request.URL.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *pacServer) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet && request.Method != http.MethodHead {
+		responseWriter.Header().Set("Allow", http.MethodGet+", "+http.MethodHead)
+		http.Error(responseWriter, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	responseWriter.Header().Set("Content-Type", "application/x-ns-proxy-autoconfig")
+	responseWriter.Header().Set("Cache-Control", "no-cache")
+	if request.Method == http.MethodHead {
+		responseWriter.WriteHeader(http.StatusOK)
+		return
+	}
+	_, _ = responseWriter.Write([]byte(p.generate(request.Host)))
+}
@@ -0,0 +1,161 @@
+package httpproxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync"
+	"testing"
+
+	"github.com/qdm12/gluetun/internal/accesslog"
+	"github.com/qdm12/gluetun/internal/httpproxy/acl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_pacProxyAddress(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		requestHost      string
+		listeningAddress string
+		address          string
+	}{
+		"host_with_port": {
+			requestHost:      "192.168.1.2:8000",
+			listeningAddress: ":8888",
+			address:          "192.168.1.2:8888",
+		},
+		"host_without_port": {
+			requestHost:      "gluetun.lan",
+			listeningAddress: "0.0.0.0:3128",
+			address:          "gluetun.lan:3128",
+		},
+		"ipv6_host": {
+			requestHost:      "[fd00::2]:8000",
+			listeningAddress: ":8888",
+			address:          "[fd00::2]:8888",
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			address := pacProxyAddress(testCase.requestHost, testCase.listeningAddress)
+
+			assert.Equal(t, testCase.address, address)
+		})
+	}
+}
+
+func Test_generatePAC(t *testing.T) {
+	t.Parallel()
+
+	bypass := []string{"example.com", "*.lan"}
+	directSubnets := []netip.Prefix{
+		netip.MustParsePrefix("192.168.1.0/24"),
+		netip.MustParsePrefix("fd00::/8"),
+	}
+
+	content := generatePAC("192.168.1.2:8888", bypass, directSubnets)
+
+	const expected = `function FindProxyForURL(url, host) {
+  if (isPlainHostName(host)) {
+    return "DIRECT";
+  }
+  if (host == "example.com") {
+    return "DIRECT";
+  }
+  if (dnsDomainIs(host, ".lan")) {
+    return "DIRECT";
+  }
+  var ip = dnsResolve(host);
+  if (ip && isInNet(ip, "192.168.1.0", "255.255.255.0")) {
+    return "DIRECT";
+  }
+  if (ip && typeof isInNetEx == "function" && isInNetEx(ip, "fd00::/8")) {
+    return "DIRECT";
+  }
+  return "PROXY 192.168.1.2:8888";
+}
+`
+	assert.Equal(t, expected, content)
+}
+
+func Test_handler_pac(t *testing.T) {
+	t.Parallel()
+
+	accessControlList, err := acl.New(nil, nil, nil, true)
+	require.NoError(t, err)
+	// Credentials are not required to fetch the proxy auto-config file.
+	credentials, err := newCredentials("user", "password", "")
+	require.NoError(t, err)
+	pac := &pacServer{
+		paths: []string{pacPath},
+		generate: func(requestHost string) string {
+			return generatePAC(pacProxyAddress(requestHost, ":8888"), nil, nil)
+		},
+	}
+	handler := newHandler(context.Background(), &sync.WaitGroup{}, noopLogger{},
+		true, false, credentials, accessControlList, newUsers(),
+		accesslog.NewRecorder(nil), nil, pac)
+
+	testCases := map[string]struct {
+		method     string
+		target     string
+		statusCode int
+		body       string
+	}{
+		"pac": {
+			method:     http.MethodGet,
+			target:     "/proxy.pac",
+			statusCode: http.StatusOK,
+			body: `function FindProxyForURL(url, host) {
+  if (isPlainHostName(host)) {
+    return "DIRECT";
+  }
+  return "PROXY gluetun.lan:8888";
+}
+`,
+		},
+		"wpad_disabled": {
+			method:     http.MethodGet,
+			target:     "/wpad.dat",
+			statusCode: http.StatusBadRequest,
+		},
+		"proxied_request": {
+			method:     http.MethodGet,
+			target:     "http://example.com/proxy.pac",
+			statusCode: http.StatusProxyAuthRequired,
+		},
+		"method_not_allowed": {
+			method:     http.MethodPost,
+			target:     "/proxy.pac",
+			statusCode: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			request := httptest.NewRequest(testCase.method, testCase.target, nil)
+			request.Host = "gluetun.lan:8888"
+			request.RemoteAddr = "192.168.1.5:5000"
+			response := httptest.NewRecorder()
+
+			handler.ServeHTTP(response, request)
+
+			assert.Equal(t, testCase.statusCode, response.Code)
+			if testCase.body != "" {
+				assert.Equal(t, testCase.body, response.Body.String())
+				assert.Equal(t, "application/x-ns-proxy-autoconfig",
+					response.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
@@ -21,9 +21,25 @@ func (l *Loop) Run(ctx context.Context, done chan<- struct{}) {
 		runCtx, runCancel := context.WithCancel(ctx)
 
 		settings := l.state.GetSettings()
+		accessControlList, err := newACL(settings)
+		if err != nil {
+			runCancel()
+			l.statusManager.SetStatus(constants.Crashed)
+			l.logAndWait(ctx, err)
+			continue
+		}
+		credentials, err := newCredentials(*settings.User,
+			*settings.Password, *settings.CredentialsFile)
+		if err != nil {
+			runCancel()
+			l.statusManager.SetStatus(constants.Crashed)
+			l.logAndWait(ctx, err)
+			continue
+		}
 		server := New(runCtx, settings.ListeningAddress, l.logger,
-			*settings.Stealth, *settings.Log, *settings.User,
-			*settings.Password, settings.ReadHeaderTimeout, settings.ReadTimeout)
+			*settings.Stealth, *settings.Log, credentials,
+			accessControlList, l.users, l.recorder, l.shaper,
+			newPACServer(settings, l.PAC), settings.ReadHeaderTimeout, settings.ReadTimeout)
 
 		errorCh := make(chan error)
 		go server.Run(runCtx, errorCh)
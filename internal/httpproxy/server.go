@@ -5,11 +5,16 @@ import (
 	"net/http"
 	"sync"
 	"time"
+
+	"github.com/qdm12/gluetun/internal/accesslog"
+	"github.com/qdm12/gluetun/internal/httpproxy/acl"
+	"github.com/qdm12/gluetun/internal/ratelimit"
 )
 
 type Server struct {
 	address           string
 	handler           http.Handler
+	credentials       *credentials
 	logger            infoErrorer
 	internalWG        *sync.WaitGroup
 	readHeaderTimeout time.Duration
@@ -17,12 +22,16 @@ type Server struct {
 }
 
 func New(ctx context.Context, address string, logger Logger,
-	stealth, verbose bool, username, password string,
-	readHeaderTimeout, readTimeout time.Duration) *Server {
+	stealth, verbose bool, credentials *credentials, acl *acl.ACL,
+	users *users, recorder *accesslog.Recorder, shaper *ratelimit.Shaper,
+	pac *pacServer, readHeaderTimeout, readTimeout time.Duration) *Server {
 	wg := &sync.WaitGroup{}
+	handler := newHandler(ctx, wg, logger, stealth, verbose,
+		credentials, acl, users, recorder, shaper, pac)
 	return &Server{
 		address:           address,
-		handler:           newHandler(ctx, wg, logger, stealth, verbose, username, password),
+		handler:           handler,
+		credentials:       credentials,
 		logger:            logger,
 		internalWG:        wg,
 		readHeaderTimeout: readHeaderTimeout,
@@ -46,9 +55,19 @@ func (s *Server) Run(ctx context.Context, errorCh chan<- error) {
 			s.logger.Error("failed shutting down: " + err.Error())
 		}
 	}()
+	credentialsWatchDone := make(chan struct{})
+	go func() {
+		defer close(credentialsWatchDone)
+		if s.credentials.path == "" {
+			return
+		}
+		const credentialsWatchPeriod = 5 * time.Second
+		s.credentials.watch(ctx, s.logger, credentialsWatchPeriod)
+	}()
 	s.logger.Info("listening on " + s.address)
 	err := server.ListenAndServe()
 	s.internalWG.Wait()
+	<-credentialsWatchDone
 	if err != nil && ctx.Err() == nil {
 		errorCh <- err
 	} else {
@@ -0,0 +1,168 @@
+package httpproxy
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// isUpgradeRequest returns true if the request asks to switch
+// protocols, for example for WebSocket or h2c.
+func isUpgradeRequest(request *http.Request) bool {
+	if request.Header.Get("Upgrade") == "" {
+		return false
+	}
+	for _, value := range request.Header.Values("Connection") {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// handleUpgrade forwards an upgrade request to the destination and,
+// if the destination switches protocols, hijacks the client connection
+// to transfer data in both directions, as done for CONNECT requests.
+func (h *handler) handleUpgrade(responseWriter http.ResponseWriter, request *http.Request,
+	exchange *exchange) {
+	client := exchange.describe()
+	var defaultPort string
+	switch request.URL.Scheme {
+	case "http":
+		defaultPort = "80"
+	case "https":
+		defaultPort = "443"
+	default:
+		h.logger.Warn("Unsupported scheme " + request.URL.Scheme)
+		http.Error(responseWriter, "unsupported scheme", http.StatusBadRequest)
+		return
+	}
+
+	address := request.URL.Host
+	if request.URL.Port() == "" {
+		address = net.JoinHostPort(request.URL.Hostname(), defaultPort)
+	}
+	destinationConn, err := h.dial(h.ctx, "tcp", address)
+	if err != nil {
+		h.writeDialError(responseWriter, client, err)
+		return
+	}
+	if request.URL.Scheme == "https" {
+		destinationConn = tls.Client(destinationConn, &tls.Config{
+			ServerName: request.URL.Hostname(),
+			MinVersion: tls.VersionTLS12,
+		})
+	}
+
+	upgrade := request.Header.Get("Upgrade")
+	for _, key := range hopHeaders {
+		request.Header.Del(key)
+	}
+	request.Header.Set("Connection", "Upgrade")
+	request.Header.Set("Upgrade", upgrade)
+	if !h.stealth {
+		setForwardedHeaders(request)
+	}
+	if request.Body != nil && request.Body != http.NoBody {
+		request.Body = &readCloser{
+			Reader: exchange.wrapSent(h.ctx, request.Body),
+			Closer: request.Body,
+		}
+	}
+
+	err = request.Write(destinationConn)
+	if err != nil {
+		_ = destinationConn.Close()
+		http.Error(responseWriter, "server error", http.StatusInternalServerError)
+		h.logger.Warn("cannot process request for client " + client + ": " + err.Error())
+		return
+	}
+
+	destinationReader := bufio.NewReader(destinationConn)
+	response, err := http.ReadResponse(destinationReader, request)
+	if err != nil {
+		_ = destinationConn.Close()
+		http.Error(responseWriter, "server error", http.StatusInternalServerError)
+		h.logger.Warn("cannot process request for client " + client + ": " + err.Error())
+		return
+	}
+	if h.verbose {
+		h.logger.Info(client + " " + response.Status + " " +
+			request.Method + " " + request.URL.String())
+	}
+
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		h.writeUpgradeRefused(responseWriter, request, response, client, exchange)
+		_ = destinationConn.Close()
+		return
+	}
+
+	hijacker, ok := responseWriter.(http.Hijacker)
+	if !ok {
+		_ = destinationConn.Close()
+		http.Error(responseWriter, "Hijacking not supported", http.StatusInternalServerError)
+		return
+	}
+	clientConnection, clientReadWriter, err := hijacker.Hijack()
+	if err != nil {
+		_ = destinationConn.Close()
+		h.logger.Warn(err.Error())
+		http.Error(responseWriter, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	exchange.status = response.StatusCode
+	// Write the response header ourselves since the response
+	// Write method would add headers not valid for a 101 status.
+	_, err = fmt.Fprintf(clientReadWriter, "HTTP/1.1 %s\r\n", response.Status)
+	if err == nil {
+		err = response.Header.Write(clientReadWriter)
+	}
+	if err == nil {
+		_, err = clientReadWriter.WriteString("\r\n")
+	}
+	if err == nil {
+		err = clientReadWriter.Flush()
+	}
+	if err != nil {
+		h.logger.Warn("cannot write upgrade response to client " + client + ": " + err.Error())
+		_ = clientConnection.Close()
+		_ = destinationConn.Close()
+		return
+	}
+
+	if h.verbose {
+		h.logger.Info(client + " <-> " + request.URL.Host + " (" + upgrade + ")")
+	}
+
+	h.tunnel(clientConnection, destinationConn,
+		clientReadWriter.Reader, destinationReader, exchange)
+}
+
+// writeUpgradeRefused writes the response of a destination which
+// did not switch protocols back to the client.
+func (h *handler) writeUpgradeRefused(responseWriter http.ResponseWriter,
+	request *http.Request, response *http.Response, client string, exchange *exchange) {
+	defer response.Body.Close()
+	for _, key := range hopHeaders {
+		response.Header.Del(key)
+	}
+	targetHeaderPtr := responseWriter.Header()
+	for key, values := range response.Header {
+		for _, value := range values {
+			targetHeaderPtr.Add(key, value)
+		}
+	}
+	responseWriter.WriteHeader(response.StatusCode)
+	body := exchange.wrapReceived(h.ctx, response.Body)
+	if _, err := io.Copy(responseWriter, body); err != nil {
+		h.logger.Error(client + " " + request.URL.String() +
+			": body copy error: " + err.Error())
+	}
+}
@@ -0,0 +1,171 @@
+package httpproxy
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1" //nolint:gosec
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/accesslog"
+	"github.com/qdm12/gluetun/internal/httpproxy/acl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_isUpgradeRequest(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		header  http.Header
+		upgrade bool
+	}{
+		"no_header": {
+			header: http.Header{},
+		},
+		"upgrade_without_connection": {
+			header: http.Header{"Upgrade": []string{"websocket"}},
+		},
+		"connection_without_upgrade": {
+			header: http.Header{"Connection": []string{"Upgrade"}},
+		},
+		"websocket": {
+			header: http.Header{
+				"Connection": []string{"keep-alive, Upgrade"},
+				"Upgrade":    []string{"websocket"},
+			},
+			upgrade: true,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			request := &http.Request{Header: testCase.header}
+
+			upgrade := isUpgradeRequest(request)
+
+			assert.Equal(t, testCase.upgrade, upgrade)
+		})
+	}
+}
+
+// websocketAccept returns the Sec-WebSocket-Accept value for the key given.
+func websocketAccept(key string) string {
+	const guid = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	digest := sha1.Sum([]byte(key + guid)) //nolint:gosec
+	return base64.StdEncoding.EncodeToString(digest[:])
+}
+
+// newWebsocketEchoServer returns a server switching to the WebSocket
+// protocol and echoing back the data received, without parsing frames.
+func newWebsocketEchoServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "websocket" {
+			http.Error(w, "websocket upgrade expected", http.StatusBadRequest)
+			return
+		}
+		connection, readWriter, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer connection.Close()
+		_, _ = readWriter.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+			"Connection: Upgrade\r\n" +
+			"Upgrade: websocket\r\n" +
+			"Sec-WebSocket-Accept: " + websocketAccept(r.Header.Get("Sec-WebSocket-Key")) + "\r\n" +
+			"\r\n")
+		_ = readWriter.Flush()
+		_, _ = io.Copy(connection, readWriter)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func Test_handler_upgrade(t *testing.T) {
+	t.Parallel()
+
+	destination := newWebsocketEchoServer(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	wg := &sync.WaitGroup{}
+	accessControlList, err := acl.New(nil, nil, nil, true)
+	require.NoError(t, err)
+	credentials, err := newCredentials("", "", "")
+	require.NoError(t, err)
+	proxy := httptest.NewServer(newHandler(ctx, wg, noopLogger{},
+		true, false, credentials, accessControlList, newUsers(), accesslog.NewRecorder(nil), nil, nil))
+	t.Cleanup(proxy.Close)
+
+	t.Run("not_upgraded", func(t *testing.T) {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, destination.URL, nil)
+		require.NoError(t, err)
+		request.Header.Set("Connection", "Upgrade")
+		request.Header.Set("Upgrade", "h2c")
+		proxyURL, err := url.Parse(proxy.URL)
+		require.NoError(t, err)
+		client := &http.Client{Transport: &http.Transport{
+			Proxy: http.ProxyURL(proxyURL),
+		}}
+
+		response, err := client.Do(request)
+		require.NoError(t, err)
+		_ = response.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	})
+
+	connection, err := net.Dial("tcp", proxy.Listener.Addr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = connection.Close() })
+
+	const key = "dGhlIHNhbXBsZSBub25jZQ=="
+	_, err = io.WriteString(connection, "GET "+destination.URL+"/echo HTTP/1.1\r\n"+
+		"Host: "+destination.Listener.Addr().String()+"\r\n"+
+		"Connection: Upgrade\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Sec-WebSocket-Version: 13\r\n"+
+		"Sec-WebSocket-Key: "+key+"\r\n"+
+		"\r\n")
+	require.NoError(t, err)
+
+	reader := bufio.NewReader(connection)
+	response, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, response.StatusCode)
+	assert.Equal(t, "websocket", response.Header.Get("Upgrade"))
+	assert.Equal(t, websocketAccept(key), response.Header.Get("Sec-WebSocket-Accept"))
+
+	for _, message := range []string{"hello", "world"} {
+		_, err = io.WriteString(connection, message)
+		require.NoError(t, err)
+		echoed := make([]byte, len(message))
+		_, err = io.ReadFull(reader, echoed)
+		require.NoError(t, err)
+		assert.Equal(t, message, string(echoed))
+	}
+
+	// Canceling the handler context closes the tunnel.
+	cancel()
+	tunnelsDone := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(tunnelsDone)
+	}()
+	select {
+	case <-tunnelsDone:
+	case <-time.After(time.Second):
+		t.Fatal("tunnel not closed after context cancellation")
+	}
+}
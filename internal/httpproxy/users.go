@@ -0,0 +1,168 @@
+package httpproxy
+
+import (
+	"context"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/qdm12/gluetun/internal/httpproxy/htpasswd"
+	"github.com/qdm12/gluetun/internal/ratelimit"
+)
+
+// UserStats is the usage of the HTTP proxy by an authenticated user.
+type UserStats struct {
+	Name string `json:"name"`
+	// ActiveConnections is the number of requests
+	// and tunnels currently proxied for the user.
+	ActiveConnections uint `json:"active_connections"`
+	// Requests is the number of requests proxied for the user.
+	Requests uint64 `json:"requests"`
+	// Rejected is the number of requests rejected
+	// because of the user concurrency limit.
+	Rejected uint64 `json:"rejected"`
+	// BytesSent is the number of bytes sent to destinations.
+	BytesSent uint64 `json:"bytes_sent"`
+	// BytesReceived is the number of bytes received from destinations.
+	BytesReceived uint64 `json:"bytes_received"`
+}
+
+// GetUserStats returns the usage statistics of the
+// authenticated users of the HTTP proxy.
+func (l *Loop) GetUserStats() (stats []UserStats) {
+	return l.users.stats()
+}
+
+// users tracks the usage of the HTTP proxy per authenticated user,
+// and enforces their limits. It is kept across server restarts.
+type users struct {
+	mutex  sync.Mutex
+	usages map[string]*userUsage
+}
+
+func newUsers() *users {
+	return &users{
+		usages: make(map[string]*userUsage),
+	}
+}
+
+type userUsage struct {
+	name          string
+	active        uint
+	requests      uint64
+	rejected      uint64
+	bytesSent     atomic.Uint64
+	bytesReceived atomic.Uint64
+	// limiter is shared by all the connections of the user,
+	// and has a rate of 0 if the user has no bandwidth limit.
+	limiter *ratelimit.Limiter
+}
+
+// acquire registers a new connection for the user given, and returns
+// false if the user reached its maximum number of connections. It
+// returns a nil usage if the user is anonymous.
+func (u *users) acquire(user htpasswd.User) (usage *userUsage, ok bool) {
+	if user.Name == "" {
+		return nil, true
+	}
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	usage, exists := u.usages[user.Name]
+	if !exists {
+		usage = &userUsage{
+			name:    user.Name,
+			limiter: ratelimit.New(user.Bandwidth),
+		}
+		u.usages[user.Name] = usage
+	} else if usage.limiter.Rate() != user.Bandwidth {
+		// the user limit changed in the credentials file
+		usage.limiter.SetRate(user.Bandwidth)
+	}
+
+	if user.MaxConnections > 0 && usage.active >= user.MaxConnections {
+		usage.rejected++
+		return usage, false
+	}
+	usage.active++
+	usage.requests++
+	return usage, true
+}
+
+// release unregisters a connection acquired for the usage given.
+func (u *users) release(usage *userUsage) {
+	if usage == nil {
+		return
+	}
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	usage.active--
+}
+
+// stats returns the usage statistics of all users sorted by name.
+func (u *users) stats() (stats []UserStats) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	stats = make([]UserStats, 0, len(u.usages))
+	for _, usage := range u.usages {
+		stats = append(stats, UserStats{
+			Name:              usage.name,
+			ActiveConnections: usage.active,
+			Requests:          usage.requests,
+			Rejected:          usage.rejected,
+			BytesSent:         usage.bytesSent.Load(),
+			BytesReceived:     usage.bytesReceived.Load(),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
+
+// describe returns the remote address given, followed by
+// the user name if the user is authenticated, for logging.
+func (u *userUsage) describe(remoteAddr string) string {
+	if u == nil {
+		return remoteAddr
+	}
+	return remoteAddr + " (user " + u.name + ")"
+}
+
+// wrapSent returns a reader counting and limiting the bytes
+// read as bytes sent by the user.
+func (u *userUsage) wrapSent(ctx context.Context, reader io.Reader) io.Reader {
+	if u == nil {
+		return reader
+	}
+	return &countingReader{
+		reader:  ratelimit.NewReader(ctx, reader, u.limiter),
+		counter: &u.bytesSent,
+	}
+}
+
+// wrapReceived returns a reader counting and limiting the bytes
+// read as bytes received by the user.
+func (u *userUsage) wrapReceived(ctx context.Context, reader io.Reader) io.Reader {
+	if u == nil {
+		return reader
+	}
+	return &countingReader{
+		reader:  ratelimit.NewReader(ctx, reader, u.limiter),
+		counter: &u.bytesReceived,
+	}
+}
+
+type countingReader struct {
+	reader  io.Reader
+	counter *atomic.Uint64
+}
+
+func (r *countingReader) Read(b []byte) (n int, err error) {
+	n, err = r.reader.Read(b)
+	r.counter.Add(uint64(n))
+	return n, err
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"net/netip"
+	"time"
+)
+
+// ExitCheck is the result of checking the public IP address
+// information against the allowed exit locations.
+type ExitCheck struct {
+	Time         time.Time  `json:"time"`
+	IP           netip.Addr `json:"public_ip"`
+	Country      string     `json:"country"`
+	Organization string     `json:"organization"`
+	Allowed      bool       `json:"allowed"`
+	// Reason is the reason the exit location is not allowed,
+	// and is empty if the exit location is allowed.
+	Reason string `json:"reason,omitempty"`
+	// Action is the action taken if the exit location is not allowed.
+	Action string `json:"action,omitempty"`
+}
@@ -14,6 +14,9 @@ type PublicIP struct {
 	Organization string     `json:"organization,omitempty"`
 	PostalCode   string     `json:"postal_code,omitempty"`
 	Timezone     string     `json:"timezone,omitempty"`
+	// IPv6 contains the public IPv6 address information,
+	// and is nil if it is not available.
+	IPv6 *PublicIP `json:"ipv6,omitempty"`
 }
 
 func (p *PublicIP) Copy() (publicIPCopy PublicIP) {
@@ -28,5 +31,9 @@ func (p *PublicIP) Copy() (publicIPCopy PublicIP) {
 		PostalCode:   p.PostalCode,
 		Timezone:     p.Timezone,
 	}
+	if p.IPv6 != nil {
+		ipv6Copy := p.IPv6.Copy()
+		publicIPCopy.IPv6 = &ipv6Copy
+	}
 	return publicIPCopy
 }
@@ -6,6 +6,7 @@ import (
 	"net/netip"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/qdm12/gluetun/internal/constants/vpn"
 )
@@ -33,6 +34,11 @@ type Server struct {
 	PortForward bool         `json:"port_forward,omitempty"`
 	Keep        bool         `json:"keep,omitempty"`
 	IPs         []netip.Addr `json:"ips,omitempty"`
+	// Reachable is set by the updater probing servers, and is
+	// nil if the server was not probed or the probe was inconclusive.
+	Reachable *bool `json:"reachable,omitempty"`
+	// RTT is the round trip time measured probing the server.
+	RTT time.Duration `json:"rtt,omitempty"`
 }
 
 var (
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"net/netip"
+)
+
+// TunnelStatus contains the settings and status of
+// an additional named VPN tunnel.
+type TunnelStatus struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Interface string `json:"interface"`
+	Provider  string `json:"provider"`
+	// Endpoint is the address of the VPN server the tunnel is
+	// connected to, and is empty if the tunnel is not connected.
+	Endpoint netip.AddrPort `json:"endpoint"`
+	// Hostname is the hostname of the VPN server the tunnel is
+	// connected to, and is empty if it is unknown.
+	Hostname     string         `json:"hostname,omitempty"`
+	Sources      []netip.Prefix `json:"sources"`
+	Destinations []netip.Prefix `json:"destinations"`
+	Status       LoopStatus     `json:"status"`
+	// Error is the last error encountered by the tunnel,
+	// and is empty if the tunnel did not crash.
+	Error string `json:"error,omitempty"`
+}
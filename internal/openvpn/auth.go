@@ -3,6 +3,7 @@ package openvpn
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -38,6 +39,10 @@ func writeIfDifferent(path, content string, puid, pgid int) (err error) {
 	}
 
 	if writeData {
+		err = os.MkdirAll(filepath.Dir(path), os.ModePerm)
+		if err != nil {
+			return fmt.Errorf("creating directory: %w", err)
+		}
 		err = os.WriteFile(path, []byte(content), perm)
 		if err != nil {
 			return fmt.Errorf("writing file: %w", err)
@@ -0,0 +1,163 @@
+package openvpn
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Challenge is an authentication challenge sent by the OpenVPN
+// server, which can be static (static-challenge option) or
+// dynamic (CRV1 challenge after a first authentication).
+type Challenge struct {
+	// Text is the challenge text to display to the user.
+	Text string `json:"text"`
+	// Echo is true if the response can be echoed back.
+	Echo bool `json:"echo"`
+	// Dynamic is true for a CRV1 dynamic challenge.
+	Dynamic bool `json:"dynamic"`
+	// stateID is the opaque state ID of a dynamic challenge.
+	stateID string
+	// username is the username given by a dynamic challenge.
+	username string
+}
+
+var (
+	ErrChallengeMalformed   = errors.New("challenge is malformed")
+	ErrNoChallengePending   = errors.New("no challenge is pending")
+	ErrChallengeNotAnswered = errors.New("challenge was not answered")
+)
+
+// parseStaticChallenge parses the static challenge from a management
+// password request such as "Need 'Auth' username/password SC:1,Enter code".
+// It returns ok as false if the request contains no static challenge.
+func parseStaticChallenge(request string) (challenge Challenge, ok bool, err error) {
+	const prefix = " SC:"
+	i := strings.Index(request, prefix)
+	if i == -1 {
+		return challenge, false, nil
+	}
+
+	flags, text, found := strings.Cut(request[i+len(prefix):], ",")
+	if !found {
+		return challenge, false, fmt.Errorf("%w: %s", ErrChallengeMalformed, request)
+	}
+
+	// OpenVPN 2.6 may set additional bits, the first one being echo.
+	const echoBit = 1
+	echo := len(flags) > 0 && (flags[len(flags)-1]-'0')&echoBit == echoBit
+
+	return Challenge{
+		Text: text,
+		Echo: echo,
+	}, true, nil
+}
+
+// parseDynamicChallenge parses a CRV1 dynamic challenge with
+// the format "CRV1:<flags>:<state_id>:<username_base64>:<text>".
+func parseDynamicChallenge(s string) (challenge Challenge, err error) {
+	const expectedFields = 5
+	fields := strings.SplitN(s, ":", expectedFields)
+	if len(fields) != expectedFields || fields[0] != "CRV1" {
+		return challenge, fmt.Errorf("%w: %s", ErrChallengeMalformed, s)
+	}
+
+	username, err := base64.StdEncoding.DecodeString(fields[3])
+	if err != nil {
+		return challenge, fmt.Errorf("decoding username: %w", err)
+	}
+
+	flags := strings.Split(fields[1], ",")
+	echo := false
+	for _, flag := range flags {
+		if flag == "E" {
+			echo = true
+		}
+	}
+
+	return Challenge{
+		Text:     fields[4],
+		Echo:     echo,
+		Dynamic:  true,
+		stateID:  fields[2],
+		username: string(username),
+	}, nil
+}
+
+// staticChallengePassword returns the password to send to answer
+// a static challenge.
+func staticChallengePassword(password, response string) string {
+	return "SCRV1:" + base64.StdEncoding.EncodeToString([]byte(password)) +
+		":" + base64.StdEncoding.EncodeToString([]byte(response))
+}
+
+// dynamicChallengePassword returns the password to send to answer
+// a dynamic challenge.
+func dynamicChallengePassword(challenge Challenge, response string) string {
+	return "CRV1::" + challenge.stateID + "::" + response
+}
+
+// Challenges holds the authentication challenge pending a response
+// from the user, which can be answered through the control server.
+type Challenges struct {
+	mutex     sync.RWMutex
+	pending   *Challenge
+	responses chan string
+}
+
+func NewChallenges() *Challenges {
+	return &Challenges{
+		responses: make(chan string),
+	}
+}
+
+// Pending returns the challenge pending a response, if any.
+func (c *Challenges) Pending() (challenge Challenge, ok bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	if c.pending == nil {
+		return challenge, false
+	}
+	return *c.pending, true
+}
+
+// Answer answers the pending challenge with the response given.
+// It returns an error if no challenge is pending.
+func (c *Challenges) Answer(response string) (err error) {
+	c.mutex.Lock()
+	if c.pending == nil {
+		c.mutex.Unlock()
+		return fmt.Errorf("%w", ErrNoChallengePending)
+	}
+	c.pending = nil
+	c.mutex.Unlock()
+
+	c.responses <- response
+	return nil
+}
+
+// wait sets the challenge given as pending and blocks until
+// it is answered or the context is canceled.
+func (c *Challenges) wait(ctx context.Context, challenge Challenge) (
+	response string, err error) {
+	c.mutex.Lock()
+	c.pending = &challenge
+	c.mutex.Unlock()
+
+	select {
+	case <-ctx.Done():
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		if c.pending != nil {
+			c.pending = nil
+			return "", fmt.Errorf("%w: %w", ErrChallengeNotAnswered, ctx.Err())
+		}
+		// Answer is sending the response concurrently
+		return <-c.responses, nil
+	case response = <-c.responses:
+		return response, nil
+	}
+}
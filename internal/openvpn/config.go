@@ -2,10 +2,19 @@ package openvpn
 
 import (
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+
+	"github.com/qdm12/gluetun/internal/constants/openvpn"
 )
 
 func (c *Configurator) WriteConfig(lines []string) error {
+	err := os.MkdirAll(filepath.Dir(c.configPath), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
 	file, err := os.OpenFile(c.configPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
 	if err != nil {
 		return err
@@ -24,3 +33,37 @@ func (c *Configurator) WriteConfig(lines []string) error {
 
 	return file.Close()
 }
+
+// TunnelConfig adapts configuration lines generated for the main
+// VPN connection to the additional tunnel of the configurator.
+// File paths point to the tunnel files, routes set by the
+// configuration or pushed by the server are ignored, and the
+// tunnel encrypted packets are marked with the firewall mark given
+// so they are routed by the tunnel policy routing.
+func (c *Configurator) TunnelConfig(lines []string, firewallMark int) (tunnelLines []string) {
+	pathReplacer := strings.NewReplacer(
+		openvpn.AuthConf, c.authFilePath,
+		openvpn.AskPassPath, c.askPassPath,
+	)
+
+	tunnelLines = make([]string, 0, len(lines))
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) > 0 {
+			switch fields[0] {
+			case "redirect-gateway", "route", "route-ipv6":
+				continue
+			}
+		}
+		tunnelLines = append(tunnelLines, pathReplacer.Replace(line))
+	}
+
+	for len(tunnelLines) > 0 && tunnelLines[len(tunnelLines)-1] == "" {
+		tunnelLines = tunnelLines[:len(tunnelLines)-1]
+	}
+	return append(tunnelLines,
+		"route-nopull",
+		"mark "+strconv.Itoa(firewallMark),
+		"",
+	)
+}
@@ -0,0 +1,37 @@
+package openvpn
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Configurator_TunnelConfig(t *testing.T) {
+	t.Parallel()
+
+	configurator := New(nil, nil, 1000, 1000).ForTunnel("us")
+	assert.Equal(t, "/etc/openvpn/tunnels/us/target.ovpn", configurator.ConfigPath())
+
+	lines := []string{
+		"client",
+		"dev tun_us",
+		"auth-user-pass /etc/openvpn/auth.conf",
+		"redirect-gateway def1",
+		"route 10.0.0.0 255.0.0.0",
+		"askpass /etc/openvpn/askpass",
+		"",
+	}
+
+	tunnelLines := configurator.TunnelConfig(lines, 51821)
+
+	expected := []string{
+		"client",
+		"dev tun_us",
+		"auth-user-pass /etc/openvpn/tunnels/us/auth.conf",
+		"askpass /etc/openvpn/tunnels/us/askpass",
+		"route-nopull",
+		"mark 51821",
+		"",
+	}
+	assert.Equal(t, expected, tunnelLines)
+}
@@ -0,0 +1,196 @@
+package openvpn
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/openvpn/totp"
+)
+
+// managementFlags returns the OpenVPN flags to query
+// the username and password through the management interface,
+// such that authentication challenges can be answered.
+func managementFlags(userSet bool) (flags []string) {
+	flags = []string{
+		"--management", managementPath, "unix",
+		"--management-query-passwords",
+		"--auth-retry", "interact",
+	}
+	if userSet {
+		// Override the auth-user-pass file path from the configuration
+		// file, since OpenVPN would not query the management interface
+		// for credentials if they are read from a file.
+		flags = append(flags, "--auth-user-pass")
+	}
+	return flags
+}
+
+type challengeResponder func(ctx context.Context, challenge Challenge) (
+	response string, err error)
+
+// management answers username and password queries received
+// on the OpenVPN management interface, handling static and
+// dynamic authentication challenges.
+type management struct {
+	user     string
+	password string
+	respond  challengeResponder
+	logger   Logger
+	// dynamic is the dynamic challenge received on the last
+	// verification failure, to answer on the next password query.
+	dynamic *Challenge
+}
+
+func (r *Runner) respondChallenge(ctx context.Context, challenge Challenge) (
+	response string, err error) {
+	if *r.settings.TOTPSecret != "" {
+		r.logger.Info("answering authentication challenge using TOTP: " + challenge.Text)
+		return totp.Generate(*r.settings.TOTPSecret, time.Now())
+	}
+
+	r.logger.Info("waiting for authentication challenge response " +
+		"through the control server: " + challenge.Text)
+	return r.challenges.wait(ctx, challenge)
+}
+
+func (r *Runner) runManagement(ctx context.Context, done chan<- struct{}) {
+	defer close(done)
+
+	connection, err := dialManagement(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			r.logger.Error("connecting to management interface: " + err.Error())
+		}
+		return
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = connection.Close()
+	}()
+
+	m := &management{
+		user:     *r.settings.User,
+		password: *r.settings.Password,
+		respond:  r.respondChallenge,
+		logger:   r.logger,
+	}
+	err = m.run(ctx, connection)
+	if err != nil && ctx.Err() == nil {
+		r.logger.Error("management interface: " + err.Error())
+	}
+}
+
+func dialManagement(ctx context.Context) (connection net.Conn, err error) {
+	// The management socket is only created once OpenVPN has started.
+	const retryPeriod = 100 * time.Millisecond
+	dialer := net.Dialer{}
+	for {
+		connection, err = dialer.DialContext(ctx, "unix", managementPath)
+		if err == nil {
+			return connection, nil
+		}
+
+		timer := time.NewTimer(retryPeriod)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+	}
+}
+
+func (m *management) run(ctx context.Context, connection io.ReadWriter) (err error) {
+	scanner := bufio.NewScanner(connection)
+	for scanner.Scan() {
+		err = m.handleLine(ctx, connection, scanner.Text())
+		if err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+var ErrManagementCommandFailed = errors.New("management command failed")
+
+func (m *management) handleLine(ctx context.Context, w io.Writer,
+	line string) (err error) {
+	switch {
+	case strings.HasPrefix(line, "ERROR:"):
+		return fmt.Errorf("%w: %s", ErrManagementCommandFailed,
+			strings.TrimSpace(strings.TrimPrefix(line, "ERROR:")))
+	case !strings.HasPrefix(line, ">PASSWORD:"):
+		return nil
+	}
+
+	request := strings.TrimPrefix(line, ">PASSWORD:")
+	switch {
+	case strings.HasPrefix(request, "Verification Failed: 'Auth'"):
+		m.dynamic = nil
+		_, crv1, found := strings.Cut(request, "['")
+		if !found {
+			m.logger.Warn("authentication failed")
+			return nil
+		}
+		crv1 = strings.TrimSuffix(crv1, "']")
+		challenge, err := parseDynamicChallenge(crv1)
+		if err != nil {
+			return fmt.Errorf("parsing dynamic challenge: %w", err)
+		}
+		m.dynamic = &challenge
+		return nil
+	case strings.HasPrefix(request, "Need 'Auth' username/password"):
+		return m.answerAuth(ctx, w, request)
+	default:
+		return nil
+	}
+}
+
+func (m *management) answerAuth(ctx context.Context, w io.Writer,
+	request string) (err error) {
+	user, password := m.user, m.password
+
+	if m.dynamic != nil {
+		challenge := *m.dynamic
+		m.dynamic = nil
+		response, err := m.respond(ctx, challenge)
+		if err != nil {
+			return fmt.Errorf("answering dynamic challenge: %w", err)
+		}
+		user = challenge.username
+		password = dynamicChallengePassword(challenge, response)
+	} else {
+		challenge, ok, err := parseStaticChallenge(request)
+		if err != nil {
+			return fmt.Errorf("parsing static challenge: %w", err)
+		} else if ok {
+			response, err := m.respond(ctx, challenge)
+			if err != nil {
+				return fmt.Errorf("answering static challenge: %w", err)
+			}
+			password = staticChallengePassword(password, response)
+		}
+	}
+
+	_, err = fmt.Fprintf(w, "username \"Auth\" %s\npassword \"Auth\" %s\n",
+		quoteManagement(user), quoteManagement(password))
+	if err != nil {
+		return fmt.Errorf("writing credentials: %w", err)
+	}
+	return nil
+}
+
+// quoteManagement quotes and escapes a string to be used
+// as an argument of a management interface command.
+func quoteManagement(s string) (quoted string) {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
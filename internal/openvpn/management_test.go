@@ -0,0 +1,138 @@
+package openvpn
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type noopLogger struct{}
+
+func (noopLogger) Debug(string) {}
+func (noopLogger) Info(string)  {}
+func (noopLogger) Warn(string)  {}
+func (noopLogger) Error(string) {}
+
+func Test_management_handleLine(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		lines      []string
+		written    string
+		challenges []Challenge
+		errMessage string
+	}{
+		"ignored lines": {
+			lines: []string{
+				">INFO:OpenVPN Management Interface Version 3",
+				"SUCCESS: 'Auth' username entered, but not yet verified",
+			},
+		},
+		"no challenge": {
+			lines: []string{">PASSWORD:Need 'Auth' username/password"},
+			written: "username \"Auth\" \"user\"\n" +
+				"password \"Auth\" \"pass\\\"word\"\n",
+		},
+		"static challenge": {
+			lines: []string{">PASSWORD:Need 'Auth' username/password SC:1,Enter code"},
+			written: "username \"Auth\" \"user\"\n" +
+				"password \"Auth\" \"SCRV1:cGFzcyJ3b3Jk:MTIzNDU2\"\n",
+			challenges: []Challenge{{Text: "Enter code", Echo: true}},
+		},
+		"dynamic challenge": {
+			lines: []string{
+				">PASSWORD:Verification Failed: 'Auth' ['CRV1:R,E:Om01u7Fh4LrGBS7uh0SWmzwabUiGiW6l:Y3Ix:Please enter token PIN']",
+				">PASSWORD:Need 'Auth' username/password",
+			},
+			written: "username \"Auth\" \"cr1\"\n" +
+				"password \"Auth\" \"CRV1::Om01u7Fh4LrGBS7uh0SWmzwabUiGiW6l::123456\"\n",
+			challenges: []Challenge{{
+				Text:     "Please enter token PIN",
+				Echo:     true,
+				Dynamic:  true,
+				stateID:  "Om01u7Fh4LrGBS7uh0SWmzwabUiGiW6l",
+				username: "cr1",
+			}},
+		},
+		"malformed dynamic challenge": {
+			lines:      []string{">PASSWORD:Verification Failed: 'Auth' ['CRV1:R']"},
+			errMessage: "parsing dynamic challenge: challenge is malformed: CRV1:R",
+		},
+		"management error": {
+			lines:      []string{"ERROR: unknown command"},
+			errMessage: "management command failed: unknown command",
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var challenges []Challenge
+			m := &management{
+				user:     "user",
+				password: `pass"word`,
+				respond: func(ctx context.Context, challenge Challenge) (string, error) {
+					challenges = append(challenges, challenge)
+					return "123456", nil
+				},
+				logger: noopLogger{},
+			}
+
+			buffer := bytes.NewBuffer(nil)
+			var err error
+			for _, line := range testCase.lines {
+				err = m.handleLine(context.Background(), buffer, line)
+				if err != nil {
+					break
+				}
+			}
+
+			if testCase.errMessage != "" {
+				require.EqualError(t, err, testCase.errMessage)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, testCase.written, buffer.String())
+			assert.Equal(t, testCase.challenges, challenges)
+		})
+	}
+}
+
+func Test_Challenges(t *testing.T) {
+	t.Parallel()
+
+	challenges := NewChallenges()
+
+	_, ok := challenges.Pending()
+	assert.False(t, ok)
+	err := challenges.Answer("x")
+	assert.ErrorIs(t, err, ErrNoChallengePending)
+
+	challenge := Challenge{Text: "Enter code"}
+	responseCh := make(chan string)
+	go func() {
+		response, err := challenges.wait(context.Background(), challenge)
+		assert.NoError(t, err)
+		responseCh <- response
+	}()
+
+	for {
+		pending, ok := challenges.Pending()
+		if ok {
+			assert.Equal(t, challenge, pending)
+			break
+		}
+	}
+
+	err = challenges.Answer("123456")
+	require.NoError(t, err)
+	assert.Equal(t, "123456", <-responseCh)
+
+	_, ok = challenges.Pending()
+	assert.False(t, ok)
+}
@@ -1,6 +1,8 @@
 package openvpn
 
 import (
+	"path/filepath"
+
 	"github.com/qdm12/gluetun/internal/constants/openvpn"
 	"github.com/qdm12/golibs/command"
 )
@@ -26,3 +28,23 @@ func New(logger Infoer, cmder command.RunStarter,
 		pgid:         pgid,
 	}
 }
+
+// ForTunnel returns a configurator for the additional tunnel
+// with the name given, writing its files in its own directory.
+func (c *Configurator) ForTunnel(name string) *Configurator {
+	directory := filepath.Join(tunnelsDirectory, name)
+	return &Configurator{
+		logger:       c.logger,
+		cmder:        c.cmder,
+		configPath:   filepath.Join(directory, filepath.Base(configPath)),
+		authFilePath: filepath.Join(directory, filepath.Base(openvpn.AuthConf)),
+		askPassPath:  filepath.Join(directory, filepath.Base(openvpn.AskPassPath)),
+		puid:         c.puid,
+		pgid:         c.pgid,
+	}
+}
+
+// ConfigPath returns the OpenVPN configuration file path.
+func (c *Configurator) ConfigPath() string {
+	return c.configPath
+}
@@ -1,3 +1,10 @@
 package openvpn
 
-const configPath = "/etc/openvpn/target.ovpn"
+const (
+	configPath = "/etc/openvpn/target.ovpn"
+	// managementPath is the unix socket path of the OpenVPN management interface.
+	managementPath = "/etc/openvpn/management.sock"
+	// tunnelsDirectory is the directory containing the
+	// files of each additional tunnel in its own directory.
+	tunnelsDirectory = "/etc/openvpn/tunnels"
+)
@@ -8,22 +8,44 @@ import (
 )
 
 type Runner struct {
-	settings settings.OpenVPN
-	starter  command.Starter
-	logger   Logger
+	settings   settings.OpenVPN
+	configPath string
+	starter    command.Starter
+	challenges *Challenges
+	logger     Logger
 }
 
 func NewRunner(settings settings.OpenVPN, starter command.Starter,
-	logger Logger) *Runner {
+	challenges *Challenges, logger Logger) *Runner {
 	return &Runner{
-		starter:  starter,
-		logger:   logger,
-		settings: settings,
+		starter:    starter,
+		challenges: challenges,
+		logger:     logger,
+		settings:   settings,
+		configPath: configPath,
+	}
+}
+
+// NewTunnelRunner returns a runner for an additional tunnel, using
+// the configuration file path given. Authentication challenges are
+// not answered, so the Challenge setting must be false.
+func NewTunnelRunner(settings settings.OpenVPN, configPath string,
+	starter command.Starter, logger Logger) *Runner {
+	return &Runner{
+		starter:    starter,
+		logger:     logger,
+		settings:   settings,
+		configPath: configPath,
 	}
 }
 
 func (r *Runner) Run(ctx context.Context, errCh chan<- error, ready chan<- struct{}) {
-	stdoutLines, stderrLines, waitError, err := start(ctx, r.starter, r.settings.Version, r.settings.Flags)
+	flags := r.settings.Flags
+	if *r.settings.Challenge {
+		flags = append(managementFlags(*r.settings.User != ""), flags...)
+	}
+
+	stdoutLines, stderrLines, waitError, err := start(ctx, r.starter, r.settings.Version, r.configPath, flags)
 	if err != nil {
 		errCh <- err
 		return
@@ -34,15 +56,27 @@ func (r *Runner) Run(ctx context.Context, errCh chan<- error, ready chan<- struc
 	go streamLines(streamCtx, streamDone, r.logger,
 		stdoutLines, stderrLines, ready)
 
+	managementCtx, managementCancel := context.WithCancel(context.Background())
+	managementDone := make(chan struct{})
+	if *r.settings.Challenge {
+		go r.runManagement(managementCtx, managementDone)
+	} else {
+		close(managementDone)
+	}
+
 	select {
 	case <-ctx.Done():
 		<-waitError
 		close(waitError)
+		managementCancel()
+		<-managementDone
 		streamCancel()
 		<-streamDone
 		errCh <- ctx.Err()
 	case err := <-waitError:
 		close(waitError)
+		managementCancel()
+		<-managementDone
 		streamCancel()
 		<-streamDone
 		errCh <- err
@@ -18,7 +18,8 @@ const (
 	binOpenvpn26 = "openvpn2.6"
 )
 
-func start(ctx context.Context, starter command.Starter, version string, flags []string) (
+func start(ctx context.Context, starter command.Starter, version, configPath string,
+	flags []string) (
 	stdoutLines, stderrLines chan string, waitError chan error, err error) {
 	var bin string
 	switch version {
@@ -0,0 +1,69 @@
+// Package totp implements time based one time passwords as
+// defined in RFC 6238, to answer OpenVPN authentication challenges.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	period = 30 * time.Second
+	digits = 6
+)
+
+var ErrSecretEmpty = errors.New("secret is empty")
+
+// DecodeSecret decodes a base32 encoded secret, ignoring spaces,
+// case and padding as commonly found in authenticator applications.
+func DecodeSecret(secret string) (key []byte, err error) {
+	secret = strings.ReplaceAll(secret, " ", "")
+	secret = strings.TrimRight(strings.ToUpper(secret), "=")
+	if secret == "" {
+		return nil, fmt.Errorf("%w", ErrSecretEmpty)
+	}
+
+	key, err = base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("decoding base32 secret: %w", err)
+	}
+	return key, nil
+}
+
+// Generate returns the one time password for the base32 encoded
+// secret given and at the time given.
+func Generate(secret string, now time.Time) (code string, err error) {
+	key, err := DecodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	counter := uint64(now.Unix() / int64(period/time.Second))
+	return hotp(key, counter), nil
+}
+
+// hotp implements the HMAC based one time password of RFC 4226.
+func hotp(key []byte, counter uint64) (code string) {
+	message := make([]byte, 8) //nolint:gomnd
+	binary.BigEndian.PutUint64(message, counter)
+
+	mac := hmac.New(sha1.New, key)
+	_, _ = mac.Write(message)
+	sum := mac.Sum(nil)
+
+	const offsetMask = 0x0f
+	offset := sum[len(sum)-1] & offsetMask
+	const truncateMask = 0x7fffffff
+	truncated := binary.BigEndian.Uint32(sum[offset:offset+4]) & truncateMask
+
+	modulo := uint32(1)
+	for i := 0; i < digits; i++ {
+		modulo *= 10 //nolint:gomnd
+	}
+	return fmt.Sprintf("%0*d", digits, truncated%modulo)
+}
@@ -0,0 +1,69 @@
+package totp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Generate(t *testing.T) {
+	t.Parallel()
+
+	// Test vectors from RFC 6238 appendix B for SHA1,
+	// truncated to 6 digits. The secret is the ASCII
+	// string "12345678901234567890" encoded in base32.
+	const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+	testCases := map[string]struct {
+		secret     string
+		now        time.Time
+		code       string
+		errMessage string
+	}{
+		"empty secret": {
+			errMessage: "secret is empty",
+		},
+		"invalid secret": {
+			secret:     "1!",
+			errMessage: "decoding base32 secret: illegal base32 data at input byte 0",
+		},
+		"59 seconds": {
+			secret: secret,
+			now:    time.Unix(59, 0),
+			code:   "287082",
+		},
+		"1111111109 seconds": {
+			secret: secret,
+			now:    time.Unix(1111111109, 0),
+			code:   "081804",
+		},
+		"2000000000 seconds": {
+			secret: secret,
+			now:    time.Unix(2000000000, 0),
+			code:   "279037",
+		},
+		"lowercase secret with spaces and padding": {
+			secret: "gezd gnbv gy3t qojq gezd gnbv gy3t qojq====",
+			now:    time.Unix(59, 0),
+			code:   "287082",
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			code, err := Generate(testCase.secret, testCase.now)
+
+			if testCase.errMessage != "" {
+				require.EqualError(t, err, testCase.errMessage)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, testCase.code, code)
+		})
+	}
+}
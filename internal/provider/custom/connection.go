@@ -8,6 +8,7 @@ import (
 	"github.com/qdm12/gluetun/internal/constants"
 	"github.com/qdm12/gluetun/internal/constants/vpn"
 	"github.com/qdm12/gluetun/internal/models"
+	"github.com/qdm12/gluetun/internal/provider/utils"
 )
 
 var (
@@ -27,6 +28,12 @@ func (p *Provider) GetConnection(selection settings.ServerSelection, _ bool) (
 	}
 }
 
+// ConnectionDefaults returns no default ports, since the ports
+// are set by the OpenVPN configuration file or by the settings.
+func (p *Provider) ConnectionDefaults() utils.ConnectionDefaults {
+	return utils.ConnectionDefaults{}
+}
+
 func getOpenVPNConnection(extractor Extractor,
 	selection settings.ServerSelection) (
 	connection models.Connection, err error) {
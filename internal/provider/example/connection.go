@@ -10,7 +10,11 @@ func (p *Provider) GetConnection(selection settings.ServerSelection, ipv6Support
 	connection models.Connection, err error) {
 	// TODO: Set the default ports for each VPN protocol+network protocol
 	// combination. If one combination is not supported, set it to `0`.
-	defaults := utils.NewConnectionDefaults(443, 1194, 51820) //nolint:gomnd
 	return utils.GetConnection(p.Name(),
-		p.storage, selection, defaults, ipv6Supported, p.randSource)
+		p.storage, selection, p.ConnectionDefaults(), ipv6Supported, p.randSource)
+}
+
+// ConnectionDefaults returns the default ports used to connect to servers.
+func (p *Provider) ConnectionDefaults() utils.ConnectionDefaults {
+	return utils.NewConnectionDefaults(443, 1194, 51820) //nolint:gomnd
 }
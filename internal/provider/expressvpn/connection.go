@@ -8,7 +8,11 @@ import (
 
 func (p *Provider) GetConnection(selection settings.ServerSelection, ipv6Supported bool) (
 	connection models.Connection, err error) {
-	defaults := utils.NewConnectionDefaults(0, 1195, 0) //nolint:gomnd
 	return utils.GetConnection(p.Name(),
-		p.storage, selection, defaults, ipv6Supported, p.randSource)
+		p.storage, selection, p.ConnectionDefaults(), ipv6Supported, p.randSource)
+}
+
+// ConnectionDefaults returns the default ports used to connect to servers.
+func (p *Provider) ConnectionDefaults() utils.ConnectionDefaults {
+	return utils.NewConnectionDefaults(0, 1195, 0) //nolint:gomnd
 }
@@ -10,12 +10,8 @@ import (
 func (p *Provider) GetConnection(selection settings.ServerSelection, ipv6Supported bool) (
 	connection models.Connection, err error) {
 	// Set port defaults depending on encryption preset.
-	var defaults utils.ConnectionDefaults
-	switch *selection.OpenVPN.PIAEncPreset {
-	case presets.None, presets.Normal:
-		defaults.OpenVPNTCPPort = 502
-		defaults.OpenVPNUDPPort = 1198
-	case presets.Strong:
+	defaults := p.ConnectionDefaults()
+	if *selection.OpenVPN.PIAEncPreset == presets.Strong {
 		defaults.OpenVPNTCPPort = 501
 		defaults.OpenVPNUDPPort = 1197
 	}
@@ -23,3 +19,9 @@ func (p *Provider) GetConnection(selection settings.ServerSelection, ipv6Support
 	return utils.GetConnection(p.Name(),
 		p.storage, selection, defaults, ipv6Supported, p.randSource)
 }
+
+// ConnectionDefaults returns the default ports used to connect
+// to servers, for the none and normal encryption presets.
+func (p *Provider) ConnectionDefaults() utils.ConnectionDefaults {
+	return utils.NewConnectionDefaults(502, 1198, 0) //nolint:gomnd
+}
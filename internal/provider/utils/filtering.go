@@ -53,6 +53,10 @@ func filterServer(server models.Server,
 		return true
 	}
 
+	if *selection.ReachableOnly && server.Reachable != nil && !*server.Reachable {
+		return true
+	}
+
 	if filterByPossibilities(server.Country, selection.Countries) {
 		return true
 	}
@@ -127,6 +127,20 @@ func Test_FilterServers(t *testing.T) {
 				{Owned: true, VPN: vpn.OpenVPN, UDP: true},
 			},
 		},
+		"filter by reachable only": {
+			selection: settings.ServerSelection{
+				ReachableOnly: boolPtr(true),
+			}.WithDefaults(providers.Mullvad),
+			servers: []models.Server{
+				{Hostname: "a", Reachable: boolPtr(false), VPN: vpn.OpenVPN, UDP: true},
+				{Hostname: "b", Reachable: boolPtr(true), VPN: vpn.OpenVPN, UDP: true},
+				{Hostname: "c", VPN: vpn.OpenVPN, UDP: true},
+			},
+			filtered: []models.Server{
+				{Hostname: "b", Reachable: boolPtr(true), VPN: vpn.OpenVPN, UDP: true},
+				{Hostname: "c", VPN: vpn.OpenVPN, UDP: true},
+			},
+		},
 		"filter by country": {
 			selection: settings.ServerSelection{
 				Countries: []string{"b"},
@@ -0,0 +1,114 @@
+// Package api defines fetchers to obtain public IP address information
+// from various web APIs, and a resilient fetcher falling back on
+// the next fetcher when one fails or is rate limited.
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/qdm12/gluetun/internal/constants"
+	"github.com/qdm12/gluetun/internal/models"
+)
+
+// Fetcher obtains public IP address information from a web API.
+type Fetcher interface {
+	// String returns the name of the fetcher.
+	String() string
+	// CanFetchAnyIP returns true if the fetcher can fetch information
+	// on any IP address, and not only the public IP address of the machine.
+	CanFetchAnyIP() bool
+	// FetchInfo obtains information on the IP address given. If the IP
+	// is the zero value, the public IP address of the machine is used.
+	FetchInfo(ctx context.Context, ip netip.Addr) (
+		result models.PublicIP, err error)
+}
+
+const (
+	IPInfo     = "ipinfo"
+	IPAPI      = "ipapi"
+	IfConfigCo = "ifconfigco"
+	Cloudflare = "cloudflare"
+	Custom     = "custom"
+)
+
+// Names returns all the valid fetcher names.
+func Names() []string {
+	return []string{IPInfo, IPAPI, IfConfigCo, Cloudflare, Custom}
+}
+
+var (
+	ErrTooManyRequests = errors.New("too many requests")
+	ErrBadHTTPStatus   = errors.New("bad HTTP status received")
+	ErrNameNotValid    = errors.New("API name is not valid")
+	ErrIPNotSupported  = errors.New("fetching information on an IP address is not supported")
+)
+
+// Settings contains settings to create fetchers.
+type Settings struct {
+	// Names is the ordered list of fetcher names to create.
+	Names []string
+	// IPInfoToken is the optional ipinfo.io token.
+	IPInfoToken string
+	// CustomURL is the URL of the user defined API.
+	CustomURL string
+	// CustomFields maps public IP model field names, such as
+	// "ip" or "country", to JSON paths in the custom API response.
+	CustomFields map[string]string
+}
+
+// New creates the fetchers named in the settings, in the same order.
+func New(settings Settings, client *http.Client) (
+	fetchers []Fetcher, err error) {
+	fetchers = make([]Fetcher, len(settings.Names))
+	for i, name := range settings.Names {
+		switch name {
+		case IPInfo:
+			fetchers[i] = newIPInfo(client, settings.IPInfoToken)
+		case IPAPI:
+			fetchers[i] = newIPAPI(client)
+		case IfConfigCo:
+			fetchers[i] = newIfConfigCo(client)
+		case Cloudflare:
+			fetchers[i] = newCloudflare(client)
+		case Custom:
+			fetchers[i], err = newCustom(client, settings.CustomURL, settings.CustomFields)
+			if err != nil {
+				return nil, fmt.Errorf("creating custom API: %w", err)
+			}
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrNameNotValid, name)
+		}
+	}
+	return fetchers, nil
+}
+
+// checkStatusCode returns an error wrapping ErrTooManyRequests if the
+// status code indicates a rate limit, or ErrBadHTTPStatus if the status
+// code is not 200.
+func checkStatusCode(url string, response *http.Response) (err error) {
+	switch response.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusTooManyRequests, http.StatusForbidden:
+		return fmt.Errorf("%w from %s: %d %s",
+			ErrTooManyRequests, url, response.StatusCode, response.Status)
+	default:
+		return fmt.Errorf("%w from %s: %d %s",
+			ErrBadHTTPStatus, url, response.StatusCode, response.Status)
+	}
+}
+
+// countryCodeToName returns the country name for the
+// country code given, or the code itself if not found.
+func countryCodeToName(code string) (name string) {
+	name, ok := constants.CountryCodes()[strings.ToLower(code)]
+	if ok {
+		return name
+	}
+	return code
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/qdm12/gluetun/internal/models"
+)
+
+type cloudflare struct {
+	client *http.Client
+}
+
+func newCloudflare(client *http.Client) *cloudflare {
+	return &cloudflare{
+		client: client,
+	}
+}
+
+func (c *cloudflare) String() string { return Cloudflare }
+
+func (c *cloudflare) CanFetchAnyIP() bool { return false }
+
+var ErrCloudflareIPNotFound = errors.New("IP address not found in Cloudflare trace")
+
+// FetchInfo obtains the public IP address and its country
+// from the Cloudflare trace endpoint. It only supports the
+// zero IP address, to obtain information on the machine.
+func (c *cloudflare) FetchInfo(ctx context.Context, ip netip.Addr) (
+	result models.PublicIP, err error) {
+	if ip.IsValid() {
+		return result, fmt.Errorf("%w: %s", ErrIPNotSupported, c)
+	}
+
+	const url = "https://www.cloudflare.com/cdn-cgi/trace"
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return result, err
+	}
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return result, err
+	}
+	defer response.Body.Close()
+
+	err = checkStatusCode(url, response)
+	if err != nil {
+		return result, err
+	}
+
+	// The response body is made of key=value lines
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "ip":
+			result.IP, err = netip.ParseAddr(value)
+			if err != nil {
+				return result, fmt.Errorf("parsing IP address: %w", err)
+			}
+		case "loc":
+			result.Country = countryCodeToName(value)
+		}
+	}
+	err = scanner.Err()
+	if err != nil {
+		return result, fmt.Errorf("reading response body: %w", err)
+	}
+
+	if !result.IP.IsValid() {
+		return result, fmt.Errorf("%w", ErrCloudflareIPNotFound)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,190 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/qdm12/gluetun/internal/models"
+)
+
+// ipPlaceholder is the placeholder in the custom URL
+// replaced by the IP address to fetch information on.
+const ipPlaceholder = "{ip}"
+
+type custom struct {
+	client *http.Client
+	url    string
+	// fields maps model field names to JSON paths.
+	fields map[string][]string
+}
+
+var (
+	ErrCustomURLEmpty       = errors.New("URL is empty")
+	ErrCustomFieldNotValid  = errors.New("field is not valid")
+	ErrCustomIPFieldMissing = errors.New("ip field mapping is missing")
+)
+
+// CustomFieldNames returns the valid public IP model field
+// names which can be mapped for the custom API.
+func CustomFieldNames() []string {
+	return []string{"ip", "region", "country", "city", "hostname",
+		"location", "organization", "postal_code", "timezone"}
+}
+
+func newCustom(client *http.Client, url string, fields map[string]string) (
+	c *custom, err error) {
+	if url == "" {
+		return nil, fmt.Errorf("%w", ErrCustomURLEmpty)
+	}
+
+	c = &custom{
+		client: client,
+		url:    url,
+		fields: make(map[string][]string, len(fields)),
+	}
+
+	validFields := CustomFieldNames()
+	for field, path := range fields {
+		if !isOneOf(field, validFields) {
+			return nil, fmt.Errorf("%w: %q must be one of %s",
+				ErrCustomFieldNotValid, field, strings.Join(validFields, ", "))
+		}
+		c.fields[field] = strings.Split(path, ".")
+	}
+
+	if _, ok := c.fields["ip"]; !ok {
+		return nil, fmt.Errorf("%w", ErrCustomIPFieldMissing)
+	}
+
+	return c, nil
+}
+
+func isOneOf(value string, choices []string) bool {
+	for _, choice := range choices {
+		if value == choice {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *custom) String() string { return Custom }
+
+func (c *custom) CanFetchAnyIP() bool {
+	return strings.Contains(c.url, ipPlaceholder)
+}
+
+// FetchInfo obtains information on the IP address given using the
+// user defined URL and maps the JSON response fields to the public
+// IP model using the JSON paths configured.
+func (c *custom) FetchInfo(ctx context.Context, ip netip.Addr) (
+	result models.PublicIP, err error) {
+	if ip.IsValid() && !c.CanFetchAnyIP() {
+		return result, fmt.Errorf("%w: %s", ErrIPNotSupported, c)
+	}
+
+	url := c.url
+	if ip.IsValid() {
+		url = strings.ReplaceAll(url, ipPlaceholder, ip.String())
+	} else {
+		url = strings.ReplaceAll(url, ipPlaceholder, "")
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return result, err
+	}
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return result, err
+	}
+	defer response.Body.Close()
+
+	err = checkStatusCode(url, response)
+	if err != nil {
+		return result, err
+	}
+
+	var data any
+	decoder := json.NewDecoder(response.Body)
+	if err := decoder.Decode(&data); err != nil {
+		return result, fmt.Errorf("decoding response: %w", err)
+	}
+
+	values := make(map[string]string, len(c.fields))
+	for field, path := range c.fields {
+		value, err := extractJSONPath(data, path)
+		if err != nil {
+			return result, fmt.Errorf("extracting field %s: %w", field, err)
+		}
+		values[field] = value
+	}
+
+	result.IP, err = netip.ParseAddr(values["ip"])
+	if err != nil {
+		return result, fmt.Errorf("parsing IP address: %w", err)
+	}
+	result.Region = values["region"]
+	result.Country = countryCodeToName(values["country"])
+	result.City = values["city"]
+	result.Hostname = values["hostname"]
+	result.Location = values["location"]
+	result.Organization = values["organization"]
+	result.PostalCode = values["postal_code"]
+	result.Timezone = values["timezone"]
+	return result, nil
+}
+
+var ErrJSONPathNotFound = errors.New("JSON path not found")
+
+// extractJSONPath returns the string representation of the
+// value found at the path given in the decoded JSON data.
+// Path elements are object keys or array indexes.
+func extractJSONPath(data any, path []string) (value string, err error) {
+	for i, element := range path {
+		switch typed := data.(type) {
+		case map[string]any:
+			var ok bool
+			data, ok = typed[element]
+			if !ok {
+				return "", fmt.Errorf("%w: %s", ErrJSONPathNotFound,
+					strings.Join(path[:i+1], "."))
+			}
+		case []any:
+			index, err := strconv.Atoi(element)
+			if err != nil || index < 0 || index >= len(typed) {
+				return "", fmt.Errorf("%w: %s", ErrJSONPathNotFound,
+					strings.Join(path[:i+1], "."))
+			}
+			data = typed[index]
+		default:
+			return "", fmt.Errorf("%w: %s", ErrJSONPathNotFound,
+				strings.Join(path[:i+1], "."))
+		}
+	}
+
+	switch typed := data.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return typed, nil
+	case float64:
+		const bitSize = 64
+		return strconv.FormatFloat(typed, 'f', -1, bitSize), nil
+	case bool:
+		return strconv.FormatBool(typed), nil
+	default:
+		encoded, err := json.Marshal(typed)
+		if err != nil {
+			return "", fmt.Errorf("encoding value: %w", err)
+		}
+		return string(encoded), nil
+	}
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/qdm12/gluetun/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_custom_FetchInfo(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/lookup/1.2.3.4", r.URL.Path)
+			_, _ = w.Write([]byte(`{"query":"1.2.3.4","geo":{"cc":"NL",` +
+				`"cities":["Amsterdam"],"lat":52.37},"asn":{"org":"Some org"}}`))
+		}))
+	t.Cleanup(server.Close)
+
+	fetcher, err := newCustom(server.Client(), server.URL+"/lookup/{ip}",
+		map[string]string{
+			"ip":           "query",
+			"country":      "geo.cc",
+			"city":         "geo.cities.0",
+			"location":     "geo.lat",
+			"organization": "asn.org",
+		})
+	require.NoError(t, err)
+	assert.True(t, fetcher.CanFetchAnyIP())
+
+	result, err := fetcher.FetchInfo(context.Background(),
+		netip.AddrFrom4([4]byte{1, 2, 3, 4}))
+	require.NoError(t, err)
+
+	expected := models.PublicIP{
+		IP:           netip.AddrFrom4([4]byte{1, 2, 3, 4}),
+		Country:      "Netherlands",
+		City:         "Amsterdam",
+		Location:     "52.37",
+		Organization: "Some org",
+	}
+	assert.Equal(t, expected, result)
+}
+
+func Test_newCustom(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		url        string
+		fields     map[string]string
+		errWrapped error
+	}{
+		"empty URL": {
+			errWrapped: ErrCustomURLEmpty,
+		},
+		"invalid field": {
+			url:        "https://example.com",
+			fields:     map[string]string{"ip": "ip", "asn": "asn"},
+			errWrapped: ErrCustomFieldNotValid,
+		},
+		"missing ip field": {
+			url:        "https://example.com",
+			fields:     map[string]string{"country": "country"},
+			errWrapped: ErrCustomIPFieldMissing,
+		},
+		"valid": {
+			url:    "https://example.com",
+			fields: map[string]string{"ip": "ip"},
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := newCustom(nil, testCase.url, testCase.fields)
+
+			assert.ErrorIs(t, err, testCase.errWrapped)
+		})
+	}
+}
+
+func Test_extractJSONPath(t *testing.T) {
+	t.Parallel()
+
+	data := map[string]any{
+		"a": map[string]any{
+			"b": []any{"x", float64(2)},
+			"c": nil,
+			"d": true,
+		},
+	}
+
+	testCases := map[string]struct {
+		path       []string
+		value      string
+		errMessage string
+	}{
+		"nested string": {
+			path:  []string{"a", "b", "0"},
+			value: "x",
+		},
+		"nested number": {
+			path:  []string{"a", "b", "1"},
+			value: "2",
+		},
+		"null": {
+			path: []string{"a", "c"},
+		},
+		"bool": {
+			path:  []string{"a", "d"},
+			value: "true",
+		},
+		"object": {
+			path:  []string{"a", "b"},
+			value: `["x",2]`,
+		},
+		"missing key": {
+			path:       []string{"a", "e"},
+			errMessage: "JSON path not found: a.e",
+		},
+		"index out of range": {
+			path:       []string{"a", "b", "2"},
+			errMessage: "JSON path not found: a.b.2",
+		},
+		"path through scalar": {
+			path:       []string{"a", "d", "x"},
+			errMessage: "JSON path not found: a.d.x",
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			value, err := extractJSONPath(data, testCase.path)
+
+			if testCase.errMessage != "" {
+				require.EqualError(t, err, testCase.errMessage)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, testCase.value, value)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+// NewFamilyClient returns a copy of the HTTP client given which
+// only dials IPv6 addresses if ipv6 is true, or only IPv4 addresses
+// otherwise, to fetch information on the public IP address of
+// a specific IP family.
+func NewFamilyClient(client *http.Client, ipv6 bool) *http.Client {
+	baseTransport, ok := client.Transport.(*http.Transport)
+	if !ok || baseTransport == nil {
+		baseTransport = http.DefaultTransport.(*http.Transport) //nolint:forcetypeassert
+	}
+	transport := baseTransport.Clone()
+
+	dialNetwork := "tcp4"
+	if ipv6 {
+		dialNetwork = "tcp6"
+	}
+	dialer := &net.Dialer{}
+	transport.DialContext = func(ctx context.Context, _, address string) (net.Conn, error) {
+		return dialer.DialContext(ctx, dialNetwork, address)
+	}
+
+	familyClient := *client
+	familyClient.Transport = transport
+	return &familyClient
+}
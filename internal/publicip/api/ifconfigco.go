@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strconv"
+
+	"github.com/qdm12/gluetun/internal/models"
+)
+
+type ifConfigCo struct {
+	client *http.Client
+}
+
+func newIfConfigCo(client *http.Client) *ifConfigCo {
+	return &ifConfigCo{
+		client: client,
+	}
+}
+
+func (i *ifConfigCo) String() string { return IfConfigCo }
+
+func (i *ifConfigCo) CanFetchAnyIP() bool { return true }
+
+// FetchInfo obtains information on the IP address given using
+// the ifconfig.co API.
+func (i *ifConfigCo) FetchInfo(ctx context.Context, ip netip.Addr) (
+	result models.PublicIP, err error) {
+	url := "https://ifconfig.co/json"
+	if ip.IsValid() {
+		url += "?ip=" + ip.String()
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return result, err
+	}
+
+	response, err := i.client.Do(request)
+	if err != nil {
+		return result, err
+	}
+	defer response.Body.Close()
+
+	err = checkStatusCode(url, response)
+	if err != nil {
+		return result, err
+	}
+
+	var data struct {
+		IP         netip.Addr `json:"ip"`
+		Country    string     `json:"country"`
+		RegionName string     `json:"region_name"`
+		City       string     `json:"city"`
+		ZipCode    string     `json:"zip_code"`
+		Latitude   float64    `json:"latitude"`
+		Longitude  float64    `json:"longitude"`
+		TimeZone   string     `json:"time_zone"`
+		ASNOrg     string     `json:"asn_org"`
+		Hostname   string     `json:"hostname"`
+	}
+	decoder := json.NewDecoder(response.Body)
+	if err := decoder.Decode(&data); err != nil {
+		return result, fmt.Errorf("decoding response: %w", err)
+	}
+
+	const bitSize = 64
+	return models.PublicIP{
+		IP:       data.IP,
+		Region:   data.RegionName,
+		Country:  data.Country,
+		City:     data.City,
+		Hostname: data.Hostname,
+		Location: strconv.FormatFloat(data.Latitude, 'f', -1, bitSize) + "," +
+			strconv.FormatFloat(data.Longitude, 'f', -1, bitSize),
+		Organization: data.ASNOrg,
+		PostalCode:   data.ZipCode,
+		Timezone:     data.TimeZone,
+	}, nil
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strconv"
+
+	"github.com/qdm12/gluetun/internal/models"
+)
+
+type ipAPI struct {
+	client *http.Client
+}
+
+func newIPAPI(client *http.Client) *ipAPI {
+	return &ipAPI{
+		client: client,
+	}
+}
+
+func (i *ipAPI) String() string { return IPAPI }
+
+func (i *ipAPI) CanFetchAnyIP() bool { return true }
+
+var ErrIPAPIQueryFailed = errors.New("ip-api query failed")
+
+// FetchInfo obtains information on the IP address given using
+// the free ip-api.com API, which only supports plain HTTP.
+func (i *ipAPI) FetchInfo(ctx context.Context, ip netip.Addr) (
+	result models.PublicIP, err error) {
+	url := "http://ip-api.com/json/"
+	if ip.IsValid() {
+		url += ip.String()
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return result, err
+	}
+
+	response, err := i.client.Do(request)
+	if err != nil {
+		return result, err
+	}
+	defer response.Body.Close()
+
+	err = checkStatusCode(url, response)
+	if err != nil {
+		return result, err
+	}
+
+	var data struct {
+		Status     string     `json:"status"`
+		Message    string     `json:"message"`
+		Query      netip.Addr `json:"query"`
+		Country    string     `json:"country"`
+		RegionName string     `json:"regionName"`
+		City       string     `json:"city"`
+		Zip        string     `json:"zip"`
+		Lat        float64    `json:"lat"`
+		Lon        float64    `json:"lon"`
+		Timezone   string     `json:"timezone"`
+		Org        string     `json:"org"`
+		ISP        string     `json:"isp"`
+	}
+	decoder := json.NewDecoder(response.Body)
+	if err := decoder.Decode(&data); err != nil {
+		return result, fmt.Errorf("decoding response: %w", err)
+	}
+
+	if data.Status != "success" {
+		return result, fmt.Errorf("%w: %s", ErrIPAPIQueryFailed, data.Message)
+	}
+
+	organization := data.Org
+	if organization == "" {
+		organization = data.ISP
+	}
+
+	const bitSize = 64
+	return models.PublicIP{
+		IP:      data.Query,
+		Region:  data.RegionName,
+		Country: data.Country,
+		City:    data.City,
+		Location: strconv.FormatFloat(data.Lat, 'f', -1, bitSize) + "," +
+			strconv.FormatFloat(data.Lon, 'f', -1, bitSize),
+		Organization: organization,
+		PostalCode:   data.Zip,
+		Timezone:     data.Timezone,
+	}, nil
+}
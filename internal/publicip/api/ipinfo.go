@@ -0,0 +1,38 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+
+	"github.com/qdm12/gluetun/internal/models"
+	"github.com/qdm12/gluetun/internal/publicip/ipinfo"
+)
+
+type ipInfo struct {
+	fetch *ipinfo.Fetch
+}
+
+func newIPInfo(client *http.Client, token string) *ipInfo {
+	return &ipInfo{
+		fetch: ipinfo.New(client, token),
+	}
+}
+
+func (i *ipInfo) String() string { return IPInfo }
+
+func (i *ipInfo) CanFetchAnyIP() bool { return true }
+
+func (i *ipInfo) FetchInfo(ctx context.Context, ip netip.Addr) (
+	result models.PublicIP, err error) {
+	response, err := i.fetch.FetchInfo(ctx, ip)
+	if err != nil {
+		if errors.Is(err, ipinfo.ErrTooManyRequests) {
+			err = fmt.Errorf("%w: %w", ErrTooManyRequests, err)
+		}
+		return result, err
+	}
+	return response.ToPublicIPModel(), nil
+}
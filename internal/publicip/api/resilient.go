@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/models"
+)
+
+// Resilient is a fetcher trying each of its fetchers in order,
+// falling back on the next one if a fetcher fails. Fetchers which
+// are rate limited are skipped for the ban duration.
+type Resilient struct {
+	fetchers    []Fetcher
+	logger      Warner
+	banDuration time.Duration
+	timeNow     func() time.Time
+	bannedMutex sync.Mutex
+	bannedUntil map[string]time.Time
+}
+
+type Warner interface {
+	Warn(message string)
+}
+
+func NewResilient(fetchers []Fetcher, logger Warner) *Resilient {
+	const banDuration = time.Hour
+	return &Resilient{
+		fetchers:    fetchers,
+		logger:      logger,
+		banDuration: banDuration,
+		timeNow:     time.Now,
+		bannedUntil: make(map[string]time.Time, len(fetchers)),
+	}
+}
+
+func (r *Resilient) String() string {
+	names := make([]string, len(r.fetchers))
+	for i, fetcher := range r.fetchers {
+		names[i] = fetcher.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+// CanFetchAnyIP returns true if any of its fetchers can
+// fetch information on any IP address.
+func (r *Resilient) CanFetchAnyIP() bool {
+	for _, fetcher := range r.fetchers {
+		if fetcher.CanFetchAnyIP() {
+			return true
+		}
+	}
+	return false
+}
+
+var ErrAllFetchersFailed = errors.New("all fetchers failed")
+
+// FetchInfo obtains information on the IP address given, trying
+// each fetcher in order until one succeeds. It returns an error
+// wrapping ErrTooManyRequests if all fetchers are rate limited.
+func (r *Resilient) FetchInfo(ctx context.Context, ip netip.Addr) (
+	result models.PublicIP, err error) {
+	errorMessages := make([]string, 0, len(r.fetchers))
+	allRateLimited := true
+	for _, fetcher := range r.fetchers {
+		if ip.IsValid() && !fetcher.CanFetchAnyIP() {
+			continue
+		}
+
+		name := fetcher.String()
+		if r.isBanned(name) {
+			errorMessages = append(errorMessages, name+": rate limited")
+			continue
+		}
+
+		result, err = fetcher.FetchInfo(ctx, ip)
+		if err == nil {
+			return result, nil
+		} else if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+
+		errorMessages = append(errorMessages, name+": "+err.Error())
+		if errors.Is(err, ErrTooManyRequests) {
+			r.ban(name)
+			r.logger.Warn(name + " is rate limited, falling back on the next API")
+			continue
+		}
+		allRateLimited = false
+		r.logger.Warn(name + " failed, falling back on the next API: " + err.Error())
+	}
+
+	if len(errorMessages) == 0 {
+		return result, fmt.Errorf("%w: by any of %s", ErrIPNotSupported, r)
+	} else if allRateLimited {
+		return result, fmt.Errorf("%w: %s", ErrTooManyRequests,
+			strings.Join(errorMessages, "; "))
+	}
+	return result, fmt.Errorf("%w: %s", ErrAllFetchersFailed,
+		strings.Join(errorMessages, "; "))
+}
+
+func (r *Resilient) isBanned(name string) (banned bool) {
+	r.bannedMutex.Lock()
+	defer r.bannedMutex.Unlock()
+	bannedUntil, ok := r.bannedUntil[name]
+	if !ok {
+		return false
+	}
+	if r.timeNow().After(bannedUntil) {
+		delete(r.bannedUntil, name)
+		return false
+	}
+	return true
+}
+
+func (r *Resilient) ban(name string) {
+	r.bannedMutex.Lock()
+	defer r.bannedMutex.Unlock()
+	r.bannedUntil[name] = r.timeNow().Add(r.banDuration)
+}
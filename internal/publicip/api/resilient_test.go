@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testFetcher struct {
+	name     string
+	anyIP    bool
+	result   models.PublicIP
+	err      error
+	requests int
+}
+
+func (f *testFetcher) String() string      { return f.name }
+func (f *testFetcher) CanFetchAnyIP() bool { return f.anyIP }
+func (f *testFetcher) FetchInfo(context.Context, netip.Addr) (models.PublicIP, error) {
+	f.requests++
+	return f.result, f.err
+}
+
+type noopWarner struct{}
+
+func (noopWarner) Warn(string) {}
+
+func Test_Resilient_FetchInfo(t *testing.T) {
+	t.Parallel()
+
+	rateLimited := &testFetcher{
+		name:  "a",
+		anyIP: true,
+		err:   fmt.Errorf("%w: test", ErrTooManyRequests),
+	}
+	failing := &testFetcher{
+		name:  "b",
+		anyIP: true,
+		err:   errors.New("test failure"),
+	}
+	working := &testFetcher{
+		name:   "c",
+		result: models.PublicIP{Country: "Netherlands"},
+	}
+
+	now := time.Unix(0, 0)
+	resilient := NewResilient([]Fetcher{rateLimited, failing, working}, noopWarner{})
+	resilient.timeNow = func() time.Time { return now }
+
+	result, err := resilient.FetchInfo(context.Background(), netip.Addr{})
+	require.NoError(t, err)
+	assert.Equal(t, models.PublicIP{Country: "Netherlands"}, result)
+	assert.Equal(t, 1, rateLimited.requests)
+	assert.Equal(t, 1, failing.requests)
+	assert.Equal(t, 1, working.requests)
+
+	// The rate limited fetcher is skipped while banned
+	_, err = resilient.FetchInfo(context.Background(), netip.Addr{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, rateLimited.requests)
+
+	// The working fetcher cannot fetch information on any IP
+	_, err = resilient.FetchInfo(context.Background(), netip.AddrFrom4([4]byte{1, 2, 3, 4}))
+	assert.ErrorIs(t, err, ErrAllFetchersFailed)
+	assert.EqualError(t, err, "all fetchers failed: a: rate limited; b: test failure")
+
+	// The ban expired
+	now = now.Add(2 * time.Hour)
+	rateLimited.err = nil
+	_, err = resilient.FetchInfo(context.Background(), netip.Addr{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, rateLimited.requests)
+}
+
+type roundTripFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func Test_Resilient_FetchInfo_ipinfoRateLimited(t *testing.T) {
+	t.Parallel()
+
+	client := &http.Client{
+		Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusTooManyRequests,
+				Status:     http.StatusText(http.StatusTooManyRequests),
+				Body:       io.NopCloser(strings.NewReader("")),
+				Request:    r,
+			}, nil
+		}),
+	}
+	ipInfo := newIPInfo(client, "")
+	working := &testFetcher{
+		name:   "c",
+		result: models.PublicIP{Country: "Netherlands"},
+	}
+
+	resilient := NewResilient([]Fetcher{ipInfo, working}, noopWarner{})
+
+	result, err := resilient.FetchInfo(context.Background(), netip.Addr{})
+	require.NoError(t, err)
+	assert.Equal(t, models.PublicIP{Country: "Netherlands"}, result)
+	assert.True(t, resilient.isBanned(IPInfo))
+
+	// With ipinfo as the only fetcher, the error is a rate limit error.
+	resilient = NewResilient([]Fetcher{ipInfo}, noopWarner{})
+	_, err = resilient.FetchInfo(context.Background(), netip.Addr{})
+	assert.ErrorIs(t, err, ErrTooManyRequests)
+}
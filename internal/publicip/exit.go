@@ -0,0 +1,116 @@
+package publicip
+
+import (
+	"strings"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/qdm12/gluetun/internal/models"
+)
+
+// checkExit checks the public IP address information given against
+// the allowed exit countries and organizations. Both the IPv4 and,
+// if present, IPv6 address information are checked, and the check
+// of the IPv6 address is returned if only the IPv6 exit is not allowed.
+// It returns `enabled` as false if no exit enforcement is configured.
+func checkExit(exitSettings settings.PublicIPExit, data models.PublicIP,
+	now time.Time) (check models.ExitCheck, enabled bool) {
+	if len(exitSettings.Countries) == 0 && len(exitSettings.Organizations) == 0 {
+		return check, false
+	}
+
+	check = checkExitAddress(exitSettings, data, now)
+	if check.Allowed && data.IPv6 != nil {
+		ipv6Check := checkExitAddress(exitSettings, *data.IPv6, now)
+		if !ipv6Check.Allowed {
+			ipv6Check.Reason = "IPv6 address " + ipv6Check.IP.String() + ": " + ipv6Check.Reason
+			check = ipv6Check
+		}
+	}
+
+	if !check.Allowed {
+		check.Action = *exitSettings.Action
+	}
+
+	return check, true
+}
+
+func checkExitAddress(exitSettings settings.PublicIPExit, data models.PublicIP,
+	now time.Time) (check models.ExitCheck) {
+	check = models.ExitCheck{
+		Time:         now,
+		IP:           data.IP,
+		Country:      data.Country,
+		Organization: data.Organization,
+		Allowed:      true,
+	}
+
+	switch {
+	case len(exitSettings.Countries) > 0 &&
+		!countryIsAllowed(data.Country, exitSettings.Countries):
+		check.Allowed = false
+		check.Reason = "country " + quoteOrUnknown(data.Country) +
+			" is not one of " + strings.Join(exitSettings.Countries, ", ")
+	case len(exitSettings.Organizations) > 0 &&
+		!organizationIsAllowed(data.Organization, exitSettings.Organizations):
+		check.Allowed = false
+		check.Reason = "organization " + quoteOrUnknown(data.Organization) +
+			" does not match any of " + strings.Join(exitSettings.Organizations, ", ")
+	}
+
+	return check
+}
+
+func countryIsAllowed(country string, allowed []string) bool {
+	for _, allowedCountry := range allowed {
+		if strings.EqualFold(country, allowedCountry) {
+			return true
+		}
+	}
+	return false
+}
+
+func organizationIsAllowed(organization string, allowed []string) bool {
+	if organization == "" {
+		return false
+	}
+	organization = strings.ToLower(organization)
+	for _, allowedOrganization := range allowed {
+		if strings.Contains(organization, strings.ToLower(allowedOrganization)) {
+			return true
+		}
+	}
+	return false
+}
+
+func quoteOrUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return `"` + s + `"`
+}
+
+// enforceExit checks the public IP data against the exit settings,
+// records the result and acts on a mismatch.
+func (l *Loop) enforceExit(data models.PublicIP) {
+	check, enabled := checkExit(l.state.GetSettings().Exit, data, l.timeNow())
+	if !enabled {
+		l.state.SetExitCheck(nil)
+		return
+	}
+	l.state.SetExitCheck(&check)
+
+	if check.Allowed {
+		return
+	}
+
+	l.logger.Warn("exit location not allowed: " + check.Reason)
+	if check.Action != settings.ExitActionRestart {
+		return
+	}
+
+	select {
+	case l.exitMismatches <- check:
+	default: // a mismatch is already pending
+	}
+}
@@ -0,0 +1,177 @@
+package publicip
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/qdm12/gluetun/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func ptrTo[T any](value T) *T { return &value }
+
+func Test_checkExit(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1, 0)
+	data := models.PublicIP{
+		IP:           netip.AddrFrom4([4]byte{1, 2, 3, 4}),
+		Country:      "Germany",
+		Organization: "AS9009 M247 Europe SRL",
+	}
+	ipv6Data := models.PublicIP{
+		IP:           netip.MustParseAddr("2001:db8::1"),
+		Country:      "United States",
+		Organization: "AS13335 Cloudflare",
+	}
+
+	testCases := map[string]struct {
+		settings settings.PublicIPExit
+		data     models.PublicIP
+		check    models.ExitCheck
+		enabled  bool
+	}{
+		"disabled": {
+			settings: settings.PublicIPExit{Action: ptrTo(settings.ExitActionWarn)},
+			data:     data,
+		},
+		"country_allowed": {
+			settings: settings.PublicIPExit{
+				Countries: []string{"netherlands", "germany"},
+				Action:    ptrTo(settings.ExitActionRestart),
+			},
+			data: data,
+			check: models.ExitCheck{
+				Time:         now,
+				IP:           data.IP,
+				Country:      "Germany",
+				Organization: "AS9009 M247 Europe SRL",
+				Allowed:      true,
+			},
+			enabled: true,
+		},
+		"country_not_allowed": {
+			settings: settings.PublicIPExit{
+				Countries: []string{"netherlands"},
+				Action:    ptrTo(settings.ExitActionRestart),
+			},
+			data: data,
+			check: models.ExitCheck{
+				Time:         now,
+				IP:           data.IP,
+				Country:      "Germany",
+				Organization: "AS9009 M247 Europe SRL",
+				Reason:       `country "Germany" is not one of netherlands`,
+				Action:       settings.ExitActionRestart,
+			},
+			enabled: true,
+		},
+		"organization_allowed": {
+			settings: settings.PublicIPExit{
+				Organizations: []string{"m247"},
+				Action:        ptrTo(settings.ExitActionWarn),
+			},
+			data: data,
+			check: models.ExitCheck{
+				Time:         now,
+				IP:           data.IP,
+				Country:      "Germany",
+				Organization: "AS9009 M247 Europe SRL",
+				Allowed:      true,
+			},
+			enabled: true,
+		},
+		"organization_unknown": {
+			settings: settings.PublicIPExit{
+				Organizations: []string{"m247"},
+				Action:        ptrTo(settings.ExitActionWarn),
+			},
+			data: models.PublicIP{IP: data.IP, Country: "Germany"},
+			check: models.ExitCheck{
+				Time:    now,
+				IP:      data.IP,
+				Country: "Germany",
+				Reason:  "organization unknown does not match any of m247",
+				Action:  settings.ExitActionWarn,
+			},
+			enabled: true,
+		},
+		"ipv6_allowed": {
+			settings: settings.PublicIPExit{
+				Countries: []string{"germany", "united states"},
+				Action:    ptrTo(settings.ExitActionRestart),
+			},
+			data: models.PublicIP{
+				IP:           data.IP,
+				Country:      data.Country,
+				Organization: data.Organization,
+				IPv6:         &ipv6Data,
+			},
+			check: models.ExitCheck{
+				Time:         now,
+				IP:           data.IP,
+				Country:      "Germany",
+				Organization: "AS9009 M247 Europe SRL",
+				Allowed:      true,
+			},
+			enabled: true,
+		},
+		"ipv6_not_allowed": {
+			settings: settings.PublicIPExit{
+				Countries: []string{"germany"},
+				Action:    ptrTo(settings.ExitActionRestart),
+			},
+			data: models.PublicIP{
+				IP:           data.IP,
+				Country:      data.Country,
+				Organization: data.Organization,
+				IPv6:         &ipv6Data,
+			},
+			check: models.ExitCheck{
+				Time:         now,
+				IP:           ipv6Data.IP,
+				Country:      "United States",
+				Organization: "AS13335 Cloudflare",
+				Reason: `IPv6 address 2001:db8::1: country "United States" ` +
+					"is not one of germany",
+				Action: settings.ExitActionRestart,
+			},
+			enabled: true,
+		},
+		"ipv4_not_allowed_with_ipv6": {
+			settings: settings.PublicIPExit{
+				Countries: []string{"united states"},
+				Action:    ptrTo(settings.ExitActionWarn),
+			},
+			data: models.PublicIP{
+				IP:           data.IP,
+				Country:      data.Country,
+				Organization: data.Organization,
+				IPv6:         &ipv6Data,
+			},
+			check: models.ExitCheck{
+				Time:         now,
+				IP:           data.IP,
+				Country:      "Germany",
+				Organization: "AS9009 M247 Europe SRL",
+				Reason:       `country "Germany" is not one of united states`,
+				Action:       settings.ExitActionWarn,
+			},
+			enabled: true,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			check, enabled := checkExit(testCase.settings, testCase.data, now)
+
+			assert.Equal(t, testCase.check, check)
+			assert.Equal(t, testCase.enabled, enabled)
+		})
+	}
+}
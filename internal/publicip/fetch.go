@@ -0,0 +1,45 @@
+package publicip
+
+import (
+	"context"
+	"net/netip"
+
+	"github.com/qdm12/gluetun/internal/models"
+)
+
+// fetchInfo fetches the public IPv4 address information and, if an IPv6
+// fetcher is set, the public IPv6 address information in parallel.
+// Failing to fetch IPv6 information is not an error, since the tunnel
+// may not have IPv6 connectivity.
+func (l *Loop) fetchInfo(ctx context.Context) (result models.PublicIP, err error) {
+	if l.fetcherIPv6 == nil {
+		return l.fetcher.FetchInfo(ctx, netip.Addr{})
+	}
+
+	ipv6Done := make(chan struct{})
+	var ipv6Result models.PublicIP
+	var ipv6Err error
+	go func() {
+		defer close(ipv6Done)
+		ipv6Result, ipv6Err = l.fetcherIPv6.FetchInfo(ctx, netip.Addr{})
+	}()
+
+	result, err = l.fetcher.FetchInfo(ctx, netip.Addr{})
+	<-ipv6Done
+	if err != nil {
+		return result, err
+	}
+
+	switch {
+	case ipv6Err != nil:
+		if ctx.Err() == nil {
+			l.logger.Info("public IPv6 address not available: " + ipv6Err.Error())
+		}
+	case !ipv6Result.IP.Is6() || ipv6Result.IP.Is4In6():
+		l.logger.Warn("public IPv6 address fetched is not IPv6: " + ipv6Result.IP.String())
+	default:
+		result.IPv6 = &ipv6Result
+	}
+
+	return result, nil
+}
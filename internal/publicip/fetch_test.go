@@ -0,0 +1,101 @@
+package publicip
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+
+	"github.com/qdm12/gluetun/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testFetcher struct {
+	result models.PublicIP
+	err    error
+}
+
+func (f *testFetcher) FetchInfo(context.Context, netip.Addr) (models.PublicIP, error) {
+	return f.result, f.err
+}
+
+type noopLogger struct{}
+
+func (noopLogger) Info(string)  {}
+func (noopLogger) Warn(string)  {}
+func (noopLogger) Error(string) {}
+
+func Test_Loop_fetchInfo(t *testing.T) {
+	t.Parallel()
+
+	ipv4 := models.PublicIP{IP: netip.AddrFrom4([4]byte{1, 2, 3, 4})}
+	ipv6 := models.PublicIP{IP: netip.MustParseAddr("2001:db8::1")}
+	errTest := errors.New("test error")
+
+	testCases := map[string]struct {
+		fetcher     Fetcher
+		fetcherIPv6 Fetcher
+		result      models.PublicIP
+		err         error
+	}{
+		"IPv4 only": {
+			fetcher: &testFetcher{result: ipv4},
+			result:  ipv4,
+		},
+		"IPv4 error": {
+			fetcher:     &testFetcher{err: errTest},
+			fetcherIPv6: &testFetcher{result: ipv6},
+			err:         errTest,
+		},
+		"IPv6 error ignored": {
+			fetcher:     &testFetcher{result: ipv4},
+			fetcherIPv6: &testFetcher{err: errTest},
+			result:      ipv4,
+		},
+		"IPv6 fetched IPv4 address": {
+			fetcher:     &testFetcher{result: ipv4},
+			fetcherIPv6: &testFetcher{result: ipv4},
+			result:      ipv4,
+		},
+		"dual stack": {
+			fetcher:     &testFetcher{result: ipv4},
+			fetcherIPv6: &testFetcher{result: ipv6},
+			result: models.PublicIP{
+				IP:   ipv4.IP,
+				IPv6: &ipv6,
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			loop := &Loop{
+				fetcher:     testCase.fetcher,
+				fetcherIPv6: testCase.fetcherIPv6,
+				logger:      noopLogger{},
+			}
+
+			result, err := loop.fetchInfo(context.Background())
+
+			if testCase.err != nil {
+				require.ErrorIs(t, err, testCase.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.result, result)
+			assert.Equal(t, testCase.result.IP.String()+ipv6Line(testCase.result),
+				ipFileContent(result))
+		})
+	}
+}
+
+func ipv6Line(data models.PublicIP) string {
+	if data.IPv6 == nil {
+		return ""
+	}
+	return "\n" + data.IPv6.IP.String()
+}
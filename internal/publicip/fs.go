@@ -2,6 +2,8 @@ package publicip
 
 import (
 	"os"
+
+	"github.com/qdm12/gluetun/internal/models"
 )
 
 func persistPublicIP(path string, content string, puid, pgid int) error {
@@ -23,3 +25,14 @@ func persistPublicIP(path string, content string, puid, pgid int) error {
 
 	return file.Close()
 }
+
+// ipFileContent returns the content of the public IP file,
+// with the IPv4 address on the first line and the IPv6
+// address on the second line if it is available.
+func ipFileContent(data models.PublicIP) (content string) {
+	content = data.IP.String()
+	if data.IPv6 != nil {
+		content += "\n" + data.IPv6.IP.String()
+	}
+	return content
+}
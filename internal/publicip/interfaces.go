@@ -4,10 +4,10 @@ import (
 	"context"
 	"net/netip"
 
-	"github.com/qdm12/gluetun/internal/publicip/ipinfo"
+	"github.com/qdm12/gluetun/internal/models"
 )
 
 type Fetcher interface {
 	FetchInfo(ctx context.Context, ip netip.Addr) (
-		result ipinfo.Response, err error)
+		result models.PublicIP, err error)
 }
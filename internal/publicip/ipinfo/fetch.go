@@ -14,11 +14,15 @@ import (
 
 type Fetch struct {
 	client *http.Client
+	token  string
 }
 
-func New(client *http.Client) *Fetch {
+// New creates a new ipinfo.io fetcher. The token
+// is optional and can be left empty.
+func New(client *http.Client, token string) *Fetch {
 	return &Fetch{
 		client: client,
+		token:  token,
 	}
 }
 
@@ -42,6 +46,9 @@ func (f *Fetch) FetchInfo(ctx context.Context, ip netip.Addr) (
 	if err != nil {
 		return result, err
 	}
+	if f.token != "" {
+		request.Header.Set("Authorization", "Bearer "+f.token)
+	}
 
 	response, err := f.client.Do(request)
 	if err != nil {
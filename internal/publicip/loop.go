@@ -14,8 +14,9 @@ type Loop struct {
 	statusManager *loopstate.State
 	state         *state.State
 	// Objects
-	fetcher Fetcher
-	logger  Logger
+	fetcher     Fetcher
+	fetcherIPv6 Fetcher // nil if IPv6 is not supported
+	logger      Logger
 	// Fixed settings
 	puid int
 	pgid int
@@ -25,15 +26,18 @@ type Loop struct {
 	stop         chan struct{}
 	stopped      chan struct{}
 	updateTicker chan struct{}
-	backoffTime  time.Duration
-	userTrigger  bool
+	// exitMismatches is buffered to signal the VPN loop
+	// without blocking if it is busy.
+	exitMismatches chan models.ExitCheck
+	backoffTime    time.Duration
+	userTrigger    bool
 	// Mock functions
 	timeNow func() time.Time
 }
 
 const defaultBackoffTime = 5 * time.Second
 
-func NewLoop(fetcher Fetcher, logger Logger,
+func NewLoop(fetcher, fetcherIPv6 Fetcher, logger Logger,
 	settings settings.PublicIP, puid, pgid int) *Loop {
 	start := make(chan struct{})
 	running := make(chan models.LoopStatus)
@@ -48,17 +52,19 @@ func NewLoop(fetcher Fetcher, logger Logger,
 		statusManager: statusManager,
 		state:         state,
 		// Objects
-		fetcher:      fetcher,
-		logger:       logger,
-		puid:         puid,
-		pgid:         pgid,
-		start:        start,
-		running:      running,
-		stop:         stop,
-		stopped:      stopped,
-		updateTicker: updateTicker,
-		userTrigger:  true,
-		backoffTime:  defaultBackoffTime,
-		timeNow:      time.Now,
+		fetcher:        fetcher,
+		fetcherIPv6:    fetcherIPv6,
+		logger:         logger,
+		puid:           puid,
+		pgid:           pgid,
+		start:          start,
+		running:        running,
+		stop:           stop,
+		stopped:        stopped,
+		updateTicker:   updateTicker,
+		exitMismatches: make(chan models.ExitCheck, 1),
+		userTrigger:    true,
+		backoffTime:    defaultBackoffTime,
+		timeNow:        time.Now,
 	}
 }
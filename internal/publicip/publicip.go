@@ -8,6 +8,25 @@ func (l *Loop) GetData() (data models.PublicIP) {
 	return l.state.GetData()
 }
 
+// SetData sets the public IP data and clears the exit check
+// result and any pending exit mismatch, which apply to previous data.
 func (l *Loop) SetData(data models.PublicIP) {
 	l.state.SetData(data)
+	l.state.SetExitCheck(nil)
+	select {
+	case <-l.exitMismatches:
+	default:
+	}
+}
+
+// GetExitCheck returns the result of the last exit check,
+// or nil if no exit check was done.
+func (l *Loop) GetExitCheck() (check *models.ExitCheck) {
+	return l.state.GetExitCheck()
+}
+
+// ExitMismatches returns a channel receiving exit checks
+// which failed and for which the action is to restart the VPN.
+func (l *Loop) ExitMismatches() <-chan models.ExitCheck {
+	return l.exitMismatches
 }
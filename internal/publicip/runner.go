@@ -3,12 +3,11 @@ package publicip
 import (
 	"context"
 	"errors"
-	"net/netip"
 	"os"
 
 	"github.com/qdm12/gluetun/internal/constants"
 	"github.com/qdm12/gluetun/internal/models"
-	"github.com/qdm12/gluetun/internal/publicip/ipinfo"
+	"github.com/qdm12/gluetun/internal/publicip/api"
 )
 
 func (l *Loop) Run(ctx context.Context, done chan<- struct{}) {
@@ -27,14 +26,14 @@ func (l *Loop) Run(ctx context.Context, done chan<- struct{}) {
 		resultCh := make(chan models.PublicIP)
 		errorCh := make(chan error)
 		go func() {
-			result, err := l.fetcher.FetchInfo(getCtx, netip.Addr{})
+			result, err := l.fetchInfo(getCtx)
 			if err != nil {
 				if getCtx.Err() == nil {
 					errorCh <- err
 				}
 				return
 			}
-			resultCh <- result.ToPublicIPModel()
+			resultCh <- result
 		}()
 
 		if l.userTrigger {
@@ -73,17 +72,24 @@ func (l *Loop) Run(ctx context.Context, done chan<- struct{}) {
 				message := "Public IP address is " + result.IP.String()
 				message += " (" + result.Country + ", " + result.Region + ", " + result.City + ")"
 				l.logger.Info(message)
+				if result.IPv6 != nil {
+					message := "Public IPv6 address is " + result.IPv6.IP.String()
+					message += " (" + result.IPv6.Country + ", " + result.IPv6.Region +
+						", " + result.IPv6.City + ")"
+					l.logger.Info(message)
+				}
 
 				l.state.SetData(result)
+				l.enforceExit(result)
 
 				filepath := *l.state.GetSettings().IPFilepath
-				err := persistPublicIP(filepath, result.IP.String(), l.puid, l.pgid)
+				err := persistPublicIP(filepath, ipFileContent(result), l.puid, l.pgid)
 				if err != nil {
 					l.logger.Error(err.Error())
 				}
 				l.statusManager.SetStatus(constants.Completed)
 			case err := <-errorCh:
-				if errors.Is(err, ipinfo.ErrTooManyRequests) {
+				if errors.Is(err, api.ErrTooManyRequests) {
 					l.logger.Warn(err.Error())
 					l.statusManager.SetStatus(constants.Crashed)
 					break
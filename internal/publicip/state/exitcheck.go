@@ -0,0 +1,28 @@
+package state
+
+import (
+	"github.com/qdm12/gluetun/internal/models"
+)
+
+// GetExitCheck returns the last exit check result,
+// or nil if no exit check was done.
+func (s *State) GetExitCheck() (check *models.ExitCheck) {
+	s.ipDataMu.RLock()
+	defer s.ipDataMu.RUnlock()
+	if s.exitCheck == nil {
+		return nil
+	}
+	checkCopy := *s.exitCheck
+	return &checkCopy
+}
+
+func (s *State) SetExitCheck(check *models.ExitCheck) {
+	s.ipDataMu.Lock()
+	defer s.ipDataMu.Unlock()
+	if check == nil {
+		s.exitCheck = nil
+		return
+	}
+	checkCopy := *check
+	s.exitCheck = &checkCopy
+}
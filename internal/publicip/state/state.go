@@ -24,8 +24,9 @@ type State struct {
 	settings   settings.PublicIP
 	settingsMu sync.RWMutex
 
-	ipData   models.PublicIP
-	ipDataMu sync.RWMutex
+	ipData    models.PublicIP
+	exitCheck *models.ExitCheck
+	ipDataMu  sync.RWMutex
 
 	updateTicker chan<- struct{}
 }
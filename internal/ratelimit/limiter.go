@@ -0,0 +1,175 @@
+// Package ratelimit implements a token bucket limiter
+// to limit the bandwidth of data streams.
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket limiter where each token is a byte.
+// It is safe for concurrent use, so it can be shared by multiple
+// data streams to limit their combined bandwidth.
+type Limiter struct {
+	mutex sync.Mutex
+	// rate is the number of bytes allowed per second,
+	// and 0 if the limiter is unlimited.
+	rate    uint64
+	tokens  float64
+	last    time.Time
+	timeNow func() time.Time
+}
+
+// New creates a limiter allowing the number of bytes per
+// second given, with a burst size of one second worth of bytes.
+// A rate of 0 means no limit.
+func New(bytesPerSecond uint64) *Limiter {
+	return &Limiter{
+		rate:    bytesPerSecond,
+		tokens:  float64(bytesPerSecond),
+		timeNow: time.Now,
+	}
+}
+
+// SetRate changes the number of bytes per second allowed,
+// where 0 means no limit.
+func (l *Limiter) SetRate(bytesPerSecond uint64) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.refill()
+	l.rate = bytesPerSecond
+	if l.tokens > float64(bytesPerSecond) {
+		l.tokens = float64(bytesPerSecond)
+	}
+}
+
+// Rate returns the number of bytes per second allowed,
+// where 0 means no limit.
+func (l *Limiter) Rate() (bytesPerSecond uint64) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.rate
+}
+
+// burst returns the maximum number of bytes which can be
+// taken at once. It is not thread-safe.
+func (l *Limiter) burst() int {
+	const minBurst = 1024
+	if l.rate < minBurst {
+		return minBurst
+	}
+	return int(l.rate)
+}
+
+// refill adds tokens for the time elapsed since the last
+// refill. It is not thread-safe.
+func (l *Limiter) refill() {
+	now := l.timeNow()
+	if !l.last.IsZero() && l.rate > 0 {
+		elapsed := now.Sub(l.last).Seconds()
+		l.tokens += elapsed * float64(l.rate)
+		if l.tokens > float64(l.burst()) {
+			l.tokens = float64(l.burst())
+		}
+	}
+	l.last = now
+}
+
+// WaitN blocks until n bytes can be transferred, or until the
+// context is canceled. The number of bytes n can exceed the burst
+// size, for example if the rate is lowered during a read, in which
+// case a full bucket is required and the tokens missing are owed.
+func (l *Limiter) WaitN(ctx context.Context, n int) (err error) {
+	for {
+		l.mutex.Lock()
+		if l.rate == 0 {
+			l.mutex.Unlock()
+			return nil
+		}
+		l.refill()
+		required := n
+		if required > l.burst() {
+			required = l.burst()
+		}
+		if l.tokens >= float64(required) {
+			l.tokens -= float64(n)
+			l.mutex.Unlock()
+			return nil
+		}
+		// Wait for the missing tokens to be refilled, checking
+		// again after waiting since the rate can change meanwhile.
+		missing := float64(required) - l.tokens
+		wait := time.Duration(missing / float64(l.rate) * float64(time.Second))
+		l.mutex.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			if !timer.Stop() {
+				<-timer.C
+			}
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// AllowN takes n bytes from the limiter if they are available
+// and returns true, or returns false without blocking otherwise.
+// The number of bytes n can exceed the burst size, in which case
+// a full bucket is required and the tokens missing are owed.
+// It suits packets which should be dropped rather than delayed.
+func (l *Limiter) AllowN(n int) (allowed bool) {
+	return allowN(n, l)
+}
+
+// allowN takes n bytes from each of the limiters given only if
+// they are available in all of them, and returns true, or returns
+// false without taking any byte otherwise. Limiters sharing others
+// must always be given in the same order to avoid deadlocks.
+func allowN(n int, limiters ...*Limiter) (allowed bool) {
+	for _, limiter := range limiters {
+		limiter.mutex.Lock()
+		defer limiter.mutex.Unlock()
+	}
+
+	for _, limiter := range limiters {
+		if !limiter.available(n) {
+			return false
+		}
+	}
+
+	for _, limiter := range limiters {
+		if limiter.rate > 0 {
+			limiter.tokens -= float64(n)
+		}
+	}
+	return true
+}
+
+// available returns true if n bytes can be taken from the
+// limiter, where a full bucket is enough if n exceeds the
+// burst size. It is not thread-safe.
+func (l *Limiter) available(n int) bool {
+	if l.rate == 0 {
+		return true
+	}
+	l.refill()
+	required := n
+	if required > l.burst() {
+		required = l.burst()
+	}
+	return l.tokens >= float64(required)
+}
+
+// maxChunk returns the maximum number of bytes to
+// transfer at once for the limiter.
+func (l *Limiter) maxChunk() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.rate == 0 {
+		return 0
+	}
+	return l.burst()
+}
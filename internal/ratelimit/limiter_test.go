@@ -0,0 +1,123 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_NewReader(t *testing.T) {
+	t.Parallel()
+
+	t.Run("unlimited", func(t *testing.T) {
+		t.Parallel()
+
+		source := bytes.NewReader(make([]byte, 1000))
+		reader := NewReader(context.Background(), source, nil, New(0))
+
+		start := time.Now()
+		data, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Len(t, data, 1000)
+		assert.Less(t, time.Since(start), 100*time.Millisecond)
+	})
+
+	t.Run("limited", func(t *testing.T) {
+		t.Parallel()
+
+		const rate = 100000
+		limiter := New(rate)
+		// The first second worth of bytes is the burst,
+		// so reading 1.5 seconds worth of bytes takes 0.5s.
+		source := bytes.NewReader(make([]byte, rate*3/2))
+		reader := NewReader(context.Background(), source, limiter)
+
+		start := time.Now()
+		data, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Len(t, data, rate*3/2)
+		elapsed := time.Since(start)
+		assert.GreaterOrEqual(t, elapsed, 400*time.Millisecond)
+		assert.Less(t, elapsed, 1500*time.Millisecond)
+	})
+
+	t.Run("rate_lowered_during_read", func(t *testing.T) {
+		t.Parallel()
+
+		const rate = 100000
+		limiter := New(rate)
+		source := &blockingReader{
+			reader:  bytes.NewReader(make([]byte, rate)),
+			reading: make(chan struct{}),
+			release: make(chan struct{}),
+		}
+		reader := NewReader(context.Background(), source, limiter)
+
+		go func() {
+			<-source.reading
+			// The read chunk is now larger than the new burst size.
+			limiter.SetRate(1000)
+			close(source.release)
+		}()
+
+		done := make(chan struct{})
+		var n int
+		var err error
+		go func() {
+			n, err = reader.Read(make([]byte, rate))
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatal("read did not complete after lowering the rate")
+		}
+		require.NoError(t, err)
+		assert.Equal(t, rate, n)
+	})
+
+	t.Run("context_canceled", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		limiter := New(1)
+		source := bytes.NewReader(make([]byte, 10000))
+		reader := NewReader(ctx, source, limiter)
+
+		cancel()
+		_, err := io.ReadAll(reader)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+// blockingReader signals when its Read method is called
+// and blocks until it is released.
+type blockingReader struct {
+	reader  io.Reader
+	reading chan struct{}
+	release chan struct{}
+}
+
+func (r *blockingReader) Read(b []byte) (n int, err error) {
+	close(r.reading)
+	<-r.release
+	return r.reader.Read(b)
+}
+
+func Test_Limiter_SetRate(t *testing.T) {
+	t.Parallel()
+
+	limiter := New(100)
+	assert.Equal(t, uint64(100), limiter.Rate())
+
+	limiter.SetRate(0)
+	assert.Equal(t, uint64(0), limiter.Rate())
+	err := limiter.WaitN(context.Background(), 1000000)
+	assert.NoError(t, err)
+}
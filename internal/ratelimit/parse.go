@@ -0,0 +1,30 @@
+package ratelimit
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ParseRate parses a number of bytes per second, which can have
+// a k, m or g suffix for kibibytes, mebibytes and gibibytes.
+func ParseRate(s string) (bytesPerSecond uint64, err error) {
+	multiplier := uint64(1)
+	switch {
+	case strings.HasSuffix(s, "k"):
+		multiplier = 1 << 10 //nolint:gomnd
+	case strings.HasSuffix(s, "m"):
+		multiplier = 1 << 20 //nolint:gomnd
+	case strings.HasSuffix(s, "g"):
+		multiplier = 1 << 30 //nolint:gomnd
+	}
+	if multiplier > 1 {
+		s = s[:len(s)-1]
+	}
+
+	const base, bitSize = 10, 32
+	value, err := strconv.ParseUint(s, base, bitSize)
+	if err != nil {
+		return 0, err
+	}
+	return value * multiplier, nil
+}
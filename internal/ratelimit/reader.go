@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"context"
+	"io"
+)
+
+// NewReader returns a reader reading from the reader given at
+// the rate allowed by the limiters given, which can be nil.
+// Reads return the context error once the context is canceled.
+func NewReader(ctx context.Context, reader io.Reader,
+	limiters ...*Limiter) io.Reader {
+	nonNilLimiters := make([]*Limiter, 0, len(limiters))
+	for _, limiter := range limiters {
+		if limiter != nil {
+			nonNilLimiters = append(nonNilLimiters, limiter)
+		}
+	}
+	if len(nonNilLimiters) == 0 {
+		return reader
+	}
+	return &limitedReader{
+		ctx:      ctx,
+		reader:   reader,
+		limiters: nonNilLimiters,
+	}
+}
+
+type limitedReader struct {
+	ctx      context.Context //nolint:containedctx
+	reader   io.Reader
+	limiters []*Limiter
+}
+
+func (r *limitedReader) Read(b []byte) (n int, err error) {
+	for _, limiter := range r.limiters {
+		maxChunk := limiter.maxChunk()
+		if maxChunk > 0 && len(b) > maxChunk {
+			b = b[:maxChunk]
+		}
+	}
+
+	n, err = r.reader.Read(b)
+	if n == 0 {
+		return n, err
+	}
+
+	for _, limiter := range r.limiters {
+		waitErr := limiter.WaitN(r.ctx, n)
+		if waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
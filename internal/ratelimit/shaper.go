@@ -0,0 +1,319 @@
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Limits are the bandwidth limits of a shaper in bytes
+// per second, where 0 means no limit.
+type Limits struct {
+	// Upload is the limit for the bytes sent by all clients combined.
+	Upload uint64
+	// Download is the limit for the bytes received by all clients combined.
+	Download uint64
+	// ClientUpload is the limit for the bytes sent by each client.
+	ClientUpload uint64
+	// ClientDownload is the limit for the bytes received by each client.
+	ClientDownload uint64
+}
+
+// Throughput is the throughput of the data streams of a shaper,
+// in bytes per second, measured over the last second.
+type Throughput struct {
+	Upload   uint64 `json:"upload"`
+	Download uint64 `json:"download"`
+	// Clients is the throughput of each client with
+	// at least one stream, sorted by client address.
+	Clients []ClientThroughput `json:"clients"`
+}
+
+// ClientThroughput is the throughput of the data streams of
+// a client, in bytes per second, measured over the last second.
+type ClientThroughput struct {
+	Client string `json:"client"`
+	// Streams is the number of data streams of the client.
+	Streams  uint   `json:"streams"`
+	Upload   uint64 `json:"upload"`
+	Download uint64 `json:"download"`
+}
+
+// Shaper limits the bandwidth of data streams, both for all
+// clients combined and for each client, and measures their
+// throughput. Its limits can be changed while streams are
+// running. It is safe for concurrent use.
+type Shaper struct {
+	upload        *Limiter
+	download      *Limiter
+	uploadMeter   *meter
+	downloadMeter *meter
+	timeNow       func() time.Time
+
+	mutex   sync.Mutex
+	limits  Limits
+	clients map[string]*Client
+	// lastPrune is the time idle clients were last removed.
+	lastPrune time.Time
+}
+
+// clientIdleTimeout is the time a client is kept after its last
+// data stream is released, so a client opening streams one after
+// the other keeps using the same limiters instead of getting a full
+// bucket for each stream. It must be longer than the one second it
+// takes for an idle limiter to refill its bucket.
+const clientIdleTimeout = time.Minute
+
+func NewShaper(limits Limits) *Shaper {
+	return &Shaper{
+		upload:        New(limits.Upload),
+		download:      New(limits.Download),
+		uploadMeter:   &meter{},
+		downloadMeter: &meter{},
+		timeNow:       time.Now,
+		limits:        limits,
+		clients:       make(map[string]*Client),
+	}
+}
+
+// SetLimits changes the limits of the shaper, including
+// for the streams already running.
+func (s *Shaper) SetLimits(limits Limits) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.limits = limits
+	s.upload.SetRate(limits.Upload)
+	s.download.SetRate(limits.Download)
+	for _, client := range s.clients {
+		client.upload.SetRate(limits.ClientUpload)
+		client.download.SetRate(limits.ClientDownload)
+	}
+}
+
+// Limits returns the current limits of the shaper.
+func (s *Shaper) Limits() (limits Limits) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.limits
+}
+
+// Throughput returns the current throughput globally and per client.
+func (s *Shaper) Throughput() (throughput Throughput) {
+	now := s.timeNow()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	throughput = Throughput{
+		Upload:   s.uploadMeter.rate(now),
+		Download: s.downloadMeter.rate(now),
+		Clients:  make([]ClientThroughput, 0, len(s.clients)),
+	}
+	for _, client := range s.clients {
+		if client.streams == 0 {
+			continue
+		}
+		throughput.Clients = append(throughput.Clients, ClientThroughput{
+			Client:   client.address,
+			Streams:  client.streams,
+			Upload:   client.uploadMeter.rate(now),
+			Download: client.downloadMeter.rate(now),
+		})
+	}
+	sort.Slice(throughput.Clients, func(i, j int) bool {
+		return throughput.Clients[i].Client < throughput.Clients[j].Client
+	})
+	return throughput
+}
+
+// Client is the shaping of a client, shared by all its
+// data streams. A nil client does not limit nor measure
+// its data streams.
+type Client struct {
+	shaper        *Shaper
+	address       string
+	upload        *Limiter
+	download      *Limiter
+	uploadMeter   *meter
+	downloadMeter *meter
+	// streams is the number of streams acquired for the
+	// client, and is protected by the shaper mutex.
+	streams uint
+	// released is the time the last stream of the client was
+	// released, and is protected by the shaper mutex.
+	released time.Time
+}
+
+// Acquire registers a data stream for the client with the address
+// given, usually its IP address, and returns the client shaping.
+// The client must be released once the stream is done.
+// It returns nil if the shaper is nil.
+func (s *Shaper) Acquire(address string) (client *Client) {
+	if s == nil {
+		return nil
+	}
+
+	now := s.timeNow()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if now.Sub(s.lastPrune) > clientIdleTimeout {
+		for clientAddress, client := range s.clients {
+			if client.streams == 0 && now.Sub(client.released) > clientIdleTimeout {
+				delete(s.clients, clientAddress)
+			}
+		}
+		s.lastPrune = now
+	}
+
+	client, ok := s.clients[address]
+	if !ok {
+		client = &Client{
+			shaper:        s,
+			address:       address,
+			upload:        New(s.limits.ClientUpload),
+			download:      New(s.limits.ClientDownload),
+			uploadMeter:   &meter{},
+			downloadMeter: &meter{},
+		}
+		client.upload.timeNow = s.timeNow
+		client.download.timeNow = s.timeNow
+		s.clients[address] = client
+	}
+	client.streams++
+	return client
+}
+
+// Release unregisters a data stream acquired for the client.
+// The client limiters are kept for a while after its last
+// stream is released, in case it acquires a stream again.
+func (c *Client) Release() {
+	if c == nil {
+		return
+	}
+	now := c.shaper.timeNow()
+	c.shaper.mutex.Lock()
+	defer c.shaper.mutex.Unlock()
+	c.streams--
+	if c.streams == 0 {
+		c.released = now
+	}
+}
+
+// WrapUpload returns a reader limiting and measuring the
+// bytes read as bytes sent by the client.
+func (c *Client) WrapUpload(ctx context.Context, reader io.Reader) io.Reader {
+	if c == nil {
+		return reader
+	}
+	return &meteredReader{
+		reader:  NewReader(ctx, reader, c.shaper.upload, c.upload),
+		meters:  [2]*meter{c.shaper.uploadMeter, c.uploadMeter},
+		timeNow: c.shaper.timeNow,
+	}
+}
+
+// WrapDownload returns a reader limiting and measuring the
+// bytes read as bytes received by the client.
+func (c *Client) WrapDownload(ctx context.Context, reader io.Reader) io.Reader {
+	if c == nil {
+		return reader
+	}
+	return &meteredReader{
+		reader:  NewReader(ctx, reader, c.shaper.download, c.download),
+		meters:  [2]*meter{c.shaper.downloadMeter, c.downloadMeter},
+		timeNow: c.shaper.timeNow,
+	}
+}
+
+// AllowUpload returns true if a packet of n bytes sent by the
+// client is within the limits, and measures it. Packets not
+// allowed should be dropped.
+func (c *Client) AllowUpload(n int) (allowed bool) {
+	if c == nil {
+		return true
+	}
+	return c.allow(n, c.shaper.upload, c.upload,
+		c.shaper.uploadMeter, c.uploadMeter)
+}
+
+// AllowDownload returns true if a packet of n bytes received by
+// the client is within the limits, and measures it. Packets not
+// allowed should be dropped.
+func (c *Client) AllowDownload(n int) (allowed bool) {
+	if c == nil {
+		return true
+	}
+	return c.allow(n, c.shaper.download, c.download,
+		c.shaper.downloadMeter, c.downloadMeter)
+}
+
+func (c *Client) allow(n int, global, client *Limiter,
+	globalMeter, clientMeter *meter) (allowed bool) {
+	// Bytes are only taken if both limiters allow them, so a packet
+	// dropped by one limiter does not use up the other limiter.
+	if !allowN(n, global, client) {
+		return false
+	}
+	now := c.shaper.timeNow()
+	globalMeter.add(n, now)
+	clientMeter.add(n, now)
+	return true
+}
+
+type meteredReader struct {
+	reader  io.Reader
+	meters  [2]*meter
+	timeNow func() time.Time
+}
+
+func (r *meteredReader) Read(b []byte) (n int, err error) {
+	n, err = r.reader.Read(b)
+	if n > 0 {
+		now := r.timeNow()
+		for _, meter := range r.meters {
+			meter.add(n, now)
+		}
+	}
+	return n, err
+}
+
+// meter measures a throughput in bytes per second,
+// over the last complete second.
+type meter struct {
+	mutex sync.Mutex
+	// second is the Unix time in seconds of the current bucket.
+	second   int64
+	current  uint64
+	previous uint64
+}
+
+func (m *meter) add(n int, now time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.rotate(now.Unix())
+	m.current += uint64(n)
+}
+
+func (m *meter) rate(now time.Time) (bytesPerSecond uint64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.rotate(now.Unix())
+	return m.previous
+}
+
+// rotate moves the buckets forward to the second given.
+// It is not thread-safe.
+func (m *meter) rotate(second int64) {
+	switch second - m.second {
+	case 0:
+		return
+	case 1:
+		m.previous = m.current
+	default:
+		m.previous = 0
+	}
+	m.current = 0
+	m.second = second
+}
@@ -0,0 +1,241 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Shaper(t *testing.T) {
+	t.Parallel()
+
+	shaper := NewShaper(Limits{ClientUpload: 1000})
+	now := time.Unix(1000, 0)
+	shaper.timeNow = func() time.Time { return now }
+
+	alice := shaper.Acquire("1.2.3.4")
+	aliceAgain := shaper.Acquire("1.2.3.4")
+	assert.Same(t, alice, aliceAgain)
+	bob := shaper.Acquire("5.6.7.8")
+	alice.upload.timeNow = shaper.timeNow
+
+	reader := alice.WrapUpload(context.Background(), bytes.NewReader(make([]byte, 600)))
+	_, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	reader = bob.WrapDownload(context.Background(), bytes.NewReader(make([]byte, 300)))
+	_, err = io.ReadAll(reader)
+	require.NoError(t, err)
+
+	// The burst of the client upload limiter is used up.
+	assert.True(t, alice.AllowUpload(400))
+	assert.False(t, alice.AllowUpload(1))
+	assert.True(t, bob.AllowUpload(1))
+
+	// Throughput is measured over the last complete second.
+	assert.Equal(t, Throughput{Clients: []ClientThroughput{
+		{Client: "1.2.3.4", Streams: 2},
+		{Client: "5.6.7.8", Streams: 1},
+	}}, shaper.Throughput())
+	now = now.Add(time.Second)
+	expected := Throughput{
+		Upload:   1001,
+		Download: 300,
+		Clients: []ClientThroughput{
+			{Client: "1.2.3.4", Streams: 2, Upload: 1000},
+			{Client: "5.6.7.8", Streams: 1, Upload: 1, Download: 300},
+		},
+	}
+	assert.Equal(t, expected, shaper.Throughput())
+
+	alice.Release()
+	bob.Release()
+	now = now.Add(2 * time.Second)
+	expected = Throughput{Clients: []ClientThroughput{
+		{Client: "1.2.3.4", Streams: 1},
+	}}
+	assert.Equal(t, expected, shaper.Throughput())
+
+	limits := Limits{Upload: 10, ClientDownload: 20}
+	shaper.SetLimits(limits)
+	assert.Equal(t, limits, shaper.Limits())
+	assert.Equal(t, uint64(0), alice.upload.Rate())
+	assert.Equal(t, uint64(20), alice.download.Rate())
+	assert.Equal(t, uint64(10), shaper.upload.Rate())
+
+	aliceAgain.Release()
+	assert.Empty(t, shaper.Throughput().Clients)
+}
+
+func Test_Shaper_backToBackStreams(t *testing.T) {
+	t.Parallel()
+
+	shaper := NewShaper(Limits{ClientUpload: 1000})
+	now := time.Unix(1000, 0)
+	shaper.timeNow = func() time.Time { return now }
+
+	// A client making requests one at a time, each within
+	// the burst size, shares its limiter across requests.
+	for i := 0; i < 3; i++ {
+		client := shaper.Acquire("1.2.3.4")
+		allowed := client.AllowUpload(600)
+		client.Release()
+		assert.Equal(t, i == 0, allowed, "request %d", i)
+		now = now.Add(50 * time.Millisecond)
+	}
+
+	client := shaper.Acquire("1.2.3.4")
+	assert.False(t, client.AllowUpload(600))
+	now = now.Add(time.Second)
+	assert.True(t, client.AllowUpload(600))
+	client.Release()
+
+	// Idle clients are removed once the idle timeout elapsed.
+	now = now.Add(clientIdleTimeout + time.Second)
+	other := shaper.Acquire("5.6.7.8")
+	assert.Len(t, shaper.clients, 1)
+	other.Release()
+}
+
+func Test_Shaper_SetLimits_duringRead(t *testing.T) {
+	t.Parallel()
+
+	const rate = 100000
+	shaper := NewShaper(Limits{Download: rate, ClientDownload: rate})
+	client := shaper.Acquire("1.2.3.4")
+	defer client.Release()
+
+	source := &blockingReader{
+		reader:  bytes.NewReader(make([]byte, rate)),
+		reading: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	reader := client.WrapDownload(context.Background(), source)
+
+	go func() {
+		<-source.reading
+		// The read chunk is now larger than the new burst sizes.
+		shaper.SetLimits(Limits{Download: 1000, ClientDownload: 500})
+		close(source.release)
+	}()
+
+	done := make(chan struct{})
+	var n int
+	var err error
+	go func() {
+		n, err = reader.Read(make([]byte, rate))
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("read did not complete after lowering the limits")
+	}
+	require.NoError(t, err)
+	assert.Equal(t, rate, n)
+}
+
+func Test_Client_allow(t *testing.T) {
+	t.Parallel()
+
+	shaper := NewShaper(Limits{Upload: 2000, ClientUpload: 1000})
+	now := time.Unix(1000, 0)
+	shaper.timeNow = func() time.Time { return now }
+	shaper.upload.timeNow = shaper.timeNow
+	alice := shaper.Acquire("1.2.3.4")
+	defer alice.Release()
+	alice.upload.timeNow = shaper.timeNow
+	bob := shaper.Acquire("5.6.7.8")
+	defer bob.Release()
+	bob.upload.timeNow = shaper.timeNow
+	carol := shaper.Acquire("9.9.9.9")
+	defer carol.Release()
+	carol.upload.timeNow = shaper.timeNow
+
+	// Bob and Carol use up the global limit.
+	assert.True(t, bob.AllowUpload(1000))
+	assert.True(t, carol.AllowUpload(1000))
+
+	// Alice's packets dropped by the global limiter
+	// must not take from her own client limiter.
+	assert.False(t, alice.AllowUpload(600))
+	assert.False(t, alice.AllowUpload(600))
+	now = now.Add(500 * time.Millisecond)
+	assert.True(t, alice.AllowUpload(1000))
+}
+
+func Test_Client_nil(t *testing.T) {
+	t.Parallel()
+
+	var shaper *Shaper
+	client := shaper.Acquire("1.2.3.4")
+	defer client.Release()
+
+	source := bytes.NewReader(nil)
+	assert.Same(t, source, client.WrapUpload(context.Background(), source))
+	assert.True(t, client.AllowDownload(1))
+}
+
+func Test_Limiter_AllowN(t *testing.T) {
+	t.Parallel()
+
+	limiter := New(2000)
+	now := time.Unix(1000, 0)
+	limiter.timeNow = func() time.Time { return now }
+
+	assert.True(t, limiter.AllowN(1500))
+	assert.False(t, limiter.AllowN(1000))
+
+	// A packet larger than the burst needs a full bucket.
+	now = now.Add(time.Second)
+	assert.True(t, limiter.AllowN(5000))
+	now = now.Add(time.Second)
+	assert.False(t, limiter.AllowN(1))
+
+	limiter.SetRate(0)
+	assert.True(t, limiter.AllowN(100000))
+}
+
+func Test_ParseRate(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		s              string
+		bytesPerSecond uint64
+		errMessage     string
+	}{
+		"bytes": {
+			s:              "1000",
+			bytesPerSecond: 1000,
+		},
+		"mebibytes": {
+			s:              "2m",
+			bytesPerSecond: 2 << 20,
+		},
+		"malformed": {
+			s:          "2mb",
+			errMessage: `strconv.ParseUint: parsing "2mb": invalid syntax`,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			bytesPerSecond, err := ParseRate(testCase.s)
+
+			assert.Equal(t, testCase.bytesPerSecond, bytesPerSecond)
+			if testCase.errMessage != "" {
+				assert.EqualError(t, err, testCase.errMessage)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package reload
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+)
+
+// apply applies the sections of the new settings which changed
+// to the running loops, and logs the changed sections which
+// require a full restart. It returns true if any section changed.
+func (r *Reloader) apply(ctx context.Context, newSettings settings.Settings) (changed bool) {
+	current := r.runningSettings()
+
+	type section struct {
+		name     string
+		old, new fmt.Stringer
+		apply    func() (outcome string)
+	}
+	sections := []section{
+		{name: "DNS", old: current.DNS, new: newSettings.DNS, apply: func() string {
+			return r.loops.DNS.SetSettings(ctx, newSettings.DNS)
+		}},
+		{name: "HTTP proxy", old: current.HTTPProxy, new: newSettings.HTTPProxy, apply: func() string {
+			return r.loops.HTTPProxy.SetSettings(ctx, newSettings.HTTPProxy)
+		}},
+		{name: "Shadowsocks", old: current.Shadowsocks, new: newSettings.Shadowsocks, apply: func() string {
+			return r.loops.Shadowsocks.SetSettings(ctx, newSettings.Shadowsocks)
+		}},
+		{name: "bandwidth shaping", old: current.Shaping, new: newSettings.Shaping, apply: func() string {
+			r.loops.Shaper.SetLimits(newSettings.Shaping.Limits())
+			return "limits updated"
+		}},
+		{
+			name: "port forwarding",
+			old:  current.VPN.Provider.PortForwarding,
+			new:  newSettings.VPN.Provider.PortForwarding,
+			apply: func() string {
+				return r.loops.PortForward.SetSettings(ctx, newSettings.VPN.Provider.PortForwarding)
+			},
+		},
+		{name: "public IP", old: current.PublicIP, new: newSettings.PublicIP, apply: func() string {
+			return r.loops.PublicIP.SetSettings(ctx, newSettings.PublicIP)
+		}},
+		{name: "updater", old: current.Updater, new: newSettings.Updater, apply: func() string {
+			return r.loops.Updater.SetSettings(newSettings.Updater)
+		}},
+		{name: "firewall", old: current.Firewall, new: newSettings.Firewall, apply: func() string {
+			return r.applyFirewall(ctx, current.Firewall, newSettings.Firewall)
+		}},
+	}
+
+	for _, section := range sections {
+		if reflect.DeepEqual(section.old, section.new) {
+			continue
+		}
+		changed = true
+		r.logger.Info(section.name + " settings changed:\n" +
+			diffLines(section.old.String(), section.new.String()))
+		outcome := section.apply()
+		if outcome != "" {
+			r.logger.Info(section.name + " settings applied: " + outcome)
+		}
+	}
+
+	restartSections := restartRequiredChanges(current, newSettings)
+	if len(restartSections) > 0 {
+		changed = true
+		r.logger.Warn("settings changed which require a full restart to apply: " +
+			strings.Join(restartSections, ", "))
+	}
+
+	r.current = newSettings
+	return changed
+}
+
+// runningSettings returns the settings last read, with the sections
+// applied at runtime replaced by the settings of the running loops,
+// since these can also be changed through the control server.
+func (r *Reloader) runningSettings() (running settings.Settings) {
+	running = r.current
+	running.DNS = r.loops.DNS.GetSettings()
+	running.HTTPProxy = r.loops.HTTPProxy.GetSettings()
+	running.Shadowsocks = r.loops.Shadowsocks.GetSettings()
+	running.Shaping = settings.ShapingFromLimits(r.loops.Shaper.Limits())
+	running.VPN.Provider.PortForwarding = r.loops.PortForward.GetSettings()
+	running.PublicIP = r.loops.PublicIP.GetSettings()
+	running.Updater = r.loops.Updater.GetSettings()
+	return running
+}
+
+// restartRequiredChanges returns the names of the changed settings
+// sections which cannot be applied at runtime.
+func restartRequiredChanges(current, newSettings settings.Settings) (names []string) {
+	// Port forwarding is applied at runtime, so ignore it for the VPN comparison.
+	currentVPN := current.VPN.Copy()
+	newVPN := newSettings.VPN.Copy()
+	newVPN.Provider.PortForwarding = currentVPN.Provider.PortForwarding
+
+	nameToChanged := []struct {
+		name    string
+		changed bool
+	}{
+		{name: "VPN", changed: !reflect.DeepEqual(currentVPN, newVPN)},
+		{name: "firewall VPN input ports", changed: !reflect.DeepEqual(
+			current.Firewall.VPNInputPorts, newSettings.Firewall.VPNInputPorts)},
+		{name: "firewall debug", changed: !reflect.DeepEqual(
+			current.Firewall.Debug, newSettings.Firewall.Debug)},
+		{name: "firewall gateway", changed: !reflect.DeepEqual(
+			current.Firewall.Gateway, newSettings.Firewall.Gateway)},
+		{name: "control server", changed: !reflect.DeepEqual(current.ControlServer, newSettings.ControlServer)},
+		{name: "health", changed: !reflect.DeepEqual(current.Health, newSettings.Health)},
+		{name: "log", changed: !reflect.DeepEqual(current.Log, newSettings.Log)},
+		{name: "system", changed: !reflect.DeepEqual(current.System, newSettings.System)},
+		{name: "version", changed: !reflect.DeepEqual(current.Version, newSettings.Version)},
+		{name: "Wireguard server", changed: !reflect.DeepEqual(current.WireguardServer, newSettings.WireguardServer)},
+		{name: "pprof", changed: !reflect.DeepEqual(current.Pprof, newSettings.Pprof)},
+	}
+
+	for _, element := range nameToChanged {
+		if element.changed {
+			names = append(names, element.name)
+		}
+	}
+	return names
+}
+
+// applyFirewall applies the firewall enabled state, outbound subnets
+// and input ports changes. Other firewall changes require a restart.
+func (r *Reloader) applyFirewall(ctx context.Context,
+	current, newSettings settings.Firewall) (outcome string) {
+	var errorMessages []string
+	applied := false
+
+	if *current.Enabled != *newSettings.Enabled {
+		applied = true
+		err := r.firewall.SetEnabled(ctx, *newSettings.Enabled)
+		if err != nil {
+			errorMessages = append(errorMessages, "setting enabled state: "+err.Error())
+		}
+	}
+
+	if !reflect.DeepEqual(current.OutboundSubnets, newSettings.OutboundSubnets) {
+		applied = true
+		err := r.firewall.SetOutboundSubnets(ctx, newSettings.OutboundSubnets)
+		if err != nil {
+			errorMessages = append(errorMessages, "setting outbound subnets: "+err.Error())
+		}
+		err = r.routing.SetOutboundRoutes(newSettings.OutboundSubnets)
+		if err != nil {
+			errorMessages = append(errorMessages, "setting outbound routes: "+err.Error())
+		}
+		r.loops.HTTPProxy.SetOutboundSubnets(newSettings.OutboundSubnets)
+	}
+
+	newPorts := make(map[uint16]struct{}, len(newSettings.InputPorts))
+	for _, port := range newSettings.InputPorts {
+		newPorts[port] = struct{}{}
+	}
+	currentPorts := make(map[uint16]struct{}, len(current.InputPorts))
+	for _, port := range current.InputPorts {
+		currentPorts[port] = struct{}{}
+		if _, keep := newPorts[port]; keep {
+			continue
+		}
+		applied = true
+		err := r.firewall.RemoveAllowedPort(ctx, port)
+		if err != nil {
+			errorMessages = append(errorMessages, "removing input port: "+err.Error())
+		}
+	}
+	for _, port := range newSettings.InputPorts {
+		if _, exists := currentPorts[port]; exists {
+			continue
+		}
+		applied = true
+		for _, intf := range r.defaultInterfaces {
+			err := r.firewall.SetAllowedPort(ctx, port, intf)
+			if err != nil {
+				errorMessages = append(errorMessages, "adding input port: "+err.Error())
+			}
+		}
+	}
+
+	switch {
+	case len(errorMessages) > 0:
+		return "failed: " + strings.Join(errorMessages, "; ")
+	case !applied:
+		return ""
+	default:
+		return "applied"
+	}
+}
+
+// diffLines returns the lines removed from the old string prefixed
+// with `- ` and the lines added to the new string prefixed with `+ `.
+func diffLines(old, new string) (diff string) {
+	oldLines := strings.Split(old, "\n")
+	newLines := strings.Split(new, "\n")
+	oldSet := make(map[string]struct{}, len(oldLines))
+	for _, line := range oldLines {
+		oldSet[line] = struct{}{}
+	}
+	newSet := make(map[string]struct{}, len(newLines))
+	for _, line := range newLines {
+		newSet[line] = struct{}{}
+	}
+
+	var diffs []string
+	for _, line := range oldLines {
+		if _, ok := newSet[line]; !ok {
+			diffs = append(diffs, "- "+strings.TrimSpace(trimTreePrefix(line)))
+		}
+	}
+	for _, line := range newLines {
+		if _, ok := oldSet[line]; !ok {
+			diffs = append(diffs, "+ "+strings.TrimSpace(trimTreePrefix(line)))
+		}
+	}
+	return strings.Join(diffs, "\n")
+}
+
+// trimTreePrefix removes the gotree indentation and branch
+// characters from the start of the line given.
+func trimTreePrefix(line string) string {
+	return strings.TrimLeft(line, "|├└─ ")
+}
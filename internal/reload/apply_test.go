@@ -0,0 +1,176 @@
+package reload
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/qdm12/gluetun/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeLoop is a loop keeping the settings it is given,
+// for each settings type used by the reloader loops.
+type fakeLoop[T any] struct {
+	settings T
+	applied  int
+}
+
+func (f *fakeLoop[T]) GetSettings() T { return f.settings }
+
+func (f *fakeLoop[T]) set(settings T) (outcome string) {
+	f.settings = settings
+	f.applied++
+	return "applied"
+}
+
+type fakeDNSLoop struct{ fakeLoop[settings.DNS] }
+
+func (f *fakeDNSLoop) SetSettings(_ context.Context, s settings.DNS) string { return f.set(s) }
+
+type fakeHTTPProxyLoop struct{ fakeLoop[settings.HTTPProxy] }
+
+func (f *fakeHTTPProxyLoop) SetSettings(_ context.Context, s settings.HTTPProxy) string {
+	return f.set(s)
+}
+
+func (f *fakeHTTPProxyLoop) SetOutboundSubnets([]netip.Prefix) {}
+
+type fakeShadowsocksLoop struct{ fakeLoop[settings.Shadowsocks] }
+
+func (f *fakeShadowsocksLoop) SetSettings(_ context.Context, s settings.Shadowsocks) string {
+	return f.set(s)
+}
+
+type fakePortForwardLoop struct{ fakeLoop[settings.PortForwarding] }
+
+func (f *fakePortForwardLoop) SetSettings(_ context.Context, s settings.PortForwarding) string {
+	return f.set(s)
+}
+
+type fakePublicIPLoop struct{ fakeLoop[settings.PublicIP] }
+
+func (f *fakePublicIPLoop) SetSettings(_ context.Context, s settings.PublicIP) string {
+	return f.set(s)
+}
+
+type fakeUpdaterLoop struct{ fakeLoop[settings.Updater] }
+
+func (f *fakeUpdaterLoop) SetSettings(s settings.Updater) string { return f.set(s) }
+
+type fakeLogger struct{}
+
+func (fakeLogger) Info(string)  {}
+func (fakeLogger) Warn(string)  {}
+func (fakeLogger) Error(string) {}
+
+func Test_Reloader_apply(t *testing.T) {
+	t.Parallel()
+
+	var fileSettings settings.Settings
+	fileSettings.SetDefaults()
+
+	dns := &fakeDNSLoop{fakeLoop[settings.DNS]{settings: fileSettings.DNS}}
+	httpProxy := &fakeHTTPProxyLoop{fakeLoop[settings.HTTPProxy]{settings: fileSettings.HTTPProxy}}
+	shadowsocks := &fakeShadowsocksLoop{fakeLoop[settings.Shadowsocks]{settings: fileSettings.Shadowsocks}}
+	portForward := &fakePortForwardLoop{fakeLoop[settings.PortForwarding]{
+		settings: fileSettings.VPN.Provider.PortForwarding,
+	}}
+	publicIP := &fakePublicIPLoop{fakeLoop[settings.PublicIP]{settings: fileSettings.PublicIP}}
+	updater := &fakeUpdaterLoop{fakeLoop[settings.Updater]{settings: fileSettings.Updater}}
+	reloader := &Reloader{
+		current: fileSettings,
+		loops: Loops{
+			DNS:         dns,
+			HTTPProxy:   httpProxy,
+			Shadowsocks: shadowsocks,
+			Shaper:      ratelimit.NewShaper(fileSettings.Shaping.Limits()),
+			PortForward: portForward,
+			PublicIP:    publicIP,
+			Updater:     updater,
+		},
+		logger: fakeLogger{},
+	}
+
+	// The HTTP proxy is enabled through the control server.
+	apiSettings := fileSettings.HTTPProxy
+	enabled := true
+	apiSettings.Enabled = &enabled
+	httpProxy.settings = apiSettings
+
+	// Reading file settings matching the settings applied
+	// through the control server applies nothing.
+	newFileSettings := fileSettings
+	newFileSettings.HTTPProxy = apiSettings
+	changed := reloader.apply(context.Background(), newFileSettings)
+	assert.False(t, changed)
+	assert.Equal(t, 0, httpProxy.applied)
+
+	// Reading file settings differing from the settings
+	// applied through the control server applies them.
+	changed = reloader.apply(context.Background(), fileSettings)
+	assert.True(t, changed)
+	assert.Equal(t, 1, httpProxy.applied)
+	assert.False(t, *httpProxy.settings.Enabled)
+}
+
+func Test_restartRequiredChanges(t *testing.T) {
+	t.Parallel()
+
+	makeSettings := func() settings.Settings {
+		var s settings.Settings
+		s.SetDefaults()
+		return s
+	}
+
+	testCases := map[string]struct {
+		modify func(s *settings.Settings)
+		names  []string
+	}{
+		"no change": {
+			modify: func(s *settings.Settings) {},
+		},
+		"runtime changes only": {
+			modify: func(s *settings.Settings) {
+				*s.VPN.Provider.PortForwarding.Enabled = true
+				*s.HTTPProxy.Enabled = true
+				s.Firewall.InputPorts = []uint16{1000}
+			},
+		},
+		"restart required changes": {
+			modify: func(s *settings.Settings) {
+				s.VPN.Type = "wireguard"
+				*s.Firewall.Debug = true
+				s.Health.TargetAddress = "example.com:443"
+			},
+			names: []string{"VPN", "firewall debug", "health"},
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			current := makeSettings()
+			newSettings := makeSettings()
+			testCase.modify(&newSettings)
+
+			names := restartRequiredChanges(current, newSettings)
+
+			assert.Equal(t, testCase.names, names)
+		})
+	}
+}
+
+func Test_diffLines(t *testing.T) {
+	t.Parallel()
+
+	old := "Settings:\n├── Enabled: no\n└── Port: 8888"
+	new := "Settings:\n├── Enabled: yes\n└── Port: 8888"
+
+	diff := diffLines(old, new)
+
+	assert.Equal(t, "- Enabled: no\n+ Enabled: yes", diff)
+}
@@ -0,0 +1,44 @@
+package reload
+
+import (
+	"os"
+	"time"
+)
+
+type fileState struct {
+	exists  bool
+	modTime time.Time
+	size    int64
+}
+
+func statFiles(paths []string) (pathToState map[string]fileState) {
+	pathToState = make(map[string]fileState, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			pathToState[path] = fileState{}
+			continue
+		}
+		pathToState[path] = fileState{
+			exists:  true,
+			modTime: info.ModTime(),
+			size:    info.Size(),
+		}
+	}
+	return pathToState
+}
+
+func (f fileState) equal(other fileState) bool {
+	return f.exists == other.exists &&
+		f.modTime.Equal(other.modTime) &&
+		f.size == other.size
+}
+
+func firstChanged(oldStates, newStates map[string]fileState) (path string, changed bool) {
+	for path, newState := range newStates {
+		if !oldStates[path].equal(newState) {
+			return path, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,38 @@
+package reload
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_firstChanged(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	paths := []string{path}
+
+	initial := statFiles(paths)
+
+	err := os.WriteFile(path, []byte("a: b"), 0600)
+	require.NoError(t, err)
+	created := statFiles(paths)
+	changedPath, changed := firstChanged(initial, created)
+	assert.True(t, changed)
+	assert.Equal(t, path, changedPath)
+
+	_, changed = firstChanged(created, statFiles(paths))
+	assert.False(t, changed)
+
+	later := time.Now().Add(time.Minute)
+	err = os.Chtimes(path, later, later)
+	require.NoError(t, err)
+	changedPath, changed = firstChanged(created, statFiles(paths))
+	assert.True(t, changed)
+	assert.Equal(t, path, changedPath)
+}
@@ -0,0 +1,72 @@
+package reload
+
+import (
+	"context"
+	"net/netip"
+
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/qdm12/gluetun/internal/ratelimit"
+)
+
+type Source interface {
+	Read() (settings settings.Settings, err error)
+}
+
+// FilepathsSource is a source reading files, which
+// are watched for changes to trigger a reload.
+type FilepathsSource interface {
+	Filepaths() (paths []string)
+}
+
+type DNSLoop interface {
+	GetSettings() (settings settings.DNS)
+	SetSettings(ctx context.Context, settings settings.DNS) (outcome string)
+}
+
+type HTTPProxyLoop interface {
+	GetSettings() (settings settings.HTTPProxy)
+	SetSettings(ctx context.Context, settings settings.HTTPProxy) (outcome string)
+	SetOutboundSubnets(subnets []netip.Prefix)
+}
+
+type ShadowsocksLoop interface {
+	GetSettings() (settings settings.Shadowsocks)
+	SetSettings(ctx context.Context, settings settings.Shadowsocks) (outcome string)
+}
+
+type Shaper interface {
+	Limits() (limits ratelimit.Limits)
+	SetLimits(limits ratelimit.Limits)
+}
+
+type PortForwardLoop interface {
+	GetSettings() (settings settings.PortForwarding)
+	SetSettings(ctx context.Context, settings settings.PortForwarding) (outcome string)
+}
+
+type PublicIPLoop interface {
+	GetSettings() (settings settings.PublicIP)
+	SetSettings(ctx context.Context, settings settings.PublicIP) (outcome string)
+}
+
+type UpdaterLoop interface {
+	GetSettings() (settings settings.Updater)
+	SetSettings(settings settings.Updater) (outcome string)
+}
+
+type Firewall interface {
+	SetEnabled(ctx context.Context, enabled bool) (err error)
+	SetOutboundSubnets(ctx context.Context, subnets []netip.Prefix) (err error)
+	SetAllowedPort(ctx context.Context, port uint16, intf string) (err error)
+	RemoveAllowedPort(ctx context.Context, port uint16) (err error)
+}
+
+type Routing interface {
+	SetOutboundRoutes(outboundSubnets []netip.Prefix) error
+}
+
+type Logger interface {
+	Info(s string)
+	Warn(s string)
+	Error(s string)
+}
@@ -0,0 +1,114 @@
+package reload
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+)
+
+// Loops are the loops to which changed settings are applied.
+type Loops struct {
+	DNS         DNSLoop
+	HTTPProxy   HTTPProxyLoop
+	Shadowsocks ShadowsocksLoop
+	Shaper      Shaper
+	PortForward PortForwardLoop
+	PublicIP    PublicIPLoop
+	Updater     UpdaterLoop
+}
+
+// Reloader reloads the settings from its source when one of the
+// watched files changes or when a hangup signal is received, and
+// applies the settings sections which changed to the running loops.
+type Reloader struct {
+	source            Source
+	storage           settings.Storage
+	ipv6Supported     bool
+	current           settings.Settings
+	loops             Loops
+	firewall          Firewall
+	routing           Routing
+	defaultInterfaces []string
+	watchedPaths      []string
+	hangup            <-chan os.Signal
+	period            time.Duration
+	logger            Logger
+}
+
+func New(currentSettings settings.Settings, source Source,
+	storage settings.Storage, ipv6Supported bool, loops Loops,
+	firewall Firewall, routing Routing, defaultInterfaces []string,
+	watchedPaths []string, hangup <-chan os.Signal, logger Logger) *Reloader {
+	const period = 5 * time.Second
+	return &Reloader{
+		source:            source,
+		storage:           storage,
+		ipv6Supported:     ipv6Supported,
+		current:           currentSettings,
+		loops:             loops,
+		firewall:          firewall,
+		routing:           routing,
+		defaultInterfaces: defaultInterfaces,
+		watchedPaths:      watchedPaths,
+		hangup:            hangup,
+		period:            period,
+		logger:            logger,
+	}
+}
+
+// Run polls the watched files for changes and listens for hangup
+// signals, reloading the settings accordingly, until the context
+// is canceled.
+func (r *Reloader) Run(ctx context.Context, done chan<- struct{}) {
+	defer close(done)
+
+	fileStates := statFiles(r.watchedPaths)
+	ticker := time.NewTicker(r.period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.hangup:
+			r.logger.Info("hangup signal received, reloading settings")
+			fileStates = statFiles(r.watchedPaths)
+			r.reload(ctx)
+		case <-ticker.C:
+			newFileStates := statFiles(r.watchedPaths)
+			changedPath, changed := firstChanged(fileStates, newFileStates)
+			fileStates = newFileStates
+			if !changed {
+				continue
+			}
+			r.logger.Info("file " + changedPath + " changed, reloading settings")
+			r.reload(ctx)
+		}
+	}
+}
+
+func (r *Reloader) reload(ctx context.Context) {
+	newSettings, err := r.source.Read()
+	if err != nil {
+		r.logger.Error("reading settings: " + err.Error())
+		return
+	}
+
+	// Carry over settings adjusted at program start.
+	newSettings.DNS.DoT.Unbound.Username = r.current.DNS.DoT.Unbound.Username
+	newSettings.VPN.OpenVPN.ProcessUser = r.current.VPN.OpenVPN.ProcessUser
+	newSettings.Pprof.HTTPServer.Logger = r.current.Pprof.HTTPServer.Logger
+
+	err = newSettings.Validate(r.storage, r.ipv6Supported)
+	if err != nil {
+		r.logger.Error("new settings are not valid, keeping current settings: " + err.Error())
+		return
+	}
+
+	changed := r.apply(ctx, newSettings)
+	if !changed {
+		r.logger.Info("no settings change detected")
+	}
+}
@@ -0,0 +1,36 @@
+package routing
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+)
+
+// EnableForwarding enables IP packets forwarding in the kernel,
+// for IPv6 as well if ipv6 is true. Note /proc/sys is usually
+// read-only in containers, so forwarding may have to be enabled
+// with sysctls on the container instead, in which case no write
+// is attempted.
+func (r *Routing) EnableForwarding(ipv6 bool) (err error) {
+	paths := []string{"/proc/sys/net/ipv4/ip_forward"}
+	if ipv6 {
+		paths = append(paths, "/proc/sys/net/ipv6/conf/all/forwarding")
+	}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading forwarding state: %w", err)
+		} else if string(bytes.TrimSpace(data)) == "1" {
+			continue
+		}
+
+		r.logger.Debug("writing 1 to " + path)
+		const perm = 0o644
+		err = os.WriteFile(path, []byte("1"), perm)
+		if err != nil {
+			return fmt.Errorf("enabling forwarding: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+
+	"github.com/qdm12/gluetun/internal/netlink"
+)
+
+// TunnelRouting contains the policy routing information
+// of an additional VPN tunnel.
+type TunnelRouting struct {
+	// Table is the routing table of the tunnel, which must contain
+	// a default route through the tunnel interface if Interface is
+	// not set. It is also the firewall mark of the tunnel encrypted
+	// packets.
+	Table int
+	// Interface is the tunnel interface, to set for tunnels not adding
+	// their own default route to the table, such as OpenVPN tunnels.
+	// If set, the IPv4 default route through the interface is added
+	// to the table, and traffic from the interface IPv4 address is
+	// routed through the tunnel.
+	Interface string
+	// Endpoint is the tunnel server IP address.
+	Endpoint netip.Addr
+	// Sources are the source subnets to route through the tunnel.
+	Sources []netip.Prefix
+	// Destinations are the destination subnets to route through the tunnel.
+	Destinations []netip.Prefix
+}
+
+// Tunnel rules are after local (98) and outbound (99) rules,
+// at the same priority as inbound rules, and before the main
+// VPN rule (101).
+const tunnelPriority = 100
+
+// SetupTunnel adds routing rules and routes for an additional tunnel,
+// such that traffic from its sources and to its destinations goes through
+// it, and its encrypted packets go through the default route. It returns
+// a cleanup function to remove these rules and routes.
+func (r *Routing) SetupTunnel(tunnel TunnelRouting) (cleanup func() error, err error) {
+	defaultRoutes, err := r.DefaultRoutes()
+	if err != nil {
+		return nil, err
+	}
+
+	var cleanups []func() error
+	cleanup = func() error {
+		var errs []error
+		for i := len(cleanups) - 1; i >= 0; i-- {
+			err := cleanups[i]()
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	}
+
+	defer func() {
+		if err == nil {
+			return
+		}
+		cleanupErr := cleanup()
+		if cleanupErr != nil {
+			r.logger.Error("cleaning up tunnel routing: " + cleanupErr.Error())
+		}
+	}()
+
+	family := netlink.FamilyV4
+	if tunnel.Endpoint.Is6() {
+		family = netlink.FamilyV6
+	}
+
+	endpointPrefix := netip.PrefixFrom(tunnel.Endpoint, tunnel.Endpoint.BitLen())
+	endpointRouted := false
+	for _, defaultRoute := range defaultRoutes {
+		if defaultRoute.Family != family {
+			continue
+		}
+		err = r.addRouteVia(endpointPrefix, defaultRoute.Gateway,
+			defaultRoute.NetInterface, tunnel.Table)
+		if err != nil {
+			return nil, fmt.Errorf("adding route to tunnel endpoint: %w", err)
+		}
+		defaultRoute := defaultRoute
+		cleanups = append(cleanups, func() error {
+			return r.deleteRouteVia(endpointPrefix, defaultRoute.Gateway,
+				defaultRoute.NetInterface, tunnel.Table)
+		})
+		endpointRouted = true
+		break
+	}
+	if !endpointRouted {
+		return nil, fmt.Errorf("%w: for tunnel endpoint %s",
+			ErrRouteDefaultNotFound, tunnel.Endpoint)
+	}
+
+	markRule := netlink.NewRule()
+	markRule.Mark = tunnel.Table
+	markRule.Table = tunnel.Table
+	markRule.Priority = tunnelPriority
+	markRule.Family = family
+	r.logger.Debug(fmt.Sprintf("ip rule add fwmark %d lookup %d pref %d",
+		tunnel.Table, tunnel.Table, tunnelPriority))
+	err = r.netLinker.RuleAdd(markRule)
+	if err != nil {
+		return nil, fmt.Errorf("adding rule %s: %w", markRule, err)
+	}
+	cleanups = append(cleanups, func() error {
+		err := r.netLinker.RuleDel(markRule)
+		if err != nil {
+			return fmt.Errorf("deleting rule %s: %w", markRule, err)
+		}
+		return nil
+	})
+
+	sources := tunnel.Sources
+	if tunnel.Interface != "" {
+		defaultPrefix := netip.PrefixFrom(netip.IPv4Unspecified(), 0)
+		err = r.addRouteVia(defaultPrefix, netip.Addr{}, tunnel.Interface, tunnel.Table)
+		if err != nil {
+			return nil, fmt.Errorf("adding default route through tunnel: %w", err)
+		}
+		cleanups = append(cleanups, func() error {
+			_, err := r.netLinker.LinkByName(tunnel.Interface)
+			if err != nil {
+				return nil //nolint:nilerr // the route is removed with the interface
+			}
+			return r.deleteRouteVia(defaultPrefix, netip.Addr{}, tunnel.Interface, tunnel.Table)
+		})
+
+		assignedIP, err := r.assignedIP(tunnel.Interface, netlink.FamilyV4)
+		if err != nil {
+			return nil, fmt.Errorf("finding tunnel interface address: %w", err)
+		}
+		sources = append([]netip.Prefix{netip.PrefixFrom(assignedIP, assignedIP.BitLen())},
+			sources...)
+	}
+
+	type srcDst struct{ src, dst netip.Prefix }
+	ruleNetworks := make([]srcDst, 0, len(sources)+len(tunnel.Destinations))
+	for _, source := range sources {
+		ruleNetworks = append(ruleNetworks, srcDst{src: source})
+	}
+	for _, destination := range tunnel.Destinations {
+		ruleNetworks = append(ruleNetworks, srcDst{dst: destination})
+	}
+
+	for _, ruleNetwork := range ruleNetworks {
+		ruleNetwork := ruleNetwork
+		err = r.addIPRule(ruleNetwork.src, ruleNetwork.dst, tunnel.Table, tunnelPriority)
+		if err != nil {
+			return nil, fmt.Errorf("adding tunnel rule: %w", err)
+		}
+		cleanups = append(cleanups, func() error {
+			return r.deleteIPRule(ruleNetwork.src, ruleNetwork.dst, tunnel.Table, tunnelPriority)
+		})
+	}
+
+	return cleanup, nil
+}
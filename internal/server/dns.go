@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"net/http"
 	"strings"
+
+	"github.com/qdm12/gluetun/internal/configuration/settings"
 )
 
 func newDNSHandler(ctx context.Context, loop DNSLoop,
@@ -32,10 +34,19 @@ func (h *dnsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		case http.MethodPut:
 			h.setStatus(w, r)
 		default:
-			http.Error(w, "method "+r.Method+" not supported", http.StatusBadRequest)
+			methodNotAllowed(w, r, http.MethodGet, http.MethodPut)
+		}
+	case "/settings":
+		switch r.Method {
+		case http.MethodGet:
+			h.getSettings(w)
+		case http.MethodPatch:
+			h.patchSettings(w, r)
+		default:
+			methodNotAllowed(w, r, http.MethodGet, http.MethodPatch)
 		}
 	default:
-		http.Error(w, "route "+r.RequestURI+" not supported", http.StatusBadRequest)
+		routeNotFound(w, r)
 	}
 }
 
@@ -54,23 +65,45 @@ func (h *dnsHandler) setStatus(w http.ResponseWriter, r *http.Request) {
 	decoder := json.NewDecoder(r.Body)
 	var data statusWrapper
 	if err := decoder.Decode(&data); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		httpError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 	status, err := data.getStatus()
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		httpError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 	outcome, err := h.loop.ApplyStatus(h.ctx, status)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		httpError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 	encoder := json.NewEncoder(w)
 	if err := encoder.Encode(outcomeWrapper{Outcome: outcome}); err != nil {
 		h.warner.Warn(err.Error())
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		httpError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+}
+
+func (h *dnsHandler) getSettings(w http.ResponseWriter) {
+	encodeResponse(w, h.loop.GetSettings(), h.warner)
+}
+
+func (h *dnsHandler) patchSettings(w http.ResponseWriter, r *http.Request) {
+	var overrideSettings settings.DNS
+	if !decodeSettings(w, r, &overrideSettings, h.warner) {
+		return
+	}
+
+	updatedSettings := h.loop.GetSettings() // already copied
+	updatedSettings.OverrideWith(overrideSettings)
+	err := updatedSettings.Validate()
+	if err != nil {
+		httpError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+
+	outcome := h.loop.SetSettings(h.ctx, updatedSettings)
+	encodeResponse(w, outcomeWrapper{Outcome: outcome}, h.warner)
 }
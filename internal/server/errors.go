@@ -0,0 +1,29 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// errorWrapper is the JSON body of all error responses of the v1 API.
+type errorWrapper struct {
+	Error string `json:"error"`
+}
+
+func httpError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(errorWrapper{Error: message})
+}
+
+func routeNotFound(w http.ResponseWriter, r *http.Request) {
+	httpError(w, http.StatusNotFound, "route "+r.URL.Path+" not found")
+}
+
+func methodNotAllowed(w http.ResponseWriter, r *http.Request, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	httpError(w, http.StatusMethodNotAllowed,
+		"method "+r.Method+" not allowed for route "+r.URL.Path)
+}
@@ -15,6 +15,10 @@ func newHandler(ctx context.Context, logger infoWarner, logging bool,
 	unboundLooper DNSLoop,
 	updaterLooper UpdaterLooper,
 	publicIPLooper PublicIPLoop,
+	portForwardLooper PortForwardLoop,
+	httpProxyLooper HTTPProxyLoop,
+	shadowsocksLooper ShadowsocksLoop,
+	shaper Shaper,
 	storage Storage,
 	ipv6Supported bool,
 ) http.Handler {
@@ -24,10 +28,16 @@ func newHandler(ctx context.Context, logger infoWarner, logging bool,
 	openvpn := newOpenvpnHandler(ctx, vpnLooper, pfGetter, logger)
 	dns := newDNSHandler(ctx, unboundLooper, logger)
 	updater := newUpdaterHandler(ctx, updaterLooper, logger)
-	publicip := newPublicIPHandler(publicIPLooper, logger)
+	publicip := newPublicIPHandler(ctx, publicIPLooper, logger)
+	portForward := newPortForwardHandler(ctx, portForwardLooper, vpnLooper, logger)
+	httpProxy := newHTTPProxyHandler(ctx, httpProxyLooper, logger)
+	shadowsocks := newShadowsocksHandler(ctx, shadowsocksLooper, logger)
+	shaping := newShapingHandler(shaper, logger)
+	servers := newServersHandler(storage, logger)
 
 	handler.v0 = newHandlerV0(ctx, logger, vpnLooper, unboundLooper, updaterLooper)
-	handler.v1 = newHandlerV1(logger, buildInfo, vpn, openvpn, dns, updater, publicip)
+	handler.v1 = newHandlerV1(logger, buildInfo, vpn, openvpn, dns, updater, publicip,
+		portForward, httpProxy, shadowsocks, shaping, servers)
 
 	handlerWithLog := withLogMiddleware(handler, logger, logging)
 	handler.setLogEnabled = handlerWithLog.setEnabled
@@ -2,7 +2,6 @@ package server
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"strings"
 
@@ -10,32 +9,54 @@ import (
 )
 
 func newHandlerV1(w warner, buildInfo models.BuildInformation,
-	vpn, openvpn, dns, updater, publicip http.Handler) http.Handler {
+	vpn, openvpn, dns, updater, publicip, portForward,
+	httpProxy, shadowsocks, shaping, servers http.Handler) http.Handler {
 	return &handlerV1{
-		warner:    w,
-		buildInfo: buildInfo,
-		vpn:       vpn,
-		openvpn:   openvpn,
-		dns:       dns,
-		updater:   updater,
-		publicip:  publicip,
+		warner:      w,
+		buildInfo:   buildInfo,
+		vpn:         vpn,
+		openvpn:     openvpn,
+		dns:         dns,
+		updater:     updater,
+		publicip:    publicip,
+		portForward: portForward,
+		httpProxy:   httpProxy,
+		shadowsocks: shadowsocks,
+		shaping:     shaping,
+		servers:     servers,
 	}
 }
 
 type handlerV1 struct {
-	warner    warner
-	buildInfo models.BuildInformation
-	vpn       http.Handler
-	openvpn   http.Handler
-	dns       http.Handler
-	updater   http.Handler
-	publicip  http.Handler
+	warner      warner
+	buildInfo   models.BuildInformation
+	vpn         http.Handler
+	openvpn     http.Handler
+	dns         http.Handler
+	updater     http.Handler
+	publicip    http.Handler
+	portForward http.Handler
+	httpProxy   http.Handler
+	shadowsocks http.Handler
+	shaping     http.Handler
+	servers     http.Handler
 }
 
 func (h *handlerV1) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
 	switch {
-	case r.RequestURI == "/version" && r.Method == http.MethodGet:
+	case r.RequestURI == "/version":
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, r, http.MethodGet)
+			return
+		}
 		h.getVersion(w)
+	case r.RequestURI == "/openapi.yaml":
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, r, http.MethodGet)
+			return
+		}
+		h.getOpenAPI(w)
 	case strings.HasPrefix(r.RequestURI, "/vpn"):
 		h.vpn.ServeHTTP(w, r)
 	case strings.HasPrefix(r.RequestURI, "/openvpn"):
@@ -46,9 +67,18 @@ func (h *handlerV1) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		h.updater.ServeHTTP(w, r)
 	case strings.HasPrefix(r.RequestURI, "/publicip"):
 		h.publicip.ServeHTTP(w, r)
+	case strings.HasPrefix(r.RequestURI, "/portforward"):
+		h.portForward.ServeHTTP(w, r)
+	case strings.HasPrefix(r.RequestURI, "/httpproxy"):
+		h.httpProxy.ServeHTTP(w, r)
+	case strings.HasPrefix(r.RequestURI, "/shadowsocks"):
+		h.shadowsocks.ServeHTTP(w, r)
+	case strings.HasPrefix(r.RequestURI, "/shaping"):
+		h.shaping.ServeHTTP(w, r)
+	case strings.HasPrefix(r.RequestURI, "/servers/"):
+		h.servers.ServeHTTP(w, r)
 	default:
-		errString := fmt.Sprintf("%s %s not found", r.Method, r.RequestURI)
-		http.Error(w, errString, http.StatusBadRequest)
+		routeNotFound(w, r)
 	}
 }
 
@@ -59,3 +89,11 @@ func (h *handlerV1) getVersion(w http.ResponseWriter) {
 		w.WriteHeader(http.StatusInternalServerError)
 	}
 }
+
+func (h *handlerV1) getOpenAPI(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/yaml")
+	_, err := w.Write(openAPISpec)
+	if err != nil {
+		h.warner.Warn("writing OpenAPI specification: " + err.Error())
+	}
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/qdm12/gluetun/internal/accesslog"
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/qdm12/gluetun/internal/httpproxy"
+)
+
+func newHTTPProxyHandler(ctx context.Context, loop HTTPProxyLoop,
+	warner warner) http.Handler {
+	return &httpProxyHandler{
+		ctx:    ctx,
+		loop:   loop,
+		warner: warner,
+	}
+}
+
+type httpProxyHandler struct {
+	ctx    context.Context //nolint:containedctx
+	loop   HTTPProxyLoop
+	warner warner
+}
+
+func (h *httpProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.RequestURI = strings.TrimPrefix(r.RequestURI, "/httpproxy")
+	switch r.RequestURI {
+	case "/settings":
+		switch r.Method {
+		case http.MethodGet:
+			h.getSettings(w)
+		case http.MethodPatch:
+			h.patchSettings(w, r)
+		default:
+			methodNotAllowed(w, r, http.MethodGet, http.MethodPatch)
+		}
+	case "/clients":
+		switch r.Method {
+		case http.MethodGet:
+			h.getClients(w)
+		default:
+			methodNotAllowed(w, r, http.MethodGet)
+		}
+	case "/pac":
+		switch r.Method {
+		case http.MethodGet:
+			h.getPAC(w, r)
+		default:
+			methodNotAllowed(w, r, http.MethodGet)
+		}
+	case "/users":
+		switch r.Method {
+		case http.MethodGet:
+			h.getUsers(w)
+		default:
+			methodNotAllowed(w, r, http.MethodGet)
+		}
+	default:
+		routeNotFound(w, r)
+	}
+}
+
+func (h *httpProxyHandler) getSettings(w http.ResponseWriter) {
+	settings := h.loop.GetSettings()
+	settings.Password = redactString(settings.Password)
+	encodeResponse(w, settings, h.warner)
+}
+
+func (h *httpProxyHandler) patchSettings(w http.ResponseWriter, r *http.Request) {
+	var overrideSettings settings.HTTPProxy
+	if !decodeSettings(w, r, &overrideSettings, h.warner) {
+		return
+	}
+	overrideSettings.Password = unredactString(overrideSettings.Password)
+
+	updatedSettings := h.loop.GetSettings() // already copied
+	updatedSettings.OverrideWith(overrideSettings)
+	err := updatedSettings.Validate()
+	if err != nil {
+		httpError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	outcome := h.loop.SetSettings(h.ctx, updatedSettings)
+	encodeResponse(w, outcomeWrapper{Outcome: outcome}, h.warner)
+}
+
+type userStatsWrapper struct {
+	Users []httpproxy.UserStats `json:"users"`
+}
+
+func (h *httpProxyHandler) getUsers(w http.ResponseWriter) {
+	users := h.loop.GetUserStats()
+	if users == nil {
+		users = []httpproxy.UserStats{}
+	}
+	encodeResponse(w, userStatsWrapper{Users: users}, h.warner)
+}
+
+func (h *httpProxyHandler) getPAC(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/x-ns-proxy-autoconfig")
+	_, err := w.Write([]byte(h.loop.PAC(r.Host)))
+	if err != nil {
+		h.warner.Warn("writing proxy auto-config file: " + err.Error())
+	}
+}
+
+func (h *httpProxyHandler) getClients(w http.ResponseWriter) {
+	clients := h.loop.GetClientStats()
+	if clients == nil {
+		clients = []accesslog.ClientStats{}
+	}
+	encodeResponse(w, clientStatsWrapper{Clients: clients}, h.warner)
+}
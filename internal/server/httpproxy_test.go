@@ -0,0 +1,91 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/qdm12/gluetun/internal/accesslog"
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/qdm12/gluetun/internal/httpproxy"
+	"github.com/stretchr/testify/assert"
+)
+
+func ptrTo[T any](value T) *T { return &value }
+
+type fakeHTTPProxyLoop struct {
+	settings settings.HTTPProxy
+}
+
+func (f *fakeHTTPProxyLoop) GetSettings() settings.HTTPProxy {
+	return f.settings
+}
+
+func (f *fakeHTTPProxyLoop) SetSettings(_ context.Context,
+	settings settings.HTTPProxy) (outcome string) {
+	f.settings = settings
+	return "settings updated"
+}
+
+func (f *fakeHTTPProxyLoop) GetUserStats() []httpproxy.UserStats {
+	return []httpproxy.UserStats{{Name: "alice", Requests: 1}}
+}
+
+func (f *fakeHTTPProxyLoop) GetClientStats() []accesslog.ClientStats {
+	return []accesslog.ClientStats{{Client: "192.168.1.5", Requests: 1}}
+}
+
+func (f *fakeHTTPProxyLoop) PAC(requestHost string) string {
+	return `function FindProxyForURL(url, host) { return "PROXY ` + requestHost + `"; }`
+}
+
+type noopWarner struct{}
+
+func (noopWarner) Warn(string) {}
+
+func Test_httpProxyHandler_settings(t *testing.T) {
+	t.Parallel()
+
+	loop := &fakeHTTPProxyLoop{
+		settings: settings.HTTPProxy{
+			User:              ptrTo("user"),
+			Password:          ptrTo("secret"),
+			CredentialsFile:   ptrTo(""),
+			ListeningAddress:  ":8888",
+			Enabled:           ptrTo(true),
+			Stealth:           ptrTo(false),
+			Log:               ptrTo(false),
+			ReadHeaderTimeout: 1,
+			ReadTimeout:       1,
+			DefaultPolicy:     settings.HTTPProxyPolicyAllow,
+		},
+	}
+	handler := newHTTPProxyHandler(context.Background(), loop, noopWarner{})
+
+	request := httptest.NewRequest(http.MethodGet, "/httpproxy/settings", nil)
+	request.RequestURI = "/httpproxy/settings"
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"Password":"[redacted]"`)
+	assert.NotContains(t, recorder.Body.String(), "secret")
+
+	body := strings.NewReader(`{"User":"other","Password":"[redacted]"}`)
+	request = httptest.NewRequest(http.MethodPatch, "/httpproxy/settings", body)
+	request.RequestURI = "/httpproxy/settings"
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "other", *loop.settings.User)
+	assert.Equal(t, "secret", *loop.settings.Password)
+
+	body = strings.NewReader(`{"ListeningAddress":"invalid"}`)
+	request = httptest.NewRequest(http.MethodPatch, "/httpproxy/settings", body)
+	request.RequestURI = "/httpproxy/settings"
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, ":8888", loop.settings.ListeningAddress)
+}
@@ -3,8 +3,14 @@ package server
 import (
 	"context"
 
+	"github.com/qdm12/gluetun/internal/accesslog"
 	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/qdm12/gluetun/internal/httpproxy"
 	"github.com/qdm12/gluetun/internal/models"
+	"github.com/qdm12/gluetun/internal/openvpn"
+	"github.com/qdm12/gluetun/internal/ratelimit"
+	"github.com/qdm12/gluetun/internal/shadowsocks/relay"
+	"github.com/qdm12/gluetun/internal/storage"
 )
 
 type VPNLooper interface {
@@ -13,22 +19,62 @@ type VPNLooper interface {
 		outcome string, err error)
 	GetSettings() (settings settings.VPN)
 	SetSettings(ctx context.Context, settings settings.VPN) (outcome string)
+	GetChallenge() (challenge openvpn.Challenge, ok bool)
+	AnswerChallenge(response string) (err error)
+	GetTunnels() (statuses []models.TunnelStatus)
+	ApplyTunnelStatus(name string, status models.LoopStatus) (
+		outcome string, err error)
+	SetTunnelSettings(tunnel settings.Tunnel) (outcome string, err error)
 }
 
 type DNSLoop interface {
 	ApplyStatus(ctx context.Context, status models.LoopStatus) (
 		outcome string, err error)
 	GetStatus() (status models.LoopStatus)
+	GetSettings() (settings settings.DNS)
+	SetSettings(ctx context.Context, settings settings.DNS) (outcome string)
 }
 
 type PortForwardedGetter interface {
 	GetPortForwarded() (portForwarded uint16)
 }
 
+type PortForwardLoop interface {
+	GetSettings() (settings settings.PortForwarding)
+	SetSettings(ctx context.Context, settings settings.PortForwarding) (outcome string)
+}
+
+type HTTPProxyLoop interface {
+	GetSettings() (settings settings.HTTPProxy)
+	SetSettings(ctx context.Context, settings settings.HTTPProxy) (outcome string)
+	GetUserStats() (stats []httpproxy.UserStats)
+	GetClientStats() (stats []accesslog.ClientStats)
+	PAC(requestHost string) (content string)
+}
+
+type ShadowsocksLoop interface {
+	GetSettings() (settings settings.Shadowsocks)
+	SetSettings(ctx context.Context, settings settings.Shadowsocks) (outcome string)
+	GetClientStats() (stats []accesslog.ClientStats)
+	GetUserStats() (stats []relay.UserStats)
+	SetUserEnabled(name string, enabled bool) (err error)
+}
+
+type Shaper interface {
+	Limits() (limits ratelimit.Limits)
+	SetLimits(limits ratelimit.Limits)
+	Throughput() (throughput ratelimit.Throughput)
+}
+
 type PublicIPLoop interface {
 	GetData() (data models.PublicIP)
+	GetExitCheck() (check *models.ExitCheck)
+	GetSettings() (settings settings.PublicIP)
+	SetSettings(ctx context.Context, settings settings.PublicIP) (outcome string)
 }
 
 type Storage interface {
 	GetFilterChoices(provider string) models.FilterChoices
+	History(provider string) (snapshots []storage.Snapshot, err error)
+	Rollback(provider string, timestamp int64) (err error)
 }
@@ -0,0 +1,11 @@
+package server
+
+import (
+	_ "embed"
+)
+
+// openAPISpec is the OpenAPI specification of the v1 API,
+// served at /v1/openapi.yaml.
+//
+//go:embed openapi.yaml
+var openAPISpec []byte //nolint:gochecknoglobals
@@ -0,0 +1,397 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/accesslog"
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/qdm12/gluetun/internal/constants"
+	"github.com/qdm12/gluetun/internal/constants/providers"
+	"github.com/qdm12/gluetun/internal/models"
+	"github.com/qdm12/gluetun/internal/openvpn"
+	"github.com/qdm12/gluetun/internal/ratelimit"
+	"github.com/qdm12/gluetun/internal/shadowsocks/relay"
+	"github.com/qdm12/gluetun/internal/storage"
+	"github.com/qdm12/gluetun/internal/updater"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+type openAPIDocument struct {
+	Paths      map[string]map[string]openAPIOperation `yaml:"paths"`
+	Components struct {
+		RequestBodies map[string]openAPIRequestBody `yaml:"requestBodies"`
+		Responses     map[string]openAPIResponse    `yaml:"responses"`
+		Schemas       map[string]*openAPISchema     `yaml:"schemas"`
+	} `yaml:"components"`
+}
+
+type openAPIOperation struct {
+	RequestBody *openAPIRequestBody        `yaml:"requestBody"`
+	Responses   map[string]openAPIResponse `yaml:"responses"`
+}
+
+type openAPIRequestBody struct {
+	Ref     string                      `yaml:"$ref"`
+	Content map[string]openAPIMediaType `yaml:"content"`
+}
+
+type openAPIResponse struct {
+	Ref     string                      `yaml:"$ref"`
+	Content map[string]openAPIMediaType `yaml:"content"`
+}
+
+type openAPIMediaType struct {
+	Schema  *openAPISchema `yaml:"schema"`
+	Example any            `yaml:"example"`
+}
+
+type openAPISchema struct {
+	Ref        string                    `yaml:"$ref"`
+	Type       string                    `yaml:"type"`
+	Nullable   bool                      `yaml:"nullable"`
+	Required   []string                  `yaml:"required"`
+	Properties map[string]*openAPISchema `yaml:"properties"`
+	Items      *openAPISchema            `yaml:"items"`
+	Enum       []string                  `yaml:"enum"`
+}
+
+func (d *openAPIDocument) requestBody(body openAPIRequestBody) openAPIRequestBody {
+	if body.Ref == "" {
+		return body
+	}
+	return d.Components.RequestBodies[strings.TrimPrefix(body.Ref, "#/components/requestBodies/")]
+}
+
+func (d *openAPIDocument) response(response openAPIResponse) openAPIResponse {
+	if response.Ref == "" {
+		return response
+	}
+	return d.Components.Responses[strings.TrimPrefix(response.Ref, "#/components/responses/")]
+}
+
+func (d *openAPIDocument) schema(schema *openAPISchema) *openAPISchema {
+	if schema.Ref == "" {
+		return schema
+	}
+	return d.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+}
+
+// validate checks the decoded JSON value matches the schema given,
+// returning an error describing the first mismatch found.
+func (d *openAPIDocument) validate(schema *openAPISchema, value any, path string) error {
+	schema = d.schema(schema)
+	if value == nil {
+		if schema.Nullable {
+			return nil
+		}
+		return fmt.Errorf("%s: is null but not nullable", path)
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: %T is not an object", path, value)
+		}
+		for _, field := range schema.Required {
+			if _, ok := object[field]; !ok {
+				return fmt.Errorf("%s: required field %q is missing", path, field)
+			}
+		}
+		for field, fieldValue := range object {
+			fieldSchema, ok := schema.Properties[field]
+			if !ok {
+				continue
+			}
+			err := d.validate(fieldSchema, fieldValue, path+"."+field)
+			if err != nil {
+				return err
+			}
+		}
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s: %T is not an array", path, value)
+		}
+		for i, item := range array {
+			err := d.validate(schema.Items, item, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return err
+			}
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: %T is not a string", path, value)
+		}
+		if len(schema.Enum) > 0 && !contains(schema.Enum, s) {
+			return fmt.Errorf("%s: %q is not one of %v", path, s, schema.Enum)
+		}
+	case "integer", "number":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("%s: %T is not a number", path, value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: %T is not a boolean", path, value)
+		}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+type fakeLoops struct {
+	settings settings.Settings
+}
+
+func newFakeLoops() *fakeLoops {
+	var allSettings settings.Settings
+	allSettings.VPN.Tunnels = []settings.Tunnel{{Name: fakeName}}
+	allSettings.SetDefaults()
+	return &fakeLoops{settings: allSettings}
+}
+
+type fakeVPNLooper struct{ *fakeLoops }
+
+func (f fakeVPNLooper) GetStatus() models.LoopStatus { return constants.Running }
+func (f fakeVPNLooper) ApplyStatus(context.Context, models.LoopStatus) (string, error) {
+	return "running", nil
+}
+func (f fakeVPNLooper) GetSettings() settings.VPN { return f.settings.VPN }
+func (f fakeVPNLooper) SetSettings(context.Context, settings.VPN) string {
+	return "settings updated"
+}
+func (f fakeVPNLooper) GetChallenge() (openvpn.Challenge, bool) { return openvpn.Challenge{}, false }
+func (f fakeVPNLooper) AnswerChallenge(string) error            { return nil }
+func (f fakeVPNLooper) GetTunnels() []models.TunnelStatus       { return nil }
+func (f fakeVPNLooper) ApplyTunnelStatus(string, models.LoopStatus) (string, error) {
+	return "running", nil
+}
+func (f fakeVPNLooper) SetTunnelSettings(settings.Tunnel) (string, error) {
+	return "settings updated", nil
+}
+
+func (f *fakeLoops) GetPortForwarded() uint16 { return 0 }
+
+type fakeDNSLoop struct{ *fakeLoops }
+
+func (f fakeDNSLoop) GetStatus() models.LoopStatus { return constants.Running }
+func (f fakeDNSLoop) ApplyStatus(context.Context, models.LoopStatus) (string, error) {
+	return "running", nil
+}
+func (f fakeDNSLoop) GetSettings() settings.DNS { return f.settings.DNS }
+func (f fakeDNSLoop) SetSettings(context.Context, settings.DNS) string {
+	return "settings updated"
+}
+
+type fakeUpdaterLoop struct{ *fakeLoops }
+
+func (f fakeUpdaterLoop) GetStatus() models.LoopStatus { return constants.Stopped }
+func (f fakeUpdaterLoop) SetStatus(context.Context, models.LoopStatus) (string, error) {
+	return "running", nil
+}
+func (f fakeUpdaterLoop) GetSettings() settings.Updater { return f.settings.Updater }
+func (f fakeUpdaterLoop) GetDiffs() []updater.ProviderDiff {
+	return []updater.ProviderDiff{{Provider: "mullvad", Added: []string{"openvpn-udp-a"},
+		Removed: []string{}, Changed: []string{}, IPsChanged: []string{}}}
+}
+func (f fakeUpdaterLoop) SetSettings(settings.Updater) string {
+	return "settings updated"
+}
+
+type fakePublicIPLoop struct{ *fakeLoops }
+
+func (f fakePublicIPLoop) GetData() models.PublicIP        { return models.PublicIP{} }
+func (f fakePublicIPLoop) GetExitCheck() *models.ExitCheck { return nil }
+func (f fakePublicIPLoop) GetSettings() settings.PublicIP  { return f.settings.PublicIP }
+func (f fakePublicIPLoop) SetSettings(context.Context, settings.PublicIP) string {
+	return "settings updated"
+}
+
+type fakePortForwardLoop struct{ *fakeLoops }
+
+func (f fakePortForwardLoop) GetSettings() settings.PortForwarding {
+	return f.settings.VPN.Provider.PortForwarding
+}
+func (f fakePortForwardLoop) SetSettings(context.Context, settings.PortForwarding) string {
+	return "settings updated"
+}
+
+type fakeShadowsocksLoop struct{ *fakeLoops }
+
+func (f fakeShadowsocksLoop) GetSettings() settings.Shadowsocks { return f.settings.Shadowsocks }
+func (f fakeShadowsocksLoop) SetSettings(context.Context, settings.Shadowsocks) string {
+	return "settings updated"
+}
+func (f fakeShadowsocksLoop) GetClientStats() []accesslog.ClientStats { return nil }
+func (f fakeShadowsocksLoop) GetUserStats() []relay.UserStats {
+	return []relay.UserStats{{Name: fakeName, Enabled: true}}
+}
+
+// fakeName is the name of the fake Shadowsocks user and of the
+// fake tunnel, used for the {name} path parameters.
+const fakeName = "alice"
+
+func (f fakeShadowsocksLoop) SetUserEnabled(name string, _ bool) error {
+	if name != fakeName {
+		return relay.ErrUserNotFound
+	}
+	return nil
+}
+
+func (f *fakeLoops) GetFilterChoices(string) models.FilterChoices {
+	return models.FilterChoices{}
+}
+
+const fakeSnapshotTimestamp = 1700000000
+
+func (f *fakeLoops) History(string) ([]storage.Snapshot, error) {
+	return []storage.Snapshot{{
+		Timestamp:  fakeSnapshotTimestamp,
+		Version:    1,
+		ReplacedAt: time.Unix(fakeSnapshotTimestamp+1, 0).UTC(),
+		Count:      2,
+	}}, nil
+}
+
+func (f *fakeLoops) Rollback(_ string, timestamp int64) error {
+	if timestamp != fakeSnapshotTimestamp {
+		return storage.ErrSnapshotNotFound
+	}
+	return nil
+}
+
+type noopLogger struct{}
+
+func (noopLogger) Info(string) {}
+func (noopLogger) Warn(string) {}
+
+func newTestHandler() http.Handler {
+	loops := newFakeLoops()
+	httpProxyLoop := &fakeHTTPProxyLoop{settings: loops.settings.HTTPProxy}
+	return newHandler(context.Background(), noopLogger{}, false,
+		models.BuildInformation{Version: "v1", Commit: "abc", Created: "now"},
+		fakeVPNLooper{loops}, loops, fakeDNSLoop{loops}, fakeUpdaterLoop{loops},
+		fakePublicIPLoop{loops}, fakePortForwardLoop{loops}, httpProxyLoop,
+		fakeShadowsocksLoop{loops}, ratelimit.NewShaper(ratelimit.Limits{}), loops, false)
+}
+
+func Test_openAPI_conformance(t *testing.T) {
+	t.Parallel()
+
+	var document openAPIDocument
+	err := yaml.Unmarshal(openAPISpec, &document)
+	require.NoError(t, err)
+	require.NotEmpty(t, document.Paths)
+
+	handler := newTestHandler()
+	allMethods := []string{http.MethodGet, http.MethodPut,
+		http.MethodPatch, http.MethodPost, http.MethodDelete}
+
+	for path, operations := range document.Paths {
+		path, operations := path, operations
+		t.Run(path, func(t *testing.T) {
+			t.Parallel()
+
+			requestPath := strings.ReplaceAll(path, "{provider}", providers.Mullvad)
+			requestPath = strings.ReplaceAll(requestPath, "{name}", fakeName)
+			allowed := make([]string, 0, len(operations))
+			for method, operation := range operations {
+				method = strings.ToUpper(method)
+				allowed = append(allowed, method)
+
+				var body string
+				if operation.RequestBody != nil {
+					requestBody := document.requestBody(*operation.RequestBody)
+					example := requestBody.Content["application/json"].Example
+					data, err := json.Marshal(example)
+					require.NoError(t, err)
+					body = string(data)
+				}
+
+				request := httptest.NewRequest(method, "/v1"+requestPath, strings.NewReader(body))
+				recorder := httptest.NewRecorder()
+				handler.ServeHTTP(recorder, request)
+
+				statusCode := fmt.Sprint(recorder.Code)
+				specResponse, ok := operation.Responses[statusCode]
+				require.Truef(t, ok, "%s %s: status code %s not in specification: %s",
+					method, path, statusCode, recorder.Body.String())
+				specResponse = document.response(specResponse)
+
+				contentType := recorder.Header().Get("Content-Type")
+				mediaType, ok := specResponse.Content[contentType]
+				require.Truef(t, ok, "%s %s: content type %q not in specification",
+					method, path, contentType)
+				if contentType != "application/json" {
+					continue
+				}
+
+				var decoded any
+				err := json.Unmarshal(recorder.Body.Bytes(), &decoded)
+				require.NoErrorf(t, err, "%s %s: body is not JSON", method, path)
+				err = document.validate(mediaType.Schema, decoded, method+" "+path)
+				assert.NoError(t, err)
+			}
+			sort.Strings(allowed)
+
+			for _, method := range allMethods {
+				if contains(allowed, method) {
+					continue
+				}
+				request := httptest.NewRequest(method, "/v1"+requestPath, nil)
+				recorder := httptest.NewRecorder()
+				handler.ServeHTTP(recorder, request)
+
+				assert.Equalf(t, http.StatusMethodNotAllowed, recorder.Code, "%s %s", method, path)
+				allowHeader := strings.Split(recorder.Header().Get("Allow"), ", ")
+				sort.Strings(allowHeader)
+				assert.Equal(t, allowed, allowHeader)
+				assertErrorBody(t, recorder)
+			}
+		})
+	}
+}
+
+func Test_handlerV1_routeNotFound(t *testing.T) {
+	t.Parallel()
+
+	handler := newTestHandler()
+	paths := []string{"/v1/unknown", "/v1/vpn/unknown", "/v1/dns", "/v1/httpproxy/unknown",
+		"/v1/servers/unknown/history", "/v1/servers/custom/history", "/v1/servers/mullvad/unknown"}
+	for _, path := range paths {
+		request := httptest.NewRequest(http.MethodGet, path, nil)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		assert.Equalf(t, http.StatusNotFound, recorder.Code, path)
+		assertErrorBody(t, recorder)
+	}
+}
+
+func assertErrorBody(t *testing.T, recorder *httptest.ResponseRecorder) {
+	t.Helper()
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	var body errorWrapper
+	err := json.Unmarshal(recorder.Body.Bytes(), &body)
+	require.NoError(t, err)
+	assert.NotEmpty(t, body.Error)
+}
@@ -34,24 +34,33 @@ func (h *openvpnHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		case http.MethodPut:
 			h.setStatus(w, r)
 		default:
-			http.Error(w, "method "+r.Method+" not supported", http.StatusBadRequest)
+			methodNotAllowed(w, r, http.MethodGet, http.MethodPut)
 		}
 	case "/settings":
 		switch r.Method {
 		case http.MethodGet:
 			h.getSettings(w)
 		default:
-			http.Error(w, "method "+r.Method+" not supported", http.StatusBadRequest)
+			methodNotAllowed(w, r, http.MethodGet)
+		}
+	case "/challenge":
+		switch r.Method {
+		case http.MethodGet:
+			h.getChallenge(w)
+		case http.MethodPut:
+			h.answerChallenge(w, r)
+		default:
+			methodNotAllowed(w, r, http.MethodGet, http.MethodPut)
 		}
 	case "/portforwarded":
 		switch r.Method {
 		case http.MethodGet:
 			h.getPortForwarded(w)
 		default:
-			http.Error(w, "method "+r.Method+" not supported", http.StatusBadRequest)
+			methodNotAllowed(w, r, http.MethodGet)
 		}
 	default:
-		http.Error(w, "route "+r.RequestURI+" not supported", http.StatusBadRequest)
+		routeNotFound(w, r)
 	}
 }
 
@@ -70,23 +79,23 @@ func (h *openvpnHandler) setStatus(w http.ResponseWriter, r *http.Request) {
 	decoder := json.NewDecoder(r.Body)
 	var data statusWrapper
 	if err := decoder.Decode(&data); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		httpError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 	status, err := data.getStatus()
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		httpError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 	outcome, err := h.looper.ApplyStatus(h.ctx, status)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		httpError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 	encoder := json.NewEncoder(w)
 	if err := encoder.Encode(outcomeWrapper{Outcome: outcome}); err != nil {
 		h.warner.Warn(err.Error())
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		httpError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
 		return
 	}
 }
@@ -102,6 +111,39 @@ func (h *openvpnHandler) getSettings(w http.ResponseWriter) {
 	}
 }
 
+func (h *openvpnHandler) getChallenge(w http.ResponseWriter) {
+	challenge, ok := h.looper.GetChallenge()
+	data := challengeWrapper{}
+	if ok {
+		data.Challenge = &challenge
+	}
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(data); err != nil {
+		h.warner.Warn(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *openvpnHandler) answerChallenge(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var data challengeResponseWrapper
+	if err := decoder.Decode(&data); err != nil {
+		httpError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.looper.AnswerChallenge(data.Response); err != nil {
+		httpError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(outcomeWrapper{Outcome: "challenge answered"}); err != nil {
+		h.warner.Warn(err.Error())
+		httpError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+}
+
 func (h *openvpnHandler) getPortForwarded(w http.ResponseWriter) {
 	port := h.pf.GetPortForwarded()
 	encoder := json.NewEncoder(w)
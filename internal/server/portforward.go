@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+)
+
+func newPortForwardHandler(ctx context.Context, loop PortForwardLoop,
+	vpnLooper VPNLooper, warner warner) http.Handler {
+	return &portForwardHandler{
+		ctx:       ctx,
+		loop:      loop,
+		vpnLooper: vpnLooper,
+		warner:    warner,
+	}
+}
+
+type portForwardHandler struct {
+	ctx       context.Context //nolint:containedctx
+	loop      PortForwardLoop
+	vpnLooper VPNLooper
+	warner    warner
+}
+
+func (h *portForwardHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.RequestURI = strings.TrimPrefix(r.RequestURI, "/portforward")
+	switch r.RequestURI {
+	case "/settings":
+		switch r.Method {
+		case http.MethodGet:
+			h.getSettings(w)
+		case http.MethodPatch:
+			h.patchSettings(w, r)
+		default:
+			methodNotAllowed(w, r, http.MethodGet, http.MethodPatch)
+		}
+	default:
+		routeNotFound(w, r)
+	}
+}
+
+func (h *portForwardHandler) getSettings(w http.ResponseWriter) {
+	encodeResponse(w, h.loop.GetSettings(), h.warner)
+}
+
+func (h *portForwardHandler) patchSettings(w http.ResponseWriter, r *http.Request) {
+	var overrideSettings settings.PortForwarding
+	if !decodeSettings(w, r, &overrideSettings, h.warner) {
+		return
+	}
+
+	updatedSettings := h.loop.GetSettings() // already copied
+	updatedSettings.OverrideWith(overrideSettings)
+	vpnProvider := *h.vpnLooper.GetSettings().Provider.Name
+	err := updatedSettings.Validate(vpnProvider)
+	if err != nil {
+		httpError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	outcome := h.loop.SetSettings(h.ctx, updatedSettings)
+	encodeResponse(w, outcomeWrapper{Outcome: outcome}, h.warner)
+}
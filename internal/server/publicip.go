@@ -1,19 +1,24 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"strings"
+
+	"github.com/qdm12/gluetun/internal/configuration/settings"
 )
 
-func newPublicIPHandler(loop PublicIPLoop, w warner) http.Handler {
+func newPublicIPHandler(ctx context.Context, loop PublicIPLoop, w warner) http.Handler {
 	return &publicIPHandler{
+		ctx:    ctx,
 		loop:   loop,
 		warner: w,
 	}
 }
 
 type publicIPHandler struct {
+	ctx    context.Context //nolint:containedctx
 	loop   PublicIPLoop
 	warner warner
 }
@@ -26,10 +31,26 @@ func (h *publicIPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		case http.MethodGet:
 			h.getPublicIP(w)
 		default:
-			http.Error(w, "method "+r.Method+" not supported", http.StatusBadRequest)
+			methodNotAllowed(w, r, http.MethodGet)
+		}
+	case "/exit":
+		switch r.Method {
+		case http.MethodGet:
+			h.getExitCheck(w)
+		default:
+			methodNotAllowed(w, r, http.MethodGet)
+		}
+	case "/settings":
+		switch r.Method {
+		case http.MethodGet:
+			h.getSettings(w)
+		case http.MethodPatch:
+			h.patchSettings(w, r)
+		default:
+			methodNotAllowed(w, r, http.MethodGet, http.MethodPatch)
 		}
 	default:
-		http.Error(w, "route "+r.RequestURI+" not supported", http.StatusBadRequest)
+		routeNotFound(w, r)
 	}
 }
 
@@ -42,3 +63,38 @@ func (h *publicIPHandler) getPublicIP(w http.ResponseWriter) {
 		return
 	}
 }
+
+func (h *publicIPHandler) getExitCheck(w http.ResponseWriter) {
+	data := exitCheckWrapper{ExitCheck: h.loop.GetExitCheck()}
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(data); err != nil {
+		h.warner.Warn(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *publicIPHandler) getSettings(w http.ResponseWriter) {
+	settings := h.loop.GetSettings()
+	settings.IPInfoToken = redactString(settings.IPInfoToken)
+	encodeResponse(w, settings, h.warner)
+}
+
+func (h *publicIPHandler) patchSettings(w http.ResponseWriter, r *http.Request) {
+	var overrideSettings settings.PublicIP
+	if !decodeSettings(w, r, &overrideSettings, h.warner) {
+		return
+	}
+	overrideSettings.IPInfoToken = unredactString(overrideSettings.IPInfoToken)
+
+	updatedSettings := h.loop.GetSettings() // already copied
+	updatedSettings.OverrideWith(overrideSettings)
+	err := updatedSettings.Validate()
+	if err != nil {
+		httpError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	outcome := h.loop.SetSettings(h.ctx, updatedSettings)
+	encodeResponse(w, outcomeWrapper{Outcome: outcome}, h.warner)
+}
@@ -11,11 +11,13 @@ import (
 func New(ctx context.Context, address string, logEnabled bool, logger Logger,
 	buildInfo models.BuildInformation, openvpnLooper VPNLooper,
 	pfGetter PortForwardedGetter, unboundLooper DNSLoop,
-	updaterLooper UpdaterLooper, publicIPLooper PublicIPLoop, storage Storage,
-	ipv6Supported bool) (
-	server *httpserver.Server, err error) {
+	updaterLooper UpdaterLooper, publicIPLooper PublicIPLoop,
+	portForwardLooper PortForwardLoop, httpProxyLooper HTTPProxyLoop,
+	shadowsocksLooper ShadowsocksLoop, shaper Shaper, storage Storage,
+	ipv6Supported bool) (server *httpserver.Server, err error) {
 	handler := newHandler(ctx, logger, logEnabled, buildInfo,
 		openvpnLooper, pfGetter, unboundLooper, updaterLooper, publicIPLooper,
+		portForwardLooper, httpProxyLooper, shadowsocksLooper, shaper,
 		storage, ipv6Supported)
 
 	httpServerSettings := httpserver.Settings{
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/qdm12/gluetun/internal/constants/providers"
+	"github.com/qdm12/gluetun/internal/storage"
+)
+
+func newServersHandler(storage Storage, warner warner) http.Handler {
+	return &serversHandler{
+		storage: storage,
+		warner:  warner,
+	}
+}
+
+type serversHandler struct {
+	storage Storage
+	warner  warner
+}
+
+func (h *serversHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.RequestURI = strings.TrimPrefix(r.RequestURI, "/servers/")
+	provider, route, ok := strings.Cut(r.RequestURI, "/")
+	if !ok || !isHistoryProvider(provider) {
+		routeNotFound(w, r)
+		return
+	}
+
+	switch route {
+	case "history":
+		switch r.Method {
+		case http.MethodGet:
+			h.getHistory(w, provider)
+		default:
+			methodNotAllowed(w, r, http.MethodGet)
+		}
+	case "rollback":
+		switch r.Method {
+		case http.MethodPut:
+			h.rollback(w, r, provider)
+		default:
+			methodNotAllowed(w, r, http.MethodPut)
+		}
+	default:
+		routeNotFound(w, r)
+	}
+}
+
+// isHistoryProvider returns true if the provider given
+// is a valid provider with servers history.
+func isHistoryProvider(provider string) bool {
+	if provider == providers.Custom {
+		return false
+	}
+	for _, validProvider := range providers.All() {
+		if provider == validProvider {
+			return true
+		}
+	}
+	return false
+}
+
+type snapshotsWrapper struct {
+	Snapshots []storage.Snapshot `json:"snapshots"`
+}
+
+type timestampWrapper struct {
+	Timestamp int64 `json:"timestamp"`
+}
+
+func (h *serversHandler) getHistory(w http.ResponseWriter, provider string) {
+	snapshots, err := h.storage.History(provider)
+	if err != nil {
+		h.warner.Warn(err.Error())
+		httpError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if snapshots == nil {
+		snapshots = []storage.Snapshot{}
+	}
+	encodeResponse(w, snapshotsWrapper{Snapshots: snapshots}, h.warner)
+}
+
+func (h *serversHandler) rollback(w http.ResponseWriter, r *http.Request, provider string) {
+	var data timestampWrapper
+	if !decodeSettings(w, r, &data, h.warner) {
+		return
+	}
+
+	err := h.storage.Rollback(provider, data.Timestamp)
+	switch {
+	case err == nil:
+	case errors.Is(err, storage.ErrSnapshotNotFound):
+		httpError(w, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, storage.ErrSnapshotOutdated):
+		httpError(w, http.StatusConflict, err.Error())
+		return
+	default:
+		h.warner.Warn(err.Error())
+		httpError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	encodeResponse(w, outcomeWrapper{Outcome: "rolled back"}, h.warner)
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// redacted replaces secret values in settings responses.
+// Sending it back in a settings patch request leaves the
+// secret value unchanged.
+const redacted = "[redacted]"
+
+// redactString returns a pointer to the redacted string if the
+// value given is set and not empty, and the value otherwise.
+func redactString(value *string) *string {
+	if value == nil || *value == "" {
+		return value
+	}
+	redactedValue := redacted
+	return &redactedValue
+}
+
+// unredactString returns nil if the value given is the redacted
+// string, so the current secret value is kept when overriding.
+func unredactString(value *string) *string {
+	if value != nil && *value == redacted {
+		return nil
+	}
+	return value
+}
+
+// decodeSettings decodes the request body into the settings pointer
+// given. It writes an error response and returns false on failure.
+func decodeSettings(w http.ResponseWriter, r *http.Request,
+	settings any, warner warner) (ok bool) {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(settings)
+	if err != nil {
+		httpError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+
+	err = r.Body.Close()
+	if err != nil {
+		warner.Warn("closing body: " + err.Error())
+	}
+	return true
+}
+
+func encodeResponse(w http.ResponseWriter, data any, warner warner) {
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(data); err != nil {
+		warner.Warn(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,187 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/qdm12/gluetun/internal/accesslog"
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/qdm12/gluetun/internal/shadowsocks/relay"
+)
+
+func newShadowsocksHandler(ctx context.Context, loop ShadowsocksLoop,
+	warner warner) http.Handler {
+	return &shadowsocksHandler{
+		ctx:    ctx,
+		loop:   loop,
+		warner: warner,
+	}
+}
+
+type shadowsocksHandler struct {
+	ctx    context.Context //nolint:containedctx
+	loop   ShadowsocksLoop
+	warner warner
+}
+
+func (h *shadowsocksHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.RequestURI = strings.TrimPrefix(r.RequestURI, "/shadowsocks")
+	if name, ok := strings.CutPrefix(r.RequestURI, "/users/"); ok && name != "" {
+		switch r.Method {
+		case http.MethodPut:
+			h.putUser(w, r, name)
+		default:
+			methodNotAllowed(w, r, http.MethodPut)
+		}
+		return
+	}
+
+	switch r.RequestURI {
+	case "/settings":
+		switch r.Method {
+		case http.MethodGet:
+			h.getSettings(w)
+		case http.MethodPatch:
+			h.patchSettings(w, r)
+		default:
+			methodNotAllowed(w, r, http.MethodGet, http.MethodPatch)
+		}
+	case "/clients":
+		switch r.Method {
+		case http.MethodGet:
+			h.getClients(w)
+		default:
+			methodNotAllowed(w, r, http.MethodGet)
+		}
+	case "/users":
+		switch r.Method {
+		case http.MethodGet:
+			h.getUsers(w)
+		default:
+			methodNotAllowed(w, r, http.MethodGet)
+		}
+	default:
+		routeNotFound(w, r)
+	}
+}
+
+func (h *shadowsocksHandler) getSettings(w http.ResponseWriter) {
+	settings := h.loop.GetSettings()
+	settings.Password = redactString(settings.Password)
+	settings.TCP.Password = redactString(settings.TCP.Password)
+	settings.UDP.Password = redactString(settings.UDP.Password)
+	settings.Users = redactShadowsocksUsers(settings.Users)
+	encodeResponse(w, settings, h.warner)
+}
+
+// redactShadowsocksUsers returns a copy of the users
+// given with their passwords redacted.
+func redactShadowsocksUsers(users []settings.ShadowsocksUser) (
+	redactedUsers []settings.ShadowsocksUser) {
+	if users == nil {
+		return nil
+	}
+	redactedUsers = make([]settings.ShadowsocksUser, len(users))
+	for i, user := range users {
+		user.Password = *redactString(&user.Password)
+		redactedUsers[i] = user
+	}
+	return redactedUsers
+}
+
+// unredactShadowsocksUsers sets the password of users with
+// the redacted password to the current password of the user
+// with the same name, if any.
+func unredactShadowsocksUsers(users, currentUsers []settings.ShadowsocksUser) {
+	for i, user := range users {
+		if user.Password != redacted {
+			continue
+		}
+		for _, currentUser := range currentUsers {
+			if currentUser.Name == user.Name {
+				users[i].Password = currentUser.Password
+				break
+			}
+		}
+	}
+}
+
+func (h *shadowsocksHandler) patchSettings(w http.ResponseWriter, r *http.Request) {
+	var overrideSettings settings.Shadowsocks
+	if !decodeSettings(w, r, &overrideSettings, h.warner) {
+		return
+	}
+	overrideSettings.Password = unredactString(overrideSettings.Password)
+	overrideSettings.TCP.Password = unredactString(overrideSettings.TCP.Password)
+	overrideSettings.UDP.Password = unredactString(overrideSettings.UDP.Password)
+
+	updatedSettings := h.loop.GetSettings() // already copied
+	unredactShadowsocksUsers(overrideSettings.Users, updatedSettings.Users)
+	updatedSettings.OverrideWith(overrideSettings)
+	err := updatedSettings.Validate()
+	if err != nil {
+		httpError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	outcome := h.loop.SetSettings(h.ctx, updatedSettings)
+	encodeResponse(w, outcomeWrapper{Outcome: outcome}, h.warner)
+}
+
+func (h *shadowsocksHandler) getClients(w http.ResponseWriter) {
+	clients := h.loop.GetClientStats()
+	if clients == nil {
+		clients = []accesslog.ClientStats{}
+	}
+	encodeResponse(w, clientStatsWrapper{Clients: clients}, h.warner)
+}
+
+type shadowsocksUsersWrapper struct {
+	Users []relay.UserStats `json:"users"`
+}
+
+func (h *shadowsocksHandler) getUsers(w http.ResponseWriter) {
+	users := h.loop.GetUserStats()
+	if users == nil {
+		users = []relay.UserStats{}
+	}
+	encodeResponse(w, shadowsocksUsersWrapper{Users: users}, h.warner)
+}
+
+type enabledWrapper struct {
+	Enabled *bool `json:"enabled"`
+}
+
+func (h *shadowsocksHandler) putUser(w http.ResponseWriter, r *http.Request,
+	name string) {
+	var data enabledWrapper
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&data)
+	if err != nil {
+		httpError(w, http.StatusBadRequest, err.Error())
+		return
+	} else if data.Enabled == nil {
+		httpError(w, http.StatusBadRequest, "enabled field is missing")
+		return
+	}
+
+	err = h.loop.SetUserEnabled(name, *data.Enabled)
+	switch {
+	case errors.Is(err, relay.ErrUserNotFound):
+		httpError(w, http.StatusNotFound, err.Error())
+		return
+	case err != nil:
+		httpError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	outcome := "user " + name + " disabled"
+	if *data.Enabled {
+		outcome = "user " + name + " enabled"
+	}
+	encodeResponse(w, outcomeWrapper{Outcome: outcome}, h.warner)
+}
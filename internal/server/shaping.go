@@ -0,0 +1,66 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+)
+
+func newShapingHandler(shaper Shaper, warner warner) http.Handler {
+	return &shapingHandler{
+		shaper: shaper,
+		warner: warner,
+	}
+}
+
+type shapingHandler struct {
+	shaper Shaper
+	warner warner
+}
+
+func (h *shapingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.RequestURI = strings.TrimPrefix(r.RequestURI, "/shaping")
+	switch r.RequestURI {
+	case "/settings":
+		switch r.Method {
+		case http.MethodGet:
+			h.getSettings(w)
+		case http.MethodPatch:
+			h.patchSettings(w, r)
+		default:
+			methodNotAllowed(w, r, http.MethodGet, http.MethodPatch)
+		}
+	case "/throughput":
+		switch r.Method {
+		case http.MethodGet:
+			encodeResponse(w, h.shaper.Throughput(), h.warner)
+		default:
+			methodNotAllowed(w, r, http.MethodGet)
+		}
+	default:
+		routeNotFound(w, r)
+	}
+}
+
+func (h *shapingHandler) getSettings(w http.ResponseWriter) {
+	encodeResponse(w, settings.ShapingFromLimits(h.shaper.Limits()), h.warner)
+}
+
+func (h *shapingHandler) patchSettings(w http.ResponseWriter, r *http.Request) {
+	var overrideSettings settings.Shaping
+	if !decodeSettings(w, r, &overrideSettings, h.warner) {
+		return
+	}
+
+	updatedSettings := settings.ShapingFromLimits(h.shaper.Limits())
+	updatedSettings.OverrideWith(overrideSettings)
+	err := updatedSettings.Validate()
+	if err != nil {
+		httpError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	h.shaper.SetLimits(updatedSettings.Limits())
+	encodeResponse(w, outcomeWrapper{Outcome: "limits updated"}, h.warner)
+}
@@ -8,13 +8,16 @@ import (
 
 	"github.com/qdm12/gluetun/internal/configuration/settings"
 	"github.com/qdm12/gluetun/internal/models"
+	"github.com/qdm12/gluetun/internal/updater"
 )
 
 type UpdaterLooper interface {
 	GetStatus() (status models.LoopStatus)
 	SetStatus(ctx context.Context, status models.LoopStatus) (
 		outcome string, err error)
+	GetSettings() (settings settings.Updater)
 	SetSettings(settings settings.Updater) (outcome string)
+	GetDiffs() (diffs []updater.ProviderDiff)
 }
 
 func newUpdaterHandler(
@@ -44,10 +47,26 @@ func (h *updaterHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		case http.MethodPut:
 			h.setStatus(w, r)
 		default:
-			http.Error(w, "method "+r.Method+" not supported", http.StatusBadRequest)
+			methodNotAllowed(w, r, http.MethodGet, http.MethodPut)
+		}
+	case "/diff":
+		switch r.Method {
+		case http.MethodGet:
+			h.getDiffs(w)
+		default:
+			methodNotAllowed(w, r, http.MethodGet)
+		}
+	case "/settings":
+		switch r.Method {
+		case http.MethodGet:
+			h.getSettings(w)
+		case http.MethodPatch:
+			h.patchSettings(w, r)
+		default:
+			methodNotAllowed(w, r, http.MethodGet, http.MethodPatch)
 		}
 	default:
-		http.Error(w, "route "+r.RequestURI+" not supported", http.StatusBadRequest)
+		routeNotFound(w, r)
 	}
 }
 
@@ -66,23 +85,49 @@ func (h *updaterHandler) setStatus(w http.ResponseWriter, r *http.Request) {
 	decoder := json.NewDecoder(r.Body)
 	var data statusWrapper
 	if err := decoder.Decode(&data); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		httpError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 	status, err := data.getStatus()
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		httpError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 	outcome, err := h.looper.SetStatus(h.ctx, status)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		httpError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 	encoder := json.NewEncoder(w)
 	if err := encoder.Encode(outcomeWrapper{Outcome: outcome}); err != nil {
 		h.warner.Warn(err.Error())
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		httpError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
 		return
 	}
 }
+
+func (h *updaterHandler) getSettings(w http.ResponseWriter) {
+	encodeResponse(w, h.looper.GetSettings(), h.warner)
+}
+
+func (h *updaterHandler) patchSettings(w http.ResponseWriter, r *http.Request) {
+	var overrideSettings settings.Updater
+	if !decodeSettings(w, r, &overrideSettings, h.warner) {
+		return
+	}
+
+	updatedSettings := h.looper.GetSettings() // already copied
+	updatedSettings.OverrideWith(overrideSettings)
+	err := updatedSettings.Validate()
+	if err != nil {
+		httpError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	outcome := h.looper.SetSettings(updatedSettings)
+	encodeResponse(w, outcomeWrapper{Outcome: outcome}, h.warner)
+}
+
+func (h *updaterHandler) getDiffs(w http.ResponseWriter) {
+	encodeResponse(w, diffsWrapper{Diffs: h.looper.GetDiffs()}, h.warner)
+}
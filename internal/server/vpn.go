@@ -3,10 +3,12 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/qdm12/gluetun/internal/vpn"
 )
 
 func newVPNHandler(ctx context.Context, looper VPNLooper,
@@ -30,6 +32,20 @@ type vpnHandler struct {
 
 func (h *vpnHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	r.RequestURI = strings.TrimPrefix(r.RequestURI, "/vpn")
+	if name, ok := strings.CutPrefix(r.RequestURI, "/tunnels/"); ok {
+		if name, ok = strings.CutSuffix(name, "/settings"); ok && name != "" {
+			switch r.Method {
+			case http.MethodGet:
+				h.getTunnelSettings(w, name)
+			case http.MethodPatch:
+				h.patchTunnelSettings(w, r, name)
+			default:
+				methodNotAllowed(w, r, http.MethodGet, http.MethodPatch)
+			}
+			return
+		}
+	}
+
 	switch r.RequestURI {
 	case "/status":
 		switch r.Method {
@@ -38,19 +54,33 @@ func (h *vpnHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		case http.MethodPut:
 			h.setStatus(w, r)
 		default:
-			http.Error(w, "method "+r.Method+" not supported", http.StatusBadRequest)
+			methodNotAllowed(w, r, http.MethodGet, http.MethodPut)
 		}
 	case "/settings":
 		switch r.Method {
 		case http.MethodGet:
 			h.getSettings(w)
-		case http.MethodPut:
+		case http.MethodPut, http.MethodPatch:
 			h.patchSettings(w, r)
 		default:
-			http.Error(w, "method "+r.Method+" not supported", http.StatusBadRequest)
+			methodNotAllowed(w, r, http.MethodGet, http.MethodPut, http.MethodPatch)
+		}
+	case "/tunnels":
+		switch r.Method {
+		case http.MethodGet:
+			h.getTunnels(w)
+		default:
+			methodNotAllowed(w, r, http.MethodGet)
+		}
+	case "/tunnels/status":
+		switch r.Method {
+		case http.MethodPut:
+			h.setTunnelStatus(w, r)
+		default:
+			methodNotAllowed(w, r, http.MethodPut)
 		}
 	default:
-		http.Error(w, "route "+r.RequestURI+" not supported", http.StatusBadRequest)
+		routeNotFound(w, r)
 	}
 }
 
@@ -69,62 +99,221 @@ func (h *vpnHandler) setStatus(w http.ResponseWriter, r *http.Request) {
 	decoder := json.NewDecoder(r.Body)
 	var data statusWrapper
 	if err := decoder.Decode(&data); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		httpError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 	status, err := data.getStatus()
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		httpError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 	outcome, err := h.looper.ApplyStatus(h.ctx, status)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		httpError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 	encoder := json.NewEncoder(w)
 	if err := encoder.Encode(outcomeWrapper{Outcome: outcome}); err != nil {
 		h.warner.Warn(err.Error())
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		httpError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
 		return
 	}
 }
 
 func (h *vpnHandler) getSettings(w http.ResponseWriter) {
-	settings := h.looper.GetSettings()
+	settings := h.looper.GetSettings() // already copied
+	redactOpenVPN(&settings.OpenVPN)
+	redactWireguard(&settings.Wireguard)
+	for i := range settings.Tunnels {
+		redactOpenVPN(&settings.Tunnels[i].OpenVPN)
+		redactWireguard(&settings.Tunnels[i].Wireguard)
+	}
+	encodeResponse(w, settings, h.warner)
+}
+
+func (h *vpnHandler) patchSettings(w http.ResponseWriter, r *http.Request) {
+	var overrideSettings settings.VPN
+	if !decodeSettings(w, r, &overrideSettings, h.warner) {
+		return
+	}
+	unredactOpenVPN(&overrideSettings.OpenVPN)
+	unredactWireguard(&overrideSettings.Wireguard)
+
+	updatedSettings := h.looper.GetSettings() // already copied
+	unredactTunnels(overrideSettings.Tunnels, updatedSettings.Tunnels)
+	updatedSettings.OverrideWith(overrideSettings)
+	// Tunnels given replace the current tunnels as a whole,
+	// so their unset fields are set to their defaults.
+	updatedSettings.SetDefaults()
+	err := updatedSettings.Validate(h.storage, h.ipv6Supported)
+	if err != nil {
+		httpError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	outcome := h.looper.SetSettings(h.ctx, updatedSettings)
+	encodeResponse(w, outcomeWrapper{Outcome: outcome}, h.warner)
+}
+
+func (h *vpnHandler) getTunnels(w http.ResponseWriter) {
+	data := tunnelsWrapper{Tunnels: h.looper.GetTunnels()}
 	encoder := json.NewEncoder(w)
-	if err := encoder.Encode(settings); err != nil {
+	if err := encoder.Encode(data); err != nil {
 		h.warner.Warn(err.Error())
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 }
 
-func (h *vpnHandler) patchSettings(w http.ResponseWriter, r *http.Request) {
-	var overrideSettings settings.VPN
+func (h *vpnHandler) setTunnelStatus(w http.ResponseWriter, r *http.Request) {
 	decoder := json.NewDecoder(r.Body)
-	err := decoder.Decode(&overrideSettings)
+	var data tunnelStatusWrapper
+	if err := decoder.Decode(&data); err != nil {
+		httpError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	status, err := data.getStatus()
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		httpError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-
-	err = r.Body.Close()
+	outcome, err := h.looper.ApplyTunnelStatus(data.Name, status)
 	if err != nil {
-		h.warner.Warn("closing body: " + err.Error())
+		httpError(w, http.StatusBadRequest, err.Error())
+		return
 	}
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(outcomeWrapper{Outcome: outcome}); err != nil {
+		h.warner.Warn(err.Error())
+		httpError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+}
+
+// findTunnel returns the index of the tunnel with the name given,
+// writing a not found error response and returning -1 if there is
+// no such tunnel.
+func findTunnel(w http.ResponseWriter, tunnels []settings.Tunnel,
+	name string) (index int) {
+	for i, tunnel := range tunnels {
+		if tunnel.Name == name {
+			return i
+		}
+	}
+	httpError(w, http.StatusNotFound, vpn.ErrTunnelNotFound.Error()+": "+name)
+	return -1
+}
+
+func (h *vpnHandler) getTunnelSettings(w http.ResponseWriter,
+	name string) {
+	tunnels := h.looper.GetSettings().Tunnels
+	index := findTunnel(w, tunnels, name)
+	if index == -1 {
+		return
+	}
+	tunnel := tunnels[index]
+	redactOpenVPN(&tunnel.OpenVPN)
+	redactWireguard(&tunnel.Wireguard)
+	encodeResponse(w, tunnel, h.warner)
+}
+
+func (h *vpnHandler) patchTunnelSettings(w http.ResponseWriter, r *http.Request,
+	name string) {
+	var overrideTunnel settings.Tunnel
+	if !decodeSettings(w, r, &overrideTunnel, h.warner) {
+		return
+	}
+	unredactOpenVPN(&overrideTunnel.OpenVPN)
+	unredactWireguard(&overrideTunnel.Wireguard)
 
 	updatedSettings := h.looper.GetSettings() // already copied
-	updatedSettings.OverrideWith(overrideSettings)
-	err = updatedSettings.Validate(h.storage, h.ipv6Supported)
+	index := findTunnel(w, updatedSettings.Tunnels, name)
+	if index == -1 {
+		return
+	}
+	updatedSettings.Tunnels[index].OverrideWith(overrideTunnel)
+	err := updatedSettings.Validate(h.storage, h.ipv6Supported)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		httpError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	outcome := h.looper.SetSettings(h.ctx, updatedSettings)
-	_, err = w.Write([]byte(outcome))
-	if err != nil {
-		h.warner.Warn("writing response: " + err.Error())
+	outcome, err := h.looper.SetTunnelSettings(updatedSettings.Tunnels[index])
+	switch {
+	case errors.Is(err, vpn.ErrTunnelNotFound):
+		httpError(w, http.StatusNotFound, err.Error())
+		return
+	case err != nil:
+		httpError(w, http.StatusInternalServerError, err.Error())
+		return
 	}
+	encodeResponse(w, outcomeWrapper{Outcome: outcome}, h.warner)
+}
+
+// redactOpenVPN redacts the secret values of the OpenVPN settings given.
+func redactOpenVPN(openvpn *settings.OpenVPN) {
+	openvpn.Password = redactString(openvpn.Password)
+	openvpn.Key = redactString(openvpn.Key)
+	openvpn.EncryptedKey = redactString(openvpn.EncryptedKey)
+	openvpn.KeyPassphrase = redactString(openvpn.KeyPassphrase)
+	openvpn.TOTPSecret = redactString(openvpn.TOTPSecret)
+}
+
+// unredactOpenVPN unsets the redacted secret values of the
+// OpenVPN settings given, so the current values are kept.
+func unredactOpenVPN(openvpn *settings.OpenVPN) {
+	openvpn.Password = unredactString(openvpn.Password)
+	openvpn.Key = unredactString(openvpn.Key)
+	openvpn.EncryptedKey = unredactString(openvpn.EncryptedKey)
+	openvpn.KeyPassphrase = unredactString(openvpn.KeyPassphrase)
+	openvpn.TOTPSecret = unredactString(openvpn.TOTPSecret)
+}
+
+// unredactTunnels sets the redacted secret values of the tunnels
+// given to the current secret values of the tunnel with the same
+// name, if any, since tunnels replace the current tunnels as a whole.
+func unredactTunnels(tunnels, currentTunnels []settings.Tunnel) {
+	for i := range tunnels {
+		tunnel := &tunnels[i]
+		for _, currentTunnel := range currentTunnels {
+			if currentTunnel.Name != tunnel.Name {
+				continue
+			}
+			openvpn, currentOpenVPN := &tunnel.OpenVPN, currentTunnel.OpenVPN
+			openvpn.Password = restoreString(openvpn.Password, currentOpenVPN.Password)
+			openvpn.Key = restoreString(openvpn.Key, currentOpenVPN.Key)
+			openvpn.EncryptedKey = restoreString(openvpn.EncryptedKey, currentOpenVPN.EncryptedKey)
+			openvpn.KeyPassphrase = restoreString(openvpn.KeyPassphrase, currentOpenVPN.KeyPassphrase)
+			openvpn.TOTPSecret = restoreString(openvpn.TOTPSecret, currentOpenVPN.TOTPSecret)
+			wireguard, currentWireguard := &tunnel.Wireguard, currentTunnel.Wireguard
+			wireguard.PrivateKey = restoreString(wireguard.PrivateKey, currentWireguard.PrivateKey)
+			wireguard.PreSharedKey = restoreString(wireguard.PreSharedKey, currentWireguard.PreSharedKey)
+			break
+		}
+		// Redacted values of new tunnels are left unset.
+		unredactOpenVPN(&tunnel.OpenVPN)
+		unredactWireguard(&tunnel.Wireguard)
+	}
+}
+
+// restoreString returns the current value given if the value
+// given is the redacted string, and the value otherwise.
+func restoreString(value, current *string) *string {
+	if value != nil && *value == redacted {
+		return current
+	}
+	return value
+}
+
+// redactWireguard redacts the secret values of the Wireguard settings given.
+func redactWireguard(wireguard *settings.Wireguard) {
+	wireguard.PrivateKey = redactString(wireguard.PrivateKey)
+	wireguard.PreSharedKey = redactString(wireguard.PreSharedKey)
+}
+
+// unredactWireguard unsets the redacted secret values of the
+// Wireguard settings given, so the current values are kept.
+func unredactWireguard(wireguard *settings.Wireguard) {
+	wireguard.PrivateKey = unredactString(wireguard.PrivateKey)
+	wireguard.PreSharedKey = unredactString(wireguard.PreSharedKey)
 }
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/qdm12/gluetun/internal/constants/providers"
+	"github.com/qdm12/gluetun/internal/constants/vpn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSettingsVPNLooper struct {
+	VPNLooper
+	settings settings.VPN
+}
+
+func (f *fakeSettingsVPNLooper) GetSettings() settings.VPN {
+	return f.settings.Copy()
+}
+
+func (f *fakeSettingsVPNLooper) SetSettings(_ context.Context, settings settings.VPN) string {
+	f.settings = settings
+	return "settings updated"
+}
+
+func (f *fakeSettingsVPNLooper) SetTunnelSettings(tunnel settings.Tunnel) (string, error) {
+	for i := range f.settings.Tunnels {
+		if f.settings.Tunnels[i].Name == tunnel.Name {
+			f.settings.Tunnels[i] = tunnel
+		}
+	}
+	return "settings updated", nil
+}
+
+func newFakeVPNSettings() settings.VPN {
+	allSettings := settings.Settings{VPN: settings.VPN{
+		Type:     vpn.Wireguard,
+		Provider: settings.Provider{Name: ptrTo(providers.Mullvad)},
+		Wireguard: settings.Wireguard{
+			PrivateKey: ptrTo("aPjc9US5ICB30D1P4glR9tO7bkB2Ga+KZiFqnoypBHk="),
+			Addresses:  []netip.Prefix{netip.MustParsePrefix("10.64.0.2/32")},
+		},
+		Tunnels: []settings.Tunnel{{
+			Name: "us",
+			Type: vpn.OpenVPN,
+			OpenVPN: settings.OpenVPN{
+				User:     ptrTo("user"),
+				Password: ptrTo("secret"),
+			},
+		}},
+	}}
+	allSettings.SetDefaults()
+	return allSettings.VPN
+}
+
+func Test_vpnHandler_settings(t *testing.T) {
+	t.Parallel()
+
+	looper := &fakeSettingsVPNLooper{settings: newFakeVPNSettings()}
+	handler := newVPNHandler(context.Background(), looper,
+		newFakeLoops(), false, noopWarner{})
+
+	request := httptest.NewRequest(http.MethodGet, "/vpn/settings", nil)
+	request.RequestURI = "/vpn/settings"
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"PrivateKey":"[redacted]"`)
+	assert.Contains(t, recorder.Body.String(), `"Password":"[redacted]"`)
+	assert.NotContains(t, recorder.Body.String(), "aPjc9US5ICB30D1P4glR9tO7bkB2Ga+KZiFqnoypBHk=")
+	assert.NotContains(t, recorder.Body.String(), "secret")
+
+	body := strings.NewReader(`{"Wireguard":{"PrivateKey":"[redacted]"},` +
+		`"Tunnels":[{"Name":"us","Type":"openvpn",` +
+		`"OpenVPN":{"User":"other","Password":"[redacted]"}}]}`)
+	request = httptest.NewRequest(http.MethodPatch, "/vpn/settings", body)
+	request.RequestURI = "/vpn/settings"
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	assert.Equal(t, "aPjc9US5ICB30D1P4glR9tO7bkB2Ga+KZiFqnoypBHk=",
+		*looper.settings.Wireguard.PrivateKey)
+	require.Len(t, looper.settings.Tunnels, 1)
+	tunnel := looper.settings.Tunnels[0]
+	assert.Equal(t, "other", *tunnel.OpenVPN.User)
+	assert.Equal(t, "secret", *tunnel.OpenVPN.Password)
+
+	body = strings.NewReader(`{"Unknown":true}`)
+	request = httptest.NewRequest(http.MethodPatch, "/vpn/settings", body)
+	request.RequestURI = "/vpn/settings"
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func Test_vpnHandler_tunnelSettings(t *testing.T) {
+	t.Parallel()
+
+	looper := &fakeSettingsVPNLooper{settings: newFakeVPNSettings()}
+	handler := newVPNHandler(context.Background(), looper,
+		newFakeLoops(), false, noopWarner{})
+
+	request := httptest.NewRequest(http.MethodGet, "/vpn/tunnels/us/settings", nil)
+	request.RequestURI = "/vpn/tunnels/us/settings"
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"Password":"[redacted]"`)
+	assert.NotContains(t, recorder.Body.String(), "secret")
+
+	body := strings.NewReader(`{"Name":"uk","OpenVPN":{"User":"other","Password":"[redacted]"},` +
+		`"Sources":["10.0.0.0/24"]}`)
+	request = httptest.NewRequest(http.MethodPatch, "/vpn/tunnels/us/settings", body)
+	request.RequestURI = "/vpn/tunnels/us/settings"
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	tunnel := looper.settings.Tunnels[0]
+	assert.Equal(t, "us", tunnel.Name)
+	assert.Equal(t, "other", *tunnel.OpenVPN.User)
+	assert.Equal(t, "secret", *tunnel.OpenVPN.Password)
+	assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/24")}, tunnel.Sources)
+
+	body = strings.NewReader(`{"Unknown":true}`)
+	request = httptest.NewRequest(http.MethodPatch, "/vpn/tunnels/us/settings", body)
+	request.RequestURI = "/vpn/tunnels/us/settings"
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	request = httptest.NewRequest(http.MethodGet, "/vpn/tunnels/uk/settings", nil)
+	request.RequestURI = "/vpn/tunnels/uk/settings"
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
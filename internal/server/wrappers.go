@@ -4,8 +4,11 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/qdm12/gluetun/internal/accesslog"
 	"github.com/qdm12/gluetun/internal/constants"
 	"github.com/qdm12/gluetun/internal/models"
+	"github.com/qdm12/gluetun/internal/openvpn"
+	"github.com/qdm12/gluetun/internal/updater"
 )
 
 type statusWrapper struct {
@@ -25,10 +28,41 @@ func (sw *statusWrapper) getStatus() (status models.LoopStatus, err error) {
 	}
 }
 
+type tunnelsWrapper struct {
+	Tunnels []models.TunnelStatus `json:"tunnels"`
+}
+
+type tunnelStatusWrapper struct {
+	Name string `json:"name"`
+	statusWrapper
+}
+
 type portWrapper struct {
 	Port uint16 `json:"port"`
 }
 
+type challengeWrapper struct {
+	// Challenge is nil if no challenge is pending.
+	Challenge *openvpn.Challenge `json:"challenge"`
+}
+
+type challengeResponseWrapper struct {
+	Response string `json:"response"`
+}
+
 type outcomeWrapper struct {
 	Outcome string `json:"outcome"`
 }
+
+type exitCheckWrapper struct {
+	// ExitCheck is nil if no exit check was done.
+	ExitCheck *models.ExitCheck `json:"exit_check"`
+}
+
+type clientStatsWrapper struct {
+	Clients []accesslog.ClientStats `json:"clients"`
+}
+
+type diffsWrapper struct {
+	Diffs []updater.ProviderDiff `json:"diffs"`
+}
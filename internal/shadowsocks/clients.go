@@ -0,0 +1,8 @@
+package shadowsocks
+
+import "github.com/qdm12/gluetun/internal/accesslog"
+
+// GetClientStats returns the traffic proxied per client.
+func (l *Loop) GetClientStats() (stats []accesslog.ClientStats) {
+	return l.recorder.Clients()
+}
@@ -5,16 +5,21 @@ import (
 	"sync"
 	"time"
 
+	"github.com/qdm12/gluetun/internal/accesslog"
 	"github.com/qdm12/gluetun/internal/configuration/settings"
 	"github.com/qdm12/gluetun/internal/constants"
 	"github.com/qdm12/gluetun/internal/models"
-	shadowsockslib "github.com/qdm12/ss-server/pkg/tcpudp"
+	"github.com/qdm12/gluetun/internal/ratelimit"
+	"github.com/qdm12/gluetun/internal/shadowsocks/relay"
 )
 
 type Loop struct {
 	state state
 	// Other objects
-	logger Logger
+	logger   Logger
+	recorder *accesslog.Recorder
+	users    *relay.Users
+	shaper   *ratelimit.Shaper
 	// Internal channels and locks
 	loopLock      sync.Mutex
 	running       chan models.LoopStatus
@@ -41,13 +46,17 @@ func (l *Loop) logAndWait(ctx context.Context, err error) {
 
 const defaultBackoffTime = 10 * time.Second
 
-func NewLoop(settings settings.Shadowsocks, logger Logger) *Loop {
+func NewLoop(settings settings.Shadowsocks, logger Logger,
+	accessLog *accesslog.Logger, shaper *ratelimit.Shaper) *Loop {
 	return &Loop{
 		state: state{
 			status:   constants.Stopped,
 			settings: settings,
 		},
 		logger:      logger,
+		recorder:    accesslog.NewRecorder(accessLog),
+		users:       relay.NewUsers(),
+		shaper:      shaper,
 		start:       make(chan struct{}),
 		running:     make(chan models.LoopStatus),
 		stop:        make(chan struct{}),
@@ -75,7 +84,8 @@ func (l *Loop) Run(ctx context.Context, done chan<- struct{}) {
 
 	for ctx.Err() == nil {
 		settings := l.GetSettings()
-		server, err := shadowsockslib.NewServer(settings.Settings, l.logger)
+		server, err := relay.New(settings.Settings, settings.RelayUsers(),
+			l.users, l.logger, l.recorder, l.shaper)
 		if err != nil {
 			crashed = true
 			l.logAndWait(ctx, err)
@@ -0,0 +1,109 @@
+package relay
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+)
+
+// SOCKS address types.
+const (
+	addressTypeIPv4       = 1
+	addressTypeDomainName = 3
+	addressTypeIPv6       = 4
+)
+
+// address is a SOCKS address, made of its type,
+// the IPv4 address, IPv6 address or length-prefixed
+// domain name, followed by the big endian port.
+type address []byte
+
+func (a address) String() string {
+	var host string
+	switch a[0] {
+	case addressTypeIPv4:
+		host = netip.AddrFrom4([4]byte(a[1 : 1+4])).String()
+	case addressTypeIPv6:
+		host = netip.AddrFrom16([16]byte(a[1 : 1+16])).String()
+	case addressTypeDomainName:
+		host = string(a[2 : 2+int(a[1])])
+	}
+	port := binary.BigEndian.Uint16(a[len(a)-2:])
+	return net.JoinHostPort(host, strconv.Itoa(int(port)))
+}
+
+var (
+	ErrAddressTypeNotSupported = errors.New("address type is not supported")
+	ErrAddressTooShort         = errors.New("address is too short")
+)
+
+// addressLength returns the length of the address from its first
+// two bytes, which are the address type and, for a domain name,
+// its length.
+func addressLength(header []byte) (length int, err error) {
+	const portSize = 2
+	switch header[0] {
+	case addressTypeIPv4:
+		return 1 + net.IPv4len + portSize, nil
+	case addressTypeIPv6:
+		return 1 + net.IPv6len + portSize, nil
+	case addressTypeDomainName:
+		if len(header) < 2 { //nolint:gomnd
+			return 0, fmt.Errorf("%w: domain name length is missing", ErrAddressTooShort)
+		}
+		return 1 + 1 + int(header[1]) + portSize, nil
+	default:
+		return 0, fmt.Errorf("%w: %d", ErrAddressTypeNotSupported, header[0])
+	}
+}
+
+// readAddress reads a SOCKS address from the reader given.
+func readAddress(reader io.Reader) (a address, err error) {
+	const maxAddressLength = 1 + 1 + 255 + 2
+	buffer := make([]byte, maxAddressLength)
+	_, err = io.ReadFull(reader, buffer[:2])
+	if err != nil {
+		return nil, fmt.Errorf("reading address: %w", err)
+	}
+	length, err := addressLength(buffer[:2])
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.ReadFull(reader, buffer[2:length])
+	if err != nil {
+		return nil, fmt.Errorf("reading address: %w", err)
+	}
+	return buffer[:length], nil
+}
+
+// extractAddress returns the SOCKS address at the start of the packet.
+func extractAddress(packet []byte) (a address, err error) {
+	if len(packet) == 0 {
+		return nil, fmt.Errorf("%w: packet is empty", ErrAddressTooShort)
+	}
+	length, err := addressLength(packet)
+	if err != nil {
+		return nil, err
+	}
+	if len(packet) < length {
+		return nil, fmt.Errorf("%w: packet has %d bytes and address needs %d bytes",
+			ErrAddressTooShort, len(packet), length)
+	}
+	return packet[:length], nil
+}
+
+// newAddress returns the SOCKS address for the IP address and port given.
+func newAddress(addrPort netip.AddrPort) (a address) {
+	ip := addrPort.Addr().Unmap()
+	if ip.Is4() {
+		a = append(a, addressTypeIPv4)
+	} else {
+		a = append(a, addressTypeIPv6)
+	}
+	a = append(a, ip.AsSlice()...)
+	return binary.BigEndian.AppendUint16(a, addrPort.Port())
+}
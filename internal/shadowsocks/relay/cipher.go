@@ -0,0 +1,185 @@
+package relay
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"  //nolint:gosec
+	"crypto/sha1" //nolint:gosec
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+	"lukechampine.com/blake3"
+)
+
+const (
+	AES128GCM            = "aes-128-gcm"
+	AES256GCM            = "aes-256-gcm"
+	Chacha20IetfPoly1305 = "chacha20-ietf-poly1305"
+	// Shadowsocks 2022 edition ciphers, using a base64 encoded
+	// pre-shared key of the cipher key size as password.
+	Blake3AES128GCM        = "2022-blake3-aes-128-gcm"
+	Blake3AES256GCM        = "2022-blake3-aes-256-gcm"
+	Blake3Chacha20Poly1305 = "2022-blake3-chacha20-poly1305"
+)
+
+var (
+	ErrCipherNotSupported = errors.New("cipher is not supported")
+	ErrKeyNotValid        = errors.New("key is not valid")
+)
+
+// aeadCipher is a Shadowsocks AEAD cipher using a
+// pre-shared key derived from the password.
+type aeadCipher struct {
+	key     []byte
+	newAEAD func(key []byte) (cipher.AEAD, error)
+	// edition2022 is true for the Shadowsocks 2022 ciphers,
+	// deriving subkeys with BLAKE3 and using timestamped
+	// request and response headers.
+	edition2022 bool
+	// udpBlock encrypts the separate header of Shadowsocks 2022
+	// UDP packets. It is nil for the other ciphers, and for the
+	// 2022 chacha20-poly1305 cipher which uses XChaCha20-Poly1305.
+	udpBlock cipher.Block
+}
+
+// ValidateCipher returns an error if the cipher name is not supported
+// or if the password is not a valid key for a 2022 edition cipher.
+func ValidateCipher(name, password string) (err error) {
+	_, err = newCipher(name, password)
+	return err
+}
+
+func newCipher(name, password string) (c *aeadCipher, err error) {
+	switch strings.ToLower(name) {
+	case AES128GCM:
+		const keySize = 16
+		return &aeadCipher{key: kdf(password, keySize), newAEAD: newAESGCM}, nil
+	case AES256GCM:
+		const keySize = 32
+		return &aeadCipher{key: kdf(password, keySize), newAEAD: newAESGCM}, nil
+	case Chacha20IetfPoly1305:
+		return &aeadCipher{
+			key:     kdf(password, chacha20poly1305.KeySize),
+			newAEAD: chacha20poly1305.New,
+		}, nil
+	case Blake3AES128GCM, Blake3AES256GCM:
+		keySize := 16
+		if strings.ToLower(name) == Blake3AES256GCM {
+			keySize = 32
+		}
+		key, err := decodeKey(password, keySize)
+		if err != nil {
+			return nil, err
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return &aeadCipher{
+			key:         key,
+			newAEAD:     newAESGCM,
+			edition2022: true,
+			udpBlock:    block,
+		}, nil
+	case Blake3Chacha20Poly1305:
+		key, err := decodeKey(password, chacha20poly1305.KeySize)
+		if err != nil {
+			return nil, err
+		}
+		return &aeadCipher{
+			key:         key,
+			newAEAD:     chacha20poly1305.New,
+			edition2022: true,
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrCipherNotSupported, name)
+	}
+}
+
+// decodeKey decodes the base64 encoded pre-shared key
+// of a 2022 edition cipher and checks its size.
+func decodeKey(password string, keySize int) (key []byte, err error) {
+	key, err = base64.StdEncoding.DecodeString(password)
+	if err != nil {
+		return nil, fmt.Errorf("%w: decoding base64: %w", ErrKeyNotValid, err)
+	} else if len(key) != keySize {
+		return nil, fmt.Errorf("%w: %d bytes instead of %d",
+			ErrKeyNotValid, len(key), keySize)
+	}
+	return key, nil
+}
+
+// saltSize returns the size of the salt prefixing
+// each stream and each packet.
+func (c *aeadCipher) saltSize() int {
+	const minimumSaltSize = 16
+	if len(c.key) > minimumSaltSize {
+		return len(c.key)
+	}
+	return minimumSaltSize
+}
+
+// aead returns the AEAD using the subkey derived
+// from the pre-shared key and the salt given.
+func (c *aeadCipher) aead(salt []byte) (aead cipher.AEAD, err error) {
+	subkey := make([]byte, len(c.key))
+	if c.edition2022 {
+		material := make([]byte, 0, len(c.key)+len(salt))
+		material = append(material, c.key...)
+		material = append(material, salt...)
+		blake3.DeriveKey(subkey, "shadowsocks 2022 session subkey", material)
+		return c.newAEAD(subkey)
+	}
+	reader := hkdf.New(sha1.New, c.key, salt, []byte("ss-subkey"))
+	_, err = io.ReadFull(reader, subkey)
+	if err != nil {
+		return nil, fmt.Errorf("deriving subkey: %w", err)
+	}
+	return c.newAEAD(subkey)
+}
+
+// maxPayloadSize returns the maximum size of the payload of a chunk.
+func (c *aeadCipher) maxPayloadSize() int {
+	if c.edition2022 {
+		return maxPayloadSize2022
+	}
+	return maxPayloadSize
+}
+
+func newAESGCM(key []byte) (aead cipher.AEAD, err error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// kdf is the key derivation function from the original
+// Shadowsocks specification, based on MD5.
+func kdf(password string, length int) (key []byte) {
+	var previous []byte
+	hasher := md5.New() //nolint:gosec
+	for len(key) < length {
+		_, _ = hasher.Write(previous)
+		_, _ = hasher.Write([]byte(password))
+		key = hasher.Sum(key)
+		previous = key[len(key)-hasher.Size():]
+		hasher.Reset()
+	}
+	return key[:length]
+}
+
+// increment increments the little endian nonce given.
+func increment(nonce []byte) {
+	for i := range nonce {
+		nonce[i]++
+		if nonce[i] != 0 {
+			return
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package relay
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Header types of the Shadowsocks 2022 edition.
+const (
+	headerTypeClient = 0
+	headerTypeServer = 1
+)
+
+const (
+	// maxTimeDifference is the maximum difference between the
+	// timestamp of a Shadowsocks 2022 header and the current time.
+	maxTimeDifference = 30 * time.Second
+	timestampSize     = 8
+	sessionIDSize     = 8
+	packetIDSize      = 8
+	// separateHeaderSize is the size of the session ID and packet
+	// ID header of Shadowsocks 2022 UDP packets.
+	separateHeaderSize = sessionIDSize + packetIDSize
+	paddingLengthSize  = 2
+)
+
+var (
+	ErrHeaderTooShort     = errors.New("header is too short")
+	ErrHeaderTypeNotValid = errors.New("header type is not valid")
+	ErrTimestampNotValid  = errors.New("timestamp is not valid")
+)
+
+// appendHeader2022 appends the type and timestamp
+// of a Shadowsocks 2022 header to the buffer given.
+func appendHeader2022(buffer []byte, headerType byte, now time.Time) []byte {
+	buffer = append(buffer, headerType)
+	return binary.BigEndian.AppendUint64(buffer, uint64(now.Unix()))
+}
+
+// checkHeader2022 checks the type and timestamp at the start
+// of the Shadowsocks 2022 header given.
+func checkHeader2022(header []byte, headerType byte, now time.Time) (err error) {
+	if len(header) < 1+timestampSize {
+		return fmt.Errorf("%w: %d bytes", ErrHeaderTooShort, len(header))
+	} else if header[0] != headerType {
+		return fmt.Errorf("%w: %d instead of %d", ErrHeaderTypeNotValid, header[0], headerType)
+	}
+	timestamp := time.Unix(int64(binary.BigEndian.Uint64(header[1:1+timestampSize])), 0)
+	return checkTimestamp(timestamp, now)
+}
+
+// checkTimestamp checks the timestamp of a Shadowsocks 2022
+// header is close enough to the current time.
+func checkTimestamp(timestamp, now time.Time) (err error) {
+	difference := now.Sub(timestamp)
+	if difference > maxTimeDifference || difference < -maxTimeDifference {
+		return fmt.Errorf("%w: %s differs from current time by %s",
+			ErrTimestampNotValid, timestamp.UTC().Format(time.RFC3339), difference)
+	}
+	return nil
+}
+
+// extractVariableHeader returns the SOCKS address and the initial
+// payload from the variable length header of a Shadowsocks 2022
+// TCP request, which contains the address followed by padding.
+func extractVariableHeader(header []byte) (target address, payload []byte, err error) {
+	target, err = extractAddress(header)
+	if err != nil {
+		return nil, nil, err
+	}
+	payload, err = skipPadding(header[len(target):])
+	if err != nil {
+		return nil, nil, err
+	}
+	return target, payload, nil
+}
+
+// skipPadding returns the data following the length
+// prefixed padding at the start of the data given.
+func skipPadding(data []byte) (rest []byte, err error) {
+	if len(data) < paddingLengthSize {
+		return nil, fmt.Errorf("%w: padding length is missing", ErrHeaderTooShort)
+	}
+	paddingLength := int(binary.BigEndian.Uint16(data))
+	data = data[paddingLengthSize:]
+	if len(data) < paddingLength {
+		return nil, fmt.Errorf("%w: padding has %d bytes instead of %d",
+			ErrHeaderTooShort, len(data), paddingLength)
+	}
+	return data[paddingLength:], nil
+}
+
+// packet2022 contains the header fields of a Shadowsocks 2022
+// UDP packet, which precede the SOCKS address and payload.
+type packet2022 struct {
+	sessionID  [sessionIDSize]byte
+	packetID   uint64
+	headerType byte
+	timestamp  time.Time
+	// clientSessionID is the session ID of the client,
+	// and is only set in packets sent by the server.
+	clientSessionID [sessionIDSize]byte
+}
+
+// pack2022 encrypts the header and plaintext given as a Shadowsocks
+// 2022 UDP packet, using the buffer given. The plaintext is made of
+// the SOCKS address followed by the payload.
+func (c *aeadCipher) pack2022(buffer []byte, header packet2022,
+	plaintext []byte) (packet []byte, err error) {
+	var separateHeader [separateHeaderSize]byte
+	copy(separateHeader[:], header.sessionID[:])
+	binary.BigEndian.PutUint64(separateHeader[sessionIDSize:], header.packetID)
+
+	body := make([]byte, 0, separateHeaderSize+1+timestampSize+
+		sessionIDSize+paddingLengthSize+len(plaintext))
+	if c.udpBlock == nil {
+		body = append(body, separateHeader[:]...)
+	}
+	body = appendHeader2022(body, header.headerType, header.timestamp)
+	if header.headerType == headerTypeServer {
+		body = append(body, header.clientSessionID[:]...)
+	}
+	body = binary.BigEndian.AppendUint16(body, 0) // no padding
+	body = append(body, plaintext...)
+
+	if c.udpBlock == nil {
+		aead, err := chacha20poly1305.NewX(c.key)
+		if err != nil {
+			return nil, err
+		}
+		nonce := buffer[:aead.NonceSize()]
+		_, err = rand.Read(nonce)
+		if err != nil {
+			return nil, fmt.Errorf("generating nonce: %w", err)
+		}
+		if len(nonce)+len(body)+aead.Overhead() > len(buffer) {
+			return nil, fmt.Errorf("%w: %d bytes of plaintext", ErrPacketTooLarge, len(plaintext))
+		}
+		return aead.Seal(nonce, nonce, body, nil), nil
+	}
+
+	aead, err := c.aead(header.sessionID[:])
+	if err != nil {
+		return nil, err
+	}
+	if separateHeaderSize+len(body)+aead.Overhead() > len(buffer) {
+		return nil, fmt.Errorf("%w: %d bytes of plaintext", ErrPacketTooLarge, len(plaintext))
+	}
+	// The nonce is the last 12 bytes of the plaintext separate header.
+	nonce := separateHeader[separateHeaderSize-aead.NonceSize():]
+	packet = buffer[:separateHeaderSize]
+	c.udpBlock.Encrypt(packet, separateHeader[:])
+	return aead.Seal(packet, nonce, body, nil), nil
+}
+
+// unpack2022 decrypts the Shadowsocks 2022 UDP packet given, and
+// returns its header and its plaintext, made of the SOCKS address
+// followed by the payload. The packet given is left unchanged.
+func (c *aeadCipher) unpack2022(packet []byte) (header packet2022,
+	plaintext []byte, err error) {
+	if c.udpBlock != nil {
+		if len(packet) < separateHeaderSize {
+			return header, nil, fmt.Errorf("%w: %d bytes", ErrPacketTooShort, len(packet))
+		}
+		var separateHeader [separateHeaderSize]byte
+		c.udpBlock.Decrypt(separateHeader[:], packet[:separateHeaderSize])
+		return c.open2022(separateHeader, packet[separateHeaderSize:])
+	}
+
+	const nonceSize = chacha20poly1305.NonceSizeX
+	if len(packet) < nonceSize {
+		return header, nil, fmt.Errorf("%w: %d bytes", ErrPacketTooShort, len(packet))
+	}
+	aead, err := chacha20poly1305.NewX(c.key)
+	if err != nil {
+		return header, nil, err
+	}
+	body, err := aead.Open(nil, packet[:nonceSize], packet[nonceSize:], nil)
+	if err != nil {
+		return header, nil, fmt.Errorf("decrypting packet: %w", err)
+	}
+	if len(body) < separateHeaderSize {
+		return header, nil, fmt.Errorf("%w: %d bytes", ErrPacketTooShort, len(packet))
+	}
+	copy(header.sessionID[:], body)
+	header.packetID = binary.BigEndian.Uint64(body[sessionIDSize:])
+	plaintext, err = parseBody2022(&header, body[separateHeaderSize:])
+	return header, plaintext, err
+}
+
+// open2022 decrypts the sealed body of a Shadowsocks 2022 AES UDP
+// packet using its decrypted separate header, and returns the packet
+// header and its plaintext, made of the SOCKS address followed by
+// the payload. The sealed body given is left unchanged.
+func (c *aeadCipher) open2022(separateHeader [separateHeaderSize]byte,
+	sealedBody []byte) (header packet2022, plaintext []byte, err error) {
+	copy(header.sessionID[:], separateHeader[:])
+	header.packetID = binary.BigEndian.Uint64(separateHeader[sessionIDSize:])
+	aead, err := c.aead(header.sessionID[:])
+	if err != nil {
+		return header, nil, err
+	}
+	nonce := separateHeader[separateHeaderSize-aead.NonceSize():]
+	body, err := aead.Open(nil, nonce, sealedBody, nil)
+	if err != nil {
+		return header, nil, fmt.Errorf("decrypting packet: %w", err)
+	}
+	plaintext, err = parseBody2022(&header, body)
+	return header, plaintext, err
+}
+
+// parseBody2022 parses the decrypted body of a Shadowsocks 2022 UDP
+// packet into the header given, and returns the plaintext, made of
+// the SOCKS address followed by the payload.
+func parseBody2022(header *packet2022, body []byte) (plaintext []byte, err error) {
+	if len(body) < 1+timestampSize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrHeaderTooShort, len(body))
+	}
+	header.headerType = body[0]
+	header.timestamp = time.Unix(int64(binary.BigEndian.Uint64(body[1:])), 0)
+	rest := body[1+timestampSize:]
+	if header.headerType == headerTypeServer {
+		if len(rest) < sessionIDSize {
+			return nil, fmt.Errorf("%w: client session ID is missing", ErrHeaderTooShort)
+		}
+		copy(header.clientSessionID[:], rest)
+		rest = rest[sessionIDSize:]
+	}
+	return skipPadding(rest)
+}
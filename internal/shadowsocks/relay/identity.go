@@ -0,0 +1,102 @@
+package relay
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+
+	"lukechampine.com/blake3"
+)
+
+// identityHeaderSize is the size of a Shadowsocks 2022
+// extensible identity header (EIH).
+const identityHeaderSize = 16
+
+// identities identifies the users sharing a listening address from
+// the Shadowsocks 2022 extensible identity headers sent by clients.
+// The identity pre-shared key (iPSK) is the key of the default user,
+// and clients of other users use it together with their own key.
+// Only the 2022 AES ciphers support identity headers.
+type identities struct {
+	// key is the identity pre-shared key.
+	key []byte
+	// block encrypts with the identity pre-shared key.
+	block cipher.Block
+	// users maps the first 16 bytes of the BLAKE3 hash
+	// of each user key to the user.
+	users map[[identityHeaderSize]byte]*user
+}
+
+// newIdentities returns the identities of the users given, using
+// the cipher of each user returned by the function given. It returns
+// nil if identity headers cannot be used for the users given, that is
+// if the first user is not the default user, if there is no other user
+// or if the cipher is not a 2022 AES cipher.
+func newIdentities(users []*user, userCipher func(user *user) *aeadCipher) *identities {
+	if len(users) <= 1 || users[0].name != DefaultUserName {
+		return nil
+	}
+	identityCipher := userCipher(users[0])
+	if !identityCipher.edition2022 || identityCipher.udpBlock == nil {
+		return nil
+	}
+
+	identities := &identities{
+		key:   identityCipher.key,
+		block: identityCipher.udpBlock,
+		users: make(map[[identityHeaderSize]byte]*user, len(users)-1),
+	}
+	for _, user := range users[1:] {
+		identities.users[identityHash(userCipher(user).key)] = user
+	}
+	return identities
+}
+
+func userTCPCipher(user *user) *aeadCipher { return user.tcpCipher }
+func userUDPCipher(user *user) *aeadCipher { return user.udpCipher }
+
+// identityHash returns the first 16 bytes of
+// the BLAKE3 hash of the user key given.
+func identityHash(key []byte) (hash [identityHeaderSize]byte) {
+	sum := blake3.Sum256(key)
+	copy(hash[:], sum[:])
+	return hash
+}
+
+// tcpUser returns the user identified by the identity header
+// given, sent after the request salt given, or nil if the data
+// given is not an identity header of a known user.
+func (i *identities) tcpUser(salt, identityHeader []byte) (user *user, err error) {
+	material := make([]byte, 0, len(i.key)+len(salt))
+	material = append(material, i.key...)
+	material = append(material, salt...)
+	subkey := make([]byte, len(i.key))
+	blake3.DeriveKey(subkey, "shadowsocks 2022 identity subkey", material)
+	block, err := aes.NewCipher(subkey)
+	if err != nil {
+		return nil, fmt.Errorf("creating identity cipher: %w", err)
+	}
+
+	var hash [identityHeaderSize]byte
+	block.Decrypt(hash[:], identityHeader)
+	return i.users[hash], nil
+}
+
+// udpUser returns the user identified by the identity header of
+// the Shadowsocks 2022 UDP packet given, together with the decrypted
+// separate header of the packet. It returns a nil user if the packet
+// has no identity header of a known user.
+func (i *identities) udpUser(packet []byte) (user *user,
+	separateHeader [separateHeaderSize]byte) {
+	if len(packet) < separateHeaderSize+identityHeaderSize {
+		return nil, separateHeader
+	}
+	i.block.Decrypt(separateHeader[:], packet[:separateHeaderSize])
+
+	var hash [identityHeaderSize]byte
+	i.block.Decrypt(hash[:], packet[separateHeaderSize:separateHeaderSize+identityHeaderSize])
+	for j := range hash {
+		hash[j] ^= separateHeader[j]
+	}
+	return i.users[hash], separateHeader
+}
@@ -0,0 +1,419 @@
+package relay
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/accesslog"
+	"github.com/qdm12/ss-server/pkg/tcp"
+	"github.com/qdm12/ss-server/pkg/udp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/chacha20poly1305"
+	"lukechampine.com/blake3"
+)
+
+// Test_interop_ssServer checks the legacy AEAD ciphers of the relay,
+// that is the key derivation, stream chunks, packets and addresses,
+// are compatible with the qdm12/ss-server reference implementation
+// the relay replaced, by exchanging data through an ss-server.
+func Test_interop_ssServer(t *testing.T) {
+	t.Parallel()
+
+	tcpEcho, udpEcho := newEchoServers(t)
+
+	for _, cipherName := range []string{AES128GCM, AES256GCM, Chacha20IetfPoly1305} {
+		cipherName := cipherName
+		t.Run(cipherName, func(t *testing.T) {
+			t.Parallel()
+
+			const password = "password"
+			tcpAddress, udpAddress := newSSServer(t, cipherName, password)
+			cipher, err := newCipher(cipherName, password)
+			require.NoError(t, err)
+
+			echoed, err := exchangeTCP(t, tcpAddress, cipher, tcpEcho, "hello")
+			require.NoError(t, err)
+			assert.Equal(t, "hello", echoed)
+
+			echoed, err = exchangeUDP(t, udpAddress, cipher, udpEcho, "hello")
+			require.NoError(t, err)
+			assert.Equal(t, "hello", echoed)
+		})
+	}
+}
+
+// newSSServer runs ss-server TCP and UDP servers with the cipher
+// and password given, and returns their listening addresses once
+// both servers are listening. The servers are stopped on cleanup.
+func newSSServer(t *testing.T, cipherName, password string) (
+	tcpAddress, udpAddress net.Addr) {
+	t.Helper()
+
+	tcpAddress = freeAddress(t, "tcp")
+	tcpServer, err := tcp.NewServer(tcp.Settings{
+		Address:    tcpAddress.String(),
+		CipherName: cipherName,
+		Password:   &password,
+	}, noopLogger{})
+	require.NoError(t, err)
+
+	udpAddress = freeAddress(t, "udp")
+	udpLogger := &listeningLogger{listening: make(chan struct{})}
+	udpServer, err := udp.NewServer(udp.Settings{
+		Address:    udpAddress.String(),
+		CipherName: cipherName,
+		Password:   &password,
+	}, udpLogger)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tcpDone := make(chan struct{})
+	go func() {
+		defer close(tcpDone)
+		_ = tcpServer.Listen(ctx)
+	}()
+	udpDone := make(chan struct{})
+	go func() {
+		defer close(udpDone)
+		_ = udpServer.Listen(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-tcpDone
+		<-udpDone
+		// The goroutine of each ss-server UDP session only
+		// exits after its one minute read timeout.
+	})
+
+	// The UDP server closes its packet connection on cancelation
+	// in a goroutine started before it finishes setting up the
+	// connection, so waiting for its listening log is required for
+	// the context to be canceled after the setup without data race.
+	select {
+	case <-udpLogger.listening:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "UDP server is not listening")
+	}
+
+	require.Eventually(t, func() bool {
+		connection, err := net.Dial("tcp", tcpAddress.String())
+		if err != nil {
+			return false
+		}
+		_ = connection.Close()
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+
+	return tcpAddress, udpAddress
+}
+
+// listeningLogger is a logger for the ss-server UDP server closing
+// the listening channel once the server logs it is listening.
+type listeningLogger struct {
+	noopLogger
+	listening chan struct{}
+	once      sync.Once
+}
+
+func (l *listeningLogger) Info(s string) {
+	if strings.HasPrefix(s, "listening UDP on ") {
+		l.once.Do(func() { close(l.listening) })
+	}
+}
+
+// freeAddress returns a local address with a port
+// free at the time of the call for the network given.
+func freeAddress(t *testing.T, network string) (address net.Addr) {
+	t.Helper()
+
+	if network == "udp" {
+		connection, err := net.ListenPacket(network, "127.0.0.1:0")
+		require.NoError(t, err)
+		address = connection.LocalAddr()
+		require.NoError(t, connection.Close())
+		return address
+	}
+
+	listener, err := net.Listen(network, "127.0.0.1:0")
+	require.NoError(t, err)
+	address = listener.Addr()
+	require.NoError(t, listener.Close())
+	return address
+}
+
+// Test_edition2022_specification checks the relay follows the wire
+// format of the Shadowsocks 2022 edition specification (SIP022), which
+// ss-server does not implement, by building requests and parsing
+// responses byte by byte without the encoding code of the relay.
+// Clients of the default user use its key only, and clients of other
+// users use their key, and the default user key as identity key to
+// send an identity header with AES ciphers.
+func Test_edition2022_specification(t *testing.T) {
+	t.Parallel()
+
+	tcpEcho, udpEcho := newEchoServers(t)
+
+	testCases := map[string]struct {
+		keySize         int
+		newAEAD         func(key []byte) (cipher.AEAD, error)
+		identityHeaders bool
+	}{
+		Blake3AES128GCM:        {keySize: 16, newAEAD: newAESGCM, identityHeaders: true},
+		Blake3AES256GCM:        {keySize: 32, newAEAD: newAESGCM, identityHeaders: true},
+		Blake3Chacha20Poly1305: {keySize: 32, newAEAD: chacha20poly1305.New},
+	}
+
+	for cipherName, testCase := range testCases {
+		cipherName, testCase := cipherName, testCase
+		t.Run(cipherName, func(t *testing.T) {
+			t.Parallel()
+
+			defaultKey := make([]byte, testCase.keySize)
+			_, err := rand.Read(defaultKey)
+			require.NoError(t, err)
+			defaultPassword := base64.StdEncoding.EncodeToString(defaultKey)
+			aliceKey := make([]byte, testCase.keySize)
+			_, err = rand.Read(aliceKey)
+			require.NoError(t, err)
+			alicePassword := base64.StdEncoding.EncodeToString(aliceKey)
+			registry := NewUsers()
+			users, err := registry.configure([]userKeys{
+				{name: DefaultUserName, tcpPassword: defaultPassword, udpPassword: defaultPassword},
+				{name: "alice", tcpPassword: alicePassword, udpPassword: alicePassword},
+			}, cipherName, cipherName)
+			require.NoError(t, err)
+			tcpAddress, udpAddress := newTestServer(t, users, accesslog.NewRecorder(nil), nil)
+
+			echoed := exchangeTCP2022(t, tcpAddress, defaultKey, nil, testCase.newAEAD, tcpEcho)
+			assert.Equal(t, "hello", echoed)
+			echoed = exchangeUDP2022(t, udpAddress, defaultKey, nil, cipherName, udpEcho)
+			assert.Equal(t, "hello", echoed)
+
+			// Clients configured with their user key only
+			// are identified by trying each user key.
+			echoed = exchangeTCP2022(t, tcpAddress, aliceKey, nil, testCase.newAEAD, tcpEcho)
+			assert.Equal(t, "hello", echoed)
+			echoed = exchangeUDP2022(t, udpAddress, aliceKey, nil, cipherName, udpEcho)
+			assert.Equal(t, "hello", echoed)
+			// Each UDP exchange is a distinct client session
+			// which remains active until it times out.
+			expectedAliceConnections, expectedAliceSessions := uint64(2), int64(1)
+
+			if testCase.identityHeaders {
+				echoed = exchangeTCP2022(t, tcpAddress, aliceKey, defaultKey, testCase.newAEAD, tcpEcho)
+				assert.Equal(t, "hello", echoed)
+				echoed = exchangeUDP2022(t, udpAddress, aliceKey, defaultKey, cipherName, udpEcho)
+				assert.Equal(t, "hello", echoed)
+				expectedAliceConnections += 2
+				expectedAliceSessions++
+			}
+
+			assert.Eventually(t, func() bool {
+				stats := registry.Stats()
+				return stats[0].Connections == expectedAliceConnections &&
+					stats[0].ActiveConnections == expectedAliceSessions
+			}, time.Second, 10*time.Millisecond)
+		})
+	}
+}
+
+// sessionAEAD returns the AEAD using the session subkey derived
+// from the pre-shared key and the salt, or session ID, given.
+func sessionAEAD(t *testing.T, key, salt []byte,
+	newAEAD func(key []byte) (cipher.AEAD, error)) cipher.AEAD {
+	t.Helper()
+	subkey := make([]byte, len(key))
+	blake3.DeriveKey(subkey, "shadowsocks 2022 session subkey",
+		append(append([]byte(nil), key...), salt...))
+	aead, err := newAEAD(subkey)
+	require.NoError(t, err)
+	return aead
+}
+
+// counterNonce returns the little endian counter nonce
+// of the given index for the TCP stream chunks.
+func counterNonce(index uint64, size int) []byte {
+	nonce := make([]byte, size)
+	binary.LittleEndian.PutUint64(nonce, index)
+	return nonce
+}
+
+func socksAddress(addrPort netip.AddrPort) []byte {
+	ip := addrPort.Addr().As4()
+	socks := append([]byte{1}, ip[:]...) // IPv4 address type
+	return binary.BigEndian.AppendUint16(socks, addrPort.Port())
+}
+
+// exchangeTCP2022 sends "hello" to the target given through the
+// Shadowsocks server using the key given, and returns the response
+// of the target. If the identity key given is not nil, the request
+// contains an identity header.
+func exchangeTCP2022(t *testing.T, server net.Addr, key, identityKey []byte,
+	newAEAD func(key []byte) (cipher.AEAD, error),
+	target netip.AddrPort) (response string) {
+	t.Helper()
+
+	connection, err := net.Dial("tcp", server.String())
+	require.NoError(t, err)
+	defer connection.Close()
+	err = connection.SetDeadline(time.Now().Add(time.Second))
+	require.NoError(t, err)
+
+	requestSalt := make([]byte, len(key))
+	_, err = rand.Read(requestSalt)
+	require.NoError(t, err)
+	aead := sessionAEAD(t, key, requestSalt, newAEAD)
+
+	variableHeader := socksAddress(target)
+	variableHeader = binary.BigEndian.AppendUint16(variableHeader, 3)
+	variableHeader = append(variableHeader, 0, 0, 0) // padding
+	variableHeader = append(variableHeader, "hello"...)
+	fixedHeader := []byte{0} // client stream
+	fixedHeader = binary.BigEndian.AppendUint64(fixedHeader, uint64(time.Now().Unix()))
+	fixedHeader = binary.BigEndian.AppendUint16(fixedHeader, uint16(len(variableHeader)))
+
+	request := append([]byte(nil), requestSalt...)
+	if identityKey != nil {
+		identitySubkey := make([]byte, len(identityKey))
+		blake3.DeriveKey(identitySubkey, "shadowsocks 2022 identity subkey",
+			append(append([]byte(nil), identityKey...), requestSalt...))
+		block, err := aes.NewCipher(identitySubkey)
+		require.NoError(t, err)
+		keyHash := blake3.Sum256(key)
+		identityHeader := make([]byte, 16)
+		block.Encrypt(identityHeader, keyHash[:16])
+		request = append(request, identityHeader...)
+	}
+	request = aead.Seal(request, counterNonce(0, aead.NonceSize()), fixedHeader, nil)
+	request = aead.Seal(request, counterNonce(1, aead.NonceSize()), variableHeader, nil)
+	_, err = connection.Write(request)
+	require.NoError(t, err)
+
+	responseSalt := make([]byte, len(key))
+	_, err = io.ReadFull(connection, responseSalt)
+	require.NoError(t, err)
+	aead = sessionAEAD(t, key, responseSalt, newAEAD)
+
+	const fixedResponseHeaderSize = 1 + 8 + 2
+	sealed := make([]byte, fixedResponseHeaderSize+len(requestSalt)+aead.Overhead())
+	_, err = io.ReadFull(connection, sealed)
+	require.NoError(t, err)
+	header, err := aead.Open(nil, counterNonce(0, aead.NonceSize()), sealed, nil)
+	require.NoError(t, err)
+	assert.Equal(t, byte(1), header[0]) // server stream
+	timestamp := time.Unix(int64(binary.BigEndian.Uint64(header[1:9])), 0)
+	assert.WithinDuration(t, time.Now(), timestamp, 30*time.Second)
+	assert.Equal(t, requestSalt, header[9:9+len(requestSalt)])
+	length := binary.BigEndian.Uint16(header[9+len(requestSalt):])
+
+	sealed = make([]byte, int(length)+aead.Overhead())
+	_, err = io.ReadFull(connection, sealed)
+	require.NoError(t, err)
+	payload, err := aead.Open(nil, counterNonce(1, aead.NonceSize()), sealed, nil)
+	require.NoError(t, err)
+	return string(payload)
+}
+
+// exchangeUDP2022 sends "hello" to the target given through the
+// Shadowsocks server using the key given, and returns the response
+// of the target. If the identity key given is not nil, the packet
+// contains an identity header.
+func exchangeUDP2022(t *testing.T, server net.Addr, key, identityKey []byte,
+	cipherName string, target netip.AddrPort) (response string) {
+	t.Helper()
+
+	connection, err := net.Dial("udp", server.String())
+	require.NoError(t, err)
+	defer connection.Close()
+
+	separateHeader := make([]byte, 16)
+	_, err = rand.Read(separateHeader[:8]) // session ID
+	require.NoError(t, err)
+	binary.BigEndian.PutUint64(separateHeader[8:], 0) // packet ID
+
+	body := []byte{0} // client packet
+	body = binary.BigEndian.AppendUint64(body, uint64(time.Now().Unix()))
+	body = binary.BigEndian.AppendUint16(body, 2)
+	body = append(body, 0, 0) // padding
+	body = append(body, socksAddress(target)...)
+	body = append(body, "hello"...)
+
+	var packet []byte
+	if cipherName == Blake3Chacha20Poly1305 {
+		aead, err := chacha20poly1305.NewX(key)
+		require.NoError(t, err)
+		nonce := make([]byte, aead.NonceSize())
+		_, err = rand.Read(nonce)
+		require.NoError(t, err)
+		plaintext := append(append([]byte(nil), separateHeader...), body...)
+		packet = aead.Seal(nonce, nonce, plaintext, nil)
+	} else {
+		headerKey := key
+		if identityKey != nil {
+			headerKey = identityKey
+		}
+		block, err := aes.NewCipher(headerKey)
+		require.NoError(t, err)
+		packet = make([]byte, len(separateHeader))
+		block.Encrypt(packet, separateHeader)
+		if identityKey != nil {
+			keyHash := blake3.Sum256(key)
+			identityHeader := make([]byte, 16)
+			for i := range identityHeader {
+				identityHeader[i] = keyHash[i] ^ separateHeader[i]
+			}
+			block.Encrypt(identityHeader, identityHeader)
+			packet = append(packet, identityHeader...)
+		}
+		aead := sessionAEAD(t, key, separateHeader[:8], newAESGCM)
+		packet = aead.Seal(packet, separateHeader[4:], body, nil)
+	}
+	_, err = connection.Write(packet)
+	require.NoError(t, err)
+
+	buffer := make([]byte, maxPacketSize)
+	err = connection.SetReadDeadline(time.Now().Add(time.Second))
+	require.NoError(t, err)
+	n, err := connection.Read(buffer)
+	require.NoError(t, err)
+	packet = buffer[:n]
+
+	if cipherName == Blake3Chacha20Poly1305 {
+		aead, err := chacha20poly1305.NewX(key)
+		require.NoError(t, err)
+		body, err = aead.Open(nil, packet[:aead.NonceSize()], packet[aead.NonceSize():], nil)
+		require.NoError(t, err)
+		body = body[len(separateHeader):]
+	} else {
+		block, err := aes.NewCipher(key)
+		require.NoError(t, err)
+		serverSeparateHeader := make([]byte, len(separateHeader))
+		block.Decrypt(serverSeparateHeader, packet[:len(separateHeader)])
+		aead := sessionAEAD(t, key, serverSeparateHeader[:8], newAESGCM)
+		body, err = aead.Open(nil, serverSeparateHeader[4:], packet[len(separateHeader):], nil)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, byte(1), body[0]) // server packet
+	timestamp := time.Unix(int64(binary.BigEndian.Uint64(body[1:9])), 0)
+	assert.WithinDuration(t, time.Now(), timestamp, 30*time.Second)
+	assert.Equal(t, separateHeader[:8], body[9:17]) // client session ID
+	paddingLength := int(binary.BigEndian.Uint16(body[17:19]))
+	rest := body[19+paddingLength:]
+	source := socksAddress(target)
+	require.True(t, bytes.HasPrefix(rest, source))
+	return string(rest[len(source):])
+}
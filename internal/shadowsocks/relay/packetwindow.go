@@ -0,0 +1,99 @@
+package relay
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	packetWindowBlockBits = 64
+	packetWindowBlocks    = 32
+	// packetWindowSize is the number of packet IDs before the
+	// highest packet ID received which are still accepted.
+	packetWindowSize = packetWindowBlockBits * (packetWindowBlocks - 1)
+)
+
+// packetWindow is a sliding window of the packet IDs received for
+// a Shadowsocks 2022 UDP client session, to reject repeated packets
+// while accepting packets received out of order.
+type packetWindow struct {
+	// last is the highest packet ID received.
+	last   uint64
+	blocks [packetWindowBlocks]uint64
+	// lastReceived is the time a packet was last received.
+	lastReceived time.Time
+}
+
+// checkAndAdd returns true if the packet ID given was already
+// received or is too old, and records it as received otherwise.
+func (w *packetWindow) checkAndAdd(packetID uint64) (rejected bool) {
+	if packetID < w.last && w.last-packetID > packetWindowSize {
+		return true
+	}
+
+	blockIndex := packetID / packetWindowBlockBits
+	if packetID > w.last {
+		// Clear the blocks the window slides over.
+		lastBlockIndex := w.last / packetWindowBlockBits
+		blocksToClear := blockIndex - lastBlockIndex
+		if blocksToClear > packetWindowBlocks {
+			blocksToClear = packetWindowBlocks
+		}
+		for i := uint64(1); i <= blocksToClear; i++ {
+			w.blocks[(lastBlockIndex+i)%packetWindowBlocks] = 0
+		}
+		w.last = packetID
+	}
+
+	block := &w.blocks[blockIndex%packetWindowBlocks]
+	bit := uint64(1) << (packetID % packetWindowBlockBits)
+	if *block&bit != 0 {
+		return true
+	}
+	*block |= bit
+	return false
+}
+
+// packetWindows holds the packet windows of the
+// Shadowsocks 2022 UDP client sessions.
+type packetWindows struct {
+	mutex   sync.Mutex
+	windows map[string]*packetWindow
+	// lastPrune is the time windows of inactive
+	// sessions were last removed.
+	lastPrune time.Time
+}
+
+func newPacketWindows() *packetWindows {
+	return &packetWindows{
+		windows: make(map[string]*packetWindow),
+	}
+}
+
+// checkAndAdd returns true if the packet ID given was already
+// received or is too old for the client session given, and
+// records it as received otherwise.
+func (p *packetWindows) checkAndAdd(session string, packetID uint64,
+	now time.Time) (rejected bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	// Packets of inactive sessions are rejected because of their
+	// timestamp, so the windows of these sessions can be removed.
+	if now.Sub(p.lastPrune) > udpSessionTimeout {
+		for key, window := range p.windows {
+			if now.Sub(window.lastReceived) > udpSessionTimeout {
+				delete(p.windows, key)
+			}
+		}
+		p.lastPrune = now
+	}
+
+	window, ok := p.windows[session]
+	if !ok {
+		window = &packetWindow{}
+		p.windows[session] = window
+	}
+	window.lastReceived = now
+	return window.checkAndAdd(packetID)
+}
@@ -0,0 +1,467 @@
+package relay
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/accesslog"
+	"github.com/qdm12/gluetun/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type noopLogger struct{}
+
+func (noopLogger) Debug(string) {}
+func (noopLogger) Info(string)  {}
+func (noopLogger) Error(string) {}
+
+func Test_kdf(t *testing.T) {
+	t.Parallel()
+
+	// Key from the original Shadowsocks EVP_BytesToKey implementation.
+	key := kdf("password", 32)
+
+	const expected = "5f4dcc3b5aa765d61d8327deb882cf99" +
+		"2b95990a9151374abd8ff8c5a7a0fe08"
+	assert.Equal(t, expected, hex.EncodeToString(key))
+}
+
+func Test_stream(t *testing.T) {
+	t.Parallel()
+
+	cipher, err := newCipher(AES128GCM, "password")
+	require.NoError(t, err)
+	salt := make([]byte, cipher.saltSize())
+	aead, err := cipher.aead(salt)
+	require.NoError(t, err)
+
+	reader, writer := io.Pipe()
+	data := make([]byte, 3*maxPayloadSize)
+	_, err = rand.Read(data)
+	require.NoError(t, err)
+	go func() {
+		_, err := newStreamWriter(writer, aead, maxPayloadSize).Write(data)
+		_ = writer.CloseWithError(err)
+	}()
+
+	received, err := io.ReadAll(newStreamReader(reader, aead, maxPayloadSize))
+	require.NoError(t, err)
+	assert.Equal(t, data, received)
+}
+
+func newTestServer(t *testing.T, users []*user, recorder *accesslog.Recorder,
+	shaper *ratelimit.Shaper) (tcpAddress, udpAddress net.Addr) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tcp := &tcpServer{
+		address:    "127.0.0.1:0",
+		users:      users,
+		identities: newIdentities(users, userTCPCipher),
+		saltFilter: newSaltFilter(),
+		logger:     noopLogger{},
+		recorder:   recorder,
+		shaper:     shaper,
+		dialer:     &net.Dialer{},
+		timeNow:    time.Now,
+	}
+	udp := &udpServer{
+		address:    "127.0.0.1:0",
+		users:      users,
+		identities: newIdentities(users, userUDPCipher),
+		saltFilter: newSaltFilter(),
+		logger:     noopLogger{},
+		recorder:   recorder,
+		shaper:     shaper,
+		timeNow:    time.Now,
+	}
+
+	ready := make(chan net.Addr)
+	tcpDone := make(chan struct{})
+	go func() {
+		defer close(tcpDone)
+		_ = tcp.listen(ctx, ready)
+	}()
+	tcpAddress = <-ready
+	udpDone := make(chan struct{})
+	go func() {
+		defer close(udpDone)
+		_ = udp.listen(ctx, ready)
+	}()
+	udpAddress = <-ready
+
+	t.Cleanup(func() {
+		cancel()
+		<-tcpDone
+		<-udpDone
+	})
+	return tcpAddress, udpAddress
+}
+
+func newEchoServers(t *testing.T) (tcpAddress, udpAddress netip.AddrPort) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			connection, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer connection.Close()
+				_, _ = io.Copy(connection, connection)
+			}()
+		}
+	}()
+
+	packetConnection, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = packetConnection.Close() })
+	go func() {
+		buffer := make([]byte, maxPacketSize)
+		for {
+			n, address, err := packetConnection.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			_, _ = packetConnection.WriteTo(buffer[:n], address)
+		}
+	}()
+
+	return listener.Addr().(*net.TCPAddr).AddrPort(), //nolint:forcetypeassert
+		packetConnection.LocalAddr().(*net.UDPAddr).AddrPort() //nolint:forcetypeassert
+}
+
+// exchangeTCP sends the payload given to the target given through
+// the Shadowsocks server, and returns the response of the target.
+func exchangeTCP(t *testing.T, server net.Addr, cipher *aeadCipher,
+	target netip.AddrPort, payload string) (response string, err error) {
+	t.Helper()
+
+	connection, err := net.Dial("tcp", server.String())
+	require.NoError(t, err)
+	defer connection.Close()
+	err = connection.SetDeadline(time.Now().Add(time.Second))
+	require.NoError(t, err)
+
+	requestSalt := make([]byte, cipher.saltSize())
+	_, err = rand.Read(requestSalt)
+	require.NoError(t, err)
+	aead, err := cipher.aead(requestSalt)
+	require.NoError(t, err)
+	_, err = connection.Write(requestSalt)
+	require.NoError(t, err)
+	writer := newStreamWriter(connection, aead, cipher.maxPayloadSize())
+	request := append(newAddress(target), payload...)
+	if cipher.edition2022 {
+		writer.header = appendHeader2022(nil, headerTypeClient, time.Now())
+		request = append(newAddress(target), 0, 0) // no padding
+		request = append(request, payload...)
+	}
+	_, err = writer.Write(request)
+	require.NoError(t, err)
+
+	responseSalt := make([]byte, cipher.saltSize())
+	_, err = io.ReadFull(connection, responseSalt)
+	if err != nil {
+		return "", err
+	}
+	aead, err = cipher.aead(responseSalt)
+	require.NoError(t, err)
+	reader := newStreamReader(connection, aead, cipher.maxPayloadSize())
+	if cipher.edition2022 {
+		const lengthSize = 2
+		header, err := reader.readSealed(1 + timestampSize + len(requestSalt) + lengthSize)
+		require.NoError(t, err)
+		require.NoError(t, checkHeader2022(header, headerTypeServer, time.Now()))
+		assert.Equal(t, requestSalt, header[1+timestampSize:len(header)-lengthSize])
+		length := binary.BigEndian.Uint16(header[len(header)-lengthSize:])
+		reader.leftover, err = reader.readSealed(int(length))
+		require.NoError(t, err)
+	}
+	echoed := make([]byte, len(payload))
+	_, err = io.ReadFull(reader, echoed)
+	return string(echoed), err
+}
+
+// exchangeUDP sends the payload given to the target given through
+// the Shadowsocks server, and returns the response of the target.
+func exchangeUDP(t *testing.T, server net.Addr, cipher *aeadCipher,
+	target netip.AddrPort, payload string) (response string, err error) {
+	t.Helper()
+
+	connection, err := net.Dial("udp", server.String())
+	require.NoError(t, err)
+	defer connection.Close()
+
+	plaintext := append(newAddress(target), payload...)
+	var packet []byte
+	if cipher.edition2022 {
+		header := packet2022{headerType: headerTypeClient, timestamp: time.Now()}
+		_, err = rand.Read(header.sessionID[:])
+		require.NoError(t, err)
+		packet, err = cipher.pack2022(make([]byte, maxPacketSize), header, plaintext)
+	} else {
+		packet, err = cipher.pack(make([]byte, maxPacketSize), plaintext)
+	}
+	require.NoError(t, err)
+	_, err = connection.Write(packet)
+	require.NoError(t, err)
+
+	buffer := make([]byte, maxPacketSize)
+	err = connection.SetReadDeadline(time.Now().Add(time.Second))
+	require.NoError(t, err)
+	n, err := connection.Read(buffer)
+	if err != nil {
+		return "", err
+	}
+	if cipher.edition2022 {
+		var header packet2022
+		header, plaintext, err = cipher.unpack2022(buffer[:n])
+		require.NoError(t, err)
+		assert.Equal(t, byte(headerTypeServer), header.headerType)
+	} else {
+		plaintext, err = cipher.unpack(buffer[:n])
+	}
+	require.NoError(t, err)
+	source, err := extractAddress(plaintext)
+	require.NoError(t, err)
+	assert.Equal(t, target.String(), source.String())
+	return string(plaintext[len(source):]), nil
+}
+
+func Test_Server(t *testing.T) {
+	t.Parallel()
+
+	const password = "password"
+	recorder := accesslog.NewRecorder(nil)
+	users, err := NewUsers().configure([]userKeys{
+		{name: DefaultUserName, tcpPassword: password, udpPassword: password},
+	}, Chacha20IetfPoly1305, Chacha20IetfPoly1305)
+	require.NoError(t, err)
+	tcpAddress, udpAddress := newTestServer(t, users, recorder, nil)
+	tcpEcho, udpEcho := newEchoServers(t)
+	cipher, err := newCipher(Chacha20IetfPoly1305, password)
+	require.NoError(t, err)
+
+	t.Run("tcp", func(t *testing.T) {
+		echoed, err := exchangeTCP(t, tcpAddress, cipher, tcpEcho, "hello")
+		require.NoError(t, err)
+		assert.Equal(t, "hello", echoed)
+	})
+
+	t.Run("udp", func(t *testing.T) {
+		echoed, err := exchangeUDP(t, udpAddress, cipher, udpEcho, "hello")
+		require.NoError(t, err)
+		assert.Equal(t, "hello", echoed)
+	})
+
+	// The TCP connection is recorded once the server closes it.
+	assert.Eventually(t, func() bool {
+		clients := recorder.Clients()
+		return len(clients) == 1 && clients[0].Requests == 1
+	}, time.Second, 10*time.Millisecond)
+	clients := recorder.Clients()
+	assert.Equal(t, "127.0.0.1", clients[0].Client)
+	assert.Equal(t, uint64(len("hello")), clients[0].BytesUp)
+	assert.Equal(t, uint64(len("hello")), clients[0].BytesDown)
+}
+
+func Test_Server_shaping(t *testing.T) {
+	t.Parallel()
+
+	const password = "password"
+	users, err := NewUsers().configure([]userKeys{
+		{name: DefaultUserName, tcpPassword: password, udpPassword: password},
+	}, Chacha20IetfPoly1305, Chacha20IetfPoly1305)
+	require.NoError(t, err)
+	// A packet larger than the limiter burst is allowed with a full
+	// bucket, and the bucket is then empty for more than a second.
+	shaper := ratelimit.NewShaper(ratelimit.Limits{ClientUpload: 1500})
+	_, udpAddress := newTestServer(t, users, accesslog.NewRecorder(nil), shaper)
+	_, udpEcho := newEchoServers(t)
+	cipher, err := newCipher(Chacha20IetfPoly1305, password)
+	require.NoError(t, err)
+
+	payload := string(make([]byte, 2000))
+	echoed, err := exchangeUDP(t, udpAddress, cipher, udpEcho, payload)
+	require.NoError(t, err)
+	assert.Equal(t, payload, echoed)
+
+	_, err = exchangeUDP(t, udpAddress, cipher, udpEcho, payload)
+	var netErr net.Error
+	require.ErrorAs(t, err, &netErr)
+	assert.True(t, netErr.Timeout())
+
+	clients := shaper.Throughput().Clients
+	require.Len(t, clients, 1)
+	assert.Equal(t, "127.0.0.1", clients[0].Client)
+	assert.Equal(t, uint(2), clients[0].Streams)
+}
+
+func Test_Server_users(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		cipherName string
+		passwords  [2]string
+	}{
+		"aead": {
+			cipherName: AES256GCM,
+			passwords:  [2]string{"alice password", "bob password"},
+		},
+		"2022_aes": {
+			cipherName: Blake3AES128GCM,
+			passwords: [2]string{
+				base64.StdEncoding.EncodeToString(make([]byte, 16)),
+				base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 16)),
+			},
+		},
+		"2022_chacha20": {
+			cipherName: Blake3Chacha20Poly1305,
+			passwords: [2]string{
+				base64.StdEncoding.EncodeToString(make([]byte, 32)),
+				base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)),
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			registry := NewUsers()
+			users, err := registry.configure([]userKeys{
+				{name: "alice", tcpPassword: testCase.passwords[0], udpPassword: testCase.passwords[0]},
+				{name: "bob", tcpPassword: testCase.passwords[1], udpPassword: testCase.passwords[1]},
+			}, testCase.cipherName, testCase.cipherName)
+			require.NoError(t, err)
+			tcpAddress, udpAddress := newTestServer(t, users, accesslog.NewRecorder(nil), nil)
+			tcpEcho, udpEcho := newEchoServers(t)
+			bobCipher, err := newCipher(testCase.cipherName, testCase.passwords[1])
+			require.NoError(t, err)
+
+			echoed, err := exchangeTCP(t, tcpAddress, bobCipher, tcpEcho, "hello")
+			require.NoError(t, err)
+			assert.Equal(t, "hello", echoed)
+			echoed, err = exchangeUDP(t, udpAddress, bobCipher, udpEcho, "hi")
+			require.NoError(t, err)
+			assert.Equal(t, "hi", echoed)
+
+			assert.Eventually(t, func() bool {
+				stats := registry.Stats()
+				return stats[1].BytesSent == uint64(len("hello")+len("hi")) &&
+					stats[1].BytesReceived == uint64(len("hello")+len("hi"))
+			}, time.Second, 10*time.Millisecond)
+			stats := registry.Stats()
+			assert.Equal(t, UserStats{Name: "alice", Enabled: true}, stats[0])
+			assert.Equal(t, uint64(2), stats[1].Connections)
+
+			err = registry.SetEnabled("bob", false)
+			require.NoError(t, err)
+			_, err = exchangeTCP(t, tcpAddress, bobCipher, tcpEcho, "hello")
+			assert.Error(t, err)
+			_, err = exchangeUDP(t, udpAddress, bobCipher, udpEcho, "hi")
+			assert.Error(t, err)
+			assert.Equal(t, uint64(2), registry.Stats()[1].Rejected)
+
+			err = registry.SetEnabled("bob", true)
+			require.NoError(t, err)
+			echoed, err = exchangeTCP(t, tcpAddress, bobCipher, tcpEcho, "hello")
+			require.NoError(t, err)
+			assert.Equal(t, "hello", echoed)
+		})
+	}
+}
+
+func Test_Users_configure(t *testing.T) {
+	t.Parallel()
+
+	users := NewUsers()
+	_, err := users.configure([]userKeys{{name: "alice"}, {name: "bob"}},
+		Chacha20IetfPoly1305, Chacha20IetfPoly1305)
+	require.NoError(t, err)
+	err = users.SetEnabled("alice", false)
+	require.NoError(t, err)
+
+	_, err = users.configure([]userKeys{{name: "alice"}},
+		Chacha20IetfPoly1305, Chacha20IetfPoly1305)
+	require.NoError(t, err)
+
+	assert.Equal(t, []UserStats{{Name: "alice"}}, users.Stats())
+	err = users.SetEnabled("bob", false)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	_, err = users.configure([]userKeys{{name: "alice", tcpPassword: "not base64"}},
+		Blake3AES256GCM, Blake3AES256GCM)
+	assert.ErrorIs(t, err, ErrKeyNotValid)
+}
+
+func Test_saltFilter(t *testing.T) {
+	t.Parallel()
+
+	filter := newSaltFilter()
+	salt := make([]byte, 32)
+
+	assert.False(t, filter.checkAndAdd(salt))
+	assert.True(t, filter.checkAndAdd(salt))
+}
+
+func Test_packetWindow(t *testing.T) {
+	t.Parallel()
+
+	window := &packetWindow{}
+
+	for packetID := uint64(0); packetID < 3; packetID++ {
+		assert.False(t, window.checkAndAdd(packetID))
+	}
+	assert.True(t, window.checkAndAdd(1))
+
+	// Packets received out of order within the window are accepted once.
+	assert.False(t, window.checkAndAdd(100))
+	assert.False(t, window.checkAndAdd(50))
+	assert.True(t, window.checkAndAdd(50))
+	assert.True(t, window.checkAndAdd(100))
+
+	// Packets older than the window are rejected.
+	const last = 10 * packetWindowSize
+	assert.False(t, window.checkAndAdd(last))
+	assert.True(t, window.checkAndAdd(last-packetWindowSize-1))
+	assert.False(t, window.checkAndAdd(last-packetWindowSize))
+	assert.True(t, window.checkAndAdd(last-packetWindowSize))
+
+	// Sliding the window clears the packet IDs it slides over,
+	// which share their bit with the new packet IDs.
+	assert.False(t, window.checkAndAdd(last+packetWindowBlocks*packetWindowBlockBits))
+}
+
+func Test_packetWindows(t *testing.T) {
+	t.Parallel()
+
+	windows := newPacketWindows()
+	now := time.Unix(1000, 0)
+
+	assert.False(t, windows.checkAndAdd("alice 1", 0, now))
+	assert.True(t, windows.checkAndAdd("alice 1", 0, now))
+	assert.False(t, windows.checkAndAdd("alice 2", 0, now))
+	assert.False(t, windows.checkAndAdd("bob 1", 0, now))
+
+	// Windows of inactive sessions are removed.
+	now = now.Add(udpSessionTimeout + time.Second)
+	assert.False(t, windows.checkAndAdd("bob 1", 1, now))
+	assert.Len(t, windows.windows, 1)
+}
@@ -0,0 +1,76 @@
+package relay
+
+import (
+	"hash/fnv"
+	"sync"
+
+	"github.com/riobard/go-bloom"
+)
+
+// saltFilter detects repeated salts to mitigate replay attacks.
+// It is a ring of Bloom filters, where the oldest filter is reset
+// once the current filter reaches its capacity.
+type saltFilter struct {
+	mutex        sync.RWMutex
+	slots        []bloom.Filter
+	slotCapacity int
+	position     int
+	count        int
+}
+
+func newSaltFilter() *saltFilter {
+	const (
+		capacity          = 1e6
+		falsePositiveRate = 1e-6
+		slotsCount        = 10
+	)
+	filter := &saltFilter{
+		slots:        make([]bloom.Filter, slotsCount),
+		slotCapacity: capacity / slotsCount,
+	}
+	for i := range filter.slots {
+		filter.slots[i] = bloom.New(filter.slotCapacity, falsePositiveRate, doubleFNV)
+	}
+	return filter
+}
+
+// checkAndAdd returns true if the salt was already seen,
+// and adds it to the filter otherwise.
+func (f *saltFilter) checkAndAdd(salt []byte) (repeated bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for _, slot := range f.slots {
+		if slot.Test(salt) {
+			return true
+		}
+	}
+	f.add(salt)
+	return false
+}
+
+// addSalt adds a salt generated by the server to the filter.
+func (f *saltFilter) addSalt(salt []byte) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.add(salt)
+}
+
+func (f *saltFilter) add(salt []byte) {
+	if f.count >= f.slotCapacity {
+		f.position = (f.position + 1) % len(f.slots)
+		f.slots[f.position].Reset()
+		f.count = 0
+	}
+	f.count++
+	f.slots[f.position].Add(salt)
+}
+
+func doubleFNV(b []byte) (x, y uint64) {
+	hasher := fnv.New64()
+	_, _ = hasher.Write(b)
+	x = hasher.Sum64()
+	hasherA := fnv.New64a()
+	_, _ = hasherA.Write(b)
+	y = hasherA.Sum64()
+	return x, y
+}
@@ -0,0 +1,177 @@
+// Package relay implements a Shadowsocks AEAD server relaying
+// TCP and UDP traffic, and recording it in access logs.
+// It is used instead of the qdm12/ss-server servers since these
+// support neither the 2022 edition ciphers nor multiple users
+// sharing a listening address, and do not expose the byte counts
+// of the traffic they relay. It is tested to interoperate with
+// them for the ciphers they support. Users are told apart by the
+// identity header sent by clients of the 2022 edition AES ciphers
+// using the key of the default user as identity key, or otherwise
+// by trying each user key.
+package relay
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/accesslog"
+	"github.com/qdm12/gluetun/internal/ratelimit"
+	"github.com/qdm12/ss-server/pkg/tcpudp"
+)
+
+type Server struct {
+	tcp    []*tcpServer
+	udp    []*udpServer
+	logger Logger
+}
+
+// New creates a Shadowsocks server using the settings given,
+// recording connections proxied with the recorder given and
+// limiting their bandwidth with the shaper given, which can be nil.
+// The users given are registered in the users registry given,
+// in addition to the default user using the main password if
+// it is set or if no user is given. Users without address share
+// the main listening address.
+func New(settings tcpudp.Settings, userSettings []UserSettings, users *Users,
+	logger Logger, recorder *accesslog.Recorder, shaper *ratelimit.Shaper) (
+	server *Server, err error) {
+	settings.SetDefaults()
+
+	keys := make([]userKeys, 0, len(userSettings)+1)
+	// addresses are the listening addresses of the users,
+	// and are empty for users sharing the main address.
+	addresses := make([]string, 0, len(userSettings)+1)
+	if *settings.Password != "" || len(userSettings) == 0 {
+		keys = append(keys, userKeys{
+			name:        DefaultUserName,
+			tcpPassword: *settings.TCP.Password,
+			udpPassword: *settings.UDP.Password,
+		})
+		addresses = append(addresses, "")
+	}
+	for _, user := range userSettings {
+		keys = append(keys, userKeys{
+			name:        user.Name,
+			tcpPassword: user.Password,
+			udpPassword: user.Password,
+		})
+		addresses = append(addresses, user.Address)
+	}
+	configuredUsers, err := users.configure(keys,
+		settings.TCP.CipherName, settings.UDP.CipherName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Group users by listening address, keeping the order of
+	// the addresses. The TCP and UDP main addresses can differ.
+	type listener struct {
+		tcpAddress, udpAddress string
+		users                  []*user
+	}
+	var listeners []*listener
+	addressToListener := make(map[string]*listener)
+	for i, user := range configuredUsers {
+		key := addresses[i]
+		existing, ok := addressToListener[key]
+		if !ok {
+			existing = &listener{tcpAddress: key, udpAddress: key}
+			if key == "" {
+				existing.tcpAddress = settings.TCP.Address
+				existing.udpAddress = settings.UDP.Address
+			}
+			addressToListener[key] = existing
+			listeners = append(listeners, existing)
+		}
+		existing.users = append(existing.users, user)
+	}
+
+	server = &Server{logger: logger}
+	for _, listener := range listeners {
+		server.tcp = append(server.tcp, &tcpServer{
+			address:      listener.tcpAddress,
+			logAddresses: *settings.TCP.LogAddresses,
+			users:        listener.users,
+			identities:   newIdentities(listener.users, userTCPCipher),
+			saltFilter:   newSaltFilter(),
+			logger:       logger,
+			recorder:     recorder,
+			shaper:       shaper,
+			dialer:       &net.Dialer{},
+			timeNow:      time.Now,
+		})
+		server.udp = append(server.udp, &udpServer{
+			address:      listener.udpAddress,
+			logAddresses: *settings.UDP.LogAddresses,
+			users:        listener.users,
+			identities:   newIdentities(listener.users, userUDPCipher),
+			saltFilter:   newSaltFilter(),
+			logger:       logger,
+			recorder:     recorder,
+			shaper:       shaper,
+			timeNow:      time.Now,
+		})
+	}
+	return server, nil
+}
+
+// Listen runs the TCP and UDP servers until the context is
+// canceled or one of the servers fails.
+func (s *Server) Listen(ctx context.Context) (err error) {
+	listenCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errorCh := make(chan error)
+	for _, tcp := range s.tcp {
+		tcp := tcp
+		go func() {
+			err := tcp.listen(listenCtx, nil)
+			if err != nil {
+				err = fmt.Errorf("TCP server on %s: %w", tcp.address, err)
+			}
+			errorCh <- err
+		}()
+	}
+	for _, udp := range s.udp {
+		udp := udp
+		go func() {
+			err := udp.listen(listenCtx, nil)
+			if err != nil {
+				err = fmt.Errorf("UDP server on %s: %w", udp.address, err)
+			}
+			errorCh <- err
+		}()
+	}
+
+	err = <-errorCh
+	cancel()
+	for i := 1; i < len(s.tcp)+len(s.udp); i++ {
+		<-errorCh
+	}
+	s.logger.Info("TCP and UDP servers exited")
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+func record(recorder *accesslog.Recorder, logger Logger, entry accesslog.Entry) {
+	entry.Client = clientIP(entry.Client)
+	entry.Proxy = "shadowsocks"
+	err := recorder.Record(entry)
+	if err != nil {
+		logger.Error(err.Error())
+	}
+}
+
+// clientIP returns the IP address of the client address given.
+func clientIP(address string) string {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	return host
+}
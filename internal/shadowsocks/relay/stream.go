@@ -0,0 +1,134 @@
+package relay
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	// maxPayloadSize is the maximum size of the payload of a chunk.
+	maxPayloadSize = 0x3FFF
+	// maxPayloadSize2022 is the maximum size of the payload
+	// of a chunk for the Shadowsocks 2022 ciphers.
+	maxPayloadSize2022 = 0xFFFF
+)
+
+// streamReader decrypts a Shadowsocks AEAD stream made of chunks,
+// each chunk being an encrypted payload length followed by the
+// encrypted payload.
+type streamReader struct {
+	reader     io.Reader
+	aead       cipher.AEAD
+	nonce      []byte
+	maxPayload int
+	buffer     []byte
+	// leftover is the decrypted payload not read yet.
+	leftover []byte
+}
+
+func newStreamReader(reader io.Reader, aead cipher.AEAD,
+	maxPayload int) *streamReader {
+	return &streamReader{
+		reader:     reader,
+		aead:       aead,
+		nonce:      make([]byte, aead.NonceSize()),
+		maxPayload: maxPayload,
+		buffer:     make([]byte, maxPayload+aead.Overhead()),
+	}
+}
+
+func (r *streamReader) Read(b []byte) (n int, err error) {
+	if len(r.leftover) == 0 {
+		r.leftover, err = r.readChunk()
+		if err != nil {
+			return 0, err
+		}
+	}
+	n = copy(b, r.leftover)
+	r.leftover = r.leftover[n:]
+	return n, nil
+}
+
+func (r *streamReader) readChunk() (payload []byte, err error) {
+	const lengthSize = 2
+	length, err := r.readSealed(lengthSize)
+	if err != nil {
+		return nil, err
+	}
+	payloadSize := int(binary.BigEndian.Uint16(length)) & r.maxPayload
+	return r.readSealed(payloadSize)
+}
+
+// readSealed reads and decrypts a sealed block of the plaintext
+// size given, which must not exceed the maximum payload size.
+// The plaintext returned is only valid until the next read.
+func (r *streamReader) readSealed(size int) (plaintext []byte, err error) {
+	sealed := r.buffer[:size+r.aead.Overhead()]
+	_, err = io.ReadFull(r.reader, sealed)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err = r.aead.Open(sealed[:0], r.nonce, sealed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting chunk: %w", err)
+	}
+	increment(r.nonce)
+	return plaintext, nil
+}
+
+// streamWriter encrypts data written to it as a
+// Shadowsocks AEAD stream made of chunks.
+type streamWriter struct {
+	writer     io.Writer
+	aead       cipher.AEAD
+	nonce      []byte
+	maxPayload int
+	buffer     []byte
+	// header is the Shadowsocks 2022 response header, sent
+	// together with the length of the first chunk payload.
+	// It is nil once sent or for other ciphers.
+	header []byte
+}
+
+func newStreamWriter(writer io.Writer, aead cipher.AEAD,
+	maxPayload int) *streamWriter {
+	const lengthSize = 2
+	return &streamWriter{
+		writer:     writer,
+		aead:       aead,
+		nonce:      make([]byte, aead.NonceSize()),
+		maxPayload: maxPayload,
+		buffer:     make([]byte, 0, lengthSize+maxPayload+2*aead.Overhead()),
+	}
+}
+
+func (w *streamWriter) Write(b []byte) (n int, err error) {
+	for len(b) > 0 {
+		payloadSize := len(b)
+		if payloadSize > w.maxPayload {
+			payloadSize = w.maxPayload
+		}
+
+		var lengthArray [2]byte
+		length := lengthArray[:]
+		binary.BigEndian.PutUint16(length, uint16(payloadSize))
+		if w.header != nil {
+			length = append(w.header, length...)
+			w.header = nil
+		}
+		chunk := w.aead.Seal(w.buffer[:0], w.nonce, length, nil)
+		increment(w.nonce)
+		chunk = w.aead.Seal(chunk, w.nonce, b[:payloadSize], nil)
+		increment(w.nonce)
+
+		_, err = w.writer.Write(chunk)
+		if err != nil {
+			return n, err
+		}
+		n += payloadSize
+		b = b[payloadSize:]
+	}
+	return n, nil
+}
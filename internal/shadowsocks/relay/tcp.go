@@ -0,0 +1,340 @@
+package relay
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/accesslog"
+	"github.com/qdm12/gluetun/internal/ratelimit"
+)
+
+type tcpServer struct {
+	address      string
+	logAddresses bool
+	// users are the users accepted on the address, all
+	// using the same cipher with a different key.
+	users []*user
+	// identities identifies users from the identity headers of
+	// Shadowsocks 2022 requests, and is nil if they are not used.
+	identities *identities
+	saltFilter *saltFilter
+	logger     Logger
+	recorder   *accesslog.Recorder
+	shaper     *ratelimit.Shaper
+	dialer     *net.Dialer
+	timeNow    func() time.Time
+}
+
+var (
+	ErrSaltRepeated     = errors.New("salt is repeated")
+	ErrNoUserKeyMatches = errors.New("no user key matches")
+)
+
+func (s *tcpServer) listen(ctx context.Context, ready chan<- net.Addr) (err error) {
+	listenConfig := net.ListenConfig{}
+	listener, err := listenConfig.Listen(ctx, "tcp", s.address)
+	if err != nil {
+		return err
+	}
+	s.logger.Info("listening TCP on " + listener.Addr().String())
+	if ready != nil {
+		ready <- listener.Addr()
+	}
+
+	listenerClosed := make(chan struct{})
+	go func() {
+		defer close(listenerClosed)
+		<-ctx.Done()
+		if err := listener.Close(); err != nil {
+			s.logger.Error(err.Error())
+		}
+	}()
+
+	wg := &sync.WaitGroup{}
+	defer func() {
+		<-listenerClosed
+		wg.Wait()
+	}()
+
+	for {
+		connection, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			s.logger.Error("cannot accept connection on TCP listener: " + err.Error())
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.handleConnection(ctx, connection)
+		}()
+	}
+}
+
+func (s *tcpServer) handleConnection(ctx context.Context, connection net.Conn) {
+	defer connection.Close()
+	start := s.timeNow()
+
+	// Close the connection if the context is canceled.
+	handlerCtx, handlerCancel := context.WithCancel(ctx)
+	defer handlerCancel()
+	go func() {
+		<-handlerCtx.Done()
+		_ = connection.SetDeadline(s.timeNow())
+	}()
+
+	user, reader, target, requestSalt, err := s.readHeader(connection)
+	if err != nil {
+		s.logger.Debug("cannot read header from " +
+			connection.RemoteAddr().String() + ": " + err.Error())
+		// Drain the connection to not leak information to probes.
+		_, _ = io.Copy(io.Discard, connection)
+		return
+	}
+
+	revoked, ok := user.acquire()
+	if !ok {
+		s.logger.Debug("rejecting connection from " + connection.RemoteAddr().String() +
+			": user " + user.name + " is disabled")
+		_, _ = io.Copy(io.Discard, connection)
+		return
+	}
+	defer user.release()
+	shaping := s.shaper.Acquire(clientIP(connection.RemoteAddr().String()))
+	defer shaping.Release()
+	go func() {
+		select {
+		case <-handlerCtx.Done():
+		case <-revoked:
+			handlerCancel()
+		}
+	}()
+
+	targetConnection, err := s.dialer.DialContext(handlerCtx, "tcp", target.String())
+	if err != nil {
+		s.logger.Error("cannot connect to target address " + target.String() + ": " + err.Error())
+		return
+	}
+	defer targetConnection.Close()
+
+	if s.logAddresses {
+		s.logger.Info("TCP proxying " + connection.RemoteAddr().String() +
+			" (user " + user.name + ") to " + target.String())
+	}
+
+	writer, err := s.newWriter(connection, user, requestSalt)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return
+	}
+
+	bytesUp, bytesDown, err := s.relay(handlerCtx, connection, targetConnection,
+		reader, writer, user, shaping)
+	if err != nil && ctx.Err() == nil {
+		var netErr net.Error
+		if (errors.As(err, &netErr) && netErr.Timeout()) || errors.Is(err, context.Canceled) {
+			s.logger.Debug("TCP relay error: " + err.Error())
+		} else {
+			s.logger.Error("TCP relay error: " + err.Error())
+		}
+	}
+
+	record(s.recorder, s.logger, accesslog.Entry{
+		Time:        start,
+		Client:      connection.RemoteAddr().String(),
+		Destination: target.String(),
+		Method:      "TCP",
+		BytesUp:     bytesUp,
+		BytesDown:   bytesDown,
+		Duration:    s.timeNow().Sub(start),
+	})
+}
+
+// readHeader reads the salt and the header sent by the client,
+// finds the user whose key decrypts the header, and returns
+// the reader decrypting the stream and the target address.
+func (s *tcpServer) readHeader(connection net.Conn) (user *user,
+	reader io.Reader, target address, salt []byte, err error) {
+	// All users use the same cipher, so the salt has the same size.
+	cipher := s.users[0].tcpCipher
+	salt = make([]byte, cipher.saltSize())
+	_, err = io.ReadFull(connection, salt)
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("reading salt: %w", err)
+	}
+	if s.saltFilter.checkAndAdd(salt) {
+		return nil, nil, nil, nil, ErrSaltRepeated
+	}
+
+	// The first sealed chunk is the payload length, or the fixed
+	// length header for Shadowsocks 2022 ciphers.
+	const lengthSize = 2
+	headerSize := lengthSize
+	if cipher.edition2022 {
+		headerSize = 1 + timestampSize + lengthSize
+	}
+
+	// Requests with an identity header are decrypted with the key of
+	// the user identified, and other requests by trying each user key.
+	candidates := s.users
+	var received []byte
+	if s.identities != nil {
+		received = make([]byte, identityHeaderSize)
+		_, err = io.ReadFull(connection, received)
+		if err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("reading identity header: %w", err)
+		}
+		identified, err := s.identities.tcpUser(salt, received)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		} else if identified != nil {
+			candidates = append(candidates[:0:0], identified)
+			received = nil
+		}
+	}
+
+	var sealed, header []byte
+	var streamReader *streamReader
+	for _, candidate := range candidates {
+		aead, err := candidate.tcpCipher.aead(salt)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		if sealed == nil {
+			// The data received is the start of the header
+			// if it is not an identity header.
+			sealed = make([]byte, headerSize+aead.Overhead())
+			n := copy(sealed, received)
+			_, err = io.ReadFull(connection, sealed[n:])
+			if err != nil {
+				return nil, nil, nil, nil, fmt.Errorf("reading header: %w", err)
+			}
+		}
+		zeroNonce := make([]byte, aead.NonceSize())
+		header, err = aead.Open(nil, zeroNonce, sealed, nil)
+		if err == nil {
+			user = candidate
+			streamReader = newStreamReader(connection, aead, cipher.maxPayloadSize())
+			increment(streamReader.nonce)
+			break
+		}
+	}
+	if user == nil {
+		return nil, nil, nil, nil, fmt.Errorf("%w", ErrNoUserKeyMatches)
+	}
+
+	length := int(binary.BigEndian.Uint16(header[headerSize-lengthSize:]))
+	if !cipher.edition2022 {
+		streamReader.leftover, err = streamReader.readSealed(length & maxPayloadSize)
+		if err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("reading chunk payload: %w", err)
+		}
+		target, err = readAddress(streamReader)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		return user, streamReader, target, salt, nil
+	}
+
+	err = checkHeader2022(header, headerTypeClient, s.timeNow())
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	variableHeader, err := streamReader.readSealed(length)
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("reading variable length header: %w", err)
+	}
+	target, streamReader.leftover, err = extractVariableHeader(variableHeader)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	// The variable header buffer is reused by the stream reader.
+	target = append(address(nil), target...)
+	return user, streamReader, target, salt, nil
+}
+
+// newWriter writes a new salt to the connection and returns
+// a writer encrypting data written to the connection for the
+// user given. For Shadowsocks 2022 ciphers, the response header
+// contains the salt of the request.
+func (s *tcpServer) newWriter(connection net.Conn, user *user,
+	requestSalt []byte) (writer io.Writer, err error) {
+	cipher := user.tcpCipher
+	salt := make([]byte, cipher.saltSize())
+	_, err = rand.Read(salt)
+	if err != nil {
+		return nil, fmt.Errorf("generating salt: %w", err)
+	}
+	s.saltFilter.addSalt(salt)
+	aead, err := cipher.aead(salt)
+	if err != nil {
+		return nil, err
+	}
+	_, err = connection.Write(salt)
+	if err != nil {
+		return nil, fmt.Errorf("writing salt: %w", err)
+	}
+	streamWriter := newStreamWriter(connection, aead, cipher.maxPayloadSize())
+	if cipher.edition2022 {
+		streamWriter.header = appendHeader2022(nil, headerTypeServer, s.timeNow())
+		streamWriter.header = append(streamWriter.header, requestSalt...)
+	}
+	return streamWriter, nil
+}
+
+// relay copies data between the client and target connections in
+// both directions, and returns the number of bytes sent and received
+// by the client. The bytes are also counted for the user given and
+// limited with the client shaping given as they are relayed.
+func (s *tcpServer) relay(ctx context.Context, clientConnection, targetConnection net.Conn,
+	clientReader io.Reader, clientWriter io.Writer, user *user, shaping *ratelimit.Client) (
+	bytesUp, bytesDown uint64, err error) {
+	upErr := make(chan error)
+	go func() {
+		clientReader := &countingReader{
+			reader:  shaping.WrapUpload(ctx, clientReader),
+			counter: &user.bytesSent,
+		}
+		n, err := io.Copy(targetConnection, clientReader)
+		bytesUp = uint64(n)
+		// wake up the other goroutine blocked on reading the target
+		_ = targetConnection.SetDeadline(s.timeNow())
+		upErr <- err
+	}()
+
+	targetReader := &countingReader{
+		reader:  shaping.WrapDownload(ctx, targetConnection),
+		counter: &user.bytesReceived,
+	}
+	n, downErr := io.Copy(clientWriter, targetReader)
+	bytesDown = uint64(n)
+	// wake up the other goroutine blocked on reading the client
+	_ = clientConnection.SetDeadline(s.timeNow())
+
+	err = <-upErr
+	if err == nil {
+		err = downErr
+	}
+	return bytesUp, bytesDown, err
+}
+
+type countingReader struct {
+	reader  io.Reader
+	counter *atomic.Uint64
+}
+
+func (r *countingReader) Read(b []byte) (n int, err error) {
+	n, err = r.reader.Read(b)
+	r.counter.Add(uint64(n))
+	return n, err
+}
@@ -0,0 +1,434 @@
+package relay
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/accesslog"
+	"github.com/qdm12/gluetun/internal/ratelimit"
+)
+
+const (
+	maxPacketSize = 64 * 1024
+	// udpSessionTimeout is the duration after which a UDP session
+	// is closed if the target did not send any packet back.
+	udpSessionTimeout = time.Minute
+)
+
+var (
+	ErrBandwidthExceeded = errors.New("bandwidth limit exceeded")
+	ErrPacketRepeated    = errors.New("packet is repeated")
+	ErrPacketTooShort    = errors.New("packet is too short")
+	ErrPacketTooLarge    = errors.New("packet is too large")
+	ErrUserDisabled      = errors.New("user is disabled")
+)
+
+type udpServer struct {
+	address      string
+	logAddresses bool
+	// users are the users accepted on the address, all
+	// using the same cipher with a different key.
+	users []*user
+	// identities identifies users from the identity headers of
+	// Shadowsocks 2022 packets, and is nil if they are not used.
+	identities *identities
+	saltFilter *saltFilter
+	logger     Logger
+	recorder   *accesslog.Recorder
+	shaper     *ratelimit.Shaper
+	timeNow    func() time.Time
+
+	// packetWindows rejects repeated Shadowsocks 2022 packets.
+	packetWindows *packetWindows
+
+	// sessions maps the client address and target
+	// address pair to their UDP session.
+	sessionsMutex sync.Mutex
+	sessions      map[string]*udpSession
+}
+
+// udpSession is the association between a client address
+// and a target address, using a dedicated UDP socket to
+// exchange packets with the target.
+type udpSession struct {
+	key        string
+	client     net.Addr
+	target     address
+	user       *user
+	revoked    <-chan struct{}
+	shaping    *ratelimit.Client
+	connection net.PacketConn
+	start      time.Time
+	bytesUp    atomic.Uint64
+	bytesDown  atomic.Uint64
+	// Shadowsocks 2022 session IDs and server packet ID,
+	// only used with the 2022 edition ciphers.
+	clientSessionID [sessionIDSize]byte
+	serverSessionID [sessionIDSize]byte
+	packetID        uint64
+}
+
+func (s *udpServer) listen(ctx context.Context, ready chan<- net.Addr) (err error) {
+	listenConfig := net.ListenConfig{}
+	packetConnection, err := listenConfig.ListenPacket(ctx, "udp", s.address)
+	if err != nil {
+		return err
+	}
+	s.logger.Info("listening UDP on " + packetConnection.LocalAddr().String())
+	if ready != nil {
+		ready <- packetConnection.LocalAddr()
+	}
+
+	connectionClosed := make(chan struct{})
+	go func() {
+		defer close(connectionClosed)
+		<-ctx.Done()
+		if err := packetConnection.Close(); err != nil {
+			s.logger.Error(err.Error())
+		}
+	}()
+
+	s.sessions = make(map[string]*udpSession)
+	s.packetWindows = newPacketWindows()
+	wg := &sync.WaitGroup{}
+	defer func() {
+		<-connectionClosed
+		s.sessionsMutex.Lock()
+		for _, session := range s.sessions {
+			_ = session.connection.Close()
+		}
+		s.sessionsMutex.Unlock()
+		wg.Wait()
+	}()
+
+	buffer := make([]byte, maxPacketSize)
+	for {
+		n, client, err := packetConnection.ReadFrom(buffer)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			s.logger.Error("cannot read from UDP connection: " + err.Error())
+			continue
+		}
+
+		err = s.handlePacket(packetConnection, client, buffer[:n], wg)
+		if err != nil {
+			s.logger.Debug("cannot handle UDP packet from " +
+				client.String() + ": " + err.Error())
+		}
+	}
+}
+
+func (s *udpServer) handlePacket(packetConnection net.PacketConn,
+	client net.Addr, packet []byte, wg *sync.WaitGroup) (err error) {
+	user, plaintext, header, err := s.unpack(packet)
+	if err != nil {
+		return err
+	}
+
+	target, err := extractAddress(plaintext)
+	if err != nil {
+		return err
+	}
+	payload := plaintext[len(target):]
+
+	targetUDPAddress, err := net.ResolveUDPAddr("udp", target.String())
+	if err != nil {
+		return fmt.Errorf("resolving target address: %w", err)
+	}
+
+	session, err := s.getOrCreateSession(packetConnection, client, target,
+		user, header.sessionID, wg)
+	if err != nil {
+		return err
+	}
+
+	if !session.shaping.AllowUpload(len(payload)) {
+		return fmt.Errorf("%w: dropping packet of %d bytes", ErrBandwidthExceeded, len(payload))
+	}
+
+	_, err = session.connection.WriteTo(payload, targetUDPAddress)
+	if err != nil {
+		return fmt.Errorf("writing to target address %s: %w", targetUDPAddress, err)
+	}
+	session.bytesUp.Add(uint64(len(payload)))
+	user.bytesSent.Add(uint64(len(payload)))
+	return nil
+}
+
+func (s *udpServer) getOrCreateSession(packetConnection net.PacketConn,
+	client net.Addr, target address, user *user,
+	clientSessionID [sessionIDSize]byte, wg *sync.WaitGroup) (
+	session *udpSession, err error) {
+	key := client.String() + " " + target.String() + " " + user.name
+	if user.udpCipher.edition2022 {
+		key += " " + string(clientSessionID[:])
+	}
+
+	s.sessionsMutex.Lock()
+	defer s.sessionsMutex.Unlock()
+
+	session, ok := s.sessions[key]
+	if ok {
+		if !user.isEnabled() {
+			user.rejected.Add(1)
+			return nil, fmt.Errorf("%w: %s", ErrUserDisabled, user.name)
+		}
+		return session, nil
+	}
+
+	revoked, ok := user.acquire()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUserDisabled, user.name)
+	}
+
+	connection, err := net.ListenPacket("udp", "")
+	if err != nil {
+		user.release()
+		return nil, fmt.Errorf("listening for target packets: %w", err)
+	}
+	session = &udpSession{
+		key:             key,
+		client:          client,
+		target:          append(address(nil), target...),
+		user:            user,
+		revoked:         revoked,
+		shaping:         s.shaper.Acquire(clientIP(client.String())),
+		connection:      connection,
+		start:           s.timeNow(),
+		clientSessionID: clientSessionID,
+	}
+	if user.udpCipher.edition2022 {
+		_, err = rand.Read(session.serverSessionID[:])
+		if err != nil {
+			user.release()
+			session.shaping.Release()
+			_ = connection.Close()
+			return nil, fmt.Errorf("generating server session ID: %w", err)
+		}
+	}
+	s.sessions[key] = session
+
+	if s.logAddresses {
+		s.logger.Info("UDP proxying " + client.String() +
+			" (user " + user.name + ") to " + target.String())
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.handleSession(packetConnection, session)
+	}()
+	return session, nil
+}
+
+// handleSession sends packets received from the target back to the
+// client, until no packet is received for the session timeout.
+func (s *udpServer) handleSession(packetConnection net.PacketConn,
+	session *udpSession) {
+	defer session.user.release()
+	defer session.shaping.Release()
+
+	relayDone := make(chan struct{})
+	go func() {
+		// Close the session if the user gets disabled.
+		select {
+		case <-relayDone:
+		case <-session.revoked:
+			_ = session.connection.Close()
+		}
+	}()
+
+	err := s.relayToClient(packetConnection, session)
+	close(relayDone)
+	var netErr net.Error
+	if err != nil && !errors.Is(err, net.ErrClosed) &&
+		!(errors.As(err, &netErr) && netErr.Timeout()) {
+		s.logger.Error("UDP relay error: " + err.Error())
+	}
+
+	s.sessionsMutex.Lock()
+	delete(s.sessions, session.key)
+	s.sessionsMutex.Unlock()
+	_ = session.connection.Close()
+
+	record(s.recorder, s.logger, accesslog.Entry{
+		Time:        session.start,
+		Client:      session.client.String(),
+		Destination: session.target.String(),
+		Method:      "UDP",
+		BytesUp:     session.bytesUp.Load(),
+		BytesDown:   session.bytesDown.Load(),
+		Duration:    s.timeNow().Sub(session.start),
+	})
+}
+
+func (s *udpServer) relayToClient(packetConnection net.PacketConn,
+	session *udpSession) (err error) {
+	buffer := make([]byte, maxPacketSize)
+	packed := make([]byte, maxPacketSize)
+	for {
+		err = session.connection.SetReadDeadline(s.timeNow().Add(udpSessionTimeout))
+		if err != nil {
+			return fmt.Errorf("setting read deadline: %w", err)
+		}
+		n, source, err := session.connection.ReadFrom(buffer)
+		if err != nil {
+			return err
+		}
+		if !session.shaping.AllowDownload(n) {
+			continue // drop the packet
+		}
+
+		sourceAddrPort := source.(*net.UDPAddr).AddrPort() //nolint:forcetypeassert
+		plaintext := append(newAddress(sourceAddrPort), buffer[:n]...)
+		packet, err := s.pack(packed, plaintext, session)
+		if err != nil {
+			return err
+		}
+		_, err = packetConnection.WriteTo(packet, session.client)
+		if err != nil {
+			return fmt.Errorf("writing to client: %w", err)
+		}
+		session.bytesDown.Add(uint64(n))
+		session.user.bytesReceived.Add(uint64(n))
+	}
+}
+
+// unpack finds the user whose key decrypts the packet given,
+// and returns the plaintext made of the SOCKS address followed
+// by the payload. For Shadowsocks 2022 ciphers, it also returns
+// the packet header and checks it.
+func (s *udpServer) unpack(packet []byte) (user *user, plaintext []byte,
+	header packet2022, err error) {
+	// All users use the same cipher, so the packet format is the same.
+	if !s.users[0].udpCipher.edition2022 {
+		saltSize := s.users[0].udpCipher.saltSize()
+		if len(packet) < saltSize {
+			return nil, nil, header, fmt.Errorf("%w: %d bytes", ErrPacketTooShort, len(packet))
+		}
+		if s.saltFilter.checkAndAdd(packet[:saltSize]) {
+			return nil, nil, header, ErrSaltRepeated
+		}
+		for _, candidate := range s.users {
+			plaintext, err = candidate.udpCipher.unpack(packet)
+			if err == nil {
+				return candidate, plaintext, header, nil
+			}
+		}
+		return nil, nil, header, fmt.Errorf("%w", ErrNoUserKeyMatches)
+	}
+
+	// Packets with an identity header are decrypted with the key of
+	// the user identified, and other packets by trying each user key.
+	if s.identities != nil {
+		var separateHeader [separateHeaderSize]byte
+		user, separateHeader = s.identities.udpUser(packet)
+		if user != nil {
+			sealedBody := packet[separateHeaderSize+identityHeaderSize:]
+			header, plaintext, err = user.udpCipher.open2022(separateHeader, sealedBody)
+			if err != nil {
+				return nil, nil, header, fmt.Errorf("user %s: %w", user.name, err)
+			}
+		}
+	}
+	if user == nil {
+		for _, candidate := range s.users {
+			header, plaintext, err = candidate.udpCipher.unpack2022(packet)
+			if err == nil {
+				user = candidate
+				break
+			}
+		}
+	}
+	switch {
+	case user == nil:
+		return nil, nil, header, fmt.Errorf("%w", ErrNoUserKeyMatches)
+	case header.headerType != headerTypeClient:
+		return nil, nil, header, fmt.Errorf("%w: %d instead of %d",
+			ErrHeaderTypeNotValid, header.headerType, headerTypeClient)
+	}
+	now := s.timeNow()
+	err = checkTimestamp(header.timestamp, now)
+	if err != nil {
+		return nil, nil, header, err
+	}
+	session := user.name + " " + string(header.sessionID[:])
+	if s.packetWindows.checkAndAdd(session, header.packetID, now) {
+		return nil, nil, header, fmt.Errorf("%w: packet ID %d", ErrPacketRepeated, header.packetID)
+	}
+	return user, plaintext, header, nil
+}
+
+// pack encrypts the plaintext given for the client of the
+// session given, using the buffer given, and returns the
+// packet to send.
+func (s *udpServer) pack(buffer, plaintext []byte,
+	session *udpSession) (packet []byte, err error) {
+	cipher := session.user.udpCipher
+	if cipher.edition2022 {
+		header := packet2022{
+			sessionID:       session.serverSessionID,
+			packetID:        session.packetID,
+			headerType:      headerTypeServer,
+			timestamp:       s.timeNow(),
+			clientSessionID: session.clientSessionID,
+		}
+		session.packetID++
+		return cipher.pack2022(buffer, header, plaintext)
+	}
+
+	packet, err = cipher.pack(buffer, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	s.saltFilter.addSalt(packet[:cipher.saltSize()])
+	return packet, nil
+}
+
+// unpack decrypts the packet given, made of a salt followed
+// by the encrypted payload. The packet given is left unchanged.
+func (c *aeadCipher) unpack(packet []byte) (plaintext []byte, err error) {
+	saltSize := c.saltSize()
+	if len(packet) < saltSize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrPacketTooShort, len(packet))
+	}
+	aead, err := c.aead(packet[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+	zeroNonce := make([]byte, aead.NonceSize())
+	plaintext, err = aead.Open(nil, zeroNonce, packet[saltSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting packet: %w", err)
+	}
+	return plaintext, nil
+}
+
+// pack encrypts the plaintext given with a new salt, using
+// the buffer given, and returns the packet to send.
+func (c *aeadCipher) pack(buffer, plaintext []byte) (packet []byte, err error) {
+	saltSize := c.saltSize()
+	salt := buffer[:saltSize]
+	_, err = rand.Read(salt)
+	if err != nil {
+		return nil, fmt.Errorf("generating salt: %w", err)
+	}
+	aead, err := c.aead(salt)
+	if err != nil {
+		return nil, err
+	}
+	if saltSize+len(plaintext)+aead.Overhead() > len(buffer) {
+		return nil, fmt.Errorf("%w: %d bytes of plaintext", ErrPacketTooLarge, len(plaintext))
+	}
+	zeroNonce := make([]byte, aead.NonceSize())
+	packet = aead.Seal(buffer[:saltSize], zeroNonce, plaintext, nil)
+	return packet, nil
+}
@@ -0,0 +1,200 @@
+package relay
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultUserName is the name of the user authenticated
+// with the main password of the server.
+const DefaultUserName = "default"
+
+// UserSettings are the settings of a Shadowsocks user.
+type UserSettings struct {
+	Name     string
+	Password string
+	// Address is the listening address dedicated to the user.
+	// If it is empty, the user shares the main listening address
+	// and is identified by trying each user key.
+	Address string
+}
+
+// UserStats is the usage of the Shadowsocks server by a user.
+type UserStats struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	// ActiveConnections is the number of TCP connections
+	// and UDP sessions currently proxied for the user.
+	ActiveConnections int64 `json:"active_connections"`
+	// Connections is the number of TCP connections
+	// and UDP sessions proxied for the user.
+	Connections uint64 `json:"connections"`
+	// Rejected is the number of TCP connections and UDP
+	// packets rejected because the user is disabled.
+	Rejected uint64 `json:"rejected"`
+	// BytesSent is the number of bytes sent to destinations.
+	BytesSent uint64 `json:"bytes_sent"`
+	// BytesReceived is the number of bytes received from destinations.
+	BytesReceived uint64 `json:"bytes_received"`
+}
+
+// Users holds the Shadowsocks users enabled state and usage.
+// It is kept across server restarts.
+type Users struct {
+	mutex sync.Mutex
+	users map[string]*user
+}
+
+func NewUsers() *Users {
+	return &Users{
+		users: make(map[string]*user),
+	}
+}
+
+type user struct {
+	name      string
+	tcpCipher *aeadCipher
+	udpCipher *aeadCipher
+	// enabledMutex protects the enabled state and the
+	// revoked channel, closed when the user is disabled.
+	enabledMutex  sync.RWMutex
+	enabled       bool
+	revoked       chan struct{}
+	active        atomic.Int64
+	connections   atomic.Uint64
+	rejected      atomic.Uint64
+	bytesSent     atomic.Uint64
+	bytesReceived atomic.Uint64
+}
+
+var ErrUserNotFound = errors.New("user not found")
+
+// SetEnabled enables or disables the user given. Connections
+// of a disabled user are rejected, and its established
+// connections are closed.
+func (u *Users) SetEnabled(name string, enabled bool) (err error) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	user, ok := u.users[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUserNotFound, name)
+	}
+	user.setEnabled(enabled)
+	return nil
+}
+
+// Stats returns the usage statistics of all users sorted by name.
+func (u *Users) Stats() (stats []UserStats) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	stats = make([]UserStats, 0, len(u.users))
+	for _, user := range u.users {
+		stats = append(stats, UserStats{
+			Name:              user.name,
+			Enabled:           user.isEnabled(),
+			ActiveConnections: user.active.Load(),
+			Connections:       user.connections.Load(),
+			Rejected:          user.rejected.Load(),
+			BytesSent:         user.bytesSent.Load(),
+			BytesReceived:     user.bytesReceived.Load(),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
+
+// userKeys are the passwords of a user for the TCP and UDP
+// servers, which can only differ for the default user.
+type userKeys struct {
+	name        string
+	tcpPassword string
+	udpPassword string
+}
+
+// configure sets the users to the ones given, keeping the enabled
+// state and usage of existing users, and returns them in the order
+// given. Users not given are removed.
+func (u *Users) configure(keys []userKeys, tcpCipherName, udpCipherName string) (
+	users []*user, err error) {
+	users = make([]*user, len(keys))
+	for i, userKeys := range keys {
+		users[i] = newUser(userKeys.name)
+		users[i].tcpCipher, err = newCipher(tcpCipherName, userKeys.tcpPassword)
+		if err != nil {
+			return nil, fmt.Errorf("user %s: TCP cipher: %w", userKeys.name, err)
+		}
+		users[i].udpCipher, err = newCipher(udpCipherName, userKeys.udpPassword)
+		if err != nil {
+			return nil, fmt.Errorf("user %s: UDP cipher: %w", userKeys.name, err)
+		}
+	}
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	configured := make(map[string]*user, len(users))
+	for i, newUser := range users {
+		existing, ok := u.users[newUser.name]
+		if !ok {
+			existing = newUser
+		}
+		existing.tcpCipher = newUser.tcpCipher
+		existing.udpCipher = newUser.udpCipher
+		users[i] = existing
+		configured[existing.name] = existing
+	}
+	u.users = configured
+	return users, nil
+}
+
+func newUser(name string) *user {
+	return &user{
+		name:    name,
+		enabled: true,
+		revoked: make(chan struct{}),
+	}
+}
+
+func (u *user) isEnabled() (enabled bool) {
+	u.enabledMutex.RLock()
+	defer u.enabledMutex.RUnlock()
+	return u.enabled
+}
+
+func (u *user) setEnabled(enabled bool) {
+	u.enabledMutex.Lock()
+	defer u.enabledMutex.Unlock()
+	switch {
+	case u.enabled && !enabled:
+		close(u.revoked)
+	case !u.enabled && enabled:
+		u.revoked = make(chan struct{})
+	}
+	u.enabled = enabled
+}
+
+// acquire registers a new connection for the user, and returns
+// false if the user is disabled. Otherwise, it returns a channel
+// closed once the user gets disabled.
+func (u *user) acquire() (revoked <-chan struct{}, ok bool) {
+	u.enabledMutex.RLock()
+	defer u.enabledMutex.RUnlock()
+	if !u.enabled {
+		u.rejected.Add(1)
+		return nil, false
+	}
+	u.active.Add(1)
+	u.connections.Add(1)
+	return u.revoked, true
+}
+
+// release unregisters a connection acquired for the user.
+func (u *user) release() {
+	u.active.Add(-1)
+}
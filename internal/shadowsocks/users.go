@@ -0,0 +1,17 @@
+package shadowsocks
+
+import "github.com/qdm12/gluetun/internal/shadowsocks/relay"
+
+// GetUserStats returns the enabled state and the
+// usage statistics of the Shadowsocks users.
+func (l *Loop) GetUserStats() (stats []relay.UserStats) {
+	return l.users.Stats()
+}
+
+// SetUserEnabled enables or disables the user given, without
+// restarting the server. Established connections of a disabled
+// user are closed. The enabled state is kept across restarts,
+// as long as the user remains in the settings.
+func (l *Loop) SetUserEnabled(name string, enabled bool) (err error) {
+	return l.users.SetEnabled(name, enabled)
+}
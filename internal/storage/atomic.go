@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// writeFileAtomically writes to the file at the path given using the
+// write function given. It writes to a temporary file in the same
+// directory first, and then renames it to the path, so the file is
+// never left partially written, for example in case of a crash.
+func writeFileAtomically(path string, write func(w io.Writer) error) (err error) {
+	dirPath := filepath.Dir(path)
+	if err := os.MkdirAll(dirPath, 0644); err != nil { //nolint:gosec
+		return err
+	}
+
+	file, err := os.CreateTemp(dirPath, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating temporary file: %w", err)
+	}
+	tempPath := file.Name()
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tempPath)
+		}
+	}()
+
+	err = write(file)
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	err = file.Sync()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("syncing temporary file: %w", err)
+	}
+
+	err = file.Close()
+	if err != nil {
+		return fmt.Errorf("closing temporary file: %w", err)
+	}
+
+	const permissions = 0644
+	err = os.Chmod(tempPath, permissions)
+	if err != nil {
+		return fmt.Errorf("setting temporary file permissions: %w", err)
+	}
+
+	err = os.Rename(tempPath, path)
+	if err != nil {
+		return fmt.Errorf("renaming temporary file: %w", err)
+	}
+
+	// Sync the directory for the rename to persist on a crash.
+	err = syncDirectory(dirPath)
+	if err != nil {
+		return fmt.Errorf("syncing directory: %w", err)
+	}
+	return nil
+}
+
+func syncDirectory(path string) (err error) {
+	directory, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	err = directory.Sync()
+	if err != nil {
+		_ = directory.Close()
+		return err
+	}
+
+	return directory.Close()
+}
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"net/netip"
+
+	"github.com/qdm12/gluetun/internal/constants/providers"
+	"github.com/qdm12/gluetun/internal/models"
+)
+
+// ExcludeServer excludes the server with the given IP address
+// for the given provider from filtered servers, until the servers
+// of the provider are set again, for example by the updater.
+func (s *Storage) ExcludeServer(provider string, ip netip.Addr) {
+	if provider == providers.Custom {
+		return
+	}
+
+	s.mergedMutex.Lock()
+	defer s.mergedMutex.Unlock()
+
+	if s.excludedIPs == nil {
+		s.excludedIPs = make(map[string]map[netip.Addr]struct{})
+	}
+	providerExcluded, ok := s.excludedIPs[provider]
+	if !ok {
+		providerExcluded = make(map[netip.Addr]struct{})
+		s.excludedIPs[provider] = providerExcluded
+	}
+	providerExcluded[ip] = struct{}{}
+}
+
+// isExcluded returns true if any of the server IP addresses is
+// excluded. It must be called with the merged mutex locked.
+func (s *Storage) isExcluded(provider string, server models.Server) bool {
+	providerExcluded := s.excludedIPs[provider]
+	if len(providerExcluded) == 0 {
+		return false
+	}
+	for _, ip := range server.IPs {
+		if _, excluded := providerExcluded[ip]; excluded {
+			return true
+		}
+	}
+	return false
+}
@@ -29,17 +29,28 @@ func (s *Storage) FilterServers(provider string, selection settings.ServerSelect
 		return nil, ErrNoServerFound
 	}
 
+	excludedCount := 0
 	for _, server := range allServers {
 		if filterServer(server, selection) {
 			continue
 		}
 
+		if s.isExcluded(provider, server) {
+			excludedCount++
+			continue
+		}
+
 		server = copyServer(server)
 		servers = append(servers, server)
 	}
 
 	if len(servers) == 0 {
-		return nil, noServerFoundError(selection)
+		err = noServerFoundError(selection)
+		if excludedCount > 0 {
+			err = fmt.Errorf("%w (%d matching servers are excluded until the next servers update)",
+				err, excludedCount)
+		}
+		return nil, err
 	}
 
 	return servers, nil
@@ -74,6 +85,10 @@ func filterServer(server models.Server,
 		return true
 	}
 
+	if *selection.ReachableOnly && server.Reachable != nil && !*server.Reachable {
+		return true
+	}
+
 	if filterByPossibilities(server.Country, selection.Countries) {
 		return true
 	}
@@ -118,6 +118,10 @@ func noServerFoundError(selection settings.ServerSelection) (err error) {
 		messageParts = append(messageParts, "premium tier only")
 	}
 
+	if *selection.ReachableOnly {
+		messageParts = append(messageParts, "reachable servers only")
+	}
+
 	message := "for " + strings.Join(messageParts, "; ")
 
 	return fmt.Errorf("%w: %s", ErrNoServerFound, message)
@@ -0,0 +1,217 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/constants/providers"
+	"github.com/qdm12/gluetun/internal/models"
+)
+
+// maxSnapshots is the maximum number of previous
+// server snapshots kept per provider.
+const maxSnapshots = 5
+
+// Snapshot is a previous version of the servers of a provider,
+// saved when they are replaced.
+type Snapshot struct {
+	// Timestamp is the Unix timestamp of when the servers were
+	// updated, and identifies the snapshot for the provider.
+	Timestamp int64 `json:"timestamp"`
+	// Version is the servers data format version of the
+	// provider updater which produced the servers.
+	Version uint16 `json:"version"`
+	// ReplacedAt is when the servers were replaced.
+	ReplacedAt time.Time `json:"replaced_at"`
+	// Count is the number of servers.
+	Count int `json:"count"`
+	// Servers are the servers, which are only set when reading
+	// a snapshot to roll back to.
+	Servers []models.Server `json:"servers,omitempty"`
+}
+
+var (
+	ErrHistoryDisabled   = errors.New("servers history is disabled")
+	ErrSnapshotNotFound  = errors.New("servers snapshot not found")
+	ErrSnapshotOutdated  = errors.New("servers snapshot version is outdated")
+	ErrProviderNoHistory = errors.New("provider has no servers history")
+)
+
+// historyPath returns the file path of the servers history
+// for the provider given, or the empty string if the storage
+// does not write to a file.
+func (s *Storage) historyPath(provider string) string {
+	if s.filepath == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(s.filepath), "servers_history", provider+".json")
+}
+
+// History returns the previous server snapshots for the provider
+// given, from the most recent to the oldest one. The servers of
+// each snapshot are not set, only their count.
+func (s *Storage) History(provider string) (snapshots []Snapshot, err error) {
+	if provider == providers.Custom {
+		return nil, fmt.Errorf("%w: %s", ErrProviderNoHistory, provider)
+	}
+
+	s.mergedMutex.RLock()
+	defer s.mergedMutex.RUnlock()
+
+	// Check the provider exists, panicking otherwise.
+	_ = s.getMergedServersObject(provider)
+
+	snapshots, err = s.readHistory(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range snapshots {
+		snapshots[i].Servers = nil
+	}
+	return snapshots, nil
+}
+
+// Rollback sets the servers of the provider given to the servers of
+// the snapshot with the timestamp given. The servers replaced are
+// saved in the history, so a rollback can itself be rolled back.
+func (s *Storage) Rollback(provider string, timestamp int64) (err error) {
+	if provider == providers.Custom {
+		return fmt.Errorf("%w: %s", ErrProviderNoHistory, provider)
+	}
+
+	s.mergedMutex.Lock()
+	defer s.mergedMutex.Unlock()
+
+	snapshots, err := s.readHistory(provider)
+	if err != nil {
+		return err
+	}
+
+	index := -1
+	for i, snapshot := range snapshots {
+		if snapshot.Timestamp == timestamp {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		return fmt.Errorf("%w: for %s at timestamp %d",
+			ErrSnapshotNotFound, provider, timestamp)
+	}
+	snapshot := snapshots[index]
+
+	serversObject := s.getMergedServersObject(provider)
+	if snapshot.Version != serversObject.Version {
+		return fmt.Errorf("%w: snapshot version %d is not the current version %d",
+			ErrSnapshotOutdated, snapshot.Version, serversObject.Version)
+	}
+
+	snapshots = append(snapshots[:index], snapshots[index+1:]...)
+	const historyChanged = true
+	err = s.replaceServers(provider, serversObject, models.Servers{
+		Version:   snapshot.Version,
+		Timestamp: snapshot.Timestamp,
+		Servers:   snapshot.Servers,
+	}, snapshots, historyChanged)
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info(fmt.Sprintf("rolled back %s servers to %d servers from %s",
+		provider, len(snapshot.Servers), time.Unix(snapshot.Timestamp, 0).UTC()))
+	return nil
+}
+
+// replaceServers saves the old servers object in the history given
+// and sets the new servers object for the provider given, writing
+// both the history and servers files. The history is written if the
+// old servers are saved in it or if historyChanged is true, for
+// example if a snapshot was removed from it. It is not thread-safe.
+func (s *Storage) replaceServers(provider string, oldServers, newServers models.Servers,
+	snapshots []Snapshot, historyChanged bool) (err error) {
+	if len(oldServers.Servers) > 0 {
+		snapshots = pushSnapshot(snapshots, Snapshot{
+			Timestamp:  oldServers.Timestamp,
+			Version:    oldServers.Version,
+			ReplacedAt: time.Now().UTC(),
+			Count:      len(oldServers.Servers),
+			Servers:    oldServers.Servers,
+		})
+		historyChanged = true
+	}
+
+	if s.filepath != "" && historyChanged {
+		err = s.writeHistory(provider, snapshots)
+		if err != nil {
+			return fmt.Errorf("saving servers history: %w", err)
+		}
+	}
+
+	s.mergedServers.ProviderToServers[provider] = newServers
+	delete(s.excludedIPs, provider)
+
+	err = s.flushToFile(s.filepath)
+	if err != nil {
+		return fmt.Errorf("saving servers to file: %w", err)
+	}
+	return nil
+}
+
+// pushSnapshot inserts the snapshot given at the start of the
+// snapshots, replacing any snapshot with the same timestamp, and
+// drops the oldest snapshots beyond the maximum number of snapshots.
+func pushSnapshot(snapshots []Snapshot, snapshot Snapshot) []Snapshot {
+	result := make([]Snapshot, 0, len(snapshots)+1)
+	result = append(result, snapshot)
+	for _, existing := range snapshots {
+		if existing.Timestamp == snapshot.Timestamp {
+			continue
+		}
+		result = append(result, existing)
+	}
+	if len(result) > maxSnapshots {
+		result = result[:maxSnapshots]
+	}
+	return result
+}
+
+// readHistory reads the snapshots of the provider given, sorted
+// from the most recent to the oldest one. It is not thread-safe.
+func (s *Storage) readHistory(provider string) (snapshots []Snapshot, err error) {
+	path := s.historyPath(provider)
+	if path == "" {
+		return nil, fmt.Errorf("%w", ErrHistoryDisabled)
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("reading servers history: %w", err)
+	}
+
+	err = json.Unmarshal(data, &snapshots)
+	if err != nil {
+		return nil, fmt.Errorf("decoding servers history: %w", err)
+	}
+
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].ReplacedAt.After(snapshots[j].ReplacedAt)
+	})
+	return snapshots, nil
+}
+
+// writeHistory writes the snapshots of the provider given
+// atomically. It is not thread-safe.
+func (s *Storage) writeHistory(provider string, snapshots []Snapshot) (err error) {
+	return writeFileAtomically(s.historyPath(provider), func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(snapshots)
+	})
+}
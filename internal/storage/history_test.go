@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/qdm12/gluetun/internal/constants/providers"
+	"github.com/qdm12/gluetun/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Storage_historyAndRollback(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+
+	logger := NewMockInfoer(ctrl)
+	logger.EXPECT().Info(gomock.Any()).AnyTimes()
+
+	dirPath := t.TempDir()
+	serversPath := filepath.Join(dirPath, "servers.json")
+	oldServers := []models.Server{{Hostname: "a"}, {Hostname: "b"}}
+	newServers := []models.Server{{Hostname: "c"}}
+
+	s := &Storage{
+		mergedServers: models.AllServers{
+			Version: 1,
+			ProviderToServers: map[string]models.Servers{
+				providers.Mullvad: {Version: 2, Timestamp: 1, Servers: oldServers},
+			},
+		},
+		logger:   logger,
+		filepath: serversPath,
+	}
+
+	snapshots, err := s.History(providers.Mullvad)
+	require.NoError(t, err)
+	assert.Empty(t, snapshots)
+
+	err = s.SetServers(providers.Mullvad, newServers)
+	require.NoError(t, err)
+	newTimestamp := s.mergedServers.ProviderToServers[providers.Mullvad].Timestamp
+
+	snapshots, err = s.History(providers.Mullvad)
+	require.NoError(t, err)
+	require.Len(t, snapshots, 1)
+	assert.Equal(t, int64(1), snapshots[0].Timestamp)
+	assert.Equal(t, uint16(2), snapshots[0].Version)
+	assert.Equal(t, 2, snapshots[0].Count)
+	assert.Nil(t, snapshots[0].Servers)
+
+	err = s.Rollback(providers.Mullvad, 99)
+	assert.ErrorIs(t, err, ErrSnapshotNotFound)
+
+	err = s.Rollback(providers.Mullvad, 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.Servers{Version: 2, Timestamp: 1, Servers: oldServers},
+		s.mergedServers.ProviderToServers[providers.Mullvad])
+
+	data, err := os.ReadFile(serversPath)
+	require.NoError(t, err)
+	var written models.AllServers
+	err = json.Unmarshal(data, &written)
+	require.NoError(t, err)
+	assert.Equal(t, oldServers, written.ProviderToServers[providers.Mullvad].Servers)
+
+	snapshots, err = s.History(providers.Mullvad)
+	require.NoError(t, err)
+	require.Len(t, snapshots, 1)
+	assert.Equal(t, newTimestamp, snapshots[0].Timestamp)
+	assert.Equal(t, 1, snapshots[0].Count)
+
+	serversObject := s.mergedServers.ProviderToServers[providers.Mullvad]
+	serversObject.Version = 3
+	s.mergedServers.ProviderToServers[providers.Mullvad] = serversObject
+	err = s.Rollback(providers.Mullvad, newTimestamp)
+	assert.ErrorIs(t, err, ErrSnapshotOutdated)
+
+	// No temporary file must be left behind by atomic writes.
+	entries, err := os.ReadDir(dirPath)
+	require.NoError(t, err)
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+	assert.ElementsMatch(t, []string{"servers.json", "servers_history"}, names)
+}
+
+func Test_Storage_Rollback_noCurrentServers(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+
+	logger := NewMockInfoer(ctrl)
+	logger.EXPECT().Info(gomock.Any()).AnyTimes()
+
+	dirPath := t.TempDir()
+	s := &Storage{
+		mergedServers: models.AllServers{
+			Version: 1,
+			ProviderToServers: map[string]models.Servers{
+				providers.Mullvad: {Version: 2, Timestamp: 2},
+			},
+		},
+		logger:   logger,
+		filepath: filepath.Join(dirPath, "servers.json"),
+	}
+	snapshotServers := []models.Server{{Hostname: "a"}}
+	err := s.writeHistory(providers.Mullvad, []Snapshot{
+		{Timestamp: 1, Version: 2, Count: 1, Servers: snapshotServers},
+	})
+	require.NoError(t, err)
+
+	err = s.Rollback(providers.Mullvad, 1)
+	require.NoError(t, err)
+
+	assert.Equal(t, models.Servers{Version: 2, Timestamp: 1, Servers: snapshotServers},
+		s.mergedServers.ProviderToServers[providers.Mullvad])
+	// The snapshot rolled back to is removed from the history,
+	// even though no servers replaced are saved in it.
+	snapshots, err := s.History(providers.Mullvad)
+	require.NoError(t, err)
+	assert.Empty(t, snapshots)
+}
+
+func Test_pushSnapshot(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(100, 0)
+	testCases := map[string]struct {
+		snapshots []Snapshot
+		snapshot  Snapshot
+		expected  []Snapshot
+	}{
+		"empty": {
+			snapshot: Snapshot{Timestamp: 1, ReplacedAt: now},
+			expected: []Snapshot{{Timestamp: 1, ReplacedAt: now}},
+		},
+		"same_timestamp_replaced": {
+			snapshots: []Snapshot{{Timestamp: 2}, {Timestamp: 1}},
+			snapshot:  Snapshot{Timestamp: 1, ReplacedAt: now},
+			expected:  []Snapshot{{Timestamp: 1, ReplacedAt: now}, {Timestamp: 2}},
+		},
+		"oldest_dropped": {
+			snapshots: []Snapshot{{Timestamp: 5}, {Timestamp: 4},
+				{Timestamp: 3}, {Timestamp: 2}, {Timestamp: 1}},
+			snapshot: Snapshot{Timestamp: 6},
+			expected: []Snapshot{{Timestamp: 6}, {Timestamp: 5},
+				{Timestamp: 4}, {Timestamp: 3}, {Timestamp: 2}},
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			snapshots := pushSnapshot(testCase.snapshots, testCase.snapshot)
+
+			assert.Equal(t, testCase.expected, snapshots)
+		})
+	}
+}
@@ -36,6 +36,21 @@ func (s *Storage) readFromFile(filepath string, hardcodedVersions map[string]uin
 	return s.extractServersFromBytes(b, hardcodedVersions)
 }
 
+// DecodeServers decodes the servers JSON data given, such as data
+// fetched from a servers mirror. Servers of providers with a version
+// different from the hardcoded servers version are discarded.
+func (s *Storage) DecodeServers(data []byte) (servers models.AllServers, err error) {
+	return s.extractServersFromBytes(data, s.hardcodedVersions())
+}
+
+func (s *Storage) hardcodedVersions() (versions map[string]uint16) {
+	versions = make(map[string]uint16, len(s.hardcodedServers.ProviderToServers))
+	for provider, servers := range s.hardcodedServers.ProviderToServers {
+		versions[provider] = servers.Version
+	}
+	return versions
+}
+
 func (s *Storage) extractServersFromBytes(b []byte, hardcodedVersions map[string]uint16) (
 	servers models.AllServers, err error) {
 	rawMessages := make(map[string]json.RawMessage)
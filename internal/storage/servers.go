@@ -10,10 +10,19 @@ import (
 
 // SetServers sets the given servers for the given provider
 // in the storage in-memory map and saves all the servers
-// to file.
+// to file. It also clears servers excluded for the provider.
+// The servers replaced are saved in the provider servers history.
 // Note the servers given are not copied so the caller must
 // NOT MUTATE them after calling this method.
 func (s *Storage) SetServers(provider string, servers []models.Server) (err error) {
+	return s.SetServersWithTimestamp(provider, servers, time.Now().Unix())
+}
+
+// SetServersWithTimestamp is like SetServers but sets the Unix
+// timestamp given for the servers instead of the current time,
+// for example the timestamp of servers fetched from a mirror.
+func (s *Storage) SetServersWithTimestamp(provider string,
+	servers []models.Server, timestamp int64) (err error) {
 	if provider == providers.Custom {
 		return
 	}
@@ -21,16 +30,22 @@ func (s *Storage) SetServers(provider string, servers []models.Server) (err erro
 	s.mergedMutex.Lock()
 	defer s.mergedMutex.Unlock()
 
-	serversObject := s.getMergedServersObject(provider)
-	serversObject.Timestamp = time.Now().Unix()
-	serversObject.Servers = servers
-	s.mergedServers.ProviderToServers[provider] = serversObject
-
-	err = s.flushToFile(s.filepath)
-	if err != nil {
-		return fmt.Errorf("saving servers to file: %w", err)
+	var snapshots []Snapshot
+	if s.filepath != "" {
+		snapshots, err = s.readHistory(provider)
+		if err != nil {
+			s.logger.Info(fmt.Sprintf("discarding %s servers history: %s", provider, err))
+			snapshots = nil
+		}
 	}
-	return nil
+
+	oldServersObject := s.getMergedServersObject(provider)
+	newServersObject := oldServersObject
+	newServersObject.Timestamp = timestamp
+	newServersObject.Servers = servers
+	const historyChanged = false
+	return s.replaceServers(provider, oldServersObject, newServersObject,
+		snapshots, historyChanged)
 }
 
 // GetServerByName returns the server for the given provider
@@ -56,17 +71,34 @@ func (s *Storage) GetServerByName(provider, name string) (
 	return server, false
 }
 
-// GetServersCount returns the number of servers for the provider given.
-func (s *Storage) GetServersCount(provider string) (count int) {
+// GetServers returns a deep copy of the servers for the provider given.
+func (s *Storage) GetServers(provider string) (servers []models.Server) {
 	if provider == providers.Custom {
-		return 0
+		return nil
 	}
 
 	s.mergedMutex.RLock()
 	defer s.mergedMutex.RUnlock()
 
 	serversObject := s.getMergedServersObject(provider)
-	return len(serversObject.Servers)
+	servers = make([]models.Server, len(serversObject.Servers))
+	for i, server := range serversObject.Servers {
+		servers[i] = copyServer(server)
+	}
+	return servers
+}
+
+// GetTimestamp returns the Unix timestamp of the
+// servers stored for the provider given.
+func (s *Storage) GetTimestamp(provider string) (timestamp int64) {
+	if provider == providers.Custom {
+		return 0
+	}
+
+	s.mergedMutex.RLock()
+	defer s.mergedMutex.RUnlock()
+
+	return s.getMergedServersObject(provider).Timestamp
 }
 
 // FormatToMarkdown Markdown formats the servers for the provider given
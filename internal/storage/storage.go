@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"net/netip"
 	"sync"
 
 	"github.com/qdm12/gluetun/internal/models"
@@ -9,6 +10,9 @@ import (
 type Storage struct {
 	mergedServers models.AllServers
 	mergedMutex   sync.RWMutex
+	// excludedIPs maps provider names to server IP addresses
+	// excluded from filtered servers, and is protected by mergedMutex.
+	excludedIPs map[string]map[netip.Addr]struct{}
 	// this is stored in memory to avoid re-parsing
 	// the embedded JSON file on every call to the
 	// SyncServers method.
@@ -16,12 +16,7 @@ func countServers(allServers models.AllServers) (count int) {
 
 // syncServers merges the hardcoded servers with the ones from the file.
 func (s *Storage) syncServers() (err error) {
-	hardcodedVersions := make(map[string]uint16, len(s.hardcodedServers.ProviderToServers))
-	for provider, servers := range s.hardcodedServers.ProviderToServers {
-		hardcodedVersions[provider] = servers.Version
-	}
-
-	serversOnFile, err := s.readFromFile(s.filepath, hardcodedVersions)
+	serversOnFile, err := s.readFromFile(s.filepath, s.hardcodedVersions())
 	if err != nil {
 		return fmt.Errorf("reading servers from file: %w", err)
 	}
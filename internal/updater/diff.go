@@ -0,0 +1,158 @@
+package updater
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/models"
+)
+
+// ProviderDiff is the difference between the stored servers and
+// the servers fetched for a provider, where servers are identified
+// by their key.
+type ProviderDiff struct {
+	Provider string    `json:"provider"`
+	Time     time.Time `json:"time"`
+	OldCount int       `json:"old_count"`
+	NewCount int       `json:"new_count"`
+	// Added contains the keys of the servers added.
+	Added []string `json:"added"`
+	// Removed contains the keys of the servers removed.
+	Removed []string `json:"removed"`
+	// Changed contains the keys of the servers for which
+	// fields other than their IP addresses changed.
+	Changed []string `json:"changed"`
+	// IPsChanged contains the keys of the servers for
+	// which IP addresses changed.
+	IPsChanged []string `json:"ips_changed"`
+	// ChangeRatio is the ratio of existing servers
+	// removed or changed, not counting IP address changes.
+	ChangeRatio float64 `json:"change_ratio"`
+	// ThresholdExceeded is true if the change ratio
+	// exceeds the updater threshold.
+	ThresholdExceeded bool `json:"threshold_exceeded"`
+	// Applied is true if the servers were written to storage.
+	Applied bool `json:"applied"`
+	// Error is the error encountered updating the provider servers,
+	// and is empty if the update succeeded.
+	Error string `json:"error,omitempty"`
+}
+
+// diffServers returns the difference between the old and new servers
+// given, for the provider given.
+func diffServers(provider string, oldServers, newServers []models.Server) (diff ProviderDiff) {
+	diff = ProviderDiff{
+		Provider:   provider,
+		OldCount:   len(oldServers),
+		NewCount:   len(newServers),
+		Added:      []string{},
+		Removed:    []string{},
+		Changed:    []string{},
+		IPsChanged: []string{},
+	}
+
+	keyToOldServer := make(map[string]models.Server, len(oldServers))
+	for _, server := range oldServers {
+		keyToOldServer[server.Key()] = server
+	}
+
+	newKeys := make(map[string]struct{}, len(newServers))
+	for _, newServer := range newServers {
+		key := newServer.Key()
+		if _, duplicate := newKeys[key]; duplicate {
+			continue
+		}
+		newKeys[key] = struct{}{}
+
+		oldServer, ok := keyToOldServer[key]
+		if !ok {
+			diff.Added = append(diff.Added, key)
+			continue
+		}
+
+		if !ipSetsAreEqual(oldServer.IPs, newServer.IPs) {
+			diff.IPsChanged = append(diff.IPsChanged, key)
+		}
+
+		oldServer.IPs, newServer.IPs = nil, nil
+		// Probe results change from one update to another
+		// and are not a change of the server itself.
+		oldServer.Reachable, newServer.Reachable = nil, nil
+		oldServer.RTT, newServer.RTT = 0, 0
+		if !oldServer.Equal(newServer) {
+			diff.Changed = append(diff.Changed, key)
+		}
+	}
+
+	for key := range keyToOldServer {
+		if _, ok := newKeys[key]; !ok {
+			diff.Removed = append(diff.Removed, key)
+		}
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+	sort.Strings(diff.IPsChanged)
+
+	if len(keyToOldServer) > 0 {
+		diff.ChangeRatio = float64(len(diff.Removed)+len(diff.Changed)) /
+			float64(len(keyToOldServer))
+	}
+
+	return diff
+}
+
+func ipSetsAreEqual(a, b []netip.Addr) (equal bool) {
+	if len(a) != len(b) {
+		return false
+	}
+
+	set := make(map[netip.Addr]struct{}, len(a))
+	for _, ip := range a {
+		set[ip] = struct{}{}
+	}
+	for _, ip := range b {
+		if _, ok := set[ip]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Summary returns a one line summary of the diff.
+func (d ProviderDiff) Summary() string {
+	summary := fmt.Sprintf("%s: %d servers added, %d removed, %d changed, "+
+		"%d with IP addresses changed (%d -> %d servers, %.0f%% changed)",
+		d.Provider, len(d.Added), len(d.Removed), len(d.Changed),
+		len(d.IPsChanged), d.OldCount, d.NewCount, d.ChangeRatio*100) //nolint:gomnd
+	if d.ThresholdExceeded {
+		summary += ", threshold exceeded"
+	}
+	if d.Error != "" {
+		summary += ": " + d.Error
+	}
+	return summary
+}
+
+// String returns the summary of the diff followed by the keys of
+// the servers added, removed and changed, one per line.
+func (d ProviderDiff) String() string {
+	lines := []string{d.Summary()}
+	for _, key := range d.Added {
+		lines = append(lines, "+ "+key)
+	}
+	for _, key := range d.Removed {
+		lines = append(lines, "- "+key)
+	}
+	for _, key := range d.Changed {
+		lines = append(lines, "~ "+key)
+	}
+	for _, key := range d.IPsChanged {
+		lines = append(lines, "~ "+key+" (IP addresses)")
+	}
+	return strings.Join(lines, "\n")
+}
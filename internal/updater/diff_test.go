@@ -0,0 +1,55 @@
+package updater
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_diffServers(t *testing.T) {
+	t.Parallel()
+
+	ip1 := netip.AddrFrom4([4]byte{1, 1, 1, 1})
+	ip2 := netip.AddrFrom4([4]byte{2, 2, 2, 2})
+	// Probe results are not counted as changes.
+	reachable := true
+
+	oldServers := []models.Server{
+		{VPN: "openvpn", UDP: true, Hostname: "kept", IPs: []netip.Addr{ip1, ip2}},
+		{VPN: "openvpn", UDP: true, Hostname: "ips", IPs: []netip.Addr{ip1}},
+		{VPN: "openvpn", UDP: true, Hostname: "changed", City: "Paris", IPs: []netip.Addr{ip1}},
+		{VPN: "openvpn", UDP: true, Hostname: "removed", IPs: []netip.Addr{ip1}},
+	}
+	newServers := []models.Server{
+		{VPN: "openvpn", UDP: true, Hostname: "kept", IPs: []netip.Addr{ip2, ip1},
+			Reachable: &reachable, RTT: time.Millisecond},
+		{VPN: "openvpn", UDP: true, Hostname: "ips", IPs: []netip.Addr{ip2}},
+		{VPN: "openvpn", UDP: true, Hostname: "changed", City: "Lyon", IPs: []netip.Addr{ip1}},
+		{VPN: "openvpn", UDP: true, Hostname: "added", IPs: []netip.Addr{ip1}},
+	}
+
+	diff := diffServers("provider", oldServers, newServers)
+
+	expected := ProviderDiff{
+		Provider:    "provider",
+		OldCount:    4,
+		NewCount:    4,
+		Added:       []string{"openvpn-udp-added"},
+		Removed:     []string{"openvpn-udp-removed"},
+		Changed:     []string{"openvpn-udp-changed"},
+		IPsChanged:  []string{"openvpn-udp-ips"},
+		ChangeRatio: 0.5,
+	}
+	assert.Equal(t, expected, diff)
+
+	const expectedString = "provider: 1 servers added, 1 removed, 1 changed, " +
+		"1 with IP addresses changed (4 -> 4 servers, 50% changed)\n" +
+		"+ openvpn-udp-added\n" +
+		"- openvpn-udp-removed\n" +
+		"~ openvpn-udp-changed\n" +
+		"~ openvpn-udp-ips (IP addresses)"
+	assert.Equal(t, expectedString, diff.String())
+}
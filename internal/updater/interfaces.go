@@ -14,8 +14,11 @@ type Providers interface {
 
 type Storage interface {
 	SetServers(provider string, servers []models.Server) (err error)
-	GetServersCount(provider string) (count int)
+	SetServersWithTimestamp(provider string, servers []models.Server, timestamp int64) (err error)
+	GetServers(provider string) (servers []models.Server)
 	ServersAreEqual(provider string, servers []models.Server) (equal bool)
+	DecodeServers(data []byte) (servers models.AllServers, err error)
+	GetTimestamp(provider string) (timestamp int64)
 	// Extra methods to match the provider.New storage interface
 	FilterServers(provider string, selection settings.ServerSelection) (filtered []models.Server, err error)
 	GetServerByName(provider string, name string) (server models.Server, ok bool)
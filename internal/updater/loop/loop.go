@@ -10,17 +10,26 @@ import (
 	"github.com/qdm12/gluetun/internal/constants"
 	"github.com/qdm12/gluetun/internal/models"
 	"github.com/qdm12/gluetun/internal/updater"
+	"github.com/qdm12/gluetun/internal/updater/resolver"
 )
 
 type Updater interface {
-	UpdateServers(ctx context.Context, providers []string, minRatio float64) (err error)
+	UpdateServers(ctx context.Context, providers []string,
+		options updater.Options) (diffs []updater.ProviderDiff, err error)
+}
+
+// Resolver is the resolver used by the providers
+// to resolve VPN server hostnames to IP addresses.
+type Resolver interface {
+	SetSettings(settings resolver.Settings)
 }
 
 type Loop struct {
 	state state
 	// Objects
-	updater Updater
-	logger  Logger
+	updater  Updater
+	resolver Resolver
+	logger   Logger
 	// Internal channels and locks
 	loopLock     sync.Mutex
 	start        chan struct{}
@@ -43,13 +52,15 @@ type Logger interface {
 }
 
 func NewLoop(settings settings.Updater, providers updater.Providers,
-	storage updater.Storage, client *http.Client, logger Logger) *Loop {
+	storage updater.Storage, resolver Resolver, client *http.Client,
+	logger Logger) *Loop {
 	return &Loop{
 		state: state{
 			status:   constants.Stopped,
 			settings: settings,
 		},
 		updater:      updater.New(client, storage, providers, logger),
+		resolver:     resolver,
 		logger:       logger,
 		start:        make(chan struct{}),
 		running:      make(chan models.LoopStatus),
@@ -90,14 +101,24 @@ func (l *Loop) Run(ctx context.Context, done chan<- struct{}) {
 	for ctx.Err() == nil {
 		updateCtx, updateCancel := context.WithCancel(ctx)
 
-		settings := l.GetSettings()
+		updaterSettings := l.GetSettings()
 
 		errorCh := make(chan error)
 		runWg := &sync.WaitGroup{}
 		runWg.Add(1)
 		go func() {
 			defer runWg.Done()
-			err := l.updater.UpdateServers(updateCtx, settings.Providers, settings.MinRatio)
+			options := updater.Options{
+				Threshold:           updaterSettings.Threshold,
+				RejectOverThreshold: updaterSettings.ThresholdAction == settings.UpdaterThresholdReject,
+				Probe:               *updaterSettings.Probe,
+				ProbeTimeout:        *updaterSettings.ProbeTimeout,
+				ProbeParallelism:    updaterSettings.ProbeParallelism,
+				MirrorURL:           updaterSettings.MirrorURL,
+				MirrorPublicKey:     updaterSettings.MirrorPublicKey,
+			}
+			diffs, err := l.updater.UpdateServers(updateCtx, updaterSettings.Providers, options)
+			l.state.setDiffs(diffs)
 			if err != nil {
 				if updateCtx.Err() == nil {
 					errorCh <- err
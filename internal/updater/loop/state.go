@@ -10,13 +10,23 @@ import (
 	"github.com/qdm12/gluetun/internal/configuration/settings"
 	"github.com/qdm12/gluetun/internal/constants"
 	"github.com/qdm12/gluetun/internal/models"
+	"github.com/qdm12/gluetun/internal/updater"
+	"github.com/qdm12/gluetun/internal/updater/resolver"
 )
 
 type state struct {
 	status   models.LoopStatus
 	settings settings.Updater
+	diffs    []updater.ProviderDiff
 	statusMu sync.RWMutex
 	periodMu sync.RWMutex
+	diffsMu  sync.RWMutex
+}
+
+func (s *state) setDiffs(diffs []updater.ProviderDiff) {
+	s.diffsMu.Lock()
+	defer s.diffsMu.Unlock()
+	s.diffs = diffs
 }
 
 func (s *state) setStatusWithLock(status models.LoopStatus) {
@@ -97,7 +107,30 @@ func (l *Loop) SetSettings(settings settings.Updater) (outcome string) {
 	if settingsUnchanged {
 		return "settings left unchanged"
 	}
+
+	resolverChanged := settings.DNSProtocol != l.state.settings.DNSProtocol ||
+		settings.DNSAddress != l.state.settings.DNSAddress ||
+		!reflect.DeepEqual(settings.DNSProviders, l.state.settings.DNSProviders)
+	if resolverChanged {
+		resolverSettings, err := resolver.ParseSettings(settings.DNSProtocol,
+			settings.DNSAddress, settings.DNSProviders)
+		if err != nil {
+			return "failed parsing resolver settings: " + err.Error()
+		}
+		l.resolver.SetSettings(resolverSettings)
+	}
+
 	l.state.settings = settings
 	l.updateTicker <- struct{}{}
 	return "settings updated"
 }
+
+// GetDiffs returns the server diffs of each provider from
+// the last update run.
+func (l *Loop) GetDiffs() (diffs []updater.ProviderDiff) {
+	l.state.diffsMu.RLock()
+	defer l.state.diffsMu.RUnlock()
+	diffs = make([]updater.ProviderDiff, len(l.state.diffs))
+	copy(diffs, l.state.diffs)
+	return diffs
+}
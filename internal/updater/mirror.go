@@ -0,0 +1,85 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/qdm12/gluetun/internal/models"
+	"github.com/qdm12/gluetun/internal/updater/mirror"
+)
+
+var (
+	ErrMirrorProviderNotFound  = errors.New("provider servers not found in mirror data")
+	ErrMirrorPublicKeyNotFound = errors.New("no mirror public key configured nor built in")
+)
+
+// fetchMirror fetches the servers data from the mirror URL given,
+// verifies its signature and decodes it. Servers of providers with
+// a version different from the hardcoded version are discarded.
+// The public key built in the program is used if the public key
+// given is empty.
+func (u *Updater) fetchMirror(ctx context.Context, url, publicKey string) (
+	servers models.AllServers, err error) {
+	if publicKey == "" {
+		publicKey = mirror.BuiltinPublicKey
+		if publicKey == "" {
+			return servers, fmt.Errorf("%w", ErrMirrorPublicKeyNotFound)
+		}
+	}
+	key, err := mirror.ParsePublicKey(publicKey)
+	if err != nil {
+		return servers, fmt.Errorf("parsing public key: %w", err)
+	}
+
+	data, err := mirror.Fetch(ctx, u.client, url, key)
+	if err != nil {
+		return servers, err
+	}
+
+	servers, err = u.storage.DecodeServers(data)
+	if err != nil {
+		return servers, fmt.Errorf("decoding servers: %w", err)
+	}
+	return servers, nil
+}
+
+// mirrorProvider is a provider fetching its servers
+// from the servers data of a mirror.
+type mirrorProvider struct {
+	Provider
+	servers models.AllServers
+	storage Storage
+}
+
+func (u *Updater) newMirrorProvider(provider Provider,
+	servers models.AllServers) *mirrorProvider {
+	return &mirrorProvider{
+		Provider: provider,
+		servers:  servers,
+		storage:  u.storage,
+	}
+}
+
+// FetchServers returns the servers of the provider from the mirror data.
+// If they are not more recent than the servers stored, the servers stored
+// are returned so no change is made.
+func (m *mirrorProvider) FetchServers(_ context.Context, _ int) (
+	servers []models.Server, err error) {
+	name := m.Provider.Name()
+	providerServers, ok := m.servers.ProviderToServers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: for provider %s", ErrMirrorProviderNotFound, name)
+	}
+
+	if providerServers.Timestamp <= m.storage.GetTimestamp(name) {
+		return m.storage.GetServers(name), nil
+	}
+	return providerServers.Servers, nil
+}
+
+// timestamp returns the Unix timestamp of the
+// servers data of the provider in the mirror.
+func (m *mirrorProvider) timestamp() int64 {
+	return m.servers.ProviderToServers[m.Provider.Name()].Timestamp
+}
@@ -0,0 +1,83 @@
+// Package mirror fetches the servers data from a mirror
+// and verifies its minisign detached signature.
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// SignatureSuffix is appended to the servers data URL
+// to obtain the URL of its minisign detached signature.
+const SignatureSuffix = ".minisig"
+
+const (
+	// maxDataSize is the maximum size of the servers data,
+	// well above the few megabytes of all the servers data.
+	maxDataSize = 64 * 1024 * 1024
+	// maxSignatureSize is the maximum size of a signature file.
+	maxSignatureSize = 4096
+)
+
+var (
+	ErrHTTPStatusCodeNotOK = errors.New("HTTP status code not OK")
+	ErrResponseTooLarge    = errors.New("response is too large")
+)
+
+// Fetch downloads the servers data at the URL given together
+// with its detached signature at the URL suffixed with .minisig,
+// and returns the data only if its signature is valid for
+// the public key given.
+func Fetch(ctx context.Context, client *http.Client, url string,
+	publicKey PublicKey) (data []byte, err error) {
+	data, err = fetch(ctx, client, url, maxDataSize)
+	if err != nil {
+		return nil, fmt.Errorf("fetching servers data: %w", err)
+	}
+
+	signature, err := fetch(ctx, client, url+SignatureSuffix, maxSignatureSize)
+	if err != nil {
+		return nil, fmt.Errorf("fetching signature: %w", err)
+	}
+
+	err = publicKey.Verify(data, signature)
+	if err != nil {
+		return nil, fmt.Errorf("verifying signature: %w", err)
+	}
+
+	return data, nil
+}
+
+// fetch returns the response body for the URL given,
+// failing if it is larger than the maximum size given.
+func fetch(ctx context.Context, client *http.Client, url string,
+	maxSize int64) (data []byte, err error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s: %d %s", ErrHTTPStatusCodeNotOK,
+			url, response.StatusCode, response.Status)
+	}
+
+	data, err = io.ReadAll(io.LimitReader(response.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	} else if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("%w: %s: exceeds %d bytes",
+			ErrResponseTooLarge, url, maxSize)
+	}
+
+	return data, response.Body.Close()
+}
@@ -0,0 +1,149 @@
+package mirror
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// BuiltinPublicKey is the minisign public key trusted to sign
+// the servers mirror data if no public key is configured.
+// It is set at build time using -ldflags.
+var BuiltinPublicKey = "" //nolint:gochecknoglobals
+
+const (
+	// algorithmLegacy is the minisign signature algorithm
+	// signing the data directly.
+	algorithmLegacy = "Ed"
+	// algorithmHashed is the minisign signature algorithm
+	// signing the BLAKE2b-512 hash of the data.
+	algorithmHashed = "ED"
+	keyIDLength     = 8
+
+	untrustedCommentPrefix = "untrusted comment: "
+	trustedCommentPrefix   = "trusted comment: "
+)
+
+// PublicKey is a minisign public key.
+type PublicKey struct {
+	keyID [keyIDLength]byte
+	key   ed25519.PublicKey
+}
+
+var (
+	ErrPublicKeyNotValid = errors.New("public key is not valid")
+	ErrSignatureNotValid = errors.New("signature is not valid")
+	ErrKeyIDMismatch     = errors.New("signature key ID does not match public key ID")
+	ErrSignatureMismatch = errors.New("signature does not match data")
+)
+
+// ParsePublicKey parses a minisign public key, either as its base64
+// encoded line or as the content of a minisign public key file.
+func ParsePublicKey(s string) (publicKey PublicKey, err error) {
+	lines := nonEmptyLines(s)
+	if len(lines) > 0 && strings.HasPrefix(lines[0], untrustedCommentPrefix) {
+		lines = lines[1:]
+	}
+	if len(lines) != 1 {
+		return publicKey, fmt.Errorf("%w: expected one base64 encoded line",
+			ErrPublicKeyNotValid)
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(lines[0])
+	if err != nil {
+		return publicKey, fmt.Errorf("%w: %w", ErrPublicKeyNotValid, err)
+	}
+
+	const length = len(algorithmLegacy) + keyIDLength + ed25519.PublicKeySize
+	switch {
+	case len(decoded) != length:
+		return publicKey, fmt.Errorf("%w: %d bytes instead of %d",
+			ErrPublicKeyNotValid, len(decoded), length)
+	case string(decoded[:2]) != algorithmLegacy:
+		return publicKey, fmt.Errorf("%w: signature algorithm %q is not supported",
+			ErrPublicKeyNotValid, decoded[:2])
+	}
+
+	copy(publicKey.keyID[:], decoded[2:2+keyIDLength])
+	publicKey.key = ed25519.PublicKey(decoded[2+keyIDLength:])
+	return publicKey, nil
+}
+
+// Verify verifies the data given against the content of its
+// minisign detached signature file, including the signature
+// of its trusted comment.
+func (p PublicKey) Verify(data, signatureFile []byte) (err error) {
+	lines := nonEmptyLines(string(signatureFile))
+	const expectedLines = 4
+	switch {
+	case len(lines) != expectedLines:
+		return fmt.Errorf("%w: %d lines instead of %d",
+			ErrSignatureNotValid, len(lines), expectedLines)
+	case !strings.HasPrefix(lines[0], untrustedCommentPrefix):
+		return fmt.Errorf("%w: first line is not an untrusted comment", ErrSignatureNotValid)
+	case !strings.HasPrefix(lines[2], trustedCommentPrefix):
+		return fmt.Errorf("%w: third line is not a trusted comment", ErrSignatureNotValid)
+	}
+
+	signatureBlock, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil {
+		return fmt.Errorf("%w: decoding signature: %w", ErrSignatureNotValid, err)
+	}
+	const signatureBlockLength = len(algorithmLegacy) + keyIDLength + ed25519.SignatureSize
+	if len(signatureBlock) != signatureBlockLength {
+		return fmt.Errorf("%w: signature is %d bytes instead of %d",
+			ErrSignatureNotValid, len(signatureBlock), signatureBlockLength)
+	}
+	algorithm := string(signatureBlock[:2])
+	keyID := signatureBlock[2 : 2+keyIDLength]
+	signature := signatureBlock[2+keyIDLength:]
+
+	if !bytes.Equal(keyID, p.keyID[:]) {
+		return fmt.Errorf("%w", ErrKeyIDMismatch)
+	}
+
+	switch algorithm {
+	case algorithmLegacy:
+	case algorithmHashed:
+		digest := blake2b.Sum512(data)
+		data = digest[:]
+	default:
+		return fmt.Errorf("%w: signature algorithm %q is not supported",
+			ErrSignatureNotValid, algorithm)
+	}
+
+	if !ed25519.Verify(p.key, data, signature) {
+		return fmt.Errorf("%w", ErrSignatureMismatch)
+	}
+
+	globalSignature, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil {
+		return fmt.Errorf("%w: decoding trusted comment signature: %w",
+			ErrSignatureNotValid, err)
+	}
+	trustedComment := strings.TrimPrefix(lines[2], trustedCommentPrefix)
+	signedComment := make([]byte, 0, len(signature)+len(trustedComment))
+	signedComment = append(signedComment, signature...)
+	signedComment = append(signedComment, trustedComment...)
+	if !ed25519.Verify(p.key, signedComment, globalSignature) {
+		return fmt.Errorf("%w: trusted comment signature does not match",
+			ErrSignatureMismatch)
+	}
+
+	return nil
+}
+
+func nonEmptyLines(s string) (lines []string) {
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
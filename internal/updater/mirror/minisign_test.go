@@ -0,0 +1,184 @@
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/blake2b"
+)
+
+// testKey is a minisign key pair for tests.
+type testKey struct {
+	keyID      [keyIDLength]byte
+	privateKey ed25519.PrivateKey
+	publicKey  string
+}
+
+func newTestKey(t *testing.T) (key testKey) {
+	t.Helper()
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, err = rand.Read(key.keyID[:])
+	require.NoError(t, err)
+	key.privateKey = privateKey
+
+	encoded := append([]byte(algorithmLegacy), key.keyID[:]...)
+	encoded = append(encoded, publicKey...)
+	key.publicKey = "untrusted comment: minisign public key\n" +
+		base64.StdEncoding.EncodeToString(encoded) + "\n"
+	return key
+}
+
+// sign returns the minisign signature file content for the data given.
+func (k testKey) sign(data []byte, algorithm, trustedComment string) (signatureFile []byte) {
+	message := data
+	if algorithm == algorithmHashed {
+		digest := blake2b.Sum512(data)
+		message = digest[:]
+	}
+	signature := ed25519.Sign(k.privateKey, message)
+	block := append([]byte(algorithm), k.keyID[:]...)
+	block = append(block, signature...)
+	globalSignature := ed25519.Sign(k.privateKey,
+		append(signature, []byte(trustedComment)...))
+	return []byte("untrusted comment: signature from minisign secret key\n" +
+		base64.StdEncoding.EncodeToString(block) + "\n" +
+		"trusted comment: " + trustedComment + "\n" +
+		base64.StdEncoding.EncodeToString(globalSignature) + "\n")
+}
+
+func Test_ParsePublicKey(t *testing.T) {
+	t.Parallel()
+
+	key := newTestKey(t)
+
+	testCases := map[string]struct {
+		s          string
+		errWrapped error
+	}{
+		"public_key_file": {
+			s: key.publicKey,
+		},
+		"empty": {
+			errWrapped: ErrPublicKeyNotValid,
+		},
+		"not_base64": {
+			s:          "not base64",
+			errWrapped: ErrPublicKeyNotValid,
+		},
+		"wrong_length": {
+			s:          base64.StdEncoding.EncodeToString([]byte("Ed")),
+			errWrapped: ErrPublicKeyNotValid,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ParsePublicKey(testCase.s)
+
+			assert.ErrorIs(t, err, testCase.errWrapped)
+		})
+	}
+}
+
+func Test_PublicKey_Verify(t *testing.T) {
+	t.Parallel()
+
+	key := newTestKey(t)
+	otherKey := newTestKey(t)
+	publicKey, err := ParsePublicKey(key.publicKey)
+	require.NoError(t, err)
+	data := []byte(`{"version":1}`)
+
+	tamperedComment := bytes.Replace(key.sign(data, algorithmHashed, "timestamp:1"),
+		[]byte("timestamp:1"), []byte("timestamp:2"), 1)
+
+	testCases := map[string]struct {
+		data          []byte
+		signatureFile []byte
+		errWrapped    error
+	}{
+		"hashed": {
+			data:          data,
+			signatureFile: key.sign(data, algorithmHashed, "timestamp:1"),
+		},
+		"legacy": {
+			data:          data,
+			signatureFile: key.sign(data, algorithmLegacy, "timestamp:1"),
+		},
+		"data_tampered": {
+			data:          []byte(`{"version":2}`),
+			signatureFile: key.sign(data, algorithmHashed, "timestamp:1"),
+			errWrapped:    ErrSignatureMismatch,
+		},
+		"trusted_comment_tampered": {
+			data:          data,
+			signatureFile: tamperedComment,
+			errWrapped:    ErrSignatureMismatch,
+		},
+		"other_key": {
+			data:          data,
+			signatureFile: otherKey.sign(data, algorithmHashed, "timestamp:1"),
+			errWrapped:    ErrKeyIDMismatch,
+		},
+		"malformed": {
+			data:          data,
+			signatureFile: []byte("malformed"),
+			errWrapped:    ErrSignatureNotValid,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := publicKey.Verify(testCase.data, testCase.signatureFile)
+
+			assert.ErrorIs(t, err, testCase.errWrapped)
+		})
+	}
+}
+
+func Test_Fetch(t *testing.T) {
+	t.Parallel()
+
+	key := newTestKey(t)
+	publicKey, err := ParsePublicKey(key.publicKey)
+	require.NoError(t, err)
+	data := []byte(`{"version":1}`)
+	signature := key.sign(data, algorithmHashed, "timestamp:1")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/servers.json", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(data)
+	})
+	mux.HandleFunc("/servers.json.minisig", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(signature)
+	})
+	mux.HandleFunc("/unsigned.json", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(data)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	fetched, err := Fetch(context.Background(), server.Client(),
+		server.URL+"/servers.json", publicKey)
+	require.NoError(t, err)
+	assert.Equal(t, data, fetched)
+
+	_, err = Fetch(context.Background(), server.Client(),
+		server.URL+"/unsigned.json", publicKey)
+	assert.ErrorIs(t, err, ErrHTTPStatusCodeNotOK)
+}
@@ -0,0 +1,214 @@
+package updater
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/qdm12/gluetun/internal/constants/providers"
+	"github.com/qdm12/gluetun/internal/constants/vpn"
+	"github.com/qdm12/gluetun/internal/models"
+	"github.com/qdm12/gluetun/internal/provider/utils"
+	"github.com/qdm12/gluetun/internal/storage"
+	"github.com/qdm12/gluetun/internal/updater/mirror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type noopLogger struct{}
+
+func (noopLogger) Info(string)  {}
+func (noopLogger) Warn(string)  {}
+func (noopLogger) Error(string) {}
+
+// timestampStorage is the storage recording the
+// timestamps of the servers set instead of setting them.
+type timestampStorage struct {
+	*storage.Storage
+	timestamps map[string]int64
+}
+
+func (s *timestampStorage) SetServersWithTimestamp(provider string,
+	_ []models.Server, timestamp int64) (err error) {
+	s.timestamps[provider] = timestamp
+	return nil
+}
+
+type fakeProvider struct {
+	name string
+}
+
+func (p fakeProvider) Name() string { return p.name }
+
+func (p fakeProvider) FetchServers(context.Context, int) ([]models.Server, error) {
+	panic("servers should be fetched from the mirror")
+}
+
+func (p fakeProvider) ConnectionDefaults() utils.ConnectionDefaults {
+	return utils.ConnectionDefaults{}
+}
+
+// minisignKey returns a minisign public key and a function
+// returning the minisign signature file content for data.
+func minisignKey(t *testing.T) (publicKey string, sign func(data []byte) []byte) {
+	t.Helper()
+	edPublicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	keyID := make([]byte, 8)
+	_, err = rand.Read(keyID)
+	require.NoError(t, err)
+
+	encodedKey := append([]byte("Ed"), keyID...)
+	encodedKey = append(encodedKey, edPublicKey...)
+	publicKey = base64.StdEncoding.EncodeToString(encodedKey)
+
+	sign = func(data []byte) []byte {
+		signature := ed25519.Sign(privateKey, data)
+		block := append([]byte("Ed"), keyID...)
+		block = append(block, signature...)
+		const trustedComment = "timestamp:1"
+		globalSignature := ed25519.Sign(privateKey,
+			append(signature, []byte(trustedComment)...))
+		return []byte("untrusted comment: signature\n" +
+			base64.StdEncoding.EncodeToString(block) + "\n" +
+			"trusted comment: " + trustedComment + "\n" +
+			base64.StdEncoding.EncodeToString(globalSignature) + "\n")
+	}
+	return publicKey, sign
+}
+
+func Test_Updater_mirror(t *testing.T) {
+	t.Parallel()
+
+	serversStorage, err := storage.New(noopLogger{}, "")
+	require.NoError(t, err)
+
+	// Read the hardcoded versions and timestamps of the providers.
+	storedPath := filepath.Join(t.TempDir(), "servers.json")
+	err = serversStorage.FlushToFile(storedPath)
+	require.NoError(t, err)
+	storedData, err := os.ReadFile(storedPath)
+	require.NoError(t, err)
+	var stored map[string]json.RawMessage
+	err = json.Unmarshal(storedData, &stored)
+	require.NoError(t, err)
+	hardcoded := func(provider string) (servers models.Servers) {
+		err := json.Unmarshal(stored[provider], &servers)
+		require.NoError(t, err)
+		return servers
+	}
+	mullvad := hardcoded(providers.Mullvad)
+	ivpn := hardcoded(providers.Ivpn)
+	surfshark := hardcoded(providers.Surfshark)
+
+	mirrorServers := []models.Server{{
+		VPN:      vpn.OpenVPN,
+		UDP:      true,
+		Hostname: "new.example.com",
+		IPs:      []netip.Addr{netip.MustParseAddr("1.2.3.4")},
+	}}
+	data, err := json.Marshal(map[string]models.Servers{
+		// More recent than the servers stored.
+		providers.Mullvad: {
+			Version:   mullvad.Version,
+			Timestamp: mullvad.Timestamp + 1,
+			Servers:   mirrorServers,
+		},
+		// Version different from the hardcoded version.
+		providers.Ivpn: {
+			Version:   ivpn.Version + 1,
+			Timestamp: ivpn.Timestamp + 1,
+			Servers:   mirrorServers,
+		},
+		// Older than the servers stored.
+		providers.Surfshark: {
+			Version:   surfshark.Version,
+			Timestamp: surfshark.Timestamp - 1,
+			Servers:   mirrorServers,
+		},
+	})
+	require.NoError(t, err)
+
+	publicKey, sign := minisignKey(t)
+	otherPublicKey, _ := minisignKey(t)
+	signature := sign(data)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/servers.json":
+			_, _ = w.Write(data)
+		case "/servers.json" + mirror.SignatureSuffix:
+			_, _ = w.Write(signature)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	url := server.URL + "/servers.json"
+
+	testStorage := &timestampStorage{
+		Storage:    serversStorage,
+		timestamps: make(map[string]int64),
+	}
+	updater := New(server.Client(), testStorage, nil, noopLogger{})
+
+	_, err = updater.fetchMirror(context.Background(), url, otherPublicKey)
+	assert.ErrorIs(t, err, mirror.ErrKeyIDMismatch)
+
+	_, err = updater.fetchMirror(context.Background(), server.URL+"/missing.json", publicKey)
+	assert.ErrorIs(t, err, mirror.ErrHTTPStatusCodeNotOK)
+
+	servers, err := updater.fetchMirror(context.Background(), url, publicKey)
+	require.NoError(t, err)
+
+	provider := updater.newMirrorProvider(fakeProvider{name: providers.Mullvad}, servers)
+	fetched, err := provider.FetchServers(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Equal(t, mirrorServers, fetched)
+
+	provider = updater.newMirrorProvider(fakeProvider{name: providers.Ivpn}, servers)
+	_, err = provider.FetchServers(context.Background(), 0)
+	assert.ErrorIs(t, err, ErrMirrorProviderNotFound)
+
+	provider = updater.newMirrorProvider(fakeProvider{name: providers.Surfshark}, servers)
+	fetched, err = provider.FetchServers(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Equal(t, serversStorage.GetServers(providers.Surfshark), fetched)
+
+	// The mirror timestamp is kept for servers updated from the mirror.
+	provider = updater.newMirrorProvider(fakeProvider{name: providers.Mullvad}, servers)
+	diff, err := updater.updateProvider(context.Background(), provider, Options{Threshold: 1})
+	require.NoError(t, err)
+	assert.True(t, diff.Applied)
+	assert.Equal(t, map[string]int64{providers.Mullvad: mullvad.Timestamp + 1},
+		testStorage.timestamps)
+}
+
+func Test_Updater_fetchMirror_badSignature(t *testing.T) {
+	t.Parallel()
+
+	publicKey, sign := minisignKey(t)
+	data := []byte(`{}`)
+	signature := sign([]byte(`{"tampered":true}`))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/servers.json"+mirror.SignatureSuffix {
+			_, _ = w.Write(signature)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+
+	updater := New(server.Client(), nil, nil, noopLogger{})
+
+	_, err := updater.fetchMirror(context.Background(), server.URL+"/servers.json", publicKey)
+
+	assert.ErrorIs(t, err, mirror.ErrSignatureMismatch)
+}
@@ -0,0 +1,202 @@
+package probe
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"time"
+
+	"golang.org/x/crypto/blake2s"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+)
+
+// openvpnHardReset returns an OpenVPN P_CONTROL_HARD_RESET_CLIENT_V2
+// packet with a random session ID, as sent by clients to initiate
+// a connection over UDP.
+func openvpnHardReset() (packet []byte) {
+	const (
+		opcodeHardResetClientV2 = 7
+		opcodeShift             = 3
+		keyID                   = 0
+		sessionIDLength         = 8
+		packetIDLength          = 4
+	)
+	packet = make([]byte, 1+sessionIDLength+1+packetIDLength)
+	packet[0] = opcodeHardResetClientV2<<opcodeShift | keyID
+	_, _ = rand.Read(packet[1 : 1+sessionIDLength])
+	// The acknowledgment array length and the packet ID are left to 0.
+	return packet
+}
+
+const (
+	wireguardConstruction = "Noise_IKpsk2_25519_ChaChaPoly_BLAKE2s"
+	wireguardIdentifier   = "WireGuard v1 zx2c4 Jason@zx2c4.com"
+	wireguardLabelMAC1    = "mac1----"
+	wireguardKeyLength    = 32
+	// wireguardMAC1Offset is the offset of the mac1 field
+	// in the handshake initiation message.
+	wireguardMAC1Offset = 116
+)
+
+var ErrWireguardPublicKeyNotValid = errors.New("wireguard public key is not valid")
+
+// wireguardInitiation returns a Wireguard handshake initiation
+// message for the server public key given, using a random static
+// key pair. The server only responds to it if the server is under
+// load and replies with a cookie, since it does not know our key.
+// Its mac1 field is valid, so that the server processes it.
+func wireguardInitiation(serverPublicKeyBase64 string) (message []byte, err error) {
+	serverPublicKey, err := base64.StdEncoding.DecodeString(serverPublicKeyBase64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrWireguardPublicKeyNotValid, err)
+	} else if len(serverPublicKey) != wireguardKeyLength {
+		return nil, fmt.Errorf("%w: %d bytes instead of %d",
+			ErrWireguardPublicKeyNotValid, len(serverPublicKey), wireguardKeyLength)
+	}
+
+	staticPrivate, staticPublic, err := newWireguardKeyPair()
+	if err != nil {
+		return nil, fmt.Errorf("creating static key pair: %w", err)
+	}
+	ephemeralPrivate, ephemeralPublic, err := newWireguardKeyPair()
+	if err != nil {
+		return nil, fmt.Errorf("creating ephemeral key pair: %w", err)
+	}
+
+	const (
+		messageTypeInitiation = 1
+		messageLength         = 148
+	)
+	message = make([]byte, 0, messageLength)
+	message = binary.LittleEndian.AppendUint32(message, messageTypeInitiation)
+	senderIndex := make([]byte, 4) //nolint:gomnd
+	_, _ = rand.Read(senderIndex)
+	message = append(message, senderIndex...)
+
+	chainingKey := blake2sHash([]byte(wireguardConstruction))
+	handshakeHash := blake2sHash(chainingKey, []byte(wireguardIdentifier))
+	handshakeHash = blake2sHash(handshakeHash, serverPublicKey)
+
+	chainingKey = kdf(chainingKey, ephemeralPublic, 1)[0]
+	message = append(message, ephemeralPublic...)
+	handshakeHash = blake2sHash(handshakeHash, ephemeralPublic)
+
+	sharedSecret, err := curve25519.X25519(ephemeralPrivate, serverPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("computing ephemeral shared secret: %w", err)
+	}
+	keys := kdf(chainingKey, sharedSecret, 2) //nolint:gomnd
+	chainingKey = keys[0]
+	encryptedStatic, err := aead(keys[1], staticPublic, handshakeHash)
+	if err != nil {
+		return nil, fmt.Errorf("encrypting static public key: %w", err)
+	}
+	message = append(message, encryptedStatic...)
+	handshakeHash = blake2sHash(handshakeHash, encryptedStatic)
+
+	sharedSecret, err = curve25519.X25519(staticPrivate, serverPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("computing static shared secret: %w", err)
+	}
+	keys = kdf(chainingKey, sharedSecret, 2) //nolint:gomnd
+	encryptedTimestamp, err := aead(keys[1], tai64n(time.Now()), handshakeHash)
+	if err != nil {
+		return nil, fmt.Errorf("encrypting timestamp: %w", err)
+	}
+	message = append(message, encryptedTimestamp...)
+
+	mac1Key := blake2sHash([]byte(wireguardLabelMAC1), serverPublicKey)
+	message = append(message, blake2sMAC(mac1Key, message)...)
+	// mac2 is only set when responding to a cookie reply.
+	const mac2Length = 16
+	message = append(message, make([]byte, mac2Length)...)
+
+	return message, nil
+}
+
+func newWireguardKeyPair() (private, public []byte, err error) {
+	private = make([]byte, wireguardKeyLength)
+	_, err = rand.Read(private)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Clamp the private key as done by Wireguard
+	private[0] &= 248
+	private[31] = (private[31] & 127) | 64 //nolint:gomnd
+	public, err = curve25519.X25519(private, curve25519.Basepoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	return private, public, nil
+}
+
+func blake2sHash(inputs ...[]byte) (digest []byte) {
+	hasher := newBlake2s()
+	for _, input := range inputs {
+		_, _ = hasher.Write(input)
+	}
+	return hasher.Sum(nil)
+}
+
+func blake2sMAC(key, input []byte) (mac []byte) {
+	hasher, err := blake2s.New128(key)
+	if err != nil {
+		panic(err) // key is never empty or too long
+	}
+	_, _ = hasher.Write(input)
+	return hasher.Sum(nil)
+}
+
+func newBlake2s() hash.Hash {
+	hasher, err := blake2s.New256(nil)
+	if err != nil {
+		panic(err) // cannot fail without key
+	}
+	return hasher
+}
+
+func hmacBlake2s(key []byte, inputs ...[]byte) (mac []byte) {
+	hasher := hmac.New(newBlake2s, key)
+	for _, input := range inputs {
+		_, _ = hasher.Write(input)
+	}
+	return hasher.Sum(nil)
+}
+
+// kdf is the HKDF function of the Noise protocol using HMAC-BLAKE2s,
+// returning n keys.
+func kdf(chainingKey, input []byte, n int) (keys [][]byte) {
+	secret := hmacBlake2s(chainingKey, input)
+	keys = make([][]byte, n)
+	previous := []byte{}
+	for i := range keys {
+		previous = hmacBlake2s(secret, previous, []byte{byte(i + 1)})
+		keys[i] = previous
+	}
+	return keys
+}
+
+// aead encrypts the plaintext with the key given and a zero nonce,
+// authenticating the additional data given.
+func aead(key, plaintext, additionalData []byte) (ciphertext []byte, err error) {
+	cipher, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	return cipher.Seal(nil, nonce, plaintext, additionalData), nil
+}
+
+// tai64n returns the TAI64N timestamp of the time given.
+func tai64n(t time.Time) (timestamp []byte) {
+	const tai64Base = uint64(0x400000000000000a)
+	timestamp = make([]byte, 0, 12) //nolint:gomnd
+	timestamp = binary.BigEndian.AppendUint64(timestamp, tai64Base+uint64(t.Unix()))
+	timestamp = binary.BigEndian.AppendUint32(timestamp, uint32(t.Nanosecond()))
+	return timestamp
+}
@@ -0,0 +1,202 @@
+// Package probe tests the reachability of VPN servers.
+package probe
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/constants/vpn"
+	"github.com/qdm12/gluetun/internal/models"
+	"github.com/qdm12/gluetun/internal/provider/utils"
+)
+
+// Prober probes VPN servers in parallel.
+type Prober struct {
+	timeout     time.Duration
+	parallelism int
+	dialer      *net.Dialer
+}
+
+// New creates a prober probing at most parallelism servers at
+// the same time, each probe timing out after the timeout given.
+func New(timeout time.Duration, parallelism int) *Prober {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	return &Prober{
+		timeout:     timeout,
+		parallelism: parallelism,
+		dialer:      &net.Dialer{},
+	}
+}
+
+// Result is the result of probing a server.
+type Result struct {
+	// Reachable is nil if the probe was inconclusive.
+	Reachable *bool
+	// RTT is the round trip time measured, and is
+	// only set if the server is reachable.
+	RTT time.Duration
+}
+
+// Summary counts the servers by probe result.
+type Summary struct {
+	Reachable   int
+	Unreachable int
+	Unknown     int
+}
+
+// ProbeServers probes the servers given in parallel and sets their
+// Reachable and RTT fields. The ports to probe are the default ports
+// given. It returns an error only if the context is canceled.
+func (p *Prober) ProbeServers(ctx context.Context, servers []models.Server,
+	defaults utils.ConnectionDefaults) (summary Summary, err error) {
+	semaphore := make(chan struct{}, p.parallelism)
+	wg := sync.WaitGroup{}
+	for i := range servers {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return summary, ctx.Err()
+		case semaphore <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(server *models.Server) {
+			defer wg.Done()
+			result := p.ProbeServer(ctx, *server, defaults)
+			server.Reachable = result.Reachable
+			server.RTT = result.RTT
+			<-semaphore
+		}(&servers[i])
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return summary, err
+	}
+
+	for _, server := range servers {
+		switch {
+		case server.Reachable == nil:
+			summary.Unknown++
+		case *server.Reachable:
+			summary.Reachable++
+		default:
+			summary.Unreachable++
+		}
+	}
+	return summary, nil
+}
+
+// ProbeServer probes the first IP address of the server given:
+//   - OpenVPN servers supporting TCP are probed with a TCP connection
+//     to the default OpenVPN TCP port.
+//   - Other OpenVPN servers are sent an OpenVPN client hard reset
+//     packet on the default OpenVPN UDP port.
+//   - Wireguard servers are sent a Wireguard handshake initiation
+//     packet on the default Wireguard port.
+//
+// UDP probes are inconclusive if no response is received, since
+// servers using tls-auth or not knowing our Wireguard public key
+// silently drop the probe packet.
+func (p *Prober) ProbeServer(ctx context.Context, server models.Server,
+	defaults utils.ConnectionDefaults) (result Result) {
+	if len(server.IPs) == 0 {
+		return result
+	}
+	ip := server.IPs[0]
+
+	switch {
+	case server.VPN == vpn.Wireguard:
+		if defaults.WireguardPort == 0 {
+			return result
+		}
+		packet, err := wireguardInitiation(server.WgPubKey)
+		if err != nil {
+			return result
+		}
+		address := netip.AddrPortFrom(ip, defaults.WireguardPort)
+		return p.probeUDP(ctx, address, packet)
+	case server.TCP && defaults.OpenVPNTCPPort != 0:
+		address := netip.AddrPortFrom(ip, defaults.OpenVPNTCPPort)
+		return p.probeTCP(ctx, address)
+	case server.UDP && defaults.OpenVPNUDPPort != 0:
+		address := netip.AddrPortFrom(ip, defaults.OpenVPNUDPPort)
+		return p.probeUDP(ctx, address, openvpnHardReset())
+	default:
+		return result
+	}
+}
+
+func (p *Prober) probeTCP(ctx context.Context, address netip.AddrPort) (result Result) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	start := time.Now()
+	connection, err := p.dialer.DialContext(ctx, "tcp", address.String())
+	if err != nil {
+		return unreachable()
+	}
+	rtt := time.Since(start)
+	_ = connection.Close()
+	return reachable(rtt)
+}
+
+func (p *Prober) probeUDP(ctx context.Context, address netip.AddrPort,
+	packet []byte) (result Result) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	connection, err := p.dialer.DialContext(ctx, "udp", address.String())
+	if err != nil {
+		return result
+	}
+	defer connection.Close()
+
+	deadline, _ := ctx.Deadline()
+	err = connection.SetDeadline(deadline)
+	if err != nil {
+		return result
+	}
+
+	start := time.Now()
+	_, err = connection.Write(packet)
+	if err != nil {
+		return udpErrorResult(err)
+	}
+
+	buffer := make([]byte, 1)
+	_, err = connection.Read(buffer)
+	if err != nil {
+		return udpErrorResult(err)
+	}
+	return reachable(time.Since(start))
+}
+
+// udpErrorResult returns an unreachable result if the error is an
+// ICMP port unreachable error, and an inconclusive result otherwise.
+func udpErrorResult(err error) (result Result) {
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return unreachable()
+	}
+	return result
+}
+
+func reachable(rtt time.Duration) Result {
+	isReachable := true
+	return Result{
+		Reachable: &isReachable,
+		RTT:       rtt.Round(time.Microsecond),
+	}
+}
+
+func unreachable() Result {
+	isReachable := false
+	return Result{Reachable: &isReachable}
+}
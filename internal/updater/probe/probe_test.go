@@ -0,0 +1,210 @@
+package probe
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/base64"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/constants/vpn"
+	"github.com/qdm12/gluetun/internal/models"
+	"github.com/qdm12/gluetun/internal/provider/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ptrTo[T any](value T) *T { return &value }
+
+var localhost = netip.AddrFrom4([4]byte{127, 0, 0, 1})
+
+func listenTCP(t *testing.T) (port uint16) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			connection, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = connection.Close()
+		}
+	}()
+	return uint16(listener.Addr().(*net.TCPAddr).Port)
+}
+
+// listenUDP listens on a local UDP port and calls
+// respond for each packet received, writing back
+// the response if it is not empty.
+func listenUDP(t *testing.T, respond func(packet []byte) (response []byte)) (port uint16) {
+	t.Helper()
+	connection, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = connection.Close() })
+	go func() {
+		buffer := make([]byte, 1500) //nolint:gomnd
+		for {
+			n, address, err := connection.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			response := respond(buffer[:n])
+			if len(response) > 0 {
+				_, _ = connection.WriteTo(response, address)
+			}
+		}
+	}()
+	return uint16(connection.LocalAddr().(*net.UDPAddr).Port)
+}
+
+// closedPort returns a local port no one listens on.
+func closedPort(t *testing.T, network string) (port uint16) {
+	t.Helper()
+	switch network {
+	case "tcp":
+		listener, err := net.Listen(network, "127.0.0.1:0")
+		require.NoError(t, err)
+		port = uint16(listener.Addr().(*net.TCPAddr).Port)
+		require.NoError(t, listener.Close())
+	default:
+		connection, err := net.ListenPacket(network, "127.0.0.1:0")
+		require.NoError(t, err)
+		port = uint16(connection.LocalAddr().(*net.UDPAddr).Port)
+		require.NoError(t, connection.Close())
+	}
+	return port
+}
+
+func Test_Prober_ProbeServer(t *testing.T) {
+	t.Parallel()
+
+	tcpPort := listenTCP(t)
+	respondingUDPPort := listenUDP(t, func(packet []byte) []byte {
+		if len(packet) == 14 && packet[0] == 0x38 { //nolint:gomnd
+			return []byte{0x40} // P_CONTROL_HARD_RESET_SERVER_V2
+		}
+		return nil
+	})
+	silentUDPPort := listenUDP(t, func([]byte) []byte { return nil })
+
+	serverPublicKey := make([]byte, wireguardKeyLength)
+	serverPublicKey[0] = 9
+	wireguardPort := listenUDP(t, func(packet []byte) []byte {
+		const messageLength = 148
+		if len(packet) != messageLength || packet[0] != 1 {
+			return nil
+		}
+		mac1Key := blake2sHash([]byte(wireguardLabelMAC1), serverPublicKey)
+		expectedMAC1 := blake2sMAC(mac1Key, packet[:wireguardMAC1Offset])
+		const mac1Length = 16
+		mac1 := packet[wireguardMAC1Offset : wireguardMAC1Offset+mac1Length]
+		if !hmac.Equal(expectedMAC1, mac1) {
+			return nil
+		}
+		return []byte{3} // cookie reply
+	})
+
+	testCases := map[string]struct {
+		server    models.Server
+		defaults  utils.ConnectionDefaults
+		reachable *bool
+	}{
+		"tcp_reachable": {
+			server:    models.Server{VPN: vpn.OpenVPN, TCP: true, UDP: true},
+			defaults:  utils.ConnectionDefaults{OpenVPNTCPPort: tcpPort},
+			reachable: ptrTo(true),
+		},
+		"tcp_unreachable": {
+			server:    models.Server{VPN: vpn.OpenVPN, TCP: true},
+			defaults:  utils.ConnectionDefaults{OpenVPNTCPPort: closedPort(t, "tcp")},
+			reachable: ptrTo(false),
+		},
+		"udp_reachable": {
+			server:    models.Server{VPN: vpn.OpenVPN, UDP: true},
+			defaults:  utils.ConnectionDefaults{OpenVPNUDPPort: respondingUDPPort},
+			reachable: ptrTo(true),
+		},
+		"udp_unreachable": {
+			server:    models.Server{VPN: vpn.OpenVPN, UDP: true},
+			defaults:  utils.ConnectionDefaults{OpenVPNUDPPort: closedPort(t, "udp")},
+			reachable: ptrTo(false),
+		},
+		"udp_no_response": {
+			server:   models.Server{VPN: vpn.OpenVPN, UDP: true},
+			defaults: utils.ConnectionDefaults{OpenVPNUDPPort: silentUDPPort},
+		},
+		"wireguard_reachable": {
+			server: models.Server{
+				VPN:      vpn.Wireguard,
+				WgPubKey: base64.StdEncoding.EncodeToString(serverPublicKey),
+			},
+			defaults:  utils.ConnectionDefaults{WireguardPort: wireguardPort},
+			reachable: ptrTo(true),
+		},
+		"wireguard_invalid_public_key": {
+			server:   models.Server{VPN: vpn.Wireguard, WgPubKey: "invalid"},
+			defaults: utils.ConnectionDefaults{WireguardPort: wireguardPort},
+		},
+		"no_port": {
+			server: models.Server{VPN: vpn.OpenVPN, TCP: true},
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			const timeout = 200 * time.Millisecond
+			prober := New(timeout, 1)
+			server := testCase.server
+			server.IPs = []netip.Addr{localhost}
+
+			result := prober.ProbeServer(context.Background(), server, testCase.defaults)
+
+			assert.Equal(t, testCase.reachable, result.Reachable)
+			if result.Reachable == nil || !*result.Reachable {
+				assert.Zero(t, result.RTT)
+			}
+		})
+	}
+}
+
+func Test_Prober_ProbeServers(t *testing.T) {
+	t.Parallel()
+
+	tcpPort := listenTCP(t)
+	servers := []models.Server{
+		{VPN: vpn.OpenVPN, TCP: true, IPs: []netip.Addr{localhost}},
+		{VPN: vpn.OpenVPN, TCP: true, IPs: []netip.Addr{localhost}},
+		{VPN: vpn.Wireguard, WgPubKey: "invalid", IPs: []netip.Addr{localhost}},
+	}
+	defaults := utils.ConnectionDefaults{
+		OpenVPNTCPPort: tcpPort,
+		WireguardPort:  closedPort(t, "udp"),
+	}
+	const timeout = time.Second
+	prober := New(timeout, 2) //nolint:gomnd
+
+	summary, err := prober.ProbeServers(context.Background(), servers, defaults)
+
+	require.NoError(t, err)
+	assert.Equal(t, Summary{Reachable: 2, Unknown: 1}, summary)
+	assert.Equal(t, ptrTo(true), servers[0].Reachable)
+	assert.Equal(t, ptrTo(true), servers[1].Reachable)
+	assert.Nil(t, servers[2].Reachable)
+}
+
+func Test_openvpnHardReset(t *testing.T) {
+	t.Parallel()
+
+	packet := openvpnHardReset()
+
+	require.Len(t, packet, 14)
+	assert.Equal(t, byte(0x38), packet[0])
+	assert.Equal(t, []byte{0, 0, 0, 0, 0}, packet[9:])
+}
@@ -7,23 +7,32 @@ import (
 	"fmt"
 
 	"github.com/qdm12/gluetun/internal/models"
+	"github.com/qdm12/gluetun/internal/provider/utils"
+	"github.com/qdm12/gluetun/internal/updater/probe"
 )
 
 type Provider interface {
 	Name() string
 	FetchServers(ctx context.Context, minServers int) (servers []models.Server, err error)
+	ConnectionDefaults() utils.ConnectionDefaults
 }
 
-var ErrServerHasNotEnoughInformation = errors.New("server has not enough information")
+var (
+	ErrServerHasNotEnoughInformation = errors.New("server has not enough information")
+	ErrThresholdExceeded             = errors.New("servers change threshold exceeded")
+)
 
 func (u *Updater) updateProvider(ctx context.Context, provider Provider,
-	minRatio float64) (err error) {
+	options Options) (diff ProviderDiff, err error) {
 	providerName := provider.Name()
-	existingServersCount := u.storage.GetServersCount(providerName)
-	minServers := int(minRatio * float64(existingServersCount))
+	existingServers := u.storage.GetServers(providerName)
+	minServers := 0
+	if options.RejectOverThreshold {
+		minServers = int((1 - options.Threshold) * float64(len(existingServers)))
+	}
 	servers, err := provider.FetchServers(ctx, minServers)
 	if err != nil {
-		return fmt.Errorf("getting servers: %w", err)
+		return diff, fmt.Errorf("getting servers: %w", err)
 	}
 
 	for _, server := range servers {
@@ -33,21 +42,48 @@ func (u *Updater) updateProvider(ctx context.Context, provider Provider,
 			if jsonErr != nil {
 				panic(jsonErr)
 			}
-			return fmt.Errorf("server %s has not enough information: %w", serverJSON, err)
+			return diff, fmt.Errorf("server %s has not enough information: %w", serverJSON, err)
+		}
+	}
+
+	if options.Probe {
+		prober := probe.New(options.ProbeTimeout, options.ProbeParallelism)
+		summary, err := prober.ProbeServers(ctx, servers, provider.ConnectionDefaults())
+		if err != nil {
+			return diff, fmt.Errorf("probing servers: %w", err)
 		}
+		u.logger.Info(fmt.Sprintf("%s: %d servers reachable, %d unreachable, %d unknown",
+			providerName, summary.Reachable, summary.Unreachable, summary.Unknown))
 	}
 
-	if u.storage.ServersAreEqual(providerName, servers) {
-		return nil
+	diff = diffServers(providerName, existingServers, servers)
+	if diff.ChangeRatio > options.Threshold {
+		diff.ThresholdExceeded = true
+		if options.RejectOverThreshold {
+			return diff, fmt.Errorf("%w: %.0f%% of %d servers removed or changed is above %.0f%%",
+				ErrThresholdExceeded, diff.ChangeRatio*100, //nolint:gomnd
+				diff.OldCount, options.Threshold*100) //nolint:gomnd
+		}
+	}
+
+	if options.DryRun || u.storage.ServersAreEqual(providerName, servers) {
+		return diff, nil
 	}
 
 	// Note the servers variable must NOT BE MUTATED after this call,
 	// since the implementation does not deep copy the servers.
 	// TODO set in storage in provider updater directly, server by server,
 	// to avoid accumulating server data in memory.
-	err = u.storage.SetServers(providerName, servers)
+	if mirror, ok := provider.(*mirrorProvider); ok {
+		// Keep the mirror timestamp so more recent mirror
+		// data is detected as such on the next update.
+		err = u.storage.SetServersWithTimestamp(providerName, servers, mirror.timestamp())
+	} else {
+		err = u.storage.SetServers(providerName, servers)
+	}
 	if err != nil {
-		return fmt.Errorf("setting servers to storage: %w", err)
+		return diff, fmt.Errorf("setting servers to storage: %w", err)
 	}
-	return nil
+	diff.Applied = true
+	return diff, nil
 }
@@ -3,11 +3,35 @@ package resolver
 import (
 	"context"
 	"net"
+
+	"github.com/qdm12/dns/pkg/doh"
+	"github.com/qdm12/dns/pkg/dot"
 )
 
-func newResolver(resolverAddress string) *net.Resolver {
+func newResolver(settings Settings) *net.Resolver {
+	switch settings.Protocol {
+	case ProtocolDoT:
+		return dot.NewResolver(dot.ResolverSettings{
+			DoTProviders: settings.Providers,
+		})
+	case ProtocolDoH:
+		return doh.NewResolver(doh.ResolverSettings{
+			DoHProviders: settings.Providers,
+			SelfDNS: doh.SelfDNS{
+				DoTProviders: settings.Providers,
+			},
+		})
+	default:
+		return newPlainResolver(settings.Address)
+	}
+}
+
+func newPlainResolver(resolverAddress string) *net.Resolver {
 	d := net.Dialer{}
-	resolverAddress = net.JoinHostPort(resolverAddress, "53")
+	_, _, err := net.SplitHostPort(resolverAddress)
+	if err != nil { // no port specified
+		resolverAddress = net.JoinHostPort(resolverAddress, "53")
+	}
 	return &net.Resolver{
 		PreferGo: true,
 		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
@@ -11,12 +11,18 @@ type Parallel struct {
 	repeatResolver *Repeat
 }
 
-func NewParallelResolver(resolverAddress string) *Parallel {
+func NewParallelResolver(settings Settings) *Parallel {
 	return &Parallel{
-		repeatResolver: NewRepeat(resolverAddress),
+		repeatResolver: NewRepeat(settings),
 	}
 }
 
+// SetSettings changes the DNS upstream settings
+// for the next host resolutions.
+func (pr *Parallel) SetSettings(settings Settings) {
+	pr.repeatResolver.SetSettings(settings)
+}
+
 type ParallelSettings struct {
 	// Hosts to resolve in parallel.
 	Hosts     []string
@@ -7,19 +7,30 @@ import (
 	"net"
 	"net/netip"
 	"sort"
+	"sync"
 	"time"
 )
 
 type Repeat struct {
-	resolver *net.Resolver
+	resolver      *net.Resolver
+	resolverMutex sync.RWMutex
 }
 
-func NewRepeat(resolverAddress string) *Repeat {
+func NewRepeat(settings Settings) *Repeat {
 	return &Repeat{
-		resolver: newResolver(resolverAddress),
+		resolver: newResolver(settings),
 	}
 }
 
+// SetSettings changes the DNS upstream settings
+// for the next host resolutions.
+func (r *Repeat) SetSettings(settings Settings) {
+	resolver := newResolver(settings)
+	r.resolverMutex.Lock()
+	defer r.resolverMutex.Unlock()
+	r.resolver = resolver
+}
+
 type RepeatSettings struct {
 	Address         string
 	MaxDuration     time.Duration
@@ -122,7 +133,10 @@ func (r *Repeat) resolveOnce(ctx, timedCtx context.Context, host string,
 }
 
 func (r *Repeat) lookupIPs(ctx context.Context, host string) (ips []netip.Addr, err error) {
-	addresses, err := r.resolver.LookupIPAddr(ctx, host)
+	r.resolverMutex.RLock()
+	resolver := r.resolver
+	r.resolverMutex.RUnlock()
+	addresses, err := resolver.LookupIPAddr(ctx, host)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,160 @@
+package resolver
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/qdm12/dns/pkg/provider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// testDoHProvider is a DNS provider whose DNS over HTTPS
+// server is the URL given.
+type testDoHProvider struct {
+	url *url.URL
+}
+
+func (p testDoHProvider) DNS() provider.DNSServer { return provider.DNSServer{} }
+func (p testDoHProvider) DoT() provider.DoTServer { return provider.DoTServer{} }
+func (p testDoHProvider) DoH() provider.DoHServer { return provider.DoHServer{URL: p.url} }
+func (p testDoHProvider) String() string          { return "test" }
+
+// newFakeDoHServer returns a DNS over HTTPS server answering
+// A queries with one of the IPv4 addresses given in turn,
+// as a round robin DNS server would, and returns the number
+// of queries it received.
+func newFakeDoHServer(t *testing.T, ips []netip.Addr) (
+	dohURL *url.URL, queries *atomic.Int32) {
+	t.Helper()
+
+	queries = new(atomic.Int32)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/dns-message", r.Header.Get("Content-Type"))
+		wire, err := io.ReadAll(r.Body)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		var parser dnsmessage.Parser
+		header, err := parser.Start(wire)
+		if !assert.NoError(t, err) {
+			return
+		}
+		question, err := parser.Question()
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{
+			ID:            header.ID,
+			Response:      true,
+			Authoritative: true,
+		})
+		builder.EnableCompression()
+		_ = builder.StartQuestions()
+		_ = builder.Question(question)
+		_ = builder.StartAnswers()
+		if question.Type == dnsmessage.TypeA {
+			n := queries.Add(1)
+			ip := ips[int(n-1)%len(ips)]
+			_ = builder.AResource(dnsmessage.ResourceHeader{
+				Name:  question.Name,
+				Class: dnsmessage.ClassINET,
+				TTL:   60, //nolint:gomnd
+			}, dnsmessage.AResource{A: ip.As4()})
+		}
+		response, err := builder.Finish()
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/dns-message")
+		_, _ = w.Write(response)
+	})
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	dohURL, err := url.Parse(server.URL + "/dns-query")
+	require.NoError(t, err)
+	return dohURL, queries
+}
+
+func Test_Repeat_Resolve_doh(t *testing.T) {
+	t.Parallel()
+
+	ips := []netip.Addr{
+		netip.AddrFrom4([4]byte{1, 2, 3, 4}),
+		netip.AddrFrom4([4]byte{5, 6, 7, 8}),
+	}
+	dohURL, queries := newFakeDoHServer(t, ips)
+
+	repeat := NewRepeat(Settings{
+		Protocol:  ProtocolDoH,
+		Providers: []provider.Provider{testDoHProvider{url: dohURL}},
+	})
+
+	const maxNoNew = 2
+	settings := RepeatSettings{
+		MaxDuration:     5 * time.Second,
+		BetweenDuration: time.Millisecond,
+		MaxNoNew:        maxNoNew,
+		MaxFails:        1,
+		SortIPs:         true,
+	}
+
+	resolved, err := repeat.Resolve(context.Background(), "vpn.example.com", settings)
+
+	require.NoError(t, err)
+	assert.Equal(t, ips, resolved)
+	// 2 resolutions finding a new IP address, followed
+	// by 2 resolutions finding no new IP address.
+	expectedQueries := len(ips) + maxNoNew
+	assert.Equal(t, int32(expectedQueries), queries.Load())
+}
+
+func Test_newPlainResolver(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		buffer := make([]byte, 512) //nolint:gomnd
+		for {
+			n, address, err := listener.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			var message dnsmessage.Message
+			if err := message.Unpack(buffer[:n]); err != nil {
+				continue
+			}
+			message.Header.Response = true
+			message.Header.RCode = dnsmessage.RCodeNameError
+			response, err := message.Pack()
+			if err != nil {
+				continue
+			}
+			_, _ = listener.WriteTo(response, address)
+		}
+	}()
+
+	// The address contains a port, so 53 must not be appended.
+	resolver := newPlainResolver(listener.LocalAddr().String())
+
+	_, err = resolver.LookupNetIP(context.Background(), "ip4", "vpn.example.com")
+	var dnsErr *net.DNSError
+	require.ErrorAs(t, err, &dnsErr)
+	assert.True(t, dnsErr.IsNotFound)
+}
@@ -0,0 +1,48 @@
+package resolver
+
+import (
+	"github.com/qdm12/dns/pkg/provider"
+)
+
+const (
+	// ProtocolPlain is plaintext DNS over UDP.
+	ProtocolPlain = "plain"
+	// ProtocolDoT is DNS over TLS.
+	ProtocolDoT = "dot"
+	// ProtocolDoH is DNS over HTTPS.
+	ProtocolDoH = "doh"
+)
+
+// Settings are the settings of the DNS upstream used to resolve hosts.
+type Settings struct {
+	// Protocol is the DNS protocol to use, and can be
+	// ProtocolPlain, ProtocolDoT or ProtocolDoH.
+	Protocol string
+	// Address is the plaintext DNS server address, with an
+	// optional port defaulting to 53. It is only used with
+	// the plain protocol.
+	Address string
+	// Providers are the DNS providers to use with the DoT
+	// or DoH protocols. For DoH, the DoT servers of these
+	// providers are used to resolve the DoH URL hostnames.
+	Providers []provider.Provider
+}
+
+// ParseSettings returns the settings for the protocol, plaintext
+// DNS server address and DNS provider names given.
+func ParseSettings(protocol, address string, providerNames []string) (
+	settings Settings, err error) {
+	providers := make([]provider.Provider, len(providerNames))
+	for i, name := range providerNames {
+		providers[i], err = provider.Parse(name)
+		if err != nil {
+			return settings, err
+		}
+	}
+
+	return Settings{
+		Protocol:  protocol,
+		Address:   address,
+		Providers: providers,
+	}, nil
+}
@@ -2,9 +2,11 @@ package updater
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"time"
 
+	"github.com/qdm12/gluetun/internal/models"
 	"github.com/qdm12/gluetun/internal/updater/unzip"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
@@ -36,33 +38,85 @@ func New(httpClient *http.Client, storage Storage,
 	}
 }
 
+// Options are options for updating servers.
+type Options struct {
+	// Threshold is the maximum ratio of existing servers which
+	// can be removed or changed for each provider.
+	Threshold float64
+	// RejectOverThreshold is true if the servers of a provider
+	// should not be updated when the threshold is exceeded.
+	// If false, the update is applied and flagged in its diff.
+	RejectOverThreshold bool
+	// DryRun is true if servers should only be compared to the
+	// existing servers, without being written to storage.
+	DryRun bool
+	// Probe is true if the servers fetched should be probed
+	// to set their reachability and round trip time.
+	Probe bool
+	// ProbeTimeout is the timeout for probing each server.
+	ProbeTimeout time.Duration
+	// ProbeParallelism is the maximum number of servers
+	// probed at the same time.
+	ProbeParallelism int
+	// MirrorURL is the URL of the servers data to fetch instead
+	// of fetching servers from each provider, if not empty.
+	MirrorURL string
+	// MirrorPublicKey is the minisign public key to verify the
+	// signature of the servers data fetched from MirrorURL, and
+	// defaults to the public key built in the program if empty.
+	MirrorPublicKey string
+}
+
+// UpdateServers updates the servers of each provider given and
+// returns the diff for each provider, including the ones for which
+// the update failed. If a single provider is given, its update error
+// is returned. Otherwise, errors are logged and only a context
+// error is returned.
 func (u *Updater) UpdateServers(ctx context.Context, providers []string,
-	minRatio float64) (err error) {
+	options Options) (diffs []ProviderDiff, err error) {
+	var mirrorServers models.AllServers
+	if options.MirrorURL != "" {
+		mirrorServers, err = u.fetchMirror(ctx, options.MirrorURL, options.MirrorPublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("fetching servers from mirror: %w", err)
+		}
+	}
+
 	caser := cases.Title(language.English)
+	diffs = make([]ProviderDiff, 0, len(providers))
 	for _, providerName := range providers {
 		u.logger.Info("updating " + caser.String(providerName) + " servers...")
 
-		fetcher := u.providers.Get(providerName)
+		var fetcher Provider = u.providers.Get(providerName)
+		if options.MirrorURL != "" {
+			fetcher = u.newMirrorProvider(fetcher, mirrorServers)
+		}
 		// TODO support servers offering only TCP or only UDP
 		// for NordVPN and PureVPN
-		err := u.updateProvider(ctx, fetcher, minRatio)
+		diff, err := u.updateProvider(ctx, fetcher, options)
+		diff.Provider = providerName
+		diff.Time = u.timeNow()
 		if err == nil {
+			diffs = append(diffs, diff)
+			u.logger.Info(diff.Summary())
 			continue
 		}
+		diff.Error = err.Error()
+		diffs = append(diffs, diff)
 
 		// return the only error for the single provider.
 		if len(providers) == 1 {
-			return err
+			return diffs, err
 		}
 
 		// stop updating the next providers if context is canceled.
 		if ctxErr := ctx.Err(); ctxErr != nil {
-			return ctxErr
+			return diffs, ctxErr
 		}
 
 		// Log the error and continue updating the next provider.
 		u.logger.Error(err.Error())
 	}
 
-	return nil
+	return diffs, nil
 }
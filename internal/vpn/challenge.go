@@ -0,0 +1,13 @@
+package vpn
+
+import (
+	"github.com/qdm12/gluetun/internal/openvpn"
+)
+
+func (l *Loop) GetChallenge() (challenge openvpn.Challenge, ok bool) {
+	return l.challenges.Pending()
+}
+
+func (l *Loop) AnswerChallenge(response string) (err error) {
+	return l.challenges.Answer(response)
+}
@@ -0,0 +1,22 @@
+package vpn
+
+import (
+	"github.com/qdm12/gluetun/internal/constants/providers"
+	"github.com/qdm12/gluetun/internal/models"
+)
+
+// excludeServer excludes the server of the connection given from
+// future server selections, until the next servers update.
+func (l *Loop) excludeServer(providerName string, connection models.Connection) {
+	if providerName == providers.Custom {
+		l.logger.Warn("cannot exclude server for custom provider")
+		return
+	}
+
+	l.storage.ExcludeServer(providerName, connection.IP)
+	serverDescription := connection.IP.String()
+	if connection.Hostname != "" {
+		serverDescription = connection.Hostname + " (" + serverDescription + ")"
+	}
+	l.logger.Info("excluded server " + serverDescription + " until the next servers update")
+}
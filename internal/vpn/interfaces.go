@@ -7,18 +7,24 @@ import (
 	"github.com/qdm12/gluetun/internal/configuration/settings"
 	"github.com/qdm12/gluetun/internal/models"
 	"github.com/qdm12/gluetun/internal/netlink"
+	"github.com/qdm12/gluetun/internal/openvpn"
 	"github.com/qdm12/gluetun/internal/portforward"
 	"github.com/qdm12/gluetun/internal/provider"
+	"github.com/qdm12/gluetun/internal/routing"
 )
 
 type Firewall interface {
 	SetVPNConnection(ctx context.Context, connection models.Connection, interfaceName string) error
 	SetAllowedPort(ctx context.Context, port uint16, interfaceName string) error
 	RemoveAllowedPort(ctx context.Context, port uint16) error
+	SetTunnelConnection(ctx context.Context, name string,
+		connection models.Connection, intf string) error
+	RemoveTunnelConnection(ctx context.Context, name string)
 }
 
 type Routing interface {
 	VPNLocalGatewayIP(vpnInterface string) (gateway netip.Addr, err error)
+	SetupTunnel(tunnel routing.TunnelRouting) (cleanup func() error, err error)
 }
 
 type PortForward interface {
@@ -30,6 +36,7 @@ type OpenVPN interface {
 	WriteConfig(lines []string) error
 	WriteAuthFile(user, password string) error
 	WriteAskPassFile(passphrase string) error
+	ForTunnel(name string) *openvpn.Configurator
 }
 
 type Providers interface {
@@ -39,6 +46,7 @@ type Providers interface {
 type Storage interface {
 	FilterServers(provider string, selection settings.ServerSelection) (servers []models.Server, err error)
 	GetServerByName(provider, name string) (server models.Server, ok bool)
+	ExcludeServer(provider string, ip netip.Addr)
 }
 
 type NetLinker interface {
@@ -79,4 +87,5 @@ type PublicIPLoop interface {
 	ApplyStatus(ctx context.Context, status models.LoopStatus) (
 		outcome string, err error)
 	SetData(data models.PublicIP)
+	ExitMismatches() <-chan models.ExitCheck
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/qdm12/gluetun/internal/constants"
 	"github.com/qdm12/gluetun/internal/loopstate"
 	"github.com/qdm12/gluetun/internal/models"
+	"github.com/qdm12/gluetun/internal/openvpn"
 	"github.com/qdm12/gluetun/internal/vpn/state"
 	"github.com/qdm12/golibs/command"
 	"github.com/qdm12/log"
@@ -32,9 +33,11 @@ type Loop struct {
 	publicip    PublicIPLoop
 	dnsLooper   DNSLoop
 	// Other objects
-	starter command.Starter // for OpenVPN
-	logger  log.LoggerInterface
-	client  *http.Client
+	starter    command.Starter     // for OpenVPN
+	challenges *openvpn.Challenges // for OpenVPN
+	tunnels    tunnels
+	logger     log.LoggerInterface
+	client     *http.Client
 	// Internal channels and values
 	stop        <-chan struct{}
 	stopped     chan<- struct{}
@@ -81,6 +84,7 @@ func NewLoop(vpnSettings settings.VPN, ipv6Supported bool, vpnInputPorts []uint1
 		publicip:      publicip,
 		dnsLooper:     dnsLooper,
 		starter:       starter,
+		challenges:    openvpn.NewChallenges(),
 		logger:        logger,
 		client:        client,
 		start:         start,
@@ -5,6 +5,7 @@ import (
 
 	"github.com/qdm12/gluetun/internal/constants"
 	"github.com/qdm12/gluetun/internal/constants/vpn"
+	"github.com/qdm12/gluetun/internal/models"
 	"github.com/qdm12/log"
 )
 
@@ -17,6 +18,9 @@ func (l *Loop) Run(ctx context.Context, done chan<- struct{}) {
 		return
 	}
 
+	l.startTunnels(ctx)
+	defer l.stopTunnels()
+
 	for ctx.Err() == nil {
 		settings := l.state.GetSettings()
 
@@ -26,16 +30,18 @@ func (l *Loop) Run(ctx context.Context, done chan<- struct{}) {
 		var vpnRunner interface {
 			Run(ctx context.Context, waitError chan<- error, tunnelReady chan<- struct{})
 		}
-		var serverName, vpnInterface string
+		var connection models.Connection
+		var vpnInterface string
 		var err error
 		subLogger := l.logger.New(log.SetComponent(settings.Type))
 		if settings.Type == vpn.OpenVPN {
 			vpnInterface = settings.OpenVPN.Interface
-			vpnRunner, serverName, err = setupOpenVPN(ctx, l.fw,
-				l.openvpnConf, providerConf, settings, l.ipv6Supported, l.starter, subLogger)
+			vpnRunner, connection, err = setupOpenVPN(ctx, l.fw,
+				l.openvpnConf, providerConf, settings, l.ipv6Supported, l.starter,
+				l.challenges, subLogger)
 		} else { // Wireguard
 			vpnInterface = settings.Wireguard.Interface
-			vpnRunner, serverName, err = setupWireguard(ctx, l.netLinker, l.fw,
+			vpnRunner, connection, err = setupWireguard(ctx, l.netLinker, l.fw,
 				providerConf, settings, l.ipv6Supported, subLogger)
 		}
 		if err != nil {
@@ -44,7 +50,7 @@ func (l *Loop) Run(ctx context.Context, done chan<- struct{}) {
 		}
 		tunnelUpData := tunnelUpData{
 			portForwarding: portForwarding,
-			serverName:     serverName,
+			serverName:     connection.ServerName,
 			portForwarder:  providerConf,
 			vpnIntf:        vpnInterface,
 		}
@@ -88,6 +94,18 @@ func (l *Loop) Run(ctx context.Context, done chan<- struct{}) {
 				l.userTrigger = true
 				l.logger.Info("starting")
 				stayHere = false
+			case check := <-l.publicip.ExitMismatches():
+				l.statusManager.Lock() // prevent SetStatus from running in parallel
+
+				l.logger.Warn("restarting VPN since exit location is not allowed: " + check.Reason)
+				l.excludeServer(*settings.Provider.Name, connection)
+				l.cleanup(context.Background(), portForwarding)
+				openvpnCancel()
+				<-waitError
+				l.statusManager.SetStatus(constants.Starting)
+				stayHere = false
+
+				l.statusManager.Unlock()
 			case err := <-waitError: // unexpected error
 				l.statusManager.Lock() // prevent SetStatus from running in parallel
 
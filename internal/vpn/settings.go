@@ -2,6 +2,7 @@ package vpn
 
 import (
 	"context"
+	"reflect"
 
 	"github.com/qdm12/gluetun/internal/configuration/settings"
 )
@@ -13,5 +14,10 @@ func (l *Loop) GetSettings() (settings settings.VPN) {
 func (l *Loop) SetSettings(ctx context.Context,
 	vpn settings.VPN) (
 	outcome string) {
-	return l.state.SetSettings(ctx, vpn)
+	tunnelsChanged := !reflect.DeepEqual(l.state.GetSettings().Tunnels, vpn.Tunnels)
+	outcome = l.state.SetSettings(ctx, vpn)
+	if tunnelsChanged {
+		l.restartTunnels()
+	}
+	return outcome
 }
@@ -29,3 +29,25 @@ func (s *State) SetSettings(ctx context.Context, vpn settings.VPN) (
 	outcome, _ = s.statusApplier.ApplyStatus(ctx, constants.Running)
 	return outcome
 }
+
+// SetTunnelSettings sets the settings of the additional tunnel with
+// the same name as the tunnel settings given, without restarting
+// the VPN. It returns found as false if there is no tunnel with
+// this name, and changed as false if its settings are unchanged.
+func (s *State) SetTunnelSettings(tunnel settings.Tunnel) (found, changed bool) {
+	s.settingsMu.Lock()
+	defer s.settingsMu.Unlock()
+	for i, existing := range s.vpn.Tunnels {
+		if existing.Name != tunnel.Name {
+			continue
+		}
+		if reflect.DeepEqual(existing, tunnel) {
+			return true, false
+		}
+		vpn := s.vpn.Copy()
+		vpn.Tunnels[i] = tunnel
+		s.vpn = vpn
+		return true, true
+	}
+	return false, false
+}
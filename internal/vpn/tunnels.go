@@ -0,0 +1,411 @@
+package vpn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/qdm12/gluetun/internal/constants"
+	"github.com/qdm12/gluetun/internal/constants/vpn"
+	"github.com/qdm12/gluetun/internal/models"
+	"github.com/qdm12/gluetun/internal/openvpn"
+	"github.com/qdm12/gluetun/internal/provider"
+	"github.com/qdm12/gluetun/internal/provider/utils"
+	"github.com/qdm12/gluetun/internal/routing"
+	"github.com/qdm12/gluetun/internal/wireguard"
+	"github.com/qdm12/log"
+)
+
+// tunnels manages the additional named tunnels,
+// each running independently of the main VPN connection.
+type tunnels struct {
+	// parentCtx is nil until the tunnels are started.
+	parentCtx context.Context //nolint:containedctx
+	nameToRun map[string]*tunnelRun
+	mutex     sync.Mutex
+}
+
+type tunnelRun struct {
+	settings   settings.Tunnel
+	cancel     context.CancelFunc
+	done       chan struct{}
+	status     models.LoopStatus
+	err        error
+	connection models.Connection
+	mutex      sync.RWMutex
+}
+
+func (t *tunnelRun) setStatus(status models.LoopStatus, err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.status = status
+	t.err = err
+	if status != constants.Running {
+		t.connection = models.Connection{}
+	}
+}
+
+func (t *tunnelRun) setRunning(connection models.Connection) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.status = constants.Running
+	t.err = nil
+	t.connection = connection
+}
+
+var (
+	ErrTunnelNotFound      = errors.New("tunnel not found")
+	ErrTunnelsNotStarted   = errors.New("tunnels are not started")
+	ErrTunnelStatusInvalid = errors.New("invalid tunnel status")
+)
+
+// GetTunnels returns the settings and status of each additional tunnel.
+func (l *Loop) GetTunnels() (statuses []models.TunnelStatus) {
+	tunnelsSettings := l.state.GetSettings().Tunnels
+
+	l.tunnels.mutex.Lock()
+	defer l.tunnels.mutex.Unlock()
+
+	statuses = make([]models.TunnelStatus, len(tunnelsSettings))
+	for i, tunnelSettings := range tunnelsSettings {
+		statuses[i] = models.TunnelStatus{
+			Name:         tunnelSettings.Name,
+			Type:         tunnelSettings.Type,
+			Interface:    tunnelSettings.Interface(),
+			Provider:     *tunnelSettings.Provider.Name,
+			Sources:      tunnelSettings.Sources,
+			Destinations: tunnelSettings.Destinations,
+			Status:       constants.Stopped,
+		}
+
+		run, ok := l.tunnels.nameToRun[tunnelSettings.Name]
+		if !ok {
+			continue
+		}
+		run.mutex.RLock()
+		statuses[i].Status = run.status
+		if run.connection.IP.IsValid() {
+			statuses[i].Endpoint = netip.AddrPortFrom(run.connection.IP, run.connection.Port)
+			statuses[i].Hostname = run.connection.Hostname
+		}
+		if run.err != nil {
+			statuses[i].Error = run.err.Error()
+		}
+		run.mutex.RUnlock()
+	}
+	return statuses
+}
+
+// ApplyTunnelStatus starts or stops the additional tunnel with the given name.
+func (l *Loop) ApplyTunnelStatus(name string, status models.LoopStatus) (
+	outcome string, err error) {
+	var tunnelSettings settings.Tunnel
+	found := false
+	for _, tunnelSettings = range l.state.GetSettings().Tunnels {
+		if tunnelSettings.Name == name {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return "", fmt.Errorf("%w: %s", ErrTunnelNotFound, name)
+	}
+
+	l.tunnels.mutex.Lock()
+	defer l.tunnels.mutex.Unlock()
+
+	switch status {
+	case constants.Running:
+		if l.tunnels.parentCtx == nil {
+			return "", fmt.Errorf("%w", ErrTunnelsNotStarted)
+		}
+		if _, running := l.tunnels.nameToRun[name]; running {
+			return "already running", nil
+		}
+		l.startTunnel(tunnelSettings)
+		return "started", nil
+	case constants.Stopped:
+		if _, running := l.tunnels.nameToRun[name]; !running {
+			return "already stopped", nil
+		}
+		l.stopTunnel(name)
+		return "stopped", nil
+	default:
+		return "", fmt.Errorf("%w: %s: possible values are: %s, %s",
+			ErrTunnelStatusInvalid, status, constants.Stopped, constants.Running)
+	}
+}
+
+// SetTunnelSettings sets the settings of the additional tunnel
+// with the same name, restarting the tunnel if it is running.
+func (l *Loop) SetTunnelSettings(tunnel settings.Tunnel) (outcome string, err error) {
+	l.tunnels.mutex.Lock()
+	defer l.tunnels.mutex.Unlock()
+
+	found, changed := l.state.SetTunnelSettings(tunnel)
+	switch {
+	case !found:
+		return "", fmt.Errorf("%w: %s", ErrTunnelNotFound, tunnel.Name)
+	case !changed:
+		return "settings left unchanged", nil
+	}
+
+	if _, running := l.tunnels.nameToRun[tunnel.Name]; !running {
+		return "settings updated", nil
+	}
+	l.stopTunnel(tunnel.Name)
+	l.startTunnel(tunnel)
+	return "settings updated and tunnel restarted", nil
+}
+
+// startTunnels starts all the additional tunnels from the settings,
+// using the given context as parent context for all tunnels.
+func (l *Loop) startTunnels(ctx context.Context) {
+	l.tunnels.mutex.Lock()
+	defer l.tunnels.mutex.Unlock()
+	l.tunnels.parentCtx = ctx
+	for _, tunnelSettings := range l.state.GetSettings().Tunnels {
+		l.startTunnel(tunnelSettings)
+	}
+}
+
+// restartTunnels stops all running tunnels and starts all the
+// tunnels from the current settings, if tunnels were started.
+func (l *Loop) restartTunnels() {
+	l.tunnels.mutex.Lock()
+	defer l.tunnels.mutex.Unlock()
+	for name := range l.tunnels.nameToRun {
+		l.stopTunnel(name)
+	}
+	if l.tunnels.parentCtx == nil {
+		return
+	}
+	for _, tunnelSettings := range l.state.GetSettings().Tunnels {
+		l.startTunnel(tunnelSettings)
+	}
+}
+
+// stopTunnels stops all the running tunnels and waits for them to exit.
+func (l *Loop) stopTunnels() {
+	l.tunnels.mutex.Lock()
+	defer l.tunnels.mutex.Unlock()
+	for name := range l.tunnels.nameToRun {
+		l.stopTunnel(name)
+	}
+	l.tunnels.parentCtx = nil
+}
+
+// startTunnel must be called with the tunnels mutex locked.
+func (l *Loop) startTunnel(tunnelSettings settings.Tunnel) {
+	ctx, cancel := context.WithCancel(l.tunnels.parentCtx)
+	run := &tunnelRun{
+		settings: tunnelSettings,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+		status:   constants.Starting,
+	}
+	if l.tunnels.nameToRun == nil {
+		l.tunnels.nameToRun = make(map[string]*tunnelRun)
+	}
+	l.tunnels.nameToRun[tunnelSettings.Name] = run
+	go l.runTunnel(ctx, run)
+}
+
+// stopTunnel must be called with the tunnels mutex locked.
+func (l *Loop) stopTunnel(name string) {
+	run := l.tunnels.nameToRun[name]
+	run.cancel()
+	<-run.done
+	delete(l.tunnels.nameToRun, name)
+}
+
+// maxTunnelBackoffTime is the maximum time to wait
+// before reconnecting a tunnel which keeps failing.
+const maxTunnelBackoffTime = 5 * time.Minute
+
+func (l *Loop) runTunnel(ctx context.Context, run *tunnelRun) {
+	defer close(run.done)
+	logger := l.logger.New(log.SetComponent("tunnel " + run.settings.Name))
+
+	backoffTime := defaultBackoffTime
+	for {
+		wasUp, err := l.connectTunnel(ctx, run, logger)
+		if ctx.Err() != nil {
+			run.setStatus(constants.Stopped, nil)
+			return
+		}
+		if wasUp {
+			backoffTime = defaultBackoffTime
+		}
+
+		run.setStatus(constants.Crashed, err)
+		logger.Error(err.Error())
+		logger.Info("retrying in " + backoffTime.String())
+		timer := time.NewTimer(backoffTime)
+		select {
+		case <-timer.C:
+			backoffTime *= 2
+			if backoffTime > maxTunnelBackoffTime {
+				backoffTime = maxTunnelBackoffTime
+			}
+		case <-ctx.Done():
+			timer.Stop()
+			run.setStatus(constants.Stopped, nil)
+			return
+		}
+	}
+}
+
+// connectTunnel connects the tunnel and blocks until the tunnel
+// exits or the context is canceled. It returns whether the
+// tunnel was up before exiting.
+func (l *Loop) connectTunnel(ctx context.Context, run *tunnelRun,
+	logger log.LoggerInterface) (wasUp bool, err error) {
+	tunnelSettings := run.settings
+
+	providerConf := l.providers.Get(*tunnelSettings.Provider.Name)
+	connection, err := providerConf.GetConnection(
+		tunnelSettings.Provider.ServerSelection, l.ipv6Supported)
+	if err != nil {
+		return false, fmt.Errorf("finding a VPN server: %w", err)
+	}
+
+	var runner tunnelRunner
+	tunnelRouting := routing.TunnelRouting{
+		Table:        tunnelSettings.FirewallMark,
+		Endpoint:     connection.IP,
+		Sources:      tunnelSettings.Sources,
+		Destinations: tunnelSettings.Destinations,
+	}
+	if tunnelSettings.Type == vpn.OpenVPN {
+		runner, err = l.setupTunnelOpenVPN(tunnelSettings, providerConf, connection, logger)
+		tunnelRouting.Interface = tunnelSettings.OpenVPN.Interface
+	} else {
+		runner, tunnelRouting.Sources, err = l.setupTunnelWireguard(tunnelSettings, connection, logger)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	err = l.fw.SetTunnelConnection(ctx, tunnelSettings.Name, connection, tunnelSettings.Interface())
+	if err != nil {
+		return false, fmt.Errorf("setting firewall: %w", err)
+	}
+	defer l.fw.RemoveTunnelConnection(context.Background(), tunnelSettings.Name)
+
+	setUp := func() (tearDown func(), err error) {
+		routingCleanup, err := l.routing.SetupTunnel(tunnelRouting)
+		if err != nil {
+			return nil, fmt.Errorf("setting up routing: %w", err)
+		}
+		run.setRunning(connection)
+		logger.Info("tunnel is up")
+		return func() {
+			err := routingCleanup()
+			if err != nil {
+				logger.Error("cleaning up routing: " + err.Error())
+			}
+		}, nil
+	}
+	return runTunnelRunner(ctx, runner, setUp)
+}
+
+type tunnelRunner interface {
+	Run(ctx context.Context, waitError chan<- error, tunnelReady chan<- struct{})
+}
+
+// runTunnelRunner runs the runner given until it exits or the
+// context is canceled, calling setUp the first time the tunnel is
+// ready and the tear down function it returns before returning.
+// It keeps receiving ready signals after the first one, since
+// OpenVPN signals it again after each soft restart and blocks
+// until the signal is received.
+func runTunnelRunner(ctx context.Context, runner tunnelRunner,
+	setUp func() (tearDown func(), err error)) (wasUp bool, err error) {
+	runnerCtx, runnerCancel := context.WithCancel(ctx)
+	defer runnerCancel()
+	waitError := make(chan error)
+	ready := make(chan struct{})
+	go runner.Run(runnerCtx, waitError, ready)
+
+	var tearDown func()
+	var setUpErr error
+	for {
+		select {
+		case <-ready:
+			if wasUp || setUpErr != nil {
+				continue
+			}
+			tearDown, setUpErr = setUp()
+			if setUpErr != nil {
+				runnerCancel()
+				continue
+			}
+			wasUp = true
+		case err = <-waitError:
+			if tearDown != nil {
+				tearDown()
+			}
+			if setUpErr != nil {
+				return false, setUpErr
+			}
+			return wasUp, err
+		}
+	}
+}
+
+func (l *Loop) setupTunnelOpenVPN(tunnelSettings settings.Tunnel,
+	providerConf provider.Provider, connection models.Connection,
+	logger log.LoggerInterface) (runner *openvpn.Runner, err error) {
+	openvpnConf := l.openvpnConf.ForTunnel(tunnelSettings.Name)
+	lines := providerConf.OpenVPNConfig(connection, tunnelSettings.OpenVPN, l.ipv6Supported)
+	lines = openvpnConf.TunnelConfig(lines, tunnelSettings.FirewallMark)
+	err = openvpnConf.WriteConfig(lines)
+	if err != nil {
+		return nil, fmt.Errorf("writing configuration to file: %w", err)
+	}
+
+	if *tunnelSettings.OpenVPN.User != "" {
+		err = openvpnConf.WriteAuthFile(*tunnelSettings.OpenVPN.User, *tunnelSettings.OpenVPN.Password)
+		if err != nil {
+			return nil, fmt.Errorf("writing auth to file: %w", err)
+		}
+	}
+
+	if *tunnelSettings.OpenVPN.KeyPassphrase != "" {
+		err = openvpnConf.WriteAskPassFile(*tunnelSettings.OpenVPN.KeyPassphrase)
+		if err != nil {
+			return nil, fmt.Errorf("writing askpass file: %w", err)
+		}
+	}
+
+	return openvpn.NewTunnelRunner(tunnelSettings.OpenVPN,
+		openvpnConf.ConfigPath(), l.starter, logger), nil
+}
+
+func (l *Loop) setupTunnelWireguard(tunnelSettings settings.Tunnel,
+	connection models.Connection, logger log.LoggerInterface) (
+	wireguarder *wireguard.Wireguard, sources []netip.Prefix, err error) {
+	wireguardSettings := utils.BuildWireguardSettings(connection,
+		tunnelSettings.Wireguard, l.ipv6Supported)
+	wireguardSettings.FirewallMark = tunnelSettings.FirewallMark
+	wireguardSettings.SkipRules = true
+
+	sources = make([]netip.Prefix, 0, len(wireguardSettings.Addresses)+len(tunnelSettings.Sources))
+	for _, address := range wireguardSettings.Addresses {
+		// Route traffic from the interface address through the tunnel,
+		// for programs binding to it.
+		sources = append(sources, netip.PrefixFrom(address.Addr(), address.Addr().BitLen()))
+	}
+	sources = append(sources, tunnelSettings.Sources...)
+
+	wireguarder, err = wireguard.New(wireguardSettings, l.netLinker, logger)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating Wireguard: %w", err)
+	}
+	return wireguarder, sources, nil
+}
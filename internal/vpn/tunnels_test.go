@@ -0,0 +1,86 @@
+package vpn
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeTunnelRunner func(ctx context.Context, waitError chan<- error,
+	tunnelReady chan<- struct{})
+
+func (f fakeTunnelRunner) Run(ctx context.Context, waitError chan<- error,
+	tunnelReady chan<- struct{}) {
+	f(ctx, waitError, tunnelReady)
+}
+
+func Test_runTunnelRunner(t *testing.T) {
+	t.Parallel()
+
+	errTest := errors.New("test error")
+
+	testCases := map[string]struct {
+		runner         fakeTunnelRunner
+		setUpErr       error
+		wasUp          bool
+		errWrapped     error
+		setUpCalls     int
+		tearDownCalled bool
+	}{
+		"runner_error": {
+			runner: func(_ context.Context, waitError chan<- error, _ chan<- struct{}) {
+				waitError <- errTest
+			},
+			errWrapped: errTest,
+		},
+		"ready_twice": {
+			runner: func(_ context.Context, waitError chan<- error, tunnelReady chan<- struct{}) {
+				tunnelReady <- struct{}{}
+				// OpenVPN signals it is ready again after a soft restart.
+				tunnelReady <- struct{}{}
+				waitError <- errTest
+			},
+			wasUp:          true,
+			errWrapped:     errTest,
+			setUpCalls:     1,
+			tearDownCalled: true,
+		},
+		"set_up_error": {
+			runner: func(ctx context.Context, waitError chan<- error, tunnelReady chan<- struct{}) {
+				tunnelReady <- struct{}{}
+				tunnelReady <- struct{}{}
+				<-ctx.Done()
+				waitError <- ctx.Err()
+			},
+			setUpErr:   errTest,
+			errWrapped: errTest,
+			setUpCalls: 1,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			setUpCalls := 0
+			tearDownCalled := false
+			setUp := func() (tearDown func(), err error) {
+				setUpCalls++
+				if testCase.setUpErr != nil {
+					return nil, testCase.setUpErr
+				}
+				return func() { tearDownCalled = true }, nil
+			}
+
+			wasUp, err := runTunnelRunner(context.Background(), testCase.runner, setUp)
+
+			assert.Equal(t, testCase.wasUp, wasUp)
+			assert.ErrorIs(t, err, testCase.errWrapped)
+			assert.Equal(t, testCase.setUpCalls, setUpCalls)
+			assert.Equal(t, testCase.tearDownCalled, tearDownCalled)
+		})
+	}
+}
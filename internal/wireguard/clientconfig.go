@@ -0,0 +1,62 @@
+package wireguard
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// ClientConfig contains the fields to generate a Wireguard
+// configuration file for a peer of the Wireguard server.
+type ClientConfig struct {
+	// PrivateKey is the peer private key in base 64 format.
+	PrivateKey string
+	// Address is the peer address.
+	Address netip.Addr
+	// DNS is the DNS server address to use, and can be
+	// left invalid to not set a DNS server.
+	DNS netip.Addr
+	// ServerPublicKey is the server public key in base 64 format.
+	ServerPublicKey string
+	// PreSharedKey is the pre-shared key in base 64 format,
+	// and can be left empty.
+	PreSharedKey string
+	// Endpoint is the server host and port to connect to.
+	Endpoint string
+}
+
+// String returns the client configuration in the wg-quick
+// configuration file format, routing all IPv4 traffic
+// through the server, as well as all IPv6 traffic if the
+// peer address is an IPv6 address.
+func (c ClientConfig) String() string {
+	lines := []string{
+		"[Interface]",
+		"PrivateKey = " + c.PrivateKey,
+		"Address = " + netip.PrefixFrom(c.Address, c.Address.BitLen()).String(),
+	}
+	if c.DNS.IsValid() {
+		lines = append(lines, "DNS = "+c.DNS.String())
+	}
+
+	lines = append(lines,
+		"",
+		"[Peer]",
+		"PublicKey = "+c.ServerPublicKey,
+	)
+	if c.PreSharedKey != "" {
+		lines = append(lines, "PresharedKey = "+c.PreSharedKey)
+	}
+	allowedIPs := "0.0.0.0/0"
+	if c.Address.Is6() {
+		allowedIPs += ", ::/0"
+	}
+	const persistentKeepalive = 25
+	lines = append(lines,
+		"Endpoint = "+c.Endpoint,
+		"AllowedIPs = "+allowedIPs,
+		fmt.Sprintf("PersistentKeepalive = %d", persistentKeepalive),
+	)
+
+	return strings.Join(lines, "\n") + "\n"
+}
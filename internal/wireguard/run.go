@@ -42,24 +42,11 @@ func (w *Wireguard) Run(ctx context.Context, waitError chan<- error, ready chan<
 		return
 	}
 
-	setupFunction := setupUserSpace
-	switch w.settings.Implementation {
-	case "auto": //nolint:goconst
-		if !kernelSupported {
-			w.logger.Info("Using userspace implementation since Kernel support does not exist")
-			break
-		}
-		w.logger.Info("Using available kernelspace implementation")
-		setupFunction = setupKernelSpace
-	case "userspace":
-	case "kernelspace":
-		if !kernelSupported {
-			waitError <- fmt.Errorf("%w", ErrKernelSupport)
-			return
-		}
-		setupFunction = setupKernelSpace
-	default:
-		panic(fmt.Sprintf("unknown implementation %q", w.settings.Implementation))
+	setupFunction, err := selectSetupFunction(w.settings.Implementation,
+		kernelSupported, w.logger)
+	if err != nil {
+		waitError <- err
+		return
 	}
 
 	client, err := wgctrl.New()
@@ -118,14 +105,16 @@ func (w *Wireguard) Run(ctx context.Context, waitError chan<- error, ready chan<
 		}
 	}
 
-	ruleCleanup, err := w.addRule(w.settings.RulePriority,
-		w.settings.FirewallMark, unix.AF_INET)
-	if err != nil {
-		waitError <- fmt.Errorf("adding IPv4 rule: %w", err)
-		return
+	if !w.settings.SkipRules {
+		ruleCleanup, err := w.addRule(w.settings.RulePriority,
+			w.settings.FirewallMark, unix.AF_INET)
+		if err != nil {
+			waitError <- fmt.Errorf("adding IPv4 rule: %w", err)
+			return
+		}
+		closers.add("removing IPv4 rule", stepOne, ruleCleanup)
 	}
 
-	closers.add("removing IPv4 rule", stepOne, ruleCleanup)
 	w.logger.Info("Wireguard is up")
 	ready <- struct{}{}
 
@@ -146,6 +135,10 @@ func (w *Wireguard) setupIPv6(link netlink.Link, closers *closers) (err error) {
 		return fmt.Errorf("%w: %s", ErrRouteAdd, err)
 	}
 
+	if w.settings.SkipRules {
+		return nil
+	}
+
 	ruleCleanup6, ruleErr := w.addRule(
 		w.settings.RulePriority, w.settings.FirewallMark,
 		unix.AF_INET6)
@@ -159,6 +152,32 @@ func (w *Wireguard) setupIPv6(link netlink.Link, closers *closers) (err error) {
 
 type waitAndCleanupFunc func() error
 
+type setupFunc func(ctx context.Context, interfaceName string,
+	netLinker NetLinker, mtu uint16, closers *closers, logger Logger) (
+	link netlink.Link, waitAndCleanup waitAndCleanupFunc, err error)
+
+func selectSetupFunction(implementation string, kernelSupported bool,
+	logger Logger) (setupFunction setupFunc, err error) {
+	switch implementation {
+	case "auto": //nolint:goconst
+		if !kernelSupported {
+			logger.Info("Using userspace implementation since Kernel support does not exist")
+			return setupUserSpace, nil
+		}
+		logger.Info("Using available kernelspace implementation")
+		return setupKernelSpace, nil
+	case "userspace":
+		return setupUserSpace, nil
+	case "kernelspace":
+		if !kernelSupported {
+			return nil, fmt.Errorf("%w", ErrKernelSupport)
+		}
+		return setupKernelSpace, nil
+	default:
+		panic(fmt.Sprintf("unknown implementation %q", implementation))
+	}
+}
+
 func setupKernelSpace(ctx context.Context,
 	interfaceName string, netLinker NetLinker, mtu uint16,
 	closers *closers, logger Logger) (
@@ -0,0 +1,248 @@
+package wireguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+
+	"github.com/qdm12/gluetun/internal/netlink"
+	"golang.zx2c4.com/wireguard/device"
+	"golang.zx2c4.com/wireguard/wgctrl"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// ServerSettings contains settings for a Wireguard server
+// interface, accepting connections from its peers.
+type ServerSettings struct {
+	// InterfaceName is the name of the Wireguard server interface.
+	// It defaults to wgs0 if unset.
+	InterfaceName string
+	// PrivateKey is the server private key in base 64 format.
+	PrivateKey string
+	// ListenPort is the UDP port to listen on.
+	// It defaults to 51820 if left to 0.
+	ListenPort uint16
+	// Address is the server interface address, with its prefix
+	// length defining the subnet of the peers.
+	Address netip.Prefix
+	// Peers are the peers allowed to connect to the server.
+	Peers []ServerPeer
+	// Maximum Transmission Unit (MTU) setting for the network interface.
+	// It defaults to device.DefaultMTU from wireguard-go which is 1420
+	MTU uint16
+	// Implementation is the implementation to use.
+	// It can be auto, kernelspace or userspace, and defaults to auto.
+	Implementation string
+}
+
+// ServerPeer is a peer of the Wireguard server.
+type ServerPeer struct {
+	// PublicKey is the peer public key in base 64 format.
+	PublicKey string
+	// PreSharedKey is the pre-shared key in base 64 format,
+	// and can be left empty.
+	PreSharedKey string
+	// Address is the single address the peer can use.
+	Address netip.Addr
+}
+
+func (s *ServerSettings) SetDefaults() {
+	if s.InterfaceName == "" {
+		const defaultInterfaceName = "wgs0"
+		s.InterfaceName = defaultInterfaceName
+	}
+
+	if s.ListenPort == 0 {
+		const defaultListenPort = 51820
+		s.ListenPort = defaultListenPort
+	}
+
+	if s.MTU == 0 {
+		s.MTU = device.DefaultMTU
+	}
+
+	if s.Implementation == "" {
+		const defaultImplementation = "auto"
+		s.Implementation = defaultImplementation
+	}
+}
+
+var ErrPeerAddressNotValid = errors.New("peer address is not valid")
+
+func (s *ServerSettings) Check() (err error) {
+	if !interfaceNameRegexp.MatchString(s.InterfaceName) {
+		return fmt.Errorf("%w: %s", ErrInterfaceNameInvalid, s.InterfaceName)
+	}
+
+	if s.PrivateKey == "" {
+		return fmt.Errorf("%w", ErrPrivateKeyMissing)
+	} else if _, err := wgtypes.ParseKey(s.PrivateKey); err != nil {
+		return fmt.Errorf("%w", ErrPrivateKeyInvalid)
+	}
+
+	if !s.Address.IsValid() {
+		return fmt.Errorf("%w", ErrAddressNotValid)
+	}
+
+	for _, peer := range s.Peers {
+		if _, err := wgtypes.ParseKey(peer.PublicKey); err != nil {
+			return fmt.Errorf("%w: %s", ErrPublicKeyInvalid, peer.PublicKey)
+		}
+
+		if peer.PreSharedKey != "" {
+			if _, err := wgtypes.ParseKey(peer.PreSharedKey); err != nil {
+				return fmt.Errorf("%w", ErrPreSharedKeyInvalid)
+			}
+		}
+
+		if !peer.Address.IsValid() {
+			return fmt.Errorf("%w: for peer %s", ErrPeerAddressNotValid, peer.PublicKey)
+		}
+	}
+
+	switch s.Implementation {
+	case "auto", "kernelspace", "userspace":
+	default:
+		return fmt.Errorf("%w: %s", ErrImplementationInvalid, s.Implementation)
+	}
+
+	return nil
+}
+
+// Server is a Wireguard server interface, to be used
+// for remote access into the network namespace.
+type Server struct {
+	logger   Logger
+	settings ServerSettings
+	netlink  NetLinker
+}
+
+func NewServer(settings ServerSettings, netlink NetLinker,
+	logger Logger) (s *Server, err error) {
+	settings.SetDefaults()
+	if err := settings.Check(); err != nil {
+		return nil, err
+	}
+
+	return &Server{
+		logger:   logger,
+		settings: settings,
+		netlink:  netlink,
+	}, nil
+}
+
+// Run creates and configures the Wireguard server interface, signals
+// on ready once it is up and then waits for the context to be canceled,
+// at which point the interface is removed and an error is sent on waitError.
+func (s *Server) Run(ctx context.Context, waitError chan<- error, ready chan<- struct{}) {
+	kernelSupported, err := s.netlink.IsWireguardSupported()
+	if err != nil {
+		waitError <- fmt.Errorf("%w: %s", ErrDetectKernel, err)
+		return
+	}
+
+	setupFunction, err := selectSetupFunction(s.settings.Implementation,
+		kernelSupported, s.logger)
+	if err != nil {
+		waitError <- err
+		return
+	}
+
+	client, err := wgctrl.New()
+	if err != nil {
+		waitError <- fmt.Errorf("%w: %s", ErrWgctrlOpen, err)
+		return
+	}
+
+	var closers closers
+	closers.add("closing controller client", stepOne, client.Close)
+
+	defer closers.cleanup(s.logger)
+
+	link, waitAndCleanup, err := setupFunction(ctx,
+		s.settings.InterfaceName, s.netlink, s.settings.MTU, &closers, s.logger)
+	if err != nil {
+		waitError <- err
+		return
+	}
+
+	err = s.netlink.AddrReplace(link, netlink.Addr{Network: s.settings.Address})
+	if err != nil {
+		waitError <- fmt.Errorf("%w: %s", ErrAddAddress, err)
+		return
+	}
+
+	deviceConfig, err := makeServerDeviceConfig(s.settings)
+	if err != nil {
+		waitError <- fmt.Errorf("%w: making device configuration: %s", ErrConfigure, err)
+		return
+	}
+
+	err = client.ConfigureDevice(s.settings.InterfaceName, deviceConfig)
+	if err != nil {
+		waitError <- fmt.Errorf("%w: %s", ErrConfigure, err)
+		return
+	}
+
+	linkIndex, err := s.netlink.LinkSetUp(link)
+	if err != nil {
+		waitError <- fmt.Errorf("%w: %s", ErrIfaceUp, err)
+		return
+	}
+	link.Index = linkIndex
+	closers.add("shutting down link", stepFour, func() error {
+		return s.netlink.LinkSetDown(link)
+	})
+
+	s.logger.Info(fmt.Sprintf("Wireguard server is listening on port %d for %d peer(s)",
+		s.settings.ListenPort, len(s.settings.Peers)))
+	ready <- struct{}{}
+
+	waitError <- waitAndCleanup()
+}
+
+func makeServerDeviceConfig(settings ServerSettings) (config wgtypes.Config, err error) {
+	privateKey, err := wgtypes.ParseKey(settings.PrivateKey)
+	if err != nil {
+		return config, ErrPrivateKeyInvalid
+	}
+
+	listenPort := int(settings.ListenPort)
+	config = wgtypes.Config{
+		PrivateKey:   &privateKey,
+		ListenPort:   &listenPort,
+		ReplacePeers: true,
+		Peers:        make([]wgtypes.PeerConfig, len(settings.Peers)),
+	}
+
+	for i, peer := range settings.Peers {
+		publicKey, err := wgtypes.ParseKey(peer.PublicKey)
+		if err != nil {
+			return wgtypes.Config{}, fmt.Errorf("%w: %s", ErrPublicKeyInvalid, peer.PublicKey)
+		}
+
+		var preSharedKey *wgtypes.Key
+		if peer.PreSharedKey != "" {
+			preSharedKeyValue, err := wgtypes.ParseKey(peer.PreSharedKey)
+			if err != nil {
+				return wgtypes.Config{}, ErrPreSharedKeyInvalid
+			}
+			preSharedKey = &preSharedKeyValue
+		}
+
+		peerPrefix := netip.PrefixFrom(peer.Address, peer.Address.BitLen())
+		config.Peers[i] = wgtypes.PeerConfig{
+			PublicKey:    publicKey,
+			PresharedKey: preSharedKey,
+			AllowedIPs: []net.IPNet{{
+				IP:   peerPrefix.Addr().AsSlice(),
+				Mask: net.CIDRMask(peerPrefix.Bits(), peerPrefix.Addr().BitLen()),
+			}},
+			ReplaceAllowedIPs: true,
+		}
+	}
+
+	return config, nil
+}
@@ -0,0 +1,180 @@
+package wireguard
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+func Test_makeServerDeviceConfig(t *testing.T) {
+	t.Parallel()
+
+	const (
+		validKey1 = "oMNSf/zJ0pt1ciy+qIRk8Rlyfs9accwuRLnKd85Yl1Q="
+		validKey2 = "aPjc9US5ICB30D1P4glR9tO7bkB2Ga+KZiFqnoypBHk="
+		validKey3 = "gFIW0lTmBYEucynoIg+XmeWckDUXTcC4Po5ijR5G+HM="
+	)
+
+	parseKey := func(t *testing.T, s string) *wgtypes.Key {
+		t.Helper()
+		key, err := wgtypes.ParseKey(s)
+		require.NoError(t, err)
+		return &key
+	}
+
+	intPtr := func(n int) *int { return &n }
+
+	testCases := map[string]struct {
+		settings ServerSettings
+		config   wgtypes.Config
+		err      error
+	}{
+		"bad private key": {
+			settings: ServerSettings{
+				PrivateKey: "bad key",
+			},
+			err: ErrPrivateKeyInvalid,
+		},
+		"bad peer public key": {
+			settings: ServerSettings{
+				PrivateKey: validKey1,
+				Peers:      []ServerPeer{{PublicKey: "bad key"}},
+			},
+			err: errors.New("cannot parse public key: bad key"),
+		},
+		"no peer": {
+			settings: ServerSettings{
+				PrivateKey: validKey1,
+				ListenPort: 51820,
+			},
+			config: wgtypes.Config{
+				PrivateKey:   parseKey(t, validKey1),
+				ListenPort:   intPtr(51820),
+				ReplacePeers: true,
+				Peers:        []wgtypes.PeerConfig{},
+			},
+		},
+		"two peers": {
+			settings: ServerSettings{
+				PrivateKey: validKey1,
+				ListenPort: 51820,
+				Peers: []ServerPeer{
+					{
+						PublicKey: validKey2,
+						Address:   netip.AddrFrom4([4]byte{10, 13, 13, 2}),
+					},
+					{
+						PublicKey:    validKey3,
+						PreSharedKey: validKey1,
+						Address:      netip.AddrFrom4([4]byte{10, 13, 13, 3}),
+					},
+				},
+			},
+			config: wgtypes.Config{
+				PrivateKey:   parseKey(t, validKey1),
+				ListenPort:   intPtr(51820),
+				ReplacePeers: true,
+				Peers: []wgtypes.PeerConfig{
+					{
+						PublicKey: *parseKey(t, validKey2),
+						AllowedIPs: []net.IPNet{{
+							IP:   net.IP{10, 13, 13, 2},
+							Mask: net.IPMask{255, 255, 255, 255},
+						}},
+						ReplaceAllowedIPs: true,
+					},
+					{
+						PublicKey:    *parseKey(t, validKey3),
+						PresharedKey: parseKey(t, validKey1),
+						AllowedIPs: []net.IPNet{{
+							IP:   net.IP{10, 13, 13, 3},
+							Mask: net.IPMask{255, 255, 255, 255},
+						}},
+						ReplaceAllowedIPs: true,
+					},
+				},
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			config, err := makeServerDeviceConfig(testCase.settings)
+
+			assert.Equal(t, testCase.config, config)
+			if testCase.err != nil {
+				require.Error(t, err)
+				assert.Equal(t, testCase.err.Error(), err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_ClientConfig_String(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		config   ClientConfig
+		expected string
+	}{
+		"ipv4": {
+			config: ClientConfig{
+				PrivateKey:      "private",
+				Address:         netip.AddrFrom4([4]byte{10, 13, 13, 2}),
+				DNS:             netip.AddrFrom4([4]byte{10, 13, 13, 1}),
+				ServerPublicKey: "public",
+				PreSharedKey:    "preshared",
+				Endpoint:        "example.com:51820",
+			},
+			expected: `[Interface]
+PrivateKey = private
+Address = 10.13.13.2/32
+DNS = 10.13.13.1
+
+[Peer]
+PublicKey = public
+PresharedKey = preshared
+Endpoint = example.com:51820
+AllowedIPs = 0.0.0.0/0
+PersistentKeepalive = 25
+`,
+		},
+		"ipv6": {
+			config: ClientConfig{
+				PrivateKey:      "private",
+				Address:         netip.MustParseAddr("fd00::2"),
+				ServerPublicKey: "public",
+				Endpoint:        "example.com:51820",
+			},
+			expected: `[Interface]
+PrivateKey = private
+Address = fd00::2/128
+
+[Peer]
+PublicKey = public
+Endpoint = example.com:51820
+AllowedIPs = 0.0.0.0/0, ::/0
+PersistentKeepalive = 25
+`,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, testCase.expected, testCase.config.String())
+		})
+	}
+}
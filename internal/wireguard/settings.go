@@ -35,6 +35,11 @@ type Settings struct {
 	// RulePriority is the priority for the rule created with the
 	// FirewallMark.
 	RulePriority int
+	// SkipRules, if true, does not add the IP rules routing all
+	// traffic not marked with FirewallMark through the tunnel, so
+	// the caller can route selected traffic to the FirewallMark table.
+	// It defaults to false.
+	SkipRules bool
 	// IPv6 can bet set to true if IPv6 should be handled.
 	// It defaults to false if left unset.
 	IPv6 *bool
@@ -0,0 +1,314 @@
+// Package client is a Go client for the Gluetun control server v1 API,
+// which is described by the OpenAPI specification served at /v1/openapi.yaml.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client is a client for the Gluetun control server.
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+}
+
+// New creates a new client for the control server at the base URL given,
+// for example http://localhost:8000. If httpClient is nil, the
+// http.DefaultClient is used.
+func New(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		httpClient: httpClient,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// do sends a request with the JSON encoded body given if it is not nil,
+// and decodes the JSON response into the response pointer given if it
+// is not nil. Non 2xx responses are returned as an *Error.
+func (c *Client) do(ctx context.Context, method, path string,
+	body, response any) (err error) {
+	var bodyReader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encoding request body: %w", err)
+		}
+		bodyReader = bytes.NewReader(data)
+	}
+
+	url := c.baseURL + "/v1" + path
+	request, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	httpResponse, err := c.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+
+	data, err := io.ReadAll(httpResponse.Body)
+	if err != nil {
+		_ = httpResponse.Body.Close()
+		return fmt.Errorf("reading response body: %w", err)
+	}
+
+	err = httpResponse.Body.Close()
+	if err != nil {
+		return fmt.Errorf("closing response body: %w", err)
+	}
+
+	if httpResponse.StatusCode < http.StatusOK ||
+		httpResponse.StatusCode >= http.StatusMultipleChoices {
+		return makeError(httpResponse.StatusCode, data)
+	}
+
+	if response == nil {
+		return nil
+	}
+
+	err = json.Unmarshal(data, response)
+	if err != nil {
+		return fmt.Errorf("decoding response body: %w", err)
+	}
+	return nil
+}
+
+// Version returns the build information of the program.
+func (c *Client) Version(ctx context.Context) (buildInfo BuildInformation, err error) {
+	err = c.do(ctx, http.MethodGet, "/version", nil, &buildInfo)
+	return buildInfo, err
+}
+
+// OpenAPI returns the OpenAPI specification of the API in YAML.
+func (c *Client) OpenAPI(ctx context.Context) (spec []byte, err error) {
+	return c.getRaw(ctx, "/openapi.yaml")
+}
+
+// getRaw sends a GET request to the v1 path given and returns
+// the response body as is, for responses which are not JSON.
+func (c *Client) getRaw(ctx context.Context, path string) (body []byte, err error) {
+	url := c.baseURL + "/v1" + path
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	body, err = io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, makeError(response.StatusCode, body)
+	}
+	return body, nil
+}
+
+// Status returns the status of the loop of the service given,
+// which can be ServiceVPN, ServiceDNS or ServiceUpdater.
+func (c *Client) Status(ctx context.Context, service Service) (status Status, err error) {
+	var data statusWrapper
+	err = c.do(ctx, http.MethodGet, "/"+string(service)+"/status", nil, &data)
+	return data.Status, err
+}
+
+// SetStatus starts or stops the loop of the service given,
+// which can be ServiceVPN, ServiceDNS or ServiceUpdater.
+func (c *Client) SetStatus(ctx context.Context, service Service,
+	status Status) (outcome string, err error) {
+	var data outcomeWrapper
+	err = c.do(ctx, http.MethodPut, "/"+string(service)+"/status",
+		statusWrapper{Status: status}, &data)
+	return data.Outcome, err
+}
+
+// Settings decodes the settings of the service given into the
+// settings pointer given. Secret values are redacted.
+func (c *Client) Settings(ctx context.Context, service Service, settings any) (err error) {
+	return c.do(ctx, http.MethodGet, "/"+string(service)+"/settings", nil, settings)
+}
+
+// PatchSettings changes the settings of the service given with the
+// fields set in the patch given, which must encode to a JSON object
+// using the same field names as the settings.
+func (c *Client) PatchSettings(ctx context.Context, service Service,
+	patch any) (outcome string, err error) {
+	var data outcomeWrapper
+	err = c.do(ctx, http.MethodPatch, "/"+string(service)+"/settings", patch, &data)
+	return data.Outcome, err
+}
+
+// Tunnels returns the statuses of the additional tunnels.
+func (c *Client) Tunnels(ctx context.Context) (tunnels []Tunnel, err error) {
+	var data tunnelsWrapper
+	err = c.do(ctx, http.MethodGet, "/vpn/tunnels", nil, &data)
+	return data.Tunnels, err
+}
+
+// SetTunnelStatus starts or stops the additional tunnel given.
+func (c *Client) SetTunnelStatus(ctx context.Context, name string,
+	status Status) (outcome string, err error) {
+	var data outcomeWrapper
+	body := tunnelStatusWrapper{Name: name, Status: status}
+	err = c.do(ctx, http.MethodPut, "/vpn/tunnels/status", body, &data)
+	return data.Outcome, err
+}
+
+// TunnelSettings decodes the settings of the additional tunnel
+// given into the settings pointer given. Secret values are redacted.
+func (c *Client) TunnelSettings(ctx context.Context, name string, settings any) (err error) {
+	path := "/vpn/tunnels/" + url.PathEscape(name) + "/settings"
+	return c.do(ctx, http.MethodGet, path, nil, settings)
+}
+
+// PatchTunnelSettings changes the settings of the additional tunnel
+// given with the fields set in the patch given, restarting the tunnel
+// if it is running.
+func (c *Client) PatchTunnelSettings(ctx context.Context, name string,
+	patch any) (outcome string, err error) {
+	var data outcomeWrapper
+	path := "/vpn/tunnels/" + url.PathEscape(name) + "/settings"
+	err = c.do(ctx, http.MethodPatch, path, patch, &data)
+	return data.Outcome, err
+}
+
+// Challenge returns the pending OpenVPN authentication challenge,
+// or nil if there is no pending challenge.
+func (c *Client) Challenge(ctx context.Context) (challenge *Challenge, err error) {
+	var data challengeWrapper
+	err = c.do(ctx, http.MethodGet, "/openvpn/challenge", nil, &data)
+	return data.Challenge, err
+}
+
+// AnswerChallenge answers the pending OpenVPN authentication challenge.
+func (c *Client) AnswerChallenge(ctx context.Context, response string) (err error) {
+	body := challengeResponseWrapper{Response: response}
+	return c.do(ctx, http.MethodPut, "/openvpn/challenge", body, nil)
+}
+
+// PortForwarded returns the forwarded port, or 0 if no port is forwarded.
+func (c *Client) PortForwarded(ctx context.Context) (port uint16, err error) {
+	var data portWrapper
+	err = c.do(ctx, http.MethodGet, "/openvpn/portforwarded", nil, &data)
+	return data.Port, err
+}
+
+// PublicIP returns the public IP address information.
+func (c *Client) PublicIP(ctx context.Context) (publicIP PublicIP, err error) {
+	err = c.do(ctx, http.MethodGet, "/publicip/ip", nil, &publicIP)
+	return publicIP, err
+}
+
+// ExitCheck returns the last exit location check result,
+// or nil if no check was done.
+func (c *Client) ExitCheck(ctx context.Context) (check *ExitCheck, err error) {
+	var data exitCheckWrapper
+	err = c.do(ctx, http.MethodGet, "/publicip/exit", nil, &data)
+	return data.ExitCheck, err
+}
+
+// UpdaterDiffs returns the servers differences of each provider
+// from the last servers update.
+func (c *Client) UpdaterDiffs(ctx context.Context) (diffs []ProviderDiff, err error) {
+	var data diffsWrapper
+	err = c.do(ctx, http.MethodGet, "/updater/diff", nil, &data)
+	return data.Diffs, err
+}
+
+// HTTPProxyClients returns the traffic proxied per client
+// by the HTTP proxy, sorted by client IP address.
+func (c *Client) HTTPProxyClients(ctx context.Context) (clients []ProxyClient, err error) {
+	var data proxyClientsWrapper
+	err = c.do(ctx, http.MethodGet, "/httpproxy/clients", nil, &data)
+	return data.Clients, err
+}
+
+// ShadowsocksClients returns the traffic proxied per client
+// by the Shadowsocks server, sorted by client IP address.
+func (c *Client) ShadowsocksClients(ctx context.Context) (clients []ProxyClient, err error) {
+	var data proxyClientsWrapper
+	err = c.do(ctx, http.MethodGet, "/shadowsocks/clients", nil, &data)
+	return data.Clients, err
+}
+
+// HTTPProxyPAC returns the proxy auto-config file for the HTTP proxy,
+// using the host of the client base URL as proxy host.
+func (c *Client) HTTPProxyPAC(ctx context.Context) (pac string, err error) {
+	data, err := c.getRaw(ctx, "/httpproxy/pac")
+	return string(data), err
+}
+
+// HTTPProxyUsers returns the usage statistics of the
+// HTTP proxy authenticated users, sorted by user name.
+func (c *Client) HTTPProxyUsers(ctx context.Context) (users []HTTPProxyUser, err error) {
+	var data httpProxyUsersWrapper
+	err = c.do(ctx, http.MethodGet, "/httpproxy/users", nil, &data)
+	return data.Users, err
+}
+
+// ShadowsocksUsers returns the enabled state and usage statistics
+// of the Shadowsocks users, sorted by user name.
+func (c *Client) ShadowsocksUsers(ctx context.Context) (users []ShadowsocksUser, err error) {
+	var data shadowsocksUsersWrapper
+	err = c.do(ctx, http.MethodGet, "/shadowsocks/users", nil, &data)
+	return data.Users, err
+}
+
+// SetShadowsocksUserEnabled enables or disables the Shadowsocks
+// user given, closing its connections if it is disabled.
+func (c *Client) SetShadowsocksUserEnabled(ctx context.Context, name string,
+	enabled bool) (outcome string, err error) {
+	var data outcomeWrapper
+	path := "/shadowsocks/users/" + url.PathEscape(name)
+	body := enabledWrapper{Enabled: enabled}
+	err = c.do(ctx, http.MethodPut, path, body, &data)
+	return data.Outcome, err
+}
+
+// ShapingThroughput returns the current throughput of the
+// HTTP proxy and Shadowsocks clients, globally and per client.
+func (c *Client) ShapingThroughput(ctx context.Context) (throughput Throughput, err error) {
+	err = c.do(ctx, http.MethodGet, "/shaping/throughput", nil, &throughput)
+	return throughput, err
+}
+
+// ServersHistory returns the previous servers snapshots of the
+// provider given, from the most recent to the oldest one.
+func (c *Client) ServersHistory(ctx context.Context, provider string) (
+	snapshots []Snapshot, err error) {
+	var data snapshotsWrapper
+	path := "/servers/" + url.PathEscape(provider) + "/history"
+	err = c.do(ctx, http.MethodGet, path, nil, &data)
+	return data.Snapshots, err
+}
+
+// RollbackServers sets the servers of the provider given back to
+// the servers of its snapshot with the timestamp given.
+func (c *Client) RollbackServers(ctx context.Context, provider string,
+	timestamp int64) (outcome string, err error) {
+	var data outcomeWrapper
+	path := "/servers/" + url.PathEscape(provider) + "/rollback"
+	body := timestampWrapper{Timestamp: timestamp}
+	err = c.do(ctx, http.MethodPut, path, body, &data)
+	return data.Outcome, err
+}
@@ -0,0 +1,154 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Client(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method + " " + r.URL.Path {
+		case "GET /v1/vpn/status":
+			_, _ = io.WriteString(w, `{"status":"running"}`)
+		case "PUT /v1/dns/status":
+			body, _ := io.ReadAll(r.Body)
+			assert.JSONEq(t, `{"status":"stopped"}`, string(body))
+			_, _ = io.WriteString(w, `{"outcome":"stopped"}`)
+		case "GET /v1/httpproxy/settings":
+			_, _ = io.WriteString(w, `{"User":"user","Password":"[redacted]"}`)
+		case "PATCH /v1/httpproxy/settings":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"error":"listening address is not valid"}`)
+		case "GET /v1/shadowsocks/clients":
+			_, _ = io.WriteString(w, `{"clients":[{"client":"10.0.0.2","requests":3,`+
+				`"bytes_up":5,"bytes_down":6,"last_seen":"2023-01-02T03:04:05Z"}]}`)
+		case "GET /v1/httpproxy/users":
+			_, _ = io.WriteString(w, `{"users":[{"name":"alice","active_connections":1,`+
+				`"requests":2,"rejected":0,"bytes_sent":3,"bytes_received":4}]}`)
+		case "GET /v1/shadowsocks/users":
+			_, _ = io.WriteString(w, `{"users":[{"name":"alice","enabled":false,`+
+				`"active_connections":0,"connections":2,"rejected":1,`+
+				`"bytes_sent":3,"bytes_received":4}]}`)
+		case "PUT /v1/shadowsocks/users/alice":
+			body, _ := io.ReadAll(r.Body)
+			assert.JSONEq(t, `{"enabled":false}`, string(body))
+			_, _ = io.WriteString(w, `{"outcome":"user alice disabled"}`)
+		case "GET /v1/vpn/tunnels/office/settings":
+			_, _ = io.WriteString(w, `{"Type":"openvpn","OpenVPN":{"Password":"[redacted]"}}`)
+		case "PATCH /v1/vpn/tunnels/office/settings":
+			body, _ := io.ReadAll(r.Body)
+			assert.JSONEq(t, `{"Sources":["10.0.0.0/24"]}`, string(body))
+			_, _ = io.WriteString(w, `{"outcome":"settings updated"}`)
+		case "GET /v1/shaping/throughput":
+			_, _ = io.WriteString(w, `{"upload":10,"download":20,"clients":`+
+				`[{"client":"10.0.0.2","streams":2,"upload":10,"download":20}]}`)
+		case "GET /v1/httpproxy/pac":
+			w.Header().Set("Content-Type", "application/x-ns-proxy-autoconfig")
+			_, _ = io.WriteString(w, `function FindProxyForURL(url, host) {}`)
+		case "GET /v1/servers/mullvad/history":
+			_, _ = io.WriteString(w, `{"snapshots":[{"timestamp":1700000000,`+
+				`"version":1,"replaced_at":"2023-11-14T22:13:21Z","count":2}]}`)
+		case "PUT /v1/servers/mullvad/rollback":
+			body, _ := io.ReadAll(r.Body)
+			assert.JSONEq(t, `{"timestamp":1700000000}`, string(body))
+			_, _ = io.WriteString(w, `{"outcome":"rolled back"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"error":"route not found"}`)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := New(server.URL+"/", server.Client())
+	ctx := context.Background()
+
+	status, err := client.Status(ctx, ServiceVPN)
+	require.NoError(t, err)
+	assert.Equal(t, StatusRunning, status)
+
+	outcome, err := client.SetStatus(ctx, ServiceDNS, StatusStopped)
+	require.NoError(t, err)
+	assert.Equal(t, "stopped", outcome)
+
+	var settings struct {
+		User     string
+		Password string
+	}
+	err = client.Settings(ctx, ServiceHTTPProxy, &settings)
+	require.NoError(t, err)
+	assert.Equal(t, "user", settings.User)
+	assert.Equal(t, "[redacted]", settings.Password)
+
+	_, err = client.PatchSettings(ctx, ServiceHTTPProxy,
+		map[string]string{"ListeningAddress": "invalid"})
+	var clientErr *Error
+	require.True(t, errors.As(err, &clientErr))
+	assert.Equal(t, http.StatusBadRequest, clientErr.StatusCode)
+	assert.Equal(t, "listening address is not valid", clientErr.Message)
+
+	clients, err := client.ShadowsocksClients(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []ProxyClient{{Client: "10.0.0.2", Requests: 3, BytesUp: 5,
+		BytesDown: 6, LastSeen: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)}}, clients)
+
+	users, err := client.HTTPProxyUsers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []HTTPProxyUser{{Name: "alice", ActiveConnections: 1,
+		Requests: 2, BytesSent: 3, BytesReceived: 4}}, users)
+
+	outcome, err = client.SetShadowsocksUserEnabled(ctx, "alice", false)
+	require.NoError(t, err)
+	assert.Equal(t, "user alice disabled", outcome)
+
+	shadowsocksUsers, err := client.ShadowsocksUsers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []ShadowsocksUser{{Name: "alice", Connections: 2,
+		Rejected: 1, BytesSent: 3, BytesReceived: 4}}, shadowsocksUsers)
+
+	throughput, err := client.ShapingThroughput(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, Throughput{Upload: 10, Download: 20, Clients: []ClientThroughput{
+		{Client: "10.0.0.2", Streams: 2, Upload: 10, Download: 20}}}, throughput)
+
+	pac, err := client.HTTPProxyPAC(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, `function FindProxyForURL(url, host) {}`, pac)
+
+	snapshots, err := client.ServersHistory(ctx, "mullvad")
+	require.NoError(t, err)
+	require.Len(t, snapshots, 1)
+	assert.Equal(t, int64(1700000000), snapshots[0].Timestamp)
+	assert.Equal(t, 2, snapshots[0].Count)
+
+	outcome, err = client.RollbackServers(ctx, "mullvad", 1700000000)
+	require.NoError(t, err)
+	assert.Equal(t, "rolled back", outcome)
+
+	var tunnelSettings struct {
+		Type    string
+		OpenVPN struct{ Password string }
+	}
+	err = client.TunnelSettings(ctx, "office", &tunnelSettings)
+	require.NoError(t, err)
+	assert.Equal(t, "openvpn", tunnelSettings.Type)
+	assert.Equal(t, "[redacted]", tunnelSettings.OpenVPN.Password)
+
+	patch := map[string]any{"Sources": []string{"10.0.0.0/24"}}
+	outcome, err = client.PatchTunnelSettings(ctx, "office", patch)
+	require.NoError(t, err)
+	assert.Equal(t, "settings updated", outcome)
+
+	_, err = client.Tunnels(ctx)
+	assert.EqualError(t, err, "HTTP status code 404: route not found")
+}
@@ -0,0 +1,34 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// Error is an error response from the control server.
+type Error struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// Message is the error message from the response body.
+	Message string
+}
+
+func (e *Error) Error() string {
+	return "HTTP status code " + strconv.Itoa(e.StatusCode) + ": " + e.Message
+}
+
+func makeError(statusCode int, body []byte) *Error {
+	var data struct {
+		Error string `json:"error"`
+	}
+	err := json.Unmarshal(body, &data)
+	message := data.Error
+	if err != nil || message == "" {
+		message = http.StatusText(statusCode)
+	}
+	return &Error{
+		StatusCode: statusCode,
+		Message:    message,
+	}
+}
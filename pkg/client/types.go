@@ -0,0 +1,277 @@
+package client
+
+import (
+	"net/netip"
+	"time"
+)
+
+// Service is the path prefix of a service of the control server.
+type Service string
+
+const (
+	ServiceVPN         Service = "vpn"
+	ServiceOpenVPN     Service = "openvpn"
+	ServiceDNS         Service = "dns"
+	ServiceUpdater     Service = "updater"
+	ServicePublicIP    Service = "publicip"
+	ServicePortForward Service = "portforward"
+	ServiceHTTPProxy   Service = "httpproxy"
+	ServiceShadowsocks Service = "shadowsocks"
+	// ServiceShaping only has settings, which are the bandwidth
+	// limits of the HTTP proxy and Shadowsocks clients.
+	ServiceShaping Service = "shaping"
+)
+
+// Status is the status of a loop.
+type Status string
+
+const (
+	StatusStarting  Status = "starting"
+	StatusRunning   Status = "running"
+	StatusStopping  Status = "stopping"
+	StatusStopped   Status = "stopped"
+	StatusCrashed   Status = "crashed"
+	StatusCompleted Status = "completed"
+)
+
+// BuildInformation is the build information of the program.
+type BuildInformation struct {
+	Version string `json:"version"`
+	Commit  string `json:"commit"`
+	Created string `json:"created"`
+}
+
+// Tunnel is the status of an additional OpenVPN or Wireguard tunnel.
+type Tunnel struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Interface string `json:"interface"`
+	Provider  string `json:"provider"`
+	// Endpoint is the address of the VPN server the tunnel is
+	// connected to, and is empty if the tunnel is not connected.
+	Endpoint netip.AddrPort `json:"endpoint"`
+	// Hostname is the hostname of the VPN server the tunnel is
+	// connected to, and is empty if it is unknown.
+	Hostname     string         `json:"hostname,omitempty"`
+	Sources      []netip.Prefix `json:"sources"`
+	Destinations []netip.Prefix `json:"destinations"`
+	Status       Status         `json:"status"`
+	// Error is the last error encountered by the tunnel,
+	// and is empty if the tunnel did not crash.
+	Error string `json:"error,omitempty"`
+}
+
+// Challenge is an OpenVPN authentication challenge.
+type Challenge struct {
+	// Text is the challenge text to display to the user.
+	Text string `json:"text"`
+	// Echo is true if the response can be echoed back.
+	Echo bool `json:"echo"`
+	// Dynamic is true for a CRV1 dynamic challenge.
+	Dynamic bool `json:"dynamic"`
+}
+
+// PublicIP is the public IP address information.
+type PublicIP struct {
+	IP           netip.Addr `json:"public_ip,omitempty"`
+	Region       string     `json:"region,omitempty"`
+	Country      string     `json:"country,omitempty"`
+	City         string     `json:"city,omitempty"`
+	Hostname     string     `json:"hostname,omitempty"`
+	Location     string     `json:"location,omitempty"`
+	Organization string     `json:"organization,omitempty"`
+	PostalCode   string     `json:"postal_code,omitempty"`
+	Timezone     string     `json:"timezone,omitempty"`
+	// IPv6 contains the public IPv6 address information,
+	// and is nil if it is not available.
+	IPv6 *PublicIP `json:"ipv6,omitempty"`
+}
+
+// ExitCheck is the result of an exit location check.
+type ExitCheck struct {
+	Time         time.Time  `json:"time"`
+	IP           netip.Addr `json:"public_ip"`
+	Country      string     `json:"country"`
+	Organization string     `json:"organization"`
+	Allowed      bool       `json:"allowed"`
+	// Reason is the reason the exit location is not allowed,
+	// and is empty if the exit location is allowed.
+	Reason string `json:"reason,omitempty"`
+	// Action is the action taken if the exit location is not allowed.
+	Action string `json:"action,omitempty"`
+}
+
+// ProviderDiff is the difference between the stored servers and
+// the servers fetched for a provider by the servers updater, where
+// servers are identified by their key.
+type ProviderDiff struct {
+	Provider string    `json:"provider"`
+	Time     time.Time `json:"time"`
+	OldCount int       `json:"old_count"`
+	NewCount int       `json:"new_count"`
+	// Added contains the keys of the servers added.
+	Added []string `json:"added"`
+	// Removed contains the keys of the servers removed.
+	Removed []string `json:"removed"`
+	// Changed contains the keys of the servers for which
+	// fields other than their IP addresses changed.
+	Changed []string `json:"changed"`
+	// IPsChanged contains the keys of the servers for
+	// which IP addresses changed.
+	IPsChanged []string `json:"ips_changed"`
+	// ChangeRatio is the ratio of existing servers
+	// removed or changed, not counting IP address changes.
+	ChangeRatio float64 `json:"change_ratio"`
+	// ThresholdExceeded is true if the change ratio
+	// exceeds the updater threshold.
+	ThresholdExceeded bool `json:"threshold_exceeded"`
+	// Applied is true if the servers were written to storage.
+	Applied bool `json:"applied"`
+	// Error is the error encountered updating the provider servers,
+	// and is empty if the update succeeded.
+	Error string `json:"error,omitempty"`
+}
+
+// ProxyClient is the traffic proxied for a client
+// by the HTTP proxy or the Shadowsocks server.
+type ProxyClient struct {
+	// Client is the IP address of the client.
+	Client string `json:"client"`
+	// Users are the user names the client authenticated with.
+	Users []string `json:"users,omitempty"`
+	// Requests is the number of requests or connections proxied.
+	Requests uint64 `json:"requests"`
+	// BytesUp is the number of bytes sent by the client.
+	BytesUp uint64 `json:"bytes_up"`
+	// BytesDown is the number of bytes received by the client.
+	BytesDown uint64    `json:"bytes_down"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// HTTPProxyUser is the usage of the HTTP proxy by an authenticated user.
+type HTTPProxyUser struct {
+	Name              string `json:"name"`
+	ActiveConnections uint   `json:"active_connections"`
+	Requests          uint64 `json:"requests"`
+	// Rejected is the number of requests rejected
+	// because of the user concurrency limit.
+	Rejected uint64 `json:"rejected"`
+	// BytesSent is the number of bytes sent to destinations.
+	BytesSent uint64 `json:"bytes_sent"`
+	// BytesReceived is the number of bytes received from destinations.
+	BytesReceived uint64 `json:"bytes_received"`
+}
+
+// ShadowsocksUser is the usage of the Shadowsocks server by a user.
+type ShadowsocksUser struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	// ActiveConnections is the number of TCP connections
+	// and UDP sessions currently proxied for the user.
+	ActiveConnections int64 `json:"active_connections"`
+	// Connections is the number of TCP connections
+	// and UDP sessions proxied for the user.
+	Connections uint64 `json:"connections"`
+	// Rejected is the number of TCP connections and UDP
+	// packets rejected because the user is disabled.
+	Rejected uint64 `json:"rejected"`
+	// BytesSent is the number of bytes sent to destinations.
+	BytesSent uint64 `json:"bytes_sent"`
+	// BytesReceived is the number of bytes received from destinations.
+	BytesReceived uint64 `json:"bytes_received"`
+}
+
+// Throughput is the throughput of the HTTP proxy and Shadowsocks
+// clients, in bytes per second, measured over the last second.
+type Throughput struct {
+	Upload   uint64 `json:"upload"`
+	Download uint64 `json:"download"`
+	// Clients is the throughput of each client with
+	// at least one stream, sorted by client address.
+	Clients []ClientThroughput `json:"clients"`
+}
+
+// ClientThroughput is the throughput of a client, in bytes
+// per second, measured over the last second.
+type ClientThroughput struct {
+	Client string `json:"client"`
+	// Streams is the number of connections and
+	// UDP sessions currently proxied for the client.
+	Streams  uint   `json:"streams"`
+	Upload   uint64 `json:"upload"`
+	Download uint64 `json:"download"`
+}
+
+// Snapshot is a previous version of the servers of a provider.
+type Snapshot struct {
+	// Timestamp is the Unix timestamp of when the servers were
+	// updated, and identifies the snapshot for the provider.
+	Timestamp int64 `json:"timestamp"`
+	// Version is the servers data format version of the provider.
+	Version uint16 `json:"version"`
+	// ReplacedAt is when the servers were replaced.
+	ReplacedAt time.Time `json:"replaced_at"`
+	// Count is the number of servers.
+	Count int `json:"count"`
+}
+
+type statusWrapper struct {
+	Status Status `json:"status"`
+}
+
+type outcomeWrapper struct {
+	Outcome string `json:"outcome"`
+}
+
+type tunnelsWrapper struct {
+	Tunnels []Tunnel `json:"tunnels"`
+}
+
+type tunnelStatusWrapper struct {
+	Name   string `json:"name"`
+	Status Status `json:"status"`
+}
+
+type challengeWrapper struct {
+	Challenge *Challenge `json:"challenge"`
+}
+
+type challengeResponseWrapper struct {
+	Response string `json:"response"`
+}
+
+type portWrapper struct {
+	Port uint16 `json:"port"`
+}
+
+type exitCheckWrapper struct {
+	ExitCheck *ExitCheck `json:"exit_check"`
+}
+
+type diffsWrapper struct {
+	Diffs []ProviderDiff `json:"diffs"`
+}
+
+type proxyClientsWrapper struct {
+	Clients []ProxyClient `json:"clients"`
+}
+
+type httpProxyUsersWrapper struct {
+	Users []HTTPProxyUser `json:"users"`
+}
+
+type shadowsocksUsersWrapper struct {
+	Users []ShadowsocksUser `json:"users"`
+}
+
+type enabledWrapper struct {
+	Enabled bool `json:"enabled"`
+}
+
+type snapshotsWrapper struct {
+	Snapshots []Snapshot `json:"snapshots"`
+}
+
+type timestampWrapper struct {
+	Timestamp int64 `json:"timestamp"`
+}